@@ -0,0 +1,107 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/alkbt/aid-metrics/pkg/analyzer"
+	"github.com/alkbt/aid-metrics/pkg/benchmark"
+)
+
+// runBenchmarkAgainst implements `aid-metrics benchmark-against corpus1,corpus2 [path]`.
+// It places the module's aggregate metrics in a percentile band against the corpus.
+func runBenchmarkAgainst(args []string) int {
+	fs := flag.NewFlagSet("benchmark-against", flag.ExitOnError)
+	var pattern string
+	var cacheDir string
+	var refresh bool
+	fs.StringVar(&pattern, "pattern", "./...", "Package pattern to analyze")
+	fs.StringVar(&cacheDir, "cache-dir", "", "Directory for cached corpus clones and results (default: user cache dir)")
+	fs.BoolVar(&refresh, "refresh", false, "Re-fetch and re-analyze cached corpus modules")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: aid-metrics benchmark-against [flags] module1,module2,... [path]\n\n")
+		fmt.Fprintf(fs.Output(), "Known corpus modules: %s\n\n", strings.Join(benchmark.KnownNames(), ", "))
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+
+	if fs.NArg() < 1 {
+		fs.Usage()
+		return 1
+	}
+	names := strings.Split(fs.Arg(0), ",")
+	modulePath := "."
+	if fs.NArg() > 1 {
+		modulePath = fs.Arg(1)
+	}
+
+	absPath, err := filepath.Abs(modulePath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: Failed to get absolute path: %v\n", err)
+		return 1
+	}
+
+	if cacheDir == "" {
+		cacheDir, err = benchmark.DefaultCacheDir()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+	}
+	corpus := &benchmark.Corpus{CacheDir: cacheDir, Refresh: refresh}
+
+	// Analyze the corpus modules
+	corpusAggregates := make(map[string]benchmark.Aggregate, len(names))
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "Analyzing corpus module %s...\n", name)
+		agg, err := corpus.Aggregate(name)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		corpusAggregates[name] = agg
+	}
+
+	// Analyze our own module
+	fmt.Fprintf(os.Stderr, "Analyzing Go module at: %s\n", absPath)
+	metrics, err := analyzer.AnalyzeModule(absPath, pattern)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: Failed to analyze module: %v\n", err)
+		return 1
+	}
+	own := benchmark.Summarize(metrics)
+
+	printBenchmark(own, corpusAggregates)
+	return 0
+}
+
+// printBenchmark writes the benchmark comparison table to stdout
+func printBenchmark(own benchmark.Aggregate, corpus map[string]benchmark.Aggregate) {
+	names := make([]string, 0, len(corpus))
+	for name := range corpus {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	defer tw.Flush()
+
+	fmt.Fprintln(tw, "MODULE\tPackages\tavg I\tavg A\tavg D")
+	fmt.Fprintln(tw, "------\t--------\t-----\t-----\t-----")
+	fmt.Fprintf(tw, "(this module)\t%d\t%.2f\t%.2f\t%.2f\n", own.Packages, own.MeanInstability, own.MeanAbstractness, own.MeanDistance)
+	distances := make([]float64, 0, len(names))
+	for _, name := range names {
+		agg := corpus[name]
+		distances = append(distances, agg.MeanDistance)
+		fmt.Fprintf(tw, "%s\t%d\t%.2f\t%.2f\t%.2f\n", name, agg.Packages, agg.MeanInstability, agg.MeanAbstractness, agg.MeanDistance)
+	}
+
+	percentile := benchmark.Percentile(own.MeanDistance, distances)
+	fmt.Fprintf(tw, "\nAverage D %.2f is higher than %.0f%% of the corpus: %s\n",
+		own.MeanDistance, percentile, benchmark.Band(percentile))
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/alkbt/aid-metrics/pkg/cache"
+)
+
+// runCacheServer implements `aid-metrics cache-server -addr :8080 -dir DIR`.
+// It serves the remote cache protocol from a local directory, so CI jobs running
+// with -remote-cache can share per-package analysis results.
+func runCacheServer(args []string) int {
+	fs := flag.NewFlagSet("cache-server", flag.ExitOnError)
+	var addr string
+	var dir string
+	fs.StringVar(&addr, "addr", ":8080", "Address to listen on")
+	fs.StringVar(&dir, "dir", "", "Directory to store cache entries in (default: aid-metrics/remote-cache in the user cache directory)")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: aid-metrics cache-server [flags]\n\n")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+
+	if dir == "" {
+		userCache, err := os.UserCacheDir()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		dir = filepath.Join(userCache, "aid-metrics", "remote-cache")
+	}
+
+	fmt.Fprintf(os.Stderr, "Serving cache from %s on %s\n", dir, addr)
+	if err := http.ListenAndServe(addr, cache.Handler(cache.Dir{Path: dir})); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,25 @@
+package main
+
+// subcommands maps subcommand names to their entry points.
+// Each entry point receives the arguments following the subcommand name
+// and returns the process exit code.
+var subcommands = map[string]func(args []string) int{
+	"benchmark-against": runBenchmarkAgainst,
+	"cache-server":      runCacheServer,
+	"coordinator":       runCoordinator,
+	"diff":              runDiff,
+	"init":              runInit,
+	"lsp":               runLSP,
+	"migrate":           runMigrate,
+	"modules":           runModules,
+	"platforms":         runPlatforms,
+	"publish":           runPublish,
+	"rules":             runRules,
+	"scorecard":         runScorecard,
+	"serve":             runServe,
+	"snapshot":          runSnapshot,
+	"trend":             runTrend,
+	"verify":            runVerify,
+	"why":               runWhy,
+	"worker":            runWorker,
+}
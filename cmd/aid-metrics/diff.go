@@ -0,0 +1,111 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/alkbt/aid-metrics/pkg/diff"
+	"github.com/alkbt/aid-metrics/pkg/reporter"
+)
+
+// runDiff implements `aid-metrics diff old.json new.json` and
+// `aid-metrics diff -git origin/main [path]`.
+// It prints the per-package metric changes between two JSON reports, or between
+// a git revision of the module and its working tree, e.g. in pull request pipelines.
+func runDiff(args []string) int {
+	fs := flag.NewFlagSet("diff", flag.ExitOnError)
+	var format, base, pattern string
+	fs.StringVar(&format, "format", "text", "Output format (text, json)")
+	fs.StringVar(&base, "git", "", "Analyze this git revision (e.g. origin/main) and the working tree and compare them instead of two reports")
+	fs.StringVar(&pattern, "pattern", "./...", "Package pattern to analyze with -git")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: aid-metrics diff [flags] old.json new.json\n")
+		fmt.Fprintf(fs.Output(), "       aid-metrics diff -git revision [flags] [path]\n\n")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+
+	var before, after *reporter.JSONReport
+	var err error
+	if base != "" {
+		if fs.NArg() > 1 {
+			fs.Usage()
+			return 1
+		}
+		modulePath := "."
+		if fs.NArg() == 1 {
+			modulePath = fs.Arg(0)
+		}
+		before, after, err = analyzeAgainstRevision(modulePath, base, pattern)
+	} else {
+		if fs.NArg() != 2 {
+			fs.Usage()
+			return 1
+		}
+		before, err = readReport(fs.Arg(0))
+		if err == nil {
+			after, err = readReport(fs.Arg(1))
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	result := diff.Compare(before, after)
+	switch format {
+	case "text":
+		err = result.WriteText(os.Stdout)
+	case "json":
+		err = result.WriteJSON(os.Stdout)
+	default:
+		err = fmt.Errorf("unknown format %q", format)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// analyzeAgainstRevision analyzes the module at a git revision, checked out into a
+// temporary worktree, and in the working tree. Each uses its own configuration file.
+func analyzeAgainstRevision(modulePath, rev, pattern string) (before, after *reporter.JSONReport, err error) {
+	absPath, err := filepath.Abs(modulePath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get absolute path: %w", err)
+	}
+
+	analyze := func(path string) (*reporter.JSONReport, error) {
+		a, err := newModuleAnalyzer(path, pattern, "", nil)
+		if err != nil {
+			return nil, err
+		}
+		metrics, err := a.Analyze()
+		if err != nil {
+			return nil, fmt.Errorf("failed to analyze module: %w", err)
+		}
+		return reporter.NewJSONReport(metrics), nil
+	}
+
+	fmt.Fprintf(os.Stderr, "Analyzing %s at %s...\n", absPath, rev)
+	treePath, removeWorktree, err := checkoutRevision(absPath, rev)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to check out %s: %w", rev, err)
+	}
+	before, err = analyze(treePath)
+	removeWorktree()
+	if err != nil {
+		return nil, nil, err
+	}
+	before.Module = absPath + "@" + rev
+
+	fmt.Fprintf(os.Stderr, "Analyzing the working tree...\n")
+	if after, err = analyze(absPath); err != nil {
+		return nil, nil, err
+	}
+	after.Module = absPath
+	return before, after, nil
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+
+	"github.com/alkbt/aid-metrics/pkg/analyzer"
+	"github.com/alkbt/aid-metrics/pkg/distributed"
+	"github.com/alkbt/aid-metrics/pkg/models"
+	"github.com/alkbt/aid-metrics/pkg/reporter"
+)
+
+// runCoordinator implements `aid-metrics coordinator -addr :7070 [path]`.
+// It hands out shards of the module's packages to workers, merges their results
+// and writes the report of the whole module. Workers must present the token read
+// from -token-file or $AID_METRICS_TOKEN, or a token an OIDC provider reports as
+// active, and connect over TLS (-tls-cert and -tls-key); both are required
+// unless the coordinator listens on a loopback address.
+func runCoordinator(args []string) int {
+	fs := flag.NewFlagSet("coordinator", flag.ExitOnError)
+	var addr string
+	var shardSize int
+	var format string
+	var pattern string
+	var configPath string
+	var findings bool
+	var tokenFile string
+	var introspectionURL string
+	var clientID string
+	var tlsCert string
+	var tlsKey string
+	fs.StringVar(&addr, "addr", ":7070", "Address to listen for workers on")
+	fs.IntVar(&shardSize, "shard-size", 50, "Number of packages handed out to a worker at once")
+	fs.StringVar(&format, "format", "text", "Output format (text, csv, json, ai-context, html)")
+	fs.StringVar(&pattern, "pattern", "./...", "Package pattern to analyze")
+	fs.StringVar(&configPath, "config", "", "Path to the configuration file")
+	fs.BoolVar(&findings, "findings", false, "Report findings")
+	fs.StringVar(&tokenFile, "token-file", "", "File containing the token workers must present (default: $AID_METRICS_TOKEN)")
+	fs.StringVar(&introspectionURL, "oidc-introspection-url", "", "OIDC token introspection endpoint used to validate worker tokens")
+	fs.StringVar(&clientID, "oidc-client-id", "", "Client ID for the introspection endpoint (secret: $AID_METRICS_OIDC_CLIENT_SECRET)")
+	fs.StringVar(&tlsCert, "tls-cert", "", "TLS certificate file; serves workers over TLS together with -tls-key")
+	fs.StringVar(&tlsKey, "tls-key", "", "TLS private key file")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: aid-metrics coordinator [flags] [path]\n\n")
+		fmt.Fprintf(fs.Output(), "Start workers with: aid-metrics worker -coordinator HOST:PORT [path]\n\n")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+
+	if (tlsCert == "") != (tlsKey == "") {
+		fmt.Fprintf(os.Stderr, "Error: -tls-cert and -tls-key must be used together\n")
+		return 1
+	}
+	auth, err := serveAuthenticator(tokenFile, introspectionURL, clientID)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	// Anyone reaching the port could otherwise lease shards and submit forged
+	// results, or read the tokens of workers off the network
+	if !isLoopback(addr) {
+		if auth == nil {
+			fmt.Fprintf(os.Stderr, "Error: a worker token (-token-file, $AID_METRICS_TOKEN or -oidc-introspection-url) is required unless -addr is a loopback address\n")
+			return 1
+		}
+		if tlsCert == "" {
+			fmt.Fprintf(os.Stderr, "Error: -tls-cert and -tls-key are required unless -addr is a loopback address\n")
+			return 1
+		}
+	}
+	var tlsConfig *tls.Config
+	if tlsCert != "" {
+		cert, err := tls.LoadX509KeyPair(tlsCert, tlsKey)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		tlsConfig = &tls.Config{MinVersion: tls.VersionTLS12, Certificates: []tls.Certificate{cert}}
+	}
+
+	a, err := newModuleAnalyzer(fs.Arg(0), pattern, configPath, nil)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	pkgs, err := a.DiscoverPackages()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	coordinator := distributed.NewCoordinator(pkgs, shardSize)
+	coordinator.Auth = auth
+	coordinator.TLS = tlsConfig
+	fmt.Fprintf(os.Stderr, "Waiting for workers on %s to analyze %d packages...\n", l.Addr(), len(pkgs))
+
+	results, err := coordinator.Serve(l)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if err := a.MergeResults(results); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	r := reporter.NewReporterWithOptions(a.Metrics(), reporter.FormatType(format), reporter.ReportOptions{Findings: findings})
+	if err := r.Generate(os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: Failed to generate report: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// runWorker implements `aid-metrics worker -coordinator HOST:PORT [path]`.
+// The worker analyzes the shards it gets from the coordinator in its own checkout
+// of the module until all work is done. It connects over TLS with -tls or
+// -tls-ca, which is required unless the coordinator is on a loopback address.
+func runWorker(args []string) int {
+	fs := flag.NewFlagSet("worker", flag.ExitOnError)
+	var addr string
+	var configPath string
+	var tokenFile string
+	var useTLS bool
+	var caFile string
+	fs.StringVar(&addr, "coordinator", "localhost:7070", "Address of the coordinator")
+	fs.StringVar(&configPath, "config", "", "Path to the configuration file")
+	fs.StringVar(&tokenFile, "token-file", "", "File containing the token of the coordinator (default: $AID_METRICS_TOKEN)")
+	fs.BoolVar(&useTLS, "tls", false, "Connect to the coordinator over TLS")
+	fs.StringVar(&caFile, "tls-ca", "", "CA certificate file to verify the coordinator with instead of the system roots (implies -tls)")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: aid-metrics worker [flags] [path]\n\n")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+
+	token, err := readToken(tokenFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	options := distributed.WorkerOptions{Token: token}
+	if useTLS || caFile != "" {
+		options.TLS = &tls.Config{MinVersion: tls.VersionTLS12}
+		if caFile != "" {
+			pem, err := os.ReadFile(caFile)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				return 1
+			}
+			options.TLS.RootCAs = x509.NewCertPool()
+			if !options.TLS.RootCAs.AppendCertsFromPEM(pem) {
+				fmt.Fprintf(os.Stderr, "Error: no certificates found in %s\n", caFile)
+				return 1
+			}
+		}
+	} else if !isLoopback(addr) {
+		fmt.Fprintf(os.Stderr, "Error: -tls or -tls-ca is required unless the coordinator is on a loopback address\n")
+		return 1
+	}
+
+	a, err := newModuleAnalyzer(fs.Arg(0), "", configPath, nil)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	name, _ := os.Hostname()
+	name = fmt.Sprintf("%s/%d", name, os.Getpid())
+	if err := distributed.RunWorker(addr, name, a, options); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// newModuleAnalyzer creates an analyzer for the module at modulePath configured from its config file
+func newModuleAnalyzer(modulePath, pattern, configPath string, progress models.ProgressReporter) (*analyzer.ModuleAnalyzer, error) {
+	if modulePath == "" {
+		modulePath = "."
+	}
+	absPath, err := filepath.Abs(modulePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get absolute path: %w", err)
+	}
+
+	cfg, err := loadConfig(configPath, absPath)
+	if err != nil {
+		return nil, err
+	}
+	opts, err := optionsFromConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	opts.ProgressReporter = progress
+	return analyzer.NewModuleAnalyzerWithOptions(absPath, pattern, opts), nil
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/alkbt/aid-metrics/pkg/analyzer"
+	"github.com/alkbt/aid-metrics/pkg/config"
+	"github.com/alkbt/aid-metrics/pkg/models"
+)
+
+// runInit implements `aid-metrics init [path]`.
+// It analyzes the module, proposes to exclude the generated packages and to
+// enforce thresholds at a percentile of today's metrics, asks about each
+// proposal and writes the accepted ones as a starter configuration file.
+func runInit(args []string) int {
+	fs := flag.NewFlagSet("init", flag.ExitOnError)
+	var output string
+	var force, yes bool
+	var percentile float64
+	fs.StringVar(&output, "o", "", "Configuration file to write (default: "+config.DefaultFileName+" in the module root)")
+	fs.BoolVar(&force, "force", false, "Overwrite an existing configuration file")
+	fs.BoolVar(&yes, "yes", false, "Accept all proposals without asking")
+	fs.Float64Var(&percentile, "percentile", 90, "Percentile of the current packages the proposed thresholds are set at")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: aid-metrics init [flags] [path]\n\n")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+
+	if percentile <= 0 || percentile > 100 {
+		fmt.Fprintf(os.Stderr, "Error: -percentile must be between 0 and 100, got %g\n", percentile)
+		return 1
+	}
+	modulePath := "."
+	if fs.NArg() > 0 {
+		modulePath = fs.Arg(0)
+	}
+	absPath, err := filepath.Abs(modulePath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: Failed to get absolute path: %v\n", err)
+		return 1
+	}
+	if output == "" {
+		output = filepath.Join(absPath, config.DefaultFileName)
+	}
+	if _, err := os.Stat(output); !force && !errors.Is(err, os.ErrNotExist) {
+		fmt.Fprintf(os.Stderr, "Error: %s already exists (use -force to overwrite it)\n", output)
+		return 1
+	}
+
+	fmt.Fprintf(os.Stderr, "Analyzing Go module at: %s\n", absPath)
+	metrics, err := analyzer.AnalyzeModule(absPath, "./...")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: Failed to analyze module: %v\n", err)
+		return 1
+	}
+
+	answers := bufio.NewReader(os.Stdin)
+	ask := func(question string) bool {
+		if yes {
+			fmt.Fprintf(os.Stderr, "%s [Y/n] y\n", question)
+			return true
+		}
+		return confirm(answers, os.Stderr, question)
+	}
+
+	var starter initConfig
+	for _, pkg := range generatedPackages(metrics) {
+		if ask(fmt.Sprintf("Exclude %s (generated by %s)?", pkg.Name, pkg.Generator)) {
+			starter.exclude = append(starter.exclude, pkg.Name)
+		}
+	}
+
+	excluded := make(map[string]bool, len(starter.exclude))
+	for _, name := range starter.exclude {
+		excluded[name] = true
+	}
+	var measured []models.PackageMetrics
+	for _, pkg := range metrics.Packages {
+		if !excluded[pkg.Name] && pkg.Ca+pkg.Ce > 0 && !pkg.GateExempt {
+			measured = append(measured, pkg)
+		}
+	}
+	if len(measured) > 0 {
+		proposals := proposeThresholds(measured, percentile)
+		for _, p := range proposals {
+			if ask(fmt.Sprintf("Enforce %s: %.2f (p%g of the packages; %d would fail today)?", p.name, p.value, p.percentile, p.failing)) {
+				starter.thresholds = append(starter.thresholds, p)
+			}
+		}
+	}
+
+	if err := os.WriteFile(output, []byte(starter.yaml()), 0o644); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: Failed to write configuration: %v\n", err)
+		return 1
+	}
+	fmt.Fprintf(os.Stderr, "Wrote %s\n", output)
+	return 0
+}
+
+// initConfig holds the accepted proposals of `aid-metrics init`
+type initConfig struct {
+	exclude    []string
+	thresholds []thresholdProposal
+}
+
+// yaml renders the starter configuration file with comments explaining each section
+func (c initConfig) yaml() string {
+	var b strings.Builder
+	fmt.Fprintln(&b, "# aid-metrics configuration, generated by aid-metrics init.")
+	fmt.Fprintln(&b, "# See the README for all settings.")
+	fmt.Fprintf(&b, "version: %d\n", config.CurrentVersion)
+	if len(c.exclude) > 0 {
+		fmt.Fprintln(&b)
+		fmt.Fprintln(&b, "# Generated packages left out of the analysis; imports of them still count as coupling")
+		fmt.Fprintln(&b, "exclude:")
+		for _, pattern := range c.exclude {
+			fmt.Fprintf(&b, "  - %s\n", pattern)
+		}
+	}
+	if len(c.thresholds) > 0 {
+		fmt.Fprintln(&b)
+		fmt.Fprintln(&b, "# Thresholds at a percentile of the packages when the file was generated;")
+		fmt.Fprintln(&b, "# tighten them as the design improves")
+		fmt.Fprintln(&b, "thresholds:")
+		for _, p := range c.thresholds {
+			fmt.Fprintf(&b, "  %s: %.2f\n", p.name, p.value)
+		}
+	}
+	return b.String()
+}
+
+// generatedPackages returns the packages consisting solely of generated files, by name
+func generatedPackages(metrics *models.ModuleMetrics) []models.PackageMetrics {
+	var generated []models.PackageMetrics
+	for _, pkg := range metrics.Packages {
+		if pkg.Generator != "" {
+			generated = append(generated, pkg)
+		}
+	}
+	sort.Slice(generated, func(i, j int) bool { return generated[i].Name < generated[j].Name })
+	return generated
+}
+
+// thresholdProposal is a proposed threshold in the configuration file
+type thresholdProposal struct {
+	name       string  // Key in the thresholds section
+	value      float64 // Proposed value
+	percentile float64 // Percentile of the packages the value was taken at
+	failing    int     // Packages violating the threshold today
+}
+
+// proposeThresholds proposes the maximum distance and instability at the given
+// percentile of the packages, and the minimum abstractness at the mirrored low
+// percentile. Values are rounded outward to 0.05 so they read as round numbers.
+func proposeThresholds(pkgs []models.PackageMetrics, p float64) []thresholdProposal {
+	values := func(metric func(models.PackageMetrics) float64) []float64 {
+		result := make([]float64, len(pkgs))
+		for i, pkg := range pkgs {
+			result[i] = metric(pkg)
+		}
+		sort.Float64s(result)
+		return result
+	}
+	distance := values(func(pkg models.PackageMetrics) float64 { return pkg.Distance })
+	instability := values(func(pkg models.PackageMetrics) float64 { return pkg.Instability })
+	abstractness := values(func(pkg models.PackageMetrics) float64 { return pkg.Abstractness })
+
+	ceil := func(v float64) float64 { return math.Min(1, math.Ceil(v*20-1e-9)/20) }
+	floor := func(v float64) float64 { return math.Max(0, math.Floor(v*20+1e-9)/20) }
+	above := func(sorted []float64, limit float64) int {
+		return len(sorted) - sort.Search(len(sorted), func(i int) bool { return sorted[i] > limit })
+	}
+	below := func(sorted []float64, limit float64) int {
+		return sort.Search(len(sorted), func(i int) bool { return sorted[i] >= limit })
+	}
+
+	maxDistance := ceil(nearestRank(distance, p))
+	maxInstability := ceil(nearestRank(instability, p))
+	minAbstractness := floor(nearestRank(abstractness, 100-p))
+	return []thresholdProposal{
+		{"max_distance", maxDistance, p, above(distance, maxDistance)},
+		{"max_instability", maxInstability, p, above(instability, maxInstability)},
+		{"min_abstractness", minAbstractness, 100 - p, below(abstractness, minAbstractness)},
+	}
+}
+
+// nearestRank returns the p-th percentile of sorted values by the nearest-rank method
+func nearestRank(sorted []float64, p float64) float64 {
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	return sorted[min(max(rank, 1), len(sorted))-1]
+}
+
+// confirm asks a yes/no question on out and reads the answer from in. An empty
+// answer or the end of the input accepts the default, yes.
+func confirm(in *bufio.Reader, out io.Writer, question string) bool {
+	fmt.Fprintf(out, "%s [Y/n] ", question)
+	answer, err := in.ReadString('\n')
+	if err != nil && answer == "" {
+		fmt.Fprintln(out)
+		return true
+	}
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "", "y", "yes":
+		return true
+	}
+	return false
+}
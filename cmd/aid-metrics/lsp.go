@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/alkbt/aid-metrics/pkg/analyzer"
+	"github.com/alkbt/aid-metrics/pkg/cache"
+	"github.com/alkbt/aid-metrics/pkg/lsp"
+	"github.com/alkbt/aid-metrics/pkg/models"
+)
+
+// runLSP implements `aid-metrics lsp [path]`.
+// It runs a language server on stdin and stdout that re-analyzes the module
+// whenever a file is saved and shows the metrics of the edited package, with
+// their change since the previous save, on hover and as diagnostics. Saves are
+// analyzed incrementally, reusing the results of the packages they cannot affect.
+func runLSP(args []string) int {
+	fs := flag.NewFlagSet("lsp", flag.ExitOnError)
+	var pattern string
+	var configPath string
+	fs.StringVar(&pattern, "pattern", "./...", "Package pattern to analyze")
+	fs.StringVar(&configPath, "config", "", "Path to the configuration file")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: aid-metrics lsp [flags] [path]\n\n")
+		fmt.Fprintf(fs.Output(), "Speaks the Language Server Protocol on stdin and stdout; configure it as a language server for Go files in the editor.\n\n")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+
+	modulePath := "."
+	if fs.NArg() > 0 {
+		modulePath = fs.Arg(0)
+	}
+	absPath, err := filepath.Abs(modulePath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: Failed to get absolute path: %v\n", err)
+		return 1
+	}
+	cfg, err := loadConfig(configPath, absPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	opts, err := optionsFromConfig(cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	opts.Cache = &cache.Memory{}
+
+	a := analyzer.NewModuleAnalyzerWithOptions(absPath, pattern, opts)
+	var latest *models.ModuleMetrics
+	server := lsp.New(absPath, analyzer.ReadModuleName(absPath), func(ctx context.Context, changedFiles []string) (*models.ModuleMetrics, error) {
+		var metrics *models.ModuleMetrics
+		var err error
+		if changedFiles == nil {
+			metrics, err = a.AnalyzeContext(ctx)
+		} else {
+			metrics, err = a.AnalyzeIncrementalContext(ctx, latest, changedFiles)
+		}
+		if err == nil {
+			latest = metrics
+		}
+		return metrics, err
+	})
+	if err := server.Serve(context.Background(), os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
@@ -1,27 +1,137 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
 
 	"github.com/alkbt/aid-metrics/pkg/analyzer"
+	"github.com/alkbt/aid-metrics/pkg/anonymize"
+	"github.com/alkbt/aid-metrics/pkg/cache"
+	"github.com/alkbt/aid-metrics/pkg/config"
+	"github.com/alkbt/aid-metrics/pkg/coverage"
 	"github.com/alkbt/aid-metrics/pkg/models"
+	"github.com/alkbt/aid-metrics/pkg/modgraph"
 	"github.com/alkbt/aid-metrics/pkg/reporter"
+	"github.com/alkbt/aid-metrics/pkg/workspace"
 )
 
 func main() {
+	// Dispatch subcommands before parsing the flags of the default analysis
+	if len(os.Args) > 1 {
+		if run, ok := subcommands[os.Args[1]]; ok {
+			os.Exit(run(os.Args[2:]))
+		}
+	}
+
 	// Parse command-line flags
 	var format string
 	var pattern string
-	var progress bool
+	var progress progressMode
 	var batchSize int
+	var workers int
+	var configPath string
+	var byRole bool
+	var profiles string
+	var endpoints bool
+	var sideEffects bool
+	var errorCoupling bool
+	var stringCoupling bool
+	var withEdges bool
+	var findings bool
+	var failOn string
+	var importsOnly bool
+	var concurrency bool
+	var remoteCache string
+	var cacheDir string
+	var noCache bool
+	var offline bool
+	var layoutSeed int
+	var canonical bool
+	var strict bool
+	var thresholds models.Thresholds
+	var baselinePath string
+	var output string
+	var platformList string
+	var rev string
+	var failOnUnusedDeps bool
+	var sortOrder string
+	var top int
+	var columns string
+	var precision int
+	var closure string
+	var includeTests bool
+	var buildTags string
+	var anonymizeNames bool
+	var ascii bool
+	var workspaceMode string
+	var failFast bool
+	var recursiveModules bool
+	var anonymizeSalt string
+	var debtBudget int
+	var debtRatchet bool
+	var coverProfile string
+	var exportedOnly bool
+	var skipGenerated, skipGeneratedImports bool
 
-	flag.StringVar(&format, "format", "text", "Output format (text, csv, json)")
+	flag.StringVar(&format, "format", "text", "Output format ("+strings.Join(reporter.Formats(), ", ")+"); several comma-separated formats are written to files named after -o (default: metrics) from one analysis")
+	flag.StringVar(&sortOrder, "sort", reporter.SortName, "Package order of text and CSV reports: name, topo for dependency layers (dependencies first), or worst first by distance, instability, ca or ce")
+	flag.IntVar(&top, "top", 0, "Report only the first N packages in -sort order in text and CSV reports (0 for all)")
+	flag.StringVar(&columns, "columns", "", "Comma-separated columns of text and CSV reports after the package name, in order: "+strings.Join(reporter.PackageColumns(), ", ")+" (default: all; a_all only with -exported-only, layer only with -sort topo)")
+	flag.IntVar(&precision, "precision", reporter.DefaultPrecision, "Decimals of I, A and D in text and CSV reports, and of all ratios with -canonical")
+	flag.StringVar(&output, "o", "", "Write the report to this file instead of stdout; with several formats, the file name with each format's extension")
 	flag.StringVar(&pattern, "pattern", "./...", "Package pattern to analyze (e.g., './...' or 'github.com/org/repo/pkg/...')")
-	flag.BoolVar(&progress, "progress", false, "Show progress bar during analysis")
+	flag.Var(&progress, "progress", "Show progress during analysis: a progress bar, or JSON lines on stderr with -progress=json")
+	flag.BoolVar(&ascii, "ascii", false, "Pure ASCII output: plain progress bar without colors, non-ASCII characters of the text report escaped")
 	flag.IntVar(&batchSize, "batch-size", 100, "Number of packages to load in each batch")
+	flag.IntVar(&workers, "workers", 0, "Number of packages to parse in parallel (0 for GOMAXPROCS)")
+	flag.StringVar(&configPath, "config", "", "Path to the configuration file (default: "+config.DefaultFileName+" in the module root)")
+	flag.BoolVar(&byRole, "by-role", false, "Add a summary of metrics aggregated per package role")
+	flag.BoolVar(&endpoints, "endpoints", false, "Report HTTP/gRPC endpoints and the packages their handlers depend on")
+	flag.BoolVar(&errorCoupling, "error-coupling", false, "Report exported errors and the packages checking for them with errors.Is/As, == or type assertions")
+	flag.BoolVar(&stringCoupling, "string-coupling", false, "Report string literals (env var names, flag keys, URL paths) shared by packages without an import relationship")
+	flag.BoolVar(&sideEffects, "side-effects", false, "Report blank imports and init-time registrations with the binaries linking them in")
+	flag.BoolVar(&withEdges, "with-edges", false, "List the dependents and dependencies of each package, not just Ca and Ce, in JSON and HTML reports")
+	flag.BoolVar(&findings, "findings", false, "Report findings: import cycles, SDP/SAP violations, data-bag packages and leaked types")
+	flag.StringVar(&failOn, "fail-on", "", "Exit with code 2 if any finding has at least this severity (info, warning, error)")
+	flag.StringVar(&buildTags, "tags", "", "Comma-separated build tags to satisfy, as with go build -tags; files whose constraints are not met are not analyzed")
+	flag.BoolVar(&includeTests, "include-tests", false, "Analyze _test.go files too and report external test packages (package foo_test) as packages of their own")
+	flag.IntVar(&debtBudget, "debt-budget", -1, "Exit with code 2 if the findings add up to more debt points than this (default: debt_budget of the config file, if any)")
+	flag.BoolVar(&debtRatchet, "debt-ratchet", false, "Lower the debt budget to the debt points of the -baseline report (written with -findings), so the debt can only go down")
+	flag.BoolVar(&skipGenerated, "skip-generated", false, "Leave declarations in generated files (\"// Code generated ... DO NOT EDIT.\") out of Na and Nc")
+	flag.BoolVar(&skipGeneratedImports, "skip-generated-imports", false, "Also leave the imports of generated files out of the dependencies (implies -skip-generated)")
+	flag.BoolVar(&exportedOnly, "exported-only", false, "Count only exported declarations for Na, Nc and A (the published abstractness); A of all declarations is reported next to it")
+	flag.StringVar(&coverProfile, "coverprofile", "", "Cover profile written by go test -coverprofile; package coverage becomes part of the health score")
+	flag.BoolVar(&failFast, "fail-fast", false, "Stop the analysis and exit with code 2 at the first threshold violation or -fail-on finding that further packages cannot undo (import cycles, leaks, data bags, A and D in the zone of pain)")
+	flag.BoolVar(&concurrency, "concurrency", false, "Count goroutine launches, channels and sync primitives per package")
+	flag.BoolVar(&importsOnly, "imports-only", false, "Fast mode: parse only imports and report coupling metrics (Ca, Ce, I), e.g. for pre-commit hooks")
+	flag.StringVar(&remoteCache, "remote-cache", "", "URL of a remote cache sharing per-package results between runs (see the cache-server subcommand)")
+	flag.StringVar(&cacheDir, "cache-dir", "", "Directory of the local cache of per-package results, keyed by the hashes of their files (default: aid-metrics/results in the user cache directory)")
+	flag.BoolVar(&offline, "offline", false, "Guarantee no network use: load modules only from the module cache or vendor/ (GOPROXY=off), fail if a report template loads resources from the network, and reject -remote-cache")
+	flag.IntVar(&layoutSeed, "layout-seed", 0, "Seed of the force-directed Graphviz layouts of -format=dot reports (the start attribute), for diagrams that do not move between runs")
+	flag.BoolVar(&canonical, "canonical", false, "Write the JSON report in canonical form: keys sorted and ratios with -precision decimals, so committed snapshots diff minimally")
+	flag.BoolVar(&strict, "strict", false, "Exit with code 1 instead of reporting when any package has load, parse or type errors")
+	flag.BoolVar(&noCache, "no-cache", false, "Analyze every package again instead of reusing the results of unchanged packages from the local cache")
+	flag.Float64Var(&thresholds.MaxDistance, "max-distance", 1, "Exit with code 2 if any package is further than this from the main sequence")
+	flag.Float64Var(&thresholds.MaxInstability, "max-instability", 1, "Exit with code 2 if any package has a higher instability")
+	flag.Float64Var(&thresholds.MinAbstractness, "min-abstractness", 0, "Exit with code 2 if any package has a lower abstractness")
+	flag.StringVar(&baselinePath, "baseline", "", "Previous JSON report; the html format shows how packages moved since then")
+	flag.StringVar(&profiles, "profile", "", "Comma-separated built-in profiles to enable (protobuf, mocks)")
+	flag.BoolVar(&failOnUnusedDeps, "fail-on-unused-deps", false, "Exit with code 2 if a go.mod requirement has no package imported by the analyzed packages or tests")
+	flag.StringVar(&rev, "rev", "", "Analyze the module as of this git commit, tag or branch, checked out into a temporary worktree")
+	flag.StringVar(&closure, "closure", "", "Report only the packages matching these comma-separated module-relative patterns (e.g. pkg/payment/...), their dependencies and their dependents; the rest of the module is collapsed into one package")
+	flag.BoolVar(&anonymizeNames, "anonymize", false, "Replace package, type and route names by consistent hashes, keeping the structure and the metrics, for sharing reports outside the organization")
+	flag.StringVar(&anonymizeSalt, "anonymize-salt", "", "Secret mixed into the -anonymize hashes, so common names cannot be recovered by hashing guesses")
+	flag.StringVar(&workspaceMode, "workspace", "", "Analyze every module of the go.work file: merged (imports between modules are edges) or modules (modules side by side); default merged if there is a go.work but no go.mod")
+	flag.BoolVar(&recursiveModules, "recursive-modules", false, "Analyze every module with a go.mod file in the tree and merge the results, keyed by module:package")
+	flag.StringVar(&platformList, "platforms", "", "Analyze each comma-separated GOOS/GOARCH (e.g. linux/amd64,windows/amd64) and report the min/max of every metric (text, json)")
 	flag.Parse()
 
 	// Get module path
@@ -38,36 +148,713 @@ func main() {
 		os.Exit(1)
 	}
 
+	if !slices.Contains(reporter.SortOrders, sortOrder) {
+		fmt.Fprintf(os.Stderr, "Error: unknown -sort %q (%s)\n", sortOrder, strings.Join(reporter.SortOrders, ", "))
+		os.Exit(1)
+	}
+	formats := strings.Split(format, ",")
+	for i, f := range formats {
+		if !slices.Contains(reporter.Formats(), f) {
+			fmt.Fprintf(os.Stderr, "Error: unknown format %q (%s)\n", f, strings.Join(reporter.Formats(), ", "))
+			os.Exit(1)
+		}
+		// Both reports would be written to the same file
+		if slices.Contains(formats[:i], f) {
+			fmt.Fprintf(os.Stderr, "Error: format %q is given more than once in -format\n", f)
+			os.Exit(1)
+		}
+	}
+	if top < 0 {
+		fmt.Fprintf(os.Stderr, "Error: -top must not be negative, got %d\n", top)
+		os.Exit(1)
+	}
+	if workers < 0 {
+		fmt.Fprintf(os.Stderr, "Error: -workers must not be negative, got %d\n", workers)
+		os.Exit(1)
+	}
+	if precision < 1 || precision > 6 {
+		fmt.Fprintf(os.Stderr, "Error: -precision must be between 1 and 6, got %d\n", precision)
+		os.Exit(1)
+	}
+	var columnList []string
+	if columns != "" {
+		columnList = strings.Split(columns, ",")
+		for _, column := range columnList {
+			if !slices.Contains(reporter.PackageColumns(), strings.ToLower(strings.TrimSpace(column))) {
+				fmt.Fprintf(os.Stderr, "Error: unknown column %q in -columns (%s)\n", column, strings.Join(reporter.PackageColumns(), ", "))
+				os.Exit(1)
+			}
+		}
+	}
+
+	if offline && remoteCache != "" {
+		fmt.Fprintln(os.Stderr, "Error: -remote-cache cannot be combined with -offline")
+		os.Exit(1)
+	}
+
+	if debtRatchet && baselinePath == "" {
+		fmt.Fprintln(os.Stderr, "Error: -debt-ratchet requires -baseline")
+		os.Exit(1)
+	}
+
+	if (closure != "" || anonymizeNames) && platformList != "" {
+		fmt.Fprintln(os.Stderr, "Error: -closure and -anonymize cannot be combined with -platforms")
+		os.Exit(1)
+	}
+	if recursiveModules && workspaceMode != "" {
+		fmt.Fprintln(os.Stderr, "Error: -recursive-modules cannot be combined with -workspace")
+		os.Exit(1)
+	}
+	if (workspaceMode != "" || recursiveModules) && (failOnUnusedDeps || platformList != "") {
+		fmt.Fprintln(os.Stderr, "Error: -workspace and -recursive-modules cannot be combined with -fail-on-unused-deps or -platforms")
+		os.Exit(1)
+	}
+
+	// Load the baseline before the analysis so a bad path fails fast
+	var baseline *reporter.JSONReport
+	if baselinePath != "" {
+		baseline, err = readReport(baselinePath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	// Check the revision out; the worktree is removed as soon as the analysis is done.
+	// Reports name the module by its path and the revision.
+	analysisPath := absPath
+	moduleLabel := absPath
+	removeWorktree := func() {}
+	if rev != "" {
+		moduleLabel = absPath + "@" + rev
+		analysisPath, removeWorktree, err = checkoutRevision(absPath, rev)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: Failed to check out %s: %v\n", rev, err)
+			os.Exit(1)
+		}
+	}
+
+	// Load configuration
+	cfg, err := loadConfig(configPath, analysisPath)
+	if err != nil {
+		removeWorktree()
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
 	// Analyze module
-	if !progress {
-		fmt.Fprintf(os.Stderr, "Analyzing Go module at: %s\n", absPath)
+	if progress == "" {
+		fmt.Fprintf(os.Stderr, "Analyzing Go module at: %s\n", moduleLabel)
 	}
-	
+
 	// Create analyzer options with progress reporter if requested
-	var metrics *models.ModuleMetrics
-	if progress {
-		opts := analyzer.AnalyzerOptions{
-			ProgressReporter: reporter.NewConsoleProgressReporter(),
-			BatchSize:        batchSize,
+	opts, err := optionsFromConfig(cfg)
+	if err != nil {
+		removeWorktree()
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	opts.BatchSize = batchSize
+	opts.Workers = workers
+	opts.ImportsOnly = importsOnly
+	opts.Concurrency = concurrency
+	opts.StringCoupling = stringCoupling
+	opts.IncludeTests = includeTests
+	opts.ExportedOnly = exportedOnly
+	opts.SkipGenerated = skipGenerated || skipGeneratedImports
+	opts.SkipGeneratedImports = skipGeneratedImports
+	if failFast {
+		opts.FailFast = true
+		if failOn != "" {
+			if opts.FailFastSeverity, err = models.ParseSeverity(failOn); err != nil {
+				removeWorktree()
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
 		}
-		metrics, err = analyzer.AnalyzeModuleWithOptions(absPath, pattern, opts)
-	} else {
-		metrics, err = analyzer.AnalyzeModule(absPath, pattern)
 	}
-	
+	switch {
+	case progress == progressJSON:
+		opts.ProgressReporter = reporter.NewJSONProgressReporter(os.Stderr)
+	case progress != "" && ascii:
+		opts.ProgressReporter = reporter.NewASCIIConsoleProgressReporter()
+	case progress != "":
+		opts.ProgressReporter = reporter.NewConsoleProgressReporter()
+	}
+	opts.Cache = resultCache(cacheDir, remoteCache, noCache)
+	opts.Offline = offline
+	// Threshold flags override the thresholds of the configuration one by one
+	if configured := opts.Thresholds; configured != nil {
+		set := make(map[string]bool)
+		flag.Visit(func(f *flag.Flag) { set[f.Name] = true })
+		if !set["max-distance"] {
+			thresholds.MaxDistance = configured.MaxDistance
+		}
+		if !set["max-instability"] {
+			thresholds.MaxInstability = configured.MaxInstability
+		}
+		if !set["min-abstractness"] {
+			thresholds.MinAbstractness = configured.MinAbstractness
+		}
+		opts.Thresholds = &thresholds
+	}
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "max-distance", "max-instability", "min-abstractness":
+			opts.Thresholds = &thresholds
+		}
+	})
+	if coverProfile != "" {
+		if opts.Coverage, err = coverage.ReadFile(coverProfile); err != nil {
+			removeWorktree()
+			fmt.Fprintf(os.Stderr, "Error: Failed to read cover profile: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if debtBudget < 0 && cfg.DebtBudget != nil {
+		debtBudget = *cfg.DebtBudget
+	}
+	if debtRatchet && (debtBudget < 0 || baseline.DebtPoints < debtBudget) {
+		debtBudget = baseline.DebtPoints
+	}
+	if profiles != "" {
+		opts.Profiles = append(opts.Profiles, strings.Split(profiles, ",")...)
+	}
+	if buildTags != "" {
+		opts.BuildTags = strings.Split(buildTags, ",")
+	}
+
+	if platformList != "" {
+		results, err := writeMergedReport(analysisPath, moduleLabel, pattern, platformList, format, output, opts)
+		removeWorktree()
+		if opts.ProgressReporter != nil {
+			opts.ProgressReporter.Complete()
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		code := 0
+		for _, result := range results {
+			if c := enforceGates(result.metrics, opts, failOn, debtBudget, result.platform+": "); c > code {
+				code = c
+			}
+		}
+		os.Exit(code)
+	}
+
+	if workspaceMode == "" && !recursiveModules && workspace.Exists(analysisPath) {
+		if _, err := os.Stat(filepath.Join(analysisPath, "go.mod")); errors.Is(err, fs.ErrNotExist) {
+			workspaceMode = workspace.Merged
+		}
+	}
+
+	var metrics *models.ModuleMetrics
+	switch {
+	case workspaceMode != "":
+		metrics, err = analyzeWorkspace(workspace.Load, analysisPath, pattern, workspaceMode, opts)
+	case recursiveModules:
+		metrics, err = analyzeWorkspace(workspace.Discover, analysisPath, pattern, workspace.Merged, opts)
+	default:
+		metrics, err = analyzer.AnalyzeModuleWithOptions(analysisPath, pattern, opts)
+	}
+	var unusedDeps []string
+	if err == nil && failOnUnusedDeps {
+		unusedDeps, err = unusedRequirements(analysisPath, metrics)
+	}
+	removeWorktree()
+	// The progress reporter stays in use for the reporting stage unless the analysis failed
+	if err != nil && opts.ProgressReporter != nil {
+		opts.ProgressReporter.Complete()
+	}
+	if gateErr := (*analyzer.GateError)(nil); errors.As(err, &gateErr) {
+		finding := gateErr.Finding
+		fmt.Fprintf(os.Stderr, "Quality gate failed, analysis stopped early: [%s %s] %s: %s\n", finding.ID, finding.Severity, finding.Package, finding.Message)
+		os.Exit(2)
+	}
+	if noPackages := (*analyzer.NoPackagesError)(nil); errors.As(err, &noPackages) {
+		printNoPackagesHint(noPackages)
+		os.Exit(1)
+	}
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: Failed to analyze module: %v\n", err)
 		os.Exit(1)
 	}
+	if len(metrics.Diagnostics) > 0 {
+		printDiagnostics(metrics.Diagnostics, strict)
+		if strict {
+			if opts.ProgressReporter != nil {
+				opts.ProgressReporter.Complete()
+			}
+			os.Exit(1)
+		}
+	}
+	metrics.Path = moduleLabel
+	if closure != "" {
+		metrics, err = analyzer.Closure(metrics, closure)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if anonymizeNames {
+		metrics = anonymize.Metrics(metrics, anonymizeSalt)
+	}
 
-	// Generate report
-	reportFormat := reporter.FormatType(format)
-	if !progress {
-		fmt.Fprintf(os.Stderr, "Generating %s report...\n", reportFormat)
+	// Generate the reports from the one analysis
+	reportOptions := reporter.ReportOptions{
+		ByRole:         byRole,
+		Endpoints:      endpoints,
+		SideEffects:    sideEffects,
+		ErrorCoupling:  errorCoupling,
+		StringCoupling: stringCoupling,
+		Edges:          withEdges,
+		Findings:       findings,
+		Baseline:       baseline,
+		Sort:           sortOrder,
+		Top:            top,
+		Columns:        columnList,
+		Precision:      precision,
+		ASCII:          ascii,
+		Offline:        offline,
+		LayoutSeed:     layoutSeed,
+		Canonical:      canonical,
 	}
-	r := reporter.NewReporter(metrics, reportFormat)
-	if err := r.Generate(os.Stdout); err != nil {
-		fmt.Fprintf(os.Stderr, "Error: Failed to generate report: %v\n", err)
-		os.Exit(1)
+	if opts.ProgressReporter != nil {
+		opts.ProgressReporter.StageStart(models.StageReporting, len(formats))
+	}
+	for i, f := range formats {
+		reportFormat := reporter.FormatType(f)
+		path := output
+		if len(formats) > 1 {
+			path = reporter.ReportPath(output, reportFormat)
+		}
+		if opts.ProgressReporter != nil {
+			opts.ProgressReporter.Update(i, fmt.Sprintf("Generating %s report", reportFormat))
+		} else {
+			fmt.Fprintf(os.Stderr, "Generating %s report...\n", reportFormat)
+		}
+		r := reporter.NewReporterWithOptions(metrics, reportFormat, reportOptions)
+		if err := writeReport(r, path); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: Failed to generate %s report: %v\n", reportFormat, err)
+			os.Exit(1)
+		}
+		if len(formats) > 1 {
+			fmt.Fprintf(os.Stderr, "Wrote %s\n", path)
+		}
+	}
+	if opts.ProgressReporter != nil {
+		opts.ProgressReporter.Update(len(formats), "Reports generated")
+		opts.ProgressReporter.StageDone(models.StageReporting)
+		opts.ProgressReporter.Complete()
+	}
+
+	if code := enforceGates(metrics, opts, failOn, debtBudget, ""); code != 0 {
+		os.Exit(code)
+	}
+
+	// Enforce the unused requirements gate
+	if len(unusedDeps) > 0 {
+		fmt.Fprintf(os.Stderr, "\nUnused requirements: %d module(s) required in go.mod with no imported package (run go mod tidy, or keep them for tools):\n", len(unusedDeps))
+		for _, module := range unusedDeps {
+			fmt.Fprintf(os.Stderr, "  %s\n", module)
+		}
+		os.Exit(2)
+	}
+}
+
+// maxPrintedDiagnostics is the number of diagnostics printed before the rest are only counted
+const maxPrintedDiagnostics = 10
+
+// printDiagnostics prints the errors loading packages, so packages with missing
+// or incomplete metrics are not mistaken for healthy ones
+func printDiagnostics(diagnostics []models.Diagnostic, strict bool) {
+	packages := make(map[string]bool)
+	for _, diagnostic := range diagnostics {
+		packages[diagnostic.Package] = true
+	}
+	if strict {
+		fmt.Fprintf(os.Stderr, "Error: %d package(s) have errors (-strict):\n", len(packages))
+	} else {
+		fmt.Fprintf(os.Stderr, "Warning: %d package(s) have errors; their metrics may be incomplete or missing (-strict fails instead):\n", len(packages))
+	}
+	for i, diagnostic := range diagnostics {
+		if i == maxPrintedDiagnostics {
+			fmt.Fprintf(os.Stderr, "  ... and %d more (see the diagnostics of the JSON report)\n", len(diagnostics)-i)
+			break
+		}
+		position := diagnostic.Position
+		if position == "" {
+			position = diagnostic.Package
+		}
+		fmt.Fprintf(os.Stderr, "  %s: %s error: %s\n", position, diagnostic.Kind, diagnostic.Message)
+	}
+}
+
+// printNoPackagesHint explains an analysis that found nothing to analyze, so an
+// empty report is not mistaken for a clean one
+func printNoPackagesHint(err *analyzer.NoPackagesError) {
+	fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+	fmt.Fprintln(os.Stderr, "Common causes:")
+	if err.Discovered > 0 {
+		fmt.Fprintln(os.Stderr, "  - all files are excluded: check -tags and GOOS/GOARCH, and -include-tests for packages with test files only")
+	}
+	fmt.Fprintln(os.Stderr, "  - wrong directory: pass the module root (the directory with go.mod), or -workspace / -recursive-modules for several modules")
+	fmt.Fprintln(os.Stderr, "  - a typo in -pattern: it is resolved against the module root, e.g. ./internal/...")
+	if err.Discovered == 0 {
+		fmt.Fprintln(os.Stderr, "  - all files are excluded: directories holding only _test.go files need -include-tests")
+	}
+}
+
+// analyzeWorkspace analyzes all modules of the workspace that load finds in dir
+func analyzeWorkspace(load func(string) (*workspace.Workspace, error), dir, pattern, mode string, opts analyzer.AnalyzerOptions) (*models.ModuleMetrics, error) {
+	ws, err := load(dir)
+	if err != nil {
+		return nil, err
+	}
+	return workspace.Analyze(context.Background(), ws, pattern, mode, opts)
+}
+
+// unusedRequirements returns the direct go.mod requirements of the module of
+// which neither the analyzed packages nor the tests import any package
+func unusedRequirements(modulePath string, metrics *models.ModuleMetrics) ([]string, error) {
+	direct, err := modgraph.DirectRequirements(modulePath)
+	if err != nil {
+		return nil, err
+	}
+	return modgraph.Unused(modulePath, direct, metrics)
+}
+
+// enforceGates checks the metric thresholds, the findings gate and the debt budget
+// (if not negative), printing the violations with the given prefix, and returns the
+// exit code: 2 if a gate failed, 1 if failOn is invalid
+func enforceGates(metrics *models.ModuleMetrics, opts analyzer.AnalyzerOptions, failOn string, debtBudget int, prefix string) int {
+	// Enforce the metric thresholds
+	if opts.Thresholds != nil {
+		if violations := findingsOfCategory(metrics.Findings, models.CategoryThreshold); len(violations) > 0 {
+			fmt.Fprintf(os.Stderr, "\n%s", prefix)
+			printThresholdViolations(violations)
+			return 2
+		}
+	}
+
+	// Enforce the architecture rules; violations of rules in their warn period
+	// are printed as warnings without failing the run
+	if len(opts.Rules) > 0 {
+		var violations, warnings []models.Finding
+		for _, finding := range findingsOfCategory(metrics.Findings, models.CategoryRule) {
+			if finding.Advisory {
+				warnings = append(warnings, finding)
+			} else {
+				violations = append(violations, finding)
+			}
+		}
+		if len(warnings) > 0 {
+			fmt.Fprintf(os.Stderr, "\n%s", prefix)
+			printRuleWarnings(warnings)
+		}
+		if len(violations) > 0 {
+			fmt.Fprintf(os.Stderr, "\n%s", prefix)
+			printRuleViolations(violations)
+			return 2
+		}
+	}
+
+	// Enforce the findings gate
+	if failOn != "" {
+		severity, err := models.ParseSeverity(failOn)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		if gated := analyzer.FindingsAtLeast(metrics.Findings, severity); len(gated) > 0 {
+			fmt.Fprintf(os.Stderr, "\n%s", prefix)
+			printNextSteps(metrics, gated)
+			return 2
+		}
+	}
+
+	// Enforce the debt budget
+	if debtBudget >= 0 {
+		points := models.DebtPoints(metrics.Findings)
+		if points > debtBudget {
+			fmt.Fprintf(os.Stderr, "\n%s", prefix)
+			printDebt(metrics, points, debtBudget)
+			return 2
+		}
+		if points < debtBudget {
+			fmt.Fprintf(os.Stderr, "\n%sDebt is %d points, %d below the budget of %d: lower the budget to keep it there\n",
+				prefix, points, debtBudget-points, debtBudget)
+		}
+	}
+	return 0
+}
+
+// Values of the -progress flag besides the empty string, no progress
+const (
+	progressBar  progressMode = "bar"
+	progressJSON progressMode = "json"
+)
+
+// progressMode is the value of the -progress flag. Given alone, the flag
+// selects the progress bar, as when it was a boolean flag.
+type progressMode string
+
+// String returns the mode
+func (m *progressMode) String() string {
+	return string(*m)
+}
+
+// Set parses the mode; true and false are accepted for the bar and no progress
+func (m *progressMode) Set(value string) error {
+	switch value {
+	case "true", string(progressBar):
+		*m = progressBar
+	case "false", "":
+		*m = ""
+	case string(progressJSON):
+		*m = progressJSON
+	default:
+		return fmt.Errorf("unknown progress mode %q (want bar or json)", value)
+	}
+	return nil
+}
+
+// IsBoolFlag lets -progress be given without a value
+func (m *progressMode) IsBoolFlag() bool {
+	return true
+}
+
+// writeReport writes the report to the file at path, or to stdout if path is empty
+func writeReport(r *reporter.Reporter, path string) error {
+	if path == "" {
+		return r.Generate(os.Stdout)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := r.Generate(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// resultCache returns the cache of per-package results: a directory, by default
+// in the user cache directory, in front of the remote cache if any. -no-cache
+// disables the local cache only, as the remote one is asked for explicitly.
+func resultCache(dir, remote string, noCache bool) analyzer.ResultCache {
+	var local analyzer.ResultCache
+	if !noCache {
+		if dir == "" {
+			if userCache, err := os.UserCacheDir(); err == nil {
+				dir = filepath.Join(userCache, "aid-metrics", "results")
+			}
+		}
+		if dir != "" {
+			local = cache.Dir{Path: dir}
+		}
+	}
+	switch {
+	case remote == "":
+		return local
+	case local == nil:
+		return cache.NewHTTPClient(remote)
+	}
+	return cache.Layered{Local: local, Shared: cache.NewHTTPClient(remote)}
+}
+
+// optionsFromConfig creates analyzer options from the settings of a configuration file
+func optionsFromConfig(cfg *config.Config) (analyzer.AnalyzerOptions, error) {
+	var opts analyzer.AnalyzerOptions
+	for _, rule := range cfg.Roles {
+		opts.RoleRules = append(opts.RoleRules, analyzer.RoleRule{Pattern: rule.Pattern, Role: rule.Role})
+	}
+	for _, r := range cfg.Rules {
+		rule, err := dependencyRule(r)
+		if err == nil {
+			err = rule.Validate()
+		}
+		if err != nil {
+			return opts, fmt.Errorf("invalid config: %w", err)
+		}
+		opts.Rules = append(opts.Rules, rule)
+	}
+	opts.Profiles = append(opts.Profiles, cfg.Profiles...)
+	opts.Exclude = cfg.Exclude
+	opts.GateEntryPoints = cfg.GateEntryPoints
+	if t := cfg.Thresholds; t != nil {
+		// Unset thresholds default to values no package can violate
+		thresholds := models.Thresholds{MaxDistance: 1, MaxInstability: 1}
+		for _, field := range []struct {
+			name  string
+			value *float64
+			dst   *float64
+		}{
+			{"max_distance", t.MaxDistance, &thresholds.MaxDistance},
+			{"max_instability", t.MaxInstability, &thresholds.MaxInstability},
+			{"min_abstractness", t.MinAbstractness, &thresholds.MinAbstractness},
+		} {
+			if field.value == nil {
+				continue
+			}
+			if *field.value < 0 || *field.value > 1 {
+				return opts, fmt.Errorf("invalid threshold %s in config: %g is not between 0 and 1", field.name, *field.value)
+			}
+			*field.dst = *field.value
+		}
+		opts.Thresholds = &thresholds
+	}
+	for category, points := range cfg.DebtWeights {
+		if points < 0 {
+			return opts, fmt.Errorf("invalid debt weight for %s in config: %d is negative", category, points)
+		}
+	}
+	opts.DebtWeights = cfg.DebtWeights
+	if len(cfg.HealthWeights) > 0 {
+		weights := models.DefaultHealthWeights
+		fields := map[string]*float64{
+			"distance":   &weights.Distance,
+			"cycles":     &weights.Cycles,
+			"complexity": &weights.Complexity,
+			"coverage":   &weights.Coverage,
+		}
+		for component, weight := range cfg.HealthWeights {
+			field, ok := fields[component]
+			if !ok {
+				return opts, fmt.Errorf("unknown health weight %q in config (distance, cycles, complexity, coverage)", component)
+			}
+			if weight < 0 {
+				return opts, fmt.Errorf("invalid health weight for %s in config: %g is negative", component, weight)
+			}
+			*field = weight
+		}
+		opts.HealthWeights = &weights
+	}
+	if len(cfg.ZoneBands) > 0 {
+		bands := models.DefaultZoneBands
+		fields := map[string]*float64{
+			"pain":        &bands.Pain,
+			"uselessness": &bands.Uselessness,
+		}
+		for zone, bound := range cfg.ZoneBands {
+			field, ok := fields[zone]
+			if !ok {
+				return opts, fmt.Errorf("unknown zone band %q in config (pain, uselessness)", zone)
+			}
+			if bound < 0 || bound > 1 {
+				return opts, fmt.Errorf("invalid zone band for %s in config: %g is not between 0 and 1", zone, bound)
+			}
+			*field = bound
+		}
+		if bands.Pain >= bands.Uselessness {
+			return opts, fmt.Errorf("invalid zone bands in config: pain (%g) must be below uselessness (%g)", bands.Pain, bands.Uselessness)
+		}
+		opts.ZoneBands = &bands
+	}
+	opts.Severities = make(map[string]models.Severity, len(cfg.Severities))
+	for category, value := range cfg.Severities {
+		if _, ok := models.FindingIDs[category]; !ok {
+			categories := make([]string, 0, len(models.FindingIDs))
+			for known := range models.FindingIDs {
+				categories = append(categories, known)
+			}
+			sort.Strings(categories)
+			return opts, fmt.Errorf("unknown finding category %q in severities of config (%s)", category, strings.Join(categories, ", "))
+		}
+		severity, err := models.ParseSeverity(value)
+		if err != nil {
+			return opts, fmt.Errorf("invalid severity for %s in config: %w", category, err)
+		}
+		opts.Severities[category] = severity
+	}
+	return opts, nil
+}
+
+// loadConfig loads the configuration file at path, or the default one of the module
+func loadConfig(path, modulePath string) (*config.Config, error) {
+	if path != "" {
+		return config.Load(path)
+	}
+	return config.LoadDefault(modulePath)
+}
+
+// findingsOfCategory returns the findings of the given category
+func findingsOfCategory(findings []models.Finding, category string) []models.Finding {
+	var result []models.Finding
+	for _, finding := range findings {
+		if finding.Category == category {
+			result = append(result, finding)
+		}
+	}
+	return result
+}
+
+// printThresholdViolations writes the packages violating the metric thresholds to stderr
+func printThresholdViolations(violations []models.Finding) {
+	fmt.Fprintf(os.Stderr, "Threshold check failed: %d violation(s):\n", len(violations))
+	for _, finding := range violations {
+		fmt.Fprintf(os.Stderr, "  %s: %s\n", finding.Package, finding.Message)
+	}
+}
+
+// printRuleViolations writes the architecture rule violations to stderr
+func printRuleViolations(violations []models.Finding) {
+	fmt.Fprintf(os.Stderr, "Architecture rules check failed: %d violation(s):\n", len(violations))
+	for _, finding := range violations {
+		fmt.Fprintf(os.Stderr, "  %s: %s\n", finding.Package, finding.Message)
+	}
+}
+
+// printRuleWarnings writes the violations of rules not enforced yet to stderr
+func printRuleWarnings(warnings []models.Finding) {
+	fmt.Fprintf(os.Stderr, "Architecture rules warnings (not enforced): %d violation(s):\n", len(warnings))
+	for _, finding := range warnings {
+		fmt.Fprintf(os.Stderr, "  %s: %s\n", finding.Package, finding.Message)
+	}
+}
+
+// printDebt writes the packages with the most debt points to stderr
+func printDebt(metrics *models.ModuleMetrics, points, budget int) {
+	fmt.Fprintf(os.Stderr, "Debt budget exceeded: %d points, budget %d. Most indebted packages:\n", points, budget)
+	packages := make([]models.PackageMetrics, 0, len(metrics.Packages))
+	for _, pkg := range metrics.Packages {
+		if pkg.DebtPoints > 0 {
+			packages = append(packages, pkg)
+		}
+	}
+	sort.Slice(packages, func(i, j int) bool {
+		if packages[i].DebtPoints != packages[j].DebtPoints {
+			return packages[i].DebtPoints > packages[j].DebtPoints
+		}
+		return packages[i].Name < packages[j].Name
+	})
+	for _, pkg := range packages[:min(len(packages), 5)] {
+		fmt.Fprintf(os.Stderr, "  %s: %d points\n", pkg.Name, pkg.DebtPoints)
+	}
+}
+
+// readReport reads a JSON report file
+func readReport(path string) (*reporter.JSONReport, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	report, err := reporter.ReadJSONReport(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return report, nil
+}
+
+// printNextSteps writes a short "what to fix first" list of the gated findings to stderr
+func printNextSteps(metrics *models.ModuleMetrics, gated []models.Finding) {
+	fmt.Fprintf(os.Stderr, "Quality gate failed: %d finding(s). What to fix first:\n", len(gated))
+	for i, finding := range analyzer.PrioritizeFindings(metrics, gated, 3) {
+		fmt.Fprintf(os.Stderr, "  %d. [%s %s] %s: %s\n", i+1, finding.ID, finding.Severity, finding.Package, finding.Message)
+		fmt.Fprintf(os.Stderr, "     affects %d package(s). %s\n", finding.BlastRadius, finding.Remediation)
 	}
 }
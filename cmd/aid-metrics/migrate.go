@@ -0,0 +1,84 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/alkbt/aid-metrics/pkg/config"
+	"github.com/alkbt/aid-metrics/pkg/reporter"
+)
+
+// runMigrate implements `aid-metrics migrate [file...]`.
+// It upgrades configuration files (YAML) and JSON reports such as baselines
+// to the current schema versions in place. Without arguments, it migrates the
+// configuration file of the module in the working directory.
+func runMigrate(args []string) int {
+	fs := flag.NewFlagSet("migrate", flag.ExitOnError)
+	var check bool
+	fs.BoolVar(&check, "check", false, "Only list the files needing migration and exit with code 1 if there are any")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: aid-metrics migrate [flags] [file...]\n\n")
+		fmt.Fprintf(fs.Output(), "Files ending in .yaml or .yml are configuration files (module, projects or publish),\n")
+		fmt.Fprintf(fs.Output(), "files ending in .json are JSON reports (e.g. baselines).\n\n")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+
+	paths := fs.Args()
+	if len(paths) == 0 {
+		paths = []string{config.DefaultFileName}
+	}
+
+	outdated := 0
+	for _, path := range paths {
+		migrated, changed, err := migrateFile(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s: %v\n", path, err)
+			return 1
+		}
+		if !changed {
+			fmt.Fprintf(os.Stderr, "%s: up to date\n", path)
+			continue
+		}
+		outdated++
+		if check {
+			fmt.Fprintf(os.Stderr, "%s: needs migration\n", path)
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		if err := os.WriteFile(path, migrated, info.Mode().Perm()); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: Failed to write %s: %v\n", path, err)
+			return 1
+		}
+		fmt.Fprintf(os.Stderr, "%s: migrated\n", path)
+	}
+
+	if check && outdated > 0 {
+		return 1
+	}
+	return 0
+}
+
+// migrateFile returns the content of the file upgraded to the current schema
+// version, and whether it differs from the file
+func migrateFile(path string) ([]byte, bool, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false, err
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return config.Migrate(content)
+	case ".json":
+		return reporter.MigrateJSONReport(content)
+	default:
+		return nil, false, fmt.Errorf("unknown file type (expected .yaml, .yml or .json)")
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/alkbt/aid-metrics/pkg/analyzer"
+	"github.com/alkbt/aid-metrics/pkg/modgraph"
+)
+
+// runModules implements `aid-metrics modules [path]`.
+// It prints the module-level view of the dependencies: the coupling among the
+// direct requirements from `go mod graph`, and the packages importing each of them.
+func runModules(args []string) int {
+	fs := flag.NewFlagSet("modules", flag.ExitOnError)
+	var format string
+	fs.StringVar(&format, "format", "text", "Output format (text, json)")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: aid-metrics modules [flags] [path]\n\n")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+
+	modulePath := "."
+	if fs.NArg() > 0 {
+		modulePath = fs.Arg(0)
+	}
+	absPath, err := filepath.Abs(modulePath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: Failed to get absolute path: %v\n", err)
+		return 1
+	}
+
+	graph, err := modgraph.Load(absPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	direct, err := modgraph.DirectRequirements(absPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	// Only the imports of each package are needed to attribute them to modules
+	metrics, err := analyzer.AnalyzeModuleWithOptions(absPath, "./...", analyzer.AnalyzerOptions{ImportsOnly: true})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: Failed to analyze module: %v\n", err)
+		return 1
+	}
+
+	report := modgraph.Build(graph, direct, metrics)
+	switch format {
+	case "text":
+		err = report.WriteText(os.Stdout)
+	case "json":
+		err = report.WriteJSON(os.Stdout)
+	default:
+		err = fmt.Errorf("unknown format %q", format)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
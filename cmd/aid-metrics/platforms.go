@@ -0,0 +1,141 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/alkbt/aid-metrics/pkg/analyzer"
+	"github.com/alkbt/aid-metrics/pkg/models"
+	"github.com/alkbt/aid-metrics/pkg/platform"
+)
+
+// defaultPlatforms are the platforms compared when -platforms is not given
+const defaultPlatforms = "linux/amd64,darwin/arm64,windows/amd64"
+
+// runPlatforms implements `aid-metrics platforms [path]`.
+// It analyzes the module once per GOOS/GOARCH and prints a matrix of the coupling
+// per platform, the imports existing only on some platforms, and the cycles they
+// create. It exits with code 2 if any cycle exists only on some platforms.
+func runPlatforms(args []string) int {
+	fs := flag.NewFlagSet("platforms", flag.ExitOnError)
+	var platformList, format, pattern, configPath string
+	var importsOnly bool
+	fs.StringVar(&platformList, "platforms", defaultPlatforms, "Comma-separated GOOS/GOARCH pairs to compare")
+	fs.StringVar(&format, "format", "text", "Output format (text, json)")
+	fs.StringVar(&pattern, "pattern", "./...", "Package pattern to analyze")
+	fs.StringVar(&configPath, "config", "", "Path to the configuration file")
+	fs.BoolVar(&importsOnly, "imports-only", false, "Parse only imports, which is much faster and enough for coupling")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: aid-metrics platforms [flags] [path]\n\n")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+
+	platforms, err := analyzer.ParsePlatforms(platformList)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if len(platforms) < 2 {
+		fmt.Fprintf(os.Stderr, "Error: -platforms must list at least two platforms\n")
+		return 1
+	}
+
+	modulePath := "."
+	if fs.NArg() > 0 {
+		modulePath = fs.Arg(0)
+	}
+	absPath, err := filepath.Abs(modulePath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: Failed to get absolute path: %v\n", err)
+		return 1
+	}
+
+	cfg, err := loadConfig(configPath, absPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	opts, err := optionsFromConfig(cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	opts.ImportsOnly = importsOnly
+
+	fmt.Fprintf(os.Stderr, "Analyzing Go module at %s for %d platforms\n", absPath, len(platforms))
+	matrix, _, err := platform.Analyze(absPath, pattern, platforms, opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: Failed to analyze module: %v\n", err)
+		return 1
+	}
+
+	switch format {
+	case "text":
+		err = matrix.WriteText(os.Stdout)
+	case "json":
+		err = matrix.WriteJSON(os.Stdout)
+	default:
+		err = fmt.Errorf("unknown format %q", format)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	if len(matrix.Cycles) > 0 {
+		return 2
+	}
+	return 0
+}
+
+// platformResult is the analysis of the module on one platform
+type platformResult struct {
+	platform string
+	metrics  *models.ModuleMetrics
+}
+
+// writeMergedReport analyzes the module once per platform of the comma-separated
+// list and writes the report of the min/max of every metric per package, naming
+// the module by label, to the file at path, or to stdout if path is empty
+func writeMergedReport(modulePath, label, pattern, platformList, format, path string, opts analyzer.AnalyzerOptions) ([]platformResult, error) {
+	if format != "text" && format != "json" {
+		return nil, fmt.Errorf("format %q is not supported with -platforms (text, json)", format)
+	}
+	platforms, err := analyzer.ParsePlatforms(platformList)
+	if err != nil {
+		return nil, err
+	}
+
+	_, results, err := platform.Analyze(modulePath, pattern, platforms, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to analyze module: %w", err)
+	}
+	names := make([]string, 0, len(platforms))
+	analyses := make([]platformResult, 0, len(platforms))
+	for i, p := range platforms {
+		names = append(names, p.String())
+		analyses = append(analyses, platformResult{platform: p.String(), metrics: results[i]})
+	}
+	merged := platform.Merge(names, results)
+	merged.Module = label
+
+	w := os.Stdout
+	if path != "" {
+		if w, err = os.Create(path); err != nil {
+			return nil, err
+		}
+		defer w.Close()
+	}
+	if format == "json" {
+		err = merged.WriteJSON(w)
+	} else {
+		err = merged.WriteText(w)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate report: %w", err)
+	}
+	return analyses, nil
+}
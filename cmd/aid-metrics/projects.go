@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/alkbt/aid-metrics/pkg/config"
+	"github.com/alkbt/aid-metrics/pkg/models"
+	"github.com/alkbt/aid-metrics/pkg/server"
+)
+
+// newProjectRegistry registers the projects of a projects file. Git projects
+// are cloned below workDir and updated before every analysis.
+func newProjectRegistry(projectsPath, workDir string) (*server.Registry, error) {
+	projects, err := config.LoadProjects(projectsPath)
+	if err != nil {
+		return nil, err
+	}
+
+	registry := server.NewRegistry()
+	for _, project := range projects.Projects {
+		source := project.Path
+		if project.Git != "" {
+			source = project.Git
+			if project.Ref != "" {
+				source += "@" + project.Ref
+			}
+		}
+		err := registry.Add(server.Project{
+			Name:     project.Name,
+			Source:   source,
+			Interval: project.Interval,
+		}, projectAnalyzeFunc(project, workDir))
+		if err != nil {
+			return nil, err
+		}
+	}
+	return registry, nil
+}
+
+// projectAnalyzeFunc returns the function analyzing a registered project
+func projectAnalyzeFunc(project config.Project, workDir string) server.AnalyzeFunc {
+	return func(progress models.ProgressReporter) (*models.ModuleMetrics, error) {
+		modulePath := project.Path
+		if project.Git != "" {
+			modulePath = filepath.Join(workDir, project.Name)
+			if err := checkoutProject(modulePath, project.Git, project.Ref); err != nil {
+				return nil, err
+			}
+		}
+
+		pattern := project.Pattern
+		if pattern == "" {
+			pattern = "./..."
+		}
+		a, err := newModuleAnalyzer(modulePath, pattern, project.Config, progress)
+		if err != nil {
+			return nil, err
+		}
+		return a.Analyze()
+	}
+}
+
+// checkoutProject clones a repository into dir, or updates an existing clone to
+// the latest commit of ref (the remote's default branch if empty)
+func checkoutProject(dir, url, ref string) error {
+	if _, err := os.Stat(filepath.Join(dir, ".git")); errors.Is(err, fs.ErrNotExist) {
+		if err := os.MkdirAll(filepath.Dir(dir), 0o755); err != nil {
+			return err
+		}
+		args := []string{"clone", "--quiet", "--depth", "1"}
+		if ref != "" {
+			args = append(args, "--branch", ref)
+		}
+		return runGit("", append(args, "--", url, dir)...)
+	}
+
+	if ref == "" {
+		ref = "HEAD"
+	}
+	if err := runGit(dir, "fetch", "--quiet", "--depth", "1", "--", "origin", ref); err != nil {
+		return err
+	}
+	return runGit(dir, "reset", "--quiet", "--hard", "FETCH_HEAD")
+}
+
+// runGit runs a git command in dir, including its output in the error on failure
+func runGit(dir string, args ...string) error {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("git %s failed: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
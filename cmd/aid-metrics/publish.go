@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/alkbt/aid-metrics/pkg/config"
+	"github.com/alkbt/aid-metrics/pkg/publish"
+	"github.com/alkbt/aid-metrics/pkg/reporter"
+)
+
+// runPublish implements `aid-metrics publish publish.yaml`.
+// It is meant for scheduled jobs: a single command analyzes the module, compares
+// the results with the previous run in the sink, stores them, notifies webhooks
+// and exits with status 2 if the configured gate fails.
+func runPublish(args []string) int {
+	fs := flag.NewFlagSet("publish", flag.ExitOnError)
+	var dryRun bool
+	fs.BoolVar(&dryRun, "dry-run", false, "Analyze and compare, but neither write to the sink nor notify")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: aid-metrics publish [flags] publish.yaml\n\n")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 1
+	}
+
+	cfg, err := config.LoadPublish(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	pattern := cfg.Pattern
+	if pattern == "" {
+		pattern = "./..."
+	}
+
+	a, err := newModuleAnalyzer(cfg.Module, pattern, cfg.Config, nil)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	metrics, err := a.Analyze()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: Failed to analyze module: %v\n", err)
+		return 1
+	}
+	current := reporter.NewJSONReport(metrics)
+
+	sink := publish.Dir{Path: cfg.Sink.Dir, Keep: cfg.Sink.Keep}
+	previous, err := sink.Latest()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: Failed to read the previous run: %v\n", err)
+		return 1
+	}
+	comparison := publish.Compare(previous, current)
+	reasons, err := publish.Check(cfg.FailOn, current, comparison)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: invalid fail_on: %v\n", err)
+		return 1
+	}
+	notification := publish.NewNotification(metrics.Path, comparison, reasons)
+	fmt.Fprintln(os.Stderr, notification.Text)
+
+	if dryRun {
+		return exitStatus(reasons)
+	}
+
+	status := exitStatus(reasons)
+	if err := sink.Write(current, time.Now()); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: Failed to write the run: %v\n", err)
+		status = 1
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	client := &http.Client{Timeout: 30 * time.Second}
+	if err := publish.Notify(ctx, client, cfg.Notify, notification); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		status = 1
+	}
+	return status
+}
+
+// exitStatus returns 2 if the gate failed for the given reasons and 0 otherwise
+func exitStatus(reasons []string) int {
+	if len(reasons) > 0 {
+		return 2
+	}
+	return 0
+}
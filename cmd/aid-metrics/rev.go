@@ -0,0 +1,56 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// checkoutRevision checks a git revision (commit, tag or branch) of the repository
+// containing modulePath out into a temporary worktree. It returns the path of the
+// module within the worktree and a function removing the worktree again.
+func checkoutRevision(modulePath, rev string) (string, func(), error) {
+	top, err := gitOutput(modulePath, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return "", nil, err
+	}
+	// Ask git for the prefix: top has symlinks resolved, modulePath may not
+	rel, err := gitOutput(modulePath, "rev-parse", "--show-prefix")
+	if err != nil {
+		return "", nil, err
+	}
+
+	tmp, err := os.MkdirTemp("", "aid-metrics-rev-")
+	if err != nil {
+		return "", nil, err
+	}
+	tree := filepath.Join(tmp, "tree")
+	if err := runGit(top, "worktree", "add", "--quiet", "--detach", tree, rev); err != nil {
+		os.RemoveAll(tmp)
+		return "", nil, err
+	}
+
+	cleanup := func() {
+		if err := runGit(top, "worktree", "remove", "--force", tree); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+		os.RemoveAll(tmp)
+	}
+	return filepath.Join(tree, rel), cleanup, nil
+}
+
+// gitOutput runs a git command in dir and returns its trimmed output
+func gitOutput(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git %s failed: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(string(out)), nil
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCheckoutRevisionThroughSymlink(t *testing.T) {
+	dir := t.TempDir()
+	repo := filepath.Join(dir, "repo")
+	if err := os.MkdirAll(filepath.Join(repo, "svc"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(repo, "svc", "go.mod"), []byte("module example.com/svc\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, args := range [][]string{
+		{"init", "--quiet"},
+		{"add", "."},
+		{"-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "--quiet", "-m", "init"},
+	} {
+		if err := runGit(repo, args...); err != nil {
+			t.Skipf("git is not usable: %v", err)
+		}
+	}
+
+	link := filepath.Join(dir, "link")
+	if err := os.Symlink(repo, link); err != nil {
+		t.Skipf("cannot create symlinks: %v", err)
+	}
+
+	path, cleanup, err := checkoutRevision(filepath.Join(link, "svc"), "HEAD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup()
+	if _, err := os.Stat(filepath.Join(path, "go.mod")); err != nil {
+		t.Errorf("expected the module in the worktree at %s: %v", path, err)
+	}
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/alkbt/aid-metrics/pkg/analyzer"
+	"github.com/alkbt/aid-metrics/pkg/config"
+)
+
+// runRules implements the `aid-metrics rules` subcommands
+func runRules(args []string) int {
+	if len(args) > 0 {
+		switch args[0] {
+		case "check":
+			return runRulesCheck(args[1:])
+		case "test":
+			return runRulesTest(args[1:])
+		}
+	}
+	fmt.Fprintf(os.Stderr, "Usage: aid-metrics rules check|test [flags] [path]\n")
+	return 1
+}
+
+// runRulesCheck implements `aid-metrics rules check [path]`.
+// It lints the dependency rules of the configuration file against the module:
+// invalid patterns, rules and patterns matching no package, redundant rules and
+// allowed imports another rule denies are reported, and each rule is listed with
+// the packages it applies to. The exit code is 1 if any rule has a problem.
+func runRulesCheck(args []string) int {
+	fs := flag.NewFlagSet("rules check", flag.ExitOnError)
+	var configPath string
+	var verbose bool
+	fs.StringVar(&configPath, "config", "", "Path to the configuration file")
+	fs.BoolVar(&verbose, "v", false, "List every package a rule applies to")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: aid-metrics rules check [flags] [path]\n\n")
+		fmt.Fprintf(fs.Output(), "Checks the rules of the configuration file against the module and shows the packages each one matches.\n\n")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+
+	modulePath := "."
+	if fs.NArg() > 0 {
+		modulePath = fs.Arg(0)
+	}
+	absPath, err := filepath.Abs(modulePath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: Failed to get absolute path: %v\n", err)
+		return 1
+	}
+	cfg, err := loadConfig(configPath, absPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	// Invalid rules are reported as problems rather than failing the configuration
+	rules := cfg.Rules
+	cfg.Rules = nil
+	opts, err := optionsFromConfig(cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	opts.Rules = dependencyRules(rules)
+	if len(opts.Rules) == 0 {
+		fmt.Println("No rules configured")
+		return 0
+	}
+	for _, r := range rules {
+		if _, err := dependencyRule(r); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: invalid config: %v\n", err)
+			return 1
+		}
+	}
+
+	checks, err := analyzer.CheckRules(context.Background(), absPath, opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: Failed to analyze module: %v\n", err)
+		return 1
+	}
+	problems := 0
+	for i, check := range checks {
+		fmt.Printf("rule %d: %s [%s]\n", i+1, check.Description, enforcementLabel(check.Rule))
+		fmt.Printf("  applies to %s\n", packageList(check.Packages, verbose))
+		if len(check.Violators) > 0 {
+			fmt.Printf("  violated by %s\n", packageList(check.Violators, true))
+		}
+		for _, problem := range check.Problems {
+			fmt.Printf("  problem: %s\n", problem)
+		}
+		problems += len(check.Problems)
+	}
+	if problems > 0 {
+		fmt.Printf("\n%d problem(s) in %d rule(s)\n", problems, len(checks))
+		return 1
+	}
+	fmt.Printf("\n%d rule(s) OK\n", len(checks))
+	return 0
+}
+
+// runRulesTest implements `aid-metrics rules test [path]`.
+// It runs the dependency rules of the configuration file against the example
+// package layouts of its rule_tests and fails if a layout does not produce
+// exactly the expected violations. The module itself is not analyzed.
+func runRulesTest(args []string) int {
+	fs := flag.NewFlagSet("rules test", flag.ExitOnError)
+	var configPath string
+	fs.StringVar(&configPath, "config", "", "Path to the configuration file")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: aid-metrics rules test [flags] [path]\n\n")
+		fmt.Fprintf(fs.Output(), "Runs the rules of the configuration file against the package layouts of its rule_tests.\n\n")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+
+	modulePath := "."
+	if fs.NArg() > 0 {
+		modulePath = fs.Arg(0)
+	}
+	absPath, err := filepath.Abs(modulePath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: Failed to get absolute path: %v\n", err)
+		return 1
+	}
+	cfg, err := loadConfig(configPath, absPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if len(cfg.RuleTests) == 0 {
+		fmt.Println("No rule tests configured")
+		return 0
+	}
+
+	tests := make([]analyzer.RuleTest, len(cfg.RuleTests))
+	for i, t := range cfg.RuleTests {
+		tests[i] = analyzer.RuleTest{Name: t.Name, Packages: t.Packages, Violations: t.Violations}
+		if tests[i].Name == "" {
+			tests[i].Name = fmt.Sprintf("rule test %d", i+1)
+		}
+	}
+	failed := 0
+	for _, result := range analyzer.RunRuleTests(dependencyRules(cfg.Rules), tests) {
+		if result.Passed() {
+			fmt.Printf("ok    %s\n", result.Test.Name)
+			continue
+		}
+		failed++
+		fmt.Printf("FAIL  %s\n", result.Test.Name)
+		for _, violation := range result.Unexpected {
+			fmt.Printf("      unexpected violation: %s\n", violation)
+		}
+		for _, violation := range result.Missing {
+			fmt.Printf("      missing violation:    %s\n", violation)
+		}
+	}
+	if failed > 0 {
+		fmt.Printf("\n%d of %d rule test(s) failed\n", failed, len(tests))
+		return 1
+	}
+	fmt.Printf("\n%d rule test(s) passed\n", len(tests))
+	return 0
+}
+
+// dependencyRules converts the rules of the configuration file without validating them
+func dependencyRules(rules []config.DependencyRule) []analyzer.DependencyRule {
+	var result []analyzer.DependencyRule
+	for _, r := range rules {
+		rule, _ := dependencyRule(r)
+		result = append(result, rule)
+	}
+	return result
+}
+
+// dependencyRule converts a rule of the configuration file. An invalid
+// enforce_from date is returned as an error along with the rest of the rule.
+func dependencyRule(r config.DependencyRule) (analyzer.DependencyRule, error) {
+	rule := analyzer.DependencyRule{Name: r.Name, From: r.From, Except: r.Except, Deny: r.Deny, Allow: r.Allow, Enforcement: r.Enforcement}
+	if r.EnforceFrom == "" {
+		return rule, nil
+	}
+	from, err := time.ParseInLocation(time.DateOnly, r.EnforceFrom, time.Local)
+	if err != nil {
+		return rule, fmt.Errorf("rule %q: invalid enforce_from %q, want YYYY-MM-DD", r.Name, r.EnforceFrom)
+	}
+	rule.EnforceFrom = from
+	return rule, nil
+}
+
+// enforcementLabel describes the enforcement of a rule today, e.g. "warn until 2026-12-01"
+func enforcementLabel(rule analyzer.DependencyRule) string {
+	level := rule.Level(time.Now())
+	if level == analyzer.RuleWarn && !rule.EnforceFrom.IsZero() {
+		return fmt.Sprintf("%s until %s", level, rule.EnforceFrom.Format(time.DateOnly))
+	}
+	return level
+}
+
+// packageList formats a count of packages, followed by their names if all is
+// set or there are few of them
+func packageList(names []string, all bool) string {
+	if len(names) == 0 {
+		return "no package"
+	}
+	if !all && len(names) > 5 {
+		return fmt.Sprintf("%d packages: %s, ...", len(names), strings.Join(names[:5], ", "))
+	}
+	return fmt.Sprintf("%d package(s): %s", len(names), strings.Join(names, ", "))
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/alkbt/aid-metrics/pkg/publish"
+	"github.com/alkbt/aid-metrics/pkg/reporter"
+	"github.com/alkbt/aid-metrics/pkg/scorecard"
+)
+
+// runScorecard implements `aid-metrics scorecard [name=]runs-dir...`.
+// It ranks repositories by the health of the latest run stored in their runs
+// directory by the publish subcommand, with their mean distance, cycle count
+// and health trend over the last runs.
+func runScorecard(args []string) int {
+	fs := flag.NewFlagSet("scorecard", flag.ExitOnError)
+	var last int
+	var format, title string
+	fs.IntVar(&last, "last", 5, "Number of most recent runs of each repository the trend covers (0 for all)")
+	fs.StringVar(&format, "format", "text", "Output format (text, csv, html, json)")
+	fs.StringVar(&title, "title", "Architecture scorecard", "Title of the HTML page")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: aid-metrics scorecard [flags] [name=]runs-dir...\n\n")
+		fmt.Fprintf(fs.Output(), "Ranks repositories by the runs the publish subcommand stored for each. A repository\n")
+		fmt.Fprintf(fs.Output(), "is named after its runs directory unless given as name=runs-dir.\n\n")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 1
+	}
+
+	var names []string
+	var runs [][]*reporter.JSONReport
+	for _, arg := range fs.Args() {
+		name, dir, ok := strings.Cut(arg, "=")
+		if !ok {
+			dir = arg
+			name = filepath.Base(filepath.Clean(dir))
+		}
+		paths, err := publish.Dir{Path: dir}.Runs()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		if len(paths) == 0 {
+			fmt.Fprintf(os.Stderr, "Warning: no runs stored in %s, skipping %s\n", dir, name)
+			continue
+		}
+		if last > 0 && len(paths) > last {
+			paths = paths[len(paths)-last:]
+		}
+		repoRuns := make([]*reporter.JSONReport, 0, len(paths))
+		for _, path := range paths {
+			run, err := publish.ReadRun(path)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				return 1
+			}
+			repoRuns = append(repoRuns, run)
+		}
+		names = append(names, name)
+		runs = append(runs, repoRuns)
+	}
+
+	card := scorecard.Build(names, runs)
+	var err error
+	switch format {
+	case "text":
+		err = card.WriteText(os.Stdout)
+	case "csv":
+		err = card.WriteCSV(os.Stdout)
+	case "html":
+		err = card.WriteHTML(os.Stdout, title)
+	case "json":
+		err = card.WriteJSON(os.Stdout)
+	default:
+		err = fmt.Errorf("unknown format %q", format)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/alkbt/aid-metrics/pkg/models"
+	"github.com/alkbt/aid-metrics/pkg/server"
+)
+
+// runServe implements `aid-metrics serve -addr 127.0.0.1:8090 [path]`.
+// It analyzes the module in the background and serves the results over HTTP;
+// POST /analyze triggers a new analysis. With -projects it serves every project
+// of a projects file under /projects/<name>/ instead.
+//
+// Requests can be required to carry a bearer token, either a static token read
+// from -token-file or $AID_METRICS_TOKEN, or any token an OIDC provider reports
+// as active through its introspection endpoint. With -tls-cert and -tls-key the
+// API is served over HTTPS. Both are required unless the server listens on a
+// loopback address or -insecure is given.
+func runServe(args []string) int {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	var addr string
+	var pattern string
+	var configPath string
+	var tokenFile string
+	var introspectionURL string
+	var clientID string
+	var tlsCert string
+	var tlsKey string
+	var projectsPath string
+	var workDir string
+	var insecure bool
+	var allowedOrigins string
+	fs.StringVar(&addr, "addr", "127.0.0.1:8090", "Address to listen on")
+	fs.StringVar(&pattern, "pattern", "./...", "Package pattern to analyze")
+	fs.StringVar(&configPath, "config", "", "Path to the configuration file")
+	fs.StringVar(&projectsPath, "projects", "", "Projects file registering several modules to serve (see README)")
+	fs.StringVar(&workDir, "work-dir", "", "Directory for clones of git projects (default: user cache dir)")
+	fs.StringVar(&tokenFile, "token-file", "", "File containing the bearer token required by all requests (default: $AID_METRICS_TOKEN)")
+	fs.StringVar(&introspectionURL, "oidc-introspection-url", "", "OIDC token introspection endpoint used to validate bearer tokens")
+	fs.StringVar(&clientID, "oidc-client-id", "", "Client ID for the introspection endpoint (secret: $AID_METRICS_OIDC_CLIENT_SECRET)")
+	fs.StringVar(&tlsCert, "tls-cert", "", "TLS certificate file; serves HTTPS together with -tls-key")
+	fs.StringVar(&tlsKey, "tls-key", "", "TLS private key file")
+	fs.StringVar(&allowedOrigins, "allowed-origins", "", "Comma-separated origins of web pages besides the server's own allowed to use the API, e.g. https://dash.example.com")
+	fs.BoolVar(&insecure, "insecure", false, "Allow serving without a token or TLS on a non-loopback address, e.g. behind a proxy that authenticates and terminates TLS")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: aid-metrics serve [flags] [path]\n       aid-metrics serve [flags] -projects projects.yaml\n\n")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+
+	if (tlsCert == "") != (tlsKey == "") {
+		fmt.Fprintf(os.Stderr, "Error: -tls-cert and -tls-key must be used together\n")
+		return 1
+	}
+	auth, err := serveAuthenticator(tokenFile, introspectionURL, clientID)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	// Anyone reaching the port could otherwise read the metrics and trigger
+	// analyses, or read the tokens of clients off the network
+	if !isLoopback(addr) && !insecure {
+		if auth == nil {
+			fmt.Fprintf(os.Stderr, "Error: a token (-token-file, $AID_METRICS_TOKEN or -oidc-introspection-url) is required unless -addr is a loopback address or -insecure is given\n")
+			return 1
+		}
+		if tlsCert == "" {
+			fmt.Fprintf(os.Stderr, "Error: -tls-cert and -tls-key are required unless -addr is a loopback address or -insecure is given\n")
+			return 1
+		}
+	}
+
+	var handler http.Handler
+	if projectsPath != "" {
+		if fs.NArg() > 0 {
+			fmt.Fprintf(os.Stderr, "Error: -projects cannot be combined with a module path\n")
+			return 1
+		}
+		if workDir == "" {
+			userCache, err := os.UserCacheDir()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				return 1
+			}
+			workDir = filepath.Join(userCache, "aid-metrics", "projects")
+		}
+		registry, err := newProjectRegistry(projectsPath, workDir)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		go registry.Run(context.Background())
+		handler = registry.Handler()
+	} else {
+		srv := server.New(func(progress models.ProgressReporter) (*models.ModuleMetrics, error) {
+			a, err := newModuleAnalyzer(fs.Arg(0), pattern, configPath, progress)
+			if err != nil {
+				return nil, err
+			}
+			return a.Analyze()
+		})
+		go srv.Analyze()
+		handler = srv.Handler()
+	}
+
+	var origins []string
+	if allowedOrigins != "" {
+		origins = strings.Split(allowedOrigins, ",")
+	}
+	handler = server.RequireSameOrigin(origins, handler)
+	if auth != nil {
+		handler = server.RequireToken(auth, handler)
+	} else if !isLoopback(addr) {
+		fmt.Fprintf(os.Stderr, "Warning: serving without authentication on %s\n", addr)
+	}
+	if tlsCert == "" && !isLoopback(addr) {
+		fmt.Fprintf(os.Stderr, "Warning: serving without TLS on %s\n", addr)
+	}
+	httpServer := &http.Server{
+		Addr:      addr,
+		Handler:   handler,
+		TLSConfig: &tls.Config{MinVersion: tls.VersionTLS12},
+	}
+
+	if tlsCert != "" {
+		fmt.Fprintf(os.Stderr, "Serving metrics on %s (TLS)\n", addr)
+		err = httpServer.ListenAndServeTLS(tlsCert, tlsKey)
+	} else {
+		fmt.Fprintf(os.Stderr, "Serving metrics on %s\n", addr)
+		err = httpServer.ListenAndServe()
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// serveAuthenticator builds the authenticator for serve mode and the coordinator
+// from their flags and environment. It returns nil if no authentication is configured.
+func serveAuthenticator(tokenFile, introspectionURL, clientID string) (server.Authenticator, error) {
+	token, err := readToken(tokenFile)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case token != "" && introspectionURL != "":
+		return nil, errors.New("a static token and OIDC introspection cannot be used together")
+	case token != "":
+		return server.StaticToken(token), nil
+	case introspectionURL != "":
+		return &server.Introspector{
+			URL:          introspectionURL,
+			ClientID:     clientID,
+			ClientSecret: os.Getenv("AID_METRICS_OIDC_CLIENT_SECRET"),
+		}, nil
+	}
+	return nil, nil
+}
+
+// readToken returns the static token read from tokenFile, or $AID_METRICS_TOKEN
+// if no file is given
+func readToken(tokenFile string) (string, error) {
+	if tokenFile == "" {
+		return os.Getenv("AID_METRICS_TOKEN"), nil
+	}
+	data, err := os.ReadFile(tokenFile)
+	if err != nil {
+		return "", fmt.Errorf("failed to read token file: %w", err)
+	}
+	token := strings.TrimSpace(string(data))
+	if token == "" {
+		return "", fmt.Errorf("token file %s is empty", tokenFile)
+	}
+	return token, nil
+}
+
+// isLoopback reports whether a listen address only accepts local connections
+func isLoopback(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
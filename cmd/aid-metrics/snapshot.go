@@ -0,0 +1,94 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/alkbt/aid-metrics/pkg/analyzer"
+	"github.com/alkbt/aid-metrics/pkg/reporter"
+	"github.com/alkbt/aid-metrics/pkg/snapshot"
+)
+
+// runSnapshot implements `aid-metrics snapshot -write dir [path]`.
+// It analyzes the module and writes its metrics as canonical JSON with a
+// Markdown summary into a directory of the repository, optionally staging them,
+// so the state of the architecture is committed and reviewed with the code.
+func runSnapshot(args []string) int {
+	fs := flag.NewFlagSet("snapshot", flag.ExitOnError)
+	var dir, pattern, configPath string
+	var precision int
+	var stage bool
+	fs.StringVar(&dir, "write", "metrics", "Directory of the snapshot, created if needed")
+	fs.StringVar(&pattern, "pattern", "./...", "Package pattern to analyze")
+	fs.StringVar(&configPath, "config", "", "Path to the configuration file")
+	fs.IntVar(&precision, "precision", reporter.DefaultPrecision, "Decimals of the ratios of the snapshot")
+	fs.BoolVar(&stage, "stage", false, "Stage the snapshot files with git add")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: aid-metrics snapshot [flags] [path]\n\n")
+		fmt.Fprintf(fs.Output(), "Writes %s (the canonical JSON report with findings) and %s into the -write directory.\n", snapshot.JSONFile, snapshot.SummaryFile)
+		fmt.Fprintf(fs.Output(), "They hold no timestamps, so snapshots of unchanged code are identical.\n\n")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+	if fs.NArg() > 1 {
+		fs.Usage()
+		return 1
+	}
+	modulePath := "."
+	if fs.NArg() == 1 {
+		modulePath = fs.Arg(0)
+	}
+
+	a, err := newModuleAnalyzer(modulePath, pattern, configPath, nil)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	metrics, err := a.Analyze()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: Failed to analyze module: %v\n", err)
+		return 1
+	}
+	if len(metrics.Diagnostics) > 0 {
+		printDiagnostics(metrics.Diagnostics, false)
+	}
+	// The directory the module was analyzed in differs between checkouts
+	absPath, err := filepath.Abs(modulePath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: Failed to get absolute path: %v\n", err)
+		return 1
+	}
+	if name := analyzer.ReadModuleName(absPath); name != "" {
+		metrics.Path = name
+	} else {
+		metrics.Path = filepath.Base(absPath)
+	}
+
+	files, err := snapshot.Write(dir, metrics, precision)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: Failed to write the snapshot: %v\n", err)
+		return 1
+	}
+	for _, file := range files {
+		fmt.Fprintf(os.Stderr, "Wrote %s\n", file)
+	}
+
+	if stage {
+		gitArgs := []string{"add", "--"}
+		for _, file := range files {
+			abs, err := filepath.Abs(file)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				return 1
+			}
+			gitArgs = append(gitArgs, abs)
+		}
+		if err := runGit(filepath.Dir(gitArgs[2]), gitArgs...); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: Failed to stage the snapshot: %v\n", err)
+			return 1
+		}
+	}
+	return 0
+}
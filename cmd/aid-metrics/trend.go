@@ -0,0 +1,73 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/alkbt/aid-metrics/pkg/publish"
+	"github.com/alkbt/aid-metrics/pkg/reporter"
+	"github.com/alkbt/aid-metrics/pkg/trend"
+)
+
+// runTrend implements `aid-metrics trend runs-dir`.
+// It prints the evolution of every package across the last runs stored by the
+// publish subcommand and flags the packages whose I or D keeps worsening.
+func runTrend(args []string) int {
+	fs := flag.NewFlagSet("trend", flag.ExitOnError)
+	var last int
+	var format string
+	fs.IntVar(&last, "last", 10, "Number of most recent runs to include (0 for all)")
+	fs.StringVar(&format, "format", "text", "Output format (text, json)")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: aid-metrics trend [flags] runs-dir\n\n")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 1
+	}
+
+	paths, err := publish.Dir{Path: fs.Arg(0)}.Runs()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if len(paths) == 0 {
+		fmt.Fprintf(os.Stderr, "Error: no runs stored in %s\n", fs.Arg(0))
+		return 1
+	}
+	if last > 0 && len(paths) > last {
+		paths = paths[len(paths)-last:]
+	}
+
+	names := make([]string, 0, len(paths))
+	runs := make([]*reporter.JSONReport, 0, len(paths))
+	for _, path := range paths {
+		run, err := publish.ReadRun(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		names = append(names, strings.TrimSuffix(filepath.Base(path), ".json"))
+		runs = append(runs, run)
+	}
+
+	result := trend.Build(names, runs)
+	switch format {
+	case "text":
+		err = result.WriteText(os.Stdout)
+	case "json":
+		err = result.WriteJSON(os.Stdout)
+	default:
+		err = fmt.Errorf("unknown format %q", format)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/alkbt/aid-metrics/pkg/analyzer"
+	"github.com/alkbt/aid-metrics/pkg/reporter"
+)
+
+// runVerify implements `aid-metrics verify -runs N [path]`.
+// It runs the analysis several times and checks that the JSON reports are
+// byte-identical, surfacing nondeterminism such as map ordering or races.
+func runVerify(args []string) int {
+	fs := flag.NewFlagSet("verify", flag.ExitOnError)
+	var runs int
+	var pattern string
+	fs.IntVar(&runs, "runs", 3, "Number of analysis runs to compare")
+	fs.StringVar(&pattern, "pattern", "./...", "Package pattern to analyze")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: aid-metrics verify [flags] [path]\n\n")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+
+	if runs < 2 {
+		fmt.Fprintf(os.Stderr, "Error: -runs must be at least 2\n")
+		return 1
+	}
+
+	modulePath := "."
+	if fs.NArg() > 0 {
+		modulePath = fs.Arg(0)
+	}
+	absPath, err := filepath.Abs(modulePath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: Failed to get absolute path: %v\n", err)
+		return 1
+	}
+
+	// Render every section of the report so all of it is compared
+	options := reporter.ReportOptions{ByRole: true, Endpoints: true, Findings: true}
+
+	var reference []byte
+	for run := 1; run <= runs; run++ {
+		fmt.Fprintf(os.Stderr, "Run %d of %d...\n", run, runs)
+		metrics, err := analyzer.AnalyzeModule(absPath, pattern)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: Failed to analyze module: %v\n", err)
+			return 1
+		}
+
+		var buf bytes.Buffer
+		if err := reporter.NewReporterWithOptions(metrics, reporter.FormatJSON, options).Generate(&buf); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: Failed to generate report: %v\n", err)
+			return 1
+		}
+
+		if reference == nil {
+			reference = buf.Bytes()
+			continue
+		}
+		if !bytes.Equal(reference, buf.Bytes()) {
+			line, want, got := firstDifference(reference, buf.Bytes())
+			fmt.Printf("NONDETERMINISTIC: run %d differs from run 1 at line %d\n", run, line)
+			fmt.Printf("  run 1: %s\n  run %d: %s\n", want, run, got)
+			return 2
+		}
+	}
+
+	fmt.Printf("OK: %d runs produced byte-identical results (%d bytes)\n", runs, len(reference))
+	return 0
+}
+
+// firstDifference returns the 1-based number and contents of the first line that differs
+func firstDifference(a, b []byte) (int, string, string) {
+	linesA := strings.Split(string(a), "\n")
+	linesB := strings.Split(string(b), "\n")
+	for i := 0; i < len(linesA) || i < len(linesB); i++ {
+		var lineA, lineB string
+		if i < len(linesA) {
+			lineA = linesA[i]
+		}
+		if i < len(linesB) {
+			lineB = linesB[i]
+		}
+		if lineA != lineB {
+			return i + 1, strings.TrimSpace(lineA), strings.TrimSpace(lineB)
+		}
+	}
+	return 0, "", ""
+}
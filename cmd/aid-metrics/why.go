@@ -0,0 +1,66 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/alkbt/aid-metrics/pkg/analyzer"
+)
+
+// runWhy implements `aid-metrics why from to [path]`.
+// It prints the shortest import paths from one package to another, showing
+// which edges to cut to break an unwanted coupling.
+func runWhy(args []string) int {
+	fs := flag.NewFlagSet("why", flag.ExitOnError)
+	var limit int
+	var tests bool
+	fs.IntVar(&limit, "max", 10, "Maximum number of paths to print (0 for all)")
+	fs.BoolVar(&tests, "tests", false, "Include test files in the import graph")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: aid-metrics why [flags] from to [path]\n\n")
+		fmt.Fprintf(fs.Output(), "Packages are given by display name (as in reports), import path or module-relative path;\n")
+		fmt.Fprintf(fs.Output(), "to may also be a dependency outside the module.\n\n")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+
+	if fs.NArg() < 2 || fs.NArg() > 3 {
+		fs.Usage()
+		return 1
+	}
+	modulePath := "."
+	if fs.NArg() == 3 {
+		modulePath = fs.Arg(2)
+	}
+	absPath, err := filepath.Abs(modulePath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: Failed to get absolute path: %v\n", err)
+		return 1
+	}
+
+	// Only the imports of each package are needed to walk the graph
+	metrics, err := analyzer.AnalyzeModuleWithOptions(absPath, "./...", analyzer.AnalyzerOptions{ImportsOnly: true, IncludeTests: tests})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: Failed to analyze module: %v\n", err)
+		return 1
+	}
+
+	from, to := fs.Arg(0), fs.Arg(1)
+	paths, err := analyzer.ShortestPaths(metrics, from, to, limit)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if len(paths) == 0 {
+		fmt.Printf("%s does not depend on %s\n", from, to)
+		return 1
+	}
+	fmt.Printf("%s imports %s through %d shortest path(s) of %d import(s):\n", from, to, len(paths), len(paths[0])-1)
+	for _, path := range paths {
+		fmt.Printf("  %s\n", strings.Join(path, " -> "))
+	}
+	return 0
+}
@@ -1,16 +1,19 @@
 package analyzer
 
 import (
+	"context"
 	"fmt"
 	"go/ast"
-	"go/parser"
-	"go/token"
+	"go/types"
 	"math"
 	"os"
 	"path/filepath"
 	"runtime"
+	"slices"
+	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 
 	"github.com/alkbt/aid-metrics/pkg/models"
 	"golang.org/x/tools/go/packages"
@@ -27,22 +30,172 @@ type AnalyzerOptions struct {
 	// Larger values use more memory but may be faster.
 	// Default is 20 if not specified.
 	BatchSize int
+
+	// Workers is the number of packages parsed in parallel; 0 means GOMAXPROCS
+	Workers int
+
+	// RoleRules override the built-in role heuristics for matching packages.
+	// Rules are evaluated in order and the first match wins.
+	RoleRules []RoleRule
+
+	// Profiles enables built-in handling of well-known package kinds, such as
+	// ProfileProtobuf for generated protobuf/gRPC packages.
+	Profiles []string
+
+	// Severities overrides the default severity of finding categories
+	// (see models.DefaultSeverities).
+	Severities map[string]models.Severity
+
+	// DebtWeights overrides the debt points of the findings of a category, which
+	// default to the points of their severity (see models.DefaultDebtPoints).
+	DebtWeights map[string]int
+
+	// ExportedOnly counts only exported declarations for Na, Nc and A, the
+	// published abstractness of a package. The counts of all declarations are
+	// reported next to them.
+	ExportedOnly bool
+
+	// Exclude lists module-relative package patterns ("dir", "dir/...", or
+	// path.Match globs like "internal/*/mocks") whose packages are not analyzed.
+	// Imports of excluded packages still count as dependencies.
+	Exclude []string
+
+	// SkipGenerated leaves the declarations of generated files (with the standard
+	// "// Code generated ... DO NOT EDIT." header) out of Na and Nc, so protobuf
+	// and mock output does not inflate them.
+	SkipGenerated bool
+
+	// SkipGeneratedImports also leaves the imports of generated files out of the
+	// dependencies of a package. It implies SkipGenerated.
+	SkipGeneratedImports bool
+
+	// HealthWeights weighs the components of the health score of packages.
+	// If nil, models.DefaultHealthWeights are used.
+	HealthWeights *models.HealthWeights
+
+	// ZoneBands sets the boundaries of the zones of pain and uselessness.
+	// If nil, models.DefaultZoneBands are used.
+	ZoneBands *models.ZoneBands
+
+	// Coverage holds the share of statements covered by tests per package import
+	// path, e.g. read from a cover profile. Packages missing from it have no
+	// coverage, which is then left out of their health score.
+	Coverage map[string]float64
+
+	// ImportsOnly enables the fast mode: only import declarations are parsed and
+	// packages are not loaded, so only coupling metrics are computed. Abstractness,
+	// distance and the type-based metrics are left at zero.
+	ImportsOnly bool
+
+	// Cache stores per-package results keyed by content hash, so unchanged
+	// packages are not parsed again. If nil, no caching is done.
+	Cache ResultCache
+
+	// Thresholds are module-wide metric bounds. Every package violating them is
+	// reported as a threshold finding. If nil, no thresholds are enforced.
+	Thresholds *models.Thresholds
+
+	// GateEntryPoints holds main packages and composition roots to the
+	// abstractness/distance checks. By default they are exempt: wiring the
+	// program together makes them maximally unstable and concrete by design.
+	GateEntryPoints bool
+
+	// Platform selects the GOOS/GOARCH to analyze. The zero value analyzes the
+	// platform of the go command's environment.
+	Platform Platform
+
+	// IncludeTests analyzes _test.go files too: their types and imports count
+	// towards the metrics of their package, and external test packages (package
+	// p_test) are reported as packages of their own.
+	IncludeTests bool
+
+	// BuildTags are the build tags to satisfy, as with go build -tags. Files
+	// whose build constraints are not met are not analyzed.
+	BuildTags []string
+
+	// Offline keeps the go command that loads packages off the network: modules
+	// missing from the module cache (or vendor/) fail to load instead of being
+	// downloaded through GOPROXY.
+	Offline bool
+
+	// Rules constrain the imports between packages. Every package violating a
+	// rule is reported as a rule finding (models.CategoryRule).
+	Rules []DependencyRule
+
+	// Concurrency adds the concurrency metric group (models.ConcurrencyGroup) to
+	// every package: goroutine launches, channels made and uses of sync primitives
+	Concurrency bool
+
+	// StringCoupling lists the key-like string literals, e.g. environment
+	// variable names and URL paths, shared by packages without an import
+	// relationship (models.ModuleMetrics.StringCouplings)
+	StringCoupling bool
+
+	// FailFast stops the analysis at the first gate violation that packages
+	// analyzed later cannot undo, and returns it as a *GateError. Violations of
+	// Thresholds always count, other findings if their severity is at least
+	// FailFastSeverity; without it, only the thresholds are gated.
+	FailFast         bool
+	FailFastSeverity models.Severity
 }
 
 // ModuleAnalyzer performs analysis on a Go module
 type ModuleAnalyzer struct {
 	modulePath     string
 	packageFilter  string
-	dependencies   map[string][]string // Package -> dependencies
-	reverseDepends map[string][]string // Package -> packages that depend on it
-	abstractTypes  map[string]int      // Package -> number of interfaces
-	totalTypes     map[string]int      // Package -> number of concrete types
+	dependencies   map[string][]string               // Package -> dependencies
+	reverseDepends map[string][]string               // Package -> packages that depend on it
+	abstractTypes  map[string]int                    // Package -> number of interfaces
+	totalTypes     map[string]int                    // Package -> number of concrete types
+	allTypes       map[string][2]int                 // Package -> interfaces and types, exported or not
+	embedding      map[string]embeddingCounts        // Package -> embedding statistics
+	methods        map[string]methodCounts           // Package -> method statistics
+	complexity     map[string]complexityCounts       // Package -> cyclomatic complexity of its functions
+	concurrency    map[string]concurrencyCounts      // Package -> use of concurrency primitives
+	constructors   map[string]constructorCounts      // Package -> constructor statistics
+	diFrameworks   map[string]string                 // Package -> dependency injection framework, if any
+	roles          map[string]string                 // Package -> architectural role
+	generated      map[string]generatedStats         // Package -> generated file statistics
+	structs        map[string]int                    // Package -> number of struct types
+	taggedStructs  map[string]int                    // Package -> number of structs with entity tags
+	deprecated     map[string][]deprecatedUse        // Package -> references to deprecated symbols of other packages
+	blank          map[string][]blankImport          // Package -> imports for side effects only
+	inits          map[string][]string               // Package -> registrations of its init functions
+	errorDecls     map[string][]errorDecl            // Package -> exported errors
+	errorChecks    map[string][]string               // Package -> errors of other packages it checks for
+	constantSets   map[string][]constantSet          // Package -> exported constant sets
+	constantShares map[string]float64                // Package -> share of exported declarations that are constants
+	constantRefs   map[string][]string               // Package -> constants of other packages it refers to
+	stringKeys     map[string][]string               // Package -> key-like string literals it uses
+	annotations    map[string][]string               // Package -> aid-metrics: annotations of its doc.go
+	mains          map[string]bool                   // Packages named main, which build binaries
+	endpoints      map[string][]endpointRegistration // Package -> registered HTTP/gRPC handlers
+	exposed        map[string][]string               // Package -> dependencies exposed in its exported API
+	leaks          map[string][]typeLeak             // Package -> types of other modules exposed in its exported API
+	interfaces     map[string][]methodSetDecl        // Package -> declared interfaces with their method sets
+	concreteTypes  map[string][]methodSetDecl        // Package -> declared concrete types with their method sets
+	synopses       map[string]string                 // Package -> first sentence of the package documentation
+	diagnostics    map[string][]models.Diagnostic    // Package -> errors loading it, set when it is loaded, and invalid annotations
+	names          map[string]string                 // Package or dependency -> unique display name, set by calculateMetrics
 
 	// Cache for the module path from go.mod
 	moduleName string
+
+	// Memoized content digests of packages used for result cache keys
+	digests sync.Map
+
+	// Deprecated symbols and init registrations of the imported packages
+	deprecations  packageIndex[map[string]bool]
+	registrations packageIndex[[]string]
 	
 	// Options for configuring analyzer behavior
 	options AnalyzerOptions
+
+	// Gate checking packages as they are analyzed, if FailFast is set
+	gate *failFastGate
+
+	// Metrics of the latest analysis, which AnalyzeIncremental can update
+	last *models.ModuleMetrics
 }
 
 // NewModuleAnalyzer creates a new ModuleAnalyzer
@@ -66,19 +219,57 @@ func NewModuleAnalyzerWithOptions(modulePath string, packageFilter string, optio
 	}
 	
 	analyzer := &ModuleAnalyzer{
-		modulePath:     modulePath,
-		packageFilter:  packageFilter,
-		dependencies:   make(map[string][]string),
-		reverseDepends: make(map[string][]string),
-		abstractTypes:  make(map[string]int),
-		totalTypes:     make(map[string]int),
-		moduleName:     readModuleName(modulePath),
-		options:        options,
+		modulePath:    modulePath,
+		packageFilter: packageFilter,
+		moduleName:    ReadModuleName(modulePath),
+		options:       options,
 	}
+	analyzer.resetResults()
 
 	return analyzer
 }
 
+// resetResults discards the results of previous analyses
+func (a *ModuleAnalyzer) resetResults() {
+	a.dependencies = make(map[string][]string)
+	a.reverseDepends = make(map[string][]string)
+	a.abstractTypes = make(map[string]int)
+	a.totalTypes = make(map[string]int)
+	a.allTypes = make(map[string][2]int)
+	a.embedding = make(map[string]embeddingCounts)
+	a.methods = make(map[string]methodCounts)
+	a.complexity = make(map[string]complexityCounts)
+	a.concurrency = make(map[string]concurrencyCounts)
+	a.constructors = make(map[string]constructorCounts)
+	a.diFrameworks = make(map[string]string)
+	a.roles = make(map[string]string)
+	a.generated = make(map[string]generatedStats)
+	a.structs = make(map[string]int)
+	a.taggedStructs = make(map[string]int)
+	a.deprecated = make(map[string][]deprecatedUse)
+	a.blank = make(map[string][]blankImport)
+	a.inits = make(map[string][]string)
+	a.errorDecls = make(map[string][]errorDecl)
+	a.errorChecks = make(map[string][]string)
+	a.constantSets = make(map[string][]constantSet)
+	a.constantShares = make(map[string]float64)
+	a.constantRefs = make(map[string][]string)
+	a.stringKeys = make(map[string][]string)
+	a.annotations = make(map[string][]string)
+	a.mains = make(map[string]bool)
+	a.endpoints = make(map[string][]endpointRegistration)
+	a.exposed = make(map[string][]string)
+	a.leaks = make(map[string][]typeLeak)
+	a.interfaces = make(map[string][]methodSetDecl)
+	a.concreteTypes = make(map[string][]methodSetDecl)
+	a.synopses = make(map[string]string)
+	a.diagnostics = make(map[string][]models.Diagnostic)
+	a.digests.Clear()
+	a.deprecations.reset()
+	a.registrations.reset()
+	a.last = nil
+}
+
 // AnalyzeModule analyzes a Go module and returns metrics
 func AnalyzeModule(modulePath string, packageFilter string) (*models.ModuleMetrics, error) {
 	analyzer := NewModuleAnalyzer(modulePath, packageFilter)
@@ -88,55 +279,82 @@ func AnalyzeModule(modulePath string, packageFilter string) (*models.ModuleMetri
 // AnalyzeModuleWithOptions analyzes a Go module with custom options and returns metrics.
 // This is the preferred method when progress reporting or custom configuration is needed.
 func AnalyzeModuleWithOptions(modulePath string, packageFilter string, options AnalyzerOptions) (*models.ModuleMetrics, error) {
+	return AnalyzeModuleContext(context.Background(), modulePath, packageFilter, options)
+}
+
+// AnalyzeModuleContext analyzes a Go module with custom options and returns metrics.
+// The analysis stops with the context's error when the context is canceled or its
+// deadline passes, so embedding programs can bound long analyses.
+func AnalyzeModuleContext(ctx context.Context, modulePath string, packageFilter string, options AnalyzerOptions) (*models.ModuleMetrics, error) {
 	analyzer := NewModuleAnalyzerWithOptions(modulePath, packageFilter, options)
-	return analyzer.Analyze()
+	return analyzer.AnalyzeContext(ctx)
 }
 
 // Analyze performs the full analysis
 func (a *ModuleAnalyzer) Analyze() (*models.ModuleMetrics, error) {
+	return a.AnalyzeContext(context.Background())
+}
+
+// AnalyzeContext performs the full analysis, stopping with the context's error
+// when the context is done. Package discovery, batch loading and the worker pool
+// all observe the context. With FailFast, the first gate violation ends the
+// analysis with a *GateError.
+func (a *ModuleAnalyzer) AnalyzeContext(ctx context.Context) (*models.ModuleMetrics, error) {
+	if a.options.FailFast {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithCancel(ctx)
+		defer cancel()
+		a.gate = newFailFastGate(a, cancel)
+		metrics, err := a.analyze(ctx)
+		if gateErr := a.gate.err(); gateErr != nil {
+			return nil, gateErr
+		}
+		return metrics, err
+	}
+	return a.analyze(ctx)
+}
+
+// analyze performs the analysis phases
+func (a *ModuleAnalyzer) analyze(ctx context.Context) (*models.ModuleMetrics, error) {
+	a.resetResults()
+
+	// Fast mode: build the dependency graph from import declarations only
+	if a.options.ImportsOnly {
+		if err := a.parseImportsOnly(ctx); err != nil {
+			return nil, fmt.Errorf("failed to parse imports: %w", err)
+		}
+		a.last = a.calculateMetrics()
+		return a.last, nil
+	}
+
 	// Step 1: Find all Go packages in the module
-	pkgs, err := a.findPackages()
+	pkgs, err := a.findPackages(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to find packages: %w", err)
 	}
 
 	// Step 2: Parse package dependencies and count types
-	err = a.parsePackages(pkgs)
+	err = a.parsePackages(ctx, pkgs)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse packages: %w", err)
 	}
 
 	// Step 3: Calculate metrics
-	metrics := a.calculateMetrics()
-	return metrics, nil
+	a.last = a.calculateMetrics()
+	return a.last, nil
 }
 
 // findPackages finds all Go packages in the module using discovery and batch loading
-func (a *ModuleAnalyzer) findPackages() ([]*packages.Package, error) {
-	// Initialize progress reporter if available
-	if a.options.ProgressReporter != nil {
-		a.options.ProgressReporter.SetTotal(100)
-	}
-	
-	// Phase 1: Discovery (0-10 on progress scale)
+func (a *ModuleAnalyzer) findPackages(ctx context.Context) ([]*packages.Package, error) {
+	// Phase 1: Discovery, of an unknown number of packages
 	if a.options.ProgressReporter != nil {
-		a.options.ProgressReporter.Update(0, "Discovering packages...")
+		a.options.ProgressReporter.StageStart(models.StageDiscovery, 0)
 	}
 	
 	// Progress callback for discovery
 	progressFunc := func(found int) {
 		if a.options.ProgressReporter != nil {
-			// Cap at 10 for discovery phase
-			progress := found / 3
-			if progress > 10 {
-				progress = 10
-			}
-			// Show count for large projects
-			if found > 100 {
-				a.options.ProgressReporter.Update(progress, fmt.Sprintf("Discovering packages... (%d found)", found))
-			} else {
-				a.options.ProgressReporter.Update(progress, "Discovering packages...")
-			}
+			a.options.ProgressReporter.Update(found, fmt.Sprintf("%d packages found", found))
 		}
 	}
 	
@@ -146,141 +364,289 @@ func (a *ModuleAnalyzer) findPackages() ([]*packages.Package, error) {
 		pattern = a.packageFilter
 	}
 	
-	packageInfos, err := discoverPackages(a.modulePath, a.moduleName, pattern, progressFunc)
+	packageInfos, err := discoverPackages(ctx, a.modulePath, a.moduleName, pattern, a.options.IncludeTests, progressFunc)
 	if err != nil {
 		return nil, fmt.Errorf("failed to discover packages: %w", err)
 	}
+	packageInfos = a.withoutExcluded(packageInfos)
 	
 	if len(packageInfos) == 0 {
-		if a.options.ProgressReporter != nil {
-			a.options.ProgressReporter.Complete()
-		}
-		return []*packages.Package{}, nil
+		return nil, a.noPackagesError(pattern, 0)
 	}
 	
 	// Update progress to show discovery complete
 	if a.options.ProgressReporter != nil {
-		a.options.ProgressReporter.Update(10, fmt.Sprintf("Found %d packages, starting to load...", len(packageInfos)))
-	}
-	
-	// Phase 2: Loading (10-80 on progress scale)
-	config := &packages.Config{
-		Mode: packages.NeedName | packages.NeedFiles | packages.NeedImports | packages.NeedDeps | packages.NeedTypes,
-		Dir:  a.modulePath,
+		a.options.ProgressReporter.Update(len(packageInfos), fmt.Sprintf("Found %d packages", len(packageInfos)))
+		a.options.ProgressReporter.StageDone(models.StageDiscovery)
 	}
 	
+	// Phase 2: Loading, reported by the batch loader
 	// Create batch loader
-	loader := NewBatchLoader(a.options.BatchSize, config, a.options.ProgressReporter, len(packageInfos))
+	loader := NewBatchLoader(a.options.BatchSize, a.packagesConfig(), a.options.ProgressReporter, len(packageInfos))
 	
 	// Load packages in batches
-	pkgs, err := loader.LoadPackages(packageInfos)
+	pkgs, err := loader.LoadPackagesContext(ctx, packageInfos)
 	if err != nil {
 		return nil, fmt.Errorf("failed to load packages: %w", err)
 	}
+	a.addDiagnostics(pkgs)
+	dropUnparsedFiles(pkgs)
+	if !hasGoFiles(pkgs) {
+		return nil, a.noPackagesError(pattern, len(packageInfos))
+	}
+	if a.options.IncludeTests {
+		pkgs = testVariants(pkgs)
+	}
 	
 	return pkgs, nil
 }
 
+// workers returns the number of packages to parse in parallel
+func (a *ModuleAnalyzer) workers() int {
+	if a.options.Workers > 0 {
+		return a.options.Workers
+	}
+	return runtime.GOMAXPROCS(0)
+}
+
+// packagesConfig returns the configuration used to load the packages to analyze
+func (a *ModuleAnalyzer) packagesConfig() *packages.Config {
+	config := &packages.Config{
+		Mode:  packages.NeedName | packages.NeedFiles | packages.NeedImports | packages.NeedDeps | packages.NeedTypes | packages.NeedSyntax,
+		Dir:   a.modulePath,
+		Env:   a.env(),
+		Tests: a.options.IncludeTests,
+	}
+	if len(a.options.BuildTags) > 0 {
+		config.BuildFlags = []string{"-tags=" + strings.Join(a.options.BuildTags, ",")}
+	}
+	return config
+}
+
 // Define a struct to hold the package analysis results
 type packageAnalysisResult struct {
-	packageID       string
-	dependencies    []string
-	abstractCount   int
-	totalTypesCount int
-	err             error
+	index            int // Position of the package in the analyzed list
+	packageID        string
+	dependencies     []string
+	exposed          []string
+	leaks            []typeLeak
+	interfaces       []methodSetDecl
+	concreteTypes    []methodSetDecl
+	abstractCount    int // Interfaces counted for A, only exported ones with ExportedOnly
+	totalTypesCount  int // Declarations counted for A, only exported ones with ExportedOnly
+	allAbstractCount int // Interfaces, exported or not
+	allTypesCount    int // Declarations, exported or not
+	embedding        embeddingCounts
+	methods          methodCounts
+	complexity       complexityCounts
+	concurrency      concurrencyCounts
+	constructors     constructorCounts
+	diFramework      string
+	role             string
+	generated        generatedStats
+	structCount      int
+	taggedStructs    int
+	deprecated       []deprecatedUse
+	blankImports     []blankImport
+	initRegistrations []string
+	main              bool
+	errorDecls        []errorDecl
+	errorChecks       []string
+	constantSets      []constantSet
+	constantShare     float64
+	constantRefs      []string
+	stringKeys        []string
+	endpoints        []endpointRegistration
+	synopsis         string
+	annotations      []string
+	diagnostics      []models.Diagnostic // Invalid annotations, and parse errors found without loading the package in imports-only mode
+	err              error
 }
 
-// parsePackages parses all Go packages to extract dependencies and count types
-func (a *ModuleAnalyzer) parsePackages(pkgs []*packages.Package) error {
-	// Phase 3: Analysis (80-100 on progress scale)
-	progressStart := 80
-	progressEnd := 100
-	progressRange := progressEnd - progressStart
-	packagesAnalyzed := 0
+// parsePackages parses all Go packages to extract dependencies and count types.
+//
+// Workers pull packages through a shared atomic cursor and accumulate their results
+// in a private shard, so no channel or lock sits between the workers and the results.
+// Once all workers are done, the shards are merged into the analyzer's maps in
+// package order, which keeps the merged state independent of scheduling.
+// When the context is done, workers stop picking up packages and its error is returned.
+func (a *ModuleAnalyzer) parsePackages(ctx context.Context, pkgs []*packages.Package) error {
+	// Phase 3: Parsing, one step per package
 	totalPackages := len(pkgs)
-	
-	// Create a worker pool with a reasonable number of workers
-	numWorkers := runtime.NumCPU()
-	if numWorkers > 8 {
-		numWorkers = 8 // Cap at 8 workers to avoid excessive goroutines
+	packagesAnalyzed := 0
+	var progressMu sync.Mutex
+	if a.options.ProgressReporter != nil {
+		a.options.ProgressReporter.StageStart(models.StageParsing, totalPackages)
 	}
 
-	// Create channels for input jobs and results
-	jobs := make(chan *packages.Package, len(pkgs))
-	results := make(chan packageAnalysisResult, len(pkgs))
+	// reportProgress updates the progress with the packages analyzed so far, counting
+	// one more if finished is set. Updates are serialized so the progress never goes back.
+	reportProgress := func(id string, finished bool) {
+		if a.options.ProgressReporter == nil {
+			return
+		}
+		progressMu.Lock()
+		defer progressMu.Unlock()
+		verb := "Parsing"
+		if finished {
+			packagesAnalyzed++
+			verb = "Analyzed"
+		}
+		// Use shorter path for display
+		shortPath := shortenPackagePath(a.getRelativePackagePath(id))
+		a.options.ProgressReporter.Update(packagesAnalyzed, fmt.Sprintf("%s %s (%d/%d)", verb, shortPath, packagesAnalyzed, totalPackages))
+	}
+
+	// Create a worker pool, never larger than the work
+	numWorkers := min(a.workers(), max(totalPackages, 1))
 
-	// Create a wait group to wait for all workers to finish
+	shards := make([]resultShard, numWorkers)
+	var next atomic.Int64
 	var wg sync.WaitGroup
 
-	// Start workers
-	for i := 0; i < numWorkers; i++ {
+	for w := range shards {
 		wg.Add(1)
-		go func() {
+		go func(shard *resultShard) {
 			defer wg.Done()
-			for pkg := range jobs {
-				// Process each package and send results through the channel
-				result := a.analyzePackage(pkg)
-				results <- result
+			for {
+				i := int(next.Add(1) - 1)
+				if i >= totalPackages || ctx.Err() != nil {
+					return
+				}
+				reportProgress(packageID(pkgs[i].ID), false)
+				result := a.analyzePackageCached(pkgs[i])
+				result.index = i
+				if a.gate != nil {
+					a.gate.add(result)
+				}
+				shard.results = append(shard.results, result)
+				reportProgress(packageID(pkgs[i].ID), true)
 			}
-		}()
+		}(&shards[w])
 	}
-
-	// Send all packages to be processed
-	for _, pkg := range pkgs {
-		jobs <- pkg
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return err
 	}
-	close(jobs) // No more jobs to send
-
-	// Create a goroutine to close the results channel when all workers are done
-	go func() {
-		wg.Wait()
-		close(results)
-	}()
 
-	// Process results in the main goroutine
-	for result := range results {
+	// Merge the shards in package order
+	ordered := make([]*packageAnalysisResult, totalPackages)
+	for w := range shards {
+		for i := range shards[w].results {
+			result := &shards[w].results[i]
+			ordered[result.index] = result
+		}
+	}
+	for _, result := range ordered {
 		if result.err != nil {
 			return result.err
 		}
+		a.storeResult(result)
+	}
 
-		// Store the analysis results in the maps
-		a.dependencies[result.packageID] = result.dependencies
+	if a.options.ProgressReporter != nil {
+		a.options.ProgressReporter.StageDone(models.StageParsing)
+	}
 
-		// Update reverse dependencies
-		for _, dep := range result.dependencies {
-			a.reverseDepends[dep] = append(a.reverseDepends[dep], result.packageID)
-		}
+	return nil
+}
 
-		a.abstractTypes[result.packageID] = result.abstractCount
-		a.totalTypes[result.packageID] = result.totalTypesCount
-		
-		// Update progress
-		packagesAnalyzed++
-		if a.options.ProgressReporter != nil {
-			progress := progressStart + (packagesAnalyzed * progressRange / totalPackages)
-			if progress > progressEnd {
-				progress = progressEnd
-			}
-			relPath := a.getRelativePackagePath(result.packageID)
-			// Use shorter path for display
-			shortPath := shortenPackagePath(relPath)
-			a.options.ProgressReporter.Update(progress, fmt.Sprintf("Analyzing %s", shortPath))
-		}
+// resultShard accumulates the analysis results of a single worker.
+// Each shard is owned by exactly one goroutine until all workers are done.
+type resultShard struct {
+	results []packageAnalysisResult
+}
+
+// storeResult stores the analysis results of a package in the analyzer's maps
+func (a *ModuleAnalyzer) storeResult(result *packageAnalysisResult) {
+	// Packages annotated to be ignored are left out like excluded ones
+	if isIgnored(result.annotations) {
+		return
 	}
-	
-	// Mark analysis complete
-	if a.options.ProgressReporter != nil {
-		a.options.ProgressReporter.Complete()
+	a.dependencies[result.packageID] = result.dependencies
+
+	// Update reverse dependencies
+	for _, dep := range result.dependencies {
+		a.reverseDepends[dep] = append(a.reverseDepends[dep], result.packageID)
 	}
 
-	return nil
+	a.abstractTypes[result.packageID] = result.abstractCount
+	a.totalTypes[result.packageID] = result.totalTypesCount
+	a.allTypes[result.packageID] = [2]int{result.allAbstractCount, result.allTypesCount}
+	a.embedding[result.packageID] = result.embedding
+	a.methods[result.packageID] = result.methods
+	a.complexity[result.packageID] = result.complexity
+	a.concurrency[result.packageID] = result.concurrency
+	a.constructors[result.packageID] = result.constructors
+	if result.diFramework != "" {
+		a.diFrameworks[result.packageID] = result.diFramework
+	}
+	a.roles[result.packageID] = result.role
+	a.generated[result.packageID] = result.generated
+	a.structs[result.packageID] = result.structCount
+	a.taggedStructs[result.packageID] = result.taggedStructs
+	if len(result.deprecated) > 0 {
+		a.deprecated[result.packageID] = result.deprecated
+	}
+	if len(result.blankImports) > 0 {
+		a.blank[result.packageID] = result.blankImports
+	}
+	if len(result.initRegistrations) > 0 {
+		a.inits[result.packageID] = result.initRegistrations
+	}
+	if result.main {
+		a.mains[result.packageID] = true
+	}
+	if len(result.errorDecls) > 0 {
+		a.errorDecls[result.packageID] = result.errorDecls
+	}
+	if len(result.errorChecks) > 0 {
+		a.errorChecks[result.packageID] = result.errorChecks
+	}
+	if len(result.constantSets) > 0 {
+		a.constantSets[result.packageID] = result.constantSets
+		a.constantShares[result.packageID] = result.constantShare
+	}
+	if len(result.constantRefs) > 0 {
+		a.constantRefs[result.packageID] = result.constantRefs
+	}
+	if len(result.stringKeys) > 0 {
+		a.stringKeys[result.packageID] = result.stringKeys
+	}
+	if len(result.endpoints) > 0 {
+		a.endpoints[result.packageID] = result.endpoints
+	}
+	if len(result.exposed) > 0 {
+		a.exposed[result.packageID] = result.exposed
+	}
+	if len(result.leaks) > 0 {
+		a.leaks[result.packageID] = result.leaks
+	}
+	if len(result.interfaces) > 0 {
+		a.interfaces[result.packageID] = result.interfaces
+	}
+	if len(result.concreteTypes) > 0 {
+		a.concreteTypes[result.packageID] = result.concreteTypes
+	}
+	if result.synopsis != "" {
+		a.synopses[result.packageID] = result.synopsis
+	}
+	if len(result.annotations) > 0 {
+		a.annotations[result.packageID] = result.annotations
+	}
+	// The errors of loading the package were recorded when it was loaded
+	for _, diagnostic := range result.diagnostics {
+		if !slices.Contains(a.diagnostics[result.packageID], diagnostic) {
+			a.diagnostics[result.packageID] = append(a.diagnostics[result.packageID], diagnostic)
+		}
+	}
 }
 
 // analyzePackage analyzes a single package but doesn't modify shared maps
 // Instead, it returns the analysis results to be processed by the main goroutine
 func (a *ModuleAnalyzer) analyzePackage(pkg *packages.Package) packageAnalysisResult {
 	result := packageAnalysisResult{
-		packageID: pkg.ID,
+		packageID: packageID(pkg.ID),
 	}
 
 	// Skip standard library packages
@@ -296,47 +662,110 @@ func (a *ModuleAnalyzer) analyzePackage(pkg *packages.Package) packageAnalysisRe
 		if isStandardLibraryPackage(imp.ID, a.moduleName) || strings.HasPrefix(imp.ID, "vendor/") {
 			continue
 		}
-		deps = append(deps, imp.ID)
+		deps = append(deps, packageID(imp.ID))
+	}
+	if a.options.SkipGeneratedImports {
+		deps = handwrittenDependencies(pkg, deps)
 	}
 	result.dependencies = deps
+	result.exposed = exposedDependencies(pkg, deps)
+	result.leaks = leakedTypes(pkg, a.moduleName)
+	result.diFramework = detectDIFramework(pkg)
+	result.role = classifyRole(pkg, a.getRelativePackagePath(result.packageID), a.options.RoleRules)
+
+	// Abstract and concrete declarations come from the type checker, the
+	// heuristics below from the syntax of the package files
+	var skip func(types.Object) bool
+	if a.options.SkipGenerated || a.options.SkipGeneratedImports {
+		skip = declaredInGenerated(pkg)
+	}
+	counts := countTypes(pkg.Types, false, skip)
+	published := counts
+	if a.options.ExportedOnly {
+		published = countTypes(pkg.Types, true, skip)
+	}
+	var embedding embeddingCounts
+	var methods methodCounts
+	var complexity complexityCounts
+	var concurrency concurrencyCounts
+	var generated generatedStats
+	var taggedStructs int
+	var constructors []constructorDecl
+	localInterfaces := make(map[string]bool)
+	mockTypes := make(map[string]bool)
+	var synopsis synopsisPicker
+
+	for _, file := range pkg.Syntax {
+		generated.add(file)
+		filename := pkg.Fset.File(file.Pos()).Name()
+		synopsis.add(filename, file)
+		annotations, invalid := a.fileAnnotations(pkg.Fset, filename, file)
+		result.annotations = append(result.annotations, annotations...)
+		result.diagnostics = append(result.diagnostics, invalid...)
+		generator, _ := generatedBy(file)
+		mockFile := mockGenerators[generator]
+		result.endpoints = append(result.endpoints, findEndpoints(file, pkg)...)
+		imports := fileImports(file, pkg)
 
-	// Parse the package files to count abstract and concrete types
-	var abstractCount, concreteCount int
-	var funcCount int
-	fset := token.NewFileSet()
-
-	for _, filePath := range pkg.GoFiles {
-		// Parse the file
-		file, err := parser.ParseFile(fset, filePath, nil, parser.AllErrors)
-		if err != nil {
-			result.err = fmt.Errorf("failed to parse file %s: %w", filePath, err)
-			return result
+		for _, spec := range typeSpecs(file) {
+			switch t := spec.Type.(type) {
+			case *ast.InterfaceType:
+				localInterfaces[spec.Name.Name] = true
+			case *ast.StructType:
+				if spec.Assign.IsValid() {
+					continue
+				}
+				if hasEntityTags(t) {
+					taggedStructs++
+				}
+				if mockFile {
+					mockTypes[spec.Name.Name] = true
+				}
+			}
 		}
 
-		// Count types and functions
 		ast.Inspect(file, func(n ast.Node) bool {
+			concurrency.count(n, imports)
 			switch t := n.(type) {
-			case *ast.TypeSpec:
-				if _, ok := t.Type.(*ast.InterfaceType); ok {
-					abstractCount++
-				} else if _, ok := t.Type.(*ast.StructType); ok {
-					// Only count structs as concrete types
-					concreteCount++
-				}
-				// Other types (like type aliases) are not counted
 			case *ast.FuncDecl:
-				// Count only standalone functions (not methods)
+				complexity.countFunction(t)
 				if t.Recv == nil {
-					funcCount++
+					if isConstructor(t) {
+						constructors = append(constructors, newConstructorDecl(t))
+					}
+				} else {
+					methods.countMethod(t)
 				}
+			case *ast.StructType, *ast.InterfaceType:
+				embedding.countEmbedding(t)
 			}
 			return true
 		})
 	}
 
-	result.abstractCount = abstractCount
-	// Include only structs and standalone functions as concrete types
-	result.totalTypesCount = abstractCount + concreteCount + funcCount
+	result.abstractCount = published.interfaces
+	result.totalTypesCount = published.total()
+	result.allAbstractCount = counts.interfaces
+	result.allTypesCount = counts.total()
+	result.embedding = embedding
+	result.methods = methods
+	result.complexity = complexity
+	result.concurrency = concurrency
+	result.generated = generated
+	result.structCount = counts.structs
+	result.taggedStructs = taggedStructs
+	result.deprecated = a.deprecatedUses(pkg)
+	result.blankImports = a.blankImports(pkg)
+	result.initRegistrations = initRegistrations(pkg)
+	result.main = pkg.Name == "main"
+	result.errorDecls = exportedErrors(pkg)
+	result.errorChecks = errorChecks(pkg)
+	result.constantSets, result.constantShare = constantSets(pkg)
+	result.constantRefs = constantRefs(pkg)
+	result.stringKeys = stringKeys(pkg)
+	result.constructors = countConstructors(pkg, constructors, localInterfaces)
+	result.interfaces, result.concreteTypes = interfaceDecls(pkg, mockTypes)
+	result.synopsis = synopsis.synopsis
 
 	return result
 }
@@ -372,8 +801,9 @@ func isStandardLibraryPackage(pkgID, mainModulePath string) bool {
 	return false
 }
 
-// readModuleName reads the module name from the go.mod file
-func readModuleName(modulePath string) string {
+// ReadModuleName reads the module name from the go.mod file in modulePath.
+// It returns an empty string if there is no go.mod file or module declaration.
+func ReadModuleName(modulePath string) string {
 	goModPath := filepath.Join(modulePath, "go.mod")
 	content, err := os.ReadFile(goModPath)
 	if err != nil {
@@ -401,11 +831,36 @@ func (a *ModuleAnalyzer) calculateMetrics() *models.ModuleMetrics {
 		Packages: make(map[string]models.PackageMetrics),
 	}
 
+	a.assignDisplayNames()
+	ownership := a.classifyInterfaces()
+	errorCheckers := a.errorCheckers()
+	constantReferrers := a.constantReferrers()
+
 	for pkg := range a.dependencies {
 		ca := len(a.reverseDepends[pkg])
 		ce := len(a.dependencies[pkg])
 		na := a.abstractTypes[pkg]
 		nc := a.totalTypes[pkg]
+		all := a.allTypes[pkg]
+		embedding := a.embedding[pkg]
+		methods := a.methods[pkg]
+		complexity := a.complexity[pkg]
+		coverage, covered := a.options.Coverage[pkg]
+		constructors := a.constructors[pkg]
+		diFramework := a.diFrameworks[pkg]
+		role := a.roles[pkg]
+		if role == "" {
+			role = models.RoleOther
+		}
+		generated := a.generated[pkg]
+		deprecated, deprecatedUses := newDeprecatedUses(a.deprecated[pkg])
+		errorTargets, errorDependents := a.errorTargets(pkg, errorCheckers)
+		constantSets, constantDependents := a.constantSharing(pkg, constantReferrers)
+		constantShare := a.constantShares[pkg]
+		gateExemptReason := a.gateExemptReason(generated)
+		if gateExemptReason == "" {
+			gateExemptReason = a.entryPointExemptReason(role, diFramework != "")
+		}
 
 		// Calculate instability (I)
 		instability := 0.0
@@ -422,8 +877,15 @@ func (a *ModuleAnalyzer) calculateMetrics() *models.ModuleMetrics {
 		// Calculate distance from main sequence (D)
 		distance := math.Abs(abstractness + instability - 1.0)
 
+		// With ExportedOnly, A is the published abstractness; keep the full one next to it
+		allAbstractness := 0.0
+		if all[1] > 0 {
+			allAbstractness = float64(all[0]) / float64(all[1])
+		}
+
 		metrics.Packages[pkg] = models.PackageMetrics{
-			Name:         a.getRelativePackagePath(pkg),
+			Name:         a.displayName(pkg),
+			Synopsis:     a.synopses[pkg],
 			Ca:           ca,
 			Ce:           ce,
 			Na:           na,
@@ -431,12 +893,124 @@ func (a *ModuleAnalyzer) calculateMetrics() *models.ModuleMetrics {
 			Instability:  instability,
 			Abstractness: abstractness,
 			Distance:     distance,
+
+			NaAll:           all[0],
+			NcAll:           all[1],
+			AbstractnessAll: allAbstractness,
+
+			StructEmbeds:    embedding.structEmbeds,
+			InterfaceEmbeds: embedding.interfaceEmbeds,
+			EmbeddingRatio:  embedding.ratio(),
+
+			Methods:           methods.total(),
+			PointerMethods:    methods.pointer,
+			ValueMethods:      methods.value,
+			ExportedMethods:   methods.exported,
+			UnexportedMethods: methods.unexported,
+
+			Constructors:              constructors.total,
+			InterfaceConstructors:     constructors.returnsInterface,
+			InterfaceConstructorRatio: constructors.ratio(),
+
+			Complexity:    complexity.mean(),
+			MaxComplexity: complexity.max,
+			Coverage:      coverage,
+			HasCoverage:   covered,
+
+			CompositionRoot: diFramework != "",
+			DIFramework:     diFramework,
+
+			Role: role,
+
+			Dependencies: a.displayNames(a.dependencies[pkg]),
+			Dependents:   a.displayNames(a.reverseDepends[pkg]),
+
+			ExternalImports: a.externalImports(a.dependencies[pkg]),
+
+			CeExported:          len(a.exposed[pkg]),
+			CeInternal:          ce - len(a.exposed[pkg]),
+			ExposedDependencies: a.displayNames(a.exposed[pkg]),
+			LeakedTypes:         newTypeLeaks(a.leaks[pkg]),
+
+			ConsumerInterfaces: ownership[pkg].consumer,
+			ProviderInterfaces: ownership[pkg].provider,
+			HeaderInterfaces:   ownership[pkg].headers,
+
+			Mocks:              a.countMocks(pkg),
+			MockedInterfaces:   ownership[pkg].mocked,
+			MockOnlyDependents: a.mockOnlyDependents(pkg),
+
+			DeprecatedUses:    deprecatedUses,
+			DeprecatedSymbols: deprecated,
+
+			BlankImports:      blankImportPaths(a.blank[pkg]),
+			InitRegistrations: a.inits[pkg],
+
+			ErrorTargets:    errorTargets,
+			ErrorDependents: errorDependents,
+
+			ConstantSets:       constantSets,
+			ConstantShare:      constantShare,
+			ConstantDependents: constantDependents,
+			SharedKernel:       isSharedKernel(constantShare, constantDependents),
+
+			TaggedStructs: a.taggedStructs[pkg],
+			DataBag:       isDataBag(a.taggedStructs[pkg], a.structs[pkg], abstractness),
+
+			Generator:        generated.generator(),
+			GateExempt:       gateExemptReason != "",
+			GateExemptReason: gateExemptReason,
+
+			Annotations: a.annotations[pkg],
+			Partial:     isPartial(a.diagnostics[pkg]),
 		}
 	}
 
+	if a.options.Concurrency {
+		a.addConcurrency(metrics)
+	}
+	TransitiveCoupling(metrics)
+	metrics.ExportedOnly = a.options.ExportedOnly
+	metrics.Roles = SummarizeRoles(metrics.Packages)
+	metrics.Endpoints = a.buildEndpoints()
+	metrics.SideEffects = a.sideEffects(metrics)
+	if a.options.StringCoupling {
+		metrics.StringCouplings = a.stringCoupling(metrics)
+	}
+	metrics.Cycles = a.findCycles()
+	metrics.Names = a.nameTable()
+	metrics.Findings = a.collectFindings(metrics)
+	metrics.Diagnostics = a.collectDiagnostics()
+	addDebtPoints(metrics)
+	ScoreHealth(metrics, a.options)
+	ClassifyZones(metrics, a.options)
+	metrics.Summary = Summarize(metrics.Packages)
+
 	return metrics
 }
 
+// displayNames converts package IDs into sorted display names
+func (a *ModuleAnalyzer) displayNames(ids []string) []string {
+	names := make([]string, 0, len(ids))
+	for _, id := range ids {
+		names = append(names, a.displayName(id))
+	}
+	sort.Strings(names)
+	return names
+}
+
+// externalImports returns the sorted dependencies that are not module packages
+func (a *ModuleAnalyzer) externalImports(ids []string) []string {
+	var imports []string
+	for _, id := range ids {
+		if !inModule(id, a.moduleName) {
+			imports = append(imports, id)
+		}
+	}
+	sort.Strings(imports)
+	return imports
+}
+
 // getRelativePackagePath extracts the import path relative to the module name
 func (a *ModuleAnalyzer) getRelativePackagePath(importPath string) string {
 	// Use the cached module path if available
@@ -1,7 +1,26 @@
 package analyzer
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/alkbt/aid-metrics/pkg/models"
+	"golang.org/x/tools/go/packages"
 )
 
 func TestGetPackageName(t *testing.T) {
@@ -67,7 +86,6 @@ func TestTypesCounting(t *testing.T) {
 
 	// Verify that the abstactness is calculated correctly
 	// A = Na / Nc = 2 / (2+3+4) = 2/9 = 0.222...
-	// Note: Only structs and standalone functions are counted as concrete, not other types
 	expectedAbstractness := float64(abstractCount) / float64(abstractCount+concreteCount+funcCount)
 
 	// Package should be added by calculateMetrics
@@ -88,3 +106,2106 @@ func TestTypesCounting(t *testing.T) {
 		t.Errorf("Expected Nc to be %v, got %v", abstractCount+concreteCount+funcCount, pkg.Nc)
 	}
 }
+
+func TestTypeCheckerCounts(t *testing.T) {
+	src := `package sample
+
+import "io"
+
+type Reader interface{ Read() }
+type Closer interface{ io.Closer }
+
+// Aliases declare no type of their own
+type ReadCloser = io.ReadCloser
+type Legacy = struct{ ID int }
+
+type ID int
+type HandlerFunc func()
+
+type Store struct{ io.Reader }
+type Snapshot Store
+
+func New() *Store {
+	type local struct{}
+	return nil
+}
+
+func (s *Store) Get() {}
+`
+	result := NewModuleAnalyzer("", "").analyzePackage(newTestPackage(t, "example.com/sample", src))
+	if result.err != nil {
+		t.Fatal(result.err)
+	}
+
+	// Na: Reader, Closer. Nc adds Store, Snapshot (structs), ID, HandlerFunc and New
+	if result.abstractCount != 2 || result.structCount != 2 || result.totalTypesCount != 7 {
+		t.Errorf("expected Na=2, 2 structs and Nc=7, got Na=%d, %d structs and Nc=%d",
+			result.abstractCount, result.structCount, result.totalTypesCount)
+	}
+}
+
+func TestExportedOnly(t *testing.T) {
+	dir := writeModule(t, map[string]string{
+		"go.mod":         "module example.com/api\n\ngo 1.21\n",
+		"store/store.go": "package store\n\ntype Store interface{ Get() }\n\ntype cache struct{}\n\ntype entry struct{}\n\nfunc New() Store { return nil }\n\nfunc hash() {}\n",
+		"app/app.go":     "package app\n\nimport _ \"example.com/api/store\"\n",
+	})
+
+	metrics, err := AnalyzeModuleWithOptions(dir, "./...", AnalyzerOptions{ExportedOnly: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !metrics.ExportedOnly {
+		t.Error("expected the metrics to be marked as exported-only")
+	}
+
+	// Store and New are exported; cache, entry and hash are not
+	store := metrics.Packages["example.com/api/store"]
+	if store.Na != 1 || store.Nc != 2 || store.Abstractness != 0.5 || store.Distance != 0.5 {
+		t.Errorf("expected the published Na=1, Nc=2, A=0.5 and D=0.5, got Na=%d, Nc=%d, A=%.2f and D=%.2f",
+			store.Na, store.Nc, store.Abstractness, store.Distance)
+	}
+	if store.NaAll != 1 || store.NcAll != 5 || store.AbstractnessAll != 0.2 {
+		t.Errorf("expected NaAll=1, NcAll=5 and A=0.2 of all declarations, got %d, %d and %.2f",
+			store.NaAll, store.NcAll, store.AbstractnessAll)
+	}
+}
+
+func TestNoPackagesFound(t *testing.T) {
+	dir := writeModule(t, map[string]string{
+		"go.mod":         "module example.com/api\n\ngo 1.21\n",
+		"tools/tools.go": "//go:build tools\n\npackage tools\n",
+	})
+
+	for _, tc := range []struct {
+		pattern    string
+		options    AnalyzerOptions
+		discovered int
+	}{
+		{"./tools/...", AnalyzerOptions{}, 1},
+		{"./missing/...", AnalyzerOptions{}, 0},
+		{"./missing/...", AnalyzerOptions{ImportsOnly: true}, 0},
+	} {
+		_, err := AnalyzeModuleWithOptions(dir, tc.pattern, tc.options)
+		if !errors.Is(err, ErrNoPackagesFound) {
+			t.Errorf("%s: expected ErrNoPackagesFound, got %v", tc.pattern, err)
+			continue
+		}
+		var noPackages *NoPackagesError
+		if !errors.As(err, &noPackages) || noPackages.Pattern != tc.pattern || noPackages.Discovered != tc.discovered ||
+			len(noPackages.Roots) != 1 || !strings.HasPrefix(noPackages.Roots[0], dir) {
+			t.Errorf("%s: unexpected error details %+v", tc.pattern, noPackages)
+		}
+	}
+
+	// The build tag selects the package
+	metrics, err := AnalyzeModuleWithOptions(dir, "./tools/...", AnalyzerOptions{BuildTags: []string{"tools"}})
+	if err != nil || len(metrics.Packages) != 1 {
+		t.Errorf("expected the tools package with -tags tools, got %v", err)
+	}
+}
+
+func TestSkipGenerated(t *testing.T) {
+	dir := writeModule(t, map[string]string{
+		"go.mod":         "module example.com/api\n\ngo 1.21\n",
+		"pb/pb.go":       "package pb\n\ntype Message struct{}\n",
+		"store/store.go": "package store\n\nimport _ \"example.com/api/pb\"\n\ntype Store interface{ Get() }\n",
+		"store/store.pb.go": "// Code generated by protoc-gen-go. DO NOT EDIT.\n\npackage store\n\n" +
+			"import _ \"example.com/api/pb\"\nimport _ \"example.com/api/wire\"\n\ntype Request struct{}\n\ntype Reply struct{}\n\nfunc file_init() {}\n",
+		"wire/wire.go": "package wire\n",
+	})
+
+	for _, tc := range []struct {
+		name    string
+		options AnalyzerOptions
+		nc, ce  int
+	}{
+		{"default", AnalyzerOptions{}, 4, 2},
+		{"skip-generated", AnalyzerOptions{SkipGenerated: true}, 1, 2},
+		{"skip-generated-imports", AnalyzerOptions{SkipGenerated: true, SkipGeneratedImports: true}, 1, 1},
+	} {
+		metrics, err := AnalyzeModuleWithOptions(dir, "./...", tc.options)
+		if err != nil {
+			t.Fatal(err)
+		}
+		store := metrics.Packages["example.com/api/store"]
+		if store.Nc != tc.nc || store.Ce != tc.ce {
+			t.Errorf("%s: expected Nc=%d and Ce=%d, got Nc=%d and Ce=%d (dependencies %v)",
+				tc.name, tc.nc, tc.ce, store.Nc, store.Ce, store.Dependencies)
+		}
+	}
+}
+
+func TestDeprecatedUses(t *testing.T) {
+	dir := writeModule(t, map[string]string{
+		"go.mod": "module example.com/shop\n\ngo 1.21\n",
+		"legacy/legacy.go": "package legacy\n\n// Old does it the old way.\n//\n// Deprecated: use New.\nfunc Old() {}\n\nfunc New() {}\n\n" +
+			"// Deprecated: use the options.\nconst (\n\tModeA = 1\n\tModeB = 2\n)\n",
+		"app/app.go": "package app\n\nimport (\n\t\"io/ioutil\"\n\n\told \"example.com/shop/legacy\"\n)\n\n" +
+			"func Run() int {\n\told.Old()\n\told.Old()\n\told.New()\n\t_, _ = ioutil.ReadAll(nil)\n\treturn old.ModeB\n}\n",
+	})
+
+	metrics, err := AnalyzeModule(dir, "./...")
+	if err != nil {
+		t.Fatal(err)
+	}
+	app := metrics.Packages["example.com/shop/app"]
+	var got []string
+	for _, use := range app.DeprecatedSymbols {
+		got = append(got, fmt.Sprintf("%s.%s=%d", use.Package, use.Symbol, use.Uses))
+	}
+	want := "example.com/shop/legacy.ModeB=1 example.com/shop/legacy.Old=2 io/ioutil.ReadAll=1"
+	if app.DeprecatedUses != 4 || strings.Join(got, " ") != want {
+		t.Errorf("expected 4 uses of %s, got %d of %s", want, app.DeprecatedUses, strings.Join(got, " "))
+	}
+
+	var found bool
+	for _, finding := range metrics.Findings {
+		found = found || (finding.Category == models.CategoryDeprecated && finding.Package == app.Name)
+	}
+	if !found {
+		t.Errorf("expected a deprecated finding for %s, got %v", app.Name, metrics.Findings)
+	}
+}
+
+func TestSideEffects(t *testing.T) {
+	dir := writeModule(t, map[string]string{
+		"go.mod":           "module example.com/shop\n\ngo 1.21\n",
+		"driver/driver.go": "package driver\n\nimport \"database/sql\"\n\nfunc init() {\n\tsql.Register(\"fake\", nil)\n}\n",
+		"store/store.go":   "package store\n\nimport (\n\t_ \"embed\"\n\n\t_ \"example.com/shop/driver\"\n)\n\nfunc Open() {}\n",
+		"cmd/api/main.go":  "package main\n\nimport \"example.com/shop/store\"\n\nfunc main() { store.Open() }\n",
+		"cmd/tool/main.go": "package main\n\nfunc main() {}\n",
+	})
+
+	metrics, err := AnalyzeModule(dir, "./...")
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := metrics.Packages["example.com/shop/store"]
+	if strings.Join(store.BlankImports, " ") != "example.com/shop/driver" {
+		t.Errorf("expected store to blank import only the driver, got %v", store.BlankImports)
+	}
+	driver := metrics.Packages["example.com/shop/driver"]
+	if strings.Join(driver.InitRegistrations, " ") != "database/sql.Register" {
+		t.Errorf("expected the driver to register with database/sql, got %v", driver.InitRegistrations)
+	}
+
+	if len(metrics.SideEffects) != 1 {
+		t.Fatalf("expected 1 side effect, got %+v", metrics.SideEffects)
+	}
+	effect := metrics.SideEffects[0]
+	if effect.Package != "example.com/shop/driver" ||
+		strings.Join(effect.Registrations, " ") != "database/sql.Register" ||
+		strings.Join(effect.BlankImportedBy, " ") != store.Name ||
+		strings.Join(effect.Binaries, " ") != metrics.Packages["example.com/shop/cmd/api"].Name {
+		t.Errorf("unexpected side effect %+v", effect)
+	}
+}
+
+func TestTransitiveCoupling(t *testing.T) {
+	// api -> service -> store -> github.com/lib/pq, with cli -> service and a
+	// cycle between store and cache
+	metrics := &models.ModuleMetrics{Packages: map[string]models.PackageMetrics{
+		"m/api":     {Name: "api", Dependencies: []string{"service"}},
+		"m/cli":     {Name: "cli", Dependencies: []string{"service"}},
+		"m/service": {Name: "service", Dependencies: []string{"store"}, Dependents: []string{"api", "cli"}},
+		"m/store":   {Name: "store", Dependencies: []string{"cache", "github.com/lib/pq"}, Dependents: []string{"cache", "service"}},
+		"m/cache":   {Name: "cache", Dependencies: []string{"store"}, Dependents: []string{"store"}},
+	}}
+	TransitiveCoupling(metrics)
+
+	for name, want := range map[string][2]int{
+		"api":     {0, 4},
+		"cli":     {0, 4},
+		"service": {2, 3},
+		"store":   {4, 2},
+		"cache":   {4, 2},
+	} {
+		pkg := metrics.Packages["m/"+name]
+		if got := [2]int{pkg.TransitiveCa, pkg.TransitiveCe}; got != want {
+			t.Errorf("%s: expected transitive Ca, Ce %v, got %v", name, want, got)
+		}
+	}
+}
+
+func TestDependencyRules(t *testing.T) {
+	dir := writeModule(t, map[string]string{
+		"go.mod":            "module example.com/shop\n\ngo 1.21\n\nrequire golang.org/x/sync v0.1.0\n",
+		"db/db.go":          "package db\n\nfunc Query() {}\n",
+		"ui/ui.go":          "package ui\n\nimport \"example.com/shop/db\"\n\nfunc Render() { db.Query() }\n",
+		"domain/domain.go":  "package domain\n\nimport (\n\t\"strings\"\n\n\t\"example.com/shop/db\"\n)\n\nfunc Name() string { db.Query(); return strings.ToUpper(\"x\") }\n",
+		"adapters/queue.go": "package adapters\n\nimport \"example.com/shop/db\"\n\nfunc Publish() { db.Query() }\n",
+	})
+
+	rules := []DependencyRule{
+		{From: []string{"ui/..."}, Deny: []string{"db/..."}},
+		{Name: "domain stays pure", From: []string{"domain"}, Allow: []string{"domain/..."}},
+		{Except: []string{"adapters/...", "ui"}, Deny: []string{"db"}},
+	}
+	metrics, err := AnalyzeModuleWithOptions(dir, "./...", AnalyzerOptions{Rules: rules})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, finding := range metrics.Findings {
+		if finding.Category == models.CategoryRule {
+			got = append(got, finding.Package+": "+finding.Message)
+		}
+	}
+	want := []string{
+		`domain: violates rule "domain stays pure" by importing db`,
+		`domain: violates rule "all packages except adapters/..., ui may not import db" by importing db`,
+		`ui: violates rule "ui/... may not import db/..." by importing db`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("expected rule findings\n%s\ngot\n%s", strings.Join(want, "\n"), strings.Join(got, "\n"))
+	}
+}
+
+func TestRuleEnforcement(t *testing.T) {
+	dir := writeModule(t, map[string]string{
+		"go.mod":   "module example.com/shop\n\ngo 1.21\n",
+		"db/db.go": "package db\n\nfunc Query() {}\n",
+		"ui/ui.go": "package ui\n\nimport \"example.com/shop/db\"\n\nfunc Render() { db.Query() }\n",
+	})
+
+	past := time.Now().AddDate(0, 0, -1)
+	future := time.Now().AddDate(0, 1, 0)
+	rules := []DependencyRule{
+		{Name: "enforced", From: []string{"ui"}, Deny: []string{"db"}},
+		{Name: "off", From: []string{"ui"}, Deny: []string{"db"}, Enforcement: RuleOff},
+		{Name: "warned", From: []string{"ui"}, Deny: []string{"db"}, Enforcement: RuleWarn},
+		{Name: "scheduled", From: []string{"ui"}, Deny: []string{"db"}, Enforcement: RuleWarn, EnforceFrom: future},
+		{Name: "promoted", From: []string{"ui"}, Deny: []string{"db"}, Enforcement: RuleWarn, EnforceFrom: past},
+	}
+	for _, rule := range rules {
+		if err := rule.Validate(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := (DependencyRule{Deny: []string{"db"}, EnforceFrom: future}).Validate(); err == nil {
+		t.Error("expected an enforcement date without warn to be invalid")
+	}
+	if err := (DependencyRule{Deny: []string{"db"}, Enforcement: "strict"}).Validate(); err == nil {
+		t.Error("expected an unknown enforcement to be invalid")
+	}
+
+	metrics, err := AnalyzeModuleWithOptions(dir, "./...", AnalyzerOptions{Rules: rules})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, finding := range metrics.Findings {
+		if finding.Category == models.CategoryRule {
+			got = append(got, fmt.Sprintf("%s advisory=%v points=%d", finding.Message, finding.Advisory, finding.Points))
+		}
+	}
+	want := []string{
+		`violates rule "enforced" by importing db advisory=false points=10`,
+		`violates rule "warned" by importing db (warning only) advisory=true points=0`,
+		`violates rule "scheduled" by importing db (warning until ` + future.Format(time.DateOnly) + `) advisory=true points=0`,
+		`violates rule "promoted" by importing db advisory=false points=10`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("expected rule findings\n%s\ngot\n%s", strings.Join(want, "\n"), strings.Join(got, "\n"))
+	}
+	gated := 0
+	for _, finding := range FindingsAtLeast(metrics.Findings, models.SeverityInfo) {
+		if finding.Category == models.CategoryRule {
+			gated++
+		}
+	}
+	if gated != 2 {
+		t.Errorf("expected the 2 enforced rule findings to be gated, got %d", gated)
+	}
+}
+
+func TestAnnotations(t *testing.T) {
+	dir := writeModule(t, map[string]string{
+		"go.mod":               "module example.com/shop\n\ngo 1.21\n",
+		"store/doc.go":         "// Package store persists orders.\n//\n//aid-metrics:max-distance=1 stable on purpose\npackage store\n",
+		"store/store.go":       "package store\n\ntype Order struct{}\n",
+		"cache/cache.go":       "package cache\n\ntype Entry struct{}\n",
+		"api/api.go":           "package api\n\nimport (\n\t\"example.com/shop/cache\"\n\t\"example.com/shop/store\"\n)\n\nvar _ = store.Order{}\nvar _ = cache.Entry{}\n",
+		"fixtures/doc.go":      "//aid-metrics:ignore test data\npackage fixtures\n",
+		"fixtures/fixtures.go": "package fixtures\n\nimport \"example.com/shop/store\"\n\nvar _ = store.Order{}\n",
+	})
+
+	opts := AnalyzerOptions{Thresholds: &models.Thresholds{MaxDistance: 0.5, MaxInstability: 1}}
+	metrics, err := AnalyzeModuleWithOptions(dir, "./...", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := metrics.Packages["example.com/shop/fixtures"]; ok {
+		t.Error("expected the ignored package to be left out")
+	}
+	store := metrics.Packages["example.com/shop/store"]
+	if store.Ca != 1 || strings.Join(store.Annotations, " ") != "max-distance=1" {
+		t.Errorf("expected store with Ca=1 and annotation max-distance=1, got Ca=%d and %v", store.Ca, store.Annotations)
+	}
+	var violations []string
+	for _, finding := range metrics.Findings {
+		if finding.Category == models.CategoryThreshold {
+			violations = append(violations, finding.Package)
+		}
+	}
+	if strings.Join(violations, " ") != "cache" {
+		t.Errorf("expected a threshold violation for cache only, got %v", violations)
+	}
+
+	writeFiles(t, dir, map[string]string{"cache/doc.go": "//aid-metrics:allow=AM004 tolerated until the cache is split\npackage cache\n"})
+	metrics, err = AnalyzeModuleWithOptions(dir, "./...", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, finding := range metrics.Findings {
+		if finding.Category == models.CategoryThreshold {
+			t.Errorf("expected the allowed threshold violation to be dropped, got %v", finding)
+		}
+	}
+
+	// An invalid annotation is skipped and reported, the valid ones still apply
+	writeFiles(t, dir, map[string]string{"store/doc.go": "//aid-metrics:max-distance=2\n//aid-metrics:allow=AM004\npackage store\n"})
+	for _, importsOnly := range []bool{false, true} {
+		opts.ImportsOnly = importsOnly
+		metrics, err = AnalyzeModuleWithOptions(dir, "./...", opts)
+		if err != nil {
+			t.Fatalf("expected an invalid annotation not to fail the analysis (imports only: %v), got %v", importsOnly, err)
+		}
+		want := models.Diagnostic{Package: "store", Kind: "annotation", Position: "store/doc.go:1:1",
+			Message: "invalid annotation //aid-metrics:max-distance=2: max-distance needs a value between 0 and 1"}
+		if len(metrics.Diagnostics) != 1 || metrics.Diagnostics[0] != want {
+			t.Errorf("expected the diagnostic %+v (imports only: %v), got %+v", want, importsOnly, metrics.Diagnostics)
+		}
+		store := metrics.Packages["example.com/shop/store"]
+		if store.Partial || strings.Join(store.Annotations, " ") != "allow=AM004" {
+			t.Errorf("expected store complete with annotation allow=AM004 (imports only: %v), got partial=%v and %v", importsOnly, store.Partial, store.Annotations)
+		}
+	}
+}
+
+func TestStringCoupling(t *testing.T) {
+	dir := writeModule(t, map[string]string{
+		"go.mod":           "module example.com/shop\n\ngo 1.21\n",
+		"config/config.go": "package config\n\nimport \"os\"\n\ntype Config struct {\n\tURL string `json:\"db_url\"`\n}\n\nfunc Load() Config { return Config{URL: os.Getenv(\"DATABASE_URL\")} }\n",
+		"store/store.go":   "package store\n\nimport \"os\"\n\nconst Key = \"orders.v1\"\n\nfunc Open() string { return os.Getenv(\"DATABASE_URL\") + Key }\n",
+		"api/api.go":       "package api\n\nimport \"example.com/shop/store\"\n\ntype Order struct {\n\tID string `json:\"db_url\"`\n}\n\nconst route = \"/v1/orders\"\n\nfunc Serve() string { return store.Open() + \"orders.v1\" + route }\n",
+		"client/client.go": "package client\n\nfunc Get() string { return \"/v1/orders\" + \"ok\" }\n",
+	})
+
+	metrics, err := AnalyzeModuleWithOptions(dir, "./...", AnalyzerOptions{StringCoupling: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, coupling := range metrics.StringCouplings {
+		got = append(got, coupling.Value+": "+strings.Join(coupling.Packages, ", "))
+	}
+	// orders.v1 is shared by api and the store it imports, db_url only by struct tags
+	want := []string{"/v1/orders: api, client", "DATABASE_URL: config, store"}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("expected string couplings\n%s\ngot\n%s", strings.Join(want, "\n"), strings.Join(got, "\n"))
+	}
+}
+
+func TestConstantSharing(t *testing.T) {
+	files := map[string]string{
+		"go.mod": "module example.com/shop\n\ngo 1.21\n",
+		"status/status.go": "package status\n\ntype Status int\n\nconst (\n\tActive Status = iota\n\tInactive\n)\n\n" +
+			"const (\n\tMaxRetries = 3\n\tTimeout    = 5\n)\n\nfunc Parse(s string) Status { return Active }\n",
+	}
+	for _, name := range []string{"api", "cli", "worker"} {
+		files[name+"/"+name+".go"] = "package " + name + "\n\nimport \"example.com/shop/status\"\n\n" +
+			"func Active(s status.Status) bool { return s == status.Active }\n"
+	}
+	files["worker/retry.go"] = "package worker\n\nimport \"example.com/shop/status\"\n\nvar retries = status.MaxRetries\n"
+	dir := writeModule(t, files)
+
+	metrics, err := AnalyzeModule(dir, "./...")
+	if err != nil {
+		t.Fatal(err)
+	}
+	status := metrics.Packages["example.com/shop/status"]
+	var got []string
+	for _, set := range status.ConstantSets {
+		got = append(got, fmt.Sprintf("%s:%d=%d", set.Name, set.Constants, len(set.ReferencedBy)))
+	}
+	if want := "MaxRetries:2=1 Status:2=3"; strings.Join(got, " ") != want {
+		t.Errorf("expected constant sets %s, got %s", want, strings.Join(got, " "))
+	}
+	if status.ConstantShare != 5.0/6 || status.ConstantDependents != 3 || !status.SharedKernel {
+		t.Errorf("expected a shared kernel with share 5/6 and 3 dependents, got %v, %d, %v",
+			status.ConstantShare, status.ConstantDependents, status.SharedKernel)
+	}
+
+	var found bool
+	for _, finding := range metrics.Findings {
+		found = found || (finding.Category == models.CategorySharedKernel && finding.Package == status.Name)
+	}
+	if !found {
+		t.Errorf("expected a shared-kernel finding for %s, got %v", status.Name, metrics.Findings)
+	}
+}
+
+func TestErrorCoupling(t *testing.T) {
+	dir := writeModule(t, map[string]string{
+		"go.mod": "module example.com/shop\n\ngo 1.21\n",
+		"store/store.go": "package store\n\nimport \"errors\"\n\n" +
+			"var ErrNotFound = errors.New(\"not found\")\n\nvar ErrConflict = errors.New(\"conflict\")\n\nvar Limit = 10\n\n" +
+			"type ValidationError struct{ Field string }\n\nfunc (e *ValidationError) Error() string { return e.Field }\n\n" +
+			"func Get() error { return ErrNotFound }\n",
+		"api/api.go": "package api\n\nimport (\n\t\"errors\"\n\n\t\"example.com/shop/store\"\n)\n\n" +
+			"func Status() int {\n\terr := store.Get()\n\tvar invalid *store.ValidationError\n\tswitch {\n" +
+			"\tcase errors.Is(err, store.ErrNotFound):\n\t\treturn 404\n\tcase errors.As(err, &invalid):\n\t\treturn 400\n\t}\n\treturn 200\n}\n",
+		"cli/cli.go": "package cli\n\nimport (\n\t\"errors\"\n\n\t\"example.com/shop/store\"\n)\n\n" +
+			"func Exit() int {\n\terr := store.Get()\n\tif err == store.ErrNotFound {\n\t\treturn 1\n\t}\n" +
+			"\tif invalid := (*store.ValidationError)(nil); errors.As(err, &invalid) {\n\t\treturn 2\n\t}\n\treturn 0\n}\n",
+	})
+
+	metrics, err := AnalyzeModule(dir, "./...")
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := metrics.Packages["example.com/shop/store"]
+	var got []string
+	for _, target := range store.ErrorTargets {
+		got = append(got, fmt.Sprintf("%s:%s=%s", target.Name, target.Kind, strings.Join(target.CheckedBy, ",")))
+	}
+	api, cli := metrics.Packages["example.com/shop/api"].Name, metrics.Packages["example.com/shop/cli"].Name
+	want := []string{"ErrConflict:var=", "ErrNotFound:var=" + api + "," + cli, "ValidationError:type=" + api + "," + cli}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("expected error targets %v, got %v", want, got)
+	}
+	if store.ErrorDependents != 2 {
+		t.Errorf("expected 2 error dependents, got %d", store.ErrorDependents)
+	}
+}
+
+func TestConcurrency(t *testing.T) {
+	dir := writeModule(t, map[string]string{
+		"go.mod": "module example.com/shop\n\ngo 1.21\n",
+		"worker/worker.go": "package worker\n\nimport \"sync\"\n\n" +
+			"type Pool struct {\n\tmu sync.Mutex\n\twg sync.WaitGroup\n}\n\n" +
+			"func (p *Pool) Run(jobs []func()) {\n\tdone := make(chan struct{})\n\tfor _, job := range jobs {\n\t\tp.wg.Add(1)\n" +
+			"\t\tgo func() {\n\t\t\tdefer p.wg.Done()\n\t\t\tjob()\n\t\t}()\n\t}\n\tgo func() { p.wg.Wait(); close(done) }()\n\t<-done\n}\n",
+		"model/model.go": "package model\n\ntype Order struct{ ID int }\n",
+	})
+
+	metrics, err := AnalyzeModuleWithOptions(dir, "./...", AnalyzerOptions{Concurrency: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, ok, err := models.GetExtension[models.ConcurrencyMetrics](metrics.Packages["example.com/shop/worker"], models.ConcurrencyGroup)
+	if err != nil || !ok {
+		t.Fatalf("expected the concurrency group for worker, got %v, %v", ok, err)
+	}
+	if want := (models.ConcurrencyMetrics{Goroutines: 2, Channels: 1, SyncPrimitives: 2}); got != want {
+		t.Errorf("expected %+v, got %+v", want, got)
+	}
+	if got, _, _ := models.GetExtension[models.ConcurrencyMetrics](metrics.Packages["example.com/shop/model"], models.ConcurrencyGroup); got != (models.ConcurrencyMetrics{}) {
+		t.Errorf("expected no concurrency in model, got %+v", got)
+	}
+
+	metrics, err = AnalyzeModule(dir, "./...")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := metrics.Packages["example.com/shop/worker"].Extensions[models.ConcurrencyGroup]; ok {
+		t.Error("expected no concurrency group without the option")
+	}
+}
+
+// writeModule writes files, keyed by slash-separated paths, into a new temporary
+// directory and returns the directory
+func writeModule(t testing.TB, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	writeFiles(t, dir, files)
+	return dir
+}
+
+// writeFiles writes files, keyed by slash-separated paths, into dir, creating
+// directories as needed and replacing existing files
+func writeFiles(t testing.TB, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// newTestPackage writes the given source into a temporary file and returns a
+// packages.Package describing it, parsed and type-checked, so analyzePackage can
+// be exercised without going through packages.Load. Imports of the standard
+// library are resolved from export data; type errors are ignored.
+func newTestPackage(t testing.TB, id string, src string) *packages.Package {
+	t.Helper()
+
+	dir := t.TempDir()
+	filePath := filepath.Join(dir, "file.go")
+	if err := os.WriteFile(filePath, []byte(src), 0o644); err != nil {
+		t.Fatalf("failed to write test file: %v", err)
+	}
+
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, filePath, src, parser.ParseComments)
+	if err != nil {
+		t.Fatalf("failed to parse test file: %v", err)
+	}
+	info := &types.Info{
+		Types: make(map[ast.Expr]types.TypeAndValue),
+		Defs:  make(map[*ast.Ident]types.Object),
+		Uses:  make(map[*ast.Ident]types.Object),
+	}
+	config := types.Config{Importer: stdlibImporter{importer.Default()}, Error: func(error) {}}
+	typesPkg, _ := config.Check(id, fset, []*ast.File{file}, info)
+
+	return &packages.Package{
+		ID:        id,
+		PkgPath:   id,
+		GoFiles:   []string{filePath},
+		Fset:      fset,
+		Syntax:    []*ast.File{file},
+		Types:     typesPkg,
+		TypesInfo: info,
+	}
+}
+
+// stdlibImporter imports the standard library only, so type-checking test sources
+// never resolves (or adds to go.mod) the third-party modules they import
+type stdlibImporter struct {
+	types.Importer
+}
+
+func (i stdlibImporter) Import(path string) (*types.Package, error) {
+	if strings.Contains(strings.Split(path, "/")[0], ".") {
+		return nil, fmt.Errorf("%s is not in the standard library", path)
+	}
+	return i.Importer.Import(path)
+}
+
+func TestEmbeddingCounts(t *testing.T) {
+	src := `package sample
+
+import "io"
+
+type Base struct{ ID int }
+
+type Derived struct {
+	Base
+	*io.PipeReader
+	Name, Title string
+}
+
+type ReadCloser interface {
+	io.Reader
+	io.Closer
+	Reset()
+}
+`
+	analyzer := NewModuleAnalyzer("", "")
+	result := analyzer.analyzePackage(newTestPackage(t, "example.com/sample", src))
+	if result.err != nil {
+		t.Fatalf("analyzePackage returned error: %v", result.err)
+	}
+
+	if result.embedding.structEmbeds != 2 {
+		t.Errorf("Expected 2 struct embeds, got %d", result.embedding.structEmbeds)
+	}
+	if result.embedding.interfaceEmbeds != 2 {
+		t.Errorf("Expected 2 interface embeds, got %d", result.embedding.interfaceEmbeds)
+	}
+
+	// 4 embeds out of 5 struct fields (ID, Base, PipeReader, Name, Title) and 3 interface elements
+	expectedRatio := 4.0 / 8.0
+	if got := result.embedding.ratio(); got != expectedRatio {
+		t.Errorf("Expected embedding ratio %v, got %v", expectedRatio, got)
+	}
+}
+
+func TestMethodCounts(t *testing.T) {
+	src := `package sample
+
+type Counter struct{ n int }
+
+func (c *Counter) Inc()       { c.n++ }
+func (c *Counter) reset()     { c.n = 0 }
+func (c Counter) Value() int  { return c.n }
+func NewCounter() *Counter    { return &Counter{} }
+`
+	analyzer := NewModuleAnalyzer("", "")
+	result := analyzer.analyzePackage(newTestPackage(t, "example.com/sample", src))
+	if result.err != nil {
+		t.Fatalf("analyzePackage returned error: %v", result.err)
+	}
+
+	want := methodCounts{pointer: 2, value: 1, exported: 2, unexported: 1}
+	if result.methods != want {
+		t.Errorf("Expected method counts %+v, got %+v", want, result.methods)
+	}
+}
+
+func TestConstructorCounts(t *testing.T) {
+	src := `package sample
+
+type Store interface{ Get(key string) string }
+
+type memStore struct{}
+
+func (memStore) Get(key string) string { return key }
+
+func NewStore() Store            { return memStore{} }
+func NewMemStore() *memStore     { return &memStore{} }
+func New() (Store, error)        { return memStore{}, nil }
+func Newsletter() string         { return "" }
+func newInternal() *memStore     { return nil }
+`
+	analyzer := NewModuleAnalyzer("", "")
+	result := analyzer.analyzePackage(newTestPackage(t, "example.com/sample", src))
+	if result.err != nil {
+		t.Fatalf("analyzePackage returned error: %v", result.err)
+	}
+
+	want := constructorCounts{total: 3, returnsInterface: 2}
+	if result.constructors != want {
+		t.Errorf("Expected constructor counts %+v, got %+v", want, result.constructors)
+	}
+}
+
+// importerFunc adapts a function to types.Importer
+type importerFunc func(path string) (*types.Package, error)
+
+func (f importerFunc) Import(path string) (*types.Package, error) { return f(path) }
+
+// newStubPackage creates a type-checked package declaring an empty struct type per name
+func newStubPackage(path string, names ...string) *types.Package {
+	pkg := types.NewPackage(path, path[strings.LastIndex(path, "/")+1:])
+	for _, name := range names {
+		obj := types.NewTypeName(token.NoPos, pkg, name, nil)
+		types.NewNamed(obj, types.NewStruct(nil, nil), nil)
+		pkg.Scope().Insert(obj)
+	}
+	pkg.MarkComplete()
+	return pkg
+}
+
+func TestExposedDependencies(t *testing.T) {
+	src := `package sample
+
+import (
+	"example.com/dep"
+	"example.com/impl"
+	"example.com/iface"
+)
+
+type Service struct {
+	Client *dep.Client
+	cache  impl.Cache
+}
+
+func (s *Service) Handle(r iface.Request) {}
+
+func helper(c impl.Cache) []impl.Cache { return nil }
+`
+	stubs := map[string]*types.Package{
+		"example.com/dep":   newStubPackage("example.com/dep", "Client"),
+		"example.com/impl":  newStubPackage("example.com/impl", "Cache"),
+		"example.com/iface": newStubPackage("example.com/iface", "Request"),
+	}
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "sample.go", src, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conf := types.Config{Importer: importerFunc(func(path string) (*types.Package, error) {
+		if pkg, ok := stubs[path]; ok {
+			return pkg, nil
+		}
+		return nil, fmt.Errorf("unknown package %s", path)
+	})}
+	typesPkg, err := conf.Check("example.com/sample", fset, []*ast.File{file}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pkg := &packages.Package{ID: "example.com/sample", Types: typesPkg}
+	deps := []string{"example.com/dep", "example.com/impl", "example.com/iface"}
+	got := exposedDependencies(pkg, deps)
+	if strings.Join(got, " ") != "example.com/dep example.com/iface" {
+		t.Errorf("Expected dep and iface to be exposed, got %v", got)
+	}
+
+	if got := exposedDependencies(&packages.Package{ID: "example.com/sample"}, deps); got != nil {
+		t.Errorf("Expected no exposed dependencies without type information, got %v", got)
+	}
+}
+
+func TestLeakedTypes(t *testing.T) {
+	src := `package shop
+
+import (
+	"example.com/shop/store"
+	"github.com/vendor/sdk"
+	"github.com/vendor/sdk/auth"
+)
+
+type Client struct {
+	Conn  *sdk.Conn
+	Store store.DB
+	token auth.Token
+}
+
+func NewClient(opts sdk.Options) *Client { return nil }
+
+func (c *Client) Login(t auth.Token, fallback auth.Token) error { return nil }
+
+func login(t auth.Token) {}
+`
+	stubs := map[string]*types.Package{
+		"example.com/shop/store":     newStubPackage("example.com/shop/store", "DB"),
+		"github.com/vendor/sdk":      newStubPackage("github.com/vendor/sdk", "Conn", "Options"),
+		"github.com/vendor/sdk/auth": newStubPackage("github.com/vendor/sdk/auth", "Token"),
+	}
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "shop.go", src, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conf := types.Config{Importer: importerFunc(func(path string) (*types.Package, error) {
+		return stubs[path], nil
+	})}
+	typesPkg, err := conf.Check("example.com/shop", fset, []*ast.File{file}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	leaks := leakedTypes(&packages.Package{ID: "example.com/shop", Name: "shop", Types: typesPkg}, "example.com/shop")
+	var got []string
+	for _, leak := range leaks {
+		got = append(got, leak.declaration+" "+leak.typeName)
+	}
+	want := "Client.Conn Conn|NewClient Options|Client.Login Token"
+	if strings.Join(got, "|") != want {
+		t.Errorf("Expected leaks %q, got %q", want, strings.Join(got, "|"))
+	}
+
+	// One finding per offending package
+	analyzer := NewModuleAnalyzer("", "")
+	findings := analyzer.leakFindings(models.PackageMetrics{Name: "shop", LeakedTypes: newTypeLeaks(leaks)})
+	if len(findings) != 2 {
+		t.Fatalf("Expected 2 leak findings, got %d: %+v", len(findings), findings)
+	}
+	if findings[0].ID != "AM007" || !strings.Contains(findings[0].Message, "github.com/vendor/sdk in 2 exported declarations") {
+		t.Errorf("Unexpected first leak finding: %+v", findings[0])
+	}
+}
+
+func TestInterfaceOwnership(t *testing.T) {
+	// store declares Store next to its only implementation (a header interface) and
+	// Cache next to one of two implementations; service declares the Loader it consumes
+	// and a Notifier implemented only by a mock. The mocks are not implementations.
+	src := map[string]string{
+		"example.com/store": `package store
+
+type Store interface{ Load(key string) string }
+type Cache interface{ Get(key string) string; Put(key, value string) }
+
+type DB struct{}
+
+func (*DB) Load(key string) string { return key }
+
+type Memory struct{}
+
+func (Memory) Get(key string) string  { return key }
+func (Memory) Put(key, value string) {}
+`,
+		"example.com/service": `package service
+
+type Loader interface{ Load(key string) string }
+type Notifier interface{ Notify() }
+
+type Remote struct{}
+
+func (Remote) Put(key, value string) {}
+func (Remote) Get(key string) string  { return key }
+func (Remote) Close()                 {}
+`,
+		"example.com/mocks": `package mocks
+
+type MockStore struct{}
+
+func (*MockStore) Load(key string) string { return key }
+func (*MockStore) EXPECT() *MockStore     { return nil }
+
+type MockNotifier struct{}
+
+func (*MockNotifier) Notify() {}
+`,
+	}
+	mockTypes := map[string]bool{"MockStore": true, "MockNotifier": true}
+
+	analyzer := NewModuleAnalyzer("", "")
+	for id, code := range src {
+		fset := token.NewFileSet()
+		file, err := parser.ParseFile(fset, id+".go", code, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		typesPkg, err := (&types.Config{}).Check(id, fset, []*ast.File{file}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		interfaces, concrete := interfaceDecls(&packages.Package{ID: id, Types: typesPkg}, mockTypes)
+		analyzer.interfaces[id] = interfaces
+		analyzer.concreteTypes[id] = concrete
+	}
+
+	ownership := analyzer.classifyInterfaces()
+	if got := ownership["example.com/store"]; got.provider != 2 || got.consumer != 0 {
+		t.Errorf("Expected 2 provider-side interfaces in store, got %+v", got)
+	}
+	if got := ownership["example.com/store"].headers; len(got) != 1 || got[0] != (models.HeaderInterface{Interface: "Store", Implementation: "DB"}) {
+		t.Errorf("Expected Store to be a header interface of DB, got %+v", got)
+	}
+	if got := ownership["example.com/service"]; got.consumer != 2 || got.provider != 0 {
+		t.Errorf("Expected Loader and Notifier to be consumer-side, got %+v", got)
+	}
+
+	// MockStore implements Loader as well, but is named after Store
+	if got := ownership["example.com/store"].mocked; len(got) != 1 || got[0] != (models.MockedInterface{Interface: "Store", Mocks: 1}) {
+		t.Errorf("Expected one mock of Store, got %+v", got)
+	}
+	if got := ownership["example.com/service"].mocked; len(got) != 1 || got[0] != (models.MockedInterface{Interface: "Notifier", Mocks: 1, OnlyMocks: true}) {
+		t.Errorf("Expected Notifier to be implemented only by a mock, got %+v", got)
+	}
+	if n := analyzer.countMocks("example.com/mocks"); n != 2 {
+		t.Errorf("Expected 2 mocks, got %d", n)
+	}
+}
+
+func TestDetectDIFramework(t *testing.T) {
+	tests := []struct {
+		name     string
+		pkg      *packages.Package
+		expected string
+	}{
+		{"no framework", &packages.Package{Imports: map[string]*packages.Package{"fmt": {}}}, ""},
+		{"fx", &packages.Package{Imports: map[string]*packages.Package{"go.uber.org/fx": {}}}, "fx"},
+		{"dig subpackage", &packages.Package{Imports: map[string]*packages.Package{"go.uber.org/dig/internal": {}}}, "dig"},
+		{"similar prefix", &packages.Package{Imports: map[string]*packages.Package{"go.uber.org/fxtest": {}}}, ""},
+		{"wire_gen", &packages.Package{GoFiles: []string{"/src/app/wire_gen.go"}}, "wire"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := detectDIFramework(tt.pkg); got != tt.expected {
+				t.Errorf("detectDIFramework() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestClassifyRole(t *testing.T) {
+	httpImports := map[string]*packages.Package{"net/http": {}}
+	rules := []RoleRule{{Pattern: "internal/billing/...", Role: models.RoleDomain}}
+
+	tests := []struct {
+		name     string
+		pkg      *packages.Package
+		relPath  string
+		expected string
+	}{
+		{"main package", &packages.Package{Name: "main"}, "cmd/server", models.RoleMain},
+		{"path segment", &packages.Package{Name: "postgres"}, "internal/store/postgres", models.RoleRepository},
+		{"deepest segment wins", &packages.Package{Name: "models"}, "internal/api/models", models.RoleModel},
+		{"import fingerprint", &packages.Package{Name: "web", Imports: httpImports}, "internal/web", models.RoleHandler},
+		{"configured rule", &packages.Package{Name: "api", Imports: httpImports}, "internal/billing/api", models.RoleDomain},
+		{"no match", &packages.Package{Name: "util"}, "internal/util", models.RoleOther},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := classifyRole(tt.pkg, tt.relPath, rules); got != tt.expected {
+				t.Errorf("classifyRole(%q) = %q, want %q", tt.relPath, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestProtobufProfile(t *testing.T) {
+	src := `// Code generated by protoc-gen-go. DO NOT EDIT.
+// source: api.proto
+
+package apipb
+
+type Request struct{ Name string }
+`
+	pkg := newTestPackage(t, "example.com/apipb", src)
+
+	analyzer := NewModuleAnalyzerWithOptions("", "", AnalyzerOptions{Profiles: []string{ProfileProtobuf}})
+	result := analyzer.analyzePackage(pkg)
+	if result.err != nil {
+		t.Fatalf("analyzePackage returned error: %v", result.err)
+	}
+
+	if got := result.generated.generator(); got != "protoc-gen-go" {
+		t.Errorf("Expected generator protoc-gen-go, got %q", got)
+	}
+	if reason := analyzer.gateExemptReason(result.generated); reason == "" {
+		t.Error("Expected protobuf package to be exempt from gating with the protobuf profile")
+	}
+
+	withoutProfile := NewModuleAnalyzer("", "")
+	if reason := withoutProfile.gateExemptReason(result.generated); reason != "" {
+		t.Errorf("Expected no gating exemption without the profile, got %q", reason)
+	}
+}
+
+func TestMocksProfile(t *testing.T) {
+	src := `// Code generated by MockGen. DO NOT EDIT.
+// Source: store.go
+
+package mocks
+
+type MockStore struct{ recorder *MockStoreRecorder }
+
+type MockStoreRecorder struct{}
+`
+	pkg := newTestPackage(t, "example.com/mocks", src)
+
+	analyzer := NewModuleAnalyzerWithOptions("", "", AnalyzerOptions{Profiles: []string{ProfileMocks}})
+	result := analyzer.analyzePackage(pkg)
+	if result.err != nil {
+		t.Fatalf("analyzePackage returned error: %v", result.err)
+	}
+
+	if !result.generated.isMocks() {
+		t.Errorf("Expected a package of generated mocks, got generators %v", result.generated.generators)
+	}
+	if reason := analyzer.gateExemptReason(result.generated); reason != "generated mocks" {
+		t.Errorf("Expected mocks to be exempt from gating with the mocks profile, got %q", reason)
+	}
+	if reason := NewModuleAnalyzer("", "").gateExemptReason(result.generated); reason != "" {
+		t.Errorf("Expected no gating exemption without the profile, got %q", reason)
+	}
+}
+
+func TestDataBagDetection(t *testing.T) {
+	src := "package entities\n\n" +
+		"type User struct {\n\tID int `json:\"id\" gorm:\"primaryKey\"`\n}\n\n" +
+		"type Order struct {\n\tID int `db:\"id\"`\n}\n\n" +
+		"type Item struct {\n\tSKU string `json:\"sku\"`\n}\n\n" +
+		"type cache struct{ items []Item }\n"
+
+	analyzer := NewModuleAnalyzer("", "")
+	result := analyzer.analyzePackage(newTestPackage(t, "example.com/entities", src))
+	if result.err != nil {
+		t.Fatalf("analyzePackage returned error: %v", result.err)
+	}
+
+	if result.taggedStructs != 3 {
+		t.Errorf("Expected 3 tagged structs, got %d", result.taggedStructs)
+	}
+	if !isDataBag(result.taggedStructs, result.structCount, 0) {
+		t.Error("Expected package to be detected as a data bag")
+	}
+	if isDataBag(result.taggedStructs, result.structCount, 0.5) {
+		t.Error("Expected abstract package not to be detected as a data bag")
+	}
+}
+
+func TestFindEndpoints(t *testing.T) {
+	src := `package server
+
+import (
+	"net/http"
+
+	orders "example.com/shop/internal/orders"
+	"example.com/shop/internal/orders/pb"
+	"google.golang.org/grpc"
+)
+
+type cache struct{}
+
+func (cache) Get(key string, v any) error { return nil }
+
+func routes(mux *http.ServeMux, s *grpc.Server, c cache, client *orders.Client) {
+	mux.HandleFunc("/health", health)
+	mux.Handle("GET /orders/{id}", orders.NewHandler(nil))
+	pb.RegisterOrderServiceServer(s, &orders.GRPCService{})
+
+	// Not registrations: a method of a local type, and a literal that is no route
+	var v string
+	c.Get("/key", &v)
+	client.Get("orders/1", &v)
+}
+
+func health(w http.ResponseWriter, r *http.Request) {}
+`
+	analyzer := NewModuleAnalyzer("", "")
+	result := analyzer.analyzePackage(newTestPackage(t, "example.com/shop/server", src))
+	if result.err != nil {
+		t.Fatalf("analyzePackage returned error: %v", result.err)
+	}
+
+	expected := []endpointRegistration{
+		{route: "/health", kind: "http", handlerPackage: "example.com/shop/server"},
+		{route: "GET /orders/{id}", kind: "http", handlerPackage: "example.com/shop/internal/orders"},
+		{route: "OrderService", kind: "grpc", handlerPackage: "example.com/shop/internal/orders"},
+	}
+	if len(result.endpoints) != len(expected) {
+		t.Fatalf("Expected %d endpoints, got %+v", len(expected), result.endpoints)
+	}
+	for i, want := range expected {
+		if result.endpoints[i] != want {
+			t.Errorf("Endpoint %d: expected %+v, got %+v", i, want, result.endpoints[i])
+		}
+	}
+}
+
+func TestCyclesAndSDPFindings(t *testing.T) {
+	analyzer := NewModuleAnalyzer("", "")
+
+	// x and y import each other. stable (I=0.25) depends on volatile (I=0.67),
+	// which violates the Stable Dependencies Principle.
+	analyzer.dependencies = map[string][]string{
+		"x":        {"y"},
+		"y":        {"x"},
+		"a":        {"stable"},
+		"b":        {"stable"},
+		"c":        {"stable"},
+		"stable":   {"volatile"},
+		"volatile": {"ext/one", "ext/two"},
+	}
+	for pkg, deps := range analyzer.dependencies {
+		for _, dep := range deps {
+			analyzer.reverseDepends[dep] = append(analyzer.reverseDepends[dep], pkg)
+		}
+	}
+
+	metrics := analyzer.calculateMetrics()
+
+	if len(metrics.Cycles) != 1 || len(metrics.Cycles[0]) != 2 || metrics.Cycles[0][0] != "x" || metrics.Cycles[0][1] != "y" {
+		t.Fatalf("Expected a single cycle [x y], got %v", metrics.Cycles)
+	}
+
+	var cycleFindings, sdpFindings int
+	for _, finding := range metrics.Findings {
+		switch finding.Category {
+		case models.CategoryCycle:
+			cycleFindings++
+			if finding.Severity != models.SeverityError || finding.ID != "AM001" {
+				t.Errorf("Unexpected cycle finding: %+v", finding)
+			}
+		case models.CategorySDP:
+			sdpFindings++
+			if finding.Package != "stable" {
+				t.Errorf("Expected SDP violation on stable, got %+v", finding)
+			}
+		}
+	}
+	if cycleFindings != 1 {
+		t.Errorf("Expected 1 cycle finding, got %d", cycleFindings)
+	}
+	if sdpFindings != 1 {
+		t.Errorf("Expected 1 SDP finding, got %d", sdpFindings)
+	}
+	if metrics.Findings[0].Category != models.CategoryCycle {
+		t.Errorf("Expected findings sorted by severity, got %+v first", metrics.Findings[0])
+	}
+}
+
+func TestDebtPoints(t *testing.T) {
+	analyzer := NewModuleAnalyzer("", "")
+	analyzer.options.DebtWeights = map[string]int{models.CategorySDP: 5}
+
+	// x and y import each other, stable depends on the less stable volatile
+	analyzer.dependencies = map[string][]string{
+		"x":        {"y"},
+		"y":        {"x"},
+		"a":        {"stable"},
+		"b":        {"stable"},
+		"stable":   {"volatile"},
+		"volatile": {"ext/one", "ext/two"},
+	}
+	for pkg, deps := range analyzer.dependencies {
+		for _, dep := range deps {
+			analyzer.reverseDepends[dep] = append(analyzer.reverseDepends[dep], pkg)
+		}
+	}
+
+	metrics := analyzer.calculateMetrics()
+
+	perPackage := make(map[string]int)
+	for _, finding := range metrics.Findings {
+		perPackage[finding.Package] += finding.Points
+		switch finding.Category {
+		case models.CategoryCycle:
+			if finding.Points != models.DefaultDebtPoints[models.SeverityError] {
+				t.Errorf("Expected the cycle to weigh the points of its severity, got %+v", finding)
+			}
+		case models.CategorySDP:
+			if finding.Points != 5 {
+				t.Errorf("Expected the configured SDP weight, got %+v", finding)
+			}
+		}
+	}
+	total := 0
+	for _, pkg := range metrics.Packages {
+		if pkg.DebtPoints != perPackage[pkg.Name] {
+			t.Errorf("Expected %s to accumulate %d points, got %d", pkg.Name, perPackage[pkg.Name], pkg.DebtPoints)
+		}
+		total += pkg.DebtPoints
+	}
+	if got := models.DebtPoints(metrics.Findings); got != total || got < 15 {
+		t.Errorf("Expected the module debt to sum the package debts (%d), got %d", total, got)
+	}
+}
+
+func TestComplexity(t *testing.T) {
+	src := `package sample
+
+func Simple() {}
+
+func Branchy(xs []int, ch chan int) int {
+	n := 0
+	for _, x := range xs {
+		if x > 0 && x < 10 || x == 42 {
+			n++
+		}
+	}
+	switch n {
+	case 1, 2:
+	case 3:
+	default:
+	}
+	select {
+	case <-ch:
+	default:
+	}
+	return n
+}
+`
+	result := NewModuleAnalyzer("", "").analyzePackage(newTestPackage(t, "example.com/sample", src))
+
+	// Branchy: 1 + range + if + && + || + 2 cases + 1 comm clause = 8
+	if result.complexity.functions != 2 || result.complexity.max != 8 || result.complexity.mean() != 4.5 {
+		t.Errorf("expected 2 functions, max 8 and mean 4.5, got %+v", result.complexity)
+	}
+}
+
+func TestHealthScore(t *testing.T) {
+	weights := models.DefaultHealthWeights
+	tests := []struct {
+		name        string
+		pkg         models.PackageMetrics
+		inCycle     bool
+		importsOnly bool
+		want        int
+	}{
+		{"on the main sequence", models.PackageMetrics{Complexity: 2}, false, false, 100},
+		{"in the zone of pain", models.PackageMetrics{Distance: 1}, false, false, 56},
+		{"in a cycle", models.PackageMetrics{}, true, false, 67},
+		{"complex", models.PackageMetrics{Complexity: 25}, false, false, 78},
+		{"uncovered", models.PackageMetrics{HasCoverage: true}, false, false, 90},
+		{"half covered", models.PackageMetrics{HasCoverage: true, Coverage: 0.5}, false, false, 95},
+		{"exempt from gating", models.PackageMetrics{Distance: 1, GateExempt: true}, false, false, 100},
+		{"imports only", models.PackageMetrics{Distance: 1}, true, true, 0},
+	}
+	for _, tt := range tests {
+		if got := health(tt.pkg, tt.inCycle, weights, tt.importsOnly); got != tt.want {
+			t.Errorf("%s: expected health %d, got %d", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestZones(t *testing.T) {
+	bands := models.DefaultZoneBands
+	tests := []struct {
+		name        string
+		pkg         models.PackageMetrics
+		bands       models.ZoneBands
+		importsOnly bool
+		want        string
+	}{
+		{"stable and concrete", models.PackageMetrics{Ca: 3, Instability: 0.1}, bands, false, models.ZonePain},
+		{"unstable and abstract", models.PackageMetrics{Ce: 3, Instability: 1, Abstractness: 0.8}, bands, false, models.ZoneUselessness},
+		{"balanced", models.PackageMetrics{Ca: 1, Ce: 1, Instability: 0.5, Abstractness: 0.5}, bands, false, models.ZoneMainSequence},
+		{"unstable and concrete", models.PackageMetrics{Ce: 2, Instability: 1}, bands, false, models.ZoneMainSequence},
+		{"narrow pain band", models.PackageMetrics{Ca: 3, Instability: 0.25}, models.ZoneBands{Pain: 0.2, Uselessness: 0.8}, false, models.ZoneMainSequence},
+		{"isolated", models.PackageMetrics{}, bands, false, ""},
+		{"imports only", models.PackageMetrics{Ca: 3}, bands, true, ""},
+	}
+	for _, tt := range tests {
+		if got := zone(tt.pkg, tt.bands, tt.importsOnly); got != tt.want {
+			t.Errorf("%s: expected zone %q, got %q", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	pkgs := map[string]models.PackageMetrics{
+		"a":    {Name: "a", Ca: 1, Distance: 0.1, Zone: models.ZoneMainSequence},
+		"b":    {Name: "b", Ca: 1, Distance: 0.9, Zone: models.ZonePain},
+		"c":    {Name: "c", Ce: 1, Distance: 0.3, Zone: models.ZoneMainSequence},
+		"d":    {Name: "d", Ce: 1, Distance: 0.5, Zone: models.ZoneUselessness},
+		"cmd":  {Name: "cmd", Ce: 2, Distance: 1, GateExempt: true, Zone: models.ZoneMainSequence},
+		"util": {Name: "util"},
+	}
+	summary := Summarize(pkgs)
+	if summary.Packages != 6 || summary.Measured != 4 {
+		t.Errorf("expected 6 packages with 4 measured, got %d and %d", summary.Packages, summary.Measured)
+	}
+	if math.Abs(summary.MeanDistance-0.45) > 1e-9 || math.Abs(summary.MedianDistance-0.4) > 1e-9 || summary.P90Distance != 0.9 {
+		t.Errorf("expected D mean 0.45, median 0.4 and p90 0.9, got %.2f, %.2f and %.2f",
+			summary.MeanDistance, summary.MedianDistance, summary.P90Distance)
+	}
+	if got := strings.Join(summary.WorstOffenders, ","); got != "b,d,c,a" {
+		t.Errorf("expected the measured packages worst first, got %s", got)
+	}
+	if summary.Zones[models.ZoneMainSequence] != 3 || summary.Zones[models.ZonePain] != 1 || summary.Zones[models.ZoneUselessness] != 1 {
+		t.Errorf("unexpected zone counts: %v", summary.Zones)
+	}
+}
+
+func TestThresholdFindings(t *testing.T) {
+	analyzer := NewModuleAnalyzerWithOptions("", "", AnalyzerOptions{
+		Thresholds: &models.Thresholds{MaxDistance: 0.6, MaxInstability: 0.4, MinAbstractness: 0},
+	})
+
+	// api (I=0.5, D=0.5) is too unstable and store (I=0, A=0, D=1) too far from the
+	// main sequence. cmd (main role) and lonely (isolated) are not held to thresholds.
+	analyzer.dependencies = map[string][]string{
+		"api":    {"store"},
+		"cmd":    {"api"},
+		"store":  nil,
+		"lonely": nil,
+	}
+	analyzer.reverseDepends = map[string][]string{
+		"api":   {"cmd"},
+		"store": {"api"},
+	}
+	analyzer.roles = map[string]string{"cmd": models.RoleMain}
+
+	metrics := analyzer.calculateMetrics()
+
+	var violations []string
+	for _, finding := range metrics.Findings {
+		if finding.Category != models.CategoryThreshold {
+			continue
+		}
+		if finding.ID != "AM004" || finding.Severity != models.SeverityError {
+			t.Errorf("Unexpected threshold finding: %+v", finding)
+		}
+		violations = append(violations, finding.Package)
+	}
+	sort.Strings(violations)
+	if strings.Join(violations, " ") != "api store" {
+		t.Errorf("Expected threshold violations on api and store, got %v", violations)
+	}
+}
+
+func TestEntryPointGating(t *testing.T) {
+	// tool is a main package that another package depends on: I=0, A=0, D=1
+	setup := func(analyzer *ModuleAnalyzer) {
+		analyzer.dependencies = map[string][]string{
+			"tool": nil,
+			"lib":  {"tool"},
+		}
+		analyzer.reverseDepends = map[string][]string{"tool": {"lib"}}
+		analyzer.roles = map[string]string{"tool": models.RoleMain}
+	}
+	sapFindings := func(metrics *models.ModuleMetrics) int {
+		var n int
+		for _, finding := range metrics.Findings {
+			if finding.Category == models.CategorySAP && finding.Package == "tool" {
+				n++
+			}
+		}
+		return n
+	}
+
+	analyzer := NewModuleAnalyzer("", "")
+	setup(analyzer)
+	metrics := analyzer.calculateMetrics()
+	if tool := metrics.Packages["tool"]; !tool.GateExempt || tool.GateExemptReason != "program entry point" {
+		t.Errorf("Expected main package to be exempt as program entry point, got %v %q", tool.GateExempt, tool.GateExemptReason)
+	}
+	if n := sapFindings(metrics); n != 0 {
+		t.Errorf("Expected no SAP finding for the main package, got %d", n)
+	}
+
+	gated := NewModuleAnalyzerWithOptions("", "", AnalyzerOptions{GateEntryPoints: true})
+	setup(gated)
+	metrics = gated.calculateMetrics()
+	if metrics.Packages["tool"].GateExempt {
+		t.Error("Expected main package not to be exempt with GateEntryPoints")
+	}
+	if n := sapFindings(metrics); n != 1 {
+		t.Errorf("Expected 1 SAP finding for the gated main package, got %d", n)
+	}
+}
+
+func TestPrioritizeFindings(t *testing.T) {
+	metrics := &models.ModuleMetrics{
+		Packages: map[string]models.PackageMetrics{
+			"core": {Name: "core", Ca: 9},
+			"leaf": {Name: "leaf", Ca: 0},
+		},
+	}
+	findings := []models.Finding{
+		{Severity: models.SeverityError, Package: "leaf"},
+		{Severity: models.SeverityWarning, Package: "core"},
+		{Severity: models.SeverityInfo, Package: "leaf"},
+	}
+
+	top := PrioritizeFindings(metrics, findings, 2)
+	if len(top) != 2 {
+		t.Fatalf("Expected 2 prioritized findings, got %d", len(top))
+	}
+	// core: warning (2) x 10 packages beats leaf: error (3) x 1 package
+	if top[0].Package != "core" || top[0].Score != 20 {
+		t.Errorf("Expected core with score 20 first, got %+v", top[0])
+	}
+	if top[1].Package != "leaf" || top[1].Score != 3 {
+		t.Errorf("Expected leaf with score 3 second, got %+v", top[1])
+	}
+
+	if gated := FindingsAtLeast(findings, models.SeverityWarning); len(gated) != 2 {
+		t.Errorf("Expected 2 findings at warning or above, got %d", len(gated))
+	}
+}
+
+func BenchmarkAnalyzePackage(b *testing.B) {
+	src := `package sample
+
+import "io"
+
+type Reader interface{ Read(p []byte) (int, error) }
+
+type Store struct {
+	ID   int    ` + "`json:\"id\" db:\"id\"`" + `
+	Name string ` + "`json:\"name\"`" + `
+	io.Reader
+}
+
+func NewStore() *Store { return &Store{} }
+
+func NewReader() Reader { return nil }
+
+func (s *Store) Get(id int) string { return s.Name }
+
+func (s Store) String() string { return s.Name }
+`
+	pkg := newTestPackage(b, "example.com/sample", src)
+	analyzer := NewModuleAnalyzerWithOptions("", "", AnalyzerOptions{})
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if result := analyzer.analyzePackage(pkg); result.err != nil {
+			b.Fatal(result.err)
+		}
+	}
+}
+
+func TestImportsOnly(t *testing.T) {
+	files := map[string]string{
+		"go.mod":         "module example.com/fast\n\ngo 1.21\n",
+		"app/app.go":     "package app\n\nimport (\n\t\"fmt\"\n\n\t\"example.com/fast/store\"\n)\n\nvar _ = fmt.Sprint\nvar _ = store.Name\n",
+		"app/ignored.go": "//go:build ignore\n\npackage app\n\nimport _ \"example.com/fast/util\"\n",
+		"store/store.go": "package store\n\nconst Name = \"store\"\n",
+		"util/util.go":   "package util\n",
+	}
+	dir := writeModule(t, files)
+
+	metrics, err := AnalyzeModuleWithOptions(dir, "./...", AnalyzerOptions{ImportsOnly: true})
+	if err != nil {
+		t.Fatalf("analysis failed: %v", err)
+	}
+
+	app := metrics.Packages["example.com/fast/app"]
+	if app.Ce != 1 || len(app.Dependencies) != 1 || app.Dependencies[0] != "store" {
+		t.Errorf("expected app to depend only on store, got Ce=%d deps=%v", app.Ce, app.Dependencies)
+	}
+	if store := metrics.Packages["example.com/fast/store"]; store.Ca != 1 {
+		t.Errorf("expected store Ca=1, got %d", store.Ca)
+	}
+	if util := metrics.Packages["example.com/fast/util"]; util.Ca != 0 {
+		t.Errorf("expected the ignored file's import not to count, got util Ca=%d", util.Ca)
+	}
+	if len(metrics.Findings) != 0 {
+		t.Errorf("expected no findings without type information, got %v", metrics.Findings)
+	}
+}
+
+// mapCache is an in-memory ResultCache for tests
+type mapCache struct {
+	entries map[string][]byte
+	hits    int
+}
+
+func (c *mapCache) Get(key string) ([]byte, bool, error) {
+	data, ok := c.entries[key]
+	if ok {
+		c.hits++
+	}
+	return data, ok, nil
+}
+
+func (c *mapCache) Put(key string, data []byte) error {
+	c.entries[key] = data
+	return nil
+}
+
+func TestResultCache(t *testing.T) {
+	src := `package sample
+
+type Reader interface{ Read() }
+
+type Store struct{ ID int ` + "`db:\"id\"`" + ` }
+
+func NewReader() Reader { return nil }
+
+func (s *Store) Get() {}
+`
+	pkg := newTestPackage(t, "example.com/sample", src)
+	cache := &mapCache{entries: make(map[string][]byte)}
+	analyzer := NewModuleAnalyzerWithOptions("", "", AnalyzerOptions{Cache: cache})
+
+	fresh := analyzer.analyzePackageCached(pkg)
+	cached := analyzer.analyzePackageCached(pkg)
+	if cache.hits != 1 {
+		t.Fatalf("expected the second analysis to hit the cache, got %d hits", cache.hits)
+	}
+	if cached.abstractCount != fresh.abstractCount || cached.totalTypesCount != fresh.totalTypesCount ||
+		cached.methods != fresh.methods || cached.constructors != fresh.constructors ||
+		cached.taggedStructs != fresh.taggedStructs || cached.role != fresh.role {
+		t.Errorf("cached result %+v differs from fresh result %+v", cached, fresh)
+	}
+
+	// Changing the source must change the key
+	pkg = newTestPackage(t, "example.com/sample", src+"\nfunc Extra() {}\n")
+	analyzer = NewModuleAnalyzerWithOptions("", "", AnalyzerOptions{Cache: cache})
+	if changed := analyzer.analyzePackageCached(pkg); cache.hits != 1 || changed.totalTypesCount != fresh.totalTypesCount+1 {
+		t.Errorf("expected a miss after the source changed, got %d hits and %d types", cache.hits, changed.totalTypesCount)
+	}
+}
+
+func TestPlatform(t *testing.T) {
+	files := map[string]string{
+		"go.mod":             "module example.com/cross\n\ngo 1.21\n",
+		"app/app.go":         "package app\n",
+		"app/app_windows.go": "package app\n\nimport _ \"example.com/cross/winapi\"\n",
+		"app/app_linux.go":   "//go:build linux\n\npackage app\n\nimport _ \"example.com/cross/unix\"\n",
+		"winapi/winapi.go":   "package winapi\n",
+		"unix/unix.go":       "package unix\n",
+	}
+	dir := writeModule(t, files)
+
+	for _, importsOnly := range []bool{true, false} {
+		for platform, want := range map[string]string{"linux/amd64": "unix", "windows/amd64": "winapi"} {
+			p, err := ParsePlatform(platform)
+			if err != nil {
+				t.Fatal(err)
+			}
+			metrics, err := AnalyzeModuleWithOptions(dir, "./...", AnalyzerOptions{ImportsOnly: importsOnly, Platform: p})
+			if err != nil {
+				t.Fatalf("%s: analysis failed: %v", platform, err)
+			}
+			app := metrics.Packages["example.com/cross/app"]
+			if len(app.Dependencies) != 1 || app.Dependencies[0] != want {
+				t.Errorf("%s (imports only: %v): expected app to depend on %s, got %v", platform, importsOnly, want, app.Dependencies)
+			}
+		}
+	}
+
+	for _, invalid := range []string{"linux", "/amd64", "linux/amd64/v2"} {
+		if _, err := ParsePlatform(invalid); err == nil {
+			t.Errorf("expected %q to be rejected", invalid)
+		}
+	}
+}
+
+func TestOfflineEnv(t *testing.T) {
+	dir := t.TempDir()
+	setting := func(env []string, key string) string {
+		value := ""
+		for _, kv := range env {
+			if k, v, _ := strings.Cut(kv, "="); k == key {
+				value = v // The last setting wins
+			}
+		}
+		return value
+	}
+
+	if env := NewModuleAnalyzerWithOptions(dir, "./...", AnalyzerOptions{}).env(); env != nil {
+		t.Errorf("expected the environment to be inherited, got %v", env)
+	}
+	env := NewModuleAnalyzerWithOptions(dir, "./...", AnalyzerOptions{Offline: true}).env()
+	for key, want := range map[string]string{"GOPROXY": "off", "GOSUMDB": "off", "GOTOOLCHAIN": "local", "GOFLAGS": "-mod=mod"} {
+		if got := setting(env, key); got != want {
+			t.Errorf("expected %s=%s offline, got %q", key, want, got)
+		}
+	}
+
+	writeFiles(t, dir, map[string]string{"vendor/modules.txt": ""})
+	env = NewModuleAnalyzerWithOptions(dir, "./...", AnalyzerOptions{Offline: true}).env()
+	if got := setting(env, "GOFLAGS"); got != "-mod=vendor" {
+		t.Errorf("expected vendored modules to be read from vendor/, got GOFLAGS=%q", got)
+	}
+}
+
+func TestPackageSynopsis(t *testing.T) {
+	files := []struct{ path, src string }{
+		{"store/a.go", "package store\n"},
+		{"store/b.go", "// Package store keeps orders. It is not thread-safe.\npackage store\n"},
+		{"store/doc.go", "/*\nPackage store persists orders in PostgreSQL.\n\nDetails follow.\n*/\npackage store\n"},
+		{"store/c.go", "// Package store is documented twice.\npackage store\n"},
+	}
+
+	var picker synopsisPicker
+	fset := token.NewFileSet()
+	for i, f := range files {
+		file, err := parser.ParseFile(fset, f.path, f.src, parser.ParseComments)
+		if err != nil {
+			t.Fatal(err)
+		}
+		picker.add(f.path, file)
+		if i == 1 && picker.synopsis != "Package store keeps orders." {
+			t.Errorf("expected the first package comment's synopsis, got %q", picker.synopsis)
+		}
+	}
+	if picker.synopsis != "Package store persists orders in PostgreSQL." {
+		t.Errorf("expected doc.go to win, got %q", picker.synopsis)
+	}
+}
+
+func TestAnalyzeContext(t *testing.T) {
+	dir := writeModule(t, map[string]string{
+		"go.mod":     "module example.com/slow\n\ngo 1.21\n",
+		"app/app.go": "package app\n",
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for _, importsOnly := range []bool{false, true} {
+		_, err := AnalyzeModuleContext(ctx, dir, "./...", AnalyzerOptions{ImportsOnly: importsOnly})
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("imports only: %v: expected context.Canceled, got %v", importsOnly, err)
+		}
+	}
+
+	metrics, err := AnalyzeModuleContext(context.Background(), dir, "./...", AnalyzerOptions{})
+	if err != nil || len(metrics.Packages) != 1 {
+		t.Errorf("expected one package without cancellation, got %v, %v", metrics, err)
+	}
+}
+
+func TestDisplayNameDisambiguation(t *testing.T) {
+	a := NewModuleAnalyzer("", "")
+	a.moduleName = "example.com/shop"
+	a.dependencies = map[string][]string{
+		"example.com/shop":          {"example.com/shop/shop", "github.com/x/util/log", "github.com/y/util/log"},
+		"example.com/shop/shop":     {"example.com/shop/util/log"},
+		"example.com/shop/util/log": {"github.com/x/util/log"},
+	}
+
+	metrics := a.calculateMetrics()
+
+	want := map[string]string{
+		"example.com/shop":          "example.com/shop",
+		"example.com/shop/shop":     "shop",
+		"example.com/shop/util/log": "util/log",
+	}
+	for id, name := range want {
+		if got := metrics.Packages[id].Name; got != name {
+			t.Errorf("%s: expected display name %q, got %q", id, name, got)
+		}
+	}
+	if deps := metrics.Packages["example.com/shop"].Dependencies; strings.Join(deps, " ") != "shop x/util/log y/util/log" {
+		t.Errorf("expected the colliding dependencies to be extended, got %v", deps)
+	}
+	if metrics.Names["x/util/log"] != "github.com/x/util/log" || metrics.Names["example.com/shop"] != "example.com/shop" {
+		t.Errorf("unexpected name table %v", metrics.Names)
+	}
+	if _, ok := metrics.Names["util/log"]; ok {
+		t.Errorf("expected module packages to be left out of the name table, got %v", metrics.Names)
+	}
+}
+
+func TestClosure(t *testing.T) {
+	// cmd -> payment -> store -> models, cmd -> billing -> models; shipping is unrelated
+	metrics := &models.ModuleMetrics{
+		Packages: map[string]models.PackageMetrics{
+			"m/cmd":      {Name: "cmd", Ce: 2, Dependencies: []string{"billing", "payment"}},
+			"m/payment":  {Name: "payment", Ca: 1, Ce: 1, Dependencies: []string{"store"}, Dependents: []string{"cmd"}},
+			"m/store":    {Name: "store", Ca: 1, Ce: 1, Dependencies: []string{"models"}, Dependents: []string{"payment"}},
+			"m/billing":  {Name: "billing", Ca: 1, Ce: 1, Nc: 4, Na: 1, Dependencies: []string{"models"}, Dependents: []string{"cmd"}},
+			"m/models":   {Name: "models", Ca: 2, Dependents: []string{"billing", "store"}},
+			"m/shipping": {Name: "shipping", Nc: 2},
+		},
+		Findings: []models.Finding{
+			{Category: models.CategorySDP, Package: "store"},
+			{Category: models.CategoryDataBag, Package: "billing"},
+		},
+	}
+
+	focused, err := Closure(metrics, "payment")
+	if err != nil {
+		t.Fatalf("Closure failed: %v", err)
+	}
+
+	for _, id := range []string{"m/cmd", "m/payment", "m/store", "m/models", CollapsedPackage} {
+		if _, ok := focused.Packages[id]; !ok {
+			t.Errorf("Expected %s in the closure", id)
+		}
+	}
+	if len(focused.Packages) != 5 {
+		t.Errorf("Expected 4 packages and the collapsed one, got %d", len(focused.Packages))
+	}
+
+	if cmd := focused.Packages["m/cmd"]; cmd.Ce != 2 || strings.Join(cmd.Dependencies, ",") != CollapsedPackage+",payment" {
+		t.Errorf("Expected cmd to keep Ce and depend on the collapsed package, got Ce=%d %v", cmd.Ce, cmd.Dependencies)
+	}
+
+	rest := focused.Packages[CollapsedPackage]
+	if rest.Ca != 1 || rest.Ce != 1 || rest.Nc != 6 || rest.Na != 1 || !rest.GateExempt {
+		t.Errorf("Unexpected collapsed package: %+v", rest)
+	}
+
+	if len(focused.Findings) != 1 || focused.Findings[0].Package != "store" {
+		t.Errorf("Expected only the finding of store, got %+v", focused.Findings)
+	}
+
+	if _, err := Closure(metrics, "pkg/unknown/..."); err == nil {
+		t.Error("Expected an error for a pattern matching no package")
+	}
+}
+
+func TestShortestPaths(t *testing.T) {
+	// cmd -> payment -> store -> models, cmd -> billing -> models, store -> yaml.v3
+	metrics := &models.ModuleMetrics{
+		Packages: map[string]models.PackageMetrics{
+			"m/cmd":      {Name: "cmd", Dependencies: []string{"billing", "payment"}},
+			"m/payment":  {Name: "payment", Dependencies: []string{"store"}},
+			"m/store":    {Name: "store", Dependencies: []string{"models", "yaml.v3"}},
+			"m/billing":  {Name: "billing", Dependencies: []string{"models"}},
+			"m/models":   {Name: "models"},
+			"m/shipping": {Name: "shipping"},
+		},
+		Names: map[string]string{"yaml.v3": "gopkg.in/yaml.v3"},
+	}
+
+	for _, tc := range []struct {
+		from, to string
+		limit    int
+		want     string
+	}{
+		{"cmd", "models", 0, "cmd billing models"},
+		{"payment", "m/models", 0, "payment store models"},
+		{"cmd", "gopkg.in/yaml.v3", 0, "cmd payment store yaml.v3"},
+		{"models", "cmd", 0, ""},
+		{"shipping", "models", 0, ""},
+	} {
+		paths, err := ShortestPaths(metrics, tc.from, tc.to, tc.limit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, path := range paths {
+			got = append(got, strings.Join(path, " "))
+		}
+		if strings.Join(got, "|") != tc.want {
+			t.Errorf("%s -> %s: expected %q, got %q", tc.from, tc.to, tc.want, strings.Join(got, "|"))
+		}
+	}
+
+	// Both routes from cmd to store's dependency models are equally short
+	metrics.Packages["m/cmd"] = models.PackageMetrics{Name: "cmd", Dependencies: []string{"billing", "store"}}
+	paths, err := ShortestPaths(metrics, "cmd", "models", 0)
+	if err != nil || len(paths) != 2 {
+		t.Errorf("expected two shortest paths, got %v (%v)", paths, err)
+	}
+	if paths, _ := ShortestPaths(metrics, "cmd", "models", 1); len(paths) != 1 {
+		t.Errorf("expected the limit to apply, got %v", paths)
+	}
+	if _, err := ShortestPaths(metrics, "cmd", "nowhere", 0); err == nil {
+		t.Error("expected an error for an unknown package")
+	}
+}
+
+func TestIncludeTests(t *testing.T) {
+	dir := writeModule(t, map[string]string{
+		"go.mod":               "module example.com/shop\n\ngo 1.21\n",
+		"store/store.go":       "package store\n\ntype Store struct{}\n",
+		"store/fake_test.go":   "package store\n\nimport \"example.com/shop/util\"\n\ntype fake interface{ Get() }\n\nvar _ = util.X\n",
+		"store/store_test.go":  "package store_test\n\nimport \"example.com/shop/store\"\n\nvar _ store.Store\n",
+		"util/util.go":         "package util\n\nvar X int\n",
+		"e2e/checkout_test.go": "package e2e\n\nimport \"example.com/shop/store\"\n\nvar _ store.Store\n",
+	})
+
+	for _, importsOnly := range []bool{false, true} {
+		metrics, err := AnalyzeModuleWithOptions(dir, "./...", AnalyzerOptions{ImportsOnly: importsOnly})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(metrics.Packages) != 2 || metrics.Packages["example.com/shop/store"].Ce != 0 {
+			t.Errorf("imports only: %v: expected test code to be ignored by default, got %+v", importsOnly, metrics.Packages)
+		}
+
+		metrics, err = AnalyzeModuleWithOptions(dir, "./...", AnalyzerOptions{ImportsOnly: importsOnly, IncludeTests: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for id := range metrics.Packages {
+			names = append(names, id)
+		}
+		sort.Strings(names)
+		if got := strings.Join(names, " "); got != "example.com/shop/e2e example.com/shop/store example.com/shop/store_test example.com/shop/util" {
+			t.Errorf("imports only: %v: unexpected packages with tests: %s", importsOnly, got)
+		}
+
+		store := metrics.Packages["example.com/shop/store"]
+		if store.Ce != 1 || store.Ca != 2 {
+			t.Errorf("imports only: %v: expected store with Ce=1 (util) and Ca=2 (store_test, e2e), got Ce=%d Ca=%d", importsOnly, store.Ce, store.Ca)
+		}
+		if !importsOnly && (store.Na != 1 || store.Nc != 2) {
+			t.Errorf("expected the test interface to count, got Na=%d Nc=%d", store.Na, store.Nc)
+		}
+		if name := metrics.Packages["example.com/shop/store_test"].Name; name != "store_test" {
+			t.Errorf("imports only: %v: expected the external test package as store_test, got %q", importsOnly, name)
+		}
+	}
+}
+
+func TestBuildTags(t *testing.T) {
+	dir := writeModule(t, map[string]string{
+		"go.mod":             "module example.com/tagged\n\ngo 1.21\n",
+		"app/app.go":         "package app\n",
+		"app/integration.go": "//go:build integration\n\npackage app\n\nimport _ \"example.com/tagged/db\"\n\ntype Fixture struct{}\n",
+		"db/db.go":           "package db\n",
+	})
+
+	for _, importsOnly := range []bool{false, true} {
+		for _, tags := range [][]string{nil, {"integration"}} {
+			metrics, err := AnalyzeModuleWithOptions(dir, "./...", AnalyzerOptions{ImportsOnly: importsOnly, BuildTags: tags})
+			if err != nil {
+				t.Fatal(err)
+			}
+			app := metrics.Packages["example.com/tagged/app"]
+			wantCe := len(tags)
+			if app.Ce != wantCe {
+				t.Errorf("imports only: %v, tags %v: expected Ce=%d, got %d", importsOnly, tags, wantCe, app.Ce)
+			}
+			if !importsOnly && app.Nc != wantCe {
+				t.Errorf("tags %v: expected Nc=%d, got %d", tags, wantCe, app.Nc)
+			}
+		}
+	}
+}
+
+func TestFailFast(t *testing.T) {
+	// The go command rejects import cycles, so they are only seen in imports-only mode
+	cyclic := writeModule(t, map[string]string{
+		"go.mod": "module example.com/cyclic\n\ngo 1.21\n",
+		"a/a.go": "package a\n\nimport _ \"example.com/cyclic/b\"\n",
+		"b/b.go": "package b\n\nimport _ \"example.com/cyclic/a\"\n",
+		"c/c.go": "package c\n",
+	})
+	_, err := AnalyzeModuleWithOptions(cyclic, "./...", AnalyzerOptions{ImportsOnly: true, FailFast: true, FailFastSeverity: models.SeverityError})
+	var gateErr *GateError
+	if !errors.As(err, &gateErr) || gateErr.Finding.Category != models.CategoryCycle {
+		t.Errorf("expected the cycle to stop the analysis, got %v", err)
+	}
+
+	// A cycle finding below the gated severity does not stop it
+	metrics, err := AnalyzeModuleWithOptions(cyclic, "./...", AnalyzerOptions{ImportsOnly: true, FailFast: true, FailFastSeverity: models.SeverityError,
+		Severities: map[string]models.Severity{models.CategoryCycle: models.SeverityWarning}})
+	if err != nil || len(metrics.Packages) != 3 {
+		t.Errorf("expected a complete analysis, got %v", err)
+	}
+
+	// core is concrete and depended upon: its distance is final once app is analyzed
+	layered := writeModule(t, map[string]string{
+		"go.mod":     "module example.com/layered\n\ngo 1.21\n",
+		"core/c.go":  "package core\n\ntype Store struct{}\n",
+		"app/app.go": "package app\n\nimport _ \"example.com/layered/core\"\n\ntype Reader interface{ Read() }\n",
+	})
+	_, err = AnalyzeModuleWithOptions(layered, "./...", AnalyzerOptions{FailFast: true, Thresholds: &models.Thresholds{MaxDistance: 0.5, MaxInstability: 1}})
+	if !errors.As(err, &gateErr) || gateErr.Finding.Package != "core" {
+		t.Errorf("expected the distance of core to stop the analysis, got %v", err)
+	}
+}
+
+// recordingReporter records the stages and progress updates of an analysis
+type recordingReporter struct {
+	events []string // "start <stage> <total>", "done <stage>"
+	stage  models.Stage
+	totals map[models.Stage]int
+	steps  map[models.Stage][]int
+	notes  map[models.Stage][]string
+}
+
+func (r *recordingReporter) StageStart(stage models.Stage, total int) {
+	r.events = append(r.events, fmt.Sprintf("start %s %d", stage, total))
+	r.stage = stage
+	r.totals[stage] = total
+}
+
+func (r *recordingReporter) Update(current int, description string) {
+	r.steps[r.stage] = append(r.steps[r.stage], current)
+	r.notes[r.stage] = append(r.notes[r.stage], description)
+}
+
+func (r *recordingReporter) StageDone(stage models.Stage) {
+	r.events = append(r.events, "done "+string(stage))
+}
+
+func (r *recordingReporter) Complete() {}
+
+func TestParsingProgress(t *testing.T) {
+	dir := writeModule(t, map[string]string{
+		"go.mod":         "module example.com/shop\n\ngo 1.21\n",
+		"store/store.go": "package store\n\ntype Order struct{}\n",
+		"api/api.go":     "package api\n\nimport \"example.com/shop/store\"\n\nvar _ = store.Order{}\n",
+	})
+
+	reporter := &recordingReporter{totals: map[models.Stage]int{}, steps: map[models.Stage][]int{}, notes: map[models.Stage][]string{}}
+	if _, err := AnalyzeModuleWithOptions(dir, "./...", AnalyzerOptions{ProgressReporter: reporter}); err != nil {
+		t.Fatal(err)
+	}
+	want := "start discovery 0, done discovery, start loading 2, done loading, start parsing 2, done parsing"
+	if got := strings.Join(reporter.events, ", "); got != want {
+		t.Errorf("expected stages %s, got %s", want, got)
+	}
+	for stage, steps := range reporter.steps {
+		total := reporter.totals[stage]
+		for i := range steps {
+			if i > 0 && steps[i] < steps[i-1] || total > 0 && steps[i] > total {
+				t.Errorf("expected the %s progress to grow up to %d, got %v", stage, total, steps)
+				break
+			}
+		}
+	}
+	parsing := strings.Join(reporter.notes[models.StageParsing], "\n")
+	for _, want := range []string{"Parsing store", "Parsing api", "(2/2)"} {
+		if !strings.Contains(parsing, want) {
+			t.Errorf("expected %q in the parsing updates, got\n%s", want, parsing)
+		}
+	}
+	if steps := reporter.steps[models.StageParsing]; steps[len(steps)-1] != 2 {
+		t.Errorf("expected the parsing progress to end at 2, got %v", steps)
+	}
+}
+
+func TestCheckRules(t *testing.T) {
+	dir := writeModule(t, map[string]string{
+		"go.mod":           "module example.com/shop\n\ngo 1.21\n",
+		"db/db.go":         "package db\n\nfunc Query() {}\n",
+		"ui/ui.go":         "package ui\n\nimport \"example.com/shop/db\"\n\nfunc Render() { db.Query() }\n",
+		"ui/forms/form.go": "package forms\n\nfunc Show() {}\n",
+		"domain/domain.go": "package domain\n\nfunc Name() string { return \"x\" }\n",
+	})
+
+	rules := []DependencyRule{
+		{From: []string{"ui/..."}, Deny: []string{"db/..."}},
+		{From: []string{"ui/forms"}, Deny: []string{"db/..."}},
+		{Name: "domain stays pure", From: []string{"domain", "domian"}, Allow: []string{"db"}},
+		{Name: "nothing imports db", Except: []string{"ui/..."}, Deny: []string{"db"}},
+		{Name: "broken", From: []string{"./ui", "ui/.../x"}},
+	}
+	checks, err := CheckRules(context.Background(), dir, AnalyzerOptions{Rules: rules})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, check := range checks {
+		got = append(got, fmt.Sprintf("%s [%s] [%s]: %s", check.Description, strings.Join(check.Packages, " "),
+			strings.Join(check.Violators, " "), strings.Join(check.Problems, "; ")))
+	}
+	want := []string{
+		"ui/... may not import db/... [ui ui/forms] [ui]: ",
+		`ui/forms may not import db/... [ui/forms] []: is redundant: rule "ui/... may not import db/..." already denies the same imports for all its packages`,
+		`domain stays pure [domain] []: from pattern "domian" matches no package of the module; conflicts with rule "nothing imports db", which denies the allowed db`,
+		"nothing imports db [db domain] []: ",
+		`broken [] []: needs deny or allow patterns; applies to no package; pattern "./ui" must be a path without leading ./ or / and trailing /; pattern "ui/.../x" may only end with /...`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("expected rule checks\n%s\ngot\n%s", strings.Join(want, "\n"), strings.Join(got, "\n"))
+	}
+}
+
+func TestRunRuleTests(t *testing.T) {
+	rules := []DependencyRule{
+		{From: []string{"ui/..."}, Deny: []string{"db/..."}},
+		{Except: []string{"adapters/..."}, Deny: []string{"github.com/aws/..."}},
+	}
+	tests := []RuleTest{
+		{Name: "ui cannot reach the database", Packages: map[string][]string{"ui/forms": {"db", "domain"}}, Violations: []string{"ui/forms->db"}},
+		{Name: "adapters use the SDK", Packages: map[string][]string{"adapters/s3": {"github.com/aws/aws-sdk-go-v2/service/s3", "db"}}},
+		{Name: "wrong expectation", Packages: map[string][]string{"domain": {"github.com/aws/aws-sdk-go-v2/service/s3"}}, Violations: []string{"domain -> db"}},
+	}
+	results := RunRuleTests(rules, tests)
+	for _, result := range results[:2] {
+		if !result.Passed() {
+			t.Errorf("expected %q to pass, got unexpected %v and missing %v", result.Test.Name, result.Unexpected, result.Missing)
+		}
+	}
+	failed := results[2]
+	if failed.Passed() || strings.Join(failed.Unexpected, " ") != "domain -> github.com/aws/aws-sdk-go-v2/service/s3" || strings.Join(failed.Missing, " ") != "domain -> db" {
+		t.Errorf("expected the unexpected SDK import and the missing db import, got %v and %v", failed.Unexpected, failed.Missing)
+	}
+}
+
+func TestWorkers(t *testing.T) {
+	dir := writeModule(t, map[string]string{
+		"go.mod":         "module example.com/shop\n\ngo 1.21\n",
+		"store/store.go": "package store\n\ntype Store interface{ Get() }\n",
+		"cache/cache.go": "package cache\n\nimport \"example.com/shop/store\"\n\nvar _ store.Store\n\nfunc New() {}\n",
+		"api/api.go":     "package api\n\nimport (\n\t\"example.com/shop/cache\"\n\t\"example.com/shop/store\"\n)\n\nvar _ store.Store\n\nvar _ = cache.New\n",
+	})
+
+	for _, importsOnly := range []bool{false, true} {
+		var results []map[string]models.PackageMetrics
+		for _, workers := range []int{1, 16, 0} {
+			metrics, err := AnalyzeModuleWithOptions(dir, "./...", AnalyzerOptions{Workers: workers, ImportsOnly: importsOnly})
+			if err != nil {
+				t.Fatal(err)
+			}
+			results = append(results, metrics.Packages)
+		}
+		if !reflect.DeepEqual(results[0], results[1]) || !reflect.DeepEqual(results[0], results[2]) {
+			t.Errorf("expected the same metrics with 1, 16 and GOMAXPROCS workers (imports only: %v)", importsOnly)
+		}
+	}
+}
+
+func TestAnalyzeIncremental(t *testing.T) {
+	dir := writeModule(t, map[string]string{
+		"go.mod":         "module example.com/shop\n\ngo 1.21\n",
+		"db/db.go":       "package db\n\ntype Conn struct{}\n",
+		"store/store.go": "package store\n\nimport \"example.com/shop/db\"\n\ntype Order struct{ c db.Conn }\n",
+		"api/api.go":     "package api\n\nimport \"example.com/shop/store\"\n\nvar _ = store.Order{}\n",
+		"util/util.go":   "package util\n\nfunc Max(a, b int) int { return max(a, b) }\n",
+	})
+
+	// summary describes the metrics an incremental update must get right
+	summary := func(metrics *models.ModuleMetrics) string {
+		var lines []string
+		for _, id := range sortedPackageIDs(metrics.Packages) {
+			pkg := metrics.Packages[id]
+			lines = append(lines, fmt.Sprintf("%s Ca=%d Ce=%d Na=%d Nc=%d deps=%v dependents=%v", pkg.Name, pkg.Ca, pkg.Ce,
+				pkg.Na, pkg.Nc, pkg.Dependencies, pkg.Dependents))
+		}
+		return strings.Join(lines, "\n")
+	}
+	reporter := &recordingReporter{totals: map[models.Stage]int{}, steps: map[models.Stage][]int{}, notes: map[models.Stage][]string{}}
+	a := NewModuleAnalyzerWithOptions(dir, "./...", AnalyzerOptions{ProgressReporter: reporter})
+	metrics, err := a.Analyze()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, step := range []struct {
+		name    string
+		write   map[string]string
+		changed []string
+		loaded  int // Packages loaded again
+	}{
+		{"no Go file changed", nil, []string{"README.md", "db/db_test.go"}, 0},
+		{"leaf package changed", map[string]string{"util/util.go": "package util\n\ntype Sorter interface{ Less() bool }\n"}, []string{"util/util.go"}, 1},
+		{"dependents of a changed package", map[string]string{"db/db.go": "package db\n\ntype Conn interface{ Close() }\n"}, []string{filepath.Join(dir, "db/db.go")}, 3},
+		{"import added", map[string]string{"util/util.go": "package util\n\nimport \"example.com/shop/db\"\n\nvar _ db.Conn\n"}, []string{"util/util.go"}, 1},
+		{"new package", map[string]string{"auth/auth.go": "package auth\n\nimport \"example.com/shop/util\"\n\nvar _ = util.Sorter(nil)\n"}, []string{"auth/auth.go"}, 5},
+		{"go.mod changed", nil, []string{"go.mod"}, 5},
+	} {
+		writeFiles(t, dir, step.write)
+		reporter.events, reporter.totals[models.StageLoading] = nil, 0
+		metrics, err = a.AnalyzeIncremental(metrics, step.changed)
+		if err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		if got := reporter.totals[models.StageLoading]; got != step.loaded {
+			t.Errorf("%s: expected %d packages to be loaded, got %d", step.name, step.loaded, got)
+		}
+		full, err := AnalyzeModule(dir, "./...")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := summary(metrics), summary(full); got != want {
+			t.Errorf("%s: expected the metrics of a full analysis\n%s\ngot\n%s", step.name, want, got)
+		}
+	}
+
+	// Metrics that are not the analyzer's latest ones are not updated
+	reporter.totals[models.StageLoading] = 0
+	if _, err := a.AnalyzeIncremental(&models.ModuleMetrics{}, []string{"util/util.go"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := reporter.totals[models.StageLoading]; got != 5 {
+		t.Errorf("expected a full analysis for foreign metrics, got %d packages loaded", got)
+	}
+}
+
+func TestDiagnostics(t *testing.T) {
+	dir := writeModule(t, map[string]string{
+		"go.mod":         "module example.com/shop\n\ngo 1.21\n",
+		"store/store.go": "package store\n\ntype Store struct{}\n\nfunc New() Store { return undefined }\n",
+		"api/api.go":     "package api\n\nimport \"example.com/shop/store\"\n\nvar _ = store.New\n",
+	})
+
+	metrics, err := AnalyzeModuleWithOptions(dir, "./...", AnalyzerOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(metrics.Diagnostics) != 1 {
+		t.Fatalf("expected a diagnostic for the type error of store, got %+v", metrics.Diagnostics)
+	}
+	want := models.Diagnostic{Package: "store", Kind: "type", Position: "store/store.go:5:27", Message: "undefined: undefined"}
+	if metrics.Diagnostics[0] != want {
+		t.Errorf("expected %+v, got %+v", want, metrics.Diagnostics[0])
+	}
+	if _, ok := metrics.Packages["example.com/shop/store"]; !ok {
+		t.Error("expected the package with a type error to be analyzed still")
+	}
+}
+
+func TestPartialPackages(t *testing.T) {
+	dir := writeModule(t, map[string]string{
+		"go.mod":         "module example.com/shop\n\ngo 1.21\n",
+		"db/db.go":       "package db\n\ntype Conn interface{ Close() }\n",
+		"store/store.go": "package store\n\nimport (\n\tbad bad\n\t\"example.com/shop/db\"\n)\n\nvar _ db.Conn\n",
+		"store/junk.go":  "packge store\n",
+		"api/api.go":     "package api\n\nimport \"example.com/shop/store\"\n\nfunc F() { store.X( }\n",
+	})
+
+	for _, options := range []AnalyzerOptions{{}, {ImportsOnly: true}} {
+		metrics, err := AnalyzeModuleWithOptions(dir, "./...", options)
+		if err != nil {
+			t.Fatalf("imports-only=%v: expected broken files not to abort the analysis, got %v", options.ImportsOnly, err)
+		}
+		for id, partial := range map[string]bool{"example.com/shop/db": false, "example.com/shop/store": true} {
+			if pkg, ok := metrics.Packages[id]; !ok || pkg.Partial != partial {
+				t.Errorf("imports-only=%v: expected %s to be reported with partial=%v, got %+v", options.ImportsOnly, id, partial, pkg)
+			}
+		}
+		if options.ImportsOnly {
+			// The imports of the file after the syntax error are still read
+			if deps := metrics.Packages["example.com/shop/store"].Dependencies; !slices.Contains(deps, "db") {
+				t.Errorf("expected the import of db to be recovered, got %v", deps)
+			}
+		} else if !metrics.Packages["example.com/shop/api"].Partial {
+			t.Error("expected api, with a syntax error in a function body, to be partial")
+		}
+		if len(metrics.Diagnostics) == 0 {
+			t.Errorf("imports-only=%v: expected diagnostics for the syntax errors", options.ImportsOnly)
+		}
+	}
+}
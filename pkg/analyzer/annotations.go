@@ -0,0 +1,153 @@
+// Package analyzer provides functionality for analyzing Go modules and calculating design metrics.
+// This file implements the aid-metrics: annotation comments of packages.
+package analyzer
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/alkbt/aid-metrics/pkg/models"
+)
+
+// annotationPrefix starts the annotation comments in the doc.go file of a
+// package, e.g. "//aid-metrics:ignore", "//aid-metrics:max-distance=0.9" or
+// "//aid-metrics:allow=AM002".
+// Like Go directives they have no space after the slashes; text after the
+// annotation, separated by a space, is a free-form reason.
+const annotationPrefix = "//aid-metrics:"
+
+// Annotations of packages
+const (
+	annotationIgnore          = "ignore"           // Leave the package out of the analysis, like an exclude pattern
+	annotationMaxDistance     = "max-distance"     // Override the maximum distance of the package
+	annotationMaxInstability  = "max-instability"  // Override the maximum instability of the package
+	annotationMinAbstractness = "min-abstractness" // Override the minimum abstractness of the package
+	annotationAllow           = "allow"            // Accept the findings of an ID about the package as intentional
+)
+
+// fileAnnotations returns the annotations of a file, as "ignore" or
+// "max-distance=0.9", if it is the doc.go file of its package. Unknown
+// annotations and invalid thresholds are skipped and returned as diagnostics,
+// so a typo costs only the annotation, not the analysis.
+func (a *ModuleAnalyzer) fileAnnotations(fset *token.FileSet, path string, file *ast.File) ([]string, []models.Diagnostic) {
+	if filepath.Base(path) != "doc.go" {
+		return nil, nil
+	}
+	var annotations []string
+	var diagnostics []models.Diagnostic
+	for _, group := range file.Comments {
+		for _, comment := range group.List {
+			text, ok := strings.CutPrefix(comment.Text, annotationPrefix)
+			if !ok {
+				continue
+			}
+			annotation, _, _ := strings.Cut(text, " ")
+			if err := validateAnnotation(annotation); err != nil {
+				diagnostics = append(diagnostics, models.Diagnostic{
+					Kind:     annotationDiagnostic,
+					Position: a.relativePosition(fset.Position(comment.Pos()).String()),
+					Message:  fmt.Sprintf("invalid annotation %s%s: %v", annotationPrefix, annotation, err),
+				})
+				continue
+			}
+			annotations = append(annotations, annotation)
+		}
+	}
+	return annotations, diagnostics
+}
+
+// validateAnnotation checks that an annotation is known and that thresholds
+// have a value between 0 and 1
+func validateAnnotation(annotation string) error {
+	name, value, hasValue := strings.Cut(annotation, "=")
+	switch name {
+	case annotationIgnore:
+		if hasValue {
+			return fmt.Errorf("%s takes no value", name)
+		}
+		return nil
+	case annotationMaxDistance, annotationMaxInstability, annotationMinAbstractness:
+		threshold, err := strconv.ParseFloat(value, 64)
+		if err != nil || threshold < 0 || threshold > 1 {
+			return fmt.Errorf("%s needs a value between 0 and 1", name)
+		}
+		return nil
+	case annotationAllow:
+		for _, id := range models.FindingIDs {
+			if value == id {
+				return nil
+			}
+		}
+		return fmt.Errorf("%s needs a finding ID such as AM002", name)
+	}
+	return fmt.Errorf("unknown annotation %q", name)
+}
+
+// isIgnored reports whether the annotations leave the package out of the analysis
+func isIgnored(annotations []string) bool {
+	for _, annotation := range annotations {
+		if annotation == annotationIgnore {
+			return true
+		}
+	}
+	return false
+}
+
+// allows reports whether the annotations accept the findings of an ID as intentional
+func allows(annotations []string, findingID string) bool {
+	for _, annotation := range annotations {
+		if annotation == annotationAllow+"="+findingID {
+			return true
+		}
+	}
+	return false
+}
+
+// withoutAllowed drops the findings the annotations of their packages accept
+func withoutAllowed(metrics *models.ModuleMetrics, findings []models.Finding) []models.Finding {
+	annotations := make(map[string][]string)
+	for _, pkg := range metrics.Packages {
+		if len(pkg.Annotations) > 0 {
+			annotations[pkg.Name] = pkg.Annotations
+		}
+	}
+	if len(annotations) == 0 {
+		return findings
+	}
+	kept := findings[:0]
+	for _, finding := range findings {
+		if !allows(annotations[finding.Package], finding.ID) {
+			kept = append(kept, finding)
+		}
+	}
+	return kept
+}
+
+// packageThresholds returns the thresholds a package is held to: the module
+// thresholds with the overrides of its annotations, nil without module thresholds
+func (a *ModuleAnalyzer) packageThresholds(annotations []string) *models.Thresholds {
+	if a.options.Thresholds == nil {
+		return nil
+	}
+	thresholds := *a.options.Thresholds
+	for _, annotation := range annotations {
+		name, value, _ := strings.Cut(annotation, "=")
+		threshold, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			continue
+		}
+		switch name {
+		case annotationMaxDistance:
+			thresholds.MaxDistance = threshold
+		case annotationMaxInstability:
+			thresholds.MaxInstability = threshold
+		case annotationMinAbstractness:
+			thresholds.MinAbstractness = threshold
+		}
+	}
+	return &thresholds
+}
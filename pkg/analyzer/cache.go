@@ -0,0 +1,419 @@
+// Package analyzer provides functionality for analyzing Go modules and calculating design metrics.
+// This file implements caching of per-package analysis results by content hash.
+package analyzer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/alkbt/aid-metrics/pkg/models"
+	"golang.org/x/tools/go/packages"
+)
+
+// cacheFormatVersion is part of every cache key. It must be bumped whenever the
+// analysis of a single package or the encoding of cachedResult changes.
+const cacheFormatVersion = "aid-metrics/18"
+
+// ResultCache stores encoded per-package analysis results by content hash.
+// Keys are lowercase hex SHA-256 digests. Implementations must be safe for
+// concurrent use; see package cache for a local directory and a remote HTTP cache.
+type ResultCache interface {
+	// Get returns the data stored under key. A missing key is not an error.
+	Get(key string) (data []byte, ok bool, err error)
+
+	// Put stores data under key
+	Put(key string, data []byte) error
+}
+
+// analyzePackageCached analyzes a package, reusing a cached result when one is available.
+// The cache is best-effort: lookups and stores that fail fall back to a normal analysis.
+func (a *ModuleAnalyzer) analyzePackageCached(pkg *packages.Package) packageAnalysisResult {
+	if a.options.Cache == nil || isStandardLibraryPackage(pkg.ID, a.moduleName) || strings.HasPrefix(pkg.ID, "vendor/") {
+		return a.analyzePackage(pkg)
+	}
+
+	key, err := a.packageKey(pkg)
+	if err != nil {
+		return a.analyzePackage(pkg)
+	}
+
+	if data, ok, err := a.options.Cache.Get(key); err == nil && ok {
+		var cached cachedResult
+		if err := json.Unmarshal(data, &cached); err == nil {
+			return cached.result(packageID(pkg.ID))
+		}
+	}
+
+	result := a.analyzePackage(pkg)
+	if result.err == nil {
+		if data, err := json.Marshal(newCachedResult(result)); err == nil {
+			_ = a.options.Cache.Put(key, data)
+		}
+	}
+	return result
+}
+
+// packageKey computes the cache key of a package. It covers the analyzer version,
+// the options affecting a single package's analysis, go.sum (which pins third-party
+// dependencies), and the sources of the package and every module package it
+// transitively imports, since type information flows in from those.
+func (a *ModuleAnalyzer) packageKey(pkg *packages.Package) (string, error) {
+	h := sha256.New()
+	io.WriteString(h, cacheFormatVersion+"\n")
+	io.WriteString(h, a.moduleName+"\n")
+	io.WriteString(h, pkg.ID+"\n")
+	if p := a.options.Platform; p != (Platform{}) {
+		io.WriteString(h, "platform "+p.String()+"\n")
+	}
+	if len(a.options.BuildTags) > 0 {
+		io.WriteString(h, "tags "+strings.Join(a.options.BuildTags, ",")+"\n")
+	}
+	if a.options.ExportedOnly {
+		io.WriteString(h, "exported-only\n")
+	}
+	if a.options.SkipGeneratedImports {
+		io.WriteString(h, "skip-generated imports\n")
+	} else if a.options.SkipGenerated {
+		io.WriteString(h, "skip-generated\n")
+	}
+	for _, rule := range a.options.RoleRules {
+		io.WriteString(h, "role "+rule.Pattern+" "+rule.Role+"\n")
+	}
+	if goSum, err := os.ReadFile(filepath.Join(a.modulePath, "go.sum")); err == nil {
+		h.Write(goSum)
+	}
+
+	// Collect the package and its transitive module dependencies
+	module := make(map[string]*packages.Package)
+	var visit func(p *packages.Package)
+	visit = func(p *packages.Package) {
+		if _, seen := module[p.ID]; seen {
+			return
+		}
+		module[p.ID] = p
+		for _, imp := range p.Imports {
+			if !isStandardLibraryPackage(imp.ID, a.moduleName) && a.moduleName != "" && strings.HasPrefix(imp.ID, a.moduleName) {
+				visit(imp)
+			}
+		}
+	}
+	visit(pkg)
+
+	ids := make([]string, 0, len(module))
+	for id := range module {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		digest, err := a.contentDigest(module[id])
+		if err != nil {
+			return "", err
+		}
+		io.WriteString(h, id+" "+digest+"\n")
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// contentDigest returns the hash of a package's import list and source files.
+// Digests are memoized, as most packages are imported by several others.
+func (a *ModuleAnalyzer) contentDigest(pkg *packages.Package) (string, error) {
+	if digest, ok := a.digests.Load(pkg.ID); ok {
+		return digest.(string), nil
+	}
+
+	h := sha256.New()
+	imports := make([]string, 0, len(pkg.Imports))
+	for path := range pkg.Imports {
+		imports = append(imports, path)
+	}
+	sort.Strings(imports)
+	io.WriteString(h, "name "+pkg.Name+"\n")
+	for _, path := range imports {
+		io.WriteString(h, "import "+path+"\n")
+	}
+
+	files := append([]string(nil), pkg.GoFiles...)
+	sort.Strings(files)
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return "", err
+		}
+		io.WriteString(h, "file "+filepath.Base(file)+"\n")
+		h.Write(content)
+	}
+
+	digest := hex.EncodeToString(h.Sum(nil))
+	a.digests.Store(pkg.ID, digest)
+	return digest, nil
+}
+
+// cachedResult is the encoding of a packageAnalysisResult stored in a ResultCache
+type cachedResult struct {
+	Dependencies    []string           `json:"dependencies"`
+	Exposed         []string           `json:"exposed,omitempty"`
+	Leaks           []cachedLeak       `json:"leaks,omitempty"`
+	Interfaces      []cachedMethods    `json:"interfaces,omitempty"`
+	ConcreteTypes   []cachedMethods    `json:"concrete_types,omitempty"`
+	AbstractCount   int                `json:"abstract_count"`
+	TotalTypesCount int                `json:"total_types_count"`
+	AllAbstract     int                `json:"all_abstract_count"`
+	AllTypes        int                `json:"all_types_count"`
+	StructEmbeds    int                `json:"struct_embeds"`
+	StructFields    int                `json:"struct_fields"`
+	InterfaceEmbeds int                `json:"interface_embeds"`
+	InterfaceElems  int                `json:"interface_elems"`
+	PointerMethods  int                `json:"pointer_methods"`
+	ValueMethods    int                `json:"value_methods"`
+	ExportedMethods int                `json:"exported_methods"`
+	Unexported      int                `json:"unexported_methods"`
+	Functions       int                `json:"functions"`
+	Complexity      int                `json:"complexity"`
+	MaxComplexity   int                `json:"max_complexity"`
+	Goroutines      int                `json:"goroutines,omitempty"`
+	Channels        int                `json:"channels,omitempty"`
+	SyncUses        int                `json:"sync_uses,omitempty"`
+	Constructors    int                `json:"constructors"`
+	InterfaceCtors  int                `json:"interface_constructors"`
+	DIFramework     string             `json:"di_framework,omitempty"`
+	Role            string             `json:"role"`
+	Files           int                `json:"files"`
+	GeneratedFiles  int                `json:"generated_files"`
+	Generators      []string           `json:"generators,omitempty"`
+	StructCount     int                `json:"struct_count"`
+	TaggedStructs   int                `json:"tagged_structs"`
+	Deprecated      []cachedDeprecated `json:"deprecated,omitempty"`
+	BlankImports    []cachedBlank      `json:"blank_imports,omitempty"`
+	Registrations   []string           `json:"init_registrations,omitempty"`
+	Main            bool               `json:"main,omitempty"`
+	Errors          []cachedError      `json:"errors,omitempty"`
+	ErrorChecks     []string           `json:"error_checks,omitempty"`
+	ConstantSets    []cachedConstants  `json:"constant_sets,omitempty"`
+	ConstantShare   float64            `json:"constant_share,omitempty"`
+	ConstantRefs    []string           `json:"constant_refs,omitempty"`
+	StringKeys      []string           `json:"string_keys,omitempty"`
+	Endpoints       []cachedEndpoint   `json:"endpoints,omitempty"`
+	Synopsis        string             `json:"synopsis,omitempty"`
+	Annotations     []string           `json:"annotations,omitempty"`
+	Diagnostics     []cachedDiagnostic `json:"diagnostics,omitempty"`
+}
+
+// cachedLeak is the encoding of a typeLeak
+type cachedLeak struct {
+	Declaration string `json:"declaration"`
+	Package     string `json:"package"`
+	Type        string `json:"type"`
+}
+
+// cachedDeprecated is the encoding of a deprecatedUse
+type cachedDeprecated struct {
+	Package string `json:"package"`
+	Symbol  string `json:"symbol"`
+	Uses    int    `json:"uses"`
+}
+
+// cachedBlank is the encoding of a blankImport
+type cachedBlank struct {
+	Package       string   `json:"package"`
+	Registrations []string `json:"registrations,omitempty"`
+}
+
+// cachedError is the encoding of an errorDecl
+type cachedError struct {
+	Name string `json:"name"`
+	Kind string `json:"kind"`
+}
+
+// cachedConstants is the encoding of a constantSet
+type cachedConstants struct {
+	Name      string   `json:"name"`
+	Constants []string `json:"constants"`
+}
+
+// cachedMethods is the encoding of a methodSetDecl
+type cachedMethods struct {
+	Name     string   `json:"name"`
+	Exported bool     `json:"exported,omitempty"`
+	Mock     bool     `json:"mock,omitempty"`
+	Methods  []string `json:"methods"`
+}
+
+// cachedDiagnostic is the encoding of a diagnostic found while analyzing a
+// package, such as an invalid annotation
+type cachedDiagnostic struct {
+	Kind     string `json:"kind"`
+	Position string `json:"position,omitempty"`
+	Message  string `json:"message"`
+}
+
+// cachedEndpoint is the encoding of an endpointRegistration
+type cachedEndpoint struct {
+	Route          string `json:"route"`
+	Kind           string `json:"kind"`
+	HandlerPackage string `json:"handler_package"`
+}
+
+// newCachedResult converts an analysis result into its cache encoding
+func newCachedResult(r packageAnalysisResult) cachedResult {
+	cached := cachedResult{
+		Dependencies:    r.dependencies,
+		Exposed:         r.exposed,
+		AbstractCount:   r.abstractCount,
+		TotalTypesCount: r.totalTypesCount,
+		AllAbstract:     r.allAbstractCount,
+		AllTypes:        r.allTypesCount,
+		StructEmbeds:    r.embedding.structEmbeds,
+		StructFields:    r.embedding.structFields,
+		InterfaceEmbeds: r.embedding.interfaceEmbeds,
+		InterfaceElems:  r.embedding.interfaceElems,
+		PointerMethods:  r.methods.pointer,
+		ValueMethods:    r.methods.value,
+		ExportedMethods: r.methods.exported,
+		Unexported:      r.methods.unexported,
+		Functions:       r.complexity.functions,
+		Complexity:      r.complexity.total,
+		MaxComplexity:   r.complexity.max,
+		Goroutines:      r.concurrency.goroutines,
+		Channels:        r.concurrency.channels,
+		SyncUses:        r.concurrency.sync,
+		Constructors:    r.constructors.total,
+		InterfaceCtors:  r.constructors.returnsInterface,
+		DIFramework:     r.diFramework,
+		Role:            r.role,
+		Files:           r.generated.files,
+		GeneratedFiles:  r.generated.generated,
+		Generators:      r.generated.generators,
+		StructCount:     r.structCount,
+		TaggedStructs:   r.taggedStructs,
+		Registrations:   r.initRegistrations,
+		Main:            r.main,
+		ErrorChecks:     r.errorChecks,
+		ConstantShare:   r.constantShare,
+		ConstantRefs:    r.constantRefs,
+		StringKeys:      r.stringKeys,
+		Synopsis:        r.synopsis,
+		Annotations:     r.annotations,
+	}
+	for _, d := range r.diagnostics {
+		cached.Diagnostics = append(cached.Diagnostics, cachedDiagnostic{Kind: d.Kind, Position: d.Position, Message: d.Message})
+	}
+	for _, l := range r.leaks {
+		cached.Leaks = append(cached.Leaks, cachedLeak{Declaration: l.declaration, Package: l.pkg, Type: l.typeName})
+	}
+	for _, d := range r.interfaces {
+		cached.Interfaces = append(cached.Interfaces, cachedMethods{Name: d.name, Exported: d.exported, Methods: d.methods})
+	}
+	for _, d := range r.concreteTypes {
+		cached.ConcreteTypes = append(cached.ConcreteTypes, cachedMethods{Name: d.name, Exported: d.exported, Mock: d.mock, Methods: d.methods})
+	}
+	for _, d := range r.deprecated {
+		cached.Deprecated = append(cached.Deprecated, cachedDeprecated{Package: d.pkg, Symbol: d.symbol, Uses: d.uses})
+	}
+	for _, b := range r.blankImports {
+		cached.BlankImports = append(cached.BlankImports, cachedBlank{Package: b.pkg, Registrations: b.registrations})
+	}
+	for _, e := range r.errorDecls {
+		cached.Errors = append(cached.Errors, cachedError{Name: e.name, Kind: e.kind})
+	}
+	for _, s := range r.constantSets {
+		cached.ConstantSets = append(cached.ConstantSets, cachedConstants{Name: s.name, Constants: s.constants})
+	}
+	for _, e := range r.endpoints {
+		cached.Endpoints = append(cached.Endpoints, cachedEndpoint{Route: e.route, Kind: e.kind, HandlerPackage: e.handlerPackage})
+	}
+	return cached
+}
+
+// result converts the cache encoding back into an analysis result
+func (c cachedResult) result(packageID string) packageAnalysisResult {
+	r := packageAnalysisResult{
+		packageID:        packageID,
+		dependencies:     c.Dependencies,
+		exposed:          c.Exposed,
+		abstractCount:    c.AbstractCount,
+		totalTypesCount:  c.TotalTypesCount,
+		allAbstractCount: c.AllAbstract,
+		allTypesCount:    c.AllTypes,
+		embedding: embeddingCounts{
+			structEmbeds:    c.StructEmbeds,
+			structFields:    c.StructFields,
+			interfaceEmbeds: c.InterfaceEmbeds,
+			interfaceElems:  c.InterfaceElems,
+		},
+		methods: methodCounts{
+			pointer:    c.PointerMethods,
+			value:      c.ValueMethods,
+			exported:   c.ExportedMethods,
+			unexported: c.Unexported,
+		},
+		complexity: complexityCounts{
+			functions: c.Functions,
+			total:     c.Complexity,
+			max:       c.MaxComplexity,
+		},
+		concurrency: concurrencyCounts{
+			goroutines: c.Goroutines,
+			channels:   c.Channels,
+			sync:       c.SyncUses,
+		},
+		constructors: constructorCounts{
+			total:            c.Constructors,
+			returnsInterface: c.InterfaceCtors,
+		},
+		diFramework: c.DIFramework,
+		role:        c.Role,
+		generated: generatedStats{
+			files:      c.Files,
+			generated:  c.GeneratedFiles,
+			generators: c.Generators,
+		},
+		structCount:       c.StructCount,
+		taggedStructs:     c.TaggedStructs,
+		initRegistrations: c.Registrations,
+		main:              c.Main,
+		errorChecks:       c.ErrorChecks,
+		constantShare:     c.ConstantShare,
+		constantRefs:      c.ConstantRefs,
+		stringKeys:        c.StringKeys,
+		synopsis:          c.Synopsis,
+		annotations:       c.Annotations,
+	}
+	if r.dependencies == nil {
+		r.dependencies = []string{}
+	}
+	for _, l := range c.Leaks {
+		r.leaks = append(r.leaks, typeLeak{declaration: l.Declaration, pkg: l.Package, typeName: l.Type})
+	}
+	for _, d := range c.Interfaces {
+		r.interfaces = append(r.interfaces, methodSetDecl{name: d.Name, exported: d.Exported, methods: d.Methods})
+	}
+	for _, d := range c.ConcreteTypes {
+		r.concreteTypes = append(r.concreteTypes, methodSetDecl{name: d.Name, exported: d.Exported, mock: d.Mock, methods: d.Methods})
+	}
+	for _, d := range c.Deprecated {
+		r.deprecated = append(r.deprecated, deprecatedUse{pkg: d.Package, symbol: d.Symbol, uses: d.Uses})
+	}
+	for _, b := range c.BlankImports {
+		r.blankImports = append(r.blankImports, blankImport{pkg: b.Package, registrations: b.Registrations})
+	}
+	for _, e := range c.Errors {
+		r.errorDecls = append(r.errorDecls, errorDecl{name: e.Name, kind: e.Kind})
+	}
+	for _, s := range c.ConstantSets {
+		r.constantSets = append(r.constantSets, constantSet{name: s.Name, constants: s.Constants})
+	}
+	for _, e := range c.Endpoints {
+		r.endpoints = append(r.endpoints, endpointRegistration{route: e.Route, kind: e.Kind, handlerPackage: e.HandlerPackage})
+	}
+	for _, d := range c.Diagnostics {
+		r.diagnostics = append(r.diagnostics, models.Diagnostic{Kind: d.Kind, Position: d.Position, Message: d.Message})
+	}
+	return r
+}
@@ -0,0 +1,220 @@
+// Package analyzer provides functionality for analyzing Go modules and calculating design metrics.
+// This file implements focusing a report on the dependency closure of selected packages.
+package analyzer
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/alkbt/aid-metrics/pkg/models"
+)
+
+// CollapsedPackage is the display name of the package standing in for all
+// packages outside a closure (see Closure)
+const CollapsedPackage = "(rest of module)"
+
+// Closure limits module metrics to the packages matching the comma-separated
+// patterns, everything they transitively depend on and everything transitively
+// depending on them. Patterns are module-relative paths as accepted by role rules,
+// e.g. "pkg/payment/..." or "internal/*/store".
+//
+// The remaining packages are collapsed into a single CollapsedPackage, whose
+// coupling counts the closure packages on either side of it; it is exempt from
+// gating. Packages of the closure keep the metrics of the full analysis, with
+// edges into the rest of the module pointing at CollapsedPackage. Findings,
+// cycles, endpoints and the role summary are limited to the closure.
+func Closure(metrics *models.ModuleMetrics, patterns string) (*models.ModuleMetrics, error) {
+	ids := make(map[string]string, len(metrics.Packages))
+	for id, pkg := range metrics.Packages {
+		ids[pkg.Name] = id
+	}
+
+	var selected []string
+	for _, pattern := range strings.Split(patterns, ",") {
+		pattern = strings.Trim(strings.TrimSpace(pattern), "/")
+		pattern = strings.TrimPrefix(pattern, "./")
+		if pattern == "" {
+			continue
+		}
+		matched := false
+		for _, id := range sortedPackageIDs(metrics.Packages) {
+			if matchesRolePattern(metrics.Packages[id].Name, pattern) || matchesRolePattern(id, pattern) {
+				selected = append(selected, id)
+				matched = true
+			}
+		}
+		if !matched {
+			return nil, fmt.Errorf("no package matches closure pattern %q", pattern)
+		}
+	}
+	if len(selected) == 0 {
+		return nil, fmt.Errorf("empty closure pattern %q", patterns)
+	}
+
+	// Walk the graph in both directions, but never turn around: dependencies of a
+	// dependent are not part of the slice unless something else brings them in.
+	inClosure := make(map[string]bool)
+	for _, edges := range []func(models.PackageMetrics) []string{
+		func(pkg models.PackageMetrics) []string { return pkg.Dependencies },
+		func(pkg models.PackageMetrics) []string { return pkg.Dependents },
+	} {
+		visited := make(map[string]bool)
+		queue := append([]string(nil), selected...)
+		for _, id := range selected {
+			visited[id] = true
+		}
+		for len(queue) > 0 {
+			id := queue[0]
+			queue = queue[1:]
+			inClosure[id] = true
+			for _, name := range edges(metrics.Packages[id]) {
+				if next, ok := ids[name]; ok && !visited[next] {
+					visited[next] = true
+					queue = append(queue, next)
+				}
+			}
+		}
+	}
+
+	names := make(map[string]bool, len(inClosure))
+	for id := range inClosure {
+		names[metrics.Packages[id].Name] = true
+	}
+	// collapse replaces the names of module packages outside the closure
+	collapse := func(list []string) []string {
+		var result []string
+		collapsed := false
+		for _, name := range list {
+			if _, module := ids[name]; module && !names[name] {
+				if !collapsed {
+					result = append(result, CollapsedPackage)
+					collapsed = true
+				}
+				continue
+			}
+			result = append(result, name)
+		}
+		sort.Strings(result)
+		return result
+	}
+
+	focused := &models.ModuleMetrics{
+		Path:         metrics.Path,
+		Packages:     make(map[string]models.PackageMetrics, len(inClosure)+1),
+		ExportedOnly: metrics.ExportedOnly,
+	}
+	for id := range inClosure {
+		pkg := metrics.Packages[id]
+		pkg.Dependencies = collapse(pkg.Dependencies)
+		pkg.Dependents = collapse(pkg.Dependents)
+		pkg.ExposedDependencies = collapse(pkg.ExposedDependencies)
+		focused.Packages[id] = pkg
+	}
+	focused.Roles = SummarizeRoles(focused.Packages)
+	if len(metrics.Packages) > len(inClosure) {
+		focused.Packages[CollapsedPackage] = collapsedPackage(metrics, inClosure, names)
+	}
+	focused.Summary = Summarize(focused.Packages)
+
+	for _, endpoint := range metrics.Endpoints {
+		if names[endpoint.Handler] {
+			endpoint.Dependencies = collapse(endpoint.Dependencies)
+			focused.Endpoints = append(focused.Endpoints, endpoint)
+		}
+	}
+	for _, cycle := range metrics.Cycles {
+		// A cycle is strongly connected, so it is either entirely in the closure or not at all
+		if names[cycle[0]] {
+			focused.Cycles = append(focused.Cycles, cycle)
+		}
+	}
+	for name, path := range metrics.Names {
+		if names[name] {
+			if focused.Names == nil {
+				focused.Names = make(map[string]string)
+			}
+			focused.Names[name] = path
+		}
+	}
+	for _, finding := range metrics.Findings {
+		if names[finding.Package] {
+			focused.Findings = append(focused.Findings, finding)
+		}
+	}
+	for _, diagnostic := range metrics.Diagnostics {
+		if names[diagnostic.Package] {
+			focused.Diagnostics = append(focused.Diagnostics, diagnostic)
+		}
+	}
+
+	return focused, nil
+}
+
+// collapsedPackage aggregates the packages outside the closure into a single package.
+// Its afferent coupling counts the closure packages depending on any of them, its
+// efferent coupling the closure packages any of them depends on. Its health is
+// the mean health of the collapsed packages.
+func collapsedPackage(metrics *models.ModuleMetrics, inClosure, names map[string]bool) models.PackageMetrics {
+	rest := models.PackageMetrics{
+		Name:             CollapsedPackage,
+		Role:             models.RoleOther,
+		GateExempt:       true,
+		GateExemptReason: "stands in for the packages outside the closure",
+	}
+	dependents := make(map[string]bool)
+	dependencies := make(map[string]bool)
+	health, collapsed := 0, 0
+	for _, id := range sortedPackageIDs(metrics.Packages) {
+		if inClosure[id] {
+			continue
+		}
+		pkg := metrics.Packages[id]
+		rest.Na += pkg.Na
+		rest.Nc += pkg.Nc
+		rest.NaAll += pkg.NaAll
+		rest.NcAll += pkg.NcAll
+		health += pkg.Health
+		collapsed++
+		for _, name := range pkg.Dependents {
+			if names[name] {
+				dependents[name] = true
+			}
+		}
+		for _, name := range pkg.Dependencies {
+			if names[name] {
+				dependencies[name] = true
+			}
+		}
+	}
+
+	rest.Dependents = sortedKeys(dependents)
+	rest.Dependencies = sortedKeys(dependencies)
+	rest.Ca = len(rest.Dependents)
+	rest.Ce = len(rest.Dependencies)
+	if rest.Ca+rest.Ce > 0 {
+		rest.Instability = float64(rest.Ce) / float64(rest.Ca+rest.Ce)
+	}
+	if rest.Nc > 0 {
+		rest.Abstractness = float64(rest.Na) / float64(rest.Nc)
+	}
+	if rest.NcAll > 0 {
+		rest.AbstractnessAll = float64(rest.NaAll) / float64(rest.NcAll)
+	}
+	rest.Distance = math.Abs(rest.Abstractness + rest.Instability - 1.0)
+	if collapsed > 0 {
+		rest.Health = int(math.Round(float64(health) / float64(collapsed)))
+	}
+	return rest
+}
+
+// sortedKeys returns the keys of a set in sorted order
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,62 @@
+// Package analyzer provides functionality for analyzing Go modules and calculating design metrics.
+// This file implements the cyclomatic complexity of functions and methods.
+package analyzer
+
+import (
+	"go/ast"
+	"go/token"
+)
+
+// complexityCounts holds the cyclomatic complexity of the functions and methods of a package
+type complexityCounts struct {
+	functions int // Functions and methods with a body
+	total     int // Sum of their complexities
+	max       int // Highest complexity of a single function or method
+}
+
+// countFunction adds the complexity of a function or method declaration.
+// Declarations without a body (e.g. implemented in assembly) are ignored.
+func (c *complexityCounts) countFunction(fn *ast.FuncDecl) {
+	if fn.Body == nil {
+		return
+	}
+	complexity := cyclomatic(fn.Body)
+	c.functions++
+	c.total += complexity
+	c.max = max(c.max, complexity)
+}
+
+// mean returns the mean complexity of the functions and methods
+func (c complexityCounts) mean() float64 {
+	if c.functions == 0 {
+		return 0
+	}
+	return float64(c.total) / float64(c.functions)
+}
+
+// cyclomatic returns the cyclomatic complexity of a function body: one plus the
+// number of branches (if, for, case and select clauses, && and ||). Function
+// literals count toward the function declaring them.
+func cyclomatic(body *ast.BlockStmt) int {
+	complexity := 1
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch t := n.(type) {
+		case *ast.IfStmt, *ast.ForStmt, *ast.RangeStmt:
+			complexity++
+		case *ast.CaseClause:
+			if t.List != nil {
+				complexity++
+			}
+		case *ast.CommClause:
+			if t.Comm != nil {
+				complexity++
+			}
+		case *ast.BinaryExpr:
+			if t.Op == token.LAND || t.Op == token.LOR {
+				complexity++
+			}
+		}
+		return true
+	})
+	return complexity
+}
@@ -0,0 +1,56 @@
+// Package analyzer provides functionality for analyzing Go modules and calculating design metrics.
+// This file implements the concurrency primitive counts of packages.
+package analyzer
+
+import (
+	"go/ast"
+	"strings"
+
+	"github.com/alkbt/aid-metrics/pkg/models"
+)
+
+// concurrencyCounts holds the use of concurrency primitives in a package
+type concurrencyCounts struct {
+	goroutines int // go statements
+	channels   int // make(chan T) calls
+	sync       int // Qualified references to the synchronization packages
+}
+
+// isSyncPackage reports whether an import path is sync, sync/atomic or one of
+// the golang.org/x/sync packages (errgroup, semaphore, singleflight, ...)
+func isSyncPackage(path string) bool {
+	return path == "sync" || path == "sync/atomic" || strings.HasPrefix(path, "golang.org/x/sync/")
+}
+
+// count adds the concurrency primitive used by a node, if any. imports maps the
+// package names of the node's file to import paths (see fileImports).
+func (c *concurrencyCounts) count(n ast.Node, imports map[string]string) {
+	switch t := n.(type) {
+	case *ast.GoStmt:
+		c.goroutines++
+	case *ast.CallExpr:
+		if fn, ok := t.Fun.(*ast.Ident); ok && fn.Name == "make" && fn.Obj == nil && len(t.Args) > 0 {
+			if _, ok := t.Args[0].(*ast.ChanType); ok {
+				c.channels++
+			}
+		}
+	case *ast.SelectorExpr:
+		if ident, ok := t.X.(*ast.Ident); ok && ident.Obj == nil && isSyncPackage(imports[ident.Name]) {
+			c.sync++
+		}
+	}
+}
+
+// addConcurrency attaches the concurrency metric group to every package
+func (a *ModuleAnalyzer) addConcurrency(metrics *models.ModuleMetrics) {
+	for id, pkg := range metrics.Packages {
+		c := a.concurrency[id]
+		// Encoding a struct of ints cannot fail
+		_ = models.SetExtension(&pkg, models.ConcurrencyGroup, models.ConcurrencyMetrics{
+			Goroutines:     c.goroutines,
+			Channels:       c.channels,
+			SyncPrimitives: c.sync,
+		})
+		metrics.Packages[id] = pkg
+	}
+}
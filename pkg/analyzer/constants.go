@@ -0,0 +1,180 @@
+// Package analyzer provides functionality for analyzing Go modules and calculating design metrics.
+// This file implements the analysis of constants and enums shared across packages.
+package analyzer
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"sort"
+
+	"github.com/alkbt/aid-metrics/pkg/models"
+	"golang.org/x/tools/go/packages"
+)
+
+// Shared kernel detection thresholds: a package is a shared kernel when at least
+// sharedKernelMinShare of its exported declarations are constants or enum types
+// and at least sharedKernelMinDependents other packages reference the constants.
+const (
+	sharedKernelMinShare      = 0.5
+	sharedKernelMinDependents = 3
+)
+
+// constantSet is a set of exported constants of a package
+type constantSet struct {
+	name      string   // Enum type name, or name of the first constant of the declaration
+	constants []string // Names of the constants
+}
+
+// constantSets returns the exported constants of a package grouped into sets,
+// sorted by name: the constants of an enum type declared in the package form a
+// set named after the type, wherever they are declared; the other constants of
+// a const declaration form a set named after the first of them. It also returns
+// the share of the exported declarations that are constants or enum types.
+func constantSets(pkg *packages.Package) ([]constantSet, float64) {
+	if pkg.Types == nil {
+		return nil, 0
+	}
+	scope := pkg.Types.Scope()
+	sets := make(map[string]*constantSet)
+	add := func(set, name string) {
+		if sets[set] == nil {
+			sets[set] = &constantSet{name: set}
+		}
+		sets[set].constants = append(sets[set].constants, name)
+	}
+	for _, file := range pkg.Syntax {
+		for _, decl := range file.Decls {
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok || gen.Tok != token.CONST {
+				continue
+			}
+			first := ""
+			for _, spec := range gen.Specs {
+				for _, ident := range spec.(*ast.ValueSpec).Names {
+					obj, ok := scope.Lookup(ident.Name).(*types.Const)
+					if !ok || !obj.Exported() {
+						continue
+					}
+					if named, ok := obj.Type().(*types.Named); ok && named.Obj().Pkg() == pkg.Types {
+						add(named.Obj().Name(), ident.Name)
+						continue
+					}
+					if first == "" {
+						first = ident.Name
+					}
+					add(first, ident.Name)
+				}
+			}
+		}
+	}
+
+	exported, constants := 0, 0
+	for _, name := range scope.Names() {
+		obj := scope.Lookup(name)
+		if !obj.Exported() {
+			continue
+		}
+		exported++
+		switch obj.(type) {
+		case *types.Const:
+			constants++
+		case *types.TypeName:
+			if sets[name] != nil {
+				constants++
+			}
+		}
+	}
+
+	result := make([]constantSet, 0, len(sets))
+	for _, set := range sets {
+		result = append(result, *set)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].name < result[j].name })
+	share := 0.0
+	if exported > 0 {
+		share = float64(constants) / float64(exported)
+	}
+	return result, share
+}
+
+// constantRefs returns the constants of other packages a package refers to, as
+// "import/path.Name", sorted. Only qualified references are resolved.
+func constantRefs(pkg *packages.Package) []string {
+	seen := make(map[string]bool)
+	for _, file := range pkg.Syntax {
+		imports := fileImports(file, pkg)
+		if len(imports) == 0 {
+			continue
+		}
+		ast.Inspect(file, func(n ast.Node) bool {
+			sel, ok := n.(*ast.SelectorExpr)
+			if !ok {
+				return true
+			}
+			ident, ok := sel.X.(*ast.Ident)
+			if !ok || ident.Obj != nil {
+				return true
+			}
+			imp, ok := pkg.Imports[imports[ident.Name]]
+			if !ok || imp.Types == nil || imp.PkgPath == pkg.PkgPath {
+				return true
+			}
+			if _, ok := imp.Types.Scope().Lookup(sel.Sel.Name).(*types.Const); ok {
+				seen[imp.PkgPath+"."+sel.Sel.Name] = true
+			}
+			return true
+		})
+	}
+
+	refs := make([]string, 0, len(seen))
+	for ref := range seen {
+		refs = append(refs, ref)
+	}
+	sort.Strings(refs)
+	return refs
+}
+
+// constantReferrers maps the constants of module packages, as
+// "import/path.Name", to the other packages referring to them
+func (a *ModuleAnalyzer) constantReferrers() map[string][]string {
+	referrers := make(map[string][]string)
+	for pkg, refs := range a.constantRefs {
+		for _, ref := range refs {
+			referrers[ref] = append(referrers[ref], pkg)
+		}
+	}
+	return referrers
+}
+
+// constantSharing returns the constant sets of a package with the packages
+// referencing them, and the number of distinct packages referencing any
+func (a *ModuleAnalyzer) constantSharing(pkg string, referrers map[string][]string) ([]models.ConstantSet, int) {
+	sets := a.constantSets[pkg]
+	if len(sets) == 0 {
+		return nil, 0
+	}
+	dependents := make(map[string]bool)
+	result := make([]models.ConstantSet, 0, len(sets))
+	for _, set := range sets {
+		referencing := make(map[string]bool)
+		for _, constant := range set.constants {
+			for _, id := range referrers[pkg+"."+constant] {
+				referencing[id] = true
+				dependents[id] = true
+			}
+		}
+		result = append(result, models.ConstantSet{
+			Name:         set.name,
+			Constants:    len(set.constants),
+			ReferencedBy: a.displayNames(sortedKeys(referencing)),
+		})
+	}
+	return result, len(dependents)
+}
+
+// isSharedKernel reports whether a package exists mainly to share constants
+// with many packages (see sharedKernelMinShare and sharedKernelMinDependents)
+func isSharedKernel(share float64, dependents int) bool {
+	return share >= sharedKernelMinShare && dependents >= sharedKernelMinDependents
+}
@@ -0,0 +1,99 @@
+// Package analyzer provides functionality for analyzing Go modules and calculating design metrics.
+// This file implements detection of NewX constructors and the kind of value they return.
+package analyzer
+
+import (
+	"go/ast"
+	"go/types"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// constructorCounts holds the constructor statistics collected for a single package.
+// The ratio of constructors returning interfaces is a practical proxy for how a team
+// follows the "accept interfaces, return structs" guidance.
+type constructorCounts struct {
+	// total is the number of NewX constructors in the package
+	total int
+
+	// returnsInterface is the number of constructors whose first result is an interface
+	returnsInterface int
+}
+
+// ratio returns the share of constructors that return an interface
+func (c constructorCounts) ratio() float64 {
+	if c.total == 0 {
+		return 0
+	}
+	return float64(c.returnsInterface) / float64(c.total)
+}
+
+// isConstructor reports whether a function declaration follows the NewX constructor convention.
+// Both "New" and "New" followed by an upper-case letter qualify; methods do not.
+func isConstructor(fn *ast.FuncDecl) bool {
+	if fn.Recv != nil || fn.Type.Results == nil || len(fn.Type.Results.List) == 0 {
+		return false
+	}
+
+	name := fn.Name.Name
+	if name == "New" {
+		return true
+	}
+	if len(name) <= 3 || name[:3] != "New" {
+		return false
+	}
+	r, _ := utf8.DecodeRuneInString(name[3:])
+	return unicode.IsUpper(r)
+}
+
+// constructorDecl is the part of a constructor declaration needed to classify it.
+// Keeping only the name and the first result type, rather than the *ast.FuncDecl,
+// lets the AST of the file be collected as soon as the file has been counted.
+type constructorDecl struct {
+	name   string
+	result ast.Expr
+}
+
+// newConstructorDecl extracts the classification data from a constructor declaration
+func newConstructorDecl(fn *ast.FuncDecl) constructorDecl {
+	return constructorDecl{name: fn.Name.Name, result: fn.Type.Results.List[0].Type}
+}
+
+// countConstructors classifies the given constructors by the kind of their first result.
+// Type information from the loaded package is used when available, which resolves
+// interfaces declared in other packages. Without it, the result is classified from
+// the syntax using the set of interface names declared in the package itself.
+func countConstructors(pkg *packages.Package, constructors []constructorDecl, localInterfaces map[string]bool) constructorCounts {
+	var counts constructorCounts
+
+	for _, fn := range constructors {
+		counts.total++
+		if constructorReturnsInterface(pkg, fn, localInterfaces) {
+			counts.returnsInterface++
+		}
+	}
+
+	return counts
+}
+
+// constructorReturnsInterface reports whether the first result of a constructor is an interface
+func constructorReturnsInterface(pkg *packages.Package, fn constructorDecl, localInterfaces map[string]bool) bool {
+	if pkg.Types != nil {
+		if obj, ok := pkg.Types.Scope().Lookup(fn.name).(*types.Func); ok {
+			results := obj.Type().(*types.Signature).Results()
+			if results.Len() > 0 {
+				return types.IsInterface(results.At(0).Type())
+			}
+		}
+	}
+
+	switch t := fn.result.(type) {
+	case *ast.InterfaceType:
+		return true
+	case *ast.Ident:
+		return t.Name == "any" || t.Name == "error" || localInterfaces[t.Name]
+	}
+	return false
+}
@@ -0,0 +1,26 @@
+// Package analyzer provides functionality for analyzing Go modules and calculating design metrics.
+// This file implements the debt points model: every finding weighs a number of
+// points, which add up per package and for the module.
+package analyzer
+
+import "github.com/alkbt/aid-metrics/pkg/models"
+
+// debtPoints returns the points of a finding of the category and severity
+func (a *ModuleAnalyzer) debtPoints(category string, severity models.Severity) int {
+	if points, ok := a.options.DebtWeights[category]; ok {
+		return points
+	}
+	return models.DefaultDebtPoints[severity]
+}
+
+// addDebtPoints sums the points of the findings into the packages they are about
+func addDebtPoints(metrics *models.ModuleMetrics) {
+	points := make(map[string]int)
+	for _, finding := range metrics.Findings {
+		points[finding.Package] += finding.Points
+	}
+	for id, pkg := range metrics.Packages {
+		pkg.DebtPoints = points[pkg.Name]
+		metrics.Packages[id] = pkg
+	}
+}
@@ -0,0 +1,158 @@
+// Package analyzer provides functionality for analyzing Go modules and calculating design metrics.
+// This file implements detection of uses of deprecated symbols of other packages.
+package analyzer
+
+import (
+	"go/ast"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/alkbt/aid-metrics/pkg/models"
+	"golang.org/x/tools/go/packages"
+)
+
+// deprecatedUse counts the references of a package to a deprecated symbol
+type deprecatedUse struct {
+	pkg    string // Import path of the package declaring the symbol
+	symbol string // Name of the symbol within its package
+	uses   int    // References to the symbol
+}
+
+// packageIndex memoizes a property of imported packages by import path, so it
+// is computed once and shared by the workers analyzing the importers
+type packageIndex[T any] struct {
+	mu     sync.Mutex
+	values map[string]T
+}
+
+// get returns the property of pkg, computing it on first use
+func (x *packageIndex[T]) get(pkg *packages.Package, compute func(*packages.Package) T) T {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if value, ok := x.values[pkg.PkgPath]; ok {
+		return value
+	}
+	if x.values == nil {
+		x.values = make(map[string]T)
+	}
+	value := compute(pkg)
+	x.values[pkg.PkgPath] = value
+	return value
+}
+
+// reset forgets the memoized properties, e.g. after imported packages changed
+func (x *packageIndex[T]) reset() {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.values = nil
+}
+
+// deprecatedSymbols returns the names of the package-level functions, types,
+// variables and constants of pkg documented as deprecated (see isDeprecated).
+// A doc comment on a parenthesized declaration group covers the specs without
+// a doc comment of their own.
+func deprecatedSymbols(pkg *packages.Package) map[string]bool {
+	symbols := make(map[string]bool)
+	for _, file := range pkg.Syntax {
+		for _, decl := range file.Decls {
+			switch d := decl.(type) {
+			case *ast.FuncDecl:
+				if d.Recv == nil && isDeprecated(d.Doc) {
+					symbols[d.Name.Name] = true
+				}
+			case *ast.GenDecl:
+				for _, spec := range d.Specs {
+					switch s := spec.(type) {
+					case *ast.TypeSpec:
+						if isDeprecated(s.Doc) || (s.Doc == nil && isDeprecated(d.Doc)) {
+							symbols[s.Name.Name] = true
+						}
+					case *ast.ValueSpec:
+						if isDeprecated(s.Doc) || (s.Doc == nil && isDeprecated(d.Doc)) {
+							for _, name := range s.Names {
+								symbols[name.Name] = true
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+	return symbols
+}
+
+// isDeprecated reports whether a doc comment has a paragraph starting with
+// "Deprecated: ", the convention recognized by go doc and gopls
+func isDeprecated(doc *ast.CommentGroup) bool {
+	if doc == nil {
+		return false
+	}
+	for _, paragraph := range strings.Split(doc.Text(), "\n\n") {
+		if strings.HasPrefix(paragraph, "Deprecated: ") {
+			return true
+		}
+	}
+	return false
+}
+
+// deprecatedUses finds the references of a package to deprecated symbols of the
+// packages it imports, internal or external, sorted by package and symbol. Only
+// qualified references to package-level symbols (pkg.Symbol) are considered;
+// deprecated methods and fields would need full type information of every use.
+func (a *ModuleAnalyzer) deprecatedUses(pkg *packages.Package) []deprecatedUse {
+	counts := make(map[deprecatedUse]int)
+	for _, file := range pkg.Syntax {
+		imports := fileImports(file, pkg)
+		if len(imports) == 0 {
+			continue
+		}
+		ast.Inspect(file, func(n ast.Node) bool {
+			sel, ok := n.(*ast.SelectorExpr)
+			if !ok {
+				return true
+			}
+			ident, ok := sel.X.(*ast.Ident)
+			if !ok || ident.Obj != nil {
+				// Not a package name, or a local declaration shadowing it
+				return true
+			}
+			imp, ok := pkg.Imports[imports[ident.Name]]
+			if !ok || imp.Types == nil || imp.Types.Scope().Lookup(sel.Sel.Name) == nil {
+				return true
+			}
+			if a.deprecations.get(imp, deprecatedSymbols)[sel.Sel.Name] {
+				counts[deprecatedUse{pkg: imp.PkgPath, symbol: sel.Sel.Name}]++
+			}
+			return true
+		})
+	}
+
+	uses := make([]deprecatedUse, 0, len(counts))
+	for use, n := range counts {
+		use.uses = n
+		uses = append(uses, use)
+	}
+	sort.Slice(uses, func(i, j int) bool {
+		if uses[i].pkg != uses[j].pkg {
+			return uses[i].pkg < uses[j].pkg
+		}
+		return uses[i].symbol < uses[j].symbol
+	})
+	return uses
+}
+
+// newDeprecatedUses converts deprecated symbol references into their model
+// representation, with the total number of references
+func newDeprecatedUses(uses []deprecatedUse) ([]models.DeprecatedUse, int) {
+	if len(uses) == 0 {
+		return nil, 0
+	}
+	result := make([]models.DeprecatedUse, 0, len(uses))
+	total := 0
+	for _, use := range uses {
+		result = append(result, models.DeprecatedUse{Package: use.pkg, Symbol: use.symbol, Uses: use.uses})
+		total += use.uses
+	}
+	return result, total
+}
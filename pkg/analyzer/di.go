@@ -0,0 +1,50 @@
+// Package analyzer provides functionality for analyzing Go modules and calculating design metrics.
+// This file implements detection of dependency injection frameworks and composition roots.
+package analyzer
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// diFrameworks maps the import path of a known dependency injection framework to its short name.
+// Packages importing any of these (or their subpackages) wire the application together.
+var diFrameworks = map[string]string{
+	"github.com/google/wire": "wire",
+	"go.uber.org/fx":         "fx",
+	"go.uber.org/dig":        "dig",
+}
+
+// detectDIFramework returns the name of the dependency injection framework used by the package,
+// or an empty string if none is detected. A package is detected either by importing a known
+// framework or by containing framework-generated wiring code (wire_gen.go).
+//
+// Such packages are composition roots: they legitimately depend on most of the module,
+// so their extreme Ce is expected rather than a design problem.
+func detectDIFramework(pkg *packages.Package) string {
+	for _, file := range pkg.GoFiles {
+		if filepath.Base(file) == "wire_gen.go" {
+			return "wire"
+		}
+	}
+
+	// Iterate imports in a stable order so the result is deterministic
+	importPaths := make([]string, 0, len(pkg.Imports))
+	for path := range pkg.Imports {
+		importPaths = append(importPaths, path)
+	}
+	sort.Strings(importPaths)
+
+	for _, path := range importPaths {
+		for frameworkPath, name := range diFrameworks {
+			if path == frameworkPath || strings.HasPrefix(path, frameworkPath+"/") {
+				return name
+			}
+		}
+	}
+
+	return ""
+}
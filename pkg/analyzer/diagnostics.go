@@ -0,0 +1,126 @@
+// Package analyzer provides functionality for analyzing Go modules and calculating design metrics.
+// This file collects the errors of loading packages, so broken packages do not vanish from reports unexplained.
+package analyzer
+
+import (
+	"errors"
+	"go/ast"
+	"go/scanner"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/alkbt/aid-metrics/pkg/models"
+	"golang.org/x/tools/go/packages"
+)
+
+// annotationDiagnostic is the kind of the diagnostics of invalid annotations,
+// which are skipped without affecting the rest of the analysis
+const annotationDiagnostic = "annotation"
+
+// isPartial reports whether the diagnostics of a package mean its metrics may
+// be incomplete, which invalid annotations do not
+func isPartial(diagnostics []models.Diagnostic) bool {
+	for _, diagnostic := range diagnostics {
+		if diagnostic.Kind != annotationDiagnostic {
+			return true
+		}
+	}
+	return false
+}
+
+// addDiagnostics records the errors of the loaded packages, replacing those of
+// earlier loads. The errors of test variants are merged into their package.
+func (a *ModuleAnalyzer) addDiagnostics(pkgs []*packages.Package) {
+	for _, pkg := range pkgs {
+		delete(a.diagnostics, packageID(pkg.ID))
+	}
+	for _, pkg := range pkgs {
+		id := packageID(pkg.ID)
+		for _, pkgErr := range pkg.Errors {
+			diagnostic := models.Diagnostic{
+				Kind:     errorKind(pkgErr.Kind),
+				Position: a.relativePosition(pkgErr.Pos),
+				Message:  pkgErr.Msg,
+			}
+			if !slices.Contains(a.diagnostics[id], diagnostic) {
+				a.diagnostics[id] = append(a.diagnostics[id], diagnostic)
+			}
+		}
+	}
+}
+
+// errorKind returns the name of the kind of a package error
+func errorKind(kind packages.ErrorKind) string {
+	switch kind {
+	case packages.ListError:
+		return "list"
+	case packages.ParseError:
+		return "parse"
+	case packages.TypeError:
+		return "type"
+	default:
+		return "unknown"
+	}
+}
+
+// relativePosition makes the file of a file:line:col position relative to the
+// module root, so reports do not depend on where the module was checked out
+func (a *ModuleAnalyzer) relativePosition(pos string) string {
+	if pos == "-" {
+		return ""
+	}
+	if !filepath.IsAbs(pos) {
+		return pos
+	}
+	if rel, err := filepath.Rel(a.modulePath, pos); err == nil && !strings.HasPrefix(rel, "..") {
+		return filepath.ToSlash(rel)
+	}
+	return pos
+}
+
+// collectDiagnostics returns the diagnostics of all packages with their display
+// names, sorted by package, then in the order they were reported
+func (a *ModuleAnalyzer) collectDiagnostics() []models.Diagnostic {
+	var diagnostics []models.Diagnostic
+	for id, errs := range a.diagnostics {
+		name := a.displayName(id)
+		for _, diagnostic := range errs {
+			diagnostic.Package = name
+			diagnostics = append(diagnostics, diagnostic)
+		}
+	}
+	sort.SliceStable(diagnostics, func(i, j int) bool {
+		return diagnostics[i].Package < diagnostics[j].Package
+	})
+	return diagnostics
+}
+
+// parseDiagnostics converts the error of parsing a file into diagnostics
+func (a *ModuleAnalyzer) parseDiagnostics(err error) []models.Diagnostic {
+	var list scanner.ErrorList
+	if !errors.As(err, &list) {
+		return []models.Diagnostic{{Kind: "parse", Message: err.Error()}}
+	}
+	diagnostics := make([]models.Diagnostic, 0, len(list))
+	for _, e := range list {
+		diagnostics = append(diagnostics, models.Diagnostic{
+			Kind:     "parse",
+			Position: a.relativePosition(e.Pos.String()),
+			Message:  e.Msg,
+		})
+	}
+	return diagnostics
+}
+
+// dropUnparsedFiles removes the files without a package clause from the syntax
+// of the packages. The parser recovers from errors after the package clause,
+// so the other files with syntax errors are analyzed as far as they parsed.
+func dropUnparsedFiles(pkgs []*packages.Package) {
+	for _, pkg := range pkgs {
+		pkg.Syntax = slices.DeleteFunc(pkg.Syntax, func(file *ast.File) bool {
+			return !file.Package.IsValid()
+		})
+	}
+}
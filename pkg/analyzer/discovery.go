@@ -3,6 +3,7 @@
 package analyzer
 
 import (
+	"context"
 	"io/fs"
 	"os"
 	"path/filepath"
@@ -22,6 +23,14 @@ type PackageInfo struct {
 	HasGoFiles bool
 }
 
+// searchRoot returns the directory searched for the packages matching pattern
+func searchRoot(modulePath, pattern string) string {
+	if pattern != "" && pattern != "./..." && pattern != "." {
+		return filepath.Join(modulePath, strings.TrimSuffix(pattern, "/..."))
+	}
+	return modulePath
+}
+
 // discoverPackages walks the filesystem to find all Go packages matching the given pattern.
 // This is the first phase of the analysis process and provides quick package discovery
 // without the overhead of loading package dependencies and type information.
@@ -31,25 +40,24 @@ type PackageInfo struct {
 //   - "." for just the current package
 //   - specific package paths
 //
-// Progress is reported through the progressFunc callback, which is called for each
-// package discovered. The discovery phase uses progress values 0-10 on the fixed
-// 0-100 scale, incrementing by 1 for every 2-3 packages found (capped at 10).
-func discoverPackages(modulePath, moduleName, pattern string, progressFunc func(found int)) ([]PackageInfo, error) {
+// Progress is reported through the progressFunc callback, which is called with the
+// number of packages found so far for each package discovered. Directories holding
+// only _test.go files are packages when includeTests is set.
+func discoverPackages(ctx context.Context, modulePath, moduleName, pattern string, includeTests bool, progressFunc func(found int)) ([]PackageInfo, error) {
 	var packages []PackageInfo
 	packagesFound := 0
-	lastProgress := 0
 
 	// Convert pattern to filesystem path
-	searchPath := modulePath
-	if pattern != "" && pattern != "./..." && pattern != "." {
-		searchPath = filepath.Join(modulePath, pattern)
-	}
+	searchPath := searchRoot(modulePath, pattern)
 
 	// Walk the filesystem
 	err := filepath.WalkDir(searchPath, func(path string, d fs.DirEntry, err error) error {
 		if err != nil {
 			return nil // Skip directories we can't read
 		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 
 		// Skip non-directories
 		if !d.IsDir() {
@@ -63,13 +71,20 @@ func discoverPackages(modulePath, moduleName, pattern string, progressFunc func(
 			return fs.SkipDir
 		}
 
+		// Nested modules are not part of the module, as with the go command
+		if path != modulePath {
+			if _, err := os.Stat(filepath.Join(path, "go.mod")); err == nil {
+				return fs.SkipDir
+			}
+		}
+
 		// Check if directory contains Go files
 		hasGoFiles := false
 		entries, err := fs.ReadDir(fs.FS(dirFS{modulePath}), strings.TrimPrefix(path, modulePath+"/"))
 		if err == nil {
 			for _, entry := range entries {
 				if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".go") &&
-					!strings.HasSuffix(entry.Name(), "_test.go") {
+					(includeTests || !strings.HasSuffix(entry.Name(), "_test.go")) {
 					hasGoFiles = true
 					break
 				}
@@ -97,15 +112,8 @@ func discoverPackages(modulePath, moduleName, pattern string, progressFunc func(
 				})
 
 				packagesFound++
-				
-				// Update progress (0-10 range, 1 point per 2-3 packages)
-				progress := packagesFound / 3
-				if progress > 10 {
-					progress = 10
-				}
-				if progress > lastProgress && progressFunc != nil {
+				if progressFunc != nil {
 					progressFunc(packagesFound)
-					lastProgress = progress
 				}
 			}
 		}
@@ -129,7 +137,7 @@ func matchesPattern(importPath, moduleName, pattern string) bool {
 	}
 
 	// For other patterns, check if it's a prefix match
-	fullPattern := filepath.Join(moduleName, pattern)
+	fullPattern := filepath.Join(moduleName, strings.TrimSuffix(pattern, "/..."))
 	return strings.HasPrefix(importPath, fullPattern)
 }
 
@@ -0,0 +1,66 @@
+// Package analyzer provides functionality for analyzing Go modules and calculating design metrics.
+// This file implements counting of embedding relationships in struct and interface types.
+package analyzer
+
+import (
+	"go/ast"
+)
+
+// embeddingCounts holds the embedding statistics collected for a single package.
+// Embedding is a form of coupling that does not show up in the import graph,
+// so it is tracked separately from Ca/Ce.
+type embeddingCounts struct {
+	// structEmbeds is the number of embedded (anonymous) fields in struct types
+	structEmbeds int
+
+	// structFields is the total number of fields in struct types, embedded or not
+	structFields int
+
+	// interfaceEmbeds is the number of embedded interfaces in interface types
+	interfaceEmbeds int
+
+	// interfaceElems is the total number of interface elements (methods and embeds)
+	interfaceElems int
+}
+
+// countEmbedding updates the counts with the embedding relationships of a type expression.
+// Only struct and interface types are inspected; other nodes are ignored.
+func (c *embeddingCounts) countEmbedding(n ast.Node) {
+	switch t := n.(type) {
+	case *ast.StructType:
+		if t.Fields == nil {
+			return
+		}
+		for _, field := range t.Fields.List {
+			if len(field.Names) == 0 {
+				c.structEmbeds++
+				c.structFields++
+			} else {
+				c.structFields += len(field.Names)
+			}
+		}
+	case *ast.InterfaceType:
+		if t.Methods == nil {
+			return
+		}
+		for _, elem := range t.Methods.List {
+			if len(elem.Names) == 0 {
+				c.interfaceEmbeds++
+				c.interfaceElems++
+			} else {
+				c.interfaceElems += len(elem.Names)
+			}
+		}
+	}
+}
+
+// ratio returns the share of struct fields and interface elements that are embedded.
+// A value near 0 indicates explicit composition through named fields, while a value
+// near 1 indicates heavy reliance on embedding chains.
+func (c embeddingCounts) ratio() float64 {
+	total := c.structFields + c.interfaceElems
+	if total == 0 {
+		return 0
+	}
+	return float64(c.structEmbeds+c.interfaceEmbeds) / float64(total)
+}
@@ -0,0 +1,232 @@
+// Package analyzer provides functionality for analyzing Go modules and calculating design metrics.
+// This file implements detection of HTTP/gRPC handler registrations and their package fan-in.
+package analyzer
+
+import (
+	"go/ast"
+	"go/token"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/alkbt/aid-metrics/pkg/models"
+	"golang.org/x/tools/go/packages"
+)
+
+// httpRegistrationMethods are the method names used to register HTTP handlers on
+// net/http muxes and the common third-party routers (chi, gin, echo, gorilla/mux).
+var httpRegistrationMethods = map[string]bool{
+	"Handle": true, "HandleFunc": true,
+	"Get": true, "Post": true, "Put": true, "Patch": true, "Delete": true,
+	"GET": true, "POST": true, "PUT": true, "PATCH": true, "DELETE": true,
+}
+
+// routerPackages are the packages whose registration methods register HTTP
+// handlers, matched with their subpackages and major versions (chi/v5, echo/v4)
+var routerPackages = []string{
+	"net/http",
+	"github.com/go-chi/chi",
+	"github.com/gin-gonic/gin",
+	"github.com/labstack/echo",
+	"github.com/gorilla/mux",
+}
+
+// httpRoute matches the patterns HTTP handlers are registered with: a path, or
+// a method and a path as in net/http since Go 1.22
+var httpRoute = regexp.MustCompile(`^([A-Z]+ +)?/`)
+
+// endpointRegistration is a handler registration found in a package's source
+type endpointRegistration struct {
+	// route is the registered HTTP path pattern or the gRPC service name
+	route string
+
+	// kind is either "http" or "grpc"
+	kind string
+
+	// handlerPackage is the import path of the package providing the handler,
+	// or the registering package itself when the handler is declared locally
+	handlerPackage string
+}
+
+// findEndpoints returns the HTTP and gRPC handler registrations in a file.
+// HTTP registrations are calls like mux.HandleFunc("/path", handler) with a string
+// literal route on net/http or a known router (see routerPackages), so that calls
+// like cache.Get("key", &v) are not taken for routes; gRPC registrations are calls
+// to generated RegisterXServer functions.
+func findEndpoints(file *ast.File, pkg *packages.Package) []endpointRegistration {
+	imports := fileImports(file, pkg)
+
+	var endpoints []endpointRegistration
+	ast.Inspect(file, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok || len(call.Args) < 2 {
+			return true
+		}
+		sel, ok := call.Fun.(*ast.SelectorExpr)
+		if !ok {
+			return true
+		}
+
+		handler := call.Args[len(call.Args)-1]
+		switch {
+		case httpRegistrationMethods[sel.Sel.Name]:
+			lit, ok := call.Args[0].(*ast.BasicLit)
+			if !ok || lit.Kind != token.STRING {
+				return true
+			}
+			route, err := strconv.Unquote(lit.Value)
+			if err != nil || !httpRoute.MatchString(route) || !registersOnRouter(sel, pkg) {
+				return true
+			}
+			endpoints = append(endpoints, endpointRegistration{
+				route:          route,
+				kind:           "http",
+				handlerPackage: handlerPackage(handler, imports, packageID(pkg.ID)),
+			})
+		case strings.HasPrefix(sel.Sel.Name, "Register") && strings.HasSuffix(sel.Sel.Name, "Server"):
+			if _, isPkg := imports[identName(sel.X)]; !isPkg {
+				return true
+			}
+			endpoints = append(endpoints, endpointRegistration{
+				route:          strings.TrimSuffix(strings.TrimPrefix(sel.Sel.Name, "Register"), "Server"),
+				kind:           "grpc",
+				handlerPackage: handlerPackage(handler, imports, packageID(pkg.ID)),
+			})
+		}
+		return true
+	})
+
+	return endpoints
+}
+
+// registersOnRouter reports whether a registration method belongs to a known
+// router. Methods whose package cannot be resolved, e.g. because the router
+// failed to type-check, are given the benefit of the doubt.
+func registersOnRouter(sel *ast.SelectorExpr, pkg *packages.Package) bool {
+	if pkg.TypesInfo == nil {
+		return true
+	}
+	obj := pkg.TypesInfo.ObjectOf(sel.Sel)
+	if obj == nil || obj.Pkg() == nil {
+		return true
+	}
+	for _, router := range routerPackages {
+		if rest, ok := strings.CutPrefix(obj.Pkg().Path(), router); ok && (rest == "" || rest[0] == '/') {
+			return true
+		}
+	}
+	return false
+}
+
+// fileImports maps the local names of a file's imports to their import paths
+func fileImports(file *ast.File, pkg *packages.Package) map[string]string {
+	imports := make(map[string]string)
+	for _, spec := range file.Imports {
+		importPath, err := strconv.Unquote(spec.Path.Value)
+		if err != nil {
+			continue
+		}
+		name := path.Base(importPath)
+		if imported, ok := pkg.Imports[importPath]; ok && imported.Name != "" {
+			name = imported.Name
+		}
+		if spec.Name != nil {
+			name = spec.Name.Name
+		}
+		imports[name] = importPath
+	}
+	return imports
+}
+
+// handlerPackage resolves the package providing a handler expression.
+// Qualified references (pkg.Handler, pkg.NewHandler(...), &pkg.Handler{}) resolve
+// to the imported package; anything else is attributed to the registering package.
+func handlerPackage(expr ast.Expr, imports map[string]string, registering string) string {
+	for {
+		switch e := expr.(type) {
+		case *ast.CallExpr:
+			expr = e.Fun
+		case *ast.UnaryExpr:
+			expr = e.X
+		case *ast.CompositeLit:
+			expr = e.Type
+		case *ast.SelectorExpr:
+			if importPath, ok := imports[identName(e.X)]; ok {
+				return importPath
+			}
+			expr = e.X
+		default:
+			return registering
+		}
+	}
+}
+
+// identName returns the name of an identifier expression, or an empty string
+func identName(expr ast.Expr) string {
+	if ident, ok := expr.(*ast.Ident); ok {
+		return ident.Name
+	}
+	return ""
+}
+
+// buildEndpoints maps every registered endpoint to the module packages its handler
+// transitively depends on, producing the endpoint -> package fan-in report.
+func (a *ModuleAnalyzer) buildEndpoints() []models.Endpoint {
+	var endpoints []models.Endpoint
+
+	// Visit registering packages in a stable order so equal routes keep a deterministic order
+	pkgs := make([]string, 0, len(a.endpoints))
+	for pkg := range a.endpoints {
+		pkgs = append(pkgs, pkg)
+	}
+	sort.Strings(pkgs)
+
+	for _, pkg := range pkgs {
+		for _, reg := range a.endpoints[pkg] {
+			endpoints = append(endpoints, models.Endpoint{
+				Route:        reg.route,
+				Kind:         reg.kind,
+				RegisteredIn: a.displayName(pkg),
+				Handler:      a.displayName(reg.handlerPackage),
+				Dependencies: a.transitiveModuleDependencies(reg.handlerPackage),
+			})
+		}
+	}
+
+	sort.SliceStable(endpoints, func(i, j int) bool {
+		if endpoints[i].Route != endpoints[j].Route {
+			return endpoints[i].Route < endpoints[j].Route
+		}
+		return endpoints[i].RegisteredIn < endpoints[j].RegisteredIn
+	})
+
+	return endpoints
+}
+
+// transitiveModuleDependencies returns the display names of all module packages
+// reachable from pkg through the dependency graph, including pkg itself.
+func (a *ModuleAnalyzer) transitiveModuleDependencies(pkg string) []string {
+	visited := map[string]bool{pkg: true}
+	queue := []string{pkg}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, dep := range a.dependencies[current] {
+			if !visited[dep] {
+				visited[dep] = true
+				queue = append(queue, dep)
+			}
+		}
+	}
+
+	deps := make([]string, 0, len(visited))
+	for dep := range visited {
+		if a.moduleName == "" || strings.HasPrefix(dep, a.moduleName) {
+			deps = append(deps, a.displayName(dep))
+		}
+	}
+	sort.Strings(deps)
+	return deps
+}
@@ -0,0 +1,222 @@
+// Package analyzer provides functionality for analyzing Go modules and calculating design metrics.
+// This file implements error-path coupling: exported errors and the packages checking for them.
+package analyzer
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"sort"
+
+	"github.com/alkbt/aid-metrics/pkg/models"
+	"golang.org/x/tools/go/packages"
+)
+
+// errorsPackages are the packages whose Is and As functions match errors
+var errorsPackages = map[string]bool{
+	"errors":                        true,
+	"github.com/pkg/errors":         true,
+	"golang.org/x/xerrors":          true,
+	"github.com/cockroachdb/errors": true,
+}
+
+// errorDecl is an exported error declared by a package
+type errorDecl struct {
+	name string
+	kind string // models.ErrorVar or models.ErrorType
+}
+
+// exportedErrors returns the exported package-level variables of type error and
+// the exported types implementing error (by value or pointer), sorted by name
+func exportedErrors(pkg *packages.Package) []errorDecl {
+	if pkg.Types == nil {
+		return nil
+	}
+	errorType := types.Universe.Lookup("error").Type().Underlying().(*types.Interface)
+	scope := pkg.Types.Scope()
+	var decls []errorDecl
+	for _, name := range scope.Names() {
+		obj := scope.Lookup(name)
+		if !obj.Exported() {
+			continue
+		}
+		switch obj := obj.(type) {
+		case *types.Var:
+			if types.Implements(obj.Type(), errorType) {
+				decls = append(decls, errorDecl{name: name, kind: models.ErrorVar})
+			}
+		case *types.TypeName:
+			if types.Implements(obj.Type(), errorType) || types.Implements(types.NewPointer(obj.Type()), errorType) {
+				decls = append(decls, errorDecl{name: name, kind: models.ErrorType})
+			}
+		}
+	}
+	return decls
+}
+
+// errorChecks returns the errors of other packages a package checks for, as
+// "import/path.Name", sorted: the targets of errors.Is and errors.As, operands
+// of == and != and cases of switches, and the types of type assertions and type
+// switches. Only qualified references are resolved; the target of errors.As is
+// found through the declaration of the variable passed to it.
+func errorChecks(pkg *packages.Package) []string {
+	seen := make(map[string]bool)
+	for _, file := range pkg.Syntax {
+		imports := fileImports(file, pkg)
+		if len(imports) == 0 {
+			continue
+		}
+		add := func(expr ast.Expr) {
+			if star, ok := expr.(*ast.StarExpr); ok {
+				expr = star.X
+			}
+			sel, ok := expr.(*ast.SelectorExpr)
+			if !ok {
+				return
+			}
+			ident, ok := sel.X.(*ast.Ident)
+			if !ok || ident.Obj != nil {
+				return
+			}
+			if path, ok := imports[ident.Name]; ok && path != pkg.PkgPath {
+				seen[path+"."+sel.Sel.Name] = true
+			}
+		}
+
+		ast.Inspect(file, func(n ast.Node) bool {
+			switch t := n.(type) {
+			case *ast.CallExpr:
+				sel, ok := t.Fun.(*ast.SelectorExpr)
+				if !ok || len(t.Args) != 2 {
+					return true
+				}
+				if ident, ok := sel.X.(*ast.Ident); !ok || !errorsPackages[imports[ident.Name]] {
+					return true
+				}
+				switch sel.Sel.Name {
+				case "Is":
+					add(t.Args[1])
+				case "As":
+					add(asTargetType(t.Args[1]))
+				}
+			case *ast.BinaryExpr:
+				if t.Op == token.EQL || t.Op == token.NEQ {
+					add(t.X)
+					add(t.Y)
+				}
+			case *ast.TypeAssertExpr:
+				if t.Type != nil {
+					add(t.Type)
+				}
+			case *ast.SwitchStmt:
+				if t.Tag != nil {
+					for _, stmt := range t.Body.List {
+						for _, expr := range stmt.(*ast.CaseClause).List {
+							add(expr)
+						}
+					}
+				}
+			case *ast.TypeSwitchStmt:
+				for _, stmt := range t.Body.List {
+					for _, expr := range stmt.(*ast.CaseClause).List {
+						add(expr)
+					}
+				}
+			}
+			return true
+		})
+	}
+
+	checks := make([]string, 0, len(seen))
+	for check := range seen {
+		checks = append(checks, check)
+	}
+	sort.Strings(checks)
+	return checks
+}
+
+// asTargetType returns the type of the variable whose address is passed to
+// errors.As, as declared (var target *pkg.Error) or initialized
+// (target := (*pkg.Error)(nil), target := &pkg.Error{}), or nil
+func asTargetType(arg ast.Expr) ast.Expr {
+	unary, ok := arg.(*ast.UnaryExpr)
+	if !ok || unary.Op != token.AND {
+		return nil
+	}
+	ident, ok := unary.X.(*ast.Ident)
+	if !ok || ident.Obj == nil {
+		return nil
+	}
+	switch decl := ident.Obj.Decl.(type) {
+	case *ast.ValueSpec:
+		return decl.Type
+	case *ast.AssignStmt:
+		if len(decl.Lhs) != len(decl.Rhs) {
+			return nil
+		}
+		for i, lhs := range decl.Lhs {
+			if lhs, ok := lhs.(*ast.Ident); ok && lhs.Name == ident.Name {
+				return valueType(decl.Rhs[i])
+			}
+		}
+	}
+	return nil
+}
+
+// valueType returns the type expression of a conversion, composite literal or
+// address of one, or nil
+func valueType(expr ast.Expr) ast.Expr {
+	for {
+		switch e := expr.(type) {
+		case *ast.ParenExpr:
+			expr = e.X
+		case *ast.UnaryExpr:
+			if e.Op != token.AND {
+				return nil
+			}
+			expr = e.X
+		case *ast.CompositeLit:
+			return e.Type
+		case *ast.CallExpr:
+			if len(e.Args) != 1 {
+				return nil
+			}
+			expr = e.Fun
+		case *ast.StarExpr:
+			return e
+		default:
+			return nil
+		}
+	}
+}
+
+// errorCheckers maps the exported errors of module packages, as
+// "import/path.Name", to the other packages checking for them
+func (a *ModuleAnalyzer) errorCheckers() map[string][]string {
+	checkers := make(map[string][]string)
+	for pkg := range a.errorChecks {
+		for _, check := range a.errorChecks[pkg] {
+			checkers[check] = append(checkers[check], pkg)
+		}
+	}
+	return checkers
+}
+
+// errorTargets returns the exported errors of a package with the packages
+// checking for them, and the number of distinct packages checking for any
+func (a *ModuleAnalyzer) errorTargets(pkg string, checkers map[string][]string) ([]models.ErrorTarget, int) {
+	decls := a.errorDecls[pkg]
+	if len(decls) == 0 {
+		return nil, 0
+	}
+	dependents := make(map[string]bool)
+	targets := make([]models.ErrorTarget, 0, len(decls))
+	for _, decl := range decls {
+		ids := checkers[pkg+"."+decl.name]
+		for _, id := range ids {
+			dependents[id] = true
+		}
+		targets = append(targets, models.ErrorTarget{Name: decl.name, Kind: decl.kind, CheckedBy: a.displayNames(ids)})
+	}
+	return targets, len(dependents)
+}
@@ -0,0 +1,29 @@
+// Package analyzer provides functionality for analyzing Go modules and calculating design metrics.
+// This file implements leaving packages out of the analysis by pattern.
+package analyzer
+
+// withoutExcluded drops the discovered packages matching an exclude pattern
+// (see AnalyzerOptions.Exclude)
+func (a *ModuleAnalyzer) withoutExcluded(infos []PackageInfo) []PackageInfo {
+	if len(a.options.Exclude) == 0 {
+		return infos
+	}
+	kept := infos[:0]
+	for _, info := range infos {
+		if !a.excluded(info.ImportPath) {
+			kept = append(kept, info)
+		}
+	}
+	return kept
+}
+
+// excluded reports whether a module package matches an exclude pattern
+func (a *ModuleAnalyzer) excluded(importPath string) bool {
+	relPath := a.getRelativePackagePath(importPath)
+	for _, pattern := range a.options.Exclude {
+		if matchesRolePattern(relPath, pattern) {
+			return true
+		}
+	}
+	return false
+}
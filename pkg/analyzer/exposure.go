@@ -0,0 +1,221 @@
+// Package analyzer provides functionality for analyzing Go modules and calculating design metrics.
+// This file implements the analysis of the exported API: which dependencies it
+// exposes and which types of other modules leak through it.
+package analyzer
+
+import (
+	"go/types"
+	"sort"
+	"strings"
+
+	"github.com/alkbt/aid-metrics/pkg/models"
+	"golang.org/x/tools/go/packages"
+)
+
+// exposedDependencies returns the dependencies whose types appear in the exported
+// API of a package (see visitExportedAPI). Dependents of the package are coupled
+// to these dependencies as well, which makes them a much stronger coupling than
+// private usage. Only packages in deps are reported; without type information nothing is.
+func exposedDependencies(pkg *packages.Package, deps []string) []string {
+	if pkg.Types == nil || len(deps) == 0 {
+		return nil
+	}
+
+	isDep := make(map[string]bool, len(deps))
+	for _, dep := range deps {
+		isDep[dep] = true
+	}
+	exposed := make(map[string]bool)
+	visitExportedAPI(pkg.Types, func(_ string, ref *types.TypeName) {
+		if isDep[ref.Pkg().Path()] {
+			exposed[ref.Pkg().Path()] = true
+		}
+	})
+
+	result := make([]string, 0, len(exposed))
+	for dep := range exposed {
+		result = append(result, dep)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// typeLeak is a type of another module exposed by an exported declaration
+type typeLeak struct {
+	declaration string // Exported declaration, e.g. "NewClient" or "Client.Transport"
+	pkg         string // Import path of the package declaring the leaked type
+	typeName    string // Name of the leaked type within its package
+}
+
+// leakedTypes returns every exported declaration of a package exposing a type
+// declared in another module, sorted by package, type and declaration. Such leaks
+// force the dependents to depend on the other module too and are usually fixed by
+// wrapping the type or by accepting a locally declared interface.
+// The standard library is not considered another module, and main packages
+// cannot be imported, so they have no API to leak through.
+func leakedTypes(pkg *packages.Package, moduleName string) []typeLeak {
+	if pkg.Types == nil || pkg.Name == "main" {
+		return nil
+	}
+
+	seen := make(map[typeLeak]bool)
+	var leaks []typeLeak
+	visitExportedAPI(pkg.Types, func(declaration string, ref *types.TypeName) {
+		path := ref.Pkg().Path()
+		if isStandardLibraryPackage(path, moduleName) || inModule(path, moduleName) {
+			return
+		}
+		leak := typeLeak{declaration: declaration, pkg: path, typeName: ref.Name()}
+		if !seen[leak] {
+			seen[leak] = true
+			leaks = append(leaks, leak)
+		}
+	})
+
+	sort.Slice(leaks, func(i, j int) bool {
+		if leaks[i].pkg != leaks[j].pkg {
+			return leaks[i].pkg < leaks[j].pkg
+		}
+		if leaks[i].typeName != leaks[j].typeName {
+			return leaks[i].typeName < leaks[j].typeName
+		}
+		return leaks[i].declaration < leaks[j].declaration
+	})
+	return leaks
+}
+
+// newTypeLeaks converts type leaks into their model representation
+func newTypeLeaks(leaks []typeLeak) []models.TypeLeak {
+	if len(leaks) == 0 {
+		return nil
+	}
+	result := make([]models.TypeLeak, 0, len(leaks))
+	for _, leak := range leaks {
+		result = append(result, models.TypeLeak{Declaration: leak.declaration, Package: leak.pkg, Type: leak.typeName})
+	}
+	return result
+}
+
+// inModule reports whether an import path belongs to the module
+func inModule(path, moduleName string) bool {
+	return moduleName != "" && (path == moduleName || strings.HasPrefix(path, moduleName+"/"))
+}
+
+// visitExportedAPI calls fn for every named type of another package referenced by
+// the exported API of a package: signatures of exported functions and methods,
+// exported struct fields, methods of exported interfaces, and types of exported
+// variables and constants. The declaration names the exported part referencing the type.
+func visitExportedAPI(pkg *types.Package, fn func(declaration string, ref *types.TypeName)) {
+	visit := func(declaration string, t types.Type) {
+		walkType(t, func(ref *types.TypeName) {
+			if ref.Pkg() != nil && ref.Pkg() != pkg {
+				fn(declaration, ref)
+			}
+		})
+	}
+
+	scope := pkg.Scope()
+	for _, name := range scope.Names() {
+		obj := scope.Lookup(name)
+		if !obj.Exported() {
+			continue
+		}
+		switch obj := obj.(type) {
+		case *types.TypeName:
+			visitExportedType(obj, visit)
+		default:
+			visit(name, obj.Type())
+		}
+	}
+}
+
+// visitExportedType visits the parts of an exported type declaration that are
+// visible to other packages: exported fields and methods, or the aliased or
+// underlying type otherwise.
+func visitExportedType(obj *types.TypeName, visit func(declaration string, t types.Type)) {
+	name := obj.Name()
+	if obj.IsAlias() {
+		visit(name, obj.Type())
+		return
+	}
+	named, ok := obj.Type().(*types.Named)
+	if !ok {
+		return
+	}
+
+	for i := 0; i < named.NumMethods(); i++ {
+		if method := named.Method(i); method.Exported() {
+			visit(name+"."+method.Name(), method.Type())
+		}
+	}
+
+	switch underlying := named.Underlying().(type) {
+	case *types.Struct:
+		for i := 0; i < underlying.NumFields(); i++ {
+			if field := underlying.Field(i); field.Exported() {
+				visit(name+"."+field.Name(), field.Type())
+			}
+		}
+	case *types.Interface:
+		for i := 0; i < underlying.NumExplicitMethods(); i++ {
+			if method := underlying.ExplicitMethod(i); method.Exported() {
+				visit(name+"."+method.Name(), method.Type())
+			}
+		}
+		for i := 0; i < underlying.NumEmbeddeds(); i++ {
+			visit(name, underlying.EmbeddedType(i))
+		}
+	default:
+		visit(name, underlying)
+	}
+}
+
+// walkType calls fn for every named type referenced by t. The underlying types
+// of named types are not entered: they belong to the API of their own package.
+func walkType(t types.Type, fn func(*types.TypeName)) {
+	switch t := t.(type) {
+	case *types.Named:
+		fn(t.Obj())
+		if args := t.TypeArgs(); args != nil {
+			for i := 0; i < args.Len(); i++ {
+				walkType(args.At(i), fn)
+			}
+		}
+	case *types.Alias:
+		walkType(types.Unalias(t), fn)
+	case *types.Pointer:
+		walkType(t.Elem(), fn)
+	case *types.Slice:
+		walkType(t.Elem(), fn)
+	case *types.Array:
+		walkType(t.Elem(), fn)
+	case *types.Chan:
+		walkType(t.Elem(), fn)
+	case *types.Map:
+		walkType(t.Key(), fn)
+		walkType(t.Elem(), fn)
+	case *types.Signature:
+		for _, tuple := range []*types.Tuple{t.Params(), t.Results()} {
+			for i := 0; i < tuple.Len(); i++ {
+				walkType(tuple.At(i).Type(), fn)
+			}
+		}
+	case *types.Struct:
+		for i := 0; i < t.NumFields(); i++ {
+			walkType(t.Field(i).Type(), fn)
+		}
+	case *types.Interface:
+		for i := 0; i < t.NumExplicitMethods(); i++ {
+			walkType(t.ExplicitMethod(i).Type(), fn)
+		}
+		for i := 0; i < t.NumEmbeddeds(); i++ {
+			walkType(t.EmbeddedType(i), fn)
+		}
+	case *types.TypeParam:
+		walkType(t.Constraint(), fn)
+	case *types.Union:
+		for i := 0; i < t.Len(); i++ {
+			walkType(t.Term(i).Type(), fn)
+		}
+	}
+}
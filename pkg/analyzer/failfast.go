@@ -0,0 +1,182 @@
+// Package analyzer provides functionality for analyzing Go modules and calculating design metrics.
+// This file implements stopping the analysis at the first gate violation.
+package analyzer
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/alkbt/aid-metrics/pkg/models"
+)
+
+// GateError is returned by the analysis when AnalyzerOptions.FailFast is set and
+// a gate violation was found before all packages were analyzed
+type GateError struct {
+	Finding models.Finding
+}
+
+// Error returns the violation as a message
+func (e *GateError) Error() string {
+	return fmt.Sprintf("gate violation in %s: %s", e.Finding.Package, e.Finding.Message)
+}
+
+// failFastGate checks packages as they are analyzed for violations that packages
+// analyzed later cannot undo, and cancels the analysis at the first one.
+//
+// Analyzing more packages only adds dependents, so Ca can only grow and I can
+// only shrink. Import cycles, leaked types, data bags and abstractness are final
+// as soon as their packages are analyzed; a distance violation in the zone of
+// pain (A+I < 1) is final as I can only move further away from the main sequence.
+// The instability threshold and the SDP/SAP findings need the whole graph.
+type failFastGate struct {
+	a      *ModuleAnalyzer
+	cancel context.CancelFunc
+
+	mu         sync.Mutex
+	results    map[string]*packageAnalysisResult // Analyzed packages
+	dependents map[string]int                    // Package -> number of analyzed dependents
+	violation  *models.Finding
+}
+
+// newFailFastGate creates a gate calling cancel at the first violation
+func newFailFastGate(a *ModuleAnalyzer, cancel context.CancelFunc) *failFastGate {
+	return &failFastGate{
+		a:          a,
+		cancel:     cancel,
+		results:    make(map[string]*packageAnalysisResult),
+		dependents: make(map[string]int),
+	}
+}
+
+// err returns the violation found, if any
+func (g *failFastGate) err() error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.violation == nil {
+		return nil
+	}
+	return &GateError{Finding: *g.violation}
+}
+
+// add checks an analyzed package, and the analyzed packages it depends on, whose Ca it raises
+func (g *failFastGate) add(result packageAnalysisResult) {
+	if result.err != nil || result.packageID == "" || isIgnored(result.annotations) {
+		return
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.violation != nil {
+		return
+	}
+	g.results[result.packageID] = &result
+
+	if cycle := g.cycleThrough(result.packageID); cycle != nil {
+		names := make([]string, len(cycle))
+		for i, id := range cycle {
+			names[i] = g.a.getRelativePackagePath(id)
+		}
+		g.report(result.packageID, g.a.newFinding(models.CategoryCycle, names[0],
+			fmt.Sprintf("import cycle between %d packages: %s", len(names), strings.Join(names, " -> ")), ""))
+	}
+
+	g.check(result.packageID)
+	for _, dep := range result.dependencies {
+		g.dependents[dep]++
+		if _, analyzed := g.results[dep]; analyzed {
+			g.check(dep)
+		}
+	}
+}
+
+// check checks the final metrics of an analyzed package
+func (g *failFastGate) check(id string) {
+	r := g.results[id]
+	a := g.a
+	name := a.getRelativePackagePath(id)
+
+	abstractness := 0.0
+	if r.totalTypesCount > 0 {
+		abstractness = float64(r.abstractCount) / float64(r.totalTypesCount)
+	}
+	for _, leak := range r.leaks {
+		g.report(id, a.newFinding(models.CategoryLeak, name, fmt.Sprintf("exposes types of %s in exported declaration %s", leak.pkg, leak.declaration), ""))
+	}
+	if isDataBag(r.taggedStructs, r.structCount, abstractness) {
+		g.report(id, a.newFinding(models.CategoryDataBag, name,
+			fmt.Sprintf("%d tagged entity structs and A=%.2f: a concrete data bag many packages couple to", r.taggedStructs, abstractness), ""))
+	}
+
+	// Thresholds apply to coupled packages that are not exempt from gating
+	t := a.packageThresholds(r.annotations)
+	ca, ce := g.dependents[id], len(r.dependencies)
+	exempt := a.gateExemptReason(r.generated) != "" || a.entryPointExemptReason(roleOrOther(r.role), r.diFramework != "") != ""
+	if t == nil || a.options.ImportsOnly || exempt || ca+ce == 0 {
+		return
+	}
+	if abstractness < t.MinAbstractness {
+		g.report(id, a.newFinding(models.CategoryThreshold, name,
+			fmt.Sprintf("abstractness A=%.2f is below the minimum of %.2f", abstractness, t.MinAbstractness), ""))
+	}
+	// The instability so far is an upper bound of the final one
+	instability := float64(ce) / float64(ca+ce)
+	if distance := 1 - abstractness - instability; distance > t.MaxDistance {
+		g.report(id, a.newFinding(models.CategoryThreshold, name,
+			fmt.Sprintf("distance D>=%.2f exceeds the maximum of %.2f (A=%.2f, I<=%.2f)", distance, t.MaxDistance, abstractness, instability), ""))
+	}
+}
+
+// report records a finding about a package as the violation if it is gated and
+// the package's annotations do not allow it, and cancels the analysis
+func (g *failFastGate) report(id string, finding models.Finding) {
+	if g.violation != nil {
+		return
+	}
+	if r := g.results[id]; r != nil && allows(r.annotations, finding.ID) {
+		return
+	}
+	threshold := finding.Category == models.CategoryThreshold
+	severity := g.a.options.FailFastSeverity
+	if !threshold && (severity == "" || finding.Severity.Level() < severity.Level()) {
+		return
+	}
+	g.violation = &finding
+	g.cancel()
+}
+
+// cycleThrough returns an import cycle through the package among the analyzed
+// packages, starting with the package, or nil if there is none
+func (g *failFastGate) cycleThrough(start string) []string {
+	visited := make(map[string]bool)
+	var path []string
+	var visit func(id string) bool
+	visit = func(id string) bool {
+		r, analyzed := g.results[id]
+		if !analyzed || visited[id] {
+			return false
+		}
+		visited[id] = true
+		path = append(path, id)
+		for _, dep := range r.dependencies {
+			if dep == start || visit(dep) {
+				return true
+			}
+		}
+		path = path[:len(path)-1]
+		return false
+	}
+	if visit(start) {
+		return path
+	}
+	return nil
+}
+
+// roleOrOther returns the role, or RoleOther if it is empty
+func roleOrOther(role string) string {
+	if role == "" {
+		return models.RoleOther
+	}
+	return role
+}
@@ -0,0 +1,265 @@
+// Package analyzer provides functionality for analyzing Go modules and calculating design metrics.
+// This file implements the checks that turn computed metrics into findings.
+package analyzer
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/alkbt/aid-metrics/pkg/models"
+)
+
+// sapMaxDistance is the distance from the main sequence above which a package
+// is reported as violating the Stable Abstractions Principle.
+const sapMaxDistance = 0.7
+
+// newFinding creates a finding of the given category, applying the configured severity
+func (a *ModuleAnalyzer) newFinding(category, pkg, message, remediation string) models.Finding {
+	severity, ok := a.options.Severities[category]
+	if !ok {
+		severity = models.DefaultSeverities[category]
+	}
+	return models.Finding{
+		ID:          models.FindingIDs[category],
+		Severity:    severity,
+		Category:    category,
+		Package:     pkg,
+		Message:     message,
+		Remediation: remediation,
+		Points:      a.debtPoints(category, severity),
+	}
+}
+
+// collectFindings runs all metric-based checks and returns the findings sorted
+// by severity (most severe first), category and package.
+func (a *ModuleAnalyzer) collectFindings(metrics *models.ModuleMetrics) []models.Finding {
+	var findings []models.Finding
+
+	for _, cycle := range metrics.Cycles {
+		findings = append(findings, a.newFinding(models.CategoryCycle, cycle[0],
+			fmt.Sprintf("import cycle between %d packages: %s", len(cycle), strings.Join(cycle, " -> ")),
+			"Break the cycle by moving the shared types into a separate package or by inverting one dependency through an interface."))
+	}
+
+	findings = append(findings, a.ruleFindings(metrics)...)
+
+	for _, id := range sortedPackageIDs(metrics.Packages) {
+		pkg := metrics.Packages[id]
+
+		// Stable Dependencies Principle: depend in the direction of stability.
+		// Composition roots depend on everything by design and are skipped.
+		if !pkg.CompositionRoot && pkg.Role != models.RoleMain {
+			var unstable []string
+			for _, dep := range a.dependencies[id] {
+				if depMetrics, ok := metrics.Packages[dep]; ok && depMetrics.Instability > pkg.Instability {
+					unstable = append(unstable, depMetrics.Name)
+				}
+			}
+			if len(unstable) > 0 {
+				sort.Strings(unstable)
+				findings = append(findings, a.newFinding(models.CategorySDP, pkg.Name,
+					fmt.Sprintf("depends on less stable packages (I=%.2f): %s", pkg.Instability, strings.Join(unstable, ", ")),
+					"Introduce an interface owned by this package and let the unstable packages implement it."))
+			}
+		}
+
+		// Stable Abstractions Principle: stability should be matched by abstractness.
+		// Isolated packages have no coupling, so their stability is meaningless.
+		// Without type information (imports-only mode) abstractness is unknown.
+		if !a.options.ImportsOnly && !pkg.GateExempt && pkg.Ca+pkg.Ce > 0 && pkg.Distance > sapMaxDistance {
+			problem := "stable but concrete (zone of pain)"
+			if pkg.Abstractness+pkg.Instability > 1 {
+				problem = "unstable but abstract (zone of uselessness)"
+			}
+			findings = append(findings, a.newFinding(models.CategorySAP, pkg.Name,
+				fmt.Sprintf("%s: D=%.2f (A=%.2f, I=%.2f)", problem, pkg.Distance, pkg.Abstractness, pkg.Instability),
+				"Move the package toward the main sequence by adding abstractions if it is stable, or by removing unused abstractions if it is unstable."))
+		}
+
+		findings = append(findings, a.thresholdFindings(pkg)...)
+		findings = append(findings, a.leakFindings(pkg)...)
+
+		for _, header := range pkg.HeaderInterfaces {
+			findings = append(findings, a.newFinding(models.CategoryHeaderInterface, pkg.Name,
+				fmt.Sprintf("interface %s is only implemented by %s in the same package (header interface)", header.Interface, header.Implementation),
+				"Export the concrete type and let the consumers declare the small interfaces they need where they use them."))
+		}
+
+		if pkg.DataBag {
+			findings = append(findings, a.newFinding(models.CategoryDataBag, pkg.Name,
+				fmt.Sprintf("%d tagged entity structs and A=%.2f: a concrete data bag many packages couple to", pkg.TaggedStructs, pkg.Abstractness),
+				"Keep persistence/serialization tags at the edges and expose domain types or interfaces to the rest of the module."))
+		}
+
+		if pkg.SharedKernel {
+			sets := make([]string, 0, len(pkg.ConstantSets))
+			for _, set := range pkg.ConstantSets {
+				if len(set.ReferencedBy) > 0 {
+					sets = append(sets, fmt.Sprintf("%s (%d packages)", set.Name, len(set.ReferencedBy)))
+				}
+			}
+			findings = append(findings, a.newFinding(models.CategorySharedKernel, pkg.Name,
+				fmt.Sprintf("%.0f%% of the exported declarations are constants, referenced by %d packages: %s",
+					pkg.ConstantShare*100, pkg.ConstantDependents, strings.Join(sets, ", ")),
+				"Treat it as a shared kernel: keep it free of dependencies and behavior so it stays stable, and expose enums as named types with methods rather than raw values."))
+		}
+
+		if pkg.DeprecatedUses > 0 {
+			symbols := make([]string, 0, len(pkg.DeprecatedSymbols))
+			for _, use := range pkg.DeprecatedSymbols {
+				symbols = append(symbols, fmt.Sprintf("%s.%s (%d)", use.Package, use.Symbol, use.Uses))
+			}
+			findings = append(findings, a.newFinding(models.CategoryDeprecated, pkg.Name,
+				fmt.Sprintf("%d references to %d deprecated symbols: %s", pkg.DeprecatedUses, len(pkg.DeprecatedSymbols), strings.Join(symbols, ", ")),
+				"Migrate to the replacements named in the deprecation notices before the symbols are removed."))
+		}
+	}
+
+	findings = withoutAllowed(metrics, findings)
+	SortFindings(findings)
+	return findings
+}
+
+// thresholdFindings checks a package against the configured thresholds, as
+// overridden by its annotations.
+// Packages exempt from gating (including entry points, unless they are gated) are
+// not held to them, nor are isolated packages whose position on the A/I chart is meaningless.
+func (a *ModuleAnalyzer) thresholdFindings(pkg models.PackageMetrics) []models.Finding {
+	t := a.packageThresholds(pkg.Annotations)
+	if t == nil || pkg.GateExempt || pkg.Ca+pkg.Ce == 0 {
+		return nil
+	}
+
+	var findings []models.Finding
+	if pkg.Instability > t.MaxInstability {
+		findings = append(findings, a.newFinding(models.CategoryThreshold, pkg.Name,
+			fmt.Sprintf("instability I=%.2f exceeds the maximum of %.2f", pkg.Instability, t.MaxInstability),
+			"Reduce the outgoing dependencies of the package or move the code its dependents need into a more stable package."))
+	}
+	// Without type information (imports-only mode) abstractness is unknown
+	if a.options.ImportsOnly {
+		return findings
+	}
+	if pkg.Distance > t.MaxDistance {
+		findings = append(findings, a.newFinding(models.CategoryThreshold, pkg.Name,
+			fmt.Sprintf("distance D=%.2f exceeds the maximum of %.2f (A=%.2f, I=%.2f)", pkg.Distance, t.MaxDistance, pkg.Abstractness, pkg.Instability),
+			"Move the package toward the main sequence by adding abstractions if it is stable, or by removing unused abstractions if it is unstable."))
+	}
+	if pkg.Abstractness < t.MinAbstractness {
+		findings = append(findings, a.newFinding(models.CategoryThreshold, pkg.Name,
+			fmt.Sprintf("abstractness A=%.2f is below the minimum of %.2f", pkg.Abstractness, t.MinAbstractness),
+			"Extract the contracts other packages rely on into interfaces owned by this package."))
+	}
+	return findings
+}
+
+// leakFindings reports the types of other modules a package exposes in its
+// exported API, one finding per offending package with the exposing declarations
+func (a *ModuleAnalyzer) leakFindings(pkg models.PackageMetrics) []models.Finding {
+	var findings []models.Finding
+	for start := 0; start < len(pkg.LeakedTypes); {
+		end := start
+		var cases []string
+		for ; end < len(pkg.LeakedTypes) && pkg.LeakedTypes[end].Package == pkg.LeakedTypes[start].Package; end++ {
+			leak := pkg.LeakedTypes[end]
+			cases = append(cases, fmt.Sprintf("%s (%s)", leak.Declaration, leak.Type))
+		}
+		findings = append(findings, a.newFinding(models.CategoryLeak, pkg.Name,
+			fmt.Sprintf("exposes types of %s in %d exported declarations: %s",
+				pkg.LeakedTypes[start].Package, len(cases), strings.Join(cases, ", ")),
+			"Wrap the types in types owned by this package, or accept a locally declared interface, so dependents do not have to depend on the other module."))
+		start = end
+	}
+	return findings
+}
+
+// SortFindings orders findings by severity (most severe first), then category and package
+func SortFindings(findings []models.Finding) {
+	sort.SliceStable(findings, func(i, j int) bool {
+		if findings[i].Severity.Level() != findings[j].Severity.Level() {
+			return findings[i].Severity.Level() > findings[j].Severity.Level()
+		}
+		if findings[i].Category != findings[j].Category {
+			return findings[i].Category < findings[j].Category
+		}
+		return findings[i].Package < findings[j].Package
+	})
+}
+
+// findCycles returns the import cycles among the analyzed packages as lists of
+// display names. Each cycle is a strongly connected component of the dependency
+// graph with more than one package, sorted by name.
+func (a *ModuleAnalyzer) findCycles() [][]string {
+	index := 0
+	indices := make(map[string]int)
+	lowlink := make(map[string]int)
+	onStack := make(map[string]bool)
+	var stack []string
+	var cycles [][]string
+
+	var strongConnect func(pkg string)
+	strongConnect = func(pkg string) {
+		indices[pkg] = index
+		lowlink[pkg] = index
+		index++
+		stack = append(stack, pkg)
+		onStack[pkg] = true
+
+		for _, dep := range a.dependencies[pkg] {
+			if _, analyzed := a.dependencies[dep]; !analyzed {
+				continue
+			}
+			if _, visited := indices[dep]; !visited {
+				strongConnect(dep)
+				lowlink[pkg] = min(lowlink[pkg], lowlink[dep])
+			} else if onStack[dep] {
+				lowlink[pkg] = min(lowlink[pkg], indices[dep])
+			}
+		}
+
+		if lowlink[pkg] == indices[pkg] {
+			var component []string
+			for {
+				top := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				onStack[top] = false
+				component = append(component, a.displayName(top))
+				if top == pkg {
+					break
+				}
+			}
+			if len(component) > 1 {
+				sort.Strings(component)
+				cycles = append(cycles, component)
+			}
+		}
+	}
+
+	// Visit packages in a stable order so the output is deterministic
+	ids := make([]string, 0, len(a.dependencies))
+	for id := range a.dependencies {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		if _, visited := indices[id]; !visited {
+			strongConnect(id)
+		}
+	}
+
+	sort.Slice(cycles, func(i, j int) bool {
+		return cycles[i][0] < cycles[j][0]
+	})
+	return cycles
+}
+
+// sortedPackageIDs returns the keys of a package metrics map in sorted order
+func sortedPackageIDs(pkgs map[string]models.PackageMetrics) []string {
+	ids := make([]string, 0, len(pkgs))
+	for id := range pkgs {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
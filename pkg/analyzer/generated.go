@@ -0,0 +1,190 @@
+// Package analyzer provides functionality for analyzing Go modules and calculating design metrics.
+// This file implements detection of generated Go files and built-in profiles for generated packages.
+package analyzer
+
+import (
+	"go/ast"
+	"go/types"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// ProfileProtobuf is the built-in profile for protoc-gen-go output.
+// Packages consisting solely of protoc generated files are exempt from
+// abstractness/distance gating, while coupling to them is still counted.
+const ProfileProtobuf = "protobuf"
+
+// ProfileMocks is the built-in profile for generated test doubles.
+// Packages consisting solely of generated mocks are exempt from
+// abstractness/distance gating, while coupling to them is still counted.
+const ProfileMocks = "mocks"
+
+// mockGenerators are the generator names of well-known mock generators
+// (mockgen, moq, counterfeiter) as they appear in the generated code header
+var mockGenerators = map[string]bool{
+	"MockGen":       true,
+	"mockgen":       true,
+	"moq":           true,
+	"counterfeiter": true,
+}
+
+// hasProfile reports whether the given built-in profile is enabled
+func (a *ModuleAnalyzer) hasProfile(profile string) bool {
+	for _, p := range a.options.Profiles {
+		if p == profile {
+			return true
+		}
+	}
+	return false
+}
+
+// gateExemptReason returns why a package is exempt from abstractness/distance gating
+// under the enabled profiles, or an empty string if it is not exempt.
+func (a *ModuleAnalyzer) gateExemptReason(generated generatedStats) string {
+	if a.hasProfile(ProfileProtobuf) && generated.isProtobuf() {
+		return "generated protobuf code"
+	}
+	if a.hasProfile(ProfileMocks) && generated.isMocks() {
+		return "generated mocks"
+	}
+	return ""
+}
+
+// generatedBy returns the generator named in the standard
+// "// Code generated ... DO NOT EDIT." header of a file.
+// The second result reports whether the header was found at all; a generated
+// file without a "by <tool>" clause yields an empty generator name.
+func generatedBy(file *ast.File) (string, bool) {
+	for _, group := range file.Comments {
+		if group.Pos() >= file.Package {
+			break
+		}
+		for _, comment := range group.List {
+			text := comment.Text
+			if !strings.HasPrefix(text, "// Code generated ") || !strings.HasSuffix(text, " DO NOT EDIT.") {
+				continue
+			}
+			generator := ""
+			if rest, ok := strings.CutPrefix(text, "// Code generated by "); ok {
+				if fields := strings.Fields(rest); len(fields) > 0 {
+					generator = strings.TrimRight(fields[0], ".;,")
+				}
+			}
+			return generator, true
+		}
+	}
+	return "", false
+}
+
+// declaredInGenerated returns a filter reporting whether an object of the package
+// is declared in a generated file, for SkipGenerated
+func declaredInGenerated(pkg *packages.Package) func(types.Object) bool {
+	generated := make(map[string]bool)
+	for _, file := range pkg.Syntax {
+		if _, ok := generatedBy(file); ok {
+			generated[pkg.Fset.File(file.Pos()).Name()] = true
+		}
+	}
+	return func(obj types.Object) bool {
+		return len(generated) > 0 && generated[pkg.Fset.Position(obj.Pos()).Filename]
+	}
+}
+
+// handwrittenDependencies returns the dependencies imported by at least one file
+// of the package without a generated code header, for SkipGeneratedImports
+func handwrittenDependencies(pkg *packages.Package, deps []string) []string {
+	imported := make(map[string]bool)
+	for _, file := range pkg.Syntax {
+		if _, ok := generatedBy(file); ok {
+			continue
+		}
+		for _, spec := range file.Imports {
+			path, err := strconv.Unquote(spec.Path.Value)
+			if err != nil {
+				continue
+			}
+			if imp, ok := pkg.Imports[path]; ok {
+				imported[packageID(imp.ID)] = true
+			}
+		}
+	}
+
+	kept := make([]string, 0, len(deps))
+	for _, dep := range deps {
+		if imported[dep] {
+			kept = append(kept, dep)
+		}
+	}
+	return kept
+}
+
+// generatedStats describes how much of a package consists of generated files
+type generatedStats struct {
+	// files is the number of files inspected
+	files int
+
+	// generated is the number of files carrying the generated code header
+	generated int
+
+	// generators holds the distinct generator names found, in order of appearance
+	generators []string
+}
+
+// add records the generated header of a single file
+func (g *generatedStats) add(file *ast.File) {
+	g.files++
+	generator, ok := generatedBy(file)
+	if !ok {
+		return
+	}
+	g.generated++
+	for _, existing := range g.generators {
+		if existing == generator {
+			return
+		}
+	}
+	g.generators = append(g.generators, generator)
+}
+
+// fullyGenerated reports whether every file of the package is generated
+func (g generatedStats) fullyGenerated() bool {
+	return g.files > 0 && g.generated == g.files
+}
+
+// generator returns the generator responsible for the package, if the whole
+// package is generated. Multiple generators are joined with a comma.
+func (g generatedStats) generator() string {
+	if !g.fullyGenerated() {
+		return ""
+	}
+	return strings.Join(g.generators, ",")
+}
+
+// isMocks reports whether the package consists solely of generated mocks
+func (g generatedStats) isMocks() bool {
+	if !g.fullyGenerated() {
+		return false
+	}
+	for _, generator := range g.generators {
+		if !mockGenerators[generator] {
+			return false
+		}
+	}
+	return true
+}
+
+// isProtobuf reports whether the package consists solely of protoc plugin output
+// (protoc-gen-go, protoc-gen-go-grpc, grpc-gateway and similar).
+func (g generatedStats) isProtobuf() bool {
+	if !g.fullyGenerated() {
+		return false
+	}
+	for _, generator := range g.generators {
+		if !strings.HasPrefix(generator, "protoc-gen-") {
+			return false
+		}
+	}
+	return true
+}
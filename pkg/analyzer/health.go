@@ -0,0 +1,68 @@
+// Package analyzer provides functionality for analyzing Go modules and calculating design metrics.
+// This file implements the composite health score of packages.
+package analyzer
+
+import (
+	"math"
+
+	"github.com/alkbt/aid-metrics/pkg/models"
+)
+
+// Mean cyclomatic complexities at which the complexity penalty starts and is full
+const (
+	simpleComplexity  = 5
+	complexComplexity = 15
+)
+
+// ScoreHealth sets the health score of every package from its metrics, its
+// membership in the import cycles of metrics and the options' health weights.
+// It is run by the analysis and must be run again whenever D or the cycles change.
+func ScoreHealth(metrics *models.ModuleMetrics, options AnalyzerOptions) {
+	weights := models.DefaultHealthWeights
+	if options.HealthWeights != nil {
+		weights = *options.HealthWeights
+	}
+	inCycle := make(map[string]bool)
+	for _, cycle := range metrics.Cycles {
+		for _, name := range cycle {
+			inCycle[name] = true
+		}
+	}
+
+	for id, pkg := range metrics.Packages {
+		pkg.Health = health(pkg, inCycle[pkg.Name], weights, options.ImportsOnly)
+		metrics.Packages[id] = pkg
+	}
+}
+
+// health returns the health score of a package between 0 and 100: the weighted
+// mean of the penalties of the available components, subtracted from 100.
+// Without type information (imports-only mode) D and the complexity are unknown;
+// D of gate-exempt packages, unstable and concrete by design, is left out as well.
+func health(pkg models.PackageMetrics, inCycle bool, weights models.HealthWeights, importsOnly bool) int {
+	var penalty, total float64
+	add := func(weight, value float64) {
+		penalty += weight * math.Max(0, math.Min(1, value))
+		total += weight
+	}
+
+	if !importsOnly && !pkg.GateExempt {
+		add(weights.Distance, pkg.Distance)
+	}
+	cycle := 0.0
+	if inCycle {
+		cycle = 1
+	}
+	add(weights.Cycles, cycle)
+	if !importsOnly {
+		add(weights.Complexity, (pkg.Complexity-simpleComplexity)/(complexComplexity-simpleComplexity))
+	}
+	if pkg.HasCoverage {
+		add(weights.Coverage, 1-pkg.Coverage)
+	}
+
+	if total == 0 {
+		return 100
+	}
+	return int(math.Round(100 * (1 - penalty/total)))
+}
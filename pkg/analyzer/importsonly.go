@@ -0,0 +1,228 @@
+// Package analyzer provides functionality for analyzing Go modules and calculating design metrics.
+// This file implements the imports-only fast mode, which builds the dependency graph
+// from import declarations without loading packages or type information.
+package analyzer
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"go/build"
+	"go/parser"
+	"go/token"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/alkbt/aid-metrics/pkg/models"
+	"golang.org/x/tools/go/packages"
+)
+
+// parseImportsOnly discovers the module's packages and parses only the import
+// declarations of their files. Files are memory-mapped and filtered by the default
+// build context, so the result matches what the go command would build on this platform.
+//
+// Only coupling (Ca, Ce, I), roles and composition roots are computed in this mode;
+// abstractness and all type-based metrics stay at zero.
+func (a *ModuleAnalyzer) parseImportsOnly(ctx context.Context) error {
+	progress := a.options.ProgressReporter
+	if progress != nil {
+		progress.StageStart(models.StageDiscovery, 0)
+	}
+
+	pattern := "./..."
+	if a.packageFilter != "" {
+		pattern = a.packageFilter
+	}
+	packageInfos, err := discoverPackages(ctx, a.modulePath, a.moduleName, pattern, a.options.IncludeTests, nil)
+	if err != nil {
+		return fmt.Errorf("failed to discover packages: %w", err)
+	}
+	packageInfos = a.withoutExcluded(packageInfos)
+	if len(packageInfos) == 0 {
+		return a.noPackagesError(pattern, 0)
+	}
+	if progress != nil {
+		progress.Update(len(packageInfos), fmt.Sprintf("Found %d packages", len(packageInfos)))
+		progress.StageDone(models.StageDiscovery)
+		progress.StageStart(models.StageParsing, len(packageInfos))
+	}
+
+	numWorkers := min(a.workers(), len(packageInfos))
+
+	// Each result slot is written by exactly one worker
+	results := make([][]packageAnalysisResult, len(packageInfos))
+	var next atomic.Int64
+	var parsed int
+	var progressMu sync.Mutex
+	var wg sync.WaitGroup
+	for w := 0; w < numWorkers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				i := int(next.Add(1) - 1)
+				if i >= len(packageInfos) || ctx.Err() != nil {
+					return
+				}
+				results[i] = a.analyzePackageImports(packageInfos[i])
+				if a.gate != nil {
+					for _, result := range results[i] {
+						a.gate.add(result)
+					}
+				}
+				if progress != nil {
+					progressMu.Lock()
+					parsed++
+					progress.Update(parsed, fmt.Sprintf("Parsed imports of %s (%d/%d)", shortenPackagePath(a.getRelativePackagePath(packageInfos[i].ImportPath)), parsed, len(packageInfos)))
+					progressMu.Unlock()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	for _, dirResults := range results {
+		for i := range dirResults {
+			if dirResults[i].err != nil {
+				return dirResults[i].err
+			}
+			a.storeResult(&dirResults[i])
+		}
+	}
+
+	if progress != nil {
+		progress.StageDone(models.StageParsing)
+	}
+
+	return nil
+}
+
+// analyzePackageImports parses the import declarations of a single discovered
+// package. It returns no result if build constraints exclude every file, and a
+// second result for the external test package if tests are included.
+func (a *ModuleAnalyzer) analyzePackageImports(info PackageInfo) []packageAnalysisResult {
+	entries, err := os.ReadDir(info.Dir)
+	if err != nil {
+		return []packageAnalysisResult{{err: fmt.Errorf("failed to read directory %s: %w", info.Dir, err)}}
+	}
+
+	pkg := &packages.Package{
+		ID:      info.ImportPath,
+		PkgPath: info.ImportPath,
+		Imports: make(map[string]*packages.Package),
+	}
+	xtest := &packages.Package{
+		ID:      info.ImportPath + "_test",
+		PkgPath: info.ImportPath + "_test",
+		Imports: make(map[string]*packages.Package),
+	}
+	fset := token.NewFileSet()
+	ctx := a.buildContext()
+	var synopsis synopsisPicker
+	var annotations []string
+	var diagnostics []models.Diagnostic
+
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".go") || (!a.options.IncludeTests && strings.HasSuffix(name, "_test.go")) {
+			continue
+		}
+
+		filePath := filepath.Join(info.Dir, name)
+		data, release, err := mapFile(filePath)
+		if err != nil {
+			return []packageAnalysisResult{{err: err}}
+		}
+
+		// A file whose build constraints cannot be read is parsed anyway, so its
+		// syntax errors are reported and the rest of it is used
+		included, err := matchBuildContext(ctx, info.Dir, name, data)
+		if err != nil {
+			included, err = true, nil
+		}
+		if included {
+			file, parseErr := parser.ParseFile(fset, filePath, data, parser.ImportsOnly|parser.ParseComments)
+			if parseErr != nil {
+				diagnostics = append(diagnostics, a.parseDiagnostics(parseErr)...)
+			}
+			// The parser recovers from errors after the package clause
+			if file.Package.IsValid() {
+				target := pkg
+				if strings.HasSuffix(name, "_test.go") && strings.HasSuffix(file.Name.Name, "_test") {
+					target = xtest
+				} else {
+					synopsis.add(filePath, file)
+					fileAnnotated, invalid := a.fileAnnotations(fset, filePath, file)
+					annotations = append(annotations, fileAnnotated...)
+					diagnostics = append(diagnostics, invalid...)
+				}
+				target.Name = file.Name.Name
+				target.GoFiles = append(target.GoFiles, filePath)
+				for _, spec := range file.Imports {
+					if importPath, unquoteErr := strconv.Unquote(spec.Path.Value); unquoteErr == nil {
+						target.Imports[importPath] = &packages.Package{ID: importPath, PkgPath: importPath}
+					}
+				}
+			}
+		}
+
+		// The parser copies everything it keeps, so the mapping can go right away
+		if releaseErr := release(); err == nil {
+			err = releaseErr
+		}
+		if err != nil {
+			return []packageAnalysisResult{{err: fmt.Errorf("failed to parse file %s: %w", filePath, err)}}
+		}
+	}
+
+	var results []packageAnalysisResult
+	for _, p := range []*packages.Package{pkg, xtest} {
+		// A package none of whose files parsed is kept, to be reported as partial
+		if len(p.GoFiles) > 0 || (p == pkg && len(diagnostics) > 0) {
+			result := a.importsResult(p)
+			if p == pkg {
+				result.synopsis = synopsis.synopsis
+				result.annotations = annotations
+				result.diagnostics = diagnostics
+			}
+			results = append(results, result)
+		}
+	}
+	return results
+}
+
+// importsResult builds the analysis result of a package from its imports
+func (a *ModuleAnalyzer) importsResult(pkg *packages.Package) packageAnalysisResult {
+	var result packageAnalysisResult
+	result.packageID = pkg.ID
+	for importPath := range pkg.Imports {
+		if isStandardLibraryPackage(importPath, a.moduleName) || strings.HasPrefix(importPath, "vendor/") {
+			continue
+		}
+		result.dependencies = append(result.dependencies, importPath)
+	}
+	sort.Strings(result.dependencies)
+	result.diFramework = detectDIFramework(pkg)
+	result.role = classifyRole(pkg, a.getRelativePackagePath(pkg.ID), a.options.RoleRules)
+
+	return result
+}
+
+// matchBuildContext reports whether a file is included in the build context,
+// judging by its name and build constraints. The already mapped contents are handed
+// to go/build so the file is not read a second time.
+func matchBuildContext(ctx build.Context, dir, name string, data []byte) (bool, error) {
+	ctx.OpenFile = func(string) (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(data)), nil
+	}
+	return ctx.MatchFile(dir, name)
+}
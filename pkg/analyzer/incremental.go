@@ -0,0 +1,219 @@
+// Package analyzer provides functionality for analyzing Go modules and calculating design metrics.
+// This file implements incremental analysis, which updates the metrics of a previous
+// analysis after some files changed by analyzing only the affected packages again.
+package analyzer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/alkbt/aid-metrics/pkg/models"
+	"golang.org/x/tools/go/packages"
+)
+
+// AnalyzeIncremental updates the metrics of the previous analysis of this analyzer
+// after the given files were changed, added or removed. Only the packages of
+// the changed files and the packages transitively importing them are loaded and
+// analyzed again; the results of all other packages are reused.
+func (a *ModuleAnalyzer) AnalyzeIncremental(prev *models.ModuleMetrics, changedFiles []string) (*models.ModuleMetrics, error) {
+	return a.AnalyzeIncrementalContext(context.Background(), prev, changedFiles)
+}
+
+// AnalyzeIncrementalContext is AnalyzeIncremental with a context.
+//
+// prev must be the latest result of this analyzer. A full analysis is done
+// instead when it is not, when go.mod, go.sum or go.work changed, when a changed
+// file belongs to a package that was not analyzed before (a new package), and with
+// IncludeTests or FailFast. Files of excluded packages and test files without
+// IncludeTests are ignored. After an error the next call does a full analysis.
+func (a *ModuleAnalyzer) AnalyzeIncrementalContext(ctx context.Context, prev *models.ModuleMetrics, changedFiles []string) (*models.ModuleMetrics, error) {
+	if prev == nil || prev != a.last || a.options.IncludeTests || a.options.FailFast {
+		return a.AnalyzeContext(ctx)
+	}
+	changed, full := a.changedPackages(changedFiles)
+	if full {
+		return a.AnalyzeContext(ctx)
+	}
+	if len(changed) == 0 {
+		return prev, nil
+	}
+
+	// Without type information, a package's results depend on its own files only
+	affected := changed
+	if !a.options.ImportsOnly {
+		affected = a.withDependents(changed)
+	}
+	a.last = nil
+	for _, id := range affected {
+		a.forgetPackage(id)
+	}
+	a.digests.Clear()
+	a.deprecations.reset()
+	a.registrations.reset()
+
+	infos := make([]PackageInfo, len(affected))
+	for i, id := range affected {
+		infos[i] = PackageInfo{ImportPath: id, Dir: a.packageDir(id), HasGoFiles: true}
+	}
+	if a.options.ImportsOnly {
+		if err := a.reparseImports(infos); err != nil {
+			return nil, fmt.Errorf("failed to parse imports: %w", err)
+		}
+	} else if err := a.reloadPackages(ctx, infos); err != nil {
+		return nil, err
+	}
+
+	a.last = a.calculateMetrics()
+	return a.last, nil
+}
+
+// changedPackages returns the import paths of the analyzed packages the changed
+// files belong to, sorted, or full if the change needs a full analysis
+func (a *ModuleAnalyzer) changedPackages(files []string) (ids []string, full bool) {
+	for _, file := range files {
+		if !filepath.IsAbs(file) {
+			file = filepath.Join(a.modulePath, file)
+		}
+		switch filepath.Base(file) {
+		case "go.mod", "go.sum", "go.work", "go.work.sum":
+			return nil, true
+		}
+		if !strings.HasSuffix(file, ".go") || (!a.options.IncludeTests && strings.HasSuffix(file, "_test.go")) {
+			continue
+		}
+		rel, err := filepath.Rel(a.modulePath, filepath.Dir(file))
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			continue
+		}
+		id := a.moduleName
+		if rel != "." {
+			id += "/" + filepath.ToSlash(rel)
+		}
+		if a.excluded(id) {
+			continue
+		}
+		if _, analyzed := a.dependencies[id]; !analyzed {
+			return nil, true
+		}
+		if !slices.Contains(ids, id) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return ids, false
+}
+
+// withDependents returns the packages and every analyzed package transitively
+// importing them, sorted, as type information flows from a package into its importers
+func (a *ModuleAnalyzer) withDependents(ids []string) []string {
+	affected := make(map[string]bool)
+	var visit func(id string)
+	visit = func(id string) {
+		if affected[id] {
+			return
+		}
+		affected[id] = true
+		for _, dependent := range a.reverseDepends[id] {
+			if _, analyzed := a.dependencies[dependent]; analyzed {
+				visit(dependent)
+			}
+		}
+	}
+	for _, id := range ids {
+		visit(id)
+	}
+	result := make([]string, 0, len(affected))
+	for id := range affected {
+		result = append(result, id)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// forgetPackage removes the results of a package from the analyzer's maps.
+// It must cover every map storeResult writes.
+func (a *ModuleAnalyzer) forgetPackage(id string) {
+	for _, dep := range a.dependencies[id] {
+		a.reverseDepends[dep] = slices.DeleteFunc(a.reverseDepends[dep], func(dependent string) bool { return dependent == id })
+		if len(a.reverseDepends[dep]) == 0 {
+			delete(a.reverseDepends, dep)
+		}
+	}
+	delete(a.dependencies, id)
+	delete(a.abstractTypes, id)
+	delete(a.totalTypes, id)
+	delete(a.allTypes, id)
+	delete(a.embedding, id)
+	delete(a.methods, id)
+	delete(a.complexity, id)
+	delete(a.concurrency, id)
+	delete(a.constructors, id)
+	delete(a.diFrameworks, id)
+	delete(a.roles, id)
+	delete(a.generated, id)
+	delete(a.structs, id)
+	delete(a.taggedStructs, id)
+	delete(a.deprecated, id)
+	delete(a.blank, id)
+	delete(a.inits, id)
+	delete(a.mains, id)
+	delete(a.errorDecls, id)
+	delete(a.errorChecks, id)
+	delete(a.constantSets, id)
+	delete(a.constantShares, id)
+	delete(a.constantRefs, id)
+	delete(a.stringKeys, id)
+	delete(a.endpoints, id)
+	delete(a.exposed, id)
+	delete(a.leaks, id)
+	delete(a.interfaces, id)
+	delete(a.concreteTypes, id)
+	delete(a.synopses, id)
+	delete(a.annotations, id)
+	delete(a.diagnostics, id)
+}
+
+// packageDir returns the directory of a module package
+func (a *ModuleAnalyzer) packageDir(id string) string {
+	return filepath.Join(a.modulePath, filepath.FromSlash(a.getRelativePackagePath(id)))
+}
+
+// reloadPackages loads and analyzes packages again. Packages whose files were
+// all removed are left out.
+func (a *ModuleAnalyzer) reloadPackages(ctx context.Context, infos []PackageInfo) error {
+	loader := NewBatchLoader(a.options.BatchSize, a.packagesConfig(), a.options.ProgressReporter, len(infos))
+	pkgs, err := loader.LoadPackagesContext(ctx, infos)
+	if err != nil {
+		return fmt.Errorf("failed to load packages: %w", err)
+	}
+	a.addDiagnostics(pkgs)
+	dropUnparsedFiles(pkgs)
+	pkgs = slices.DeleteFunc(pkgs, func(pkg *packages.Package) bool { return len(pkg.GoFiles) == 0 })
+	if err := a.parsePackages(ctx, pkgs); err != nil {
+		return fmt.Errorf("failed to parse packages: %w", err)
+	}
+	return nil
+}
+
+// reparseImports parses the imports of packages again in imports-only mode.
+// Packages whose directory was removed are left out.
+func (a *ModuleAnalyzer) reparseImports(infos []PackageInfo) error {
+	for _, info := range infos {
+		if _, err := os.Stat(info.Dir); os.IsNotExist(err) {
+			continue
+		}
+		results := a.analyzePackageImports(info)
+		for i := range results {
+			if results[i].err != nil {
+				return results[i].err
+			}
+			a.storeResult(&results[i])
+		}
+	}
+	return nil
+}
@@ -0,0 +1,258 @@
+// Package analyzer provides functionality for analyzing Go modules and calculating design metrics.
+// This file implements the interface ownership analysis: whether interfaces are
+// declared by the packages consuming them or by the packages providing them.
+package analyzer
+
+import (
+	"go/types"
+	"sort"
+	"strings"
+
+	"github.com/alkbt/aid-metrics/pkg/models"
+	"golang.org/x/tools/go/packages"
+)
+
+// methodSetDecl is an interface or a concrete type with its method set.
+// Methods are encoded as canonical strings (see methodKey), so implementations
+// can be matched across packages without keeping type information around,
+// which keeps the per-package results cacheable.
+type methodSetDecl struct {
+	name     string
+	exported bool
+	mock     bool // Concrete type declared in a generated mock file
+	methods  []string
+}
+
+// methodKey encodes a method as its name and signature with fully qualified types.
+// Unexported method names are qualified with their package, as they can only be
+// implemented within it.
+func methodKey(method *types.Func) string {
+	name := method.Name()
+	if !method.Exported() && method.Pkg() != nil {
+		name = method.Pkg().Path() + "." + name
+	}
+	return name + types.TypeString(method.Type(), nil)
+}
+
+// interfaceDecls returns the interfaces declared in a package that have methods, and
+// the concrete named types with the method sets of their pointer types (which include
+// the methods of the value type). Concrete types named in mockTypes are marked as
+// mocks. Generic declarations are skipped.
+func interfaceDecls(pkg *packages.Package, mockTypes map[string]bool) (interfaces, concrete []methodSetDecl) {
+	if pkg.Types == nil {
+		return nil, nil
+	}
+
+	scope := pkg.Types.Scope()
+	for _, name := range scope.Names() {
+		obj, ok := scope.Lookup(name).(*types.TypeName)
+		if !ok || obj.IsAlias() {
+			continue
+		}
+		named, ok := obj.Type().(*types.Named)
+		if !ok || named.TypeParams().Len() > 0 {
+			continue
+		}
+
+		decl := methodSetDecl{name: name, exported: obj.Exported()}
+		if iface, ok := named.Underlying().(*types.Interface); ok {
+			if !iface.IsMethodSet() || iface.NumMethods() == 0 {
+				continue
+			}
+			for i := 0; i < iface.NumMethods(); i++ {
+				decl.methods = append(decl.methods, methodKey(iface.Method(i)))
+			}
+			sort.Strings(decl.methods)
+			interfaces = append(interfaces, decl)
+			continue
+		}
+
+		decl.mock = mockTypes[name]
+		methodSet := types.NewMethodSet(types.NewPointer(named))
+		if methodSet.Len() == 0 {
+			continue
+		}
+		for i := 0; i < methodSet.Len(); i++ {
+			decl.methods = append(decl.methods, methodKey(methodSet.At(i).Obj().(*types.Func)))
+		}
+		sort.Strings(decl.methods)
+		concrete = append(concrete, decl)
+	}
+	return interfaces, concrete
+}
+
+// interfaceOwnership is the classification of the interfaces declared in a package
+type interfaceOwnership struct {
+	consumer int                      // Interfaces not implemented in the package itself
+	provider int                      // Interfaces implemented in the package itself
+	headers  []models.HeaderInterface // Provider-side interfaces with a single implementation
+	mocked   []models.MockedInterface // Interfaces with generated mocks
+}
+
+// interfaceRef is an interface declared in a package
+type interfaceRef struct {
+	pkg  string
+	decl *methodSetDecl
+}
+
+// classifyInterfaces classifies the interfaces of every analyzed package.
+// An interface is provider-side if its package also implements it, and consumer-side
+// otherwise: the idiomatic Go style, where packages declare the small interfaces they
+// need and the implementations satisfy them implicitly. Exported provider-side interfaces
+// with a single implementation in the whole module are header interfaces: a mirror of
+// one concrete type that adds indirection without inverting any dependency.
+// Generated mocks are not counted as implementations but inventoried per interface.
+func (a *ModuleAnalyzer) classifyInterfaces() map[string]interfaceOwnership {
+	// Index the concrete types by method, so only types sharing a method with an
+	// interface are checked against it
+	type implementation struct {
+		pkg  string
+		decl *methodSetDecl
+	}
+	byMethod := make(map[string][]implementation)
+	for pkg, decls := range a.concreteTypes {
+		for i := range decls {
+			for _, method := range decls[i].methods {
+				byMethod[method] = append(byMethod[method], implementation{pkg, &decls[i]})
+			}
+		}
+	}
+
+	// Mocks implement the interface they were generated for, and often smaller
+	// ones by accident: collect all of them and attribute the mocks afterwards
+	mockCandidates := make(map[*methodSetDecl][]interfaceRef)
+	implementations := make(map[*methodSetDecl]int)
+
+	ownership := make(map[string]interfaceOwnership)
+	for pkg, interfaces := range a.interfaces {
+		var result interfaceOwnership
+		for i := range interfaces {
+			iface := &interfaces[i]
+			var impls []implementation
+			local := false
+			for _, candidate := range byMethod[iface.methods[0]] {
+				if !implementsAll(candidate.decl.methods, iface.methods) {
+					continue
+				}
+				if candidate.decl.mock {
+					mockCandidates[candidate.decl] = append(mockCandidates[candidate.decl], interfaceRef{pkg, iface})
+					continue
+				}
+				impls = append(impls, candidate)
+				local = local || candidate.pkg == pkg
+			}
+			implementations[iface] = len(impls)
+
+			if !local {
+				result.consumer++
+				continue
+			}
+			result.provider++
+			if iface.exported && len(impls) == 1 {
+				result.headers = append(result.headers, models.HeaderInterface{
+					Interface:      iface.name,
+					Implementation: impls[0].decl.name,
+				})
+			}
+		}
+		sort.Slice(result.headers, func(i, j int) bool {
+			return result.headers[i].Interface < result.headers[j].Interface
+		})
+		ownership[pkg] = result
+	}
+
+	mocks := make(map[*methodSetDecl]int)
+	for mock, candidates := range mockCandidates {
+		for _, iface := range mockedInterfaces(mock, candidates) {
+			mocks[iface.decl]++
+		}
+	}
+	for pkg, interfaces := range a.interfaces {
+		result := ownership[pkg]
+		for i := range interfaces {
+			if n := mocks[&interfaces[i]]; n > 0 {
+				result.mocked = append(result.mocked, models.MockedInterface{
+					Interface: interfaces[i].name,
+					Mocks:     n,
+					OnlyMocks: implementations[&interfaces[i]] == 0,
+				})
+			}
+		}
+		ownership[pkg] = result
+	}
+	return ownership
+}
+
+// mockedInterfaces picks the interfaces a mock was generated for among those it
+// implements: the ones named after the mock (MockStore, StoreMock and FakeStore
+// mock Store), or else the ones with the most methods, since mocks add only
+// helper methods of their own.
+func mockedInterfaces(mock *methodSetDecl, candidates []interfaceRef) []interfaceRef {
+	base := mockBaseName(mock.name)
+	var named, largest []interfaceRef
+	for _, candidate := range candidates {
+		if candidate.decl.name == base {
+			named = append(named, candidate)
+		}
+		switch {
+		case len(largest) == 0 || len(candidate.decl.methods) > len(largest[0].decl.methods):
+			largest = []interfaceRef{candidate}
+		case len(candidate.decl.methods) == len(largest[0].decl.methods):
+			largest = append(largest, candidate)
+		}
+	}
+	if len(named) > 0 {
+		return named
+	}
+	return largest
+}
+
+// mockBaseName strips the affixes mock generators add to the interface name
+func mockBaseName(name string) string {
+	for _, affix := range []string{"Mock", "mock", "Fake", "fake"} {
+		if rest, ok := strings.CutPrefix(name, affix); ok && rest != "" {
+			return rest
+		}
+		if rest, ok := strings.CutSuffix(name, affix); ok && rest != "" {
+			return rest
+		}
+	}
+	return name
+}
+
+// countMocks returns the number of generated mock types declared in a package
+func (a *ModuleAnalyzer) countMocks(pkg string) int {
+	var n int
+	for _, decl := range a.concreteTypes[pkg] {
+		if decl.mock {
+			n++
+		}
+	}
+	return n
+}
+
+// mockOnlyDependents reports whether a package is depended on only by packages
+// consisting solely of generated mocks, i.e. no production code uses it
+func (a *ModuleAnalyzer) mockOnlyDependents(pkg string) bool {
+	dependents := a.reverseDepends[pkg]
+	for _, dependent := range dependents {
+		if !a.generated[dependent].isMocks() {
+			return false
+		}
+	}
+	return len(dependents) > 0
+}
+
+// implementsAll reports whether the sorted method set has every method of the sorted required set
+func implementsAll(methods, required []string) bool {
+	i := 0
+	for _, method := range required {
+		for i < len(methods) && methods[i] < method {
+			i++
+		}
+		if i == len(methods) || methods[i] != method {
+			return false
+		}
+	}
+	return true
+}
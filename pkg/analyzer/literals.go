@@ -0,0 +1,134 @@
+// Package analyzer provides functionality for analyzing Go modules and calculating design metrics.
+// This file implements the detection of string literals shared by unrelated packages.
+package analyzer
+
+import (
+	"go/ast"
+	"go/token"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/alkbt/aid-metrics/pkg/models"
+	"golang.org/x/tools/go/packages"
+)
+
+// Bounds of the length of the string literals taken for keys
+const (
+	minKeyLength = 4
+	maxKeyLength = 200
+)
+
+// isKeyLike reports whether a string literal looks like a key two packages may
+// agree on: an environment variable name, a feature flag or registry key, a URL
+// or a URL path. Such literals have no spaces or format verbs, contain a letter
+// and are structured by one of / . _ - : as in "DATABASE_URL" or "/v1/orders".
+func isKeyLike(s string) bool {
+	if len(s) < minKeyLength || len(s) > maxKeyLength || strings.Contains(s, "%") || !strings.ContainsAny(s, "/._-:") {
+		return false
+	}
+	letter := false
+	for _, r := range s {
+		if unicode.IsSpace(r) || !unicode.IsPrint(r) {
+			return false
+		}
+		letter = letter || unicode.IsLetter(r)
+	}
+	return letter
+}
+
+// stringKeys returns the distinct key-like string literals of the hand-written
+// files of a package, sorted. Import paths and struct tags are not literals
+// the code uses, so they are left out.
+func stringKeys(pkg *packages.Package) []string {
+	seen := make(map[string]bool)
+	for _, file := range pkg.Syntax {
+		if _, ok := generatedBy(file); ok {
+			continue
+		}
+		tags := make(map[*ast.BasicLit]bool)
+		ast.Inspect(file, func(n ast.Node) bool {
+			switch t := n.(type) {
+			case *ast.ImportSpec:
+				return false
+			case *ast.Field:
+				if t.Tag != nil {
+					tags[t.Tag] = true
+				}
+			case *ast.BasicLit:
+				if t.Kind != token.STRING || tags[t] {
+					return true
+				}
+				if s, err := strconv.Unquote(t.Value); err == nil && isKeyLike(s) {
+					seen[s] = true
+				}
+			}
+			return true
+		})
+	}
+	return sortedKeys(seen)
+}
+
+// stringCoupling lists the key-like string literals used by at least two module
+// packages without an import relationship between them, direct or transitive,
+// in either direction. Literals used by the most packages come first.
+func (a *ModuleAnalyzer) stringCoupling(metrics *models.ModuleMetrics) []models.StringCoupling {
+	users := make(map[string][]string) // Literal -> packages using it
+	for _, id := range sortedPackageIDs(metrics.Packages) {
+		for _, key := range a.stringKeys[id] {
+			users[key] = append(users[key], id)
+		}
+	}
+
+	reach := make(map[string]map[string]bool)
+	reaches := func(from, to string) bool {
+		if reach[from] == nil {
+			reach[from] = a.reachableFrom(from)
+		}
+		return reach[from][to]
+	}
+	unrelated := func(ids []string) bool {
+		for i, x := range ids {
+			for _, y := range ids[i+1:] {
+				if !reaches(x, y) && !reaches(y, x) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+
+	var result []models.StringCoupling
+	for key, ids := range users {
+		if len(ids) < 2 || !unrelated(ids) {
+			continue
+		}
+		result = append(result, models.StringCoupling{Value: key, Packages: a.displayNames(ids)})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if len(result[i].Packages) != len(result[j].Packages) {
+			return len(result[i].Packages) > len(result[j].Packages)
+		}
+		return result[i].Value < result[j].Value
+	})
+	return result
+}
+
+// reachableFrom returns the set of packages reachable from pkg through the
+// dependency graph, pkg excluded unless it lies on a cycle
+func (a *ModuleAnalyzer) reachableFrom(pkg string) map[string]bool {
+	visited := make(map[string]bool)
+	queue := []string{pkg}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, dep := range a.dependencies[current] {
+			if !visited[dep] {
+				visited[dep] = true
+				queue = append(queue, dep)
+			}
+		}
+	}
+	return visited
+}
@@ -3,6 +3,7 @@
 package analyzer
 
 import (
+	"context"
 	"fmt"
 	"strings"
 	
@@ -48,7 +49,7 @@ func NewBatchLoader(batchSize int, config *packages.Config, progressReporter mod
 }
 
 // LoadPackages loads all packages in batches, reporting progress as it goes.
-// The loading phase uses progress values 10-80 on the fixed 0-100 scale.
+// The loading stage counts one step per package.
 //
 // This method:
 //   1. Splits the package list into batches
@@ -56,15 +57,23 @@ func NewBatchLoader(batchSize int, config *packages.Config, progressReporter mod
 //   3. Reports progress after each batch
 //   4. Collects all loaded packages and returns them
 //
-// Returns an error if any batch fails to load.
+// Returns an error if any batch fails to load. Errors of single packages do not
+// fail the loading; they are left in the Errors of the packages.
 func (bl *BatchLoader) LoadPackages(packageInfos []PackageInfo) ([]*packages.Package, error) {
+	return bl.LoadPackagesContext(context.Background(), packageInfos)
+}
+
+// LoadPackagesContext is LoadPackages with a context: the go command of the
+// running batch is killed and no further batch is started once the context is done.
+func (bl *BatchLoader) LoadPackagesContext(ctx context.Context, packageInfos []PackageInfo) ([]*packages.Package, error) {
+	config := *bl.config
+	config.Context = ctx
+
 	var allPackages []*packages.Package
 	packagesLoaded := 0
-	
-	// Calculate progress range (10-80 on our 0-100 scale)
-	progressStart := 10
-	progressEnd := 80
-	progressRange := progressEnd - progressStart
+	if bl.progressReporter != nil {
+		bl.progressReporter.StageStart(models.StageLoading, bl.totalPackages)
+	}
 	
 	// Process packages in batches
 	for i := 0; i < len(packageInfos); i += bl.batchSize {
@@ -82,27 +91,26 @@ func (bl *BatchLoader) LoadPackages(packageInfos []PackageInfo) ([]*packages.Pac
 		
 		// Report progress with current package being loaded
 		if bl.progressReporter != nil && len(batchPaths) > 0 {
-			progress := progressStart + (packagesLoaded * progressRange / bl.totalPackages)
 			// Show only upper bound of loaded packages
 			upperBound := packagesLoaded + len(batchPaths)
 			description := fmt.Sprintf("Loading %d of %d packages", upperBound, bl.totalPackages)
-			bl.progressReporter.Update(progress, description)
+			bl.progressReporter.Update(packagesLoaded, description)
 		}
 		
 		// Load this batch
-		pkgs, err := packages.Load(bl.config, batchPaths...)
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		pkgs, err := packages.Load(&config, batchPaths...)
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, ctxErr
+		}
 		if err != nil {
 			return nil, fmt.Errorf("failed to load packages batch starting at %s: %w", batchPaths[0], err)
 		}
 		
-		// Check for errors in loaded packages
-		for _, pkg := range pkgs {
-			if len(pkg.Errors) > 0 {
-				// Log package errors but don't fail - some packages might have issues
-				// This matches the behavior of the original implementation
-				continue
-			}
-		}
+		// Packages with errors are kept: the analyzer reports their Errors as
+		// diagnostics and analyzes what could be loaded
 		
 		// Add to results
 		allPackages = append(allPackages, pkgs...)
@@ -110,13 +118,12 @@ func (bl *BatchLoader) LoadPackages(packageInfos []PackageInfo) ([]*packages.Pac
 		
 		// Update progress after batch completes
 		if bl.progressReporter != nil {
-			progress := progressStart + (packagesLoaded * progressRange / bl.totalPackages)
-			if progress > progressEnd {
-				progress = progressEnd
-			}
-			bl.progressReporter.Update(progress, fmt.Sprintf("Loaded %d of %d packages", packagesLoaded, bl.totalPackages))
+			bl.progressReporter.Update(min(packagesLoaded, bl.totalPackages), fmt.Sprintf("Loaded %d of %d packages", packagesLoaded, bl.totalPackages))
 		}
 	}
+	if bl.progressReporter != nil {
+		bl.progressReporter.StageDone(models.StageLoading)
+	}
 	
 	return allPackages, nil
 }
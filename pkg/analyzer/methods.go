@@ -0,0 +1,48 @@
+// Package analyzer provides functionality for analyzing Go modules and calculating design metrics.
+// This file implements counting of methods by receiver kind and visibility.
+package analyzer
+
+import (
+	"go/ast"
+)
+
+// methodCounts holds the method statistics collected for a single package.
+// It describes how "behavioral" a package is beyond the abstract/concrete type split.
+type methodCounts struct {
+	// pointer is the number of methods declared with a pointer receiver
+	pointer int
+
+	// value is the number of methods declared with a value receiver
+	value int
+
+	// exported is the number of methods with an exported name
+	exported int
+
+	// unexported is the number of methods with an unexported name
+	unexported int
+}
+
+// countMethod updates the counts with a function declaration.
+// Declarations without a receiver (standalone functions) are ignored.
+func (c *methodCounts) countMethod(fn *ast.FuncDecl) {
+	if fn.Recv == nil || len(fn.Recv.List) == 0 {
+		return
+	}
+
+	if _, ok := fn.Recv.List[0].Type.(*ast.StarExpr); ok {
+		c.pointer++
+	} else {
+		c.value++
+	}
+
+	if fn.Name.IsExported() {
+		c.exported++
+	} else {
+		c.unexported++
+	}
+}
+
+// total returns the total number of methods in the package
+func (c methodCounts) total() int {
+	return c.pointer + c.value
+}
@@ -0,0 +1,17 @@
+//go:build !unix
+
+// Package analyzer provides functionality for analyzing Go modules and calculating design metrics.
+// This file implements the file reading fallback for systems without mmap support.
+package analyzer
+
+import "os"
+
+// mapFile reads a file into memory. On systems without mmap support this is a plain
+// read, and the returned release function does nothing.
+func mapFile(path string) ([]byte, func() error, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	return data, func() error { return nil }, nil
+}
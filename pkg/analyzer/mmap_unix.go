@@ -0,0 +1,41 @@
+//go:build unix
+
+// Package analyzer provides functionality for analyzing Go modules and calculating design metrics.
+// This file implements memory-mapped file reading on Unix systems.
+package analyzer
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// mapFile maps a file read-only into memory and returns its contents together with
+// a function that releases the mapping. The contents must not be used after release.
+func mapFile(path string) ([]byte, func() error, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Empty files cannot be mapped
+	size := info.Size()
+	if size == 0 {
+		return nil, func() error { return nil }, nil
+	}
+	if int64(int(size)) != size {
+		return nil, nil, fmt.Errorf("file %s is too large to map", path)
+	}
+
+	data, err := syscall.Mmap(int(f.Fd()), 0, int(size), syscall.PROT_READ, syscall.MAP_SHARED)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to map %s: %w", path, err)
+	}
+	return data, func() error { return syscall.Munmap(data) }, nil
+}
@@ -0,0 +1,84 @@
+// Package analyzer provides functionality for analyzing Go modules and calculating design metrics.
+// This file implements the assignment of unique display names to packages.
+package analyzer
+
+import (
+	"strings"
+)
+
+// assignDisplayNames gives every analyzed package and dependency a unique display
+// name, stored in a.names. Names start out as getRelativePackagePath returns them;
+// module packages keep their relative path, which is unique, while the shortened
+// names of other packages and of the module root are extended by one leading path
+// element at a time for as long as they collide with another name.
+func (a *ModuleAnalyzer) assignDisplayNames() {
+	names := make(map[string]string)
+	for pkg, deps := range a.dependencies {
+		names[pkg] = a.getRelativePackagePath(pkg)
+		for _, dep := range deps {
+			names[dep] = a.getRelativePackagePath(dep)
+		}
+	}
+
+	for {
+		byName := make(map[string][]string, len(names))
+		for id, name := range names {
+			byName[name] = append(byName[name], id)
+		}
+
+		extended := false
+		for _, ids := range byName {
+			if len(ids) < 2 {
+				continue
+			}
+			for _, id := range ids {
+				if inModule(id, a.moduleName) && id != a.moduleName {
+					continue
+				}
+				if name, ok := extendDisplayName(id, names[id]); ok {
+					names[id] = name
+					extended = true
+				}
+			}
+		}
+		if !extended {
+			break
+		}
+	}
+	a.names = names
+}
+
+// extendDisplayName prepends the next element of the import path to a display name
+// that is a suffix of it; ok is false if the name is the full import path already
+func extendDisplayName(importPath, name string) (string, bool) {
+	importPath, _, _ = strings.Cut(importPath, " ")
+	elems := strings.Split(importPath, "/")
+	shown := strings.Count(name, "/") + 1
+	if shown >= len(elems) {
+		return name, false
+	}
+	return strings.Join(elems[len(elems)-shown-1:], "/"), true
+}
+
+// displayName returns the unique display name of a package
+func (a *ModuleAnalyzer) displayName(id string) string {
+	if name, ok := a.names[id]; ok {
+		return name
+	}
+	return a.getRelativePackagePath(id)
+}
+
+// nameTable maps the display names that are not module-relative paths, i.e. those
+// of other modules' packages and of the module root, to their import paths
+func (a *ModuleAnalyzer) nameTable() map[string]string {
+	table := make(map[string]string)
+	for id, name := range a.names {
+		if !inModule(id, a.moduleName) || id == a.moduleName {
+			table[name] = id
+		}
+	}
+	if len(table) == 0 {
+		return nil
+	}
+	return table
+}
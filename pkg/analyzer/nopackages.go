@@ -0,0 +1,61 @@
+// Package analyzer provides functionality for analyzing Go modules and calculating design metrics.
+// This file defines the error returned when the package pattern matches nothing.
+package analyzer
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// ErrNoPackagesFound is matched by errors.Is for a *NoPackagesError
+var ErrNoPackagesFound = errors.New("no packages found")
+
+// NoPackagesError is returned by the analysis when the package pattern matches
+// no package with Go files to analyze, rather than reporting an empty module
+type NoPackagesError struct {
+	Pattern string   // Package pattern, as resolved against the module
+	Roots   []string // Directories searched for packages
+
+	// Discovered is the number of directories with Go files matching the
+	// pattern. It is only non-zero when build constraints (GOOS, GOARCH, build
+	// tags) exclude every file of them.
+	Discovered int
+}
+
+// Error returns the pattern and the searched directories as a message
+func (e *NoPackagesError) Error() string {
+	if e.Discovered > 0 {
+		return fmt.Sprintf("no packages found for pattern %s in %s: build constraints exclude all Go files of the %d matching directories",
+			e.Pattern, strings.Join(e.Roots, ", "), e.Discovered)
+	}
+	return fmt.Sprintf("no packages found for pattern %s in %s", e.Pattern, strings.Join(e.Roots, ", "))
+}
+
+// Is makes errors.Is(err, ErrNoPackagesFound) match
+func (e *NoPackagesError) Is(target error) bool {
+	return target == ErrNoPackagesFound
+}
+
+// noPackagesError returns the error for a pattern matching no package with Go files
+// among the discovered ones
+func (a *ModuleAnalyzer) noPackagesError(pattern string, discovered int) *NoPackagesError {
+	return &NoPackagesError{
+		Pattern:    pattern,
+		Roots:      []string{searchRoot(a.modulePath, pattern)},
+		Discovered: discovered,
+	}
+}
+
+// hasGoFiles reports whether any of the loaded packages has Go files left
+// after applying the build constraints
+func hasGoFiles(pkgs []*packages.Package) bool {
+	for _, pkg := range pkgs {
+		if len(pkg.GoFiles) > 0 {
+			return true
+		}
+	}
+	return false
+}
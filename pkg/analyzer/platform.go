@@ -0,0 +1,95 @@
+// Package analyzer provides functionality for analyzing Go modules and calculating design metrics.
+// This file implements the selection of the build configuration (GOOS/GOARCH) to analyze.
+package analyzer
+
+import (
+	"fmt"
+	"go/build"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// Platform is a GOOS/GOARCH build configuration. Files excluded by build
+// constraints or file name suffixes (e.g. _windows.go) for the platform are not
+// analyzed, so dependencies and types may differ between platforms.
+type Platform struct {
+	GOOS   string
+	GOARCH string
+}
+
+// ParsePlatform parses a platform written as "goos/goarch", e.g. "linux/amd64"
+func ParsePlatform(s string) (Platform, error) {
+	goos, goarch, ok := strings.Cut(strings.TrimSpace(s), "/")
+	if !ok || goos == "" || goarch == "" || strings.Contains(goarch, "/") {
+		return Platform{}, fmt.Errorf("invalid platform %q (expected goos/goarch, e.g. linux/amd64)", s)
+	}
+	return Platform{GOOS: goos, GOARCH: goarch}, nil
+}
+
+// ParsePlatforms parses a comma-separated list of platforms
+func ParsePlatforms(s string) ([]Platform, error) {
+	var platforms []Platform
+	for _, field := range strings.Split(s, ",") {
+		if strings.TrimSpace(field) == "" {
+			continue
+		}
+		platform, err := ParsePlatform(field)
+		if err != nil {
+			return nil, err
+		}
+		platforms = append(platforms, platform)
+	}
+	return platforms, nil
+}
+
+// String returns the platform as "goos/goarch"
+func (p Platform) String() string {
+	return p.GOOS + "/" + p.GOARCH
+}
+
+// isHost reports whether the platform is the zero value or the platform the analyzer runs on
+func (p Platform) isHost() bool {
+	return p == Platform{} || (p.GOOS == runtime.GOOS && p.GOARCH == runtime.GOARCH)
+}
+
+// env returns the environment of the go command for the analyzed platform, or nil
+// to inherit the environment. Cgo is disabled when cross-analyzing, as a C
+// toolchain for the target is rarely available. In offline mode the go command
+// may use neither the module proxy, nor the checksum database, nor download a
+// toolchain, and modules are read from vendor/ if the module vendors them.
+func (a *ModuleAnalyzer) env() []string {
+	var env []string
+	if p := a.options.Platform; p != (Platform{}) {
+		env = append(env, "GOOS="+p.GOOS, "GOARCH="+p.GOARCH)
+		if !p.isHost() {
+			env = append(env, "CGO_ENABLED=0")
+		}
+	}
+	if a.options.Offline {
+		mod := "-mod=mod"
+		if _, err := os.Stat(filepath.Join(a.modulePath, "vendor", "modules.txt")); err == nil {
+			mod = "-mod=vendor"
+		}
+		env = append(env, "GOPROXY=off", "GOSUMDB=off", "GOTOOLCHAIN=local", "GOFLAGS="+mod)
+	}
+	if env == nil {
+		return nil
+	}
+	return append(os.Environ(), env...)
+}
+
+// buildContext returns the build context of the analyzed platform and build tags
+func (a *ModuleAnalyzer) buildContext() build.Context {
+	ctx := build.Default
+	ctx.BuildTags = append([]string(nil), a.options.BuildTags...)
+	if p := a.options.Platform; p != (Platform{}) {
+		ctx.GOOS = p.GOOS
+		ctx.GOARCH = p.GOARCH
+		if !p.isHost() {
+			ctx.CgoEnabled = false
+		}
+	}
+	return ctx
+}
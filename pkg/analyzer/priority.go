@@ -0,0 +1,64 @@
+// Package analyzer provides functionality for analyzing Go modules and calculating design metrics.
+// This file implements prioritization of findings for the "what to fix first" summary.
+package analyzer
+
+import (
+	"sort"
+
+	"github.com/alkbt/aid-metrics/pkg/models"
+)
+
+// PrioritizedFinding is a finding together with its priority score
+type PrioritizedFinding struct {
+	models.Finding
+
+	// BlastRadius is the number of packages affected by the finding's package:
+	// the package itself plus its dependents.
+	BlastRadius int
+
+	// Score is the severity level multiplied by the blast radius
+	Score int
+}
+
+// PrioritizeFindings ranks findings by severity × blast radius and returns the top n.
+// A problem in a package many others depend on spreads further than the same problem
+// in a leaf package, so it is worth fixing first. A non-positive n returns all findings.
+func PrioritizeFindings(metrics *models.ModuleMetrics, findings []models.Finding, n int) []PrioritizedFinding {
+	dependents := make(map[string]int, len(metrics.Packages))
+	for _, pkg := range metrics.Packages {
+		dependents[pkg.Name] = pkg.Ca
+	}
+
+	prioritized := make([]PrioritizedFinding, 0, len(findings))
+	for _, finding := range findings {
+		blastRadius := dependents[finding.Package] + 1
+		prioritized = append(prioritized, PrioritizedFinding{
+			Finding:     finding,
+			BlastRadius: blastRadius,
+			Score:       finding.Severity.Level() * blastRadius,
+		})
+	}
+
+	// Findings arrive sorted by severity, category and package; a stable sort keeps
+	// that order between findings with equal scores.
+	sort.SliceStable(prioritized, func(i, j int) bool {
+		return prioritized[i].Score > prioritized[j].Score
+	})
+
+	if n > 0 && len(prioritized) > n {
+		prioritized = prioritized[:n]
+	}
+	return prioritized
+}
+
+// FindingsAtLeast returns the findings with at least the given severity that can
+// fail a gate, leaving advisory ones out
+func FindingsAtLeast(findings []models.Finding, severity models.Severity) []models.Finding {
+	var result []models.Finding
+	for _, finding := range findings {
+		if !finding.Advisory && finding.Severity.Level() >= severity.Level() {
+			result = append(result, finding)
+		}
+	}
+	return result
+}
@@ -0,0 +1,175 @@
+// Package analyzer provides functionality for analyzing Go modules and calculating design metrics.
+// This file implements classification of packages into architectural roles.
+package analyzer
+
+import (
+	"path"
+	"strings"
+
+	"github.com/alkbt/aid-metrics/pkg/models"
+	"golang.org/x/tools/go/packages"
+)
+
+// RoleRule assigns a role to every package whose module-relative path matches Pattern.
+// Patterns ending in "/..." match the package and all its subpackages; other patterns
+// are matched with path.Match, so "internal/*/store" is allowed.
+type RoleRule struct {
+	Pattern string
+	Role    string
+}
+
+// DefaultRoleThresholds holds the default thresholds for each role.
+// Handlers and main packages are expected to be unstable and concrete, models are
+// expected to be stable and concrete (the zone of pain is acceptable for plain data),
+// while domain packages should stay close to the main sequence.
+var DefaultRoleThresholds = map[string]models.Thresholds{
+	models.RoleMain:       {MaxDistance: 1.0, MaxInstability: 1.0, MinAbstractness: 0},
+	models.RoleHandler:    {MaxDistance: 0.7, MaxInstability: 1.0, MinAbstractness: 0},
+	models.RoleRepository: {MaxDistance: 0.6, MaxInstability: 1.0, MinAbstractness: 0},
+	models.RoleDomain:     {MaxDistance: 0.4, MaxInstability: 0.7, MinAbstractness: 0},
+	models.RoleModel:      {MaxDistance: 0.9, MaxInstability: 0.5, MinAbstractness: 0},
+	models.RoleOther:      {MaxDistance: 0.5, MaxInstability: 1.0, MinAbstractness: 0},
+}
+
+// rolePathSegments maps path segments to the role they indicate.
+var rolePathSegments = map[string]string{
+	"cmd":          models.RoleMain,
+	"handler":      models.RoleHandler,
+	"handlers":     models.RoleHandler,
+	"controller":   models.RoleHandler,
+	"controllers":  models.RoleHandler,
+	"transport":    models.RoleHandler,
+	"api":          models.RoleHandler,
+	"rest":         models.RoleHandler,
+	"repository":   models.RoleRepository,
+	"repositories": models.RoleRepository,
+	"repo":         models.RoleRepository,
+	"store":        models.RoleRepository,
+	"storage":      models.RoleRepository,
+	"persistence":  models.RoleRepository,
+	"dao":          models.RoleRepository,
+	"domain":       models.RoleDomain,
+	"usecase":      models.RoleDomain,
+	"usecases":     models.RoleDomain,
+	"service":      models.RoleDomain,
+	"services":     models.RoleDomain,
+	"model":        models.RoleModel,
+	"models":       models.RoleModel,
+	"entity":       models.RoleModel,
+	"entities":     models.RoleModel,
+	"dto":          models.RoleModel,
+}
+
+// roleImportFingerprints maps import paths to the role they indicate when no path segment matched.
+// Entries ending in "/" match any package below that path.
+var roleImportFingerprints = []struct {
+	importPath string
+	role       string
+}{
+	{"net/http", models.RoleHandler},
+	{"google.golang.org/grpc", models.RoleHandler},
+	{"github.com/gin-gonic/gin", models.RoleHandler},
+	{"github.com/labstack/echo/", models.RoleHandler},
+	{"database/sql", models.RoleRepository},
+	{"gorm.io/gorm", models.RoleRepository},
+	{"github.com/jmoiron/sqlx", models.RoleRepository},
+	{"github.com/jackc/pgx/", models.RoleRepository},
+	{"go.mongodb.org/mongo-driver/", models.RoleRepository},
+}
+
+// classifyRole determines the role of a package.
+// Configured rules take precedence, followed by main packages and composition roots, path segments
+// (deepest segment wins) and finally import fingerprints.
+func classifyRole(pkg *packages.Package, relPath string, rules []RoleRule) string {
+	for _, rule := range rules {
+		if matchesRolePattern(relPath, rule.Pattern) {
+			return rule.Role
+		}
+	}
+
+	if pkg.Name == "main" || detectDIFramework(pkg) != "" {
+		return models.RoleMain
+	}
+
+	segments := strings.Split(relPath, "/")
+	for i := len(segments) - 1; i >= 0; i-- {
+		if role, ok := rolePathSegments[segments[i]]; ok {
+			return role
+		}
+	}
+
+	for _, fingerprint := range roleImportFingerprints {
+		for importPath := range pkg.Imports {
+			if importPath == strings.TrimSuffix(fingerprint.importPath, "/") ||
+				(strings.HasSuffix(fingerprint.importPath, "/") && strings.HasPrefix(importPath, fingerprint.importPath)) {
+				return fingerprint.role
+			}
+		}
+	}
+
+	return models.RoleOther
+}
+
+// entryPointExemptReason returns why a main package or composition root is exempt
+// from abstractness/distance gating, or an empty string if the package is neither
+// or entry points are gated (see AnalyzerOptions.GateEntryPoints).
+func (a *ModuleAnalyzer) entryPointExemptReason(role string, compositionRoot bool) string {
+	switch {
+	case a.options.GateEntryPoints:
+		return ""
+	case compositionRoot:
+		return "composition root"
+	case role == models.RoleMain:
+		return "program entry point"
+	}
+	return ""
+}
+
+// matchesRolePattern checks if a module-relative package path matches a role rule pattern
+func matchesRolePattern(relPath, pattern string) bool {
+	if prefix, ok := strings.CutSuffix(pattern, "/..."); ok {
+		return relPath == prefix || strings.HasPrefix(relPath, prefix+"/")
+	}
+	matched, err := path.Match(pattern, relPath)
+	return err == nil && matched
+}
+
+// SummarizeRoles aggregates package metrics per role
+func SummarizeRoles(pkgs map[string]models.PackageMetrics) map[string]models.RoleMetrics {
+	roles := make(map[string]models.RoleMetrics)
+
+	// Iterate packages in a stable order so float sums are deterministic
+	for _, id := range sortedPackageIDs(pkgs) {
+		pkg := pkgs[id]
+		summary := roles[pkg.Role]
+		summary.Role = pkg.Role
+		summary.Packages++
+		summary.MeanInstability += pkg.Instability
+		summary.MeanAbstractness += pkg.Abstractness
+		summary.MeanDistance += pkg.Distance
+		if pkg.Distance > summary.MaxDistance {
+			summary.MaxDistance = pkg.Distance
+		}
+		roles[pkg.Role] = summary
+	}
+
+	for role, summary := range roles {
+		n := float64(summary.Packages)
+		summary.MeanInstability /= n
+		summary.MeanAbstractness /= n
+		summary.MeanDistance /= n
+		summary.Thresholds = RoleThresholds(role)
+		roles[role] = summary
+	}
+
+	return roles
+}
+
+// RoleThresholds returns the default thresholds for a role.
+// Unknown roles (for example ones introduced through RoleRule) get the defaults of RoleOther.
+func RoleThresholds(role string) models.Thresholds {
+	if thresholds, ok := DefaultRoleThresholds[role]; ok {
+		return thresholds
+	}
+	return DefaultRoleThresholds[models.RoleOther]
+}
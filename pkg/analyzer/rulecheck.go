@@ -0,0 +1,185 @@
+// Package analyzer provides functionality for analyzing Go modules and calculating design metrics.
+// This file implements the linter of the dependency rules behind `aid-metrics rules check`.
+package analyzer
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+)
+
+// RuleCheck is the result of linting a dependency rule against the module
+type RuleCheck struct {
+	Rule        DependencyRule
+	Description string   // Name of the rule, or a description of its patterns
+	Packages    []string // Display names of the packages the rule applies to
+	Violators   []string // Display names of the packages violating the rule
+	Problems    []string // Mistakes in the rule, empty if it looks sound
+}
+
+// CheckRules lints the dependency rules of the options against the module:
+// it reports invalid patterns, patterns and rules that match no package, rules
+// another rule makes redundant and allowed imports another rule denies, and lists
+// the packages each rule applies to and currently violate it. Only the imports of
+// the packages are parsed.
+func CheckRules(ctx context.Context, modulePath string, options AnalyzerOptions) ([]RuleCheck, error) {
+	options.ImportsOnly = true
+	options.FailFast = false
+	a := NewModuleAnalyzerWithOptions(modulePath, "./...", options)
+	metrics, err := a.AnalyzeContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ids := sortedPackageIDs(metrics.Packages)
+
+	checks := make([]RuleCheck, len(options.Rules))
+	applies := make([][]string, len(options.Rules))
+	for i, rule := range options.Rules {
+		check := RuleCheck{Rule: rule, Description: rule.describe()}
+		if err := rule.Validate(); err != nil {
+			check.Problems = append(check.Problems, strings.TrimPrefix(err.Error(), "rule "+check.Description+": "))
+		}
+
+		for _, id := range ids {
+			if !a.appliesTo(rule, id) {
+				continue
+			}
+			applies[i] = append(applies[i], id)
+			check.Packages = append(check.Packages, metrics.Packages[id].Name)
+			if len(a.ruleViolations(rule, id)) > 0 {
+				check.Violators = append(check.Violators, metrics.Packages[id].Name)
+			}
+		}
+		if len(check.Packages) == 0 {
+			check.Problems = append(check.Problems, "applies to no package")
+		}
+
+		for _, field := range []struct {
+			name     string
+			patterns []string
+		}{{"from", rule.From}, {"except", rule.Except}, {"deny", rule.Deny}, {"allow", rule.Allow}} {
+			for _, pattern := range field.patterns {
+				if problem := patternProblem(pattern); problem != "" {
+					check.Problems = append(check.Problems, problem)
+				} else if isModulePattern(pattern) && !a.matchesModulePackage(ids, pattern) {
+					check.Problems = append(check.Problems, fmt.Sprintf("%s pattern %q matches no package of the module", field.name, pattern))
+				}
+			}
+		}
+		for _, pattern := range rule.Deny {
+			if slices.Contains(rule.Allow, pattern) {
+				check.Problems = append(check.Problems, fmt.Sprintf("both allows and denies %q", pattern))
+			}
+		}
+		checks[i] = check
+	}
+
+	// Rules are checked independently, so an earlier rule can make a later one
+	// redundant, and a deny of one rule wins over an allow of another
+	for i := range options.Rules {
+		for j := range options.Rules {
+			if i == j {
+				continue
+			}
+			if j < i && len(applies[i]) > 0 && redundant(options.Rules[i], options.Rules[j], applies[i], applies[j]) {
+				checks[i].Problems = append(checks[i].Problems,
+					fmt.Sprintf("is redundant: rule %q already denies the same imports for all its packages", checks[j].Description))
+			}
+			if denied := a.deniedAllows(ids, options.Rules[i], options.Rules[j], applies[i], applies[j]); len(denied) > 0 {
+				checks[i].Problems = append(checks[i].Problems,
+					fmt.Sprintf("conflicts with rule %q, which denies the allowed %s", checks[j].Description, strings.Join(denied, ", ")))
+			}
+		}
+	}
+	return checks, nil
+}
+
+// patternProblem returns the syntax error of a rule pattern, empty if there is none
+func patternProblem(pattern string) string {
+	prefix := strings.TrimSuffix(pattern, "/...")
+	switch {
+	case pattern == "":
+		return "has an empty pattern"
+	case strings.HasPrefix(pattern, "./") || strings.HasPrefix(pattern, "/") || strings.HasSuffix(pattern, "/"):
+		return fmt.Sprintf("pattern %q must be a path without leading ./ or / and trailing /", pattern)
+	case strings.Contains(prefix, "..."):
+		return fmt.Sprintf("pattern %q may only end with /...", pattern)
+	}
+	if _, err := path.Match(prefix, ""); err != nil {
+		return fmt.Sprintf("pattern %q is invalid: %v", pattern, err)
+	}
+	return ""
+}
+
+// isModulePattern reports whether a pattern is a module-relative path rather
+// than ThirdParty or the import path of another module, whose first element
+// contains a dot
+func isModulePattern(pattern string) bool {
+	first, _, _ := strings.Cut(pattern, "/")
+	return pattern != ThirdParty && pattern != "" && !strings.Contains(first, ".")
+}
+
+// matchesModulePackage reports whether a pattern matches any of the packages
+func (a *ModuleAnalyzer) matchesModulePackage(ids []string, pattern string) bool {
+	for _, id := range ids {
+		if a.matchesAny(a.getRelativePackagePath(id), id, []string{pattern}) {
+			return true
+		}
+	}
+	return false
+}
+
+// redundant reports whether an earlier rule already reports every import a
+// deny-only rule denies, for every package the rule applies to. Patterns are
+// compared as written.
+func redundant(rule, earlier DependencyRule, packages, earlierPackages []string) bool {
+	if len(rule.Allow) > 0 || len(rule.Deny) == 0 || len(earlier.Allow) > 0 {
+		return false
+	}
+	for _, pattern := range rule.Deny {
+		if !slices.Contains(earlier.Deny, pattern) {
+			return false
+		}
+	}
+	for _, id := range packages {
+		if !slices.Contains(earlierPackages, id) {
+			return false
+		}
+	}
+	return true
+}
+
+// deniedAllows returns the allow patterns of a rule that another rule denies
+// for a package both apply to: a deny pattern written the same way, or one
+// matching a module package (of ids) the allow pattern matches
+func (a *ModuleAnalyzer) deniedAllows(ids []string, rule, other DependencyRule, packages, otherPackages []string) []string {
+	shared := false
+	for _, id := range packages {
+		if slices.Contains(otherPackages, id) {
+			shared = true
+			break
+		}
+	}
+	if !shared {
+		return nil
+	}
+	var denied []string
+	for _, allow := range rule.Allow {
+		for _, id := range ids {
+			rel := a.getRelativePackagePath(id)
+			if allow == ThirdParty || !a.matchesAny(rel, id, []string{allow}) {
+				continue
+			}
+			if a.matchesAny(rel, id, other.Deny) {
+				denied = append(denied, allow)
+				break
+			}
+		}
+		if slices.Contains(other.Deny, allow) && !slices.Contains(denied, allow) {
+			denied = append(denied, allow)
+		}
+	}
+	return denied
+}
@@ -0,0 +1,181 @@
+// Package analyzer provides functionality for analyzing Go modules and calculating design metrics.
+// This file implements the architecture rules constraining the imports between packages.
+package analyzer
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/alkbt/aid-metrics/pkg/models"
+)
+
+// Enforcement levels of a DependencyRule
+const (
+	RuleOff     = "off"     // The rule is not checked
+	RuleWarn    = "warn"    // Violations are advisory findings, failing no gate
+	RuleEnforce = "enforce" // Violations fail the run
+)
+
+// ThirdParty is the pattern of DependencyRule.Deny and Allow matching every
+// import outside the module and the standard library
+const ThirdParty = "third-party"
+
+// DependencyRule constrains the imports of the packages matching From, e.g.
+// "pkg/ui/... may not import pkg/db/..." or "only pkg/adapters/... may import
+// third-party packages". Patterns are module-relative paths as in RoleRule; in
+// Deny and Allow they may also be import path patterns of other modules
+// ("github.com/aws/...") or ThirdParty.
+type DependencyRule struct {
+	// Name describes the rule in findings; by default the rule is described
+	// by its patterns
+	Name string
+
+	// From selects the packages the rule applies to, all packages when empty
+	From []string
+
+	// Except exempts packages matching From from the rule
+	Except []string
+
+	// Deny lists the imports the packages may not have
+	Deny []string
+
+	// Allow, if not empty, lists the only imports the packages may have besides
+	// the standard library
+	Allow []string
+
+	// Enforcement is RuleOff, RuleWarn or RuleEnforce, the default when empty.
+	// Set RuleWarn when introducing a rule, so it blocks no one until the
+	// existing violations are fixed.
+	Enforcement string
+
+	// EnforceFrom, if set, promotes a RuleWarn rule to RuleEnforce from that day on
+	EnforceFrom time.Time
+}
+
+// Validate checks that the rule restricts something and has a valid enforcement
+func (r DependencyRule) Validate() error {
+	if len(r.Deny) == 0 && len(r.Allow) == 0 {
+		return fmt.Errorf("rule %s: needs deny or allow patterns", r.describe())
+	}
+	switch r.Enforcement {
+	case "", RuleOff, RuleWarn, RuleEnforce:
+	default:
+		return fmt.Errorf("rule %s: unknown enforcement %q (want off, warn or enforce)", r.describe(), r.Enforcement)
+	}
+	if !r.EnforceFrom.IsZero() && r.Enforcement != RuleWarn {
+		return fmt.Errorf("rule %s: an enforcement date needs enforcement warn", r.describe())
+	}
+	return nil
+}
+
+// Level returns the enforcement of the rule at a time, RuleEnforce by default
+// and for a RuleWarn rule past its EnforceFrom day
+func (r DependencyRule) Level(now time.Time) string {
+	switch {
+	case r.Enforcement == "":
+		return RuleEnforce
+	case r.Enforcement == RuleWarn && !r.EnforceFrom.IsZero() && !now.Before(r.EnforceFrom):
+		return RuleEnforce
+	}
+	return r.Enforcement
+}
+
+// describe returns the name of the rule, or a description of its patterns
+func (r DependencyRule) describe() string {
+	if r.Name != "" {
+		return r.Name
+	}
+	from := "all packages"
+	if len(r.From) > 0 {
+		from = strings.Join(r.From, ", ")
+	}
+	if len(r.Except) > 0 {
+		from += " except " + strings.Join(r.Except, ", ")
+	}
+	if len(r.Deny) > 0 {
+		return fmt.Sprintf("%s may not import %s", from, strings.Join(r.Deny, ", "))
+	}
+	return fmt.Sprintf("%s may only import %s", from, strings.Join(r.Allow, ", "))
+}
+
+// ruleFindings checks the imports of every package against the dependency rules
+func (a *ModuleAnalyzer) ruleFindings(metrics *models.ModuleMetrics) []models.Finding {
+	var findings []models.Finding
+	now := time.Now()
+	for _, id := range sortedPackageIDs(metrics.Packages) {
+		pkg := metrics.Packages[id]
+		for _, rule := range a.options.Rules {
+			level := rule.Level(now)
+			if level == RuleOff || !a.appliesTo(rule, id) {
+				continue
+			}
+			violations := a.ruleViolations(rule, id)
+			if len(violations) == 0 {
+				continue
+			}
+			finding := a.newFinding(models.CategoryRule, pkg.Name,
+				fmt.Sprintf("violates rule %q by importing %s", rule.describe(), strings.Join(a.displayNames(violations), ", ")),
+				"Remove the imports, or route them through a package the rule allows, e.g. an interface owned by this package.")
+			if level == RuleWarn {
+				finding.Advisory = true
+				finding.Points = 0
+				if !rule.EnforceFrom.IsZero() {
+					finding.Message += fmt.Sprintf(" (warning until %s)", rule.EnforceFrom.Format(time.DateOnly))
+				} else {
+					finding.Message += " (warning only)"
+				}
+			}
+			findings = append(findings, finding)
+		}
+	}
+	return findings
+}
+
+// appliesTo reports whether a rule constrains the imports of a package
+func (a *ModuleAnalyzer) appliesTo(rule DependencyRule, id string) bool {
+	rel := a.getRelativePackagePath(id)
+	if len(rule.From) > 0 && !a.matchesAny(rel, id, rule.From) {
+		return false
+	}
+	return !a.matchesAny(rel, id, rule.Except)
+}
+
+// ruleViolations returns the imports of a package the rule denies
+func (a *ModuleAnalyzer) ruleViolations(rule DependencyRule, id string) []string {
+	var violations []string
+	for _, dep := range a.dependencies[id] {
+		depRel := a.getRelativePackagePath(dep)
+		denied := a.matchesAny(depRel, dep, rule.Deny)
+		if len(rule.Allow) > 0 && !a.matchesAny(depRel, dep, rule.Allow) {
+			denied = true
+		}
+		if denied {
+			violations = append(violations, dep)
+		}
+	}
+	return violations
+}
+
+// matchesAny reports whether a package, given by module-relative and import
+// path, matches any of the rule patterns. ThirdParty matches packages outside
+// the module.
+func (a *ModuleAnalyzer) matchesAny(rel, importPath string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if pattern == ThirdParty {
+			if !a.isModulePackage(importPath) {
+				return true
+			}
+			continue
+		}
+		if matchesRolePattern(rel, pattern) || matchesRolePattern(importPath, pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+// isModulePackage reports whether an import path belongs to the analyzed module
+func (a *ModuleAnalyzer) isModulePackage(importPath string) bool {
+	return importPath == a.moduleName || strings.HasPrefix(importPath, a.moduleName+"/")
+}
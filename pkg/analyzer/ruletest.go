@@ -0,0 +1,135 @@
+// Package analyzer provides functionality for analyzing Go modules and calculating design metrics.
+// This file implements the test harness of the dependency rules behind `aid-metrics rules test`.
+package analyzer
+
+import (
+	"slices"
+	"sort"
+	"strings"
+)
+
+// fixtureModule is the module path of the packages of rule tests
+const fixtureModule = "fixture.test/module"
+
+// RuleTest is an example package layout the dependency rules are run against,
+// with the violations they must report. Imports whose first path element
+// contains a dot are import paths of other modules; the others are module
+// packages, which need not be listed themselves.
+type RuleTest struct {
+	// Name describes the example
+	Name string
+
+	// Packages maps module-relative package paths to their imports
+	Packages map[string][]string
+
+	// Violations lists the expected violations as "package -> import", none
+	// if the layout must pass the rules
+	Violations []string
+}
+
+// RuleTestResult is the outcome of a rule test
+type RuleTestResult struct {
+	Test       RuleTest
+	Unexpected []string // Violations reported but not expected
+	Missing    []string // Violations expected but not reported
+}
+
+// Passed reports whether the rules reported exactly the expected violations
+func (r RuleTestResult) Passed() bool {
+	return len(r.Unexpected) == 0 && len(r.Missing) == 0
+}
+
+// RunRuleTests runs the dependency rules against the layout of every test.
+// Violations of several rules by the same import count once. Rules in their
+// warn period report violations too; rules turned off report none.
+func RunRuleTests(rules []DependencyRule, tests []RuleTest) []RuleTestResult {
+	results := make([]RuleTestResult, len(tests))
+	for i, test := range tests {
+		var reported []string
+		for _, violation := range fixtureViolations(rules, test.Packages) {
+			if !slices.Contains(reported, violation) {
+				reported = append(reported, violation)
+			}
+		}
+		expected := make([]string, len(test.Violations))
+		for j, violation := range test.Violations {
+			expected[j] = normalizeViolation(violation)
+		}
+
+		result := RuleTestResult{Test: test}
+		for _, violation := range reported {
+			if !slices.Contains(expected, violation) {
+				result.Unexpected = append(result.Unexpected, violation)
+			}
+		}
+		for _, violation := range expected {
+			if !slices.Contains(reported, violation) {
+				result.Missing = append(result.Missing, violation)
+			}
+		}
+		results[i] = result
+	}
+	return results
+}
+
+// fixtureViolations returns the violations of the rules by a package layout as
+// "package -> import", sorted
+func fixtureViolations(rules []DependencyRule, layout map[string][]string) []string {
+	a := &ModuleAnalyzer{
+		moduleName:   fixtureModule,
+		dependencies: make(map[string][]string),
+		options:      AnalyzerOptions{Rules: rules},
+	}
+	importPath := func(pkg string) string {
+		first, _, _ := strings.Cut(pkg, "/")
+		if strings.Contains(first, ".") {
+			return pkg
+		}
+		return fixtureModule + "/" + strings.Trim(pkg, "/")
+	}
+	for pkg, imports := range layout {
+		id := importPath(pkg)
+		if !a.isModulePackage(id) {
+			continue
+		}
+		if _, listed := a.dependencies[id]; !listed {
+			a.dependencies[id] = nil
+		}
+		for _, imp := range imports {
+			dep := importPath(imp)
+			a.dependencies[id] = append(a.dependencies[id], dep)
+			if _, listed := a.dependencies[dep]; !listed && a.isModulePackage(dep) {
+				a.dependencies[dep] = nil
+			}
+		}
+	}
+
+	var violations []string
+	for id := range a.dependencies {
+		for _, rule := range rules {
+			if rule.Enforcement == RuleOff || !a.appliesTo(rule, id) {
+				continue
+			}
+			for _, dep := range a.ruleViolations(rule, id) {
+				violations = append(violations, fixtureName(id)+" -> "+fixtureName(dep))
+			}
+		}
+	}
+	sort.Strings(violations)
+	return violations
+}
+
+// fixtureName returns the module-relative path of a package of a rule test, or
+// the import path of a package of another module
+func fixtureName(importPath string) string {
+	return strings.TrimPrefix(importPath, fixtureModule+"/")
+}
+
+// normalizeViolation formats an expected violation like the reported ones
+func normalizeViolation(violation string) string {
+	from, to, ok := strings.Cut(violation, "->")
+	if !ok {
+		return strings.TrimSpace(violation)
+	}
+	return strings.Trim(strings.TrimSpace(from), "/") + " -> " + strings.Trim(strings.TrimSpace(to), "/")
+}
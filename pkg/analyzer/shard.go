@@ -0,0 +1,90 @@
+// Package analyzer provides functionality for analyzing Go modules and calculating design metrics.
+// This file implements the analysis of package shards, which lets several processes
+// share the analysis of one module and merge their results centrally.
+package analyzer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/alkbt/aid-metrics/pkg/models"
+	"golang.org/x/tools/go/packages"
+)
+
+// PackageResult is the analysis result of a single package in a form that can be
+// sent to another process. Data holds the same encoding used by ResultCache.
+type PackageResult struct {
+	ID   string
+	Data []byte
+}
+
+// DiscoverPackages returns the import paths of the packages matching the analyzer's pattern
+func (a *ModuleAnalyzer) DiscoverPackages() ([]string, error) {
+	pattern := "./..."
+	if a.packageFilter != "" {
+		pattern = a.packageFilter
+	}
+
+	packageInfos, err := discoverPackages(context.Background(), a.modulePath, a.moduleName, pattern, a.options.IncludeTests, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to discover packages: %w", err)
+	}
+	packageInfos = a.withoutExcluded(packageInfos)
+
+	importPaths := make([]string, 0, len(packageInfos))
+	for _, info := range packageInfos {
+		importPaths = append(importPaths, info.ImportPath)
+	}
+	return importPaths, nil
+}
+
+// AnalyzeShard loads and analyzes the given packages and returns their results.
+// Only the per-package analysis is done; metrics need the results of all packages
+// and are calculated after MergeResults.
+func (a *ModuleAnalyzer) AnalyzeShard(importPaths []string) ([]PackageResult, error) {
+	if len(importPaths) == 0 {
+		return nil, nil
+	}
+
+	pkgs, err := packages.Load(a.packagesConfig(), importPaths...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load packages: %w", err)
+	}
+	if a.options.IncludeTests {
+		pkgs = testVariants(pkgs)
+	}
+
+	results := make([]PackageResult, 0, len(pkgs))
+	for _, pkg := range pkgs {
+		result := a.analyzePackageCached(pkg)
+		if result.err != nil {
+			return nil, result.err
+		}
+		data, err := json.Marshal(newCachedResult(result))
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode result of %s: %w", result.packageID, err)
+		}
+		results = append(results, PackageResult{ID: result.packageID, Data: data})
+	}
+	return results, nil
+}
+
+// MergeResults stores results produced by AnalyzeShard, possibly in another process.
+// Merging the same results in the same order always yields the same metrics.
+func (a *ModuleAnalyzer) MergeResults(results []PackageResult) error {
+	for _, r := range results {
+		var cached cachedResult
+		if err := json.Unmarshal(r.Data, &cached); err != nil {
+			return fmt.Errorf("failed to decode result of %s: %w", r.ID, err)
+		}
+		result := cached.result(r.ID)
+		a.storeResult(&result)
+	}
+	return nil
+}
+
+// Metrics calculates the metrics of all packages merged with MergeResults
+func (a *ModuleAnalyzer) Metrics() *models.ModuleMetrics {
+	return a.calculateMetrics()
+}
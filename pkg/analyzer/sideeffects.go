@@ -0,0 +1,176 @@
+// Package analyzer provides functionality for analyzing Go modules and calculating design metrics.
+// This file implements the detection of side-effect imports and init-time registrations.
+package analyzer
+
+import (
+	"go/ast"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/alkbt/aid-metrics/pkg/models"
+	"golang.org/x/tools/go/packages"
+)
+
+// registrationPrefixes are the name prefixes of the calls through which init
+// functions typically register global state: sql.Register, gob.Register,
+// image.RegisterFormat, prometheus.MustRegister, http.HandleFunc and the like
+var registrationPrefixes = []string{"Register", "MustRegister", "Handle"}
+
+// blankImport is an import of a package for its side effects only
+type blankImport struct {
+	pkg           string   // Import path of the imported package
+	registrations []string // Registrations of its init functions (see initRegistrations)
+}
+
+// blankImports returns the imports of a package for their side effects only,
+// sorted by import path, with the registrations made by the imported packages.
+// The blank import of embed, which only enables //go:embed, is left out.
+func (a *ModuleAnalyzer) blankImports(pkg *packages.Package) []blankImport {
+	seen := make(map[string]bool)
+	var imports []blankImport
+	for _, file := range pkg.Syntax {
+		for _, spec := range file.Imports {
+			if spec.Name == nil || spec.Name.Name != "_" {
+				continue
+			}
+			path, err := strconv.Unquote(spec.Path.Value)
+			if err != nil || path == "embed" || seen[path] {
+				continue
+			}
+			seen[path] = true
+			imp := blankImport{pkg: path}
+			if imported, ok := pkg.Imports[path]; ok {
+				imp.registrations = a.registrations.get(imported, initRegistrations)
+			}
+			imports = append(imports, imp)
+		}
+	}
+	sort.Slice(imports, func(i, j int) bool { return imports[i].pkg < imports[j].pkg })
+	return imports
+}
+
+// blankImportPaths returns the import paths of blank imports
+func blankImportPaths(imports []blankImport) []string {
+	var paths []string
+	for _, imp := range imports {
+		paths = append(paths, imp.pkg)
+	}
+	return paths
+}
+
+// initRegistrations returns the registrations of the init functions of a
+// package: qualified calls into other packages whose name starts with one of
+// registrationPrefixes, as "import/path.Function", sorted. Calls of methods of
+// package-level variables (prometheus.DefaultRegisterer.MustRegister) count as
+// calls into the variable's package.
+func initRegistrations(pkg *packages.Package) []string {
+	seen := make(map[string]bool)
+	var registrations []string
+	for _, file := range pkg.Syntax {
+		imports := fileImports(file, pkg)
+		for _, decl := range file.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok || fn.Recv != nil || fn.Name.Name != "init" || fn.Body == nil {
+				continue
+			}
+			ast.Inspect(fn.Body, func(n ast.Node) bool {
+				call, ok := n.(*ast.CallExpr)
+				if !ok {
+					return true
+				}
+				sel, ok := call.Fun.(*ast.SelectorExpr)
+				if !ok || !isRegistration(sel.Sel.Name) {
+					return true
+				}
+				x := sel.X
+				if inner, ok := x.(*ast.SelectorExpr); ok {
+					x = inner.X
+				}
+				ident, ok := x.(*ast.Ident)
+				if !ok || ident.Obj != nil {
+					return true
+				}
+				if path, ok := imports[ident.Name]; ok {
+					registration := path + "." + sel.Sel.Name
+					if !seen[registration] {
+						seen[registration] = true
+						registrations = append(registrations, registration)
+					}
+				}
+				return true
+			})
+		}
+	}
+	sort.Strings(registrations)
+	return registrations
+}
+
+// isRegistration reports whether a function name starts with one of registrationPrefixes
+func isRegistration(name string) bool {
+	for _, prefix := range registrationPrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// sideEffects lists the packages with effects at run time: those imported for
+// their side effects only and the module packages whose init functions register
+// global state, with the binaries linking each of them in, sorted by import path
+func (a *ModuleAnalyzer) sideEffects(metrics *models.ModuleMetrics) []models.SideEffect {
+	effects := make(map[string]*models.SideEffect)
+	importers := make(map[string][]string) // Side-effect package -> module packages importing it
+	effect := func(path string) *models.SideEffect {
+		if effects[path] == nil {
+			effects[path] = &models.SideEffect{Package: path}
+		}
+		return effects[path]
+	}
+
+	for _, id := range sortedPackageIDs(metrics.Packages) {
+		for _, imp := range a.blank[id] {
+			e := effect(imp.pkg)
+			e.Registrations = imp.registrations
+			e.BlankImportedBy = append(e.BlankImportedBy, metrics.Packages[id].Name)
+			importers[imp.pkg] = append(importers[imp.pkg], id)
+		}
+		if registrations := a.inits[id]; len(registrations) > 0 {
+			effect(id).Registrations = registrations
+			importers[id] = append(importers[id], id)
+		}
+	}
+
+	result := make([]models.SideEffect, 0, len(effects))
+	for path, e := range effects {
+		// Every package importing the side-effect package links it in, blank or not
+		start := append(importers[path], a.reverseDepends[path]...)
+		e.Binaries = a.binariesLinking(metrics, start)
+		result = append(result, *e)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Package < result[j].Package })
+	return result
+}
+
+// binariesLinking returns the display names of the packages named main among
+// the given packages and everything transitively depending on them, sorted
+func (a *ModuleAnalyzer) binariesLinking(metrics *models.ModuleMetrics, start []string) []string {
+	visited := make(map[string]bool)
+	queue := append([]string(nil), start...)
+	var binaries []string
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		if visited[id] {
+			continue
+		}
+		visited[id] = true
+		if a.mains[id] {
+			binaries = append(binaries, metrics.Packages[id].Name)
+		}
+		queue = append(queue, a.reverseDepends[id]...)
+	}
+	sort.Strings(binaries)
+	return binaries
+}
@@ -0,0 +1,59 @@
+// Package analyzer provides functionality for analyzing Go modules and calculating design metrics.
+// This file implements the module-level summary statistics.
+package analyzer
+
+import (
+	"math"
+	"sort"
+
+	"github.com/alkbt/aid-metrics/pkg/models"
+)
+
+// summaryWorstOffenders is the number of packages listed as worst offenders
+const summaryWorstOffenders = 5
+
+// Summarize aggregates package metrics into module-level statistics. It must be
+// run again whenever the packages, their D or their zones change.
+func Summarize(pkgs map[string]models.PackageMetrics) models.Summary {
+	summary := models.Summary{Packages: len(pkgs), Zones: make(map[string]int)}
+
+	var measured []models.PackageMetrics
+	for _, id := range sortedPackageIDs(pkgs) {
+		pkg := pkgs[id]
+		if pkg.Zone != "" {
+			summary.Zones[pkg.Zone]++
+		}
+		if pkg.Ca+pkg.Ce > 0 && !pkg.GateExempt {
+			measured = append(measured, pkg)
+		}
+	}
+	if len(measured) == 0 {
+		return summary
+	}
+
+	// Worst first; ties keep the order of the package IDs
+	sort.SliceStable(measured, func(i, j int) bool { return measured[i].Distance > measured[j].Distance })
+	n := len(measured)
+	total := 0.0
+	for _, pkg := range measured {
+		total += pkg.Distance
+	}
+	summary.Measured = n
+	summary.MeanDistance = total / float64(n)
+	if n%2 == 1 {
+		summary.MedianDistance = measured[n/2].Distance
+	} else {
+		summary.MedianDistance = (measured[n/2-1].Distance + measured[n/2].Distance) / 2
+	}
+	// The nearest rank of the 90th percentile, counted from the lowest distance
+	rank := int(math.Ceil(0.9 * float64(n)))
+	summary.P90Distance = measured[n-rank].Distance
+
+	for _, pkg := range measured[:min(n, summaryWorstOffenders)] {
+		if pkg.Distance == 0 {
+			break
+		}
+		summary.WorstOffenders = append(summary.WorstOffenders, pkg.Name)
+	}
+	return summary
+}
@@ -0,0 +1,32 @@
+// Package analyzer provides functionality for analyzing Go modules and calculating design metrics.
+// This file implements the extraction of the package doc synopsis.
+package analyzer
+
+import (
+	"go/ast"
+	"go/doc"
+	"path/filepath"
+)
+
+// synopsisPicker picks the synopsis of a package among the package comments of its
+// files. The comment in doc.go wins, as it is the conventional home of the package
+// documentation; otherwise the first file with a package comment is used.
+type synopsisPicker struct {
+	synopsis  string
+	fromDocGo bool
+}
+
+// add considers the package comment of a parsed file
+func (p *synopsisPicker) add(path string, file *ast.File) {
+	if file.Doc == nil || p.fromDocGo {
+		return
+	}
+	isDocGo := filepath.Base(path) == "doc.go"
+	if p.synopsis != "" && !isDocGo {
+		return
+	}
+	if synopsis := new(doc.Package).Synopsis(file.Doc.Text()); synopsis != "" {
+		p.synopsis = synopsis
+		p.fromDocGo = isDocGo
+	}
+}
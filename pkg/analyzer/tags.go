@@ -0,0 +1,53 @@
+// Package analyzer provides functionality for analyzing Go modules and calculating design metrics.
+// This file implements detection of struct tags and "data-bag" entity packages.
+package analyzer
+
+import (
+	"go/ast"
+	"reflect"
+	"strconv"
+)
+
+// entityTagKeys are the struct tag keys that mark a struct as a serialized or persisted entity
+var entityTagKeys = []string{"json", "gorm", "db", "bson", "yaml", "xml"}
+
+// Data-bag detection thresholds: a package is a data bag when it declares at least
+// dataBagMinTaggedStructs tagged structs, they make up at least dataBagMinTaggedShare
+// of its structs, and its abstractness is below dataBagMaxAbstractness.
+const (
+	dataBagMinTaggedStructs = 3
+	dataBagMinTaggedShare   = 0.5
+	dataBagMaxAbstractness  = 0.1
+)
+
+// hasEntityTags reports whether any field of the struct carries an entity tag
+func hasEntityTags(st *ast.StructType) bool {
+	if st.Fields == nil {
+		return false
+	}
+	for _, field := range st.Fields.List {
+		if field.Tag == nil {
+			continue
+		}
+		tag, err := strconv.Unquote(field.Tag.Value)
+		if err != nil {
+			continue
+		}
+		for _, key := range entityTagKeys {
+			if _, ok := reflect.StructTag(tag).Lookup(key); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isDataBag reports whether a package is dominated by tagged entity structs
+// with near-zero abstraction, a common coupling hotspot in Go services.
+func isDataBag(taggedStructs, structs int, abstractness float64) bool {
+	if taggedStructs < dataBagMinTaggedStructs || structs == 0 {
+		return false
+	}
+	share := float64(taggedStructs) / float64(structs)
+	return share >= dataBagMinTaggedShare && abstractness < dataBagMaxAbstractness
+}
@@ -0,0 +1,44 @@
+// Package analyzer provides functionality for analyzing Go modules and calculating design metrics.
+// This file implements the analysis of test files and test packages.
+package analyzer
+
+import (
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// packageID returns the ID of a package without the test variant suffix:
+// go/packages loads "p [p.test]" for p compiled with its _test.go files and
+// "p_test [p.test]" for its external test package.
+func packageID(id string) string {
+	if i := strings.Index(id, " ["); i >= 0 {
+		return id[:i]
+	}
+	return id
+}
+
+// testVariants selects the packages to analyze from packages loaded with tests.
+// A package with in-package test files is replaced by its test variant, external
+// test packages are kept as packages of their own, and the generated test mains
+// are dropped.
+func testVariants(pkgs []*packages.Package) []*packages.Package {
+	hasVariant := make(map[string]bool)
+	for _, pkg := range pkgs {
+		if id := packageID(pkg.ID); id != pkg.ID && id == pkg.PkgPath {
+			hasVariant[id] = true
+		}
+	}
+
+	selected := make([]*packages.Package, 0, len(pkgs))
+	for _, pkg := range pkgs {
+		switch {
+		case pkg.Name == "main" && strings.HasSuffix(pkg.ID, ".test"):
+			continue
+		case pkg.ID == pkg.PkgPath && hasVariant[pkg.ID]:
+			continue
+		}
+		selected = append(selected, pkg)
+	}
+	return selected
+}
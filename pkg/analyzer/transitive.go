@@ -0,0 +1,47 @@
+// Package analyzer provides functionality for analyzing Go modules and calculating design metrics.
+// This file implements transitive afferent and efferent coupling.
+package analyzer
+
+import "github.com/alkbt/aid-metrics/pkg/models"
+
+// TransitiveCoupling sets the transitive afferent and efferent coupling of every
+// package: the number of packages reachable through its dependents and through
+// its dependencies, itself excluded. Dependencies outside the module count but
+// are not followed further.
+func TransitiveCoupling(metrics *models.ModuleMetrics) {
+	ids := make(map[string]string, len(metrics.Packages))
+	for id, pkg := range metrics.Packages {
+		ids[pkg.Name] = id
+	}
+	dependents := func(name string) []string { return metrics.Packages[ids[name]].Dependents }
+	dependencies := func(name string) []string {
+		if id, ok := ids[name]; ok {
+			return metrics.Packages[id].Dependencies
+		}
+		return nil
+	}
+
+	for id, pkg := range metrics.Packages {
+		pkg.TransitiveCa = reachable(pkg.Name, dependents)
+		pkg.TransitiveCe = reachable(pkg.Name, dependencies)
+		metrics.Packages[id] = pkg
+	}
+}
+
+// reachable returns the number of nodes reachable from start through edges,
+// start excluded even when it lies on a cycle
+func reachable(start string, edges func(string) []string) int {
+	visited := map[string]bool{start: true}
+	queue := []string{start}
+	for len(queue) > 0 {
+		name := queue[0]
+		queue = queue[1:]
+		for _, next := range edges(name) {
+			if !visited[next] {
+				visited[next] = true
+				queue = append(queue, next)
+			}
+		}
+	}
+	return len(visited) - 1
+}
@@ -0,0 +1,78 @@
+// Package analyzer provides functionality for analyzing Go modules and calculating design metrics.
+// This file implements counting the declarations behind abstractness with the type checker.
+package analyzer
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+)
+
+// typeCounts holds the package-level declarations of a package as seen by the
+// type checker. Unlike the syntax, it sees through type aliases, which declare no
+// type of their own, and covers exactly the files the build constraints select.
+type typeCounts struct {
+	interfaces int // Named interface types
+	structs    int // Named struct types
+	named      int // Other named types, e.g. type ID int or type HandlerFunc func()
+	funcs      int // Functions, not counting methods
+}
+
+// countTypes counts the package-level declarations of a type-checked package,
+// only the exported ones if exportedOnly is set. Declarations for which skip
+// returns true (e.g. those in generated files) are left out; skip may be nil.
+// Without type information all counts are zero.
+func countTypes(pkg *types.Package, exportedOnly bool, skip func(types.Object) bool) typeCounts {
+	var counts typeCounts
+	if pkg == nil {
+		return counts
+	}
+
+	scope := pkg.Scope()
+	for _, name := range scope.Names() {
+		if exportedOnly && !token.IsExported(name) {
+			continue
+		}
+		obj := scope.Lookup(name)
+		if skip != nil && skip(obj) {
+			continue
+		}
+		switch obj := obj.(type) {
+		case *types.TypeName:
+			if obj.IsAlias() {
+				continue
+			}
+			switch obj.Type().Underlying().(type) {
+			case *types.Interface:
+				counts.interfaces++
+			case *types.Struct:
+				counts.structs++
+			default:
+				counts.named++
+			}
+		case *types.Func:
+			counts.funcs++
+		}
+	}
+	return counts
+}
+
+// typeSpecs returns the package-level type declarations of a file
+func typeSpecs(file *ast.File) []*ast.TypeSpec {
+	var specs []*ast.TypeSpec
+	for _, decl := range file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.TYPE {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			specs = append(specs, spec.(*ast.TypeSpec))
+		}
+	}
+	return specs
+}
+
+// total returns the number of abstract and concrete declarations (Nc)
+func (c typeCounts) total() int {
+	return c.interfaces + c.structs + c.named + c.funcs
+}
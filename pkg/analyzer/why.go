@@ -0,0 +1,108 @@
+// Package analyzer provides functionality for analyzing Go modules and calculating design metrics.
+// This file implements the shortest import paths between two packages.
+package analyzer
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/alkbt/aid-metrics/pkg/models"
+)
+
+// ShortestPaths returns the shortest import paths from one package to another as
+// display names, starting with from and ending with to, sorted and at most limit
+// of them (0 for all). All paths have the same length; none means to is not
+// reachable from from. Packages are given by display name, import path or
+// module-relative path; to may also be a dependency outside the module.
+func ShortestPaths(metrics *models.ModuleMetrics, from, to string, limit int) ([][]string, error) {
+	ids := make(map[string]string, len(metrics.Packages))
+	for id, pkg := range metrics.Packages {
+		ids[pkg.Name] = id
+	}
+	source, ok := resolvePackageName(metrics, from)
+	if !ok {
+		return nil, fmt.Errorf("no package %q in the module", from)
+	}
+	target, ok := resolvePackageName(metrics, to)
+	if !ok {
+		return nil, fmt.Errorf("no package %q in the module or its dependencies", to)
+	}
+
+	// Breadth-first search recording every predecessor on a shortest path
+	depth := map[string]int{source: 0}
+	predecessors := make(map[string][]string)
+	queue := []string{source}
+	for len(queue) > 0 {
+		name := queue[0]
+		queue = queue[1:]
+		for _, dep := range metrics.Packages[ids[name]].Dependencies {
+			d, seen := depth[dep]
+			if !seen {
+				depth[dep] = depth[name] + 1
+				queue = append(queue, dep)
+			} else if d != depth[name]+1 {
+				continue
+			}
+			predecessors[dep] = append(predecessors[dep], name)
+		}
+	}
+	if _, reached := depth[target]; !reached {
+		return nil, nil
+	}
+
+	// Walk the predecessors back from the target, in sorted order
+	var paths [][]string
+	var walk func(name string, suffix []string) bool
+	walk = func(name string, suffix []string) bool {
+		suffix = append([]string{name}, suffix...)
+		if name == source {
+			paths = append(paths, suffix)
+			return limit == 0 || len(paths) < limit
+		}
+		previous := predecessors[name]
+		sort.Strings(previous)
+		for _, p := range previous {
+			if !walk(p, suffix) {
+				return false
+			}
+		}
+		return true
+	}
+	walk(target, nil)
+	sort.Slice(paths, func(i, j int) bool { return strings.Join(paths[i], " ") < strings.Join(paths[j], " ") })
+	return paths, nil
+}
+
+// resolvePackageName finds the display name of a package given by display name,
+// import path or module-relative path, among the module's packages and their
+// dependencies
+func resolvePackageName(metrics *models.ModuleMetrics, name string) (string, bool) {
+	name = strings.TrimPrefix(strings.Trim(name, "/"), "./")
+	if pkg, ok := metrics.Packages[name]; ok {
+		return pkg.Name, true
+	}
+	for _, pkg := range metrics.Packages {
+		if pkg.Name == name || slices.Contains(pkg.Dependencies, name) {
+			return name, true
+		}
+	}
+	for display, importPath := range metrics.Names {
+		if importPath == name {
+			return display, true
+		}
+	}
+
+	// A module-relative path, unless it is ambiguous
+	var found []string
+	for _, id := range sortedPackageIDs(metrics.Packages) {
+		if strings.HasSuffix(id, "/"+name) {
+			found = append(found, metrics.Packages[id].Name)
+		}
+	}
+	if len(found) == 1 {
+		return found[0], true
+	}
+	return "", false
+}
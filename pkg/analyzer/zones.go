@@ -0,0 +1,37 @@
+// Package analyzer provides functionality for analyzing Go modules and calculating design metrics.
+// This file implements the classification of packages into the zones of the A/I chart.
+package analyzer
+
+import (
+	"github.com/alkbt/aid-metrics/pkg/models"
+)
+
+// ClassifyZones sets the zone of every package from its A and I and the
+// options' zone bands. It is run by the analysis and must be run again
+// whenever A or I change.
+func ClassifyZones(metrics *models.ModuleMetrics, options AnalyzerOptions) {
+	bands := models.DefaultZoneBands
+	if options.ZoneBands != nil {
+		bands = *options.ZoneBands
+	}
+	for id, pkg := range metrics.Packages {
+		pkg.Zone = zone(pkg, bands, options.ImportsOnly)
+		metrics.Packages[id] = pkg
+	}
+}
+
+// zone returns the zone of a package on the A/I chart. Isolated packages have
+// no meaningful stability and packages analyzed without type information
+// (imports-only mode) no abstractness, so neither gets a zone.
+func zone(pkg models.PackageMetrics, bands models.ZoneBands, importsOnly bool) string {
+	switch {
+	case importsOnly || pkg.Ca+pkg.Ce == 0:
+		return ""
+	case pkg.Abstractness <= bands.Pain && pkg.Instability <= bands.Pain:
+		return models.ZonePain
+	case pkg.Abstractness >= bands.Uselessness && pkg.Instability >= bands.Uselessness:
+		return models.ZoneUselessness
+	default:
+		return models.ZoneMainSequence
+	}
+}
@@ -0,0 +1,253 @@
+// Package anonymize redacts the names in module metrics, so reports can be
+// shared outside the organization without leaking proprietary naming.
+//
+// Every element of a package path, type name or route is replaced by a hash of
+// itself, so equal names stay equal and paths keep their hierarchy: pkg/billing
+// and internal/billing become pkg/nXXXXXXXX and internal/nXXXXXXXX with the same
+// hash. The metrics
+// themselves are not changed. Without a salt, common names can be recovered by
+// hashing guesses; pass a secret salt when that matters.
+package anonymize
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/alkbt/aid-metrics/pkg/models"
+)
+
+// kept holds path elements carrying Go layout conventions rather than naming
+var kept = map[string]bool{
+	"cmd":      true,
+	"internal": true,
+	"pkg":      true,
+}
+
+// anonymizer maps names to their hashes and remembers every mapping, so names
+// can also be replaced within finding messages
+type anonymizer struct {
+	salt     string
+	replaced map[string]string
+}
+
+// Metrics returns a copy of metrics with all package, type and route names
+// hashed with salt. Package documentation is dropped, and the module path
+// becomes "module".
+func Metrics(metrics *models.ModuleMetrics, salt string) *models.ModuleMetrics {
+	a := &anonymizer{salt: salt, replaced: make(map[string]string)}
+
+	result := &models.ModuleMetrics{
+		Path:         "module",
+		Packages:     make(map[string]models.PackageMetrics, len(metrics.Packages)),
+		Roles:        metrics.Roles,
+		ExportedOnly: metrics.ExportedOnly,
+		Summary:      metrics.Summary,
+	}
+	result.Summary.WorstOffenders = nil
+	for _, name := range metrics.Summary.WorstOffenders {
+		// Worst first, so not sorted like the other name lists
+		result.Summary.WorstOffenders = append(result.Summary.WorstOffenders, a.path(name))
+	}
+	for id, pkg := range metrics.Packages {
+		pkg.Name = a.path(pkg.Name)
+		pkg.Synopsis = ""
+		pkg.Dependencies = a.paths(pkg.Dependencies)
+		pkg.Dependents = a.paths(pkg.Dependents)
+		pkg.ExternalImports = a.paths(pkg.ExternalImports)
+		pkg.ExposedDependencies = a.paths(pkg.ExposedDependencies)
+
+		leaks := make([]models.TypeLeak, 0, len(pkg.LeakedTypes))
+		for _, leak := range pkg.LeakedTypes {
+			leaks = append(leaks, models.TypeLeak{
+				Declaration: a.identifier(leak.Declaration),
+				Package:     a.path(leak.Package),
+				Type:        a.identifier(leak.Type),
+			})
+		}
+		pkg.LeakedTypes = leaks
+
+		deprecated := make([]models.DeprecatedUse, 0, len(pkg.DeprecatedSymbols))
+		for _, use := range pkg.DeprecatedSymbols {
+			deprecated = append(deprecated, models.DeprecatedUse{
+				Package: a.path(use.Package),
+				Symbol:  a.identifier(use.Symbol),
+				Uses:    use.Uses,
+			})
+		}
+		pkg.DeprecatedSymbols = deprecated
+		errorTargets := make([]models.ErrorTarget, 0, len(pkg.ErrorTargets))
+		for _, target := range pkg.ErrorTargets {
+			errorTargets = append(errorTargets, models.ErrorTarget{
+				Name:      a.identifier(target.Name),
+				Kind:      target.Kind,
+				CheckedBy: a.paths(target.CheckedBy),
+			})
+		}
+		pkg.ErrorTargets = errorTargets
+
+		constantSets := make([]models.ConstantSet, 0, len(pkg.ConstantSets))
+		for _, set := range pkg.ConstantSets {
+			constantSets = append(constantSets, models.ConstantSet{
+				Name:         a.identifier(set.Name),
+				Constants:    set.Constants,
+				ReferencedBy: a.paths(set.ReferencedBy),
+			})
+		}
+		pkg.ConstantSets = constantSets
+
+		pkg.BlankImports = a.paths(pkg.BlankImports)
+		pkg.InitRegistrations = a.registrations(pkg.InitRegistrations)
+
+		headers := make([]models.HeaderInterface, 0, len(pkg.HeaderInterfaces))
+		for _, header := range pkg.HeaderInterfaces {
+			headers = append(headers, models.HeaderInterface{
+				Interface:      a.identifier(header.Interface),
+				Implementation: a.identifier(header.Implementation),
+			})
+		}
+		pkg.HeaderInterfaces = headers
+
+		mocked := make([]models.MockedInterface, 0, len(pkg.MockedInterfaces))
+		for _, m := range pkg.MockedInterfaces {
+			m.Interface = a.identifier(m.Interface)
+			mocked = append(mocked, m)
+		}
+		pkg.MockedInterfaces = mocked
+
+		result.Packages[a.path(id)] = pkg
+	}
+
+	for _, endpoint := range metrics.Endpoints {
+		endpoint.Route = a.path(endpoint.Route)
+		endpoint.RegisteredIn = a.path(endpoint.RegisteredIn)
+		endpoint.Handler = a.path(endpoint.Handler)
+		endpoint.Dependencies = a.paths(endpoint.Dependencies)
+		result.Endpoints = append(result.Endpoints, endpoint)
+	}
+	for _, effect := range metrics.SideEffects {
+		effect.Package = a.path(effect.Package)
+		effect.Registrations = a.registrations(effect.Registrations)
+		effect.BlankImportedBy = a.paths(effect.BlankImportedBy)
+		effect.Binaries = a.paths(effect.Binaries)
+		result.SideEffects = append(result.SideEffects, effect)
+	}
+	for _, coupling := range metrics.StringCouplings {
+		coupling.Value = a.hash(coupling.Value)
+		coupling.Packages = a.paths(coupling.Packages)
+		result.StringCouplings = append(result.StringCouplings, coupling)
+	}
+	for _, cycle := range metrics.Cycles {
+		result.Cycles = append(result.Cycles, a.paths(cycle))
+	}
+	if metrics.Names != nil {
+		result.Names = make(map[string]string, len(metrics.Names))
+		for name, importPath := range metrics.Names {
+			result.Names[a.path(name)] = a.path(importPath)
+		}
+	}
+
+	// Messages are free text built from the names replaced above
+	replace := a.replacer()
+	for _, finding := range metrics.Findings {
+		finding.Package = a.path(finding.Package)
+		finding.Message = replace(finding.Message)
+		finding.Remediation = replace(finding.Remediation)
+		result.Findings = append(result.Findings, finding)
+	}
+	for _, diagnostic := range metrics.Diagnostics {
+		diagnostic.Package = a.path(diagnostic.Package)
+		diagnostic.Position = "" // File names would give the code away
+		diagnostic.Message = replace(diagnostic.Message)
+		result.Diagnostics = append(result.Diagnostics, diagnostic)
+	}
+
+	return result
+}
+
+// hash returns the anonymized form of a single name element
+func (a *anonymizer) hash(element string) string {
+	if element == "" || kept[element] {
+		return element
+	}
+	sum := sha256.Sum256([]byte(a.salt + "\x00" + element))
+	return "n" + hex.EncodeToString(sum[:4])
+}
+
+// registrations anonymizes registration calls of the form "import/path.Function"
+func (a *anonymizer) registrations(calls []string) []string {
+	if calls == nil {
+		return nil
+	}
+	result := make([]string, 0, len(calls))
+	for _, call := range calls {
+		i := strings.LastIndex(call, ".")
+		result = append(result, a.path(call[:i])+"."+a.identifier(call[i+1:]))
+	}
+	return result
+}
+
+// path anonymizes every element of a slash-separated path. A _test suffix of
+// the last element is kept, so external test packages stay recognizable.
+func (a *anonymizer) path(p string) string {
+	if p == "" {
+		return p
+	}
+	elements := strings.Split(p, "/")
+	for i, element := range elements {
+		if base, ok := strings.CutSuffix(element, "_test"); ok && i == len(elements)-1 && base != "" {
+			elements[i] = a.hash(base) + "_test"
+			continue
+		}
+		elements[i] = a.hash(element)
+	}
+	anonymized := strings.Join(elements, "/")
+	a.replaced[p] = anonymized
+	return anonymized
+}
+
+// paths anonymizes a list of paths
+func (a *anonymizer) paths(list []string) []string {
+	if list == nil {
+		return nil
+	}
+	result := make([]string, 0, len(list))
+	for _, p := range list {
+		result = append(result, a.path(p))
+	}
+	sort.Strings(result)
+	return result
+}
+
+// identifier anonymizes a dotted identifier such as "Client.Transport"
+func (a *anonymizer) identifier(name string) string {
+	parts := strings.Split(name, ".")
+	for i, part := range parts {
+		parts[i] = a.hash(part)
+	}
+	anonymized := strings.Join(parts, ".")
+	a.replaced[name] = anonymized
+	return anonymized
+}
+
+// words matches the names within free text: identifiers, dotted names and paths
+var words = regexp.MustCompile(`[\w./-]+`)
+
+// replacer returns a function replacing every name anonymized so far within a
+// text. A trailing period is taken for punctuation unless it is part of a name.
+func (a *anonymizer) replacer() func(string) string {
+	return func(s string) string {
+		return words.ReplaceAllStringFunc(s, func(word string) string {
+			if anonymized, ok := a.replaced[word]; ok {
+				return anonymized
+			}
+			trimmed := strings.TrimRight(word, ".")
+			if anonymized, ok := a.replaced[trimmed]; ok {
+				return anonymized + word[len(trimmed):]
+			}
+			return word
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package anonymize
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/alkbt/aid-metrics/pkg/models"
+)
+
+func TestMetrics(t *testing.T) {
+	metrics := &models.ModuleMetrics{
+		Path: "/home/acme/billing-platform",
+		Packages: map[string]models.PackageMetrics{
+			"acme.com/billing/pkg/invoice": {
+				Name: "pkg/invoice", Ca: 1, Ce: 1, Distance: 0.5, Synopsis: "Package invoice bills Acme customers.",
+				Dependencies: []string{"internal/invoice"},
+				LeakedTypes:  []models.TypeLeak{{Declaration: "Client.Transport", Package: "acme.com/secret/http", Type: "Transport"}},
+			},
+			"acme.com/billing/internal/invoice": {Name: "internal/invoice", Ca: 1, Dependents: []string{"pkg/invoice"}},
+			"acme.com/billing/pkg/invoice_test": {Name: "pkg/invoice_test"},
+		},
+		Findings: []models.Finding{
+			{Package: "pkg/invoice", Message: "exposes types of acme.com/secret/http in 1 exported declarations: Client.Transport (Transport)."},
+		},
+	}
+
+	anonymized := Metrics(metrics, "salt")
+	if anonymized.Path != "module" {
+		t.Errorf("expected the module path to be hidden, got %q", anonymized.Path)
+	}
+
+	var public, internal models.PackageMetrics
+	byName := make(map[string]models.PackageMetrics)
+	for _, pkg := range anonymized.Packages {
+		byName[pkg.Name] = pkg
+		switch {
+		case pkg.Distance == 0.5:
+			public = pkg
+		case strings.HasPrefix(pkg.Name, "internal/"):
+			internal = pkg
+		}
+	}
+
+	// Names are consistent: pkg/invoice and internal/invoice share the hashed element
+	publicBase := strings.TrimPrefix(public.Name, "pkg/")
+	if internal.Name != "internal/"+publicBase || public.Dependencies[0] != internal.Name || internal.Dependents[0] != public.Name {
+		t.Errorf("expected consistent names, got %q depending on %v and %q", public.Name, public.Dependencies, internal.Name)
+	}
+	if _, ok := byName["pkg/"+publicBase+"_test"]; !ok {
+		t.Errorf("expected the external test package to keep its _test suffix, got %v", byName)
+	}
+	if public.Ca != 1 || public.Distance != 0.5 || public.Synopsis != "" {
+		t.Errorf("expected metrics kept and documentation dropped, got %+v", public)
+	}
+
+	// Nothing of the original naming is left anywhere
+	var text strings.Builder
+	for id, pkg := range anonymized.Packages {
+		text.WriteString(id + " " + pkg.Name + " " + strings.Join(pkg.Dependencies, " "))
+		for _, leak := range pkg.LeakedTypes {
+			text.WriteString(" " + leak.Declaration + " " + leak.Package + " " + leak.Type)
+		}
+	}
+	for _, finding := range anonymized.Findings {
+		text.WriteString(" " + finding.Package + " " + finding.Message)
+	}
+	for _, secret := range []string{"acme", "invoice", "billing", "secret", "Client", "Transport"} {
+		if strings.Contains(text.String(), secret) {
+			t.Errorf("expected %q to be redacted, got %s", secret, text.String())
+		}
+	}
+	if !strings.HasSuffix(anonymized.Findings[0].Message, ").") {
+		t.Errorf("expected punctuation to be kept, got %q", anonymized.Findings[0].Message)
+	}
+
+	if other := Metrics(metrics, "other salt"); other.Findings[0].Package == anonymized.Findings[0].Package {
+		t.Error("expected the salt to change the hashes")
+	}
+}
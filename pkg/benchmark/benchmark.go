@@ -0,0 +1,177 @@
+// Package benchmark compares a module's aggregate metrics against a corpus of
+// well-known open source Go modules, answering whether a given average distance
+// is actually good or bad in practice.
+package benchmark
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/alkbt/aid-metrics/pkg/analyzer"
+	"github.com/alkbt/aid-metrics/pkg/models"
+)
+
+// KnownModules lists the corpus modules that can be benchmarked against by name
+var KnownModules = map[string]string{
+	"kubernetes": "https://github.com/kubernetes/kubernetes.git",
+	"grpc-go":    "https://github.com/grpc/grpc-go.git",
+	"hugo":       "https://github.com/gohugoio/hugo.git",
+	"prometheus": "https://github.com/prometheus/prometheus.git",
+	"etcd":       "https://github.com/etcd-io/etcd.git",
+	"cobra":      "https://github.com/spf13/cobra.git",
+	"gin":        "https://github.com/gin-gonic/gin.git",
+	"terraform":  "https://github.com/hashicorp/terraform.git",
+}
+
+// Aggregate holds module-wide averages used for benchmarking
+type Aggregate struct {
+	Packages         int     `json:"packages"`
+	MeanInstability  float64 `json:"mean_instability"`
+	MeanAbstractness float64 `json:"mean_abstractness"`
+	MeanDistance     float64 `json:"mean_distance"`
+}
+
+// Summarize computes the aggregate metrics of a module
+func Summarize(metrics *models.ModuleMetrics) Aggregate {
+	var agg Aggregate
+	for _, pkg := range metrics.Packages {
+		agg.Packages++
+		agg.MeanInstability += pkg.Instability
+		agg.MeanAbstractness += pkg.Abstractness
+		agg.MeanDistance += pkg.Distance
+	}
+	if agg.Packages > 0 {
+		n := float64(agg.Packages)
+		agg.MeanInstability /= n
+		agg.MeanAbstractness /= n
+		agg.MeanDistance /= n
+	}
+	return agg
+}
+
+// Corpus fetches and analyzes corpus modules, caching both the shallow
+// clones and the computed aggregates under CacheDir.
+type Corpus struct {
+	// CacheDir is the directory holding clones and cached aggregates
+	CacheDir string
+
+	// Refresh forces re-fetching and re-analyzing cached modules
+	Refresh bool
+}
+
+// DefaultCacheDir returns the default corpus cache directory under the user cache dir
+func DefaultCacheDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to determine user cache directory: %w", err)
+	}
+	return filepath.Join(dir, "aid-metrics", "corpus"), nil
+}
+
+// Aggregate returns the aggregate metrics of a known corpus module,
+// fetching and analyzing it if no cached result exists.
+func (c *Corpus) Aggregate(name string) (Aggregate, error) {
+	repository, ok := KnownModules[name]
+	if !ok {
+		return Aggregate{}, fmt.Errorf("unknown corpus module %q (known: %s)", name, strings.Join(KnownNames(), ", "))
+	}
+
+	cloneDir := filepath.Join(c.CacheDir, name)
+	resultPath := filepath.Join(c.CacheDir, name+".json")
+
+	if c.Refresh {
+		if err := os.RemoveAll(cloneDir); err != nil {
+			return Aggregate{}, fmt.Errorf("failed to clear cached clone of %s: %w", name, err)
+		}
+	} else if agg, err := readAggregate(resultPath); err == nil {
+		return agg, nil
+	}
+
+	if _, err := os.Stat(cloneDir); os.IsNotExist(err) {
+		if err := os.MkdirAll(c.CacheDir, 0o755); err != nil {
+			return Aggregate{}, fmt.Errorf("failed to create cache directory: %w", err)
+		}
+		cmd := exec.Command("git", "clone", "--depth", "1", "--quiet", repository, cloneDir)
+		cmd.Stderr = os.Stderr
+		if err := cmd.Run(); err != nil {
+			return Aggregate{}, fmt.Errorf("failed to clone %s: %w", repository, err)
+		}
+	}
+
+	metrics, err := analyzer.AnalyzeModule(cloneDir, "./...")
+	if err != nil {
+		return Aggregate{}, fmt.Errorf("failed to analyze %s: %w", name, err)
+	}
+
+	agg := Summarize(metrics)
+	if err := writeAggregate(resultPath, agg); err != nil {
+		return Aggregate{}, err
+	}
+	return agg, nil
+}
+
+// KnownNames returns the names of all known corpus modules, sorted
+func KnownNames() []string {
+	names := make([]string, 0, len(KnownModules))
+	for name := range KnownModules {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Percentile returns the percentage (0-100) of the population strictly below value
+func Percentile(value float64, population []float64) float64 {
+	if len(population) == 0 {
+		return 0
+	}
+	below := 0
+	for _, v := range population {
+		if v < value {
+			below++
+		}
+	}
+	return 100 * float64(below) / float64(len(population))
+}
+
+// Band describes where a percentile falls, for metrics where lower is better
+func Band(percentile float64) string {
+	switch {
+	case percentile < 25:
+		return "top quartile (better than most of the corpus)"
+	case percentile < 50:
+		return "second quartile (better than the median)"
+	case percentile < 75:
+		return "third quartile (worse than the median)"
+	default:
+		return "bottom quartile (worse than most of the corpus)"
+	}
+}
+
+// readAggregate loads a cached aggregate
+func readAggregate(path string) (Aggregate, error) {
+	var agg Aggregate
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return agg, err
+	}
+	err = json.Unmarshal(content, &agg)
+	return agg, err
+}
+
+// writeAggregate stores an aggregate in the cache
+func writeAggregate(path string, agg Aggregate) error {
+	content, err := json.MarshalIndent(agg, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, content, 0o644); err != nil {
+		return fmt.Errorf("failed to cache aggregate: %w", err)
+	}
+	return nil
+}
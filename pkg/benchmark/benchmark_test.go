@@ -0,0 +1,40 @@
+package benchmark
+
+import (
+	"testing"
+
+	"github.com/alkbt/aid-metrics/pkg/models"
+)
+
+func TestSummarize(t *testing.T) {
+	metrics := &models.ModuleMetrics{
+		Packages: map[string]models.PackageMetrics{
+			"a": {Instability: 1, Abstractness: 0, Distance: 0},
+			"b": {Instability: 0, Abstractness: 0, Distance: 1},
+		},
+	}
+
+	agg := Summarize(metrics)
+	if agg.Packages != 2 || agg.MeanInstability != 0.5 || agg.MeanDistance != 0.5 {
+		t.Errorf("Unexpected aggregate: %+v", agg)
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	population := []float64{0.1, 0.2, 0.3, 0.4}
+
+	tests := []struct {
+		value    float64
+		expected float64
+	}{
+		{0.05, 0},
+		{0.25, 50},
+		{0.5, 100},
+	}
+
+	for _, tt := range tests {
+		if got := Percentile(tt.value, population); got != tt.expected {
+			t.Errorf("Percentile(%v) = %v, want %v", tt.value, got, tt.expected)
+		}
+	}
+}
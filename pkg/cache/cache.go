@@ -0,0 +1,92 @@
+// Package cache provides stores for per-package analysis results, so many CI jobs
+// across branches can share analysis work.
+//
+// The remote cache protocol is plain HTTP on top of a base URL:
+//
+//	GET <base>/<key>  returns 200 with the stored result, or 404 if it is unknown
+//	PUT <base>/<key>  stores the request body and returns 204
+//
+// Keys are lowercase hex SHA-256 digests of the analyzed content, so entries never
+// need to be invalidated and a cache can be shared by any number of clients.
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// ErrInvalidKey is returned for keys that are not hex SHA-256 digests
+var ErrInvalidKey = errors.New("invalid cache key")
+
+// ValidKey reports whether key is a lowercase hex SHA-256 digest
+func ValidKey(key string) bool {
+	if len(key) != 64 {
+		return false
+	}
+	for _, c := range key {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// Dir is a cache stored in a local directory, one file per key.
+// Keys are spread over subdirectories named after their first two characters.
+type Dir struct {
+	Path string
+}
+
+// Get returns the data stored under key
+func (d Dir) Get(key string) ([]byte, bool, error) {
+	if !ValidKey(key) {
+		return nil, false, ErrInvalidKey
+	}
+	data, err := os.ReadFile(d.path(key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return data, true, nil
+}
+
+// Put stores data under key. The entry is written to a temporary file first and
+// renamed into place, so concurrent readers never observe a partial entry.
+func (d Dir) Put(key string, data []byte) error {
+	if !ValidKey(key) {
+		return ErrInvalidKey
+	}
+	path := d.path(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), key+".tmp*")
+	if err != nil {
+		return fmt.Errorf("failed to create cache entry: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to store cache entry: %w", err)
+	}
+	return nil
+}
+
+// path returns the file holding the entry of a key
+func (d Dir) path(key string) string {
+	return filepath.Join(d.Path, key[:2], key)
+}
@@ -0,0 +1,59 @@
+package cache
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRemoteCacheRoundTrip(t *testing.T) {
+	server := httptest.NewServer(Handler(Dir{Path: t.TempDir()}))
+	defer server.Close()
+	client := NewHTTPClient(server.URL)
+
+	key := strings.Repeat("ab", 32)
+	if _, ok, err := client.Get(key); err != nil || ok {
+		t.Fatalf("expected a miss for an unknown key, got ok=%v err=%v", ok, err)
+	}
+
+	if err := client.Put(key, []byte(`{"role":"domain"}`)); err != nil {
+		t.Fatalf("put failed: %v", err)
+	}
+	data, ok, err := client.Get(key)
+	if err != nil || !ok {
+		t.Fatalf("expected a hit after put, got ok=%v err=%v", ok, err)
+	}
+	if string(data) != `{"role":"domain"}` {
+		t.Errorf("unexpected entry %q", data)
+	}
+
+	if err := client.Put("../../etc/passwd", nil); err != ErrInvalidKey {
+		t.Errorf("expected ErrInvalidKey for a path-like key, got %v", err)
+	}
+}
+
+func TestLayered(t *testing.T) {
+	local, shared := &Memory{}, &Memory{}
+	l := Layered{Local: local, Shared: shared}
+	key := strings.Repeat("cd", 32)
+
+	if err := shared.Put(key, []byte("result")); err != nil {
+		t.Fatal(err)
+	}
+	if data, ok, err := l.Get(key); err != nil || !ok || string(data) != "result" {
+		t.Fatalf("expected the shared entry, got %q ok=%v err=%v", data, ok, err)
+	}
+	if _, ok, _ := local.Get(key); !ok {
+		t.Error("expected the shared entry to be copied to the local cache")
+	}
+
+	other := strings.Repeat("ef", 32)
+	if err := l.Put(other, []byte("new")); err != nil {
+		t.Fatal(err)
+	}
+	for name, c := range map[string]*Memory{"local": local, "shared": shared} {
+		if _, ok, _ := c.Get(other); !ok {
+			t.Errorf("expected a new entry in the %s cache", name)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package cache
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// MaxEntrySize is the largest entry accepted by the reference server
+const MaxEntrySize = 16 << 20
+
+// Store is the interface implemented by cache backends
+type Store interface {
+	Get(key string) ([]byte, bool, error)
+	Put(key string, data []byte) error
+}
+
+// HTTPClient is a remote cache speaking the GET/PUT protocol described in the package documentation
+type HTTPClient struct {
+	// BaseURL is the URL entries are stored under, e.g. "http://cache:8080/aid-metrics"
+	BaseURL string
+
+	// Client is the HTTP client used for requests. If nil, a client with a short timeout is used.
+	Client *http.Client
+}
+
+// NewHTTPClient creates a remote cache client for the given base URL
+func NewHTTPClient(baseURL string) *HTTPClient {
+	return &HTTPClient{
+		BaseURL: strings.TrimRight(baseURL, "/"),
+		Client:  &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Get fetches the entry stored under key
+func (c *HTTPClient) Get(key string) ([]byte, bool, error) {
+	if !ValidKey(key) {
+		return nil, false, ErrInvalidKey
+	}
+	resp, err := c.client().Get(c.BaseURL + "/" + key)
+	if err != nil {
+		return nil, false, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		data, err := io.ReadAll(io.LimitReader(resp.Body, MaxEntrySize+1))
+		if err != nil {
+			return nil, false, err
+		}
+		if len(data) > MaxEntrySize {
+			return nil, false, fmt.Errorf("cache entry %s exceeds %d bytes", key, MaxEntrySize)
+		}
+		return data, true, nil
+	case http.StatusNotFound:
+		return nil, false, nil
+	default:
+		return nil, false, fmt.Errorf("cache GET %s: unexpected status %s", key, resp.Status)
+	}
+}
+
+// Put uploads an entry under key
+func (c *HTTPClient) Put(key string, data []byte) error {
+	if !ValidKey(key) {
+		return ErrInvalidKey
+	}
+	req, err := http.NewRequest(http.MethodPut, c.BaseURL+"/"+key, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+
+	resp, err := c.client().Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		return fmt.Errorf("cache PUT %s: unexpected status %s", key, resp.Status)
+	}
+	return nil
+}
+
+// client returns the HTTP client to use
+func (c *HTTPClient) client() *http.Client {
+	if c.Client != nil {
+		return c.Client
+	}
+	return http.DefaultClient
+}
+
+// Handler returns the reference cache server, serving the GET/PUT protocol
+// for the entries of store directly below the root path.
+func Handler(store Store) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := strings.TrimPrefix(r.URL.Path, "/")
+		if !ValidKey(key) {
+			http.Error(w, ErrInvalidKey.Error(), http.StatusBadRequest)
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet, http.MethodHead:
+			data, ok, err := store.Get(key)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			if !ok {
+				http.NotFound(w, r)
+				return
+			}
+			w.Header().Set("Content-Type", "application/octet-stream")
+			w.Write(data)
+		case http.MethodPut:
+			data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, MaxEntrySize))
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+				return
+			}
+			if err := store.Put(key, data); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.Header().Set("Allow", "GET, HEAD, PUT")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+}
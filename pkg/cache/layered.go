@@ -0,0 +1,29 @@
+package cache
+
+// Layered is a local cache in front of a shared one, e.g. a Dir in the user
+// cache directory in front of a remote cache. Entries found only in Shared are
+// copied to Local, and new entries are stored in both.
+type Layered struct {
+	Local  Store
+	Shared Store
+}
+
+// Get returns the entry of the local cache, or else of the shared cache
+func (l Layered) Get(key string) ([]byte, bool, error) {
+	if data, ok, err := l.Local.Get(key); err == nil && ok {
+		return data, true, nil
+	}
+	data, ok, err := l.Shared.Get(key)
+	if err != nil || !ok {
+		return nil, false, err
+	}
+	_ = l.Local.Put(key, data)
+	return data, true, nil
+}
+
+// Put stores data under key in both caches. A failure of the local cache is
+// ignored, as the shared cache holds the entry anyway.
+func (l Layered) Put(key string, data []byte) error {
+	_ = l.Local.Put(key, data)
+	return l.Shared.Put(key, data)
+}
@@ -0,0 +1,35 @@
+package cache
+
+import "sync"
+
+// Memory is a cache held in memory, for long-running processes analyzing the
+// same module repeatedly, such as the language server. The zero value is ready to use.
+type Memory struct {
+	mu      sync.RWMutex
+	entries map[string][]byte
+}
+
+// Get returns the data stored under key
+func (m *Memory) Get(key string) ([]byte, bool, error) {
+	if !ValidKey(key) {
+		return nil, false, ErrInvalidKey
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	data, ok := m.entries[key]
+	return data, ok, nil
+}
+
+// Put stores data under key
+func (m *Memory) Put(key string, data []byte) error {
+	if !ValidKey(key) {
+		return ErrInvalidKey
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.entries == nil {
+		m.entries = make(map[string][]byte)
+	}
+	m.entries[key] = append([]byte(nil), data...)
+	return nil
+}
@@ -0,0 +1,233 @@
+// Package client is a Go client for the HTTP API of `aid-metrics serve`, so
+// tools can query architecture metrics without re-implementing the HTTP plumbing.
+// Responses are decoded into the types the server encodes them from.
+//
+//	c := client.New("https://metrics.example.com", client.WithToken(token))
+//	page, err := c.Project("shop").Packages(ctx, client.PackageQuery{Sort: "-distance", PerPage: 20})
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/alkbt/aid-metrics/pkg/reporter"
+	"github.com/alkbt/aid-metrics/pkg/server"
+)
+
+// ErrAnalysisRunning is returned by Analyze if an analysis is already running
+var ErrAnalysisRunning = errors.New("an analysis is already running")
+
+// APIError is an error response of the server
+type APIError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("aid-metrics server: %s (HTTP %d)", e.Message, e.StatusCode)
+}
+
+// Client accesses the API of a server. A client for a multi-project server
+// accesses its project list; use Project to access the API of a project.
+type Client struct {
+	baseURL    string
+	token      string
+	httpClient *http.Client
+}
+
+// Option configures a Client
+type Option func(*Client)
+
+// WithToken authenticates requests with a bearer token
+func WithToken(token string) Option {
+	return func(c *Client) { c.token = token }
+}
+
+// WithHTTPClient sets the HTTP client used for requests (default: http.DefaultClient)
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) { c.httpClient = httpClient }
+}
+
+// New creates a client for the server at baseURL
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		httpClient: http.DefaultClient,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Project returns a client for the API of a project of a multi-project server
+func (c *Client) Project(name string) *Client {
+	project := *c
+	project.baseURL = c.baseURL + "/projects/" + url.PathEscape(name)
+	return &project
+}
+
+// Projects lists the projects of a multi-project server
+func (c *Client) Projects(ctx context.Context) ([]server.ProjectStatus, error) {
+	var projects []server.ProjectStatus
+	err := c.do(ctx, http.MethodGet, "/projects", nil, &projects)
+	return projects, err
+}
+
+// Module returns the analysis status and the module-wide aggregates
+func (c *Client) Module(ctx context.Context) (*server.ModuleStatus, error) {
+	var module server.ModuleStatus
+	if err := c.do(ctx, http.MethodGet, "/module", nil, &module); err != nil {
+		return nil, err
+	}
+	return &module, nil
+}
+
+// PackageQuery selects, orders and pages the packages returned by Packages.
+// Zero values use the server defaults.
+type PackageQuery struct {
+	Page    int
+	PerPage int
+
+	// Sort is "name" or a metric name, prefixed with "-" for descending order
+	Sort string
+
+	// Search matches a substring of the package name
+	Search string
+
+	Role string
+
+	// Min and Max are inclusive bounds per metric, e.g. {"distance": 0.5}
+	Min map[string]float64
+	Max map[string]float64
+}
+
+// values encodes the query as query parameters
+func (q PackageQuery) values() url.Values {
+	values := url.Values{}
+	if q.Page > 0 {
+		values.Set("page", strconv.Itoa(q.Page))
+	}
+	if q.PerPage > 0 {
+		values.Set("per_page", strconv.Itoa(q.PerPage))
+	}
+	if q.Sort != "" {
+		values.Set("sort", q.Sort)
+	}
+	if q.Search != "" {
+		values.Set("q", q.Search)
+	}
+	if q.Role != "" {
+		values.Set("role", q.Role)
+	}
+	for metric, bound := range q.Min {
+		values.Set("min_"+metric, strconv.FormatFloat(bound, 'g', -1, 64))
+	}
+	for metric, bound := range q.Max {
+		values.Set("max_"+metric, strconv.FormatFloat(bound, 'g', -1, 64))
+	}
+	return values
+}
+
+// Packages returns a page of package metrics
+func (c *Client) Packages(ctx context.Context, query PackageQuery) (*server.PackagePage, error) {
+	path := "/packages"
+	if values := query.values(); len(values) > 0 {
+		path += "?" + values.Encode()
+	}
+	var page server.PackagePage
+	if err := c.do(ctx, http.MethodGet, path, nil, &page); err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
+
+// AllPackages returns the packages matching the query from all pages.
+// The Page field of the query is ignored.
+func (c *Client) AllPackages(ctx context.Context, query PackageQuery) ([]reporter.JSONPackage, error) {
+	if query.PerPage == 0 {
+		query.PerPage = server.MaxPerPage
+	}
+	var packages []reporter.JSONPackage
+	for query.Page = 1; ; query.Page++ {
+		page, err := c.Packages(ctx, query)
+		if err != nil {
+			return nil, err
+		}
+		packages = append(packages, page.Packages...)
+		if len(page.Packages) == 0 || len(packages) >= page.Total {
+			return packages, nil
+		}
+	}
+}
+
+// Findings returns all findings
+func (c *Client) Findings(ctx context.Context) ([]reporter.JSONFinding, error) {
+	var findings []reporter.JSONFinding
+	err := c.do(ctx, http.MethodGet, "/findings", nil, &findings)
+	return findings, err
+}
+
+// History returns the aggregates of past analyses, oldest first
+func (c *Client) History(ctx context.Context) ([]server.HistoryEntry, error) {
+	var history []server.HistoryEntry
+	err := c.do(ctx, http.MethodGet, "/history", nil, &history)
+	return history, err
+}
+
+// Analyze starts a new analysis. It returns ErrAnalysisRunning if one is already running.
+func (c *Client) Analyze(ctx context.Context) error {
+	err := c.do(ctx, http.MethodPost, "/analyze", nil, nil)
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusConflict {
+		return ErrAnalysisRunning
+	}
+	return err
+}
+
+// do sends a request and decodes the JSON response into out, if not nil
+func (c *Client) do(ctx context.Context, method, path string, body io.Reader, out any) error {
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if method == http.MethodPost {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		apiErr := &APIError{StatusCode: resp.StatusCode, Message: resp.Status}
+		var errBody struct {
+			Error string `json:"error"`
+		}
+		if json.NewDecoder(resp.Body).Decode(&errBody) == nil && errBody.Error != "" {
+			apiErr.Message = errBody.Error
+		}
+		return apiErr
+	}
+
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response of %s %s: %w", method, path, err)
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alkbt/aid-metrics/pkg/models"
+	"github.com/alkbt/aid-metrics/pkg/server"
+)
+
+func TestClient(t *testing.T) {
+	registry := server.NewRegistry()
+	err := registry.Add(server.Project{Name: "shop"}, func(progress models.ProgressReporter) (*models.ModuleMetrics, error) {
+		metrics := &models.ModuleMetrics{Path: "example.com/shop", Packages: map[string]models.PackageMetrics{}}
+		for i := range 5 {
+			name := fmt.Sprintf("pkg%d", i)
+			metrics.Packages[name] = models.PackageMetrics{Name: name, Distance: float64(i) / 4}
+		}
+		metrics.Findings = []models.Finding{{ID: "AM003", Severity: models.SeverityWarning, Package: "pkg4"}}
+		return metrics, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	registry.Run(context.Background())
+
+	ts := httptest.NewServer(server.RequireToken(server.StaticToken("s3cret"), registry.Handler()))
+	defer ts.Close()
+	ctx := context.Background()
+
+	var apiErr *APIError
+	if _, err := New(ts.URL).Projects(ctx); !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without a token, got %v", err)
+	}
+
+	c := New(ts.URL+"/", WithToken("s3cret"))
+	projects, err := c.Projects(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(projects) != 1 || projects[0].Name != "shop" || projects[0].Status != server.StatusReady {
+		t.Errorf("unexpected projects %+v", projects)
+	}
+
+	shop := c.Project("shop")
+	module, err := shop.Module(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if module.Module != "example.com/shop" || module.Packages != 5 || module.Findings != 1 {
+		t.Errorf("unexpected module %+v", module)
+	}
+
+	page, err := shop.Packages(ctx, PackageQuery{Sort: "-distance", PerPage: 2, Min: map[string]float64{"distance": 0.25}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.Total != 4 || len(page.Packages) != 2 || page.Packages[0].Name != "pkg4" {
+		t.Errorf("unexpected page %+v", page)
+	}
+
+	all, err := shop.AllPackages(ctx, PackageQuery{PerPage: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 5 {
+		t.Errorf("expected all 5 packages, got %d", len(all))
+	}
+
+	if findings, err := shop.Findings(ctx); err != nil || len(findings) != 1 || findings[0].ID != "AM003" {
+		t.Errorf("unexpected findings %+v, %v", findings, err)
+	}
+	if history, err := shop.History(ctx); err != nil || len(history) != 1 {
+		t.Errorf("unexpected history %+v, %v", history, err)
+	}
+	if err := shop.Analyze(ctx); err != nil {
+		t.Errorf("Analyze: %v", err)
+	}
+
+	if _, err := shop.Packages(ctx, PackageQuery{Sort: "color"}); !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadRequest || apiErr.Message != `cannot sort by "color"` {
+		t.Errorf("expected a 400 API error, got %v", err)
+	}
+	if _, err := c.Project("unknown").Module(ctx); !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusNotFound {
+		t.Errorf("expected a 404 API error, got %v", err)
+	}
+}
@@ -0,0 +1,131 @@
+// Package config loads the aid-metrics configuration file.
+// The configuration lives next to the analyzed module and lets teams tune
+// the analysis without repeating command-line flags on every run.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultFileName is the configuration file looked up in the module root
+const DefaultFileName = ".aid-metrics.yaml"
+
+// Config represents the contents of an aid-metrics configuration file
+type Config struct {
+	// Version is the schema version of the file (see CurrentVersion and Migrate)
+	Version int `yaml:"version"`
+
+	// Roles assign architectural roles to packages, overriding the built-in heuristics
+	Roles []RoleRule `yaml:"roles"`
+
+	// Exclude lists module-relative package patterns left out of the analysis,
+	// e.g. "internal/mocks/..." or "api/gen"; imports of them still count as coupling
+	Exclude []string `yaml:"exclude"`
+
+	// Rules constrain the imports between packages; violations fail the run
+	Rules []DependencyRule `yaml:"rules"`
+
+	// RuleTests are example package layouts with the rule violations they must
+	// produce, run by `aid-metrics rules test`
+	RuleTests []RuleTest `yaml:"rule_tests"`
+
+	// Thresholds are the metric thresholds enforced on every package, as with
+	// the -max-distance, -max-instability and -min-abstractness flags
+	Thresholds *Thresholds `yaml:"thresholds"`
+
+	// Profiles enables built-in handling of well-known package kinds (e.g. "protobuf")
+	Profiles []string `yaml:"profiles"`
+
+	// Severities overrides the default severity per finding category,
+	// e.g. {"sdp": "info", "cycle": "error"}
+	Severities map[string]string `yaml:"severities"`
+
+	// DebtWeights overrides the debt points of a finding per category, which
+	// default to 1, 3 and 10 for info, warning and error findings
+	DebtWeights map[string]int `yaml:"debt_weights"`
+
+	// DebtBudget is the maximum total debt points of the findings. Lower it as
+	// the debt is paid down, so it cannot grow back.
+	DebtBudget *int `yaml:"debt_budget"`
+
+	// HealthWeights overrides the weights of the health score components
+	// (distance, cycles, complexity, coverage), e.g. {"coverage": 0.3}
+	HealthWeights map[string]float64 `yaml:"health_weights"`
+
+	// ZoneBands overrides the boundaries of the zones of pain and uselessness:
+	// A and I both at most pain, or both at least uselessness, e.g. {"pain": 0.2}
+	ZoneBands map[string]float64 `yaml:"zone_bands"`
+
+	// GateEntryPoints holds main packages and composition roots to the
+	// abstractness/distance checks instead of exempting them
+	GateEntryPoints bool `yaml:"gate_entry_points"`
+}
+
+// Thresholds holds the configured metric thresholds; unset ones are not enforced
+type Thresholds struct {
+	MaxDistance     *float64 `yaml:"max_distance"`
+	MaxInstability  *float64 `yaml:"max_instability"`
+	MinAbstractness *float64 `yaml:"min_abstractness"`
+}
+
+// DependencyRule forbids imports of the packages matching From, e.g.
+// {from: [pkg/ui/...], deny: [pkg/db/...]}. Patterns are module-relative paths
+// as in role rules, import path patterns of other modules, or "third-party" for
+// every import outside the module and the standard library.
+type DependencyRule struct {
+	Name        string   `yaml:"name"`
+	From        []string `yaml:"from"`         // Packages the rule applies to, all when empty
+	Except      []string `yaml:"except"`       // Packages exempt from the rule
+	Deny        []string `yaml:"deny"`         // Imports the packages may not have
+	Allow       []string `yaml:"allow"`        // If set, the only imports the packages may have
+	Enforcement string   `yaml:"enforcement"`  // off, warn or enforce (the default)
+	EnforceFrom string   `yaml:"enforce_from"` // Date (YYYY-MM-DD) a warn rule is enforced from
+}
+
+// RuleTest is an example package layout for the rules, e.g.
+// {packages: {pkg/ui: [pkg/db]}, violations: ["pkg/ui -> pkg/db"]}
+type RuleTest struct {
+	Name       string              `yaml:"name"`
+	Packages   map[string][]string `yaml:"packages"`   // Module-relative package paths and their imports
+	Violations []string            `yaml:"violations"` // Expected "package -> import" violations, none to pass
+}
+
+// RoleRule assigns a role to all packages matching a module-relative pattern
+type RoleRule struct {
+	Pattern string `yaml:"pattern"`
+	Role    string `yaml:"role"`
+}
+
+// Load reads and parses the configuration file at path
+func Load(path string) (*Config, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var cfg Config
+	if err := yaml.Unmarshal(content, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+	if err := checkVersion(path, cfg.Version); err != nil {
+		return nil, err
+	}
+
+	return &cfg, nil
+}
+
+// LoadDefault reads DefaultFileName from the module directory.
+// A missing file is not an error: an empty configuration is returned instead.
+func LoadDefault(modulePath string) (*Config, error) {
+	cfg, err := Load(filepath.Join(modulePath, DefaultFileName))
+	if errors.Is(err, fs.ErrNotExist) {
+		return &Config{}, nil
+	}
+	return cfg, err
+}
@@ -0,0 +1,102 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+
+	"gopkg.in/yaml.v3"
+)
+
+// CurrentVersion is the schema version of configuration files understood by
+// this version of aid-metrics. Files without a version key are version 0.
+const CurrentVersion = 1
+
+// migrations upgrade the document of a configuration file from version i to
+// i+1. Append a step whenever a key is renamed, moved or changes meaning.
+var migrations = []func(root *yaml.Node) error{
+	// 0 → 1: the version key was introduced; the settings are unchanged
+	func(*yaml.Node) error { return nil },
+}
+
+// checkVersion rejects a configuration file written for a newer aid-metrics,
+// whose settings could silently mean something else
+func checkVersion(path string, version int) error {
+	if version > CurrentVersion {
+		return fmt.Errorf("config file %s has version %d, newer than the supported %d: upgrade aid-metrics", path, version, CurrentVersion)
+	}
+	return nil
+}
+
+// Migrate upgrades the content of a configuration file (the module's, a
+// projects or a publish file) to CurrentVersion, keeping comments and the order
+// of the keys. It returns the content unchanged and false if it is current.
+func Migrate(content []byte) ([]byte, bool, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(content, &doc); err != nil {
+		return nil, false, fmt.Errorf("failed to parse config file: %w", err)
+	}
+	if doc.Kind == 0 {
+		// An empty file
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}}
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil, false, fmt.Errorf("config file is not a mapping of settings")
+	}
+
+	version := 0
+	value := mappingValue(root, "version")
+	if value != nil {
+		v, err := strconv.Atoi(value.Value)
+		if err != nil || v < 0 {
+			return nil, false, fmt.Errorf("invalid config file version %q", value.Value)
+		}
+		version = v
+	}
+	if version > CurrentVersion {
+		return nil, false, fmt.Errorf("config file version %d is newer than the supported %d: upgrade aid-metrics", version, CurrentVersion)
+	}
+	if version == CurrentVersion {
+		return content, false, nil
+	}
+
+	for v := version; v < CurrentVersion; v++ {
+		if err := migrations[v](root); err != nil {
+			return nil, false, fmt.Errorf("migrating config file from version %d: %w", v, err)
+		}
+	}
+	current := strconv.Itoa(CurrentVersion)
+	if value != nil {
+		value.Value = current
+	} else {
+		key := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: "version"}
+		val := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!int", Value: current}
+		if len(root.Content) > 0 {
+			// The comment heading the file stays on top
+			key.HeadComment, root.Content[0].HeadComment = root.Content[0].HeadComment, ""
+		}
+		root.Content = append([]*yaml.Node{key, val}, root.Content...)
+	}
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return nil, false, fmt.Errorf("failed to encode config file: %w", err)
+	}
+	if err := enc.Close(); err != nil {
+		return nil, false, fmt.Errorf("failed to encode config file: %w", err)
+	}
+	return buf.Bytes(), true, nil
+}
+
+// mappingValue returns the value of key in a mapping node, or nil
+func mappingValue(mapping *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i+1]
+		}
+	}
+	return nil
+}
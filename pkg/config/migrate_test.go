@@ -0,0 +1,48 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMigrate(t *testing.T) {
+	v0 := "# Settings of the shop module\nexclude:\n  - internal/mocks # generated\n\nthresholds:\n  max_distance: 0.7\nprofiles: [protobuf]\n"
+	migrated, changed, err := Migrate([]byte(v0))
+	if err != nil || !changed {
+		t.Fatalf("expected version 0 to be migrated, got changed=%v (%v)", changed, err)
+	}
+	want := "# Settings of the shop module\nversion: 1\nexclude:\n  - internal/mocks # generated\nthresholds:\n  max_distance: 0.7\nprofiles: [protobuf]\n"
+	if string(migrated) != want {
+		t.Errorf("expected the version inserted first with comments and key order kept\n%s\ngot\n%s", want, migrated)
+	}
+
+	if out, _, err := Migrate([]byte("version: 0 # before the schema\nexclude: [a]\n")); err != nil || string(out) != "version: 1 # before the schema\nexclude: [a]\n" {
+		t.Errorf("expected an explicit version 0 to be raised in place, got %v\n%s", err, out)
+	}
+
+	// A migrated file is current and left as is, byte for byte
+	again, changed, err := Migrate(migrated)
+	if err != nil || changed || string(again) != string(migrated) {
+		t.Errorf("expected a current file to be unchanged, got changed=%v (%v)\n%s", changed, err, again)
+	}
+	current := "version: 1   # current\nexclude: [ vendor ]\n"
+	if out, changed, err := Migrate([]byte(current)); err != nil || changed || string(out) != current {
+		t.Errorf("expected a current file to be returned byte for byte, got changed=%v (%v)\n%s", changed, err, out)
+	}
+
+	for _, empty := range []string{"", "\n", "# no settings yet\n"} {
+		out, changed, err := Migrate([]byte(empty))
+		if err != nil || !changed || !strings.Contains(string(out), "version: 1") {
+			t.Errorf("expected the empty file %q to get version 1, got changed=%v (%v)\n%s", empty, changed, err, out)
+		}
+	}
+
+	if _, _, err := Migrate([]byte("version: 2\n")); err == nil || !strings.Contains(err.Error(), "newer") {
+		t.Errorf("expected a newer version to be rejected, got %v", err)
+	}
+	for _, invalid := range []string{"version: one\n", "version: -1\n", "version: 1.5\n", "version: [1]\n"} {
+		if _, _, err := Migrate([]byte(invalid)); err == nil {
+			t.Errorf("expected %q to be rejected", invalid)
+		}
+	}
+}
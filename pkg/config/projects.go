@@ -0,0 +1,104 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Projects represents a projects file listing the modules served by a
+// multi-project aid-metrics server
+type Projects struct {
+	// Version is the schema version of the file (see CurrentVersion and Migrate)
+	Version int `yaml:"version"`
+
+	Projects []Project `yaml:"projects"`
+}
+
+// Project is a module registered with the server.
+// Exactly one of Path and Git must be set.
+type Project struct {
+	// Name identifies the project in API paths (/projects/<name>/...)
+	Name string `yaml:"name"`
+
+	// Path is the local module directory
+	Path string `yaml:"path"`
+
+	// Git is the URL of a repository cloned by the server, and Ref the branch or
+	// tag to analyze (default: the remote's default branch)
+	Git string `yaml:"git"`
+	Ref string `yaml:"ref"`
+
+	// Pattern is the package pattern to analyze (default: ./...)
+	Pattern string `yaml:"pattern"`
+
+	// Config is the path of the configuration file (default: DefaultFileName in the module root)
+	Config string `yaml:"config"`
+
+	// Interval re-runs the analysis periodically, e.g. "1h". Zero analyzes only on
+	// startup and on POST /analyze.
+	Interval time.Duration `yaml:"interval"`
+}
+
+// projectName restricts project names to what can be used in a URL path segment
+var projectName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// LoadProjects reads, parses and validates the projects file at path
+func LoadProjects(path string) (*Projects, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read projects file: %w", err)
+	}
+
+	var projects Projects
+	if err := yaml.Unmarshal(content, &projects); err != nil {
+		return nil, fmt.Errorf("failed to parse projects file %s: %w", path, err)
+	}
+	if err := checkVersion(path, projects.Version); err != nil {
+		return nil, err
+	}
+	if err := projects.validate(); err != nil {
+		return nil, fmt.Errorf("invalid projects file %s: %w", path, err)
+	}
+
+	return &projects, nil
+}
+
+// validate checks that project names are unique, each project has exactly one
+// source and git URLs and refs are safe to pass to git
+func (p *Projects) validate() error {
+	if len(p.Projects) == 0 {
+		return errors.New("no projects defined")
+	}
+
+	seen := make(map[string]bool)
+	for _, project := range p.Projects {
+		if !projectName.MatchString(project.Name) {
+			return fmt.Errorf("invalid project name %q", project.Name)
+		}
+		if seen[project.Name] {
+			return fmt.Errorf("duplicate project %q", project.Name)
+		}
+		seen[project.Name] = true
+
+		if (project.Path == "") == (project.Git == "") {
+			return fmt.Errorf("project %q must set exactly one of path and git", project.Name)
+		}
+		if project.Ref != "" && project.Git == "" {
+			return fmt.Errorf("project %q sets ref without git", project.Name)
+		}
+		// Both end up on the git command line, where they would be taken for options
+		if strings.HasPrefix(project.Git, "-") || strings.HasPrefix(project.Ref, "-") {
+			return fmt.Errorf("project %q has a git URL or ref starting with \"-\"", project.Name)
+		}
+		if project.Interval < 0 {
+			return fmt.Errorf("project %q has a negative interval", project.Name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,35 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadProjects(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{"valid", "projects:\n  - name: shop\n    git: https://example.com/shop.git\n    ref: main\n  - name: billing\n    path: /srv/billing\n", ""},
+		{"duplicate", "projects:\n  - name: shop\n    path: a\n  - name: shop\n    path: b\n", "duplicate"},
+		{"two sources", "projects:\n  - name: shop\n    path: a\n    git: https://example.com/shop.git\n", "exactly one"},
+		{"option as url", "projects:\n  - name: shop\n    git: --upload-pack=touch /tmp/pwned\n", "starting with"},
+		{"option as ref", "projects:\n  - name: shop\n    git: https://example.com/shop.git\n    ref: --output=/tmp/x\n", "starting with"},
+	}
+	for _, tt := range tests {
+		path := filepath.Join(t.TempDir(), "projects.yaml")
+		if err := os.WriteFile(path, []byte(tt.content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		_, err := LoadProjects(path)
+		if tt.wantErr == "" && err != nil {
+			t.Errorf("%s: unexpected error %v", tt.name, err)
+		}
+		if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+			t.Errorf("%s: expected an error containing %q, got %v", tt.name, tt.wantErr, err)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Publish represents the configuration file of the publish subcommand, which
+// describes a complete scheduled run: what to analyze, where to store the
+// results, when to fail and whom to notify
+type Publish struct {
+	// Version is the schema version of the file (see CurrentVersion and Migrate)
+	Version int `yaml:"version"`
+
+	// Module is the module directory to analyze (default: the working directory)
+	Module string `yaml:"module"`
+
+	// Pattern is the package pattern to analyze (default: ./...)
+	Pattern string `yaml:"pattern"`
+
+	// Config is the path of the analysis configuration file (default: DefaultFileName in the module root)
+	Config string `yaml:"config"`
+
+	// Sink is where runs are stored and the previous run is read from
+	Sink PublishSink `yaml:"sink"`
+
+	// FailOn defines when the run exits with status 2
+	FailOn PublishGate `yaml:"fail_on"`
+
+	// Notify lists the webhooks announcing the run
+	Notify []Webhook `yaml:"notify"`
+}
+
+// PublishSink configures the storage of published runs
+type PublishSink struct {
+	// Dir is the directory runs are written to as JSON reports
+	Dir string `yaml:"dir"`
+
+	// Keep is the number of runs retained; older runs are deleted. Zero keeps all runs.
+	Keep int `yaml:"keep"`
+}
+
+// PublishGate defines when a published run fails
+type PublishGate struct {
+	// Severity fails the run if any finding has at least this severity
+	Severity string `yaml:"severity"`
+
+	// NewFindings fails the run if it has findings the previous run did not have
+	NewFindings bool `yaml:"new_findings"`
+
+	// MaxDistanceIncrease fails the run if the mean distance grew by more than
+	// this since the previous run
+	MaxDistanceIncrease *float64 `yaml:"max_distance_increase"`
+}
+
+// Webhook conditions
+const (
+	NotifyAlways  = "always"  // Every run
+	NotifyFailed  = "failed"  // Runs failing the gate
+	NotifyChanged = "changed" // Runs with new or resolved findings, or failing the gate
+)
+
+// Webhook is a URL receiving a JSON POST about published runs.
+// Environment variables in the URL are expanded, so secrets can be kept out of the file.
+type Webhook struct {
+	URL string `yaml:"webhook"`
+
+	// On is the condition for notifying (default: NotifyFailed)
+	On string `yaml:"on"`
+}
+
+// LoadPublish reads, parses and validates the publish configuration at path
+func LoadPublish(path string) (*Publish, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read publish config: %w", err)
+	}
+
+	var cfg Publish
+	if err := yaml.Unmarshal(content, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse publish config %s: %w", path, err)
+	}
+	if err := checkVersion(path, cfg.Version); err != nil {
+		return nil, err
+	}
+	if err := cfg.validate(); err != nil {
+		return nil, fmt.Errorf("invalid publish config %s: %w", path, err)
+	}
+
+	return &cfg, nil
+}
+
+// validate checks the sink and webhooks and applies defaults
+func (p *Publish) validate() error {
+	if p.Sink.Dir == "" {
+		return errors.New("sink.dir is required")
+	}
+	if p.Sink.Keep < 0 {
+		return errors.New("sink.keep must not be negative")
+	}
+
+	for i := range p.Notify {
+		hook := &p.Notify[i]
+		if hook.URL == "" {
+			return fmt.Errorf("notify entry %d has no webhook", i+1)
+		}
+		hook.URL = os.ExpandEnv(hook.URL)
+		switch hook.On {
+		case "":
+			hook.On = NotifyFailed
+		case NotifyAlways, NotifyFailed, NotifyChanged:
+		default:
+			return fmt.Errorf("unknown notify condition %q (expected always, failed or changed)", hook.On)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+// Package coverage reads Go cover profiles, as written by go test -coverprofile,
+// into the share of statements covered by tests per package.
+//
+// Blocks listed more than once, as in profiles of several test runs appended to
+// each other, are counted once and covered if any run covered them.
+package coverage
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// block is a counted block of a cover profile
+type block struct {
+	statements int
+	covered    bool
+}
+
+// ReadFile reads the cover profile at path
+func ReadFile(path string) (map[string]float64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	coverage, err := Parse(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return coverage, nil
+}
+
+// Parse reads a cover profile and returns the share of covered statements by
+// package import path. Packages without statements are left out.
+func Parse(r io.Reader) (map[string]float64, error) {
+	blocks := make(map[string]block)
+	scanner := bufio.NewScanner(r)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "mode:") {
+			continue
+		}
+
+		// file.go:startLine.startCol,endLine.endCol statements count
+		fields := strings.Fields(line)
+		if len(fields) != 3 || !strings.Contains(fields[0], ":") {
+			return nil, fmt.Errorf("line %d: invalid cover profile block %q", lineNumber, line)
+		}
+		statements, err := strconv.Atoi(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid number of statements %q", lineNumber, fields[1])
+		}
+		count, err := strconv.Atoi(fields[2])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid count %q", lineNumber, fields[2])
+		}
+
+		b := blocks[fields[0]]
+		b.statements = statements
+		b.covered = b.covered || count > 0
+		blocks[fields[0]] = b
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	statements := make(map[string]int)
+	covered := make(map[string]int)
+	for position, b := range blocks {
+		file := position[:strings.LastIndex(position, ":")]
+		pkg := path.Dir(file)
+		statements[pkg] += b.statements
+		if b.covered {
+			covered[pkg] += b.statements
+		}
+	}
+
+	coverage := make(map[string]float64, len(statements))
+	for pkg, n := range statements {
+		if n > 0 {
+			coverage[pkg] = float64(covered[pkg]) / float64(n)
+		}
+	}
+	return coverage, nil
+}
@@ -0,0 +1,37 @@
+package coverage
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	profile := `mode: set
+example.com/shop/cart/cart.go:10.2,12.3 3 1
+example.com/shop/cart/cart.go:14.2,15.3 1 0
+example.com/shop/cart/total.go:5.2,9.3 4 0
+example.com/shop/store/store.go:7.2,8.3 2 0
+mode: set
+example.com/shop/store/store.go:7.2,8.3 2 1
+example.com/shop/gen/gen.go:3.2,3.3 0 0
+`
+	coverage, err := Parse(strings.NewReader(profile))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// cart: 3 of 8 statements; store: the block covered by the second run counts once
+	if got := coverage["example.com/shop/cart"]; got != 3.0/8 {
+		t.Errorf("expected cart coverage 0.375, got %v", got)
+	}
+	if got := coverage["example.com/shop/store"]; got != 1 {
+		t.Errorf("expected store coverage 1, got %v", got)
+	}
+	if _, ok := coverage["example.com/shop/gen"]; ok {
+		t.Error("expected a package without statements to be left out")
+	}
+
+	if _, err := Parse(strings.NewReader("mode: set\nnot a block\n")); err == nil {
+		t.Error("expected an error for an invalid block")
+	}
+}
@@ -0,0 +1,216 @@
+// Package diff compares two JSON reports package by package, e.g. the reports
+// of the base and the head of a pull request.
+package diff
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/alkbt/aid-metrics/pkg/reporter"
+)
+
+// Epsilon is the smallest change of I, A or D that is reported. Smaller changes
+// are invisible at the printed precision and treated as unchanged.
+const Epsilon = 0.005
+
+// Package change verdicts
+const (
+	Improved = "improved" // D decreased, or Ce decreased at equal D
+	Worsened = "worsened" // D increased, or Ce increased at equal D
+)
+
+// PackageDelta is the change of a package present in both reports.
+// The metric fields hold the new values, the Delta fields the change since the old report.
+type PackageDelta struct {
+	Name string `json:"package"`
+
+	Ca           int     `json:"ca"`
+	Ce           int     `json:"ce"`
+	Instability  float64 `json:"instability"`
+	Abstractness float64 `json:"abstractness"`
+	Distance     float64 `json:"distance"`
+
+	DeltaCa           int     `json:"delta_ca"`
+	DeltaCe           int     `json:"delta_ce"`
+	DeltaInstability  float64 `json:"delta_instability"`
+	DeltaAbstractness float64 `json:"delta_abstractness"`
+	DeltaDistance     float64 `json:"delta_distance"`
+
+	// Change is Improved, Worsened, or empty if only neutral metrics (Ca, I, A) changed
+	Change string `json:"change,omitempty"`
+}
+
+// Result is the comparison of two reports
+type Result struct {
+	OldModule string `json:"old_module"`
+	NewModule string `json:"new_module"`
+
+	// Changed lists the packages with any changed metric, sorted by name
+	Changed []PackageDelta `json:"changed"`
+
+	// Unchanged is the number of packages present in both reports with equal metrics
+	Unchanged int `json:"unchanged"`
+
+	Added   []reporter.JSONPackage `json:"added"`
+	Removed []reporter.JSONPackage `json:"removed"`
+}
+
+// Compare compares the packages of two reports, matching them by name
+func Compare(before, after *reporter.JSONReport) *Result {
+	result := &Result{
+		OldModule: before.Module,
+		NewModule: after.Module,
+		Changed:   []PackageDelta{},
+		Added:     []reporter.JSONPackage{},
+		Removed:   []reporter.JSONPackage{},
+	}
+
+	oldPackages := make(map[string]reporter.JSONPackage, len(before.Packages))
+	for _, pkg := range before.Packages {
+		oldPackages[pkg.Name] = pkg
+	}
+	newPackages := make(map[string]bool, len(after.Packages))
+
+	for _, pkg := range after.Packages {
+		newPackages[pkg.Name] = true
+		old, ok := oldPackages[pkg.Name]
+		if !ok {
+			result.Added = append(result.Added, pkg)
+			continue
+		}
+		delta := comparePackage(old, pkg)
+		if delta == nil {
+			result.Unchanged++
+			continue
+		}
+		result.Changed = append(result.Changed, *delta)
+	}
+	for _, pkg := range before.Packages {
+		if !newPackages[pkg.Name] {
+			result.Removed = append(result.Removed, pkg)
+		}
+	}
+
+	sort.Slice(result.Changed, func(i, j int) bool { return result.Changed[i].Name < result.Changed[j].Name })
+	sort.Slice(result.Added, func(i, j int) bool { return result.Added[i].Name < result.Added[j].Name })
+	sort.Slice(result.Removed, func(i, j int) bool { return result.Removed[i].Name < result.Removed[j].Name })
+	return result
+}
+
+// comparePackage returns the change of a package, or nil if its metrics are equal
+func comparePackage(old, cur reporter.JSONPackage) *PackageDelta {
+	delta := &PackageDelta{
+		Name:              cur.Name,
+		Ca:                cur.Ca,
+		Ce:                cur.Ce,
+		Instability:       cur.Instability,
+		Abstractness:      cur.Abstractness,
+		Distance:          cur.Distance,
+		DeltaCa:           cur.Ca - old.Ca,
+		DeltaCe:           cur.Ce - old.Ce,
+		DeltaInstability:  significant(cur.Instability - old.Instability),
+		DeltaAbstractness: significant(cur.Abstractness - old.Abstractness),
+		DeltaDistance:     significant(cur.Distance - old.Distance),
+	}
+	if delta.DeltaCa == 0 && delta.DeltaCe == 0 && delta.DeltaInstability == 0 &&
+		delta.DeltaAbstractness == 0 && delta.DeltaDistance == 0 {
+		return nil
+	}
+
+	switch {
+	case delta.DeltaDistance < 0, delta.DeltaDistance == 0 && delta.DeltaCe < 0:
+		delta.Change = Improved
+	case delta.DeltaDistance > 0, delta.DeltaDistance == 0 && delta.DeltaCe > 0:
+		delta.Change = Worsened
+	}
+	return delta
+}
+
+// significant returns d, or zero if it is smaller than Epsilon
+func significant(d float64) float64 {
+	if math.Abs(d) < Epsilon {
+		return 0
+	}
+	return d
+}
+
+// Count returns the number of changed packages with the given verdict
+func (r *Result) Count(change string) int {
+	var n int
+	for _, delta := range r.Changed {
+		if delta.Change == change {
+			n++
+		}
+	}
+	return n
+}
+
+// WriteJSON writes the result as indented JSON
+func (r *Result) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// WriteText writes the result as a table of changed packages followed by the
+// added and removed packages and a summary line
+func (r *Result) WriteText(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	if r.OldModule != r.NewModule {
+		fmt.Fprintf(tw, "MODULE: %s -> %s\n\n", r.OldModule, r.NewModule)
+	} else {
+		fmt.Fprintf(tw, "MODULE: %s\n\n", r.NewModule)
+	}
+
+	if len(r.Changed) > 0 {
+		fmt.Fprintln(tw, "PACKAGE\tCa\tCe\tI\tA\tD\tChange")
+		fmt.Fprintln(tw, "-------\t--\t--\t-\t-\t-\t------")
+		for _, d := range r.Changed {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", d.Name,
+				intCell(d.Ca, d.DeltaCa), intCell(d.Ce, d.DeltaCe),
+				floatCell(d.Instability, d.DeltaInstability), floatCell(d.Abstractness, d.DeltaAbstractness),
+				floatCell(d.Distance, d.DeltaDistance), d.Change)
+		}
+	} else {
+		fmt.Fprintln(tw, "No changed packages.")
+	}
+
+	for _, section := range []struct {
+		title    string
+		packages []reporter.JSONPackage
+	}{{"ADDED", r.Added}, {"REMOVED", r.Removed}} {
+		if len(section.packages) == 0 {
+			continue
+		}
+		fmt.Fprintf(tw, "\n%s\tCa\tCe\tI\tA\tD\n", section.title)
+		for _, pkg := range section.packages {
+			fmt.Fprintf(tw, "%s\t%d\t%d\t%.2f\t%.2f\t%.2f\n",
+				pkg.Name, pkg.Ca, pkg.Ce, pkg.Instability, pkg.Abstractness, pkg.Distance)
+		}
+	}
+
+	fmt.Fprintf(tw, "\n%d improved, %d worsened, %d changed otherwise, %d unchanged, %d added, %d removed\n",
+		r.Count(Improved), r.Count(Worsened), r.Count(""), r.Unchanged, len(r.Added), len(r.Removed))
+	return tw.Flush()
+}
+
+// intCell formats an integer metric with its change, if any
+func intCell(value, delta int) string {
+	if delta == 0 {
+		return fmt.Sprintf("%d", value)
+	}
+	return fmt.Sprintf("%d (%+d)", value, delta)
+}
+
+// floatCell formats a ratio metric with its change, if any
+func floatCell(value, delta float64) string {
+	if delta == 0 {
+		return fmt.Sprintf("%.2f", value)
+	}
+	return fmt.Sprintf("%.2f (%+.2f)", value, delta)
+}
@@ -0,0 +1,66 @@
+package diff
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/alkbt/aid-metrics/pkg/reporter"
+)
+
+func TestCompare(t *testing.T) {
+	before := &reporter.JSONReport{
+		Module: "example.com/shop",
+		Packages: []reporter.JSONPackage{
+			{Name: "api", Ca: 0, Ce: 2, Instability: 1, Distance: 0},
+			{Name: "billing", Ca: 1, Ce: 1, Instability: 0.5, Distance: 0.5},
+			{Name: "legacy", Ca: 1, Distance: 1},
+			{Name: "store", Ca: 2, Ce: 1, Instability: 0.33, Distance: 0.67},
+			{Name: "util", Ca: 3, Distance: 1},
+		},
+	}
+	after := &reporter.JSONReport{
+		Module: "example.com/shop",
+		Packages: []reporter.JSONPackage{
+			{Name: "api", Ca: 0, Ce: 3, Instability: 1, Distance: 0},
+			{Name: "billing", Ca: 1, Ce: 1, Instability: 0.5, Abstractness: 0.25, Distance: 0.25},
+			{Name: "payments", Ca: 1, Ce: 1, Instability: 0.5, Distance: 0.5},
+			{Name: "store", Ca: 3, Ce: 1, Instability: 0.25, Distance: 0.75},
+			{Name: "util", Ca: 3, Distance: 1.001},
+		},
+	}
+
+	result := Compare(before, after)
+
+	want := map[string]string{"api": Worsened, "billing": Improved, "store": Worsened}
+	if len(result.Changed) != len(want) {
+		t.Fatalf("expected %d changed packages, got %+v", len(want), result.Changed)
+	}
+	for _, delta := range result.Changed {
+		if delta.Change != want[delta.Name] {
+			t.Errorf("%s: got change %q, want %q", delta.Name, delta.Change, want[delta.Name])
+		}
+	}
+	if result.Unchanged != 1 {
+		t.Errorf("expected util to be unchanged within epsilon, got %d unchanged", result.Unchanged)
+	}
+	if len(result.Added) != 1 || result.Added[0].Name != "payments" {
+		t.Errorf("unexpected added packages %+v", result.Added)
+	}
+	if len(result.Removed) != 1 || result.Removed[0].Name != "legacy" {
+		t.Errorf("unexpected removed packages %+v", result.Removed)
+	}
+
+	var buf bytes.Buffer
+	if err := result.WriteText(&buf); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{
+		"billing  1       1       0.50          0.25 (+0.25)  0.25 (-0.25)  improved",
+		"1 improved, 2 worsened, 0 changed otherwise, 1 unchanged, 1 added, 1 removed",
+	} {
+		if !strings.Contains(buf.String(), line) {
+			t.Errorf("expected output to contain %q, got:\n%s", line, buf.String())
+		}
+	}
+}
@@ -0,0 +1,288 @@
+// Package distributed splits the analysis of extremely large modules over worker
+// processes, possibly running on different machines.
+//
+// A coordinator discovers the packages, hands them out to workers in shards and
+// merges the per-package results (edge lists and type counts) it gets back, so
+// coupling and all derived metrics are calculated centrally over the whole module.
+// Every worker must have the same checkout of the module. Coordinator and workers
+// talk gRPC (see distributedpb/distributed.proto), over TLS unless both are on
+// the same host; every call carries a bearer token that the coordinator
+// validates before it hands out work or accepts results.
+package distributed
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/alkbt/aid-metrics/pkg/analyzer"
+	pb "github.com/alkbt/aid-metrics/pkg/distributed/distributedpb"
+	"github.com/alkbt/aid-metrics/pkg/server"
+)
+
+// DefaultLeaseTimeout is how long a worker may hold a shard before it is handed out again
+const DefaultLeaseTimeout = 10 * time.Minute
+
+// maxMessageSize bounds the size of a shard result. The results of a shard of
+// large packages easily exceed the 4 MiB default of gRPC.
+const maxMessageSize = 256 << 20
+
+// Coordinator hands out shards of packages and collects their results.
+// It implements the Coordinator gRPC service.
+type Coordinator struct {
+	pb.UnimplementedCoordinatorServer
+
+	// LeaseTimeout overrides DefaultLeaseTimeout if set
+	LeaseTimeout time.Duration
+
+	// Auth validates the bearer token of every call. If nil, any caller that
+	// can reach the listener is served, which is only safe on a loopback address.
+	Auth server.Authenticator
+
+	// TLS, if set, secures the connections of workers, which is required for
+	// the tokens to stay secret on the network
+	TLS *tls.Config
+
+	mu        sync.Mutex
+	shards    [][]string
+	leases    map[int]time.Time // Shard -> lease deadline
+	results   map[int][]analyzer.PackageResult
+	err       error
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewCoordinator creates a coordinator for the given packages, split into shards
+// of at most shardSize packages.
+func NewCoordinator(packages []string, shardSize int) *Coordinator {
+	if shardSize <= 0 {
+		shardSize = 50
+	}
+
+	c := &Coordinator{
+		leases:  make(map[int]time.Time),
+		results: make(map[int][]analyzer.PackageResult),
+		done:    make(chan struct{}),
+	}
+	for start := 0; start < len(packages); start += shardSize {
+		end := min(start+shardSize, len(packages))
+		c.shards = append(c.shards, packages[start:end])
+	}
+	if len(c.shards) == 0 {
+		close(c.done)
+	}
+	return c
+}
+
+// NextShard hands out the next shard that is neither analyzed nor leased.
+// Shards whose lease has expired are handed out again.
+func (c *Coordinator) NextShard(ctx context.Context, req *pb.ShardRequest) (*pb.Shard, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.err != nil || len(c.results) == len(c.shards) {
+		return &pb.Shard{Done: true}, nil
+	}
+
+	now := time.Now()
+	for id, packages := range c.shards {
+		if _, analyzed := c.results[id]; analyzed {
+			continue
+		}
+		if deadline, leased := c.leases[id]; leased && now.Before(deadline) {
+			continue
+		}
+		c.leases[id] = now.Add(c.leaseTimeout())
+		return &pb.Shard{Id: int32(id), Packages: packages}, nil
+	}
+
+	return &pb.Shard{Wait: true}, nil
+}
+
+// SubmitShard records the results of a shard. Results for a shard that has
+// already been analyzed by another worker are ignored.
+func (c *Coordinator) SubmitShard(ctx context.Context, result *pb.ShardResult) (*pb.SubmitResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	id := int(result.ShardId)
+	if id < 0 || id >= len(c.shards) {
+		return nil, status.Errorf(codes.InvalidArgument, "unknown shard %d", id)
+	}
+	if _, analyzed := c.results[id]; analyzed {
+		return &pb.SubmitResponse{}, nil
+	}
+
+	if result.Error != "" {
+		if c.err == nil {
+			c.err = fmt.Errorf("worker %s failed to analyze shard %d: %s", result.Worker, id, result.Error)
+		}
+		c.finish()
+		return &pb.SubmitResponse{}, nil
+	}
+
+	results := make([]analyzer.PackageResult, len(result.Results))
+	for i, r := range result.Results {
+		results[i] = analyzer.PackageResult{ID: r.Id, Data: r.Data}
+	}
+	delete(c.leases, id)
+	c.results[id] = results
+	if len(c.results) == len(c.shards) {
+		c.finish()
+	}
+	return &pb.SubmitResponse{}, nil
+}
+
+// Serve serves workers on the listener until every shard has been analyzed and
+// returns the results of all packages in shard order. The listener is closed on return.
+// Further options are passed on to the gRPC server.
+func (c *Coordinator) Serve(l net.Listener, opts ...grpc.ServerOption) ([]analyzer.PackageResult, error) {
+	opts = append(opts, grpc.UnaryInterceptor(c.authenticate), grpc.MaxRecvMsgSize(maxMessageSize))
+	if c.TLS != nil {
+		opts = append(opts, grpc.Creds(credentials.NewTLS(c.TLS)))
+	}
+	srv := grpc.NewServer(opts...)
+	pb.RegisterCoordinatorServer(srv, c)
+	go srv.Serve(l)
+
+	<-c.done
+	// Let calls in flight complete, so the last worker gets its acknowledgement
+	srv.GracefulStop()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err != nil {
+		return nil, c.err
+	}
+
+	var results []analyzer.PackageResult
+	for id := range c.shards {
+		results = append(results, c.results[id]...)
+	}
+	return results, nil
+}
+
+// authenticate rejects calls without a valid bearer token in their
+// authorization metadata before they reach the coordinator
+func (c *Coordinator) authenticate(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if c.Auth == nil {
+		return handler(ctx, req)
+	}
+
+	var token string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get("authorization"); len(values) > 0 {
+			token, _ = strings.CutPrefix(values[0], "Bearer ")
+		}
+	}
+	valid, err := c.Auth.Authenticate(ctx, strings.TrimSpace(token))
+	if err != nil {
+		return nil, status.Error(codes.Unavailable, "cannot verify token")
+	}
+	if !valid {
+		return nil, status.Error(codes.Unauthenticated, "missing or invalid token")
+	}
+	return handler(ctx, req)
+}
+
+// finish signals that the run is over. The caller must hold c.mu.
+func (c *Coordinator) finish() {
+	c.closeOnce.Do(func() { close(c.done) })
+}
+
+// leaseTimeout returns the effective lease timeout
+func (c *Coordinator) leaseTimeout() time.Duration {
+	if c.LeaseTimeout > 0 {
+		return c.LeaseTimeout
+	}
+	return DefaultLeaseTimeout
+}
+
+// WorkerOptions configures the connection of a worker to the coordinator
+type WorkerOptions struct {
+	// Token is the bearer token sent with every call
+	Token string
+
+	// TLS, if set, secures the connection; it must be set unless the
+	// coordinator runs on the same host
+	TLS *tls.Config
+}
+
+// RunWorker connects to the coordinator at addr and analyzes the shards it hands out
+// with the given analyzer until the coordinator reports that all work is done.
+func RunWorker(addr, name string, a *analyzer.ModuleAnalyzer, opts WorkerOptions) error {
+	transport := insecure.NewCredentials()
+	if opts.TLS != nil {
+		transport = credentials.NewTLS(opts.TLS)
+	}
+	conn, err := grpc.NewClient(addr,
+		grpc.WithTransportCredentials(transport),
+		grpc.WithPerRPCCredentials(bearerToken(opts.Token)),
+		grpc.WithDefaultCallOptions(grpc.MaxCallSendMsgSize(maxMessageSize)))
+	if err != nil {
+		return fmt.Errorf("failed to connect to coordinator: %w", err)
+	}
+	defer conn.Close()
+	client := pb.NewCoordinatorClient(conn)
+
+	ctx := context.Background()
+	served := false
+	for {
+		shard, err := client.NextShard(ctx, &pb.ShardRequest{Worker: name})
+		if err != nil {
+			// The coordinator stops serving once all shards are in
+			if served && status.Code(err) == codes.Unavailable {
+				return nil
+			}
+			return fmt.Errorf("failed to get shard: %w", err)
+		}
+		served = true
+		if shard.Done {
+			return nil
+		}
+		if shard.Wait {
+			time.Sleep(time.Second)
+			continue
+		}
+
+		result := &pb.ShardResult{ShardId: shard.Id, Worker: name}
+		results, err := a.AnalyzeShard(shard.Packages)
+		if err != nil {
+			result.Error = err.Error()
+		}
+		for _, r := range results {
+			result.Results = append(result.Results, &pb.PackageResult{Id: r.ID, Data: r.Data})
+		}
+
+		if _, err := client.SubmitShard(ctx, result); err != nil {
+			return fmt.Errorf("failed to submit shard %d: %w", shard.Id, err)
+		}
+	}
+}
+
+// bearerToken sends a token in the authorization metadata of every call
+type bearerToken string
+
+// GetRequestMetadata implements credentials.PerRPCCredentials
+func (t bearerToken) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	if t == "" {
+		return nil, nil
+	}
+	return map[string]string{"authorization": "Bearer " + string(t)}, nil
+}
+
+// RequireTransportSecurity implements credentials.PerRPCCredentials
+func (t bearerToken) RequireTransportSecurity() bool {
+	return false
+}
@@ -0,0 +1,163 @@
+package distributed
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/alkbt/aid-metrics/pkg/distributed/distributedpb"
+	"github.com/alkbt/aid-metrics/pkg/server"
+)
+
+func TestCoordinatorShards(t *testing.T) {
+	c := NewCoordinator([]string{"a", "b", "c"}, 2)
+	c.LeaseTimeout = time.Hour
+	ctx := context.Background()
+
+	first, err := c.NextShard(ctx, &pb.ShardRequest{Worker: "w1"})
+	if err != nil || len(first.Packages) != 2 {
+		t.Fatalf("expected a shard of 2 packages, got %+v (%v)", first, err)
+	}
+	second, err := c.NextShard(ctx, &pb.ShardRequest{Worker: "w2"})
+	if err != nil || len(second.Packages) != 1 {
+		t.Fatalf("expected a shard of 1 package, got %+v (%v)", second, err)
+	}
+	if third, err := c.NextShard(ctx, &pb.ShardRequest{Worker: "w3"}); err != nil || !third.Wait {
+		t.Fatalf("expected to wait while all shards are leased, got %+v (%v)", third, err)
+	}
+
+	// An expired lease is handed out again
+	c.leases[int(second.Id)] = time.Now().Add(-time.Second)
+	if retry, err := c.NextShard(ctx, &pb.ShardRequest{Worker: "w3"}); err != nil || retry.Id != second.Id {
+		t.Fatalf("expected the expired shard %d again, got %+v (%v)", second.Id, retry, err)
+	}
+
+	c.SubmitShard(ctx, &pb.ShardResult{ShardId: second.Id, Results: []*pb.PackageResult{{Id: "c"}}})
+	c.SubmitShard(ctx, &pb.ShardResult{ShardId: first.Id, Results: []*pb.PackageResult{{Id: "a"}, {Id: "b"}}})
+
+	if done, err := c.NextShard(ctx, &pb.ShardRequest{Worker: "w1"}); err != nil || !done.Done {
+		t.Fatalf("expected done after all shards were submitted, got %+v (%v)", done, err)
+	}
+
+	// Results are returned in shard order, independent of submission order
+	var ids []string
+	for id := range c.shards {
+		for _, r := range c.results[id] {
+			ids = append(ids, r.ID)
+		}
+	}
+	if len(ids) != 3 || ids[0] != "a" || ids[1] != "b" || ids[2] != "c" {
+		t.Errorf("unexpected result order %v", ids)
+	}
+}
+
+func TestCoordinatorRequiresToken(t *testing.T) {
+	c := NewCoordinator([]string{"a"}, 1)
+	c.Auth = server.StaticToken("secret")
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	served := make(chan error, 1)
+	go func() {
+		_, err := c.Serve(l)
+		served <- err
+	}()
+
+	call := func(token string) (*pb.Shard, error) {
+		conn, err := grpc.NewClient(l.Addr().String(),
+			grpc.WithTransportCredentials(insecure.NewCredentials()),
+			grpc.WithPerRPCCredentials(bearerToken(token)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		return pb.NewCoordinatorClient(conn).NextShard(context.Background(), &pb.ShardRequest{Worker: "w"})
+	}
+
+	for _, token := range []string{"", "wrong"} {
+		if shard, err := call(token); status.Code(err) != codes.Unauthenticated {
+			t.Errorf("token %q: expected Unauthenticated, got %+v (%v)", token, shard, err)
+		}
+	}
+	c.mu.Lock()
+	_, leased := c.leases[0]
+	c.mu.Unlock()
+	if leased {
+		t.Error("shard leased to an unauthenticated caller")
+	}
+
+	shard, err := call("secret")
+	if err != nil || len(shard.Packages) != 1 {
+		t.Fatalf("expected a shard with the token, got %+v (%v)", shard, err)
+	}
+	c.SubmitShard(context.Background(), &pb.ShardResult{ShardId: shard.Id, Results: []*pb.PackageResult{{Id: "a"}}})
+	if err := <-served; err != nil {
+		t.Errorf("Serve: %v", err)
+	}
+}
+
+func TestCoordinatorTLS(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	roots := x509.NewCertPool()
+	roots.AddCert(cert)
+
+	c := NewCoordinator([]string{"a"}, 1)
+	c.Auth = server.StaticToken("secret")
+	c.TLS = &tls.Config{Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}}}
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go c.Serve(l)
+	defer c.finish()
+
+	call := func(transport credentials.TransportCredentials) (*pb.Shard, error) {
+		conn, err := grpc.NewClient(l.Addr().String(),
+			grpc.WithTransportCredentials(transport),
+			grpc.WithPerRPCCredentials(bearerToken("secret")))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		return pb.NewCoordinatorClient(conn).NextShard(context.Background(), &pb.ShardRequest{Worker: "w"})
+	}
+
+	if shard, err := call(insecure.NewCredentials()); err == nil {
+		t.Errorf("expected a plaintext connection to fail, got %+v", shard)
+	}
+	if shard, err := call(credentials.NewTLS(&tls.Config{RootCAs: roots})); err != nil || len(shard.Packages) != 1 {
+		t.Errorf("expected a shard over TLS, got %+v (%v)", shard, err)
+	}
+}
@@ -0,0 +1,398 @@
+// Protocol between the coordinator of a distributed analysis and its workers.
+//
+// Workers lease shards of packages with NextShard, analyze them in their own
+// checkout of the module and send the per-package results back with
+// SubmitShard. Every call carries the bearer token of the worker in the
+// authorization metadata.
+//
+// Regenerate the Go code with:
+//
+//	protoc --go_out=. --go_opt=paths=source_relative \
+//	  --go-grpc_out=. --go-grpc_opt=paths=source_relative distributed.proto
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.6
+// 	protoc        v5.29.3
+// source: distributed.proto
+
+package distributedpb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// ShardRequest asks the coordinator for work
+type ShardRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Identifies the requesting worker in logs
+	Worker        string `protobuf:"bytes,1,opt,name=worker,proto3" json:"worker,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ShardRequest) Reset() {
+	*x = ShardRequest{}
+	mi := &file_distributed_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ShardRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ShardRequest) ProtoMessage() {}
+
+func (x *ShardRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_distributed_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ShardRequest.ProtoReflect.Descriptor instead.
+func (*ShardRequest) Descriptor() ([]byte, []int) {
+	return file_distributed_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *ShardRequest) GetWorker() string {
+	if x != nil {
+		return x.Worker
+	}
+	return ""
+}
+
+// Shard is a unit of work handed out to a worker
+type Shard struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Id    int32                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	// Import paths of the packages to analyze
+	Packages []string `protobuf:"bytes,2,rep,name=packages,proto3" json:"packages,omitempty"`
+	// Set when every remaining shard is leased to another worker. The worker
+	// should ask again later, as a lease may expire.
+	Wait bool `protobuf:"varint,3,opt,name=wait,proto3" json:"wait,omitempty"`
+	// Set when all shards have been analyzed and the worker can exit
+	Done          bool `protobuf:"varint,4,opt,name=done,proto3" json:"done,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Shard) Reset() {
+	*x = Shard{}
+	mi := &file_distributed_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Shard) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Shard) ProtoMessage() {}
+
+func (x *Shard) ProtoReflect() protoreflect.Message {
+	mi := &file_distributed_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Shard.ProtoReflect.Descriptor instead.
+func (*Shard) Descriptor() ([]byte, []int) {
+	return file_distributed_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Shard) GetId() int32 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *Shard) GetPackages() []string {
+	if x != nil {
+		return x.Packages
+	}
+	return nil
+}
+
+func (x *Shard) GetWait() bool {
+	if x != nil {
+		return x.Wait
+	}
+	return false
+}
+
+func (x *Shard) GetDone() bool {
+	if x != nil {
+		return x.Done
+	}
+	return false
+}
+
+// PackageResult is the analysis result of a single package
+type PackageResult struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Package ID
+	Id string `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	// Edge lists and type counts, encoded by the analyzer
+	Data          []byte `protobuf:"bytes,2,opt,name=data,proto3" json:"data,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PackageResult) Reset() {
+	*x = PackageResult{}
+	mi := &file_distributed_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PackageResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PackageResult) ProtoMessage() {}
+
+func (x *PackageResult) ProtoReflect() protoreflect.Message {
+	mi := &file_distributed_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PackageResult.ProtoReflect.Descriptor instead.
+func (*PackageResult) Descriptor() ([]byte, []int) {
+	return file_distributed_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *PackageResult) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *PackageResult) GetData() []byte {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+// ShardResult carries the results of a shard back to the coordinator
+type ShardResult struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	ShardId int32                  `protobuf:"varint,1,opt,name=shard_id,json=shardId,proto3" json:"shard_id,omitempty"`
+	Worker  string                 `protobuf:"bytes,2,opt,name=worker,proto3" json:"worker,omitempty"`
+	Results []*PackageResult       `protobuf:"bytes,3,rep,name=results,proto3" json:"results,omitempty"`
+	// Analysis error of the shard, if any. Analysis errors are deterministic,
+	// so a failed shard fails the whole run.
+	Error         string `protobuf:"bytes,4,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ShardResult) Reset() {
+	*x = ShardResult{}
+	mi := &file_distributed_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ShardResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ShardResult) ProtoMessage() {}
+
+func (x *ShardResult) ProtoReflect() protoreflect.Message {
+	mi := &file_distributed_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ShardResult.ProtoReflect.Descriptor instead.
+func (*ShardResult) Descriptor() ([]byte, []int) {
+	return file_distributed_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *ShardResult) GetShardId() int32 {
+	if x != nil {
+		return x.ShardId
+	}
+	return 0
+}
+
+func (x *ShardResult) GetWorker() string {
+	if x != nil {
+		return x.Worker
+	}
+	return ""
+}
+
+func (x *ShardResult) GetResults() []*PackageResult {
+	if x != nil {
+		return x.Results
+	}
+	return nil
+}
+
+func (x *ShardResult) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+// SubmitResponse acknowledges a ShardResult
+type SubmitResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SubmitResponse) Reset() {
+	*x = SubmitResponse{}
+	mi := &file_distributed_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SubmitResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SubmitResponse) ProtoMessage() {}
+
+func (x *SubmitResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_distributed_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SubmitResponse.ProtoReflect.Descriptor instead.
+func (*SubmitResponse) Descriptor() ([]byte, []int) {
+	return file_distributed_proto_rawDescGZIP(), []int{4}
+}
+
+var File_distributed_proto protoreflect.FileDescriptor
+
+const file_distributed_proto_rawDesc = "" +
+	"\n" +
+	"\x11distributed.proto\x12\x19aidmetrics.distributed.v1\"&\n" +
+	"\fShardRequest\x12\x16\n" +
+	"\x06worker\x18\x01 \x01(\tR\x06worker\"[\n" +
+	"\x05Shard\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x05R\x02id\x12\x1a\n" +
+	"\bpackages\x18\x02 \x03(\tR\bpackages\x12\x12\n" +
+	"\x04wait\x18\x03 \x01(\bR\x04wait\x12\x12\n" +
+	"\x04done\x18\x04 \x01(\bR\x04done\"3\n" +
+	"\rPackageResult\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x12\n" +
+	"\x04data\x18\x02 \x01(\fR\x04data\"\x9a\x01\n" +
+	"\vShardResult\x12\x19\n" +
+	"\bshard_id\x18\x01 \x01(\x05R\ashardId\x12\x16\n" +
+	"\x06worker\x18\x02 \x01(\tR\x06worker\x12B\n" +
+	"\aresults\x18\x03 \x03(\v2(.aidmetrics.distributed.v1.PackageResultR\aresults\x12\x14\n" +
+	"\x05error\x18\x04 \x01(\tR\x05error\"\x10\n" +
+	"\x0eSubmitResponse2\xc7\x01\n" +
+	"\vCoordinator\x12V\n" +
+	"\tNextShard\x12'.aidmetrics.distributed.v1.ShardRequest\x1a .aidmetrics.distributed.v1.Shard\x12`\n" +
+	"\vSubmitShard\x12&.aidmetrics.distributed.v1.ShardResult\x1a).aidmetrics.distributed.v1.SubmitResponseB<Z:github.com/alkbt/aid-metrics/pkg/distributed/distributedpbb\x06proto3"
+
+var (
+	file_distributed_proto_rawDescOnce sync.Once
+	file_distributed_proto_rawDescData []byte
+)
+
+func file_distributed_proto_rawDescGZIP() []byte {
+	file_distributed_proto_rawDescOnce.Do(func() {
+		file_distributed_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_distributed_proto_rawDesc), len(file_distributed_proto_rawDesc)))
+	})
+	return file_distributed_proto_rawDescData
+}
+
+var file_distributed_proto_msgTypes = make([]protoimpl.MessageInfo, 5)
+var file_distributed_proto_goTypes = []any{
+	(*ShardRequest)(nil),   // 0: aidmetrics.distributed.v1.ShardRequest
+	(*Shard)(nil),          // 1: aidmetrics.distributed.v1.Shard
+	(*PackageResult)(nil),  // 2: aidmetrics.distributed.v1.PackageResult
+	(*ShardResult)(nil),    // 3: aidmetrics.distributed.v1.ShardResult
+	(*SubmitResponse)(nil), // 4: aidmetrics.distributed.v1.SubmitResponse
+}
+var file_distributed_proto_depIdxs = []int32{
+	2, // 0: aidmetrics.distributed.v1.ShardResult.results:type_name -> aidmetrics.distributed.v1.PackageResult
+	0, // 1: aidmetrics.distributed.v1.Coordinator.NextShard:input_type -> aidmetrics.distributed.v1.ShardRequest
+	3, // 2: aidmetrics.distributed.v1.Coordinator.SubmitShard:input_type -> aidmetrics.distributed.v1.ShardResult
+	1, // 3: aidmetrics.distributed.v1.Coordinator.NextShard:output_type -> aidmetrics.distributed.v1.Shard
+	4, // 4: aidmetrics.distributed.v1.Coordinator.SubmitShard:output_type -> aidmetrics.distributed.v1.SubmitResponse
+	3, // [3:5] is the sub-list for method output_type
+	1, // [1:3] is the sub-list for method input_type
+	1, // [1:1] is the sub-list for extension type_name
+	1, // [1:1] is the sub-list for extension extendee
+	0, // [0:1] is the sub-list for field type_name
+}
+
+func init() { file_distributed_proto_init() }
+func file_distributed_proto_init() {
+	if File_distributed_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_distributed_proto_rawDesc), len(file_distributed_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   5,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_distributed_proto_goTypes,
+		DependencyIndexes: file_distributed_proto_depIdxs,
+		MessageInfos:      file_distributed_proto_msgTypes,
+	}.Build()
+	File_distributed_proto = out.File
+	file_distributed_proto_goTypes = nil
+	file_distributed_proto_depIdxs = nil
+}
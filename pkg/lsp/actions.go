@@ -0,0 +1,303 @@
+package lsp
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// Code action kinds offered by the server
+const (
+	kindQuickFix = "quickfix"
+	kindExtract  = "refactor.extract"
+)
+
+// codeActionParams are the parameters of textDocument/codeAction. Diagnostics
+// stay raw, as those of other servers may not decode into diagnostic.
+type codeActionParams struct {
+	TextDocument textDocumentIdentifier `json:"textDocument"`
+	Range        textRange              `json:"range"`
+	Context      struct {
+		Diagnostics []json.RawMessage `json:"diagnostics"`
+	} `json:"context"`
+}
+
+// codeAction is a change the client offers to apply. Actions whose edit is
+// expensive to compute carry Data instead, and get their edit in codeAction/resolve.
+type codeAction struct {
+	Title       string         `json:"title"`
+	Kind        string         `json:"kind"`
+	Diagnostics []diagnostic   `json:"diagnostics,omitempty"`
+	Edit        *workspaceEdit `json:"edit,omitempty"`
+	Data        *extractData   `json:"data,omitempty"`
+}
+
+// extractData identifies the interfaces an extract action resolves to
+type extractData struct {
+	Consumer   string `json:"consumer"`
+	Dependency string `json:"dependency"`
+}
+
+// workspaceEdit is a list of file creations and document edits, applied in order
+type workspaceEdit struct {
+	DocumentChanges []any `json:"documentChanges"`
+}
+
+// createFile creates a file unless it exists
+type createFile struct {
+	Kind    string            `json:"kind"`
+	URI     string            `json:"uri"`
+	Options createFileOptions `json:"options"`
+}
+
+// createFileOptions control what happens if the file to create exists
+type createFileOptions struct {
+	IgnoreIfExists bool `json:"ignoreIfExists"`
+}
+
+// textDocumentEdit is a list of edits of one document, in any version
+type textDocumentEdit struct {
+	TextDocument versionedTextDocumentIdentifier `json:"textDocument"`
+	Edits        []textEdit                      `json:"edits"`
+}
+
+// versionedTextDocumentIdentifier names a document; a nil version is any version
+type versionedTextDocumentIdentifier struct {
+	URI     string `json:"uri"`
+	Version *int   `json:"version"`
+}
+
+// textEdit replaces a range of a document
+type textEdit struct {
+	Range   textRange `json:"range"`
+	NewText string    `json:"newText"`
+}
+
+// codeActions returns the actions for a range of a document: accepting the
+// findings of the package diagnosed there as intentional, and extracting the
+// methods the package calls on the types of an imported module package into
+// interfaces of its own. Extracting needs the package type-checked, so it is
+// only offered to clients resolving edits lazily (see resolveCodeAction).
+func (s *Server) codeActions(params codeActionParams) []codeAction {
+	id, filePath := s.packageOf(params.TextDocument.URI)
+	s.mu.Lock()
+	metrics := s.metrics
+	resolveEdits := s.resolveEdits
+	s.mu.Unlock()
+	if metrics == nil || id == "" {
+		return nil
+	}
+	pkg, ok := metrics.Packages[id]
+	if !ok {
+		return nil
+	}
+
+	actions := []codeAction{}
+	allowed := make(map[string]bool)
+	for _, raw := range params.Context.Diagnostics {
+		var d diagnostic
+		if err := json.Unmarshal(raw, &d); err != nil || d.Source != source || d.Code == "" || allowed[d.Code] {
+			continue
+		}
+		allowed[d.Code] = true
+		if edit := allowEdit(filePath, d.Code); edit != nil {
+			actions = append(actions, codeAction{
+				Title:       fmt.Sprintf("Accept %s findings of %s as intentional (//aid-metrics:allow in doc.go)", d.Code, pkg.Name),
+				Kind:        kindQuickFix,
+				Diagnostics: []diagnostic{d},
+				Edit:        edit,
+			})
+		}
+	}
+
+	if !resolveEdits {
+		return actions
+	}
+	for _, file := range s.packageFiles(id) {
+		if file.path != filePath {
+			continue
+		}
+		for _, importPath := range sortedNames(file.imports) {
+			dep, ok := metrics.Packages[importPath]
+			if !ok || importPath == id || !overlaps(file.imports[importPath], params.Range) {
+				continue
+			}
+			actions = append(actions, codeAction{
+				Title: fmt.Sprintf("Extract interfaces for the %s types used here into %s", dep.Name, pkg.Name),
+				Kind:  kindExtract,
+				Data:  &extractData{Consumer: id, Dependency: importPath},
+			})
+		}
+	}
+	return actions
+}
+
+// resolveCodeAction computes the edit of an action returned without one
+func (s *Server) resolveCodeAction(action codeAction) (codeAction, error) {
+	if action.Data == nil {
+		return action, nil
+	}
+	edit, names := s.extractInterfaces(action.Data.Consumer, action.Data.Dependency)
+	if edit == nil {
+		return action, fmt.Errorf("no interfaces to extract from %s", action.Data.Dependency)
+	}
+	action.Title += " (" + strings.Join(names, ", ") + ")"
+	action.Edit = edit
+	return action, nil
+}
+
+// allowEdit returns the edit adding an allow annotation for a finding ID to the
+// doc.go file of the package of a Go file, created if the package has none
+func allowEdit(filePath, findingID string) *workspaceEdit {
+	annotation := "//aid-metrics:allow=" + findingID + "\n"
+	docPath := filepath.Join(filepath.Dir(filePath), "doc.go")
+	fset := token.NewFileSet()
+	if doc, err := parser.ParseFile(fset, docPath, nil, parser.PackageClauseOnly); err == nil {
+		line := fset.Position(doc.Package).Line - 1
+		return insertEdit(docPath, position{Line: line}, annotation, false)
+	}
+	file, err := parser.ParseFile(fset, filePath, nil, parser.PackageClauseOnly)
+	if err != nil {
+		return nil
+	}
+	return insertEdit(docPath, position{}, annotation+"package "+file.Name.Name+"\n", true)
+}
+
+// insertEdit returns the edit inserting text at a position of a file, creating
+// the file first if create is set
+func insertEdit(filePath string, at position, text string, create bool) *workspaceEdit {
+	uri := fileURI(filePath)
+	edit := &workspaceEdit{}
+	if create {
+		edit.DocumentChanges = append(edit.DocumentChanges, createFile{Kind: "create", URI: uri, Options: createFileOptions{IgnoreIfExists: true}})
+	}
+	edit.DocumentChanges = append(edit.DocumentChanges, textDocumentEdit{
+		TextDocument: versionedTextDocumentIdentifier{URI: uri},
+		Edits:        []textEdit{{Range: textRange{Start: at, End: at}, NewText: text}},
+	})
+	return edit
+}
+
+// extractInterfaces returns the edit creating <dependency>_interfaces.go in the
+// consumer package, declaring for every concrete type of the dependency the
+// consumer calls methods on an interface of those methods, and the names of the
+// interfaces. It returns nil if there is nothing to extract or the file exists.
+func (s *Server) extractInterfaces(consumer, dependency string) (*workspaceEdit, []string) {
+	cfg := &packages.Config{
+		Mode: packages.NeedName | packages.NeedFiles | packages.NeedSyntax | packages.NeedImports | packages.NeedDeps | packages.NeedTypes | packages.NeedTypesInfo,
+		Dir:  s.root,
+	}
+	pkgs, err := packages.Load(cfg, consumer)
+	if err != nil || len(pkgs) != 1 || pkgs[0].Types == nil || pkgs[0].TypesInfo == nil || len(pkgs[0].GoFiles) == 0 {
+		return nil, nil
+	}
+	pkg := pkgs[0]
+
+	// Methods called on each concrete type of the dependency
+	used := make(map[string]map[string]*types.Signature)
+	for _, sel := range pkg.TypesInfo.Selections {
+		if sel.Kind() != types.MethodVal {
+			continue
+		}
+		recv := sel.Recv()
+		if ptr, ok := recv.(*types.Pointer); ok {
+			recv = ptr.Elem()
+		}
+		named, ok := recv.(*types.Named)
+		if !ok || named.Obj().Pkg() == nil || named.Obj().Pkg().Path() != dependency || types.IsInterface(named) {
+			continue
+		}
+		fn := sel.Obj().(*types.Func)
+		if used[named.Obj().Name()] == nil {
+			used[named.Obj().Name()] = make(map[string]*types.Signature)
+		}
+		used[named.Obj().Name()][fn.Name()] = fn.Type().(*types.Signature)
+	}
+	if len(used) == 0 {
+		return nil, nil
+	}
+
+	depName := path.Base(dependency)
+	filePath := filepath.Join(filepath.Dir(pkg.GoFiles[0]), depName+"_interfaces.go")
+	if _, err := os.Stat(filePath); err == nil {
+		return nil, nil
+	}
+
+	imports := make(map[string]string)
+	qualifier := func(p *types.Package) string {
+		if p == pkg.Types {
+			return ""
+		}
+		imports[p.Path()] = p.Name()
+		return p.Name()
+	}
+	var names []string
+	var decls strings.Builder
+	for _, typeName := range sortedNames(used) {
+		name := typeName
+		if pkg.Types.Scope().Lookup(name) != nil {
+			name = strings.ToUpper(depName[:1]) + depName[1:] + typeName
+			if pkg.Types.Scope().Lookup(name) != nil {
+				continue
+			}
+		}
+		names = append(names, name)
+		fmt.Fprintf(&decls, "\n// %s is the part of %s.%s that %s uses, so it can depend on an\n// interface of its own instead of the concrete type.\ntype %s interface {\n",
+			name, depName, typeName, pkg.Name, name)
+		for _, method := range sortedNames(used[typeName]) {
+			signature := strings.TrimPrefix(types.TypeString(used[typeName][method], qualifier), "func")
+			fmt.Fprintf(&decls, "\t%s%s\n", method, signature)
+		}
+		decls.WriteString("}\n")
+	}
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	var src strings.Builder
+	fmt.Fprintf(&src, "package %s\n", pkg.Name)
+	if len(imports) > 0 {
+		src.WriteString("\nimport (\n")
+		for _, importPath := range sortedNames(imports) {
+			if imports[importPath] == path.Base(importPath) {
+				fmt.Fprintf(&src, "\t%q\n", importPath)
+			} else {
+				fmt.Fprintf(&src, "\t%s %q\n", imports[importPath], importPath)
+			}
+		}
+		src.WriteString(")\n")
+	}
+	src.WriteString(decls.String())
+	formatted, err := format.Source([]byte(src.String()))
+	if err != nil {
+		return nil, nil
+	}
+	return insertEdit(filePath, position{}, string(formatted), true), names
+}
+
+// overlaps reports whether two ranges share a position, ends included so that
+// an empty range (the cursor) at either end of a range touches it
+func overlaps(a, b textRange) bool {
+	before := func(p, q position) bool { return p.Line < q.Line || p.Line == q.Line && p.Character < q.Character }
+	return !before(a.End, b.Start) && !before(b.End, a.Start)
+}
+
+// sortedNames returns the keys of a map, sorted
+func sortedNames[V any](m map[string]V) []string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
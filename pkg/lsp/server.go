@@ -0,0 +1,523 @@
+// Package lsp implements a minimal language server for editors: it analyzes the
+// module when the client connects and again whenever a Go file or go.mod is saved,
+// and shows the metrics of the package being edited with their change since the
+// previous save, so the coupling impact of a new import is visible right away.
+//
+// The server speaks the Language Server Protocol over a pair of streams, usually
+// stdin and stdout (aid-metrics lsp). It supports:
+//
+//	textDocument/hover               metrics of the file's package, with deltas
+//	textDocument/codeAction          allow annotations for findings, and the
+//	                                 extraction of interfaces (codeAction/resolve)
+//	textDocument/publishDiagnostics  findings at the package clause, and the
+//	                                 change of Ca, Ce, I and D at new imports
+//
+// Analyses after a save are told the files saved since the previous one, so
+// they can be incremental and only do the work of the changed packages and
+// their dependents.
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/alkbt/aid-metrics/pkg/models"
+)
+
+// source names the server in the diagnostics it publishes
+const source = "aid-metrics"
+
+// AnalyzeFunc analyzes the module served. changedFiles lists the paths of the
+// files saved since the previous analysis; it is nil for the first analysis,
+// which must be a full one.
+type AnalyzeFunc func(ctx context.Context, changedFiles []string) (*models.ModuleMetrics, error)
+
+// Server answers the requests of one client. The metrics of the latest
+// successful analysis are kept together with those of the one before, which
+// the deltas are computed against.
+type Server struct {
+	root    string // Module root directory
+	module  string // Module path
+	analyze AnalyzeFunc
+
+	conn    *conn
+	trigger chan struct{} // Pending analysis request, coalescing saves
+
+	changedMu sync.Mutex
+	changed   []string // Files saved since the latest analysis started
+
+	mu           sync.Mutex
+	metrics      *models.ModuleMetrics
+	previous     *models.ModuleMetrics
+	published    map[string]bool // URIs holding diagnostics
+	resolveEdits bool            // The client resolves the edits of code actions lazily
+}
+
+// New creates a server for the module with the given root directory and module path
+func New(root, module string, analyze AnalyzeFunc) *Server {
+	return &Server{
+		root:      root,
+		module:    module,
+		analyze:   analyze,
+		trigger:   make(chan struct{}, 1),
+		published: make(map[string]bool),
+	}
+}
+
+// Serve reads requests from r and writes responses and notifications to w
+// until the client sends exit, r ends or the context is done
+func (s *Server) Serve(ctx context.Context, r io.Reader, w io.Writer) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	s.conn = newConn(r, w)
+	go s.analysisLoop(ctx)
+
+	for {
+		msg, err := s.conn.read()
+		var parseErr *responseError
+		if errors.As(err, &parseErr) {
+			_ = s.conn.write(errorResponse{JSONRPC: "2.0", ID: json.RawMessage("null"), Error: *parseErr})
+			continue
+		}
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if msg.Method == "exit" {
+			return nil
+		}
+		if err := s.handle(msg); err != nil {
+			return err
+		}
+	}
+}
+
+// handle dispatches a message. Requests always get a response; notifications
+// the server does not know are ignored.
+func (s *Server) handle(msg *message) error {
+	var result any
+	var failure *responseError
+	switch msg.Method {
+	case "initialize":
+		var params initializeParams
+		if err := json.Unmarshal(msg.Params, &params); err == nil {
+			s.mu.Lock()
+			s.resolveEdits = slices.Contains(params.Capabilities.TextDocument.CodeAction.ResolveSupport.Properties, "edit")
+			s.mu.Unlock()
+		}
+		result = map[string]any{
+			"capabilities": map[string]any{
+				"hoverProvider": true,
+				"codeActionProvider": map[string]any{
+					"codeActionKinds": []string{kindQuickFix, kindExtract},
+					"resolveProvider": true,
+				},
+				"textDocumentSync": map[string]any{
+					"openClose": true,
+					"change":    0,
+					"save":      map[string]bool{"includeText": false},
+				},
+			},
+			"serverInfo": map[string]string{"name": source},
+		}
+	case "initialized":
+		s.requestAnalysis()
+	case "textDocument/didSave":
+		var params textDocumentParams
+		if err := json.Unmarshal(msg.Params, &params); err == nil && affectsMetrics(params.TextDocument.URI) {
+			if u, err := url.Parse(params.TextDocument.URI); err == nil && u.Scheme == "file" {
+				s.changedMu.Lock()
+				s.changed = append(s.changed, filepath.FromSlash(u.Path))
+				s.changedMu.Unlock()
+			}
+			s.requestAnalysis()
+		}
+	case "textDocument/hover":
+		var params hoverParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			failure = &responseError{Code: codeInvalidParams, Message: err.Error()}
+			break
+		}
+		if h := s.hover(params.TextDocument.URI); h != nil {
+			result = h
+		}
+	case "textDocument/codeAction":
+		var params codeActionParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			failure = &responseError{Code: codeInvalidParams, Message: err.Error()}
+			break
+		}
+		result = s.codeActions(params)
+	case "codeAction/resolve":
+		var action codeAction
+		if err := json.Unmarshal(msg.Params, &action); err != nil {
+			failure = &responseError{Code: codeInvalidParams, Message: err.Error()}
+			break
+		}
+		resolved, err := s.resolveCodeAction(action)
+		if err != nil {
+			failure = &responseError{Code: codeInvalidParams, Message: err.Error()}
+			break
+		}
+		result = resolved
+	case "shutdown":
+	default:
+		if msg.ID != nil {
+			failure = &responseError{Code: codeMethodNotFound, Message: "method not supported: " + msg.Method}
+		}
+	}
+
+	if msg.ID == nil {
+		return nil
+	}
+	if failure != nil {
+		return s.conn.write(errorResponse{JSONRPC: "2.0", ID: msg.ID, Error: *failure})
+	}
+	return s.conn.write(response{JSONRPC: "2.0", ID: msg.ID, Result: result})
+}
+
+// affectsMetrics reports whether saving a document can change the metrics
+func affectsMetrics(uri string) bool {
+	return strings.HasSuffix(uri, ".go") || strings.HasSuffix(uri, "/go.mod")
+}
+
+// requestAnalysis schedules an analysis. Requests arriving while one is pending
+// are merged into it.
+func (s *Server) requestAnalysis() {
+	select {
+	case s.trigger <- struct{}{}:
+	default:
+	}
+}
+
+// analysisLoop runs the requested analyses one at a time and publishes their results
+func (s *Server) analysisLoop(ctx context.Context) {
+	first := true
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.trigger:
+		}
+
+		s.changedMu.Lock()
+		changed := s.changed
+		s.changed = nil
+		s.changedMu.Unlock()
+		if first {
+			changed, first = nil, false
+		}
+		metrics, err := s.analyze(ctx, changed)
+		if err != nil {
+			if ctx.Err() == nil {
+				_ = s.conn.write(notification{JSONRPC: "2.0", Method: "window/logMessage",
+					Params: logMessageParams{Type: 1, Message: "aid-metrics analysis failed: " + err.Error()}})
+			}
+			continue
+		}
+		s.mu.Lock()
+		s.previous, s.metrics = s.metrics, metrics
+		s.mu.Unlock()
+		s.publishDiagnostics()
+	}
+}
+
+// packageOf returns the import path of the package of a document and the
+// document's path, or empty strings if the document is not in the module
+func (s *Server) packageOf(uri string) (string, string) {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" {
+		return "", ""
+	}
+	path := filepath.FromSlash(u.Path)
+	rel, err := filepath.Rel(s.root, filepath.Dir(path))
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", ""
+	}
+	if rel == "." {
+		return s.module, path
+	}
+	return s.module + "/" + filepath.ToSlash(rel), path
+}
+
+// hover describes the metrics of the document's package, nil if it was not analyzed
+func (s *Server) hover(uri string) *hover {
+	id, _ := s.packageOf(uri)
+	s.mu.Lock()
+	metrics, previous := s.metrics, s.previous
+	s.mu.Unlock()
+	if metrics == nil || id == "" {
+		return nil
+	}
+	pkg, ok := metrics.Packages[id]
+	if !ok {
+		return nil
+	}
+	var before *models.PackageMetrics
+	if previous != nil {
+		if p, ok := previous.Packages[id]; ok {
+			before = &p
+		}
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "**%s** (aid-metrics)\n\n", pkg.Name)
+	fmt.Fprintf(&b, "%s\n\n", metricLine(pkg, before))
+	if pkg.Zone != "" {
+		fmt.Fprintf(&b, "Zone: %s  \n", pkg.Zone)
+	}
+	fmt.Fprintf(&b, "Health: %d\n", pkg.Health)
+	if before != nil {
+		added, removed := difference(pkg.Dependencies, before.Dependencies), difference(before.Dependencies, pkg.Dependencies)
+		if len(added) > 0 {
+			fmt.Fprintf(&b, "\nNew dependencies: %s\n", strings.Join(added, ", "))
+		}
+		if len(removed) > 0 {
+			fmt.Fprintf(&b, "\nRemoved dependencies: %s\n", strings.Join(removed, ", "))
+		}
+	}
+	for _, finding := range packageFindings(metrics, pkg.Name) {
+		fmt.Fprintf(&b, "\n- %s %s: %s", finding.ID, finding.Severity, finding.Message)
+	}
+	return &hover{Contents: markupContent{Kind: "markdown", Value: strings.TrimRight(b.String(), "\n")}}
+}
+
+// metricLine formats Ca, Ce, I, A and D of a package, each with its change since
+// the previous analysis if there was one and it differs
+func metricLine(pkg models.PackageMetrics, before *models.PackageMetrics) string {
+	count := func(name string, now, then int) string {
+		if before == nil || now == then {
+			return fmt.Sprintf("%s %d", name, now)
+		}
+		return fmt.Sprintf("%s %d (%+d)", name, now, now-then)
+	}
+	ratio := func(name string, now, then float64) string {
+		if before == nil || fmt.Sprintf("%.2f", now) == fmt.Sprintf("%.2f", then) {
+			return fmt.Sprintf("%s %.2f", name, now)
+		}
+		return fmt.Sprintf("%s %.2f (%+.2f)", name, now, now-then)
+	}
+	var was models.PackageMetrics
+	if before != nil {
+		was = *before
+	}
+	return strings.Join([]string{
+		count("Ca", pkg.Ca, was.Ca),
+		count("Ce", pkg.Ce, was.Ce),
+		ratio("I", pkg.Instability, was.Instability),
+		ratio("A", pkg.Abstractness, was.Abstractness),
+		ratio("D", pkg.Distance, was.Distance),
+	}, " · ")
+}
+
+// publishDiagnostics publishes the diagnostics of every Go file of the packages
+// with findings or new dependencies, and clears those published before that no
+// longer apply
+func (s *Server) publishDiagnostics() {
+	s.mu.Lock()
+	metrics, previous := s.metrics, s.previous
+	s.mu.Unlock()
+
+	byURI := make(map[string][]diagnostic)
+	for id, pkg := range metrics.Packages {
+		findings := packageFindings(metrics, pkg.Name)
+		var added []string
+		var before *models.PackageMetrics
+		if previous != nil {
+			if p, ok := previous.Packages[id]; ok {
+				before = &p
+				added = difference(pkg.Dependencies, p.Dependencies)
+			}
+		}
+		if len(findings) == 0 && len(added) == 0 {
+			continue
+		}
+		for _, file := range s.packageFiles(id) {
+			diagnostics := fileDiagnostics(file, metrics, pkg, before, findings, added)
+			if len(diagnostics) > 0 {
+				byURI[fileURI(file.path)] = diagnostics
+			}
+		}
+	}
+
+	s.mu.Lock()
+	stale := s.published
+	s.published = make(map[string]bool, len(byURI))
+	for uri := range byURI {
+		s.published[uri] = true
+	}
+	s.mu.Unlock()
+
+	for uri := range stale {
+		if _, ok := byURI[uri]; !ok {
+			byURI[uri] = []diagnostic{}
+		}
+	}
+	uris := make([]string, 0, len(byURI))
+	for uri := range byURI {
+		uris = append(uris, uri)
+	}
+	sort.Strings(uris)
+	for _, uri := range uris {
+		_ = s.conn.write(notification{JSONRPC: "2.0", Method: "textDocument/publishDiagnostics",
+			Params: publishDiagnosticsParams{URI: uri, Diagnostics: byURI[uri]}})
+	}
+}
+
+// goFile is a Go file of a package with the positions diagnostics attach to
+type goFile struct {
+	path    string
+	clause  textRange            // The package clause
+	imports map[string]textRange // Import path -> import spec
+}
+
+// packageFiles parses the package clauses and imports of the Go files of a
+// package; files of external test packages and files that fail to parse are skipped
+func (s *Server) packageFiles(id string) []goFile {
+	dir := s.root
+	if rel, ok := strings.CutPrefix(id, s.module+"/"); ok {
+		dir = filepath.Join(s.root, filepath.FromSlash(rel))
+	} else if id != s.module {
+		return nil
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+
+	var files []goFile
+	fset := token.NewFileSet()
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".go") {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		file, err := parser.ParseFile(fset, path, nil, parser.ImportsOnly)
+		if err != nil || strings.HasSuffix(file.Name.Name, "_test") {
+			continue
+		}
+		f := goFile{path: path, clause: nodeRange(fset, file.Package, file.Name.End()), imports: make(map[string]textRange)}
+		for _, spec := range file.Imports {
+			if importPath, err := strconv.Unquote(spec.Path.Value); err == nil {
+				f.imports[importPath] = nodeRange(fset, spec.Pos(), spec.End())
+			}
+		}
+		files = append(files, f)
+	}
+	return files
+}
+
+// fileDiagnostics returns the diagnostics of a file: the findings of its package
+// at the package clause, and the change of the package's metrics at the imports
+// of the dependencies added since the previous analysis
+func fileDiagnostics(file goFile, metrics *models.ModuleMetrics, pkg models.PackageMetrics, before *models.PackageMetrics,
+	findings []models.Finding, added []string) []diagnostic {
+	var diagnostics []diagnostic
+	for _, finding := range findings {
+		diagnostics = append(diagnostics, diagnostic{
+			Range:    file.clause,
+			Severity: findingSeverity(finding.Severity),
+			Code:     finding.ID,
+			Source:   source,
+			Message:  finding.Message,
+		})
+	}
+	for _, name := range added {
+		r, ok := file.imports[importPath(metrics, name)]
+		if !ok {
+			continue
+		}
+		diagnostics = append(diagnostics, diagnostic{
+			Range:    r,
+			Severity: severityInformation,
+			Source:   source,
+			Message:  fmt.Sprintf("new dependency of %s: %s", pkg.Name, metricLine(pkg, before)),
+		})
+	}
+	return diagnostics
+}
+
+// packageFindings returns the findings about a package, by display name
+func packageFindings(metrics *models.ModuleMetrics, name string) []models.Finding {
+	var findings []models.Finding
+	for _, finding := range metrics.Findings {
+		if finding.Package == name {
+			findings = append(findings, finding)
+		}
+	}
+	return findings
+}
+
+// findingSeverity maps the severity of a finding to a diagnostic severity
+func findingSeverity(severity models.Severity) int {
+	switch severity {
+	case models.SeverityError:
+		return severityError
+	case models.SeverityWarning:
+		return severityWarning
+	case models.SeverityInfo:
+		return severityInformation
+	}
+	return severityHint
+}
+
+// importPath returns the import path of a package by display name: module
+// packages are named by their path relative to the module, other packages
+// and the module root are listed in the name table
+func importPath(metrics *models.ModuleMetrics, name string) string {
+	if path, ok := metrics.Names[name]; ok {
+		return path
+	}
+	for id, pkg := range metrics.Packages {
+		if pkg.Name == name {
+			return id
+		}
+	}
+	return name
+}
+
+// difference returns the names in a but not in b
+func difference(a, b []string) []string {
+	in := make(map[string]bool, len(b))
+	for _, name := range b {
+		in[name] = true
+	}
+	var result []string
+	for _, name := range a {
+		if !in[name] {
+			result = append(result, name)
+		}
+	}
+	return result
+}
+
+// nodeRange converts the positions of a syntax node into a document range.
+// Columns count bytes, which matches UTF-16 offsets for the ASCII of package
+// clauses and import specs.
+func nodeRange(fset *token.FileSet, start, end token.Pos) textRange {
+	from, to := fset.Position(start), fset.Position(end)
+	return textRange{
+		Start: position{Line: from.Line - 1, Character: from.Column - 1},
+		End:   position{Line: to.Line - 1, Character: to.Column - 1},
+	}
+}
+
+// fileURI returns the file URI of a path
+func fileURI(path string) string {
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(path)}).String()
+}
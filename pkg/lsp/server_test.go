@@ -0,0 +1,208 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/alkbt/aid-metrics/pkg/models"
+)
+
+// testClient drives a server through the protocol
+type testClient struct {
+	t    *testing.T
+	conn *conn
+	id   int
+}
+
+// send writes a request, or a notification if id is 0
+func (c *testClient) send(id int, method string, params any) {
+	c.t.Helper()
+	msg := map[string]any{"jsonrpc": "2.0", "method": method, "params": params}
+	if id != 0 {
+		msg["id"] = id
+	}
+	if err := c.conn.write(msg); err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+// next reads the next message of the server, decoded into a generic map
+func (c *testClient) next() map[string]any {
+	c.t.Helper()
+	header, err := c.conn.r.ReadMIMEHeader()
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	var length int
+	fmt.Sscan(header.Get("Content-Length"), &length)
+	content := make([]byte, length)
+	if _, err := io.ReadFull(c.conn.r.R, content); err != nil {
+		c.t.Fatal(err)
+	}
+	var msg map[string]any
+	if err := json.Unmarshal(content, &msg); err != nil {
+		c.t.Fatal(err)
+	}
+	return msg
+}
+
+// writeModule writes files, keyed by slash-separated paths, into a new temporary
+// directory and returns the directory
+func writeModule(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestServerHoverAndDiagnostics(t *testing.T) {
+	root := writeModule(t, map[string]string{"api/api.go": "package api\n\nimport \"example.com/shop/store\"\n\nvar _ = store.Get\n"})
+	apiFile := filepath.Join(root, "api", "api.go")
+
+	finding := models.Finding{ID: "AM003", Severity: models.SeverityWarning, Category: models.CategorySAP, Package: "api", Message: "far from the main sequence"}
+	runs := []*models.ModuleMetrics{
+		{Packages: map[string]models.PackageMetrics{
+			"example.com/shop/api":   {Name: "api", Ca: 1, Instability: 0},
+			"example.com/shop/store": {Name: "store"},
+		}, Findings: []models.Finding{finding}},
+		{Packages: map[string]models.PackageMetrics{
+			"example.com/shop/api":   {Name: "api", Ca: 1, Ce: 1, Instability: 0.5, Dependencies: []string{"store"}},
+			"example.com/shop/store": {Name: "store", Ca: 1, Dependents: []string{"api"}},
+		}},
+	}
+	analyses := 0
+	var changed [][]string
+	server := New(root, "example.com/shop", func(_ context.Context, changedFiles []string) (*models.ModuleMetrics, error) {
+		metrics := runs[analyses]
+		analyses++
+		changed = append(changed, changedFiles)
+		return metrics, nil
+	})
+
+	clientIn, serverOut := io.Pipe()
+	serverIn, clientOut := io.Pipe()
+	done := make(chan error, 1)
+	go func() { done <- server.Serve(context.Background(), serverIn, serverOut) }()
+	client := &testClient{t: t, conn: newConn(clientIn, clientOut)}
+	uri := fileURI(apiFile)
+
+	client.send(1, "initialize", map[string]any{"rootUri": fileURI(root)})
+	if caps := client.next()["result"].(map[string]any)["capabilities"].(map[string]any); caps["hoverProvider"] != true {
+		t.Errorf("expected the hover capability, got %v", caps)
+	}
+
+	client.send(0, "initialized", map[string]any{})
+	published := client.next()
+	params := published["params"].(map[string]any)
+	if published["method"] != "textDocument/publishDiagnostics" || params["uri"] != uri {
+		t.Fatalf("expected diagnostics for %s, got %v", uri, published)
+	}
+	if diagnostics := params["diagnostics"].([]any); len(diagnostics) != 1 || diagnostics[0].(map[string]any)["code"] != "AM003" {
+		t.Errorf("expected the AM003 finding, got %v", diagnostics)
+	}
+
+	// The finding is gone and the new import is annotated with the change of the metrics
+	client.send(0, "textDocument/didSave", map[string]any{"textDocument": map[string]any{"uri": uri}})
+	params = client.next()["params"].(map[string]any)
+	diagnostics := params["diagnostics"].([]any)
+	if len(diagnostics) != 1 {
+		t.Fatalf("expected one diagnostic for the new import, got %v", diagnostics)
+	}
+	if len(changed) != 2 || changed[0] != nil || len(changed[1]) != 1 || changed[1][0] != apiFile {
+		t.Errorf("expected a full analysis, then one of the saved %s, got %v", apiFile, changed)
+	}
+	note := diagnostics[0].(map[string]any)
+	if line := note["range"].(map[string]any)["start"].(map[string]any)["line"]; line != 2.0 || !strings.Contains(note["message"].(string), "Ce 1 (+1)") {
+		t.Errorf("expected a note on line 2 with Ce 1 (+1), got %v", note)
+	}
+
+	client.send(2, "textDocument/hover", map[string]any{"textDocument": map[string]any{"uri": uri}, "position": map[string]any{"line": 0, "character": 0}})
+	value := client.next()["result"].(map[string]any)["contents"].(map[string]any)["value"].(string)
+	for _, want := range []string{"**api**", "Ce 1 (+1)", "I 0.50 (+0.50)", "New dependencies: store"} {
+		if !strings.Contains(value, want) {
+			t.Errorf("expected %q in the hover, got\n%s", want, value)
+		}
+	}
+
+	client.send(3, "shutdown", nil)
+	if msg := client.next(); msg["id"] != 3.0 {
+		t.Errorf("expected the shutdown response, got %v", msg)
+	}
+	client.send(0, "exit", nil)
+	if err := <-done; err != nil {
+		t.Errorf("expected a clean exit, got %v", err)
+	}
+}
+
+func TestCodeActions(t *testing.T) {
+	root := writeModule(t, map[string]string{
+		"go.mod":         "module example.com/shop\n\ngo 1.21\n",
+		"store/store.go": "package store\n\nimport \"context\"\n\ntype Order struct{}\n\ntype Store struct{}\n\nfunc (*Store) Get(ctx context.Context, id string) (Order, error) { return Order{}, nil }\n\nfunc (*Store) Put(Order) error { return nil }\n",
+		"api/api.go":     "package api\n\nimport (\n\t\"context\"\n\n\t\"example.com/shop/store\"\n)\n\nfunc Handle(s *store.Store) error {\n\t_, err := s.Get(context.Background(), \"1\")\n\treturn err\n}\n",
+	})
+	server := New(root, "example.com/shop", nil)
+	server.resolveEdits = true
+	server.metrics = &models.ModuleMetrics{Packages: map[string]models.PackageMetrics{
+		"example.com/shop/api":   {Name: "api"},
+		"example.com/shop/store": {Name: "store"},
+	}}
+
+	finding, _ := json.Marshal(diagnostic{Code: "AM002", Source: source, Message: "depends on a less stable package"})
+	var params codeActionParams
+	params.TextDocument.URI = fileURI(filepath.Join(root, "api", "api.go"))
+	params.Range = textRange{Start: position{Line: 5, Character: 2}, End: position{Line: 5, Character: 2}}
+	params.Context.Diagnostics = []json.RawMessage{finding}
+	actions := server.codeActions(params)
+	if len(actions) != 2 {
+		t.Fatalf("expected an allow and an extract action, got %v", actions)
+	}
+
+	allow := actions[0].Edit.DocumentChanges
+	if create, ok := allow[0].(createFile); !ok || create.URI != fileURI(filepath.Join(root, "api", "doc.go")) {
+		t.Errorf("expected api/doc.go to be created, got %v", allow[0])
+	}
+	if text := allow[1].(textDocumentEdit).Edits[0].NewText; text != "//aid-metrics:allow=AM002\npackage api\n" {
+		t.Errorf("expected the allow annotation, got %q", text)
+	}
+
+	// The interfaces are only computed when the action is resolved
+	if actions[1].Edit != nil || actions[1].Data == nil {
+		t.Fatalf("expected the extract action to be resolved lazily, got %+v", actions[1])
+	}
+	extract, err := server.resolveCodeAction(actions[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if extract.Kind != kindExtract || !strings.HasSuffix(extract.Title, "(Store)") {
+		t.Errorf("expected the extraction of Store, got %q", extract.Title)
+	}
+	text := extract.Edit.DocumentChanges[1].(textDocumentEdit).Edits[0].NewText
+	for _, want := range []string{"\t\"example.com/shop/store\"\n", "type Store interface {\n\tGet(ctx context.Context, id string) (store.Order, error)\n}"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("expected %q in the extracted interfaces, got\n%s", want, text)
+		}
+	}
+	if strings.Contains(text, "Put") {
+		t.Errorf("expected only the methods api calls, got\n%s", text)
+	}
+
+	// Clients that cannot resolve edits are not offered the extraction
+	server.resolveEdits = false
+	if actions := server.codeActions(params); len(actions) != 1 || actions[0].Kind != kindQuickFix {
+		t.Errorf("expected only the allow action, got %v", actions)
+	}
+}
@@ -0,0 +1,53 @@
+// Package models contains data structures and interfaces used throughout the aid-metrics tool.
+// This file defines the extension groups of package metrics.
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Extensions holds optional groups of package metrics beyond the core fields,
+// keyed by group name (e.g. "coverage" or a plugin's name). Each group is kept
+// as its JSON encoding, so groups unknown to a consumer survive reading and
+// writing a report unchanged. Use SetExtension and GetExtension for typed access.
+type Extensions map[string]json.RawMessage
+
+// SetExtension stores value as the metric group of the package, replacing any
+// previous value of the group
+func SetExtension[T any](pkg *PackageMetrics, group string, value T) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("encoding metric group %s: %w", group, err)
+	}
+	if pkg.Extensions == nil {
+		pkg.Extensions = make(Extensions)
+	}
+	pkg.Extensions[group] = data
+	return nil
+}
+
+// GetExtension decodes the metric group of the package into a T. The second
+// result reports whether the package has the group at all.
+func GetExtension[T any](pkg PackageMetrics, group string) (T, bool, error) {
+	var value T
+	data, ok := pkg.Extensions[group]
+	if !ok {
+		return value, false, nil
+	}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return value, true, fmt.Errorf("decoding metric group %s: %w", group, err)
+	}
+	return value, true, nil
+}
+
+// ConcurrencyGroup is the name of the metric group of ConcurrencyMetrics
+const ConcurrencyGroup = "concurrency"
+
+// ConcurrencyMetrics counts the use of concurrency primitives in a package.
+// Concurrency-heavy packages with many dependents deserve special review attention.
+type ConcurrencyMetrics struct {
+	Goroutines     int `json:"goroutines"`      // go statements
+	Channels       int `json:"channels"`        // Channels made with make(chan T)
+	SyncPrimitives int `json:"sync_primitives"` // References to sync, sync/atomic and golang.org/x/sync
+}
@@ -0,0 +1,119 @@
+// Package models contains data structures and interfaces used throughout the aid-metrics tool.
+// This file defines the finding model shared by all checks and report formats.
+package models
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Severity ranks how urgently a finding should be addressed
+type Severity string
+
+// Supported severities, from least to most severe
+const (
+	SeverityInfo    Severity = "info"
+	SeverityWarning Severity = "warning"
+	SeverityError   Severity = "error"
+)
+
+// Level returns the numeric rank of the severity, higher is more severe.
+// Unknown severities rank below SeverityInfo.
+func (s Severity) Level() int {
+	switch s {
+	case SeverityInfo:
+		return 1
+	case SeverityWarning:
+		return 2
+	case SeverityError:
+		return 3
+	default:
+		return 0
+	}
+}
+
+// ParseSeverity converts a string into a Severity, rejecting unknown values
+func ParseSeverity(s string) (Severity, error) {
+	severity := Severity(strings.ToLower(strings.TrimSpace(s)))
+	if severity.Level() == 0 {
+		return "", fmt.Errorf("unknown severity %q (expected info, warning or error)", s)
+	}
+	return severity, nil
+}
+
+// Finding categories. Each category has a stable finding ID (see FindingIDs)
+// so findings can be referenced, suppressed and tracked across runs.
+const (
+	CategoryCycle           = "cycle"            // Import cycle between packages
+	CategorySDP             = "sdp"              // Stable Dependencies Principle violation
+	CategorySAP             = "sap"              // Stable Abstractions Principle violation
+	CategoryThreshold       = "threshold"        // Metric threshold violation
+	CategoryRule            = "rule"             // Architecture rule violation
+	CategoryDataBag         = "data-bag"         // Package dominated by tagged entity structs
+	CategoryLeak            = "leak"             // Exported API exposes types of another module
+	CategoryHeaderInterface = "header-interface" // Provider-side interface with a single implementation
+	CategoryDeprecated      = "deprecated"       // References to deprecated symbols of other packages
+	CategorySharedKernel    = "shared-kernel"    // Package sharing constants with many packages
+)
+
+// FindingIDs maps each category to its stable finding ID
+var FindingIDs = map[string]string{
+	CategoryCycle:           "AM001",
+	CategorySDP:             "AM002",
+	CategorySAP:             "AM003",
+	CategoryThreshold:       "AM004",
+	CategoryRule:            "AM005",
+	CategoryDataBag:         "AM006",
+	CategoryLeak:            "AM007",
+	CategoryHeaderInterface: "AM008",
+	CategoryDeprecated:      "AM009",
+	CategorySharedKernel:    "AM010",
+}
+
+// DefaultSeverities holds the severity of each category unless configured otherwise
+var DefaultSeverities = map[string]Severity{
+	CategoryCycle:           SeverityError,
+	CategorySDP:             SeverityWarning,
+	CategorySAP:             SeverityWarning,
+	CategoryThreshold:       SeverityError,
+	CategoryRule:            SeverityError,
+	CategoryDataBag:         SeverityInfo,
+	CategoryLeak:            SeverityWarning,
+	CategoryHeaderInterface: SeverityInfo,
+	CategoryDeprecated:      SeverityInfo,
+	CategorySharedKernel:    SeverityInfo,
+}
+
+// DefaultDebtPoints holds the debt points of a finding by severity, unless its
+// category is weighted otherwise. Summed over all findings they give the module a
+// single debt figure that a budget can hold and lower over time.
+var DefaultDebtPoints = map[Severity]int{
+	SeverityInfo:    1,
+	SeverityWarning: 3,
+	SeverityError:   10,
+}
+
+// DebtPoints returns the total debt points of the findings
+func DebtPoints(findings []Finding) int {
+	total := 0
+	for _, finding := range findings {
+		total += finding.Points
+	}
+	return total
+}
+
+// Finding is a single problem detected in the analyzed module.
+// Every check reports its results as findings so that all report formats render them uniformly.
+type Finding struct {
+	ID          string   // Stable finding ID of the category (e.g. AM001)
+	Severity    Severity // How urgently the finding should be addressed
+	Category    string   // Finding category (see Category* constants)
+	Package     string   // Display name of the package the finding is about
+	Message     string   // Human-readable description of the problem
+	Remediation string   // Suggested way to fix the problem
+	Points      int      // Debt points the finding adds to its package and the module
+
+	// Advisory findings are reported only, e.g. violations of a rule in its warn
+	// period: they fail no gate and add no debt points
+	Advisory bool
+}
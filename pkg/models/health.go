@@ -0,0 +1,22 @@
+// Package models contains data structures and interfaces used throughout the aid-metrics tool.
+// This file defines the weights of the composite health score.
+package models
+
+// HealthWeights weighs the components of the health score of a package. Each
+// component is a penalty between 0 and 1; components that are not available for
+// a package (e.g. coverage without a cover profile) are left out and the
+// remaining weights are scaled up accordingly.
+type HealthWeights struct {
+	Distance   float64 // Distance from the main sequence, D
+	Cycles     float64 // Membership in an import cycle
+	Complexity float64 // Mean cyclomatic complexity of the package's functions
+	Coverage   float64 // Share of statements not covered by tests
+}
+
+// DefaultHealthWeights holds the health weights unless configured otherwise
+var DefaultHealthWeights = HealthWeights{
+	Distance:   0.4,
+	Cycles:     0.3,
+	Complexity: 0.2,
+	Coverage:   0.1,
+}
@@ -3,6 +3,7 @@ package models
 // PackageMetrics represents the metrics for a specific package
 type PackageMetrics struct {
 	Name         string  // Package name
+	Synopsis     string  // First sentence of the package documentation, if any
 	Ca           int     // Afferent coupling - packages that depend on this package
 	Ce           int     // Efferent coupling - packages this package depends on
 	Na           int     // Number of abstract types (interfaces)
@@ -10,10 +11,229 @@ type PackageMetrics struct {
 	Instability  float64 // I = Ce/(Ca+Ce)
 	Abstractness float64 // A = Na/Nc
 	Distance     float64 // D = |A + I - 1|
+
+	// Na, Nc and A of all declarations, exported or not. They differ from the
+	// above with ModuleMetrics.ExportedOnly, which counts exported declarations only.
+	NaAll           int
+	NcAll           int
+	AbstractnessAll float64
+
+	// Embedding metrics: composition through embedding is a coupling form
+	// that is invisible to the import graph.
+	StructEmbeds    int     // Embedded fields in struct types
+	InterfaceEmbeds int     // Embedded interfaces in interface types
+	EmbeddingRatio  float64 // Share of struct fields and interface elements that are embedded
+
+	// Method metrics: how much behavior the package attaches to its types
+	Methods           int // Total number of methods
+	PointerMethods    int // Methods with a pointer receiver
+	ValueMethods      int // Methods with a value receiver
+	ExportedMethods   int // Methods with an exported name
+	UnexportedMethods int // Methods with an unexported name
+
+	// Constructor metrics: NewX functions and whether they return abstractions
+	Constructors              int     // Number of NewX constructors
+	InterfaceConstructors     int     // Constructors returning an interface
+	InterfaceConstructorRatio float64 // InterfaceConstructors / Constructors
+
+	// Composition root classification: packages wiring the application together
+	// through a dependency injection framework legitimately have extreme Ce.
+	CompositionRoot bool   // Package is a dependency injection composition root
+	DIFramework     string // Detected framework (wire, fx, dig), empty if none
+
+	Role string // Architectural role of the package (see Role* constants)
+
+	DebtPoints int // Sum of the debt points of the findings about the package
+
+	// Code metrics beyond the dependency graph, combined with D and cycles into
+	// a single 0-100 health score (see HealthWeights)
+	Complexity    float64 // Mean cyclomatic complexity of the functions and methods
+	MaxComplexity int     // Highest cyclomatic complexity of a single function or method
+	Coverage      float64 // Share of statements covered by tests, if HasCoverage
+	HasCoverage   bool    // A cover profile covers the package
+	Health        int     // Composite health score, 100 is healthiest
+
+	// Zone of the A/I chart the package is in (see Zone* constants), empty for
+	// isolated packages and without type information
+	Zone string
+
+	// Edges behind Ca and Ce, as sorted display names
+	Dependencies []string // Packages this package depends on
+	Dependents   []string // Packages that depend on this package
+
+	// Transitive coupling: the packages reachable through the edges above,
+	// the package itself excluded
+	TransitiveCa int // Packages depending on this package directly or indirectly
+	TransitiveCe int // Packages this package depends on directly or indirectly
+
+	// ExternalImports lists the full import paths of the dependencies outside the
+	// module and the standard library, sorted; display names shorten them
+	ExternalImports []string
+
+	// Ce split by visibility: dependencies whose types appear in the exported API
+	// leak into every dependent, a much stronger coupling than private usage
+	CeExported          int        // Dependencies exposed in exported signatures, fields and methods
+	CeInternal          int        // Dependencies used by the implementation only
+	ExposedDependencies []string   // Display names of the exposed dependencies
+	LeakedTypes         []TypeLeak // Types of other modules exposed by exported declarations
+
+	// Interface ownership: Go interfaces are idiomatically declared by their consumers.
+	// Interfaces declared next to their implementation are provider-side.
+	ConsumerInterfaces int               // Interfaces not implemented in the package itself
+	ProviderInterfaces int               // Interfaces implemented in the package itself
+	HeaderInterfaces   []HeaderInterface // Exported provider-side interfaces with a single implementation
+
+	// Test doubles generated by mockgen, moq or counterfeiter. Mocks are not counted
+	// as implementations in the interface ownership analysis.
+	Mocks              int               // Generated mock types declared in the package
+	MockedInterfaces   []MockedInterface // Interfaces of the package that have generated mocks
+	MockOnlyDependents bool              // Every dependent of the package consists solely of generated mocks
+
+	// Deprecation exposure: references to symbols documented as deprecated in
+	// other packages, internal or external, which will have to be migrated away from
+	DeprecatedUses    int             // References to deprecated symbols
+	DeprecatedSymbols []DeprecatedUse // Deprecated symbols referenced, by package and symbol
+
+	// Constant sharing: packages that exist mainly to share constants and enums
+	// are shared kernels that should be stable and abstract
+	ConstantSets       []ConstantSet // Exported constant sets, by name
+	ConstantShare      float64       // Share of the exported declarations that are constants or enum types
+	ConstantDependents int           // Other packages referencing any of the constants
+	SharedKernel       bool          // Mainly constants, referenced by many packages
+
+	// Run-time coupling: imports for side effects only and global state
+	// registered by init functions, e.g. database drivers and codecs
+	BlankImports      []string // Import paths of the packages imported as _
+	InitRegistrations []string // Registration calls of the init functions, e.g. "database/sql.Register"
+
+	// Error-path coupling: exported sentinel errors and error types, and the
+	// packages checking for them, a channel the import graph shows only coarsely
+	ErrorTargets    []ErrorTarget // Exported errors of the package, by name
+	ErrorDependents int           // Other packages checking for any of them
+
+	// Entity metrics: packages full of serialization/ORM tagged structs with
+	// near-zero abstraction are "data bags" that many packages couple to.
+	TaggedStructs int  // Structs with json/gorm/db (and similar) field tags
+	DataBag       bool // Package is dominated by tagged structs and has A close to 0
+
+	// Generated code handling: coupling to generated packages is still counted,
+	// but enabled profiles may exempt them from abstractness/distance gating.
+	// Main packages and composition roots are exempt by default as well.
+	Generator        string // Code generator, if every file in the package is generated
+	GateExempt       bool   // Package is excluded from A/D threshold gating
+	GateExemptReason string // Why the package is exempt from gating
+
+	// Annotations are the aid-metrics: comments of the package's doc.go, such as
+	// "max-distance=0.9", overriding the thresholds for the package
+	Annotations []string
+
+	// Partial is set when the package has load, parse or type errors (see
+	// ModuleMetrics.Diagnostics): its metrics cover what could be parsed only
+	Partial bool
+
+	// Extensions holds optional metric groups by name, such as those of plugins
+	Extensions Extensions
+}
+
+// TypeLeak is a type declared in another module that a package exposes in its exported API
+type TypeLeak struct {
+	Declaration string // Exported declaration exposing the type, e.g. "NewClient" or "Client.Transport"
+	Package     string // Import path of the package declaring the type
+	Type        string // Name of the type within its package
+}
+
+// DeprecatedUse is a deprecated symbol of another package that a package refers to
+type DeprecatedUse struct {
+	Package string // Import path of the package declaring the symbol
+	Symbol  string // Name of the symbol within its package
+	Uses    int    // References to the symbol
+}
+
+// ConstantSet is a set of exported constants of a package: those of an enum
+// type declared in the package, or those of one const declaration otherwise
+type ConstantSet struct {
+	Name         string   // Enum type name, or name of the first constant of the declaration
+	Constants    int      // Number of constants in the set
+	ReferencedBy []string // Display names of the other packages referencing any of them
+}
+
+// Error kinds of ErrorTarget
+const (
+	ErrorVar  = "var"  // Sentinel error variable, checked with errors.Is or ==
+	ErrorType = "type" // Error type, checked with errors.As or type assertions
+)
+
+// ErrorTarget is an exported error of a package that callers may check for
+type ErrorTarget struct {
+	Name      string   // Name of the variable or type
+	Kind      string   // ErrorVar or ErrorType
+	CheckedBy []string // Display names of the other packages checking for it
+}
+
+// SideEffect is a package with effects at run time: it is imported for its side
+// effects only, or its init functions register global state
+type SideEffect struct {
+	Package         string   // Import path
+	Registrations   []string // Registration calls of its init functions, e.g. "database/sql.Register"
+	BlankImportedBy []string // Display names of the module packages importing it as _
+	Binaries        []string // Display names of the main packages linking it in
+}
+
+// StringCoupling is a string literal, such as an environment variable name, a
+// feature flag key or a URL path, used by packages without an import relationship
+// between them: coupling the import graph does not show
+type StringCoupling struct {
+	Value    string   // The literal
+	Packages []string // Display names of the packages using it
+}
+
+// HeaderInterface is an exported interface mirroring the only type implementing it
+type HeaderInterface struct {
+	Interface      string // Name of the interface
+	Implementation string // Name of the implementing type in the same package
+}
+
+// MockedInterface is an interface with the number of generated mocks implementing it
+type MockedInterface struct {
+	Interface string // Name of the interface
+	Mocks     int    // Number of generated mock types implementing it
+	OnlyMocks bool   // The interface has no implementation in the module besides mocks
 }
 
 // ModuleMetrics represents the metrics for an entire module
 type ModuleMetrics struct {
-	Path     string                    // Module path
-	Packages map[string]PackageMetrics // Map of package metrics by package path
+	Path            string                    // Module path
+	Packages        map[string]PackageMetrics // Map of package metrics by package path
+	Roles           map[string]RoleMetrics    // Metrics aggregated per package role
+	Endpoints       []Endpoint                // HTTP/gRPC endpoints and the packages their handlers depend on
+	SideEffects     []SideEffect              // Side-effect imports and init registrations, by import path
+	StringCouplings []StringCoupling          // String literals shared by packages without an import relationship
+	Cycles          [][]string                // Import cycles, each listing the packages involved
+	Names           map[string]string         // Import paths of the display names that are not module-relative paths
+	Findings        []Finding                 // Problems detected by all checks, sorted by severity
+	Diagnostics     []Diagnostic              // Errors loading the packages, sorted by package
+
+	ExportedOnly bool // Na, Nc and A of the packages count exported declarations only
+
+	Summary Summary // Module-level statistics of the packages
+}
+
+// Diagnostic is an error reported by the go command, the parser or the type
+// checker while loading a package. The metrics of the package may be incomplete,
+// or the package may be missing from the report. Invalid aid-metrics:
+// annotations, which are skipped, are reported as diagnostics too.
+type Diagnostic struct {
+	Package  string // Display name of the package
+	Kind     string // "list", "parse", "type" or "annotation"; "unknown" for other errors
+	Position string // file:line:col relative to the module root, empty if unknown
+	Message  string
+}
+
+// Endpoint describes a registered HTTP or gRPC handler and its transitive package fan-in
+type Endpoint struct {
+	Route        string   // HTTP path pattern or gRPC service name
+	Kind         string   // "http" or "grpc"
+	RegisteredIn string   // Package registering the handler
+	Handler      string   // Package providing the handler
+	Dependencies []string // Module packages the handler transitively depends on
 }
@@ -2,25 +2,39 @@
 // This file defines the progress reporting interface used to provide feedback during analysis.
 package models
 
+// Stage is a phase of an analysis. Each stage reports its progress against its
+// own total, so a stage that dominates the run does not make the others look stuck.
+type Stage string
+
+// Stages of an analysis, in the order they run
+const (
+	StageDiscovery Stage = "discovery" // Finding the packages matching the pattern; the total is not known in advance
+	StageLoading   Stage = "loading"   // Loading the packages and their types with the go command
+	StageParsing   Stage = "parsing"   // Analyzing the syntax and types of each package
+	StageReporting Stage = "reporting" // Generating the reports
+)
+
 // ProgressReporter defines an interface for reporting progress during package analysis.
 // Implementations can provide visual feedback through progress bars, spinners, or logs.
-// The interface uses a fixed 0-100 scale for consistent progress representation.
+// Progress is reported per stage: StageStart, any number of Updates, then StageDone.
 type ProgressReporter interface {
-	// SetTotal sets the total number of steps for the progress bar.
-	// This should be called once at the beginning of the operation.
-	// For aid-metrics, we use a fixed scale of 100.
-	SetTotal(total int)
+	// StageStart begins a stage of total steps, or of an unknown number of
+	// steps if total is 0. The steps of later Updates count from 0 again.
+	StageStart(stage Stage, total int)
 
-	// Update updates the current progress with a description of the current operation.
-	// current should be between 0 and the total value set with SetTotal.
-	// description should be a short, descriptive string of what's currently happening.
+	// Update updates the number of steps of the current stage done so far with a
+	// description of the current operation. current is between 0 and the total of
+	// the stage; description is a short string of what's currently happening.
 	//
 	// Example:
-	//   reporter.Update(25, "Loading package: github.com/user/project/pkg/analyzer")
+	//   reporter.Update(25, "Loaded 25 of 120 packages")
 	Update(current int, description string)
 
-	// Complete marks the operation as complete.
-	// This should be called when all operations are finished.
-	// Implementations may use this to clean up resources or show a final message.
+	// StageDone ends a stage once all its steps are done.
+	StageDone(stage Stage)
+
+	// Complete marks the whole operation as complete. It is called by the owner
+	// of the reporter, as an operation may span several analyses and the
+	// reporting, and also after a failure, so implementations can clean up.
 	Complete()
-}
\ No newline at end of file
+}
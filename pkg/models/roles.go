@@ -0,0 +1,33 @@
+// Package models contains data structures and interfaces used throughout the aid-metrics tool.
+// This file defines package roles and the per-role metric summary.
+package models
+
+// Package roles assigned by the analyzer from path and import fingerprints.
+// Each role has its own expectations for where packages sit on the A/I chart.
+const (
+	RoleMain       = "main"       // Program entry points and composition roots
+	RoleHandler    = "handler"    // Transport layer: HTTP/gRPC handlers, controllers
+	RoleRepository = "repository" // Persistence layer: database access, stores
+	RoleDomain     = "domain"     // Business logic and use cases
+	RoleModel      = "model"      // Data structures shared across layers
+	RoleOther      = "other"      // Packages matching no role heuristic
+)
+
+// Thresholds defines the acceptable metric bounds for a package.
+// A zero MinAbstractness and a MaxDistance/MaxInstability of 1 disable the respective check.
+type Thresholds struct {
+	MaxDistance     float64 // Maximum allowed distance from the main sequence
+	MaxInstability  float64 // Maximum allowed instability
+	MinAbstractness float64 // Minimum required abstractness
+}
+
+// RoleMetrics summarizes the metrics of all packages sharing a role
+type RoleMetrics struct {
+	Role             string     // Role name
+	Packages         int        // Number of packages with this role
+	MeanInstability  float64    // Average I across the role's packages
+	MeanAbstractness float64    // Average A across the role's packages
+	MeanDistance     float64    // Average D across the role's packages
+	MaxDistance      float64    // Worst D across the role's packages
+	Thresholds       Thresholds // Default thresholds for the role
+}
@@ -0,0 +1,22 @@
+// Package models contains data structures and interfaces used throughout the aid-metrics tool.
+// This file defines the module-level summary statistics.
+package models
+
+// Summary aggregates the metrics of all packages of a module into a few
+// numbers readable at a glance
+type Summary struct {
+	Packages int // Number of packages
+
+	// Distance statistics over the measured packages: those with coupling that
+	// are not exempt from gating, as entry points are unstable and concrete by design
+	Measured       int
+	MeanDistance   float64
+	MedianDistance float64
+	P90Distance    float64 // 90th percentile, nearest rank
+
+	// WorstOffenders lists the names of the measured packages farthest from the
+	// main sequence, worst first; packages on it are not listed
+	WorstOffenders []string
+
+	Zones map[string]int // Number of packages per zone (see Zone* constants)
+}
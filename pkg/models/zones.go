@@ -0,0 +1,24 @@
+// Package models contains data structures and interfaces used throughout the aid-metrics tool.
+// This file defines the zones of the A/I chart packages are classified into.
+package models
+
+// Zones of the A/I chart, as set in PackageMetrics.Zone
+const (
+	ZoneMainSequence = "main-sequence" // Abstractness roughly matches stability
+	ZonePain         = "pain"          // Stable and concrete: rigid, hard to change
+	ZoneUselessness  = "uselessness"   // Unstable and abstract: abstractions nobody depends on
+)
+
+// ZoneBands are the boundaries of the zones of pain and uselessness. A package
+// is in the zone of pain if both its A and I are at most Pain, in the zone of
+// uselessness if both are at least Uselessness, and on the main sequence otherwise.
+type ZoneBands struct {
+	Pain        float64
+	Uselessness float64
+}
+
+// DefaultZoneBands holds the zone bands unless configured otherwise
+var DefaultZoneBands = ZoneBands{
+	Pain:        0.3,
+	Uselessness: 0.7,
+}
@@ -0,0 +1,247 @@
+// Package modgraph provides a module-level view of the dependencies of a module,
+// built from `go mod graph`, beside the package-level view of the analyzer: the
+// coupling among the direct requirements and which packages use each of them.
+package modgraph
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/alkbt/aid-metrics/pkg/models"
+)
+
+// Graph is the module requirement graph. Module versions are dropped, so all
+// versions of a module are a single node.
+type Graph struct {
+	Main     string              // Path of the main module
+	Versions map[string]string   // Versions of the main module's requirements
+	Requires map[string][]string // Sorted requirements of each module
+}
+
+// Load runs `go mod graph` in the module directory and parses its output
+func Load(dir string) (*Graph, error) {
+	cmd := exec.Command("go", "mod", "graph")
+	cmd.Dir = dir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("go mod graph failed: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return Parse(bytes.NewReader(out))
+}
+
+// Parse parses the output of `go mod graph`: one "module@version requirement@version"
+// edge per line, where the main module has no version. The go and toolchain
+// requirements are skipped.
+func Parse(r io.Reader) (*Graph, error) {
+	graph := &Graph{Versions: make(map[string]string), Requires: make(map[string][]string)}
+	seen := make(map[[2]string]bool)
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("invalid go mod graph line %q", scanner.Text())
+		}
+		from, _, versioned := strings.Cut(fields[0], "@")
+		to, version, _ := strings.Cut(fields[1], "@")
+		if to == "go" || to == "toolchain" {
+			continue
+		}
+		if !versioned {
+			graph.Main = from
+			graph.Versions[to] = version
+		}
+		if edge := [2]string{from, to}; !seen[edge] {
+			seen[edge] = true
+			graph.Requires[from] = append(graph.Requires[from], to)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if graph.Main == "" {
+		return nil, fmt.Errorf("go mod graph has no main module")
+	}
+	for _, requires := range graph.Requires {
+		sort.Strings(requires)
+	}
+	return graph, nil
+}
+
+// Requirement is a direct requirement of the main module
+type Requirement struct {
+	Path    string `json:"path"`
+	Version string `json:"version"`
+
+	// Coupling among the direct requirements: Ca is the number of other direct
+	// requirements requiring the module, Ce the number it requires
+	Ca          int     `json:"ca"`
+	Ce          int     `json:"ce"`
+	Instability float64 `json:"instability"`
+
+	// Packages lists the packages of the main module importing packages of the module
+	Packages []string `json:"packages"`
+}
+
+// Report is the module-level view of a module's dependencies
+type Report struct {
+	Module       string        `json:"module"`
+	Requirements []Requirement `json:"requirements"`
+}
+
+// Build builds the module-level report of the direct, non-indirect requirements
+// of the main module, attributing the package imports of the analysis to the
+// modules providing them
+func Build(graph *Graph, direct []string, metrics *models.ModuleMetrics) *Report {
+	report := &Report{Module: graph.Main, Requirements: []Requirement{}}
+
+	isDirect := make(map[string]bool, len(direct))
+	for _, path := range direct {
+		isDirect[path] = true
+	}
+
+	importers := make(map[string]map[string]bool)
+	for _, pkg := range metrics.Packages {
+		for _, dep := range pkg.ExternalImports {
+			if module := providingModule(dep, direct); module != "" {
+				if importers[module] == nil {
+					importers[module] = make(map[string]bool)
+				}
+				importers[module][pkg.Name] = true
+			}
+		}
+	}
+
+	ca := make(map[string]int)
+	ce := make(map[string]int)
+	for _, from := range direct {
+		for _, to := range graph.Requires[from] {
+			if isDirect[to] {
+				ce[from]++
+				ca[to]++
+			}
+		}
+	}
+
+	for _, path := range direct {
+		requirement := Requirement{
+			Path:     path,
+			Version:  graph.Versions[path],
+			Ca:       ca[path],
+			Ce:       ce[path],
+			Packages: []string{},
+		}
+		if requirement.Ca+requirement.Ce > 0 {
+			requirement.Instability = float64(requirement.Ce) / float64(requirement.Ca+requirement.Ce)
+		}
+		for pkg := range importers[path] {
+			requirement.Packages = append(requirement.Packages, pkg)
+		}
+		sort.Strings(requirement.Packages)
+		report.Requirements = append(report.Requirements, requirement)
+	}
+	sort.Slice(report.Requirements, func(i, j int) bool { return report.Requirements[i].Path < report.Requirements[j].Path })
+	return report
+}
+
+// providingModule returns the module of the given paths providing an import path:
+// the longest module path the import path is within
+func providingModule(importPath string, modules []string) string {
+	var best string
+	for _, module := range modules {
+		if (importPath == module || strings.HasPrefix(importPath, module+"/")) && len(module) > len(best) {
+			best = module
+		}
+	}
+	return best
+}
+
+// DirectRequirements returns the requirements of the go.mod file in dir that are
+// not marked "// indirect", i.e. the modules the main module imports itself
+func DirectRequirements(dir string) ([]string, error) {
+	data, err := os.ReadFile(filepath.Join(dir, "go.mod"))
+	if err != nil {
+		return nil, err
+	}
+
+	var direct []string
+	inBlock := false
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case inBlock && line == ")":
+			inBlock = false
+			continue
+		case line == "require (":
+			inBlock = true
+			continue
+		case strings.HasPrefix(line, "require "):
+			line = strings.TrimSpace(strings.TrimPrefix(line, "require "))
+		case !inBlock:
+			continue
+		}
+		if strings.Contains(line, "// indirect") {
+			continue
+		}
+		if fields := strings.Fields(line); len(fields) >= 2 && !strings.HasPrefix(fields[0], "//") {
+			direct = append(direct, strings.Trim(fields[0], `"`))
+		}
+	}
+	return direct, nil
+}
+
+// WriteJSON writes the report as indented JSON
+func (r *Report) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// WriteText writes the report as a table of the direct requirements followed by
+// the requirements imported by a single package, which are candidates for
+// replacement or isolation, and those imported by none (e.g. tools)
+func (r *Report) WriteText(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintf(tw, "MODULE: %s\n\n", r.Module)
+	fmt.Fprintln(tw, "REQUIREMENT\tVersion\tCa\tCe\tI\tPackages")
+	fmt.Fprintln(tw, "-----------\t-------\t--\t--\t-\t--------")
+	var single, unused []Requirement
+	for _, req := range r.Requirements {
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%d\t%.2f\t%d\n", req.Path, req.Version, req.Ca, req.Ce, req.Instability, len(req.Packages))
+		switch len(req.Packages) {
+		case 0:
+			unused = append(unused, req)
+		case 1:
+			single = append(single, req)
+		}
+	}
+
+	if len(single) > 0 {
+		fmt.Fprintln(tw, "\nREQUIRED BY ONE PACKAGE\tPackage")
+		for _, req := range single {
+			fmt.Fprintf(tw, "%s\t%s\n", req.Path, req.Packages[0])
+		}
+	}
+	if len(unused) > 0 {
+		fmt.Fprintln(tw, "\nNOT IMPORTED BY ANY PACKAGE")
+		for _, req := range unused {
+			fmt.Fprintln(tw, req.Path)
+		}
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,132 @@
+package modgraph
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/alkbt/aid-metrics/pkg/models"
+)
+
+func TestBuild(t *testing.T) {
+	graph, err := Parse(strings.NewReader(`example.com/shop example.com/db@v1.2.0
+example.com/shop example.com/log@v0.3.0
+example.com/shop example.com/yaml@v3.0.1
+example.com/shop go@1.23.0
+example.com/shop golang.org/x/sys@v0.1.0
+example.com/db@v1.2.0 example.com/log@v0.3.0
+example.com/db@v1.2.0 golang.org/x/sys@v0.1.0
+example.com/db@v1.1.0 example.com/log@v0.2.0
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if graph.Main != "example.com/shop" || graph.Versions["example.com/db"] != "v1.2.0" {
+		t.Fatalf("unexpected graph %+v", graph)
+	}
+	if requires := graph.Requires["example.com/db"]; len(requires) != 2 {
+		t.Errorf("expected the versions of db to be merged, got %v", requires)
+	}
+
+	dir := t.TempDir()
+	goMod := `module example.com/shop
+
+go 1.23.0
+
+require example.com/yaml v3.0.1
+
+require (
+	example.com/db v1.2.0
+	example.com/log v0.3.0
+	golang.org/x/sys v0.1.0 // indirect
+)
+`
+	if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte(goMod), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	direct, err := DirectRequirements(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(direct, " ") != "example.com/yaml example.com/db example.com/log" {
+		t.Fatalf("unexpected direct requirements %v", direct)
+	}
+
+	metrics := &models.ModuleMetrics{Packages: map[string]models.PackageMetrics{
+		"example.com/shop/api":    {Name: "api", ExternalImports: []string{"example.com/log"}},
+		"example.com/shop/store":  {Name: "store", ExternalImports: []string{"example.com/db/sql", "example.com/log"}},
+		"example.com/shop/config": {Name: "config"},
+	}}
+	report := Build(graph, direct, metrics)
+
+	if len(report.Requirements) != 3 {
+		t.Fatalf("expected 3 requirements, got %+v", report.Requirements)
+	}
+	db, log, yaml := report.Requirements[0], report.Requirements[1], report.Requirements[2]
+	if db.Ca != 0 || db.Ce != 1 || db.Instability != 1 || strings.Join(db.Packages, " ") != "store" {
+		t.Errorf("unexpected db requirement %+v", db)
+	}
+	if log.Ca != 1 || log.Ce != 0 || len(log.Packages) != 2 {
+		t.Errorf("unexpected log requirement %+v", log)
+	}
+	if len(yaml.Packages) != 0 {
+		t.Errorf("expected yaml to be imported by no package, got %v", yaml.Packages)
+	}
+
+	var buf bytes.Buffer
+	if err := report.WriteText(&buf); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{
+		"example.com/db    v1.2.0   0   1   1.00  1",
+		"example.com/db           store",
+		"NOT IMPORTED BY ANY PACKAGE\nexample.com/yaml",
+	} {
+		if !strings.Contains(buf.String(), line) {
+			t.Errorf("expected output to contain %q, got:\n%s", line, buf.String())
+		}
+	}
+}
+
+func TestUnused(t *testing.T) {
+	files := map[string]string{
+		"store/store_test.go":   "package store\n\nimport \"example.com/assert\"\n\nvar _ = assert.Equal\n",
+		"nested/go.mod":         "module example.com/shop/nested\n",
+		"nested/nested_test.go": "package nested\n\nimport _ \"example.com/mockgen/gomock\"\n",
+		"testdata/data_test.go": "package data\n\nimport _ \"example.com/mockgen/gomock\"\n",
+		"store/store.go":        "package store\n",
+		"store/broken_test.txt": "not go",
+	}
+	dir := writeModule(t, files)
+
+	metrics := &models.ModuleMetrics{Packages: map[string]models.PackageMetrics{
+		"example.com/shop/store": {Name: "store", ExternalImports: []string{"example.com/db/sql"}},
+	}}
+	direct := []string{"example.com/assert", "example.com/db", "example.com/mockgen"}
+	unused, err := Unused(dir, direct, metrics)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(unused) != 1 || unused[0] != "example.com/mockgen" {
+		t.Errorf("expected only mockgen to be unused, got %v", unused)
+	}
+}
+
+// writeModule writes files, keyed by slash-separated paths, into a new temporary
+// directory and returns the directory
+func writeModule(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
@@ -0,0 +1,83 @@
+// Package modgraph provides a module-level view of the dependencies of a module.
+// This file implements the detection of requirements no package imports.
+package modgraph
+
+import (
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/alkbt/aid-metrics/pkg/models"
+)
+
+// Unused returns the direct requirements of which no package is imported, neither
+// by the analyzed packages nor by the test files of the module in dir (which the
+// analysis skips). They are candidates for `go mod tidy`, or tooling-only requirements.
+func Unused(dir string, direct []string, metrics *models.ModuleMetrics) ([]string, error) {
+	imported := make(map[string]bool)
+	for _, pkg := range metrics.Packages {
+		for _, imp := range pkg.ExternalImports {
+			imported[providingModule(imp, direct)] = true
+		}
+	}
+	testImports, err := testImports(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, imp := range testImports {
+		imported[providingModule(imp, direct)] = true
+	}
+
+	var unused []string
+	for _, module := range direct {
+		if !imported[module] {
+			unused = append(unused, module)
+		}
+	}
+	sort.Strings(unused)
+	return unused, nil
+}
+
+// testImports returns the import paths of the _test.go files of the module in dir.
+// Nested modules, vendor and testdata directories, and hidden directories are skipped.
+func testImports(dir string) ([]string, error) {
+	var imports []string
+	fset := token.NewFileSet()
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		name := entry.Name()
+		if entry.IsDir() {
+			if path == dir {
+				return nil
+			}
+			if name == "vendor" || name == "testdata" || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") {
+				return filepath.SkipDir
+			}
+			if _, err := os.Stat(filepath.Join(path, "go.mod")); err == nil {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(name, "_test.go") {
+			return nil
+		}
+		file, err := parser.ParseFile(fset, path, nil, parser.ImportsOnly)
+		if err != nil {
+			return err
+		}
+		for _, spec := range file.Imports {
+			if imp, err := strconv.Unquote(spec.Path.Value); err == nil {
+				imports = append(imports, imp)
+			}
+		}
+		return nil
+	})
+	return imports, err
+}
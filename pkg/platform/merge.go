@@ -0,0 +1,143 @@
+// Package platform compares the analysis of a module across build configurations.
+// This file implements the merged report with the range of each metric across platforms.
+package platform
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/alkbt/aid-metrics/pkg/models"
+)
+
+// IntRange is the range of an integer metric across platforms
+type IntRange struct {
+	Min int `json:"min"`
+	Max int `json:"max"`
+}
+
+// Range is the range of a ratio metric across platforms
+type Range struct {
+	Min float64 `json:"min"`
+	Max float64 `json:"max"`
+}
+
+// MergedPackage is a package with the range of its metrics across the platforms it exists on
+type MergedPackage struct {
+	Name string `json:"package"`
+
+	// Platforms lists the platforms the package has files on
+	Platforms []string `json:"platforms"`
+
+	Ca           IntRange `json:"ca"`
+	Ce           IntRange `json:"ce"`
+	Instability  Range    `json:"instability"`
+	Abstractness Range    `json:"abstractness"`
+	Distance     Range    `json:"distance"`
+}
+
+// Merged is the merged report of the analyses of a module on several platforms
+type Merged struct {
+	Module    string          `json:"module"`
+	Platforms []string        `json:"platforms"`
+	Packages  []MergedPackage `json:"packages"`
+}
+
+// Merge merges the analyses of a module, one per platform, into the minimum and
+// maximum of every metric per package. Packages are matched by name.
+func Merge(platforms []string, results []*models.ModuleMetrics) *Merged {
+	merged := &Merged{Platforms: platforms, Packages: []MergedPackage{}}
+	if len(results) > 0 {
+		merged.Module = results[0].Path
+	}
+
+	packages := make(map[string]*MergedPackage)
+	for i, metrics := range results {
+		for _, pkg := range metrics.Packages {
+			m, ok := packages[pkg.Name]
+			if !ok {
+				m = &MergedPackage{
+					Name:         pkg.Name,
+					Ca:           IntRange{pkg.Ca, pkg.Ca},
+					Ce:           IntRange{pkg.Ce, pkg.Ce},
+					Instability:  Range{pkg.Instability, pkg.Instability},
+					Abstractness: Range{pkg.Abstractness, pkg.Abstractness},
+					Distance:     Range{pkg.Distance, pkg.Distance},
+				}
+				packages[pkg.Name] = m
+			}
+			m.Platforms = append(m.Platforms, platforms[i])
+			m.Ca.add(pkg.Ca)
+			m.Ce.add(pkg.Ce)
+			m.Instability.add(pkg.Instability)
+			m.Abstractness.add(pkg.Abstractness)
+			m.Distance.add(pkg.Distance)
+		}
+	}
+
+	for _, m := range packages {
+		merged.Packages = append(merged.Packages, *m)
+	}
+	sort.Slice(merged.Packages, func(i, j int) bool { return merged.Packages[i].Name < merged.Packages[j].Name })
+	return merged
+}
+
+// add widens the range to include value
+func (r *IntRange) add(value int) {
+	r.Min = min(r.Min, value)
+	r.Max = max(r.Max, value)
+}
+
+// add widens the range to include value
+func (r *Range) add(value float64) {
+	r.Min = min(r.Min, value)
+	r.Max = max(r.Max, value)
+}
+
+// String formats the range as "min..max", or as a single value if both are equal
+func (r IntRange) String() string {
+	if r.Min == r.Max {
+		return fmt.Sprintf("%d", r.Min)
+	}
+	return fmt.Sprintf("%d..%d", r.Min, r.Max)
+}
+
+// String formats the range as "min..max", or as a single value if both are equal
+// at the printed precision
+func (r Range) String() string {
+	lo, hi := fmt.Sprintf("%.2f", r.Min), fmt.Sprintf("%.2f", r.Max)
+	if lo == hi {
+		return lo
+	}
+	return lo + ".." + hi
+}
+
+// WriteJSON writes the merged report as indented JSON
+func (m *Merged) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(m)
+}
+
+// WriteText writes the merged report as a table of metric ranges. Packages
+// missing on some platforms list the platforms they exist on.
+func (m *Merged) WriteText(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintf(tw, "MODULE: %s\n", m.Module)
+	fmt.Fprintf(tw, "PLATFORMS: %s\n\n", strings.Join(m.Platforms, ", "))
+	fmt.Fprintln(tw, "PACKAGE\tCa\tCe\tI\tA\tD\tOnly on")
+	fmt.Fprintln(tw, "-------\t--\t--\t-\t-\t-\t-------")
+	for _, pkg := range m.Packages {
+		var only string
+		if len(pkg.Platforms) < len(m.Platforms) {
+			only = strings.Join(pkg.Platforms, ", ")
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", pkg.Name, pkg.Ca, pkg.Ce,
+			pkg.Instability, pkg.Abstractness, pkg.Distance, only)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,214 @@
+// Package platform compares the analysis of a module across build configurations
+// (GOOS/GOARCH), revealing imports that exist only on some platforms because of
+// build constraints or file name suffixes such as _windows.go.
+package platform
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/alkbt/aid-metrics/pkg/analyzer"
+	"github.com/alkbt/aid-metrics/pkg/models"
+)
+
+// Cell holds the coupling metrics of a package on one platform
+type Cell struct {
+	Ca          int     `json:"ca"`
+	Ce          int     `json:"ce"`
+	Instability float64 `json:"instability"`
+	Distance    float64 `json:"distance"`
+}
+
+// Row is a package with its metrics per platform
+type Row struct {
+	Name string `json:"package"`
+
+	// Cells holds one entry per platform of the matrix, in the same order;
+	// nil if the package has no files on the platform
+	Cells []*Cell `json:"cells"`
+
+	// Varies is set when the package is missing on some platform or its Ca or Ce differ
+	Varies bool `json:"varies"`
+}
+
+// Edge is an import between module packages that exists only on some platforms
+type Edge struct {
+	From      string   `json:"from"`
+	To        string   `json:"to"`
+	Platforms []string `json:"platforms"`
+}
+
+// Cycle is an import cycle that exists only on some platforms
+type Cycle struct {
+	Packages  []string `json:"packages"`
+	Platforms []string `json:"platforms"`
+}
+
+// Matrix is the comparison of the analyses of a module on several platforms
+type Matrix struct {
+	Module    string   `json:"module"`
+	Platforms []string `json:"platforms"`
+	Packages  []Row    `json:"packages"`
+	Edges     []Edge   `json:"platform_edges"`
+	Cycles    []Cycle  `json:"platform_cycles"`
+}
+
+// Analyze analyzes the module once per platform with the given options and
+// compares the results. The platforms are analyzed one after the other, as each
+// analysis already uses all CPUs.
+func Analyze(modulePath, pattern string, platforms []analyzer.Platform, options analyzer.AnalyzerOptions) (*Matrix, []*models.ModuleMetrics, error) {
+	names := make([]string, 0, len(platforms))
+	results := make([]*models.ModuleMetrics, 0, len(platforms))
+	for _, p := range platforms {
+		options.Platform = p
+		metrics, err := analyzer.AnalyzeModuleWithOptions(modulePath, pattern, options)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", p, err)
+		}
+		names = append(names, p.String())
+		results = append(results, metrics)
+	}
+	return Compare(names, results), results, nil
+}
+
+// Compare builds the matrix of the analyses of a module, one per platform.
+// Packages are matched by name.
+func Compare(platforms []string, results []*models.ModuleMetrics) *Matrix {
+	matrix := &Matrix{
+		Platforms: platforms,
+		Packages:  []Row{},
+		Edges:     []Edge{},
+		Cycles:    []Cycle{},
+	}
+	if len(results) > 0 {
+		matrix.Module = results[0].Path
+	}
+
+	rows := make(map[string]*Row)
+	edges := make(map[[2]string][]string)
+	cycles := make(map[string]*Cycle)
+	for i, metrics := range results {
+		for _, pkg := range metrics.Packages {
+			row, ok := rows[pkg.Name]
+			if !ok {
+				row = &Row{Name: pkg.Name, Cells: make([]*Cell, len(results))}
+				rows[pkg.Name] = row
+			}
+			row.Cells[i] = &Cell{Ca: pkg.Ca, Ce: pkg.Ce, Instability: pkg.Instability, Distance: pkg.Distance}
+			for _, dep := range pkg.Dependencies {
+				edge := [2]string{pkg.Name, dep}
+				edges[edge] = append(edges[edge], platforms[i])
+			}
+		}
+		for _, packages := range metrics.Cycles {
+			names := append([]string(nil), packages...)
+			sort.Strings(names)
+			key := strings.Join(names, "\x00")
+			if cycles[key] == nil {
+				cycles[key] = &Cycle{Packages: packages}
+			}
+			cycles[key].Platforms = append(cycles[key].Platforms, platforms[i])
+		}
+	}
+
+	for _, row := range rows {
+		row.Varies = varies(row.Cells)
+		matrix.Packages = append(matrix.Packages, *row)
+	}
+	sort.Slice(matrix.Packages, func(i, j int) bool { return matrix.Packages[i].Name < matrix.Packages[j].Name })
+
+	for edge, on := range edges {
+		if len(on) < len(results) {
+			matrix.Edges = append(matrix.Edges, Edge{From: edge[0], To: edge[1], Platforms: on})
+		}
+	}
+	sort.Slice(matrix.Edges, func(i, j int) bool {
+		if matrix.Edges[i].From != matrix.Edges[j].From {
+			return matrix.Edges[i].From < matrix.Edges[j].From
+		}
+		return matrix.Edges[i].To < matrix.Edges[j].To
+	})
+
+	for _, cycle := range cycles {
+		if len(cycle.Platforms) < len(results) {
+			matrix.Cycles = append(matrix.Cycles, *cycle)
+		}
+	}
+	sort.Slice(matrix.Cycles, func(i, j int) bool {
+		return strings.Join(matrix.Cycles[i].Packages, " ") < strings.Join(matrix.Cycles[j].Packages, " ")
+	})
+
+	return matrix
+}
+
+// varies reports whether a package is missing on some platform or its coupling differs
+func varies(cells []*Cell) bool {
+	for _, cell := range cells {
+		if cell == nil {
+			return true
+		}
+		if cell.Ca != cells[0].Ca || cell.Ce != cells[0].Ce {
+			return true
+		}
+	}
+	return false
+}
+
+// WriteJSON writes the matrix as indented JSON
+func (m *Matrix) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(m)
+}
+
+// WriteText writes the matrix as a table with one Ca/Ce column per platform,
+// followed by the platform-specific imports and cycles. Packages whose coupling
+// differs between platforms are marked with an asterisk.
+func (m *Matrix) WriteText(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintf(tw, "MODULE: %s\n\n", m.Module)
+	fmt.Fprintf(tw, "PACKAGE (Ca/Ce)\t%s\n", strings.Join(m.Platforms, "\t"))
+	for _, row := range m.Packages {
+		name := row.Name
+		if row.Varies {
+			name += " *"
+		}
+		cells := make([]string, 0, len(row.Cells))
+		for _, cell := range row.Cells {
+			if cell == nil {
+				cells = append(cells, "-")
+				continue
+			}
+			cells = append(cells, fmt.Sprintf("%d/%d", cell.Ca, cell.Ce))
+		}
+		fmt.Fprintf(tw, "%s\t%s\n", name, strings.Join(cells, "\t"))
+	}
+
+	if len(m.Edges) > 0 {
+		fmt.Fprintln(tw, "\nPLATFORM-SPECIFIC IMPORTS\tPlatforms")
+		for _, edge := range m.Edges {
+			fmt.Fprintf(tw, "%s -> %s\t%s\n", edge.From, edge.To, strings.Join(edge.Platforms, ", "))
+		}
+	}
+	if len(m.Cycles) > 0 {
+		fmt.Fprintln(tw, "\nPLATFORM-SPECIFIC CYCLES\tPlatforms")
+		for _, cycle := range m.Cycles {
+			fmt.Fprintf(tw, "%s\t%s\n", strings.Join(cycle.Packages, " -> "), strings.Join(cycle.Platforms, ", "))
+		}
+	}
+
+	var varying int
+	for _, row := range m.Packages {
+		if row.Varies {
+			varying++
+		}
+	}
+	fmt.Fprintf(tw, "\n%d platforms, %d packages, %d varying, %d platform-specific imports, %d platform-specific cycles\n",
+		len(m.Platforms), len(m.Packages), varying, len(m.Edges), len(m.Cycles))
+	return tw.Flush()
+}
@@ -0,0 +1,106 @@
+package platform
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/alkbt/aid-metrics/pkg/models"
+)
+
+func TestCompare(t *testing.T) {
+	linux := &models.ModuleMetrics{
+		Path: "example.com/cross",
+		Packages: map[string]models.PackageMetrics{
+			"example.com/cross/app":  {Name: "app", Ce: 1, Dependencies: []string{"util"}},
+			"example.com/cross/util": {Name: "util", Ca: 1},
+		},
+	}
+	windows := &models.ModuleMetrics{
+		Path: "example.com/cross",
+		Packages: map[string]models.PackageMetrics{
+			"example.com/cross/app":    {Name: "app", Ca: 1, Ce: 2, Dependencies: []string{"util", "winapi"}},
+			"example.com/cross/util":   {Name: "util", Ca: 1},
+			"example.com/cross/winapi": {Name: "winapi", Ca: 1, Ce: 1, Dependencies: []string{"app"}},
+		},
+		Cycles: [][]string{{"app", "winapi"}},
+	}
+
+	matrix := Compare([]string{"linux/amd64", "windows/amd64"}, []*models.ModuleMetrics{linux, windows})
+
+	if len(matrix.Packages) != 3 {
+		t.Fatalf("expected 3 packages, got %+v", matrix.Packages)
+	}
+	for _, row := range matrix.Packages {
+		if want := row.Name != "util"; row.Varies != want {
+			t.Errorf("%s: expected varies=%v", row.Name, want)
+		}
+	}
+	if winapi := matrix.Packages[2]; winapi.Cells[0] != nil || winapi.Cells[1] == nil {
+		t.Errorf("expected winapi to exist on windows only, got %+v", winapi.Cells)
+	}
+
+	if len(matrix.Edges) != 2 || matrix.Edges[0].From != "app" || matrix.Edges[0].To != "winapi" ||
+		len(matrix.Edges[0].Platforms) != 1 || matrix.Edges[0].Platforms[0] != "windows/amd64" {
+		t.Errorf("expected the windows-only imports app -> winapi and winapi -> app, got %+v", matrix.Edges)
+	}
+	if len(matrix.Cycles) != 1 || matrix.Cycles[0].Platforms[0] != "windows/amd64" {
+		t.Errorf("expected a windows-only cycle, got %+v", matrix.Cycles)
+	}
+
+	var buf bytes.Buffer
+	if err := matrix.WriteText(&buf); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{
+		"winapi *         -            1/1",
+		"app -> winapi              windows/amd64",
+		"2 platforms, 3 packages, 2 varying, 2 platform-specific imports, 1 platform-specific cycles",
+	} {
+		if !strings.Contains(buf.String(), line) {
+			t.Errorf("expected output to contain %q, got:\n%s", line, buf.String())
+		}
+	}
+}
+
+func TestMerge(t *testing.T) {
+	linux := &models.ModuleMetrics{
+		Path: "example.com/cross",
+		Packages: map[string]models.PackageMetrics{
+			"example.com/cross/app": {Name: "app", Ce: 1, Instability: 1, Distance: 0},
+			"example.com/cross/fs":  {Name: "fs", Ca: 1, Distance: 1},
+		},
+	}
+	windows := &models.ModuleMetrics{
+		Path: "example.com/cross",
+		Packages: map[string]models.PackageMetrics{
+			"example.com/cross/app":    {Name: "app", Ce: 3, Instability: 1, Distance: 0},
+			"example.com/cross/fs":     {Name: "fs", Ca: 1, Ce: 1, Instability: 0.5, Distance: 0.5},
+			"example.com/cross/winapi": {Name: "winapi", Ca: 1, Distance: 1},
+		},
+	}
+
+	merged := Merge([]string{"linux/amd64", "windows/amd64"}, []*models.ModuleMetrics{linux, windows})
+
+	if len(merged.Packages) != 3 {
+		t.Fatalf("expected 3 packages, got %+v", merged.Packages)
+	}
+	fs := merged.Packages[1]
+	if fs.Ce != (IntRange{0, 1}) || fs.Distance != (Range{0.5, 1}) || len(fs.Platforms) != 2 {
+		t.Errorf("unexpected ranges of fs: %+v", fs)
+	}
+
+	var buf bytes.Buffer
+	if err := merged.WriteText(&buf); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{
+		"app      0   1..3  1.00        0.00  0.00",
+		"fs       1   0..1  0.00..0.50  0.00  0.50..1.00",
+		"winapi   1   0     0.00        0.00  1.00        windows/amd64",
+	} {
+		if !strings.Contains(buf.String(), line) {
+			t.Errorf("expected output to contain %q, got:\n%s", line, buf.String())
+		}
+	}
+}
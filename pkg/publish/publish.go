@@ -0,0 +1,203 @@
+// Package publish implements one-shot publishing of analysis runs, designed
+// for scheduled jobs such as Kubernetes CronJobs: a run is compared against the
+// previous run of a sink, checked against a gate, stored in the sink and
+// announced to webhooks.
+package publish
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/alkbt/aid-metrics/pkg/config"
+	"github.com/alkbt/aid-metrics/pkg/models"
+	"github.com/alkbt/aid-metrics/pkg/reporter"
+)
+
+// Summary holds the module-wide aggregates of a run
+type Summary struct {
+	Packages         int     `json:"packages"`
+	Findings         int     `json:"findings"`
+	MeanInstability  float64 `json:"mean_instability"`
+	MeanAbstractness float64 `json:"mean_abstractness"`
+	MeanDistance     float64 `json:"mean_distance"`
+}
+
+// Summarize computes the aggregates of a run
+func Summarize(report *reporter.JSONReport) Summary {
+	summary := Summary{Packages: len(report.Packages), Findings: len(report.Findings)}
+	for _, pkg := range report.Packages {
+		summary.MeanInstability += pkg.Instability
+		summary.MeanAbstractness += pkg.Abstractness
+		summary.MeanDistance += pkg.Distance
+	}
+	if n := float64(len(report.Packages)); n > 0 {
+		summary.MeanInstability /= n
+		summary.MeanAbstractness /= n
+		summary.MeanDistance /= n
+	}
+	return summary
+}
+
+// Comparison describes how a run differs from the previous one
+type Comparison struct {
+	Current  Summary  `json:"current"`
+	Previous *Summary `json:"previous,omitempty"`
+
+	// NewFindings and ResolvedFindings are empty if there is no previous run.
+	// Findings are matched by ID and package, as messages contain metric values.
+	NewFindings      []reporter.JSONFinding `json:"new_findings,omitempty"`
+	ResolvedFindings []reporter.JSONFinding `json:"resolved_findings,omitempty"`
+}
+
+// Compare compares a run with the previous run, which may be nil
+func Compare(previous, current *reporter.JSONReport) Comparison {
+	c := Comparison{Current: Summarize(current)}
+	if previous == nil {
+		return c
+	}
+	summary := Summarize(previous)
+	c.Previous = &summary
+	c.NewFindings = findingsMissingFrom(current.Findings, previous.Findings)
+	c.ResolvedFindings = findingsMissingFrom(previous.Findings, current.Findings)
+	return c
+}
+
+// Changed reports whether findings appeared or disappeared since the previous run
+func (c Comparison) Changed() bool {
+	return len(c.NewFindings) > 0 || len(c.ResolvedFindings) > 0
+}
+
+// findingsMissingFrom returns the findings that have no counterpart in other
+func findingsMissingFrom(findings, other []reporter.JSONFinding) []reporter.JSONFinding {
+	seen := make(map[string]int)
+	for _, f := range other {
+		seen[f.ID+"\x00"+f.Package]++
+	}
+	var missing []reporter.JSONFinding
+	for _, f := range findings {
+		key := f.ID + "\x00" + f.Package
+		if seen[key] > 0 {
+			seen[key]--
+			continue
+		}
+		missing = append(missing, f)
+	}
+	return missing
+}
+
+// Check applies the gate to a run and returns why it fails, if it does
+func Check(gate config.PublishGate, current *reporter.JSONReport, c Comparison) ([]string, error) {
+	var reasons []string
+
+	if gate.Severity != "" {
+		severity, err := models.ParseSeverity(gate.Severity)
+		if err != nil {
+			return nil, err
+		}
+		var gated int
+		for _, f := range current.Findings {
+			if models.Severity(f.Severity).Level() >= severity.Level() {
+				gated++
+			}
+		}
+		if gated > 0 {
+			reasons = append(reasons, fmt.Sprintf("%d finding(s) with severity %s or higher", gated, severity))
+		}
+	}
+
+	if gate.NewFindings && len(c.NewFindings) > 0 {
+		reasons = append(reasons, fmt.Sprintf("%d new finding(s) since the previous run", len(c.NewFindings)))
+	}
+
+	if gate.MaxDistanceIncrease != nil && c.Previous != nil {
+		if increase := c.Current.MeanDistance - c.Previous.MeanDistance; increase > *gate.MaxDistanceIncrease {
+			reasons = append(reasons, fmt.Sprintf("mean distance grew by %.3f (from %.3f to %.3f), more than %.3f",
+				increase, c.Previous.MeanDistance, c.Current.MeanDistance, *gate.MaxDistanceIncrease))
+		}
+	}
+
+	return reasons, nil
+}
+
+// Notification is the JSON body posted to webhooks. Text is a one-line summary,
+// so chat webhooks (e.g. Slack incoming webhooks) can display it as is.
+type Notification struct {
+	Text    string     `json:"text"`
+	Module  string     `json:"module"`
+	Failed  bool       `json:"failed"`
+	Reasons []string   `json:"reasons,omitempty"`
+	Result  Comparison `json:"comparison"`
+}
+
+// NewNotification describes a run for webhooks
+func NewNotification(module string, c Comparison, reasons []string) Notification {
+	status := "passed"
+	if len(reasons) > 0 {
+		status = "failed: " + strings.Join(reasons, "; ")
+	}
+	text := fmt.Sprintf("aid-metrics %s: %s. %d packages, mean D=%.3f, %d findings (%d new, %d resolved)",
+		module, status, c.Current.Packages, c.Current.MeanDistance, c.Current.Findings, len(c.NewFindings), len(c.ResolvedFindings))
+	return Notification{Text: text, Module: module, Failed: len(reasons) > 0, Reasons: reasons, Result: c}
+}
+
+// Notify posts the notification to every webhook whose condition is met.
+// All webhooks are tried; the first error is returned.
+func Notify(ctx context.Context, client *http.Client, hooks []config.Webhook, n Notification) error {
+	body, err := json.Marshal(n)
+	if err != nil {
+		return err
+	}
+
+	var firstErr error
+	for _, hook := range hooks {
+		switch {
+		case hook.On == config.NotifyAlways,
+			hook.On == config.NotifyFailed && n.Failed,
+			hook.On == config.NotifyChanged && (n.Failed || n.Result.Changed()):
+		default:
+			continue
+		}
+		if err := post(ctx, client, hook.URL, body); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// post sends a JSON body to a webhook
+func post(ctx context.Context, client *http.Client, target string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return errors.New("invalid webhook URL") // The error would contain the URL
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		// The URL may carry a secret token, so only the host is reported
+		if urlErr, ok := err.(*url.Error); ok {
+			err = urlErr.Err
+		}
+		return fmt.Errorf("webhook %s failed: %w", req.URL.Host, err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("webhook %s failed: unexpected status %s", req.URL.Host, resp.Status)
+	}
+	return nil
+}
+
+// writeReport writes a run in the format of the JSON report
+func writeReport(w io.Writer, report *reporter.JSONReport) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(report)
+}
@@ -0,0 +1,126 @@
+package publish
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alkbt/aid-metrics/pkg/config"
+	"github.com/alkbt/aid-metrics/pkg/reporter"
+)
+
+func newReport(distance float64, findings ...reporter.JSONFinding) *reporter.JSONReport {
+	return &reporter.JSONReport{
+		Module:   "example.com/shop",
+		Packages: []reporter.JSONPackage{{Name: "api", Distance: distance}, {Name: "store", Distance: 1}},
+		Findings: findings,
+	}
+}
+
+func TestDirSink(t *testing.T) {
+	sink := Dir{Path: t.TempDir(), Keep: 2}
+	if latest, err := sink.Latest(); err != nil || latest != nil {
+		t.Fatalf("expected no runs, got %v, %v", latest, err)
+	}
+
+	start := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	for i, distance := range []float64{0.1, 0.2, 0.3} {
+		if err := sink.Write(newReport(distance), start.Add(time.Duration(i)*time.Hour)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	runs, err := sink.Runs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(runs) != 2 {
+		t.Fatalf("expected 2 retained runs, got %v", runs)
+	}
+	latest, err := sink.Latest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if latest.Module != "example.com/shop" || latest.Packages[0].Distance != 0.3 {
+		t.Errorf("unexpected latest run %+v", latest)
+	}
+}
+
+func TestCompareAndCheck(t *testing.T) {
+	cycle := reporter.JSONFinding{ID: "AM001", Severity: "error", Package: "api", Message: "cycle"}
+	sap := reporter.JSONFinding{ID: "AM003", Severity: "warning", Package: "store", Message: "D=0.80"}
+	sapMoved := reporter.JSONFinding{ID: "AM003", Severity: "warning", Package: "store", Message: "D=0.90"}
+
+	previous := newReport(0.2, cycle, sap)
+	current := newReport(0.4, sapMoved, reporter.JSONFinding{ID: "AM002", Severity: "warning", Package: "api"})
+	c := Compare(previous, current)
+
+	if len(c.NewFindings) != 1 || c.NewFindings[0].ID != "AM002" {
+		t.Errorf("unexpected new findings %+v", c.NewFindings)
+	}
+	if len(c.ResolvedFindings) != 1 || c.ResolvedFindings[0].ID != "AM001" {
+		t.Errorf("unexpected resolved findings %+v", c.ResolvedFindings)
+	}
+
+	maxIncrease := 0.05
+	tests := []struct {
+		gate    config.PublishGate
+		reasons int
+	}{
+		{config.PublishGate{}, 0},
+		{config.PublishGate{Severity: "error"}, 0},
+		{config.PublishGate{Severity: "warning"}, 1},
+		{config.PublishGate{NewFindings: true, MaxDistanceIncrease: &maxIncrease}, 2},
+	}
+	for _, tt := range tests {
+		reasons, err := Check(tt.gate, current, c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(reasons) != tt.reasons {
+			t.Errorf("%+v: got reasons %v, want %d", tt.gate, reasons, tt.reasons)
+		}
+	}
+
+	// Without a previous run, only the current findings can fail the gate
+	first := Compare(nil, current)
+	if reasons, _ := Check(config.PublishGate{NewFindings: true, MaxDistanceIncrease: &maxIncrease}, current, first); len(reasons) != 0 {
+		t.Errorf("expected the first run to pass, got %v", reasons)
+	}
+}
+
+func TestNotify(t *testing.T) {
+	var received []Notification
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var n Notification
+		if err := json.NewDecoder(r.Body).Decode(&n); err != nil {
+			t.Error(err)
+		}
+		received = append(received, n)
+	}))
+	defer hook.Close()
+
+	hooks := []config.Webhook{
+		{URL: hook.URL + "/always", On: config.NotifyAlways},
+		{URL: hook.URL + "/failed", On: config.NotifyFailed},
+		{URL: hook.URL + "/changed", On: config.NotifyChanged},
+	}
+	passed := NewNotification("example.com/shop", Compare(nil, newReport(0.2)), nil)
+	if err := Notify(context.Background(), http.DefaultClient, hooks, passed); err != nil {
+		t.Fatal(err)
+	}
+	if len(received) != 1 {
+		t.Fatalf("expected only the always webhook for a passing run, got %d notifications", len(received))
+	}
+
+	failed := NewNotification("example.com/shop", Compare(nil, newReport(0.2)), []string{"too far"})
+	if err := Notify(context.Background(), http.DefaultClient, hooks, failed); err != nil {
+		t.Fatal(err)
+	}
+	if len(received) != 4 || !received[3].Failed || received[3].Text == "" {
+		t.Errorf("expected all webhooks for a failing run, got %+v", received)
+	}
+}
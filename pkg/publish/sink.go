@@ -0,0 +1,122 @@
+package publish
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/alkbt/aid-metrics/pkg/reporter"
+)
+
+// runTimeFormat names run files so that lexical order is chronological
+const runTimeFormat = "20060102T150405Z"
+
+// Sink stores published runs
+type Sink interface {
+	// Latest returns the most recent run, or nil if there is none
+	Latest() (*reporter.JSONReport, error)
+
+	// Write stores a run
+	Write(report *reporter.JSONReport, at time.Time) error
+}
+
+// Dir is a sink storing every run as a JSON report named after its UTC time,
+// e.g. 20261016T150405Z.json
+type Dir struct {
+	Path string
+
+	// Keep is the number of runs retained. Zero keeps all runs.
+	Keep int
+}
+
+// Runs returns the paths of the stored runs, oldest first
+func (d Dir) Runs() ([]string, error) {
+	entries, err := os.ReadDir(d.Path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var runs []string
+	for _, entry := range entries {
+		name := entry.Name()
+		stamp, ok := strings.CutSuffix(name, ".json")
+		if !ok || entry.IsDir() {
+			continue
+		}
+		if _, err := time.Parse(runTimeFormat, stamp); err != nil {
+			continue
+		}
+		runs = append(runs, filepath.Join(d.Path, name))
+	}
+	sort.Strings(runs)
+	return runs, nil
+}
+
+// Latest reads the most recent run
+func (d Dir) Latest() (*reporter.JSONReport, error) {
+	runs, err := d.Runs()
+	if err != nil || len(runs) == 0 {
+		return nil, err
+	}
+	return ReadRun(runs[len(runs)-1])
+}
+
+// Write stores a run and deletes the oldest runs beyond Keep
+func (d Dir) Write(report *reporter.JSONReport, at time.Time) error {
+	if err := os.MkdirAll(d.Path, 0o755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(d.Path, ".run-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err := writeReport(tmp, report); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	path := filepath.Join(d.Path, at.UTC().Format(runTimeFormat)+".json")
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+
+	if d.Keep <= 0 {
+		return nil
+	}
+	runs, err := d.Runs()
+	if err != nil {
+		return err
+	}
+	for len(runs) > d.Keep {
+		if err := os.Remove(runs[0]); err != nil {
+			return err
+		}
+		runs = runs[1:]
+	}
+	return nil
+}
+
+// ReadRun reads a stored run
+func ReadRun(path string) (*reporter.JSONReport, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	report, err := reporter.ReadJSONReport(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return report, nil
+}
@@ -0,0 +1,103 @@
+// Package reporter handles output generation for aid-metrics analysis results.
+// This file implements the compact ai-context format intended for LLM coding agents.
+package reporter
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/alkbt/aid-metrics/pkg/models"
+)
+
+// aiContextWorstPackages is the number of packages detailed in the ai-context report
+const aiContextWorstPackages = 10
+
+// generateAIContextReport generates a compact, token-efficient summary of the module:
+// aggregates, the worst packages with their edges, cycles and findings. It is designed
+// to be pasted into or fetched by coding agents guiding refactors.
+func (r *Reporter) generateAIContextReport(w io.Writer) error {
+	pkgs := make([]models.PackageMetrics, 0, len(r.metrics.Packages))
+	var sumI, sumA, sumD float64
+	for _, pkg := range r.metrics.Packages {
+		pkgs = append(pkgs, pkg)
+		sumI += pkg.Instability
+		sumA += pkg.Abstractness
+		sumD += pkg.Distance
+	}
+	sort.Slice(pkgs, func(i, j int) bool {
+		if pkgs[i].Distance != pkgs[j].Distance {
+			return pkgs[i].Distance > pkgs[j].Distance
+		}
+		return pkgs[i].Name < pkgs[j].Name
+	})
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# aid-metrics context: %s\n", r.metrics.Path)
+	b.WriteString("# Ca=dependents Ce=dependencies (api=exposed in exported API) I=Ce/(Ca+Ce) A=interfaces/types D=|A+I-1| (0 best); <- dependents, -> dependencies\n")
+	if n := float64(len(pkgs)); n > 0 {
+		fmt.Fprintf(&b, "module packages=%d avgI=%s avgA=%s avgD=%s cycles=%d findings=%d\n",
+			len(pkgs), compactFloat(sumI/n), compactFloat(sumA/n), compactFloat(sumD/n),
+			len(r.metrics.Cycles), len(r.metrics.Findings))
+	}
+
+	// Gate-exempt packages such as entry points would crowd out the packages worth refactoring
+	var worst []models.PackageMetrics
+	var exempt []string
+	for _, pkg := range pkgs {
+		if pkg.GateExempt {
+			exempt = append(exempt, fmt.Sprintf("%s(%s)", pkg.Name, pkg.GateExemptReason))
+			continue
+		}
+		worst = append(worst, pkg)
+	}
+	if len(worst) > aiContextWorstPackages {
+		worst = worst[:aiContextWorstPackages]
+	}
+	fmt.Fprintf(&b, "worst %d by D:\n", len(worst))
+	for _, pkg := range worst {
+		ce := fmt.Sprintf("%d", pkg.Ce)
+		if pkg.CeExported > 0 {
+			ce += fmt.Sprintf("(api=%d)", pkg.CeExported)
+		}
+		fmt.Fprintf(&b, "%s D=%s A=%s I=%s Ca=%d Ce=%s role=%s\n",
+			pkg.Name, compactFloat(pkg.Distance), compactFloat(pkg.Abstractness),
+			compactFloat(pkg.Instability), pkg.Ca, ce, pkg.Role)
+		if len(pkg.Dependents) > 0 {
+			fmt.Fprintf(&b, " <- %s\n", strings.Join(pkg.Dependents, ","))
+		}
+		if len(pkg.Dependencies) > 0 {
+			fmt.Fprintf(&b, " -> %s\n", strings.Join(pkg.Dependencies, ","))
+		}
+	}
+
+	if len(exempt) > 0 {
+		sort.Strings(exempt)
+		fmt.Fprintf(&b, "exempt from D gating: %s\n", strings.Join(exempt, ","))
+	}
+
+	if len(r.metrics.Cycles) > 0 {
+		b.WriteString("cycles:\n")
+		for _, cycle := range r.metrics.Cycles {
+			fmt.Fprintf(&b, " %s\n", strings.Join(cycle, ","))
+		}
+	}
+
+	if len(r.metrics.Findings) > 0 {
+		b.WriteString("findings:\n")
+		for _, finding := range r.metrics.Findings {
+			fmt.Fprintf(&b, " %s %s %s: %s\n", finding.ID, finding.Severity, finding.Package, finding.Message)
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// compactFloat formats a metric with at most two decimals and no trailing zeros
+func compactFloat(v float64) string {
+	return strconv.FormatFloat(math.Round(v*100)/100, 'f', -1, 64)
+}
@@ -0,0 +1,42 @@
+// Package reporter handles output generation for aid-metrics analysis results.
+// This file implements the ASCII-only output mode.
+package reporter
+
+import (
+	"fmt"
+	"io"
+	"unicode/utf8"
+)
+
+// asciiWriter escapes the non-ASCII characters written through it: runes as
+// \uXXXX (\UXXXXXXXX beyond the BMP) and invalid UTF-8 bytes as \xXX.
+// Every write must hold complete runes, as the writes of package fmt do.
+type asciiWriter struct {
+	w io.Writer
+}
+
+// Write writes p to the underlying writer with non-ASCII characters escaped
+func (a asciiWriter) Write(p []byte) (int, error) {
+	escaped := make([]byte, 0, len(p))
+	for i := 0; i < len(p); {
+		if p[i] < utf8.RuneSelf {
+			escaped = append(escaped, p[i])
+			i++
+			continue
+		}
+		r, size := utf8.DecodeRune(p[i:])
+		switch {
+		case r == utf8.RuneError && size == 1:
+			escaped = fmt.Appendf(escaped, `\x%02x`, p[i])
+		case r > 0xFFFF:
+			escaped = fmt.Appendf(escaped, `\U%08X`, r)
+		default:
+			escaped = fmt.Appendf(escaped, `\u%04X`, r)
+		}
+		i += size
+	}
+	if _, err := a.w.Write(escaped); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
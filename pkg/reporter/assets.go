@@ -0,0 +1,80 @@
+// Package reporter handles output generation for aid-metrics analysis results.
+// This file implements the file system of templates used by the HTML, SVG and DSM reports.
+package reporter
+
+import (
+	"embed"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"regexp"
+	"strings"
+)
+
+// Names of the templates looked up in the assets of a report (see ReportOptions.Assets).
+// The built-in templates load no scripts, stylesheets or fonts from the network:
+// their CSS and JavaScript are inline and original to this project, and text uses
+// the fonts installed on the viewer's system.
+const (
+	// HTMLTemplate renders the HTML report. It has no external dependencies,
+	// so the page works offline and from any artifact store.
+	HTMLTemplate = "report.html.tmpl"
+
+	// SVGTemplate renders the SVG chart. The image is self-contained, so it can
+	// be embedded in documents and wikis; hovering a point shows its metrics.
+	SVGTemplate = "chart.svg.tmpl"
+
+	// DSMTemplate renders the design structure matrix as an HTML heatmap
+	DSMTemplate = "dsm.html.tmpl"
+)
+
+//go:embed assets
+var embeddedAssets embed.FS
+
+// DefaultAssets returns the built-in templates of the HTML, SVG and DSM reports.
+// Tools customizing a report can copy them as a starting point.
+func DefaultAssets() fs.FS {
+	assets, err := fs.Sub(embeddedAssets, "assets")
+	if err != nil {
+		panic(err) // the directory is embedded at build time
+	}
+	return assets
+}
+
+// template parses the named template from the report assets, falling back to
+// DefaultAssets when no assets are configured
+func (r *Reporter) template(name string) (*template.Template, error) {
+	assets := r.options.Assets
+	if assets == nil {
+		assets = DefaultAssets()
+	}
+	if r.options.Offline {
+		content, err := fs.ReadFile(assets, name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load report template: %w", err)
+		}
+		if refs := ExternalReferences(content); len(refs) > 0 {
+			return nil, fmt.Errorf("report template %s loads resources from the network, which offline reports must not: %s", name, strings.Join(refs, ", "))
+		}
+	}
+	tmpl, err := template.ParseFS(assets, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load report template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// externalReference matches the places a page or image loads a resource from,
+// src attributes, link, image and use tags, CSS url() and @import, followed by
+// an absolute or protocol-relative URL. Plain links are not loaded, so they are fine.
+var externalReference = regexp.MustCompile(`(?i)(?:\bsrc\s*=\s*["']?|<(?:link|image|use)\b[^>]*\bhref\s*=\s*["']?|url\(\s*["']?|@import\s+["']?)((?:https?:)?//[^"'\s)>]+)`)
+
+// ExternalReferences returns the URLs of the resources a template or page loads
+// from the network, e.g. scripts and fonts from a CDN, in order of appearance
+func ExternalReferences(content []byte) []string {
+	var refs []string
+	for _, match := range externalReference.FindAllSubmatch(content, -1) {
+		refs = append(refs, string(match[1]))
+	}
+	return refs
+}
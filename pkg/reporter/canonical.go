@@ -0,0 +1,140 @@
+// Package reporter handles output generation for aid-metrics analysis results.
+// This file implements the canonical form of JSON reports, for snapshots kept under version control.
+package reporter
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// jsonDocument declares every section the JSON report may have, so CanonicalJSON
+// knows the Go type, and hence whether a number is a float, of every member
+type jsonDocument struct {
+	JSONReport
+	Roles          []jsonRole           `json:"roles"`
+	Endpoints      []jsonEndpoint       `json:"endpoints"`
+	SideEffects    []jsonSideEffect     `json:"side_effects"`
+	StringCoupling []jsonStringCoupling `json:"string_coupling"`
+}
+
+// CanonicalJSON rewrites a JSON report in canonical form: the members of every
+// object sorted by key, the float fields of the report (ratios, means and the
+// like) written with precision decimals even if integral, and two-space
+// indentation. Reports with the same content, up to the precision, are byte for
+// byte identical, so snapshots diff minimally. Numbers of members the report does
+// not declare are written with precision decimals if they have a fraction.
+func CanonicalJSON(data []byte, precision int) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var doc any
+	if err := dec.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("failed to parse JSON: %w", err)
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetIndent("", "  ")
+	// Maps are encoded with their keys sorted
+	if err := enc.Encode(canonicalValue(doc, reflect.TypeFor[jsonDocument](), precision)); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// canonicalValue returns a decoded JSON value with its numbers in canonical form.
+// t is the Go type the value was encoded from, or nil if it is unknown.
+func canonicalValue(value any, t reflect.Type, precision int) any {
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch v := value.(type) {
+	case map[string]any:
+		var fields map[string]reflect.Type
+		if t != nil && t.Kind() == reflect.Struct {
+			fields = jsonFields(t)
+		}
+		for key, member := range v {
+			var memberType reflect.Type
+			switch {
+			case fields != nil:
+				memberType = fields[key]
+			case t != nil && t.Kind() == reflect.Map:
+				memberType = t.Elem()
+			}
+			v[key] = canonicalValue(member, memberType, precision)
+		}
+	case []any:
+		var elemType reflect.Type
+		if t != nil && (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) {
+			elemType = t.Elem()
+		}
+		for i, elem := range v {
+			v[i] = canonicalValue(elem, elemType, precision)
+		}
+	case json.Number:
+		isFloat := t != nil && (t.Kind() == reflect.Float64 || t.Kind() == reflect.Float32)
+		if !isFloat && (t != nil || !strings.ContainsAny(string(v), ".eE")) {
+			return v
+		}
+		f, err := v.Float64()
+		if err != nil {
+			return v
+		}
+		s := strconv.FormatFloat(f, 'f', precision, 64)
+		if strings.Trim(s, "-0.") == "" {
+			// Rounded to zero: no negative zero
+			s = strings.TrimPrefix(s, "-")
+		}
+		return json.Number(s)
+	}
+	return value
+}
+
+// jsonFields returns the types of the members of a struct by their JSON names,
+// including those of embedded structs
+func jsonFields(t reflect.Type) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		switch {
+		case name == "-" || !field.IsExported() && !field.Anonymous:
+			continue
+		case field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct:
+			for embedded, fieldType := range jsonFields(field.Type) {
+				if _, ok := fields[embedded]; !ok {
+					fields[embedded] = fieldType
+				}
+			}
+			continue
+		case name == "":
+			name = field.Name
+		}
+		fields[name] = field.Type
+	}
+	return fields
+}
+
+// generateCanonicalJSONReport generates the JSON report in canonical form (see
+// CanonicalJSON), with ratios written with the precision of the report. The
+// JSON report holds no timestamps, so a snapshot of the same metrics is
+// identical. Unlike the JSON report, it is built in memory before writing.
+func (r *Reporter) generateCanonicalJSONReport(w io.Writer) error {
+	plain := *r
+	plain.options.Canonical = false
+	var buf bytes.Buffer
+	if err := plain.generateJSONReport(&buf); err != nil {
+		return err
+	}
+	data, err := CanonicalJSON(buf.Bytes(), r.precision())
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
@@ -0,0 +1,134 @@
+// Package reporter handles output generation for aid-metrics analysis results.
+// This file implements the column selection of the text and CSV package tables.
+package reporter
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/alkbt/aid-metrics/pkg/models"
+)
+
+// DefaultPrecision is the number of decimals of ratios when ReportOptions.Precision is 0
+const DefaultPrecision = 2
+
+// packageColumn is a column of the package tables of the text and CSV reports.
+// Exactly one of the value functions is set.
+type packageColumn struct {
+	key   string // Name in ReportOptions.Columns
+	text  string // Header in the text report
+	csv   string // Header in the CSV report
+	int   func(pkg models.PackageMetrics, layer int) int
+	float func(pkg models.PackageMetrics) float64
+	str   func(pkg models.PackageMetrics) string
+}
+
+// packageColumns lists the selectable columns in their default order
+var packageColumns = []packageColumn{
+	{key: "ca", text: "Ca", csv: "Ca", int: func(pkg models.PackageMetrics, _ int) int { return pkg.Ca }},
+	{key: "ce", text: "Ce", csv: "Ce", int: func(pkg models.PackageMetrics, _ int) int { return pkg.Ce }},
+	{key: "tca", text: "TCa", csv: "TransitiveCa", int: func(pkg models.PackageMetrics, _ int) int { return pkg.TransitiveCa }},
+	{key: "tce", text: "TCe", csv: "TransitiveCe", int: func(pkg models.PackageMetrics, _ int) int { return pkg.TransitiveCe }},
+	{key: "i", text: "I", csv: "I", float: func(pkg models.PackageMetrics) float64 { return pkg.Instability }},
+	{key: "na", text: "Na", csv: "Na", int: func(pkg models.PackageMetrics, _ int) int { return pkg.Na }},
+	{key: "nc", text: "Nc", csv: "Nc", int: func(pkg models.PackageMetrics, _ int) int { return pkg.Nc }},
+	{key: "a", text: "A", csv: "A", float: func(pkg models.PackageMetrics) float64 { return pkg.Abstractness }},
+	{key: "a_all", text: "A (all)", csv: "AAll", float: func(pkg models.PackageMetrics) float64 { return pkg.AbstractnessAll }},
+	{key: "d", text: "D", csv: "D", float: func(pkg models.PackageMetrics) float64 { return pkg.Distance }},
+	{key: "health", text: "Health", csv: "Health", int: func(pkg models.PackageMetrics, _ int) int { return pkg.Health }},
+	{key: "zone", text: "Zone", csv: "Zone", str: func(pkg models.PackageMetrics) string { return pkg.Zone }},
+	{key: "layer", text: "Layer", csv: "Layer", int: func(_ models.PackageMetrics, layer int) int { return layer }},
+	{key: "go", text: "Go", csv: "Goroutines", int: func(pkg models.PackageMetrics, _ int) int { return concurrencyOf(pkg).Goroutines }},
+	{key: "chan", text: "Chan", csv: "Channels", int: func(pkg models.PackageMetrics, _ int) int { return concurrencyOf(pkg).Channels }},
+	{key: "sync", text: "Sync", csv: "SyncPrimitives", int: func(pkg models.PackageMetrics, _ int) int { return concurrencyOf(pkg).SyncPrimitives }},
+}
+
+// concurrencyColumns are the columns of the concurrency metric group
+var concurrencyColumns = []string{"go", "chan", "sync"}
+
+// optionalColumns are only shown when selected
+var optionalColumns = []string{"tca", "tce"}
+
+// concurrencyOf returns the concurrency metric group of a package, zero if it has none
+func concurrencyOf(pkg models.PackageMetrics) models.ConcurrencyMetrics {
+	c, _, _ := models.GetExtension[models.ConcurrencyMetrics](pkg, models.ConcurrencyGroup)
+	return c
+}
+
+// PackageColumns returns the column names accepted by ReportOptions.Columns
+func PackageColumns() []string {
+	keys := make([]string, len(packageColumns))
+	for i, column := range packageColumns {
+		keys[i] = column.key
+	}
+	return keys
+}
+
+// columns returns the columns of the package tables after the package name:
+// those of options.Columns, or by default all of them except A (all) unless
+// only exported declarations were counted, the layer unless sorting by it, the
+// concurrency columns unless the packages have that metric group and the
+// optionalColumns
+func (r *Reporter) columns() ([]packageColumn, error) {
+	if len(r.options.Columns) == 0 {
+		concurrency := r.hasConcurrency()
+		var columns []packageColumn
+		for _, column := range packageColumns {
+			if (column.key == "a_all" && !r.metrics.ExportedOnly) || (column.key == "layer" && r.options.Sort != SortTopo) ||
+				(slices.Contains(concurrencyColumns, column.key) && !concurrency) || slices.Contains(optionalColumns, column.key) {
+				continue
+			}
+			columns = append(columns, column)
+		}
+		return columns, nil
+	}
+
+	columns := make([]packageColumn, 0, len(r.options.Columns))
+	for _, key := range r.options.Columns {
+		column, ok := findColumn(strings.ToLower(strings.TrimSpace(key)))
+		if !ok {
+			return nil, fmt.Errorf("unknown column %q (%s)", key, strings.Join(PackageColumns(), ", "))
+		}
+		columns = append(columns, column)
+	}
+	return columns, nil
+}
+
+// findColumn returns the column named key
+func findColumn(key string) (packageColumn, bool) {
+	for _, column := range packageColumns {
+		if column.key == key {
+			return column, true
+		}
+	}
+	return packageColumn{}, false
+}
+
+// hasConcurrency reports whether any package has the concurrency metric group
+func (r *Reporter) hasConcurrency() bool {
+	for _, pkg := range r.metrics.Packages {
+		if _, ok := pkg.Extensions[models.ConcurrencyGroup]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// hasLayer reports whether the columns include the dependency layer
+func hasLayer(columns []packageColumn) bool {
+	for _, column := range columns {
+		if column.key == "layer" {
+			return true
+		}
+	}
+	return false
+}
+
+// precision returns the number of decimals of ratios in the text and CSV reports
+func (r *Reporter) precision() int {
+	if r.options.Precision > 0 {
+		return r.options.Precision
+	}
+	return DefaultPrecision
+}
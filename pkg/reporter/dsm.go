@@ -0,0 +1,133 @@
+// Package reporter handles output generation for aid-metrics analysis results.
+// This file implements the design structure matrix (DSM) of package dependencies.
+package reporter
+
+import "io"
+
+// dsmCell is a cell of the matrix: whether the row package depends on the
+// column package
+type dsmCell struct {
+	// Class is empty for no dependency, "self" on the diagonal, "dep" for a
+	// dependency and "feedback" for a dependency above the diagonal of a
+	// partitioned matrix, which only import cycles produce. "boundary" is
+	// added to the first column of a layer.
+	Class string
+	Title string
+}
+
+// dsmRow is a package of the matrix with its row of cells
+type dsmRow struct {
+	Index    int // Position in the matrix, from 1
+	Name     string
+	Layer    int
+	Boundary bool // First package of a layer of a partitioned matrix
+	Ce       int  // Dependencies among the packages of the matrix
+	Ca       int  // Dependents among the packages of the matrix
+	Cells    []dsmCell
+}
+
+// dsmReport is the data rendered by DSMTemplate
+type dsmReport struct {
+	Module      string
+	Partitioned bool
+	Feedback    int // Marks above the diagonal of a partitioned matrix
+	Packages    []dsmRow
+}
+
+// dsm builds the matrix of the packages in the order of the text report. With
+// SortTopo the matrix is partitioned: dependencies come before their dependents,
+// so every mark falls below the diagonal except those of import cycles, whose
+// packages share a layer.
+func (r *Reporter) dsm() dsmReport {
+	var layers map[string]int
+	if r.options.Sort == SortTopo {
+		layers = r.dependencyLayers()
+	}
+	ids := r.packageIDsInOrder(layers)
+	index := make(map[string]int, len(ids))
+	for i, id := range ids {
+		index[r.metrics.Packages[id].Name] = i
+	}
+
+	report := dsmReport{Module: r.metrics.Path, Partitioned: layers != nil}
+	for i, id := range ids {
+		pkg := r.metrics.Packages[id]
+		row := dsmRow{Index: i + 1, Name: pkg.Name, Layer: layers[id], Cells: make([]dsmCell, len(ids))}
+		row.Boundary = report.Partitioned && i > 0 && layers[ids[i-1]] != row.Layer
+		row.Cells[i].Class = "self"
+		for _, dep := range pkg.Dependencies {
+			j, ok := index[dep]
+			if !ok || j == i {
+				continue
+			}
+			row.Ce++
+			row.Cells[j] = dsmCell{Class: "dep", Title: pkg.Name + " imports " + dep}
+			if report.Partitioned && j > i {
+				row.Cells[j].Class = "feedback"
+				report.Feedback++
+			}
+		}
+		report.Packages = append(report.Packages, row)
+	}
+	for i := range report.Packages {
+		for j, row := range report.Packages {
+			if row.Cells[i].Title != "" {
+				report.Packages[i].Ca++
+			}
+			if report.Packages[i].Boundary {
+				report.Packages[j].Cells[i].Class = joinClass(report.Packages[j].Cells[i].Class, "boundary")
+			}
+		}
+	}
+	return report
+}
+
+// joinClass appends a CSS class to a class attribute
+func joinClass(class, add string) string {
+	if class == "" {
+		return add
+	}
+	return class + " " + add
+}
+
+// generateDSMReport writes the matrix as CSV: a header row of package names,
+// then one row per package with 1 in the columns of its dependencies. A
+// partitioned matrix has the dependency layer of each package after its name.
+func (r *Reporter) generateDSMReport(w io.Writer) error {
+	dsm := r.dsm()
+	c := newCSVStream(w)
+
+	c.str("Package")
+	if dsm.Partitioned {
+		c.str("Layer")
+	}
+	for _, row := range dsm.Packages {
+		c.str(row.Name)
+	}
+	c.end()
+
+	for _, row := range dsm.Packages {
+		c.str(row.Name)
+		if dsm.Partitioned {
+			c.int(row.Layer)
+		}
+		for _, cell := range row.Cells {
+			if cell.Title != "" {
+				c.str("1")
+			} else {
+				c.str("")
+			}
+		}
+		c.end()
+	}
+	return c.flush()
+}
+
+// generateDSMHTMLReport renders the matrix as a standalone HTML heatmap
+func (r *Reporter) generateDSMHTMLReport(w io.Writer) error {
+	tmpl, err := r.template(DSMTemplate)
+	if err != nil {
+		return err
+	}
+	return tmpl.Execute(w, r.dsm())
+}
@@ -0,0 +1,119 @@
+// Package reporter handles output generation for aid-metrics analysis results.
+// This file implements the package dependency graph as Graphviz DOT and Mermaid diagrams.
+package reporter
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// graphNode is a package of the dependency graph with its dependencies among
+// the packages of the graph
+type graphNode struct {
+	ID   string // Identifier in the diagram, derived from the name alone (see graphID)
+	Name string
+	Deps []string // IDs of the dependencies, sorted
+}
+
+// graph returns the packages in the order of the text report (partitioned into
+// dependency layers with SortTopo) with their dependencies sorted, so the
+// diagrams are identical for identical metrics and diff cleanly between runs
+func (r *Reporter) graph() []graphNode {
+	var layers map[string]int
+	if r.options.Sort == SortTopo {
+		layers = r.dependencyLayers()
+	}
+	ids := r.packageIDsInOrder(layers)
+
+	nodes := make([]graphNode, len(ids))
+	byName := make(map[string]string, len(ids))
+	for i, id := range ids {
+		pkg := r.metrics.Packages[id]
+		byName[pkg.Name] = graphID(pkg.Name)
+		nodes[i] = graphNode{ID: byName[pkg.Name], Name: pkg.Name}
+	}
+	for i, id := range ids {
+		for _, dep := range r.metrics.Packages[id].Dependencies {
+			if depID, ok := byName[dep]; ok && depID != nodes[i].ID {
+				nodes[i].Deps = append(nodes[i].Deps, depID)
+			}
+		}
+		sort.Strings(nodes[i].Deps)
+	}
+	return nodes
+}
+
+// graphID turns a package name into an identifier valid in DOT and Mermaid.
+// Letters and digits are kept and every other character is escaped after an
+// underscore ("_" as "__", "/" as "_s", "." as "_d", "-" as "_h", other bytes
+// as "_xHH"), so distinct names never share an identifier and the identifier
+// of a package depends on nothing but its name.
+func graphID(name string) string {
+	var b strings.Builder
+	b.WriteString("p_")
+	for i := 0; i < len(name); i++ {
+		switch c := name[i]; {
+		case c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9':
+			b.WriteByte(c)
+		case c == '_':
+			b.WriteString("__")
+		case c == '/':
+			b.WriteString("_s")
+		case c == '.':
+			b.WriteString("_d")
+		case c == '-':
+			b.WriteString("_h")
+		default:
+			fmt.Fprintf(&b, "_x%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// generateDOTReport writes the dependency graph in the Graphviz DOT language.
+// The dot layout is deterministic for a given input; LayoutSeed sets the start
+// attribute, which fixes the initial positions of the neato, fdp and sfdp layouts.
+func (r *Reporter) generateDOTReport(w io.Writer) error {
+	nodes := r.graph()
+	fmt.Fprintf(w, "digraph %s {\n", dotQuote(r.metrics.Path))
+	fmt.Fprintln(w, "\trankdir=LR;")
+	fmt.Fprintln(w, "\tordering=out;")
+	if r.options.LayoutSeed != 0 {
+		fmt.Fprintf(w, "\tstart=%d;\n", r.options.LayoutSeed)
+	}
+	fmt.Fprintln(w, "\tnode [shape=box];")
+	for _, node := range nodes {
+		fmt.Fprintf(w, "\t%s [label=%s];\n", node.ID, dotQuote(node.Name))
+	}
+	for _, node := range nodes {
+		for _, dep := range node.Deps {
+			fmt.Fprintf(w, "\t%s -> %s;\n", node.ID, dep)
+		}
+	}
+	_, err := fmt.Fprintln(w, "}")
+	return err
+}
+
+// dotQuote returns s as a DOT string
+func dotQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// generateMermaidReport writes the dependency graph as a Mermaid flowchart, for
+// Markdown documentation. Mermaid lays out the graph deterministically from the
+// order of its statements, so LayoutSeed does not apply.
+func (r *Reporter) generateMermaidReport(w io.Writer) error {
+	nodes := r.graph()
+	fmt.Fprintln(w, "flowchart LR")
+	for _, node := range nodes {
+		fmt.Fprintf(w, "\t%s[\"%s\"]\n", node.ID, strings.ReplaceAll(node.Name, `"`, "#quot;"))
+	}
+	for _, node := range nodes {
+		for _, dep := range node.Deps {
+			fmt.Fprintf(w, "\t%s --> %s\n", node.ID, dep)
+		}
+	}
+	return nil
+}
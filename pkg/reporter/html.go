@@ -0,0 +1,155 @@
+// Package reporter handles output generation for aid-metrics analysis results.
+// This file implements the standalone HTML report with a sortable, filterable table
+// and the A/I chart.
+package reporter
+
+import (
+	"io"
+	"math"
+	"sort"
+
+	"github.com/alkbt/aid-metrics/pkg/models"
+)
+
+// htmlDefaultMaxDistance is the distance above which a package is highlighted
+// when its role has no thresholds in the metrics
+const htmlDefaultMaxDistance = 0.7
+
+// Layout of the A/I chart: the plot area is htmlChartSize pixels square, with
+// htmlChartMargin pixels around it for the axis labels
+const (
+	htmlChartSize   = 360
+	htmlChartMargin = 40
+)
+
+// htmlMinChange is the smallest change of I, A or D shown as a change since the baseline
+const htmlMinChange = 0.005
+
+// htmlPackage is a table row of the HTML report
+type htmlPackage struct {
+	models.PackageMetrics
+
+	// MaxDistance is the distance limit of the package's role
+	MaxDistance float64
+
+	// HighDistance is set when the package exceeds MaxDistance.
+	// Isolated and gate-exempt packages are never highlighted.
+	HighDistance bool
+
+	// X and Y are the position of the package on the A/I chart
+	X, Y float64
+
+	// Baseline holds the metrics of the package in the baseline report, if it was there
+	Baseline *JSONPackage
+
+	// BaselineX and BaselineY are the position of the package in the baseline
+	BaselineX, BaselineY float64
+
+	// Moved is set when the package moved on the A/I chart since the baseline
+	Moved bool
+
+	// DeltaDistance is the change of D since the baseline, and DistanceTrend
+	// "better" or "worse" if it is significant
+	DeltaDistance float64
+	DistanceTrend string
+}
+
+// htmlReport is the data rendered by HTMLTemplate
+type htmlReport struct {
+	Module   string
+	Summary  *JSONSummary
+	Zones    string // Packages per zone, empty without zones
+	Packages []htmlPackage
+	Findings []models.Finding
+	Warnings []models.Finding // Advisory findings, listed apart as they fail no gate
+
+	// HasBaseline is set when the report compares against a baseline
+	HasBaseline bool
+
+	// HasEdges is set when the table lists the dependents and dependencies
+	HasEdges bool
+
+	// Removed lists the baseline packages that no longer exist
+	Removed []string
+}
+
+// generateHTMLReport generates a standalone HTML page that can be published as a
+// CI artifact. The table can be sorted by clicking a column header and filtered
+// by package name; packages whose distance exceeds the limit of their role are highlighted.
+// With a baseline, the chart connects each package's baseline position (a ghost
+// point) to its current position, and the table shows the change of D.
+func (r *Reporter) generateHTMLReport(w io.Writer) error {
+	report := htmlReport{Module: r.metrics.Path, HasBaseline: r.options.Baseline != nil, HasEdges: r.options.Edges}
+
+	baseline := make(map[string]JSONPackage)
+	if r.options.Baseline != nil {
+		for _, pkg := range r.options.Baseline.Packages {
+			baseline[pkg.Name] = pkg
+		}
+	}
+
+	for _, id := range r.packageIDsByName() {
+		pkg := r.metrics.Packages[id]
+		row := htmlPackage{
+			PackageMetrics: pkg,
+			MaxDistance:    r.maxDistance(pkg),
+			HighDistance:   r.highDistance(pkg),
+		}
+		row.X, row.Y = chartPosition(pkg.Instability, pkg.Abstractness)
+		if old, ok := baseline[pkg.Name]; ok {
+			row.Baseline = &old
+			row.BaselineX, row.BaselineY = chartPosition(old.Instability, old.Abstractness)
+			row.Moved = math.Abs(pkg.Instability-old.Instability) >= htmlMinChange ||
+				math.Abs(pkg.Abstractness-old.Abstractness) >= htmlMinChange
+			row.DeltaDistance = pkg.Distance - old.Distance
+			switch {
+			case row.DeltaDistance >= htmlMinChange:
+				row.DistanceTrend = "worse"
+			case row.DeltaDistance <= -htmlMinChange:
+				row.DistanceTrend = "better"
+			}
+			delete(baseline, pkg.Name)
+		}
+		report.Packages = append(report.Packages, row)
+	}
+	for name := range baseline {
+		report.Removed = append(report.Removed, name)
+	}
+	sort.Strings(report.Removed)
+	if r.metrics.Summary.Packages > 0 {
+		summary := NewJSONSummary(r.metrics.Summary)
+		report.Summary = &summary
+	}
+	if len(r.metrics.Summary.Zones) > 0 {
+		report.Zones = zoneCounts(r.metrics.Summary)
+	}
+	if r.options.Findings {
+		report.Findings, report.Warnings = splitAdvisory(r.metrics.Findings)
+	}
+
+	tmpl, err := r.template(HTMLTemplate)
+	if err != nil {
+		return err
+	}
+	return tmpl.Execute(w, report)
+}
+
+// maxDistance returns the distance limit of the package's role
+func (r *Reporter) maxDistance(pkg models.PackageMetrics) float64 {
+	if role, ok := r.metrics.Roles[pkg.Role]; ok && role.Thresholds.MaxDistance > 0 {
+		return role.Thresholds.MaxDistance
+	}
+	return htmlDefaultMaxDistance
+}
+
+// highDistance reports whether a package exceeds the distance limit of its role.
+// Isolated and gate-exempt packages never do.
+func (r *Reporter) highDistance(pkg models.PackageMetrics) bool {
+	return !pkg.GateExempt && pkg.Ca+pkg.Ce > 0 && pkg.Distance > r.maxDistance(pkg)
+}
+
+// chartPosition returns the pixel position of a package on the A/I chart,
+// with instability on the horizontal and abstractness on the vertical axis
+func chartPosition(instability, abstractness float64) (x, y float64) {
+	return htmlChartMargin + instability*htmlChartSize, htmlChartMargin + (1-abstractness)*htmlChartSize
+}
@@ -0,0 +1,123 @@
+// Package reporter handles output generation for aid-metrics analysis results.
+// This file implements the schema versions of JSON reports and their migration.
+package reporter
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// SchemaVersion is the schema version of the JSON reports written by this
+// version of aid-metrics
+const SchemaVersion = 1
+
+// reportMigrations upgrade the top-level members of a JSON report from schema
+// version i to i+1. Append a step whenever a member is renamed, moved or
+// changes meaning, so baselines keep working across releases.
+var reportMigrations = []func(report *reportDocument) error{
+	// 0 → 1: the schema_version member was introduced; the members are unchanged
+	func(*reportDocument) error { return nil },
+}
+
+// reportDocument holds the top-level members of a JSON report in their order,
+// including sections unknown to JSONReport such as roles and endpoints
+type reportDocument []reportMember
+
+type reportMember struct {
+	name  string
+	value json.RawMessage
+}
+
+// MigrateJSONReport upgrades a JSON report (e.g. a baseline) to SchemaVersion,
+// keeping all sections and their order. It returns the report unchanged and
+// false if it is current.
+func MigrateJSONReport(data []byte) ([]byte, bool, error) {
+	doc, err := parseReportDocument(data)
+	if err != nil {
+		return nil, false, err
+	}
+
+	version := 0
+	if raw, ok := doc.get("schema_version"); ok {
+		if err := json.Unmarshal(raw, &version); err != nil || version < 0 {
+			return nil, false, fmt.Errorf("invalid JSON report schema version %s", raw)
+		}
+	}
+	if version > SchemaVersion {
+		return nil, false, fmt.Errorf("JSON report schema version %d is newer than the supported %d: upgrade aid-metrics", version, SchemaVersion)
+	}
+	if version == SchemaVersion {
+		return data, false, nil
+	}
+
+	for v := version; v < SchemaVersion; v++ {
+		if err := reportMigrations[v](&doc); err != nil {
+			return nil, false, fmt.Errorf("migrating JSON report from schema version %d: %w", v, err)
+		}
+	}
+	doc.set("schema_version", json.RawMessage(fmt.Sprint(SchemaVersion)))
+	return doc.encode()
+}
+
+// parseReportDocument splits a JSON report into its top-level members
+func parseReportDocument(data []byte) (reportDocument, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return nil, fmt.Errorf("failed to read JSON report: not a JSON object")
+	}
+	var doc reportDocument
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read JSON report: %w", err)
+		}
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return nil, fmt.Errorf("failed to read JSON report: %w", err)
+		}
+		doc = append(doc, reportMember{name: tok.(string), value: value})
+	}
+	return doc, nil
+}
+
+// get returns the value of the named member
+func (d reportDocument) get(name string) (json.RawMessage, bool) {
+	for _, member := range d {
+		if member.name == name {
+			return member.value, true
+		}
+	}
+	return nil, false
+}
+
+// set replaces the value of the named member, or adds it as the first member
+func (d *reportDocument) set(name string, value json.RawMessage) {
+	for i := range *d {
+		if (*d)[i].name == name {
+			(*d)[i].value = value
+			return
+		}
+	}
+	*d = append(reportDocument{{name: name, value: value}}, *d...)
+}
+
+// encode writes the members with two-space indentation, like the JSON report
+func (d reportDocument) encode() ([]byte, bool, error) {
+	var buf bytes.Buffer
+	buf.WriteString("{")
+	for i, member := range d {
+		if i > 0 {
+			buf.WriteString(",")
+		}
+		name, _ := json.Marshal(member.name)
+		buf.WriteString("\n  ")
+		buf.Write(name)
+		buf.WriteString(": ")
+		if err := json.Indent(&buf, member.value, "  ", "  "); err != nil {
+			return nil, false, fmt.Errorf("failed to encode JSON report: %w", err)
+		}
+	}
+	buf.WriteString("\n}\n")
+	return buf.Bytes(), true, nil
+}
@@ -1,18 +1,28 @@
 // Package reporter handles output generation for aid-metrics analysis results.
-// This file implements progress reporting for console output.
+// This file implements progress reporting for console output and as a JSON lines stream.
 package reporter
 
 import (
+	"encoding/json"
 	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
 	"time"
 	
+	"github.com/alkbt/aid-metrics/pkg/models"
 	"github.com/schollz/progressbar/v3"
 )
 
 // ConsoleProgressReporter implements models.ProgressReporter using a terminal progress bar.
-// It provides visual feedback during long-running operations like package discovery and analysis.
+// It provides visual feedback during long-running operations like package discovery and analysis,
+// with a bar per stage drawn on stderr so it never mixes with a report on stdout.
 type ConsoleProgressReporter struct {
-	bar *progressbar.ProgressBar
+	mu    sync.Mutex
+	bar   *progressbar.ProgressBar
+	stage models.Stage
+	ascii bool
 }
 
 // NewConsoleProgressReporter creates a new progress reporter that outputs to the console.
@@ -21,41 +31,165 @@ func NewConsoleProgressReporter() *ConsoleProgressReporter {
 	return &ConsoleProgressReporter{}
 }
 
-// SetTotal initializes the progress bar with the given total value.
-// For aid-metrics, this is typically set to 100 for a percentage-based display.
-func (r *ConsoleProgressReporter) SetTotal(total int) {
+// NewASCIIConsoleProgressReporter creates a progress reporter drawing the bar with
+// plain ASCII characters and no color codes, for terminals and CI log viewers
+// that mangle Unicode block characters or ANSI escapes.
+func NewASCIIConsoleProgressReporter() *ConsoleProgressReporter {
+	return &ConsoleProgressReporter{ascii: true}
+}
+
+// StageStart finishes the bar of the previous stage, if any, and starts a bar for
+// the stage. A stage of unknown total gets a spinner with a count instead.
+func (r *ConsoleProgressReporter) StageStart(stage models.Stage, total int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.finish()
+
+	theme := progressbar.Theme{
+		Saucer:        "[green]█[reset]",
+		SaucerHead:    "[green]█[reset]",
+		SaucerPadding: " ",
+		BarStart:      "[",
+		BarEnd:        "]",
+	}
+	if r.ascii {
+		theme.Saucer = "="
+		theme.SaucerHead = ">"
+	}
+	if total <= 0 {
+		total = -1
+	}
+	r.stage = stage
 	r.bar = progressbar.NewOptions(total,
-		progressbar.OptionEnableColorCodes(true),
+		progressbar.OptionSetWriter(os.Stderr),
+		progressbar.OptionEnableColorCodes(!r.ascii),
 		progressbar.OptionSetWidth(40),
+		progressbar.OptionSetDescription(stageTitle(stage)),
 		progressbar.OptionShowDescriptionAtLineEnd(),
-		progressbar.OptionSetTheme(progressbar.Theme{
-			Saucer:        "[green]█[reset]",
-			SaucerHead:    "[green]█[reset]",
-			SaucerPadding: " ",
-			BarStart:      "[",
-			BarEnd:        "]",
-		}),
+		progressbar.OptionSetTheme(theme),
+		progressbar.OptionShowCount(),
 		progressbar.OptionShowElapsedTimeOnFinish(),
 		progressbar.OptionThrottle(1*time.Second), // Update display at most once per second
 	)
 }
 
-// Update sets the current progress and updates the description.
+// Update sets the current progress of the stage and updates the description.
 // This is thread-safe and can be called from multiple goroutines.
 func (r *ConsoleProgressReporter) Update(current int, description string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
 	if r.bar == nil {
 		return
 	}
-	r.bar.Describe(description)
+	r.bar.Describe(stageTitle(r.stage) + ": " + description)
 	_ = r.bar.Set(current)
 }
 
-// Complete marks the progress as complete and cleans up the progress bar.
+// StageDone completes the bar of the stage
+func (r *ConsoleProgressReporter) StageDone(stage models.Stage) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.stage == stage {
+		r.finish()
+	}
+}
+
+// Complete finishes the bar of a stage left unfinished, e.g. by a failure
 func (r *ConsoleProgressReporter) Complete() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.finish()
+}
+
+// finish completes the current bar, if any, and ends its line
+func (r *ConsoleProgressReporter) finish() {
 	if r.bar == nil {
 		return
 	}
 	_ = r.bar.Finish()
 	// Add newline after progress bar to separate from following output
-	fmt.Println()
-}
\ No newline at end of file
+	fmt.Fprintln(os.Stderr)
+	r.bar = nil
+}
+
+// stageTitle returns the name of a stage as shown in front of the bar
+func stageTitle(stage models.Stage) string {
+	if stage == "" {
+		return ""
+	}
+	return strings.ToUpper(string(stage[:1])) + string(stage[1:])
+}
+
+// JSONProgressReporter implements models.ProgressReporter by writing one JSON
+// object per event, so wrappers, IDEs and CI systems can render their own
+// progress UI. Updates carry the stage, the steps done and the total of the
+// stage (0 if unknown); the start and end of a stage and of the whole operation
+// are marked by an event field:
+//
+//	{"event":"start","stage":"loading","current":0,"total":300}
+//	{"stage":"loading","current":42,"total":300,"detail":"Loaded 42 of 300 packages"}
+//	{"event":"done","stage":"loading","current":300,"total":300}
+//	{"event":"complete"}
+//
+// It is safe for concurrent use.
+type JSONProgressReporter struct {
+	mu      sync.Mutex
+	enc     *json.Encoder
+	stage   models.Stage
+	current int
+	total   int
+}
+
+// progressEvent is a line of the JSON progress stream
+type progressEvent struct {
+	Event   string       `json:"event,omitempty"`
+	Stage   models.Stage `json:"stage,omitempty"`
+	Current int          `json:"current"`
+	Total   int          `json:"total"`
+	Detail  string       `json:"detail,omitempty"`
+}
+
+// NewJSONProgressReporter creates a progress reporter writing JSON lines to w,
+// usually os.Stderr
+func NewJSONProgressReporter(w io.Writer) *JSONProgressReporter {
+	return &JSONProgressReporter{enc: json.NewEncoder(w)}
+}
+
+// StageStart writes the start event of a stage
+func (r *JSONProgressReporter) StageStart(stage models.Stage, total int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.stage, r.current, r.total = stage, 0, total
+	r.write("start", "")
+}
+
+// Update writes a line with the progress of the stage and the description as detail
+func (r *JSONProgressReporter) Update(current int, description string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.current = current
+	r.write("", description)
+}
+
+// StageDone writes the done event of a stage
+func (r *JSONProgressReporter) StageDone(stage models.Stage) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.stage = stage
+	if r.total > 0 {
+		r.current = r.total
+	}
+	r.write("done", "")
+}
+
+// Complete writes the final line of the stream
+func (r *JSONProgressReporter) Complete() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_ = r.enc.Encode(progressEvent{Event: "complete"})
+}
+
+// write encodes an event of the current stage as a line
+func (r *JSONProgressReporter) write(event, detail string) {
+	_ = r.enc.Encode(progressEvent{Event: event, Stage: r.stage, Current: r.current, Total: r.total, Detail: detail})
+}
@@ -0,0 +1,83 @@
+// Package reporter handles output generation for aid-metrics analysis results.
+// This file implements the registry of report formats contributed by other packages.
+package reporter
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+
+	"github.com/alkbt/aid-metrics/pkg/models"
+)
+
+// Generator writes a report. *Reporter implements it, as must the reporters
+// created by the factories of registered formats.
+type Generator interface {
+	Generate(w io.Writer) error
+}
+
+// builtinFormats are the formats generated by Reporter itself
+var builtinFormats = []FormatType{FormatText, FormatCSV, FormatJSON, FormatAIContext, FormatHTML, FormatSVG, FormatDSM, FormatDSMHTML, FormatDOT, FormatMermaid}
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[FormatType]func(*models.ModuleMetrics) Generator)
+)
+
+// Register makes a report format available by name, so that Reporter.Generate
+// (and the -format flag of the CLI) can produce it. It is meant to be called
+// from an init function, like database/sql.Register, and panics if the name is
+// empty, taken by a built-in format or registered twice, or factory is nil.
+func Register(name string, factory func(*models.ModuleMetrics) Generator) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	format := FormatType(name)
+	if name == "" || factory == nil {
+		panic("reporter: Register requires a name and a factory")
+	}
+	if isBuiltinFormat(format) {
+		panic(fmt.Sprintf("reporter: format %s is built in", name))
+	}
+	if _, dup := registry[format]; dup {
+		panic(fmt.Sprintf("reporter: Register called twice for format %s", name))
+	}
+	registry[format] = factory
+}
+
+// Formats returns the names of the built-in formats followed by the registered ones, sorted
+func Formats() []string {
+	names := make([]string, 0, len(builtinFormats))
+	for _, format := range builtinFormats {
+		names = append(names, string(format))
+	}
+
+	registryMu.RLock()
+	registered := make([]string, 0, len(registry))
+	for format := range registry {
+		registered = append(registered, string(format))
+	}
+	registryMu.RUnlock()
+
+	sort.Strings(registered)
+	return append(names, registered...)
+}
+
+// registeredFormat returns the factory of a registered format
+func registeredFormat(format FormatType) (func(*models.ModuleMetrics) Generator, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	factory, ok := registry[format]
+	return factory, ok
+}
+
+// isBuiltinFormat reports whether Reporter generates the format itself
+func isBuiltinFormat(format FormatType) bool {
+	for _, builtin := range builtinFormats {
+		if format == builtin {
+			return true
+		}
+	}
+	return false
+}
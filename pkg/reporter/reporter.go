@@ -1,12 +1,14 @@
 package reporter
 
 import (
-	"encoding/csv"
 	"encoding/json"
 	"fmt"
 	"io"
+	"io/fs"
+	"path/filepath"
 	"sort"
 	"strconv"
+	"strings"
 	"text/tabwriter"
 
 	"github.com/alkbt/aid-metrics/pkg/models"
@@ -19,22 +21,173 @@ const (
 	FormatText FormatType = "text"
 	FormatCSV  FormatType = "csv"
 	FormatJSON FormatType = "json"
+
+	// FormatAIContext is a compact summary designed for LLM coding agents
+	FormatAIContext FormatType = "ai-context"
+
+	// FormatHTML is a standalone HTML page with a sortable, filterable table
+	FormatHTML FormatType = "html"
+
+	// FormatSVG is the A/I scatter plot with the main sequence as an SVG image
+	FormatSVG FormatType = "svg"
+
+	// FormatDSM is the design structure matrix of package dependencies as CSV,
+	// and FormatDSMHTML the same matrix as a standalone HTML heatmap. With
+	// SortTopo the matrix is partitioned into dependency layers.
+	FormatDSM     FormatType = "dsm"
+	FormatDSMHTML FormatType = "dsm-html"
+
+	// FormatDOT is the package dependency graph in the Graphviz DOT language,
+	// and FormatMermaid the same graph as a Mermaid flowchart. Both list nodes
+	// and edges in a stable order, so diagrams kept in a repository diff cleanly.
+	FormatDOT     FormatType = "dot"
+	FormatMermaid FormatType = "mermaid"
 )
 
+// FileExtension returns the file name extension of reports in the format,
+// e.g. ".json". Registered formats use their name as the extension.
+func FileExtension(format FormatType) string {
+	switch format {
+	case FormatText:
+		return ".txt"
+	case FormatAIContext:
+		return ".ai.txt"
+	case FormatDSM:
+		return ".dsm.csv"
+	case FormatDSMHTML:
+		return ".dsm.html"
+	case FormatMermaid:
+		return ".mmd"
+	default:
+		return "." + string(format)
+	}
+}
+
+// ReportPath returns the file of the report in the given format when reports in
+// several formats are written: output with its extension replaced by the
+// format's, or metrics.<ext> in the working directory if output is empty. The
+// extension of a format, e.g. ".ai.txt", is replaced as a whole.
+func ReportPath(output string, format FormatType) string {
+	if output == "" {
+		output = "metrics"
+	}
+	base := strings.TrimSuffix(output, filepath.Ext(output))
+	for _, name := range Formats() {
+		ext := FileExtension(FormatType(name))
+		if stem, ok := strings.CutSuffix(output, ext); ok && len(stem) < len(base) {
+			base = stem
+		}
+	}
+	return base + FileExtension(format)
+}
+
+// ReportOptions configures the content of generated reports
+type ReportOptions struct {
+	// ByRole adds a summary of metrics aggregated per package role.
+	// In CSV output the role summary replaces the package rows.
+	ByRole bool
+
+	// Edges adds the dependents and dependencies of each package, not just
+	// their counts, to JSON and HTML output
+	Edges bool
+
+	// Endpoints adds the HTTP/gRPC endpoint to package fan-in report
+	// to text and JSON output.
+	Endpoints bool
+
+	// SideEffects adds the packages imported for their side effects only or
+	// registering global state in init functions, with the binaries linking
+	// them in, to text and JSON output
+	SideEffects bool
+
+	// ErrorCoupling adds the exported errors of the packages with the packages
+	// checking for them to text output
+	ErrorCoupling bool
+
+	// StringCoupling adds the string literals shared by packages without an
+	// import relationship to text and JSON output
+	StringCoupling bool
+
+	// Findings adds the detected findings (cycles, principle violations, ...).
+	// In CSV output the findings replace the package rows.
+	Findings bool
+
+	// Baseline is a previous report to compare against. The HTML report shows
+	// how packages moved on the A/I chart since then; other formats ignore it.
+	Baseline *JSONReport
+
+	// Sort orders the packages of the text and CSV reports: SortName (the
+	// default when empty), SortTopo, which adds a dependency layer column, or
+	// worst first by a metric (SortDistance, SortInstability, SortCa, SortCe)
+	Sort string
+
+	// Columns selects the columns of the package tables of the text and CSV
+	// reports after the package name, in order (see PackageColumns). When
+	// empty, the default columns are shown.
+	Columns []string
+
+	// Precision is the number of decimals of ratios in the text package table
+	// and the CSV report; 0 means DefaultPrecision
+	Precision int
+
+	// Top limits the text and CSV reports to the first Top packages in the
+	// order of Sort; 0 reports all of them. Module totals are unaffected.
+	Top int
+
+	// ASCII escapes every non-ASCII character of the text report (e.g. in
+	// package names or paths) as \uXXXX, for terminals and log viewers that
+	// mangle Unicode
+	ASCII bool
+
+	// Assets holds the templates of the HTML, SVG and DSM reports (HTMLTemplate,
+	// SVGTemplate and DSMTemplate). When nil, DefaultAssets is used.
+	Assets fs.FS
+
+	// Offline fails the HTML, SVG and DSM reports if their template loads any
+	// resource from the network (see ExternalReferences), so the reports are
+	// guaranteed to render without egress
+	Offline bool
+
+	// LayoutSeed fixes the initial node positions of the force-directed
+	// Graphviz layouts of the DOT report (the start graph attribute); 0 leaves
+	// it out. The dot layout, the Mermaid report and the SVG chart, which
+	// plots each package at its metrics, are deterministic without a seed.
+	LayoutSeed int
+
+	// Canonical writes the JSON report in canonical form: keys sorted, ratios
+	// with Precision decimals (see CanonicalJSON), for snapshots committed to a
+	// repository and reviewed like code
+	Canonical bool
+}
+
 // Reporter generates reports for module metrics
 type Reporter struct {
 	metrics *models.ModuleMetrics
 	format  FormatType
+	options ReportOptions
 }
 
 // NewReporter creates a new Reporter
 func NewReporter(metrics *models.ModuleMetrics, format FormatType) *Reporter {
+	return NewReporterWithOptions(metrics, format, ReportOptions{})
+}
+
+// NewReporterWithOptions creates a new Reporter with custom report options
+func NewReporterWithOptions(metrics *models.ModuleMetrics, format FormatType, options ReportOptions) *Reporter {
 	return &Reporter{
 		metrics: metrics,
 		format:  format,
+		options: options,
 	}
 }
 
+// Render writes a report of metrics in the given format to w. It depends on
+// nothing but its arguments, so tools embedding the reporter can call it on
+// metrics they built or loaded themselves.
+func Render(w io.Writer, metrics models.ModuleMetrics, format FormatType, options ReportOptions) error {
+	return NewReporterWithOptions(&metrics, format, options).Generate(w)
+}
+
 // Format returns the current format
 func (r *Reporter) Format() FormatType {
 	return r.format
@@ -49,119 +202,859 @@ func (r *Reporter) Generate(w io.Writer) error {
 		return r.generateCSVReport(w)
 	case FormatJSON:
 		return r.generateJSONReport(w)
+	case FormatAIContext:
+		return r.generateAIContextReport(w)
+	case FormatHTML:
+		return r.generateHTMLReport(w)
+	case FormatSVG:
+		return r.generateSVGReport(w)
+	case FormatDSM:
+		return r.generateDSMReport(w)
+	case FormatDSMHTML:
+		return r.generateDSMHTMLReport(w)
+	case FormatDOT:
+		return r.generateDOTReport(w)
+	case FormatMermaid:
+		return r.generateMermaidReport(w)
 	default:
+		if factory, ok := registeredFormat(r.format); ok {
+			return factory(r.metrics).Generate(w)
+		}
 		return fmt.Errorf("unsupported format: %s", r.format)
 	}
 }
 
 // generateTextReport generates a text report
 func (r *Reporter) generateTextReport(w io.Writer) error {
-	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
-	defer tw.Flush()
+	tabs := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	defer tabs.Flush()
+	var tw io.Writer = tabs
+	if r.options.ASCII {
+		// Escape before the tabwriter, so columns are aligned on the escaped text
+		tw = asciiWriter{tabs}
+	}
 
-	fmt.Fprintf(tw, "MODULE: %s\n\n", r.metrics.Path)
-	fmt.Fprintln(tw, "PACKAGE\tCa\tCe\tI\tNa\tNc\tA\tD")
-	fmt.Fprintln(tw, "-------\t--\t--\t-\t--\t--\t-\t-")
+	fmt.Fprintf(tw, "MODULE: %s\n", r.metrics.Path)
+	if r.metrics.ExportedOnly {
+		fmt.Fprintln(tw, "Na, Nc and A count exported declarations only; A (all) counts all of them.")
+	}
+	r.writeTextSummary(tw)
+	fmt.Fprintln(tw)
 
-	// Sort packages by name for consistent output
-	packageNames := make([]string, 0, len(r.metrics.Packages))
-	for pkgName := range r.metrics.Packages {
-		packageNames = append(packageNames, pkgName)
+	columns, err := r.columns()
+	if err != nil {
+		return err
+	}
+	layers, order := r.layers(columns)
+	header := []string{"PACKAGE"}
+	for _, column := range columns {
+		header = append(header, column.text)
+	}
+	underline := make([]string, len(header))
+	for i, column := range header {
+		underline[i] = strings.Repeat("-", len(column))
 	}
-	sort.Strings(packageNames)
+	fmt.Fprintln(tw, strings.Join(header, "\t"))
+	fmt.Fprintln(tw, strings.Join(underline, "\t"))
 
+	precision := r.precision()
+	packageNames := r.packageIDsInOrder(order)
 	for _, pkgName := range packageNames {
 		pkg := r.metrics.Packages[pkgName]
-		fmt.Fprintf(tw, "%s\t%d\t%d\t%.2f\t%d\t%d\t%.2f\t%.2f\n",
-			pkg.Name, pkg.Ca, pkg.Ce, pkg.Instability, pkg.Na, pkg.Nc, pkg.Abstractness, pkg.Distance)
+		fmt.Fprint(tw, pkg.Name)
+		for _, column := range columns {
+			switch {
+			case column.int != nil:
+				fmt.Fprintf(tw, "\t%d", column.int(pkg, layers[pkgName]))
+			case column.float != nil:
+				fmt.Fprintf(tw, "\t%.*f", precision, column.float(pkg))
+			case column.str(pkg) == "":
+				fmt.Fprint(tw, "\t-")
+			default:
+				fmt.Fprintf(tw, "\t%s", column.str(pkg))
+			}
+		}
+		fmt.Fprintln(tw)
+	}
+	if len(packageNames) < len(r.metrics.Packages) {
+		fmt.Fprintf(tw, "(%d of %d packages shown)\n", len(packageNames), len(r.metrics.Packages))
+	}
+
+	// Entry points are maximally unstable and concrete by design: list them
+	// apart so their D is not mistaken for a design problem
+	var exempt []models.PackageMetrics
+	for _, pkgName := range packageNames {
+		if pkg := r.metrics.Packages[pkgName]; pkg.GateExempt {
+			exempt = append(exempt, pkg)
+		}
+	}
+	if len(exempt) > 0 {
+		fmt.Fprintln(tw)
+		fmt.Fprintln(tw, "EXEMPT FROM GATING\tReason")
+		fmt.Fprintln(tw, "------------------\t------")
+		for _, pkg := range exempt {
+			fmt.Fprintf(tw, "%s\t%s\n", pkg.Name, pkg.GateExemptReason)
+		}
+	}
+
+	// Metrics of broken packages are a lower bound, not a measurement
+	var partial []string
+	for _, pkgName := range packageNames {
+		if pkg := r.metrics.Packages[pkgName]; pkg.Partial {
+			partial = append(partial, pkg.Name)
+		}
+	}
+	if len(partial) > 0 {
+		fmt.Fprintln(tw)
+		fmt.Fprintf(tw, "Partial (metrics of the parsable code only, see the diagnostics): %s\n", strings.Join(partial, ", "))
+	}
+
+	if r.options.ByRole {
+		fmt.Fprintln(tw)
+		fmt.Fprintln(tw, "ROLE\tPackages\tavg I\tavg A\tavg D\tmax D\tD limit")
+		fmt.Fprintln(tw, "----\t--------\t-----\t-----\t-----\t-----\t-------")
+		for _, role := range r.sortedRoles() {
+			fmt.Fprintf(tw, "%s\t%d\t%.2f\t%.2f\t%.2f\t%.2f\t%.2f\n",
+				role.Role, role.Packages, role.MeanInstability, role.MeanAbstractness,
+				role.MeanDistance, role.MaxDistance, role.Thresholds.MaxDistance)
+		}
+	}
+
+	if r.options.Findings {
+		fmt.Fprintln(tw)
+		fmt.Fprintln(tw, "FINDINGS")
+		findings, warnings := splitAdvisory(r.metrics.Findings)
+		if len(findings) == 0 {
+			fmt.Fprintln(tw, "No findings.")
+		}
+		for _, finding := range findings {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", finding.ID, finding.Severity, finding.Package, finding.Message)
+		}
+		if len(findings) > 0 {
+			fmt.Fprintf(tw, "Debt: %d points\n", models.DebtPoints(findings))
+		}
+		if len(warnings) > 0 {
+			fmt.Fprintln(tw)
+			fmt.Fprintln(tw, "WARNINGS (not enforced)")
+			for _, finding := range warnings {
+				fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", finding.ID, finding.Severity, finding.Package, finding.Message)
+			}
+		}
+	}
+
+	if r.options.Endpoints {
+		fmt.Fprintln(tw)
+		fmt.Fprintln(tw, "ENDPOINT\tKind\tHandler\tPackages\tDepends on")
+		fmt.Fprintln(tw, "--------\t----\t-------\t--------\t----------")
+		for _, endpoint := range r.metrics.Endpoints {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%s\n",
+				endpoint.Route, endpoint.Kind, endpoint.Handler,
+				len(endpoint.Dependencies), strings.Join(endpoint.Dependencies, ", "))
+		}
+	}
+
+	if r.options.ErrorCoupling {
+		fmt.Fprintln(tw)
+		fmt.Fprintln(tw, "ERROR\tKind\tCheckers\tChecked by")
+		fmt.Fprintln(tw, "-----\t----\t--------\t----------")
+		ids := make([]string, 0, len(r.metrics.Packages))
+		for id := range r.metrics.Packages {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+		for _, id := range ids {
+			pkg := r.metrics.Packages[id]
+			for _, target := range pkg.ErrorTargets {
+				fmt.Fprintf(tw, "%s.%s\t%s\t%d\t%s\n",
+					pkg.Name, target.Name, target.Kind, len(target.CheckedBy), strings.Join(target.CheckedBy, ", "))
+			}
+		}
+	}
+
+	if r.options.SideEffects {
+		fmt.Fprintln(tw)
+		fmt.Fprintln(tw, "SIDE EFFECT\tRegistrations\tBlank imported by\tBinaries")
+		fmt.Fprintln(tw, "-----------\t-------------\t-----------------\t--------")
+		for _, effect := range r.metrics.SideEffects {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n",
+				effect.Package, strings.Join(effect.Registrations, ", "),
+				strings.Join(effect.BlankImportedBy, ", "), strings.Join(effect.Binaries, ", "))
+		}
+	}
+
+	if r.options.StringCoupling {
+		fmt.Fprintln(tw)
+		fmt.Fprintln(tw, "STRING\tPackages\tUsed in")
+		fmt.Fprintln(tw, "------\t--------\t-------")
+		for _, coupling := range r.metrics.StringCouplings {
+			fmt.Fprintf(tw, "%q\t%d\t%s\n", coupling.Value, len(coupling.Packages), strings.Join(coupling.Packages, ", "))
+		}
 	}
 
 	return nil
 }
 
-// generateCSVReport generates a CSV report
+// sortedRoles returns the role summaries sorted by role name
+func (r *Reporter) sortedRoles() []models.RoleMetrics {
+	roles := make([]models.RoleMetrics, 0, len(r.metrics.Roles))
+	for _, role := range r.metrics.Roles {
+		roles = append(roles, role)
+	}
+	sort.Slice(roles, func(i, j int) bool {
+		return roles[i].Role < roles[j].Role
+	})
+	return roles
+}
+
+// generateCSVReport generates a CSV report.
+// Rows are streamed without building the records of the whole module first.
 func (r *Reporter) generateCSVReport(w io.Writer) error {
-	csvWriter := csv.NewWriter(w)
-	defer csvWriter.Flush()
+	c := newCSVStream(w)
+	c.precision = r.precision()
+
+	if r.options.ByRole {
+		r.writeRoleCSV(c)
+		return c.flush()
+	}
+	if r.options.Findings {
+		r.writeFindingsCSV(c)
+		return c.flush()
+	}
 
 	// Write header
-	if err := csvWriter.Write([]string{"Package", "Ca", "Ce", "I", "Na", "Nc", "A", "D"}); err != nil {
+	columns, err := r.columns()
+	if err != nil {
 		return err
 	}
-
-	// Sort packages by name for consistent output
-	packageNames := make([]string, 0, len(r.metrics.Packages))
-	for pkgName := range r.metrics.Packages {
-		packageNames = append(packageNames, pkgName)
+	layers, order := r.layers(columns)
+	c.str("Package")
+	for _, column := range columns {
+		c.str(column.csv)
 	}
-	sort.Strings(packageNames)
+	c.end()
 
 	// Write data
-	for _, pkgName := range packageNames {
+	for _, pkgName := range r.packageIDsInOrder(order) {
 		pkg := r.metrics.Packages[pkgName]
-		record := []string{
-			pkg.Name,
-			strconv.Itoa(pkg.Ca),
-			strconv.Itoa(pkg.Ce),
-			fmt.Sprintf("%.2f", pkg.Instability),
-			strconv.Itoa(pkg.Na),
-			strconv.Itoa(pkg.Nc),
-			fmt.Sprintf("%.2f", pkg.Abstractness),
-			fmt.Sprintf("%.2f", pkg.Distance),
+		c.str(pkg.Name)
+		for _, column := range columns {
+			switch {
+			case column.int != nil:
+				c.int(column.int(pkg, layers[pkgName]))
+			case column.float != nil:
+				c.float(column.float(pkg))
+			default:
+				c.str(column.str(pkg))
+			}
 		}
-		if err := csvWriter.Write(record); err != nil {
-			return err
+		c.end()
+	}
+
+	return c.flush()
+}
+
+// writeRoleCSV writes the per-role summary as CSV rows
+func (r *Reporter) writeRoleCSV(c *csvStream) {
+	c.record("Role", "Packages", "AvgI", "AvgA", "AvgD", "MaxD", "DLimit")
+
+	for _, role := range r.sortedRoles() {
+		c.str(role.Role)
+		c.int(role.Packages)
+		c.float(role.MeanInstability)
+		c.float(role.MeanAbstractness)
+		c.float(role.MeanDistance)
+		c.float(role.MaxDistance)
+		c.float(role.Thresholds.MaxDistance)
+		c.end()
+	}
+}
+
+// writeFindingsCSV writes the findings as CSV rows
+func (r *Reporter) writeFindingsCSV(c *csvStream) {
+	c.record("ID", "Severity", "Category", "Package", "Message", "Remediation", "Points", "Advisory")
+
+	for _, finding := range r.metrics.Findings {
+		c.str(finding.ID)
+		c.str(string(finding.Severity))
+		c.str(finding.Category)
+		c.str(finding.Package)
+		c.str(finding.Message)
+		c.str(finding.Remediation)
+		c.int(finding.Points)
+		c.str(strconv.FormatBool(finding.Advisory))
+		c.end()
+	}
+}
+
+// splitAdvisory separates the findings that fail gates from the advisory ones,
+// keeping their order
+func splitAdvisory(all []models.Finding) (findings, advisory []models.Finding) {
+	for _, finding := range all {
+		if finding.Advisory {
+			advisory = append(advisory, finding)
+		} else {
+			findings = append(findings, finding)
 		}
 	}
+	return findings, advisory
+}
 
-	return nil
+// JSONPackage is the JSON representation of a package's metrics,
+// shared by the JSON report and the server API
+type JSONPackage struct {
+	Name         string  `json:"name"`
+	Synopsis     string  `json:"synopsis,omitempty"`
+	Ca           int     `json:"ca"`
+	Ce           int     `json:"ce"`
+	Instability  float64 `json:"instability"`
+	Na           int     `json:"na"`
+	Nc           int     `json:"nc"`
+	Abstractness float64 `json:"abstractness"`
+	Distance     float64 `json:"distance"`
+
+	// Dependents and Dependencies name the packages on either side of the
+	// package's edges; they are only filled in with ReportOptions.Edges
+	Dependents   []string `json:"dependents,omitempty"`
+	Dependencies []string `json:"dependencies,omitempty"`
+
+	TransitiveCa int `json:"transitive_ca"`
+	TransitiveCe int `json:"transitive_ce"`
+
+	NaAll           int     `json:"na_all"`
+	NcAll           int     `json:"nc_all"`
+	AbstractnessAll float64 `json:"abstractness_all"`
+
+	CeExported          int            `json:"ce_exported"`
+	CeInternal          int            `json:"ce_internal"`
+	ExposedDependencies []string       `json:"exposed_dependencies,omitempty"`
+	LeakedTypes         []JSONTypeLeak `json:"leaked_types,omitempty"`
+
+	ConsumerInterfaces int                   `json:"consumer_interfaces"`
+	ProviderInterfaces int                   `json:"provider_interfaces"`
+	HeaderInterfaces   []JSONHeaderInterface `json:"header_interfaces,omitempty"`
+
+	Mocks              int                   `json:"mocks"`
+	MockedInterfaces   []JSONMockedInterface `json:"mocked_interfaces,omitempty"`
+	MockOnlyDependents bool                  `json:"mock_only_dependents,omitempty"`
+
+	StructEmbeds    int     `json:"struct_embeds"`
+	InterfaceEmbeds int     `json:"interface_embeds"`
+	EmbeddingRatio  float64 `json:"embedding_ratio"`
+
+	Methods           int `json:"methods"`
+	PointerMethods    int `json:"pointer_methods"`
+	ValueMethods      int `json:"value_methods"`
+	ExportedMethods   int `json:"exported_methods"`
+	UnexportedMethods int `json:"unexported_methods"`
+
+	Constructors              int     `json:"constructors"`
+	InterfaceConstructors     int     `json:"interface_constructors"`
+	InterfaceConstructorRatio float64 `json:"interface_constructor_ratio"`
+
+	CompositionRoot bool   `json:"composition_root"`
+	DIFramework     string `json:"di_framework,omitempty"`
+
+	Role string `json:"role"`
+
+	DeprecatedUses    int                 `json:"deprecated_uses"`
+	DeprecatedSymbols []JSONDeprecatedUse `json:"deprecated_symbols,omitempty"`
+
+	ErrorDependents int               `json:"error_dependents"`
+	ErrorTargets    []JSONErrorTarget `json:"error_targets,omitempty"`
+
+	ConstantShare      float64           `json:"constant_share"`
+	ConstantDependents int               `json:"constant_dependents"`
+	SharedKernel       bool              `json:"shared_kernel"`
+	ConstantSets       []JSONConstantSet `json:"constant_sets,omitempty"`
+
+	BlankImports      []string `json:"blank_imports,omitempty"`
+	InitRegistrations []string `json:"init_registrations,omitempty"`
+
+	TaggedStructs int  `json:"tagged_structs"`
+	DataBag       bool `json:"data_bag"`
+
+	Generator        string `json:"generator,omitempty"`
+	GateExempt       bool   `json:"gate_exempt,omitempty"`
+	GateExemptReason string `json:"gate_exempt_reason,omitempty"`
+
+	Annotations []string `json:"annotations,omitempty"`
+
+	Partial bool `json:"partial,omitempty"`
+
+	DebtPoints int `json:"debt_points,omitempty"`
+
+	Complexity    float64  `json:"complexity"`
+	MaxComplexity int      `json:"max_complexity"`
+	Coverage      *float64 `json:"coverage,omitempty"`
+	Health        int      `json:"health"`
+	Zone          string   `json:"zone,omitempty"`
+
+	// Extensions are the optional metric groups by name, passed through as is
+	Extensions models.Extensions `json:"extensions,omitempty"`
 }
 
-// generateJSONReport generates a JSON report
+// JSONTypeLeak is the JSON representation of a type of another module exposed in the exported API
+type JSONTypeLeak struct {
+	Declaration string `json:"declaration"`
+	Package     string `json:"package"`
+	Type        string `json:"type"`
+}
+
+// JSONDeprecatedUse is the JSON representation of a deprecated symbol a package refers to
+type JSONDeprecatedUse struct {
+	Package string `json:"package"`
+	Symbol  string `json:"symbol"`
+	Uses    int    `json:"uses"`
+}
+
+// JSONErrorTarget is the JSON representation of an exported error
+type JSONErrorTarget struct {
+	Name      string   `json:"name"`
+	Kind      string   `json:"kind"`
+	CheckedBy []string `json:"checked_by,omitempty"`
+}
+
+// JSONConstantSet is the JSON representation of a constant set
+type JSONConstantSet struct {
+	Name         string   `json:"name"`
+	Constants    int      `json:"constants"`
+	ReferencedBy []string `json:"referenced_by,omitempty"`
+}
+
+// JSONHeaderInterface is the JSON representation of an interface with a single implementation
+type JSONHeaderInterface struct {
+	Interface      string `json:"interface"`
+	Implementation string `json:"implementation"`
+}
+
+// JSONMockedInterface is the JSON representation of an interface with generated mocks
+type JSONMockedInterface struct {
+	Interface string `json:"interface"`
+	Mocks     int    `json:"mocks"`
+	OnlyMocks bool   `json:"only_mocks,omitempty"`
+}
+
+// jsonThresholds is the JSON representation of role thresholds
+type jsonThresholds struct {
+	MaxDistance     float64 `json:"max_distance"`
+	MaxInstability  float64 `json:"max_instability"`
+	MinAbstractness float64 `json:"min_abstractness"`
+}
+
+// jsonRole is the JSON representation of a role summary
+type jsonRole struct {
+	Role             string         `json:"role"`
+	Packages         int            `json:"packages"`
+	MeanInstability  float64        `json:"mean_instability"`
+	MeanAbstractness float64        `json:"mean_abstractness"`
+	MeanDistance     float64        `json:"mean_distance"`
+	MaxDistance      float64        `json:"max_distance"`
+	Thresholds       jsonThresholds `json:"thresholds"`
+}
+
+// jsonEndpoint is the JSON representation of an endpoint
+type jsonEndpoint struct {
+	Route        string   `json:"route"`
+	Kind         string   `json:"kind"`
+	RegisteredIn string   `json:"registered_in"`
+	Handler      string   `json:"handler"`
+	Dependencies []string `json:"dependencies"`
+}
+
+// jsonSideEffect is the JSON representation of a side effect
+type jsonSideEffect struct {
+	Package         string   `json:"package"`
+	Registrations   []string `json:"registrations,omitempty"`
+	BlankImportedBy []string `json:"blank_imported_by,omitempty"`
+	Binaries        []string `json:"binaries,omitempty"`
+}
+
+// jsonStringCoupling is the JSON representation of a shared string literal
+type jsonStringCoupling struct {
+	Value    string   `json:"value"`
+	Packages []string `json:"packages"`
+}
+
+// JSONFinding is the JSON representation of a finding,
+// shared by the JSON report and the server API
+type JSONFinding struct {
+	ID          string `json:"id"`
+	Severity    string `json:"severity"`
+	Category    string `json:"category"`
+	Package     string `json:"package"`
+	Message     string `json:"message"`
+	Remediation string `json:"remediation"`
+	Points      int    `json:"points"`
+
+	// Advisory is set for findings that fail no gate, e.g. violations of a rule
+	// in its warn period
+	Advisory bool `json:"advisory,omitempty"`
+}
+
+// JSONReport is the JSON report with packages and findings, as written by the
+// json format with findings enabled. It is used to read stored reports back.
+type JSONReport struct {
+	// SchemaVersion is the schema version of the report (see SchemaVersion
+	// and MigrateJSONReport); reports without one are version 0
+	SchemaVersion int `json:"schema_version"`
+
+	Module string `json:"module"`
+
+	// ExportedOnly is set if na, nc and abstractness of the packages count
+	// exported declarations only
+	ExportedOnly bool `json:"exported_only,omitempty"`
+
+	// Summary holds the module-level statistics, if they were computed
+	Summary *JSONSummary `json:"summary,omitempty"`
+
+	Packages []JSONPackage `json:"packages"`
+
+	// Names maps the display names of other modules' packages and of the module
+	// root, which are shortened or extended to stay unique, to their import paths
+	Names map[string]string `json:"package_names,omitempty"`
+
+	Findings []JSONFinding `json:"findings,omitempty"`
+
+	// DebtPoints is the total debt points of the findings
+	DebtPoints int `json:"debt_points,omitempty"`
+
+	// Diagnostics are the errors loading the packages, whose metrics may be
+	// incomplete
+	Diagnostics []JSONDiagnostic `json:"diagnostics,omitempty"`
+}
+
+// JSONDiagnostic is the JSON representation of an error loading a package
+type JSONDiagnostic struct {
+	Package  string `json:"package"`
+	Kind     string `json:"kind"`
+	Position string `json:"position,omitempty"`
+	Message  string `json:"message"`
+}
+
+// NewJSONDiagnostic converts a diagnostic into its JSON representation
+func NewJSONDiagnostic(diagnostic models.Diagnostic) JSONDiagnostic {
+	return JSONDiagnostic{
+		Package:  diagnostic.Package,
+		Kind:     diagnostic.Kind,
+		Position: diagnostic.Position,
+		Message:  diagnostic.Message,
+	}
+}
+
+// NewJSONReport converts module metrics into a JSON report with packages sorted by name
+func NewJSONReport(metrics *models.ModuleMetrics) *JSONReport {
+	r := &Reporter{metrics: metrics}
+	ids := r.packageIDsByName()
+	report := &JSONReport{
+		SchemaVersion: SchemaVersion,
+		Module:        metrics.Path,
+		ExportedOnly:  metrics.ExportedOnly,
+		Packages:      make([]JSONPackage, 0, len(ids)),
+		Names:         metrics.Names,
+	}
+	if metrics.Summary.Packages > 0 {
+		summary := NewJSONSummary(metrics.Summary)
+		report.Summary = &summary
+	}
+	for _, id := range ids {
+		report.Packages = append(report.Packages, NewJSONPackage(metrics.Packages[id]))
+	}
+	for _, finding := range metrics.Findings {
+		report.Findings = append(report.Findings, NewJSONFinding(finding))
+	}
+	report.DebtPoints = models.DebtPoints(metrics.Findings)
+	for _, diagnostic := range metrics.Diagnostics {
+		report.Diagnostics = append(report.Diagnostics, NewJSONDiagnostic(diagnostic))
+	}
+	return report
+}
+
+// ReadJSONReport decodes a JSON report. Sections other than those of JSONReport
+// are ignored. Reports of a newer schema version than SchemaVersion are rejected.
+func ReadJSONReport(r io.Reader) (*JSONReport, error) {
+	var report JSONReport
+	if err := json.NewDecoder(r).Decode(&report); err != nil {
+		return nil, fmt.Errorf("failed to read JSON report: %w", err)
+	}
+	if report.SchemaVersion > SchemaVersion {
+		return nil, fmt.Errorf("JSON report has schema version %d, newer than the supported %d: upgrade aid-metrics", report.SchemaVersion, SchemaVersion)
+	}
+	return &report, nil
+}
+
+// generateJSONReport generates a JSON report.
+// The report is streamed one package at a time, so memory use does not grow with
+// the size of the module. The output is identical to encoding the whole report
+// with json.Encoder and two-space indentation.
 func (r *Reporter) generateJSONReport(w io.Writer) error {
-	// Create a simplified structure for JSON output
-	type jsonPackage struct {
-		Name         string  `json:"name"`
-		Ca           int     `json:"ca"`
-		Ce           int     `json:"ce"`
-		Instability  float64 `json:"instability"`
-		Na           int     `json:"na"`
-		Nc           int     `json:"nc"`
-		Abstractness float64 `json:"abstractness"`
-		Distance     float64 `json:"distance"`
-	}
-
-	type jsonReport struct {
-		Module   string        `json:"module"`
-		Packages []jsonPackage `json:"packages"`
-	}
-
-	// Convert metrics to JSON format
-	report := jsonReport{
-		Module:   r.metrics.Path,
-		Packages: make([]jsonPackage, 0, len(r.metrics.Packages)),
-	}
-
-	for _, pkg := range r.metrics.Packages {
-		report.Packages = append(report.Packages, jsonPackage{
-			Name:         pkg.Name,
-			Ca:           pkg.Ca,
-			Ce:           pkg.Ce,
-			Instability:  pkg.Instability,
-			Na:           pkg.Na,
-			Nc:           pkg.Nc,
-			Abstractness: pkg.Abstractness,
-			Distance:     pkg.Distance,
-		})
+	if r.options.Canonical {
+		return r.generateCanonicalJSONReport(w)
+	}
+	s := newJSONStream(w)
+
+	s.member("schema_version", SchemaVersion)
+	s.member("module", r.metrics.Path)
+	if r.metrics.ExportedOnly {
+		s.member("exported_only", true)
+	}
+	if r.metrics.Summary.Packages > 0 {
+		s.member("summary", NewJSONSummary(r.metrics.Summary))
 	}
 
 	// Sort packages by name for consistent output
-	sort.Slice(report.Packages, func(i, j int) bool {
-		return report.Packages[i].Name < report.Packages[j].Name
+	ids := r.packageIDsByName()
+	s.array("packages", len(ids), false, func(i int) any {
+		pkg := r.metrics.Packages[ids[i]]
+		jp := NewJSONPackage(pkg)
+		if r.options.Edges {
+			jp.Dependents, jp.Dependencies = pkg.Dependents, pkg.Dependencies
+		}
+		return jp
+	})
+	if len(r.metrics.Names) > 0 {
+		s.member("package_names", r.metrics.Names)
+	}
+
+	if r.options.ByRole {
+		roles := r.sortedRoles()
+		s.array("roles", len(roles), true, func(i int) any {
+			role := roles[i]
+			return jsonRole{
+				Role:             role.Role,
+				Packages:         role.Packages,
+				MeanInstability:  role.MeanInstability,
+				MeanAbstractness: role.MeanAbstractness,
+				MeanDistance:     role.MeanDistance,
+				MaxDistance:      role.MaxDistance,
+				Thresholds: jsonThresholds{
+					MaxDistance:     role.Thresholds.MaxDistance,
+					MaxInstability:  role.Thresholds.MaxInstability,
+					MinAbstractness: role.Thresholds.MinAbstractness,
+				},
+			}
+		})
+	}
+
+	if r.options.Endpoints {
+		endpoints := r.metrics.Endpoints
+		s.array("endpoints", len(endpoints), true, func(i int) any {
+			endpoint := endpoints[i]
+			return jsonEndpoint{
+				Route:        endpoint.Route,
+				Kind:         endpoint.Kind,
+				RegisteredIn: endpoint.RegisteredIn,
+				Handler:      endpoint.Handler,
+				Dependencies: endpoint.Dependencies,
+			}
+		})
+	}
+
+	if r.options.SideEffects {
+		effects := r.metrics.SideEffects
+		s.array("side_effects", len(effects), true, func(i int) any {
+			effect := effects[i]
+			return jsonSideEffect{
+				Package:         effect.Package,
+				Registrations:   effect.Registrations,
+				BlankImportedBy: effect.BlankImportedBy,
+				Binaries:        effect.Binaries,
+			}
+		})
+	}
+
+	if r.options.StringCoupling {
+		couplings := r.metrics.StringCouplings
+		s.array("string_coupling", len(couplings), true, func(i int) any {
+			return jsonStringCoupling{Value: couplings[i].Value, Packages: couplings[i].Packages}
+		})
+	}
+
+	if r.options.Findings {
+		findings := r.metrics.Findings
+		s.array("findings", len(findings), true, func(i int) any {
+			return NewJSONFinding(findings[i])
+		})
+		if points := models.DebtPoints(findings); points > 0 {
+			s.member("debt_points", points)
+		}
+	}
+
+	diagnostics := r.metrics.Diagnostics
+	s.array("diagnostics", len(diagnostics), true, func(i int) any {
+		return NewJSONDiagnostic(diagnostics[i])
 	})
 
-	// Encode JSON
-	encoder := json.NewEncoder(w)
-	encoder.SetIndent("", "  ")
-	return encoder.Encode(report)
+	return s.close()
+}
+
+// newJSONDeprecatedUses converts deprecated symbol references into their JSON representation
+func newJSONDeprecatedUses(uses []models.DeprecatedUse) []JSONDeprecatedUse {
+	var result []JSONDeprecatedUse
+	for _, use := range uses {
+		result = append(result, JSONDeprecatedUse{Package: use.Package, Symbol: use.Symbol, Uses: use.Uses})
+	}
+	return result
+}
+
+// newJSONErrorTargets converts exported errors into their JSON representation
+func newJSONErrorTargets(targets []models.ErrorTarget) []JSONErrorTarget {
+	var result []JSONErrorTarget
+	for _, target := range targets {
+		result = append(result, JSONErrorTarget{Name: target.Name, Kind: target.Kind, CheckedBy: target.CheckedBy})
+	}
+	return result
+}
+
+// newJSONConstantSets converts constant sets into their JSON representation
+func newJSONConstantSets(sets []models.ConstantSet) []JSONConstantSet {
+	var result []JSONConstantSet
+	for _, set := range sets {
+		result = append(result, JSONConstantSet{Name: set.Name, Constants: set.Constants, ReferencedBy: set.ReferencedBy})
+	}
+	return result
+}
+
+// newJSONTypeLeaks converts type leaks into their JSON representation
+func newJSONTypeLeaks(leaks []models.TypeLeak) []JSONTypeLeak {
+	var result []JSONTypeLeak
+	for _, leak := range leaks {
+		result = append(result, JSONTypeLeak{Declaration: leak.Declaration, Package: leak.Package, Type: leak.Type})
+	}
+	return result
+}
+
+// newJSONHeaderInterfaces converts header interfaces into their JSON representation
+func newJSONHeaderInterfaces(headers []models.HeaderInterface) []JSONHeaderInterface {
+	var result []JSONHeaderInterface
+	for _, header := range headers {
+		result = append(result, JSONHeaderInterface{Interface: header.Interface, Implementation: header.Implementation})
+	}
+	return result
+}
+
+// newJSONMockedInterfaces converts mocked interfaces into their JSON representation
+func newJSONMockedInterfaces(mocked []models.MockedInterface) []JSONMockedInterface {
+	var result []JSONMockedInterface
+	for _, m := range mocked {
+		result = append(result, JSONMockedInterface{Interface: m.Interface, Mocks: m.Mocks, OnlyMocks: m.OnlyMocks})
+	}
+	return result
+}
+
+// NewJSONPackage converts package metrics into their JSON representation
+func NewJSONPackage(pkg models.PackageMetrics) JSONPackage {
+	var coverage *float64
+	if pkg.HasCoverage {
+		coverage = &pkg.Coverage
+	}
+	return JSONPackage{
+		Name:         pkg.Name,
+		Synopsis:     pkg.Synopsis,
+		Ca:           pkg.Ca,
+		Ce:           pkg.Ce,
+		Instability:  pkg.Instability,
+		Na:           pkg.Na,
+		Nc:           pkg.Nc,
+		Abstractness: pkg.Abstractness,
+		Distance:     pkg.Distance,
+
+		TransitiveCa: pkg.TransitiveCa,
+		TransitiveCe: pkg.TransitiveCe,
+
+		NaAll:           pkg.NaAll,
+		NcAll:           pkg.NcAll,
+		AbstractnessAll: pkg.AbstractnessAll,
+
+		CeExported:          pkg.CeExported,
+		CeInternal:          pkg.CeInternal,
+		ExposedDependencies: pkg.ExposedDependencies,
+		LeakedTypes:         newJSONTypeLeaks(pkg.LeakedTypes),
+
+		ConsumerInterfaces: pkg.ConsumerInterfaces,
+		ProviderInterfaces: pkg.ProviderInterfaces,
+		HeaderInterfaces:   newJSONHeaderInterfaces(pkg.HeaderInterfaces),
+
+		Mocks:              pkg.Mocks,
+		MockedInterfaces:   newJSONMockedInterfaces(pkg.MockedInterfaces),
+		MockOnlyDependents: pkg.MockOnlyDependents,
+
+		StructEmbeds:    pkg.StructEmbeds,
+		InterfaceEmbeds: pkg.InterfaceEmbeds,
+		EmbeddingRatio:  pkg.EmbeddingRatio,
+
+		Methods:           pkg.Methods,
+		PointerMethods:    pkg.PointerMethods,
+		ValueMethods:      pkg.ValueMethods,
+		ExportedMethods:   pkg.ExportedMethods,
+		UnexportedMethods: pkg.UnexportedMethods,
+
+		Constructors:              pkg.Constructors,
+		InterfaceConstructors:     pkg.InterfaceConstructors,
+		InterfaceConstructorRatio: pkg.InterfaceConstructorRatio,
+
+		CompositionRoot: pkg.CompositionRoot,
+		DIFramework:     pkg.DIFramework,
+
+		Role: pkg.Role,
+
+		DeprecatedUses:    pkg.DeprecatedUses,
+		DeprecatedSymbols: newJSONDeprecatedUses(pkg.DeprecatedSymbols),
+
+		ErrorDependents: pkg.ErrorDependents,
+		ErrorTargets:    newJSONErrorTargets(pkg.ErrorTargets),
+
+		ConstantShare:      pkg.ConstantShare,
+		ConstantDependents: pkg.ConstantDependents,
+		SharedKernel:       pkg.SharedKernel,
+		ConstantSets:       newJSONConstantSets(pkg.ConstantSets),
+
+		BlankImports:      pkg.BlankImports,
+		InitRegistrations: pkg.InitRegistrations,
+
+		TaggedStructs: pkg.TaggedStructs,
+		DataBag:       pkg.DataBag,
+
+		Generator:        pkg.Generator,
+		GateExempt:       pkg.GateExempt,
+		GateExemptReason: pkg.GateExemptReason,
+
+		Annotations: pkg.Annotations,
+		Partial:     pkg.Partial,
+
+		DebtPoints: pkg.DebtPoints,
+
+		Complexity:    pkg.Complexity,
+		MaxComplexity: pkg.MaxComplexity,
+		Coverage:      coverage,
+		Health:        pkg.Health,
+		Zone:          pkg.Zone,
+
+		Extensions: pkg.Extensions,
+	}
+}
+
+// NewJSONFinding converts a finding into its JSON representation
+func NewJSONFinding(finding models.Finding) JSONFinding {
+	return JSONFinding{
+		ID:          finding.ID,
+		Severity:    string(finding.Severity),
+		Category:    finding.Category,
+		Package:     finding.Package,
+		Message:     finding.Message,
+		Remediation: finding.Remediation,
+		Points:      finding.Points,
+		Advisory:    finding.Advisory,
+	}
+}
+
+// packageIDsByName returns the package keys ordered by package display name
+func (r *Reporter) packageIDsByName() []string {
+	ids := make([]string, 0, len(r.metrics.Packages))
+	for id := range r.metrics.Packages {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return r.metrics.Packages[ids[i]].Name < r.metrics.Packages[ids[j]].Name
+	})
+	return ids
 }
@@ -0,0 +1,702 @@
+package reporter
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/alkbt/aid-metrics/pkg/models"
+)
+
+// newTestMetrics returns a small module with a cycle and a finding
+func newTestMetrics() *models.ModuleMetrics {
+	return &models.ModuleMetrics{
+		Path: "example.com/shop",
+		Packages: map[string]models.PackageMetrics{
+			"example.com/shop/api": {
+				Name: "api", Ca: 0, Ce: 1, Instability: 1, Distance: 0, Role: models.RoleHandler,
+				Dependencies: []string{"store"},
+			},
+			"example.com/shop/store": {
+				Name: "store", Ca: 1, Ce: 0, Instability: 0, Nc: 4, Distance: 1, Role: models.RoleRepository,
+				Dependents: []string{"api"},
+			},
+		},
+		Cycles: [][]string{{"a", "b"}},
+		Findings: []models.Finding{
+			{ID: "AM003", Severity: models.SeverityWarning, Category: models.CategorySAP, Package: "store", Message: "zone of pain"},
+		},
+	}
+}
+
+func TestAIContextReport(t *testing.T) {
+	var buf bytes.Buffer
+	if err := NewReporter(newTestMetrics(), FormatAIContext).Generate(&buf); err != nil {
+		t.Fatalf("Generate returned error: %v", err)
+	}
+	out := buf.String()
+
+	for _, want := range []string{
+		"module packages=2 avgI=0.5 avgA=0 avgD=0.5 cycles=1 findings=1",
+		"store D=1 A=0 I=0 Ca=1 Ce=0 role=repository\n <- api\n",
+		"api D=0 A=0 I=1 Ca=0 Ce=1 role=handler\n -> store\n",
+		"cycles:\n a,b\n",
+		"AM003 warning store: zone of pain",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected ai-context output to contain %q, got:\n%s", want, out)
+		}
+	}
+
+	// The worst package must come first
+	if strings.Index(out, "store D=") > strings.Index(out, "api D=") {
+		t.Errorf("Expected packages ordered by distance, got:\n%s", out)
+	}
+}
+
+func TestStreamedReportsParse(t *testing.T) {
+	metrics := newTestMetrics()
+	metrics.Findings[0].Message = "zone of pain, \"quoted\""
+	metrics.Findings[0].Points = 3
+	options := ReportOptions{Endpoints: true, Findings: true}
+
+	var jsonOut bytes.Buffer
+	if err := NewReporterWithOptions(metrics, FormatJSON, options).Generate(&jsonOut); err != nil {
+		t.Fatalf("Generate returned error: %v", err)
+	}
+	var report struct {
+		Module   string `json:"module"`
+		Packages []struct {
+			Name string `json:"name"`
+		} `json:"packages"`
+		Endpoints []any `json:"endpoints"`
+		Findings  []struct {
+			Message string `json:"message"`
+		} `json:"findings"`
+	}
+	if err := json.Unmarshal(jsonOut.Bytes(), &report); err != nil {
+		t.Fatalf("invalid JSON report: %v\n%s", err, jsonOut.String())
+	}
+	if len(report.Packages) != 2 || report.Packages[0].Name != "api" || report.Endpoints != nil ||
+		len(report.Findings) != 1 || report.Findings[0].Message != metrics.Findings[0].Message {
+		t.Errorf("unexpected JSON report: %s", jsonOut.String())
+	}
+	// The debt total is read back as the budget of -debt-ratchet
+	if stored, err := ReadJSONReport(bytes.NewReader(jsonOut.Bytes())); err != nil || stored.DebtPoints != 3 {
+		t.Errorf("expected 3 debt points read back, got %v: %s", err, jsonOut.String())
+	}
+
+	var csvOut bytes.Buffer
+	if err := NewReporterWithOptions(metrics, FormatCSV, options).Generate(&csvOut); err != nil {
+		t.Fatalf("Generate returned error: %v", err)
+	}
+	records, err := csv.NewReader(&csvOut).ReadAll()
+	if err != nil {
+		t.Fatalf("invalid CSV report: %v", err)
+	}
+	if len(records) != 2 || records[1][4] != metrics.Findings[0].Message || records[1][6] != "3" {
+		t.Errorf("unexpected CSV records: %q", records)
+	}
+}
+
+func TestExtensions(t *testing.T) {
+	type lint struct {
+		Issues int `json:"issues"`
+	}
+	metrics := newTestMetrics()
+	api := metrics.Packages["example.com/shop/api"]
+	if err := models.SetExtension(&api, "lint", lint{Issues: 2}); err != nil {
+		t.Fatal(err)
+	}
+	api.Extensions["acme"] = json.RawMessage(`{"owners":["payments"]}`)
+	metrics.Packages["example.com/shop/api"] = api
+
+	if got, ok, err := models.GetExtension[lint](api, "lint"); err != nil || !ok || got.Issues != 2 {
+		t.Errorf("expected the lint group back, got %+v, %v, %v", got, ok, err)
+	}
+	if _, ok, _ := models.GetExtension[lint](metrics.Packages["example.com/shop/store"], "lint"); ok {
+		t.Error("expected no lint group for a package without extensions")
+	}
+
+	// Groups unknown to the reader survive a round trip through the JSON report
+	var out bytes.Buffer
+	if err := NewReporter(metrics, FormatJSON).Generate(&out); err != nil {
+		t.Fatalf("Generate returned error: %v", err)
+	}
+	stored, err := ReadJSONReport(&out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rewritten, err := json.Marshal(stored.Packages[0].Extensions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(rewritten) != `{"acme":{"owners":["payments"]},"lint":{"issues":2}}` {
+		t.Errorf("unexpected extensions after a round trip: %s", rewritten)
+	}
+	if stored.Packages[1].Extensions != nil {
+		t.Errorf("expected no extensions for store, got %s", stored.Packages[1].Extensions)
+	}
+}
+
+// newLargeMetrics returns a synthetic module with n packages for benchmarks
+func newLargeMetrics(n int) *models.ModuleMetrics {
+	metrics := &models.ModuleMetrics{
+		Path:     "example.com/monorepo",
+		Packages: make(map[string]models.PackageMetrics, n),
+	}
+	for i := 0; i < n; i++ {
+		name := fmt.Sprintf("pkg/area%d/component%d", i%50, i)
+		metrics.Packages["example.com/monorepo/"+name] = models.PackageMetrics{
+			Name: name, Ca: i % 7, Ce: i % 5, Instability: 0.42, Na: i % 3, Nc: 10, Abstractness: 0.1,
+			Distance: 0.48, Role: models.RoleDomain, Methods: 12, PointerMethods: 8, ValueMethods: 4,
+		}
+		if i%10 == 0 {
+			metrics.Findings = append(metrics.Findings, models.Finding{
+				ID: "AM003", Severity: models.SeverityWarning, Category: models.CategorySAP, Package: name, Message: "zone of pain",
+			})
+		}
+	}
+	return metrics
+}
+
+func benchmarkReport(b *testing.B, format FormatType) {
+	metrics := newLargeMetrics(10000)
+	r := NewReporterWithOptions(metrics, format, ReportOptions{Findings: format != FormatCSV})
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := r.Generate(io.Discard); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkJSONReport(b *testing.B) { benchmarkReport(b, FormatJSON) }
+
+func BenchmarkCSVReport(b *testing.B) { benchmarkReport(b, FormatCSV) }
+
+func BenchmarkTextReport(b *testing.B) { benchmarkReport(b, FormatText) }
+
+func TestHTMLReport(t *testing.T) {
+	metrics := newTestMetrics()
+	metrics.Packages["example.com/shop/store"] = models.PackageMetrics{
+		Name: "store<x>", Ca: 1, Distance: 1, Role: models.RoleRepository,
+	}
+
+	var buf bytes.Buffer
+	if err := NewReporterWithOptions(metrics, FormatHTML, ReportOptions{Findings: true}).Generate(&buf); err != nil {
+		t.Fatalf("Generate returned error: %v", err)
+	}
+	out := buf.String()
+
+	for _, want := range []string{
+		"<!DOCTYPE html>",
+		`<tr class="high"><td>store&lt;x&gt;</td>`,
+		"<tr><td>api</td>",
+		"<h2>Findings</h2>",
+		"<td>AM003</td>",
+		`<circle class="point high" cx="40.0" cy="400.0" r="4">`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected HTML report to contain %q", want)
+		}
+	}
+	if strings.Contains(out, "D change") {
+		t.Error("expected no baseline column without a baseline")
+	}
+
+	baseline := &JSONReport{Packages: []JSONPackage{
+		{Name: "store<x>", Instability: 0.5, Abstractness: 0.5},
+		{Name: "legacy", Distance: 1},
+	}}
+	buf.Reset()
+	if err := NewReporterWithOptions(metrics, FormatHTML, ReportOptions{Baseline: baseline}).Generate(&buf); err != nil {
+		t.Fatalf("Generate returned error: %v", err)
+	}
+	out = buf.String()
+
+	for _, want := range []string{
+		"<th>D change</th>",
+		`<td class="worse">&#43;1.00</td>`,
+		`<line class="move" x1="220.0" y1="220.0" x2="40.0" y2="400.0"/>`,
+		"Removed since the baseline: legacy",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected HTML report with baseline to contain %q", want)
+		}
+	}
+}
+
+func TestSVGReport(t *testing.T) {
+	metrics := newTestMetrics()
+	metrics.Packages["example.com/shop/store"] = models.PackageMetrics{
+		Name: "store<x>", Ca: 1, Distance: 1, Role: models.RoleRepository,
+	}
+
+	var buf bytes.Buffer
+	if err := NewReporter(metrics, FormatSVG).Generate(&buf); err != nil {
+		t.Fatalf("Generate returned error: %v", err)
+	}
+
+	// The image must be well-formed XML with one point per package
+	var circles int
+	var labels []string
+	dec := xml.NewDecoder(&buf)
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("invalid SVG: %v", err)
+		}
+		if start, ok := tok.(xml.StartElement); ok && start.Name.Local == "circle" {
+			circles++
+		}
+		if text, ok := tok.(xml.CharData); ok {
+			labels = append(labels, string(text))
+		}
+	}
+	if circles != 2 {
+		t.Errorf("expected 2 points, got %d", circles)
+	}
+	all := strings.Join(labels, "|")
+	for _, want := range []string{"zone of pain", "zone of uselessness", "main sequence", "store<x>"} {
+		if !strings.Contains(all, want) {
+			t.Errorf("expected SVG to contain the text %q", want)
+		}
+	}
+}
+
+func TestTopologicalSort(t *testing.T) {
+	metrics := &models.ModuleMetrics{
+		Path: "example.com/shop",
+		Packages: map[string]models.PackageMetrics{
+			"example.com/shop/api":     {Name: "api", Dependencies: []string{"billing", "orders"}},
+			"example.com/shop/billing": {Name: "billing", Dependencies: []string{"orders", "models"}},
+			"example.com/shop/orders":  {Name: "orders", Dependencies: []string{"billing", "models", "go/packages"}},
+			"example.com/shop/models":  {Name: "models"},
+			"example.com/shop/util":    {Name: "util"},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := NewReporterWithOptions(metrics, FormatCSV, ReportOptions{Sort: SortTopo}).Generate(&buf); err != nil {
+		t.Fatal(err)
+	}
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var order []string
+	for _, record := range records[1:] {
+		order = append(order, record[0]+":"+record[len(record)-1])
+	}
+	// billing and orders import each other, so they share a layer
+	if want := "models:0 util:0 billing:1 orders:1 api:2"; strings.Join(order, " ") != want {
+		t.Errorf("expected order %q, got %q", want, strings.Join(order, " "))
+	}
+}
+
+func TestTopWorst(t *testing.T) {
+	metrics := &models.ModuleMetrics{
+		Path: "example.com/shop",
+		Packages: map[string]models.PackageMetrics{
+			"example.com/shop/api":     {Name: "api", Ce: 3, Distance: 0.2},
+			"example.com/shop/billing": {Name: "billing", Ca: 1, Ce: 2, Distance: 0.6},
+			"example.com/shop/orders":  {Name: "orders", Ca: 2, Ce: 2, Distance: 0.6},
+			"example.com/shop/models":  {Name: "models", Ca: 3, Distance: 0.9},
+		},
+	}
+
+	for _, tc := range []struct {
+		sort string
+		want string
+	}{
+		{SortDistance, "models billing"}, // ties broken by name
+		{SortCa, "models orders"},
+		{SortCe, "api billing"},
+	} {
+		var buf bytes.Buffer
+		if err := NewReporterWithOptions(metrics, FormatCSV, ReportOptions{Sort: tc.sort, Top: 2}).Generate(&buf); err != nil {
+			t.Fatal(err)
+		}
+		records, err := csv.NewReader(&buf).ReadAll()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, record := range records[1:] {
+			names = append(names, record[0])
+		}
+		if got := strings.Join(names, " "); got != tc.want {
+			t.Errorf("-sort %s -top 2: expected %q, got %q", tc.sort, tc.want, got)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := NewReporterWithOptions(metrics, FormatText, ReportOptions{Sort: SortDistance, Top: 1}).Generate(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "(1 of 4 packages shown)") {
+		t.Errorf("expected a note on the omitted packages, got %q", buf.String())
+	}
+}
+
+func TestDSM(t *testing.T) {
+	// api -> orders <-> billing -> models
+	metrics := &models.ModuleMetrics{
+		Path: "example.com/shop",
+		Packages: map[string]models.PackageMetrics{
+			"example.com/shop/api":     {Name: "api", Dependencies: []string{"orders"}},
+			"example.com/shop/orders":  {Name: "orders", Dependencies: []string{"billing"}},
+			"example.com/shop/billing": {Name: "billing", Dependencies: []string{"models", "orders"}},
+			"example.com/shop/models":  {Name: "models", Dependencies: []string{"github.com/google/uuid"}},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := NewReporterWithOptions(metrics, FormatDSM, ReportOptions{Sort: SortTopo}).Generate(&buf); err != nil {
+		t.Fatal(err)
+	}
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rows []string
+	for _, record := range records {
+		rows = append(rows, strings.Join(record, ","))
+	}
+	want := []string{
+		"Package,Layer,models,billing,orders,api",
+		"models,0,,,,",
+		"billing,1,1,,1,",
+		"orders,1,,1,,",
+		"api,2,,,1,",
+	}
+	if strings.Join(rows, "\n") != strings.Join(want, "\n") {
+		t.Errorf("expected\n%s\ngot\n%s", strings.Join(want, "\n"), strings.Join(rows, "\n"))
+	}
+
+	buf.Reset()
+	if err := NewReporterWithOptions(metrics, FormatDSMHTML, ReportOptions{Sort: SortTopo}).Generate(&buf); err != nil {
+		t.Fatal(err)
+	}
+	page := buf.String()
+	for _, want := range []string{`<td class="feedback" title="billing imports orders">`, "red, 1 of them", `<tr class="boundary">`} {
+		if !strings.Contains(page, want) {
+			t.Errorf("expected the DSM page to contain %s", want)
+		}
+	}
+}
+
+func TestReportPath(t *testing.T) {
+	tests := []struct {
+		output string
+		format FormatType
+		want   string
+	}{
+		{"report", FormatJSON, "report.json"},
+		{"out/report.json", FormatHTML, "out/report.html"},
+		{"", FormatCSV, "metrics.csv"},
+		{"report", FormatText, "report.txt"},
+		{"report", FormatAIContext, "report.ai.txt"},
+		{"report", FormatMermaid, "report.mmd"},
+		{"report", FormatDSM, "report.dsm.csv"},
+		{"report", FormatDSMHTML, "report.dsm.html"},
+		{"out/report.ai.txt", FormatJSON, "out/report.json"},
+		{"out/report.dsm.csv", FormatCSV, "out/report.csv"},
+		{"out.d/report", FormatDOT, "out.d/report.dot"},
+	}
+	for _, tt := range tests {
+		if got := ReportPath(tt.output, tt.format); got != tt.want {
+			t.Errorf("ReportPath(%q, %s) = %q, want %q", tt.output, tt.format, got, tt.want)
+		}
+	}
+}
+
+func TestGraphReports(t *testing.T) {
+	metrics := &models.ModuleMetrics{
+		Path: "example.com/shop",
+		Packages: map[string]models.PackageMetrics{
+			"example.com/shop/orders":     {Name: "orders", Dependencies: []string{"store", "api/v1", "github.com/google/uuid"}},
+			"example.com/shop/api/v1":     {Name: "api/v1", Dependencies: []string{"orders"}},
+			"example.com/shop/store":      {Name: "store"},
+			"example.com/shop/api/v1/sub": {Name: "api_v1"},
+		},
+	}
+
+	generate := func(format FormatType, options ReportOptions) string {
+		var buf bytes.Buffer
+		if err := NewReporterWithOptions(metrics, format, options).Generate(&buf); err != nil {
+			t.Fatal(err)
+		}
+		return buf.String()
+	}
+
+	want := "digraph \"example.com/shop\" {\n\trankdir=LR;\n\tordering=out;\n\tstart=42;\n\tnode [shape=box];\n" +
+		"\tp_api_sv1 [label=\"api/v1\"];\n\tp_api__v1 [label=\"api_v1\"];\n\tp_orders [label=\"orders\"];\n\tp_store [label=\"store\"];\n" +
+		"\tp_api_sv1 -> p_orders;\n\tp_orders -> p_api_sv1;\n\tp_orders -> p_store;\n}\n"
+	if got := generate(FormatDOT, ReportOptions{LayoutSeed: 42}); got != want {
+		t.Errorf("expected DOT\n%s\ngot\n%s", want, got)
+	}
+	for i := 0; i < 5; i++ {
+		if got := generate(FormatDOT, ReportOptions{LayoutSeed: 42}); got != want {
+			t.Fatalf("expected the same DOT on every run, got\n%s", got)
+		}
+	}
+
+	// Dependency layers: api_v1 and store, then the cycle of api/v1 and orders
+	// The IDs are those of the DOT graph, whatever the order of the packages
+	want = "flowchart LR\n\tp_api__v1[\"api_v1\"]\n\tp_store[\"store\"]\n\tp_api_sv1[\"api/v1\"]\n\tp_orders[\"orders\"]\n" +
+		"\tp_api_sv1 --> p_orders\n\tp_orders --> p_api_sv1\n\tp_orders --> p_store\n"
+	if got := generate(FormatMermaid, ReportOptions{Sort: SortTopo}); got != want {
+		t.Errorf("expected Mermaid\n%s\ngot\n%s", want, got)
+	}
+
+	seen := make(map[string]string)
+	for _, name := range []string{"a/b", "a_b", "a__sb", "a_sb", "a.b", "a-b", "a b", "a+b", "a_x2Bb", "aé"} {
+		id := graphID(name)
+		if other, ok := seen[id]; ok {
+			t.Errorf("%q and %q share the ID %s", other, name, id)
+		}
+		seen[id] = name
+	}
+}
+
+func TestCanonicalJSON(t *testing.T) {
+	metrics := newTestMetrics()
+	pkg := metrics.Packages["example.com/shop/api"]
+	pkg.Distance, pkg.Abstractness = 0.1+0.2, -0.0001
+	metrics.Packages["example.com/shop/api"] = pkg
+
+	var buf bytes.Buffer
+	if err := NewReporterWithOptions(metrics, FormatJSON, ReportOptions{Canonical: true, Findings: true}).Generate(&buf); err != nil {
+		t.Fatal(err)
+	}
+	report := buf.String()
+	for _, want := range []string{`"abstractness": 0.00,`, `"distance": 0.30,`, `"instability": 1.00,`, `"instability": 0.00,`, "\"schema_version\": 1\n"} {
+		if !strings.Contains(report, want) {
+			t.Errorf("expected the canonical report to contain %s, got\n%s", want, report)
+		}
+	}
+	if strings.Index(report, `"findings"`) > strings.Index(report, `"module"`) {
+		t.Error("expected the members of the canonical report sorted by key")
+	}
+
+	again, err := CanonicalJSON(buf.Bytes(), DefaultPrecision)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(again) != report {
+		t.Errorf("expected canonicalizing a canonical report to leave it unchanged, got\n%s", again)
+	}
+	if _, err := ReadJSONReport(&buf); err != nil {
+		t.Errorf("expected the canonical report to be readable: %v", err)
+	}
+}
+
+func TestColumns(t *testing.T) {
+	metrics := &models.ModuleMetrics{
+		Path: "example.com/shop",
+		Packages: map[string]models.PackageMetrics{
+			"example.com/shop/api":    {Name: "api", Ce: 1, Distance: 0.12345, Dependencies: []string{"models"}},
+			"example.com/shop/models": {Name: "models", Ca: 1},
+		},
+	}
+
+	var buf bytes.Buffer
+	options := ReportOptions{Columns: []string{"D", "layer", "ca"}, Precision: 3}
+	if err := NewReporterWithOptions(metrics, FormatCSV, options).Generate(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if want := "Package,D,Layer,Ca\napi,0.123,1,0\nmodels,0.000,0,1\n"; buf.String() != want {
+		t.Errorf("expected\n%s\ngot\n%s", want, buf.String())
+	}
+
+	buf.Reset()
+	if err := NewReporterWithOptions(metrics, FormatText, options).Generate(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "PACKAGE  D      Layer  Ca") {
+		t.Errorf("expected the selected columns in the text header, got %q", buf.String())
+	}
+
+	err := NewReporterWithOptions(metrics, FormatCSV, ReportOptions{Columns: []string{"bogus"}}).Generate(&buf)
+	if err == nil || !strings.Contains(err.Error(), "unknown column") {
+		t.Errorf("expected an unknown column error, got %v", err)
+	}
+}
+
+func TestMigrateJSONReport(t *testing.T) {
+	metrics := &models.ModuleMetrics{
+		Path: "example.com/shop",
+		Packages: map[string]models.PackageMetrics{
+			"example.com/shop/api": {Name: "api", Ce: 1, Role: "handler"},
+		},
+		Roles: map[string]models.RoleMetrics{"handler": {Role: "handler", Packages: 1}},
+	}
+	var buf bytes.Buffer
+	if err := NewReporterWithOptions(metrics, FormatJSON, ReportOptions{ByRole: true}).Generate(&buf); err != nil {
+		t.Fatal(err)
+	}
+	current := buf.String()
+
+	// A report written before schema versions, with a section unknown to JSONReport
+	legacy := strings.Replace(current, "  \"schema_version\": 1,\n", "", 1)
+	if legacy == current || !strings.Contains(legacy, `"roles"`) {
+		t.Fatalf("unexpected report layout:\n%s", current)
+	}
+	migrated, changed, err := MigrateJSONReport([]byte(legacy))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !changed || string(migrated) != current {
+		t.Errorf("expected the migrated report to match a current one\nwant:\n%s\ngot:\n%s", current, migrated)
+	}
+
+	if _, changed, err := MigrateJSONReport([]byte(current)); err != nil || changed {
+		t.Errorf("expected a current report to be left alone, got changed=%v err=%v", changed, err)
+	}
+	if _, _, err := MigrateJSONReport([]byte(`{"schema_version": 99}`)); err == nil {
+		t.Error("expected an error for a report of a newer schema version")
+	}
+	if _, err := ReadJSONReport(strings.NewReader(`{"schema_version": 99}`)); err == nil {
+		t.Error("expected ReadJSONReport to reject a newer schema version")
+	}
+}
+
+func TestEdges(t *testing.T) {
+	metrics := &models.ModuleMetrics{
+		Path: "example.com/shop",
+		Packages: map[string]models.PackageMetrics{
+			"example.com/shop/api":    {Name: "api", Ce: 1, Dependencies: []string{"models"}},
+			"example.com/shop/models": {Name: "models", Ca: 1, Dependents: []string{"api"}},
+		},
+	}
+
+	for _, edges := range []bool{false, true} {
+		var buf bytes.Buffer
+		if err := NewReporterWithOptions(metrics, FormatJSON, ReportOptions{Edges: edges}).Generate(&buf); err != nil {
+			t.Fatal(err)
+		}
+		report, err := ReadJSONReport(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		api, base := report.Packages[0], report.Packages[1]
+		got := strings.Join(api.Dependencies, ",") + "|" + strings.Join(base.Dependents, ",")
+		if want := map[bool]string{false: "|", true: "models|api"}[edges]; got != want {
+			t.Errorf("edges=%v: expected JSON edges %q, got %q", edges, want, got)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := NewReporterWithOptions(metrics, FormatHTML, ReportOptions{Edges: true}).Generate(&buf); err != nil {
+		t.Fatal(err)
+	}
+	html := buf.String()
+	if !strings.Contains(html, "<th data-type=\"text\">Dependents</th>") || !strings.Contains(html, `<td class="text edges">api</td>`) {
+		t.Errorf("expected the edge columns in the HTML report, got %s", html)
+	}
+}
+
+func TestASCIIText(t *testing.T) {
+	metrics := &models.ModuleMetrics{
+		Path: "/home/josé/shop",
+		Packages: map[string]models.PackageMetrics{
+			"example.com/shop/café":  {Name: "café", Ca: 1},
+			"example.com/shop/store": {Name: "store"},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := NewReporterWithOptions(metrics, FormatText, ReportOptions{ASCII: true}).Generate(&buf); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, b := range []byte(out) {
+		if b >= 0x80 {
+			t.Fatalf("expected pure ASCII output, got %q", out)
+		}
+	}
+	if !strings.Contains(out, `MODULE: /home/jos\u00E9/shop`) {
+		t.Errorf("expected the escaped module path, got %q", out)
+	}
+
+	// Columns are aligned on the escaped names
+	lines := strings.Split(out, "\n")
+	if !strings.HasPrefix(lines[4], `caf\u00E9  1`) || !strings.HasPrefix(lines[5], "store      0") {
+		t.Errorf("expected aligned columns, got %q", lines[4:6])
+	}
+}
+
+// namesReporter is a third-party format listing the package names
+type namesReporter struct {
+	metrics *models.ModuleMetrics
+}
+
+func (n namesReporter) Generate(w io.Writer) error {
+	for _, id := range NewReporter(n.metrics, FormatText).packageIDsByName() {
+		fmt.Fprintln(w, n.metrics.Packages[id].Name)
+	}
+	return nil
+}
+
+func TestRegister(t *testing.T) {
+	Register("names", func(metrics *models.ModuleMetrics) Generator { return namesReporter{metrics} })
+
+	var buf bytes.Buffer
+	if err := NewReporter(newTestMetrics(), "names").Generate(&buf); err != nil {
+		t.Fatalf("Generate returned error: %v", err)
+	}
+	if buf.String() != "api\nstore\n" {
+		t.Errorf("unexpected output of the registered format: %q", buf.String())
+	}
+	if formats := strings.Join(Formats(), ","); !strings.HasSuffix(formats, ",mermaid,names") {
+		t.Errorf("expected the registered format after the built-in ones, got %s", formats)
+	}
+
+	for _, name := range []string{"names", "json"} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("expected Register(%q) to panic", name)
+				}
+			}()
+			Register(name, func(metrics *models.ModuleMetrics) Generator { return namesReporter{metrics} })
+		}()
+	}
+}
+
+func TestJSONProgressReporter(t *testing.T) {
+	var buf bytes.Buffer
+	r := NewJSONProgressReporter(&buf)
+	r.StageStart(models.StageDiscovery, 0)
+	r.Update(5, "5 packages found")
+	r.StageDone(models.StageDiscovery)
+	r.StageStart(models.StageLoading, 30)
+	r.Update(12, "Loaded 12 of 30 packages")
+	r.StageDone(models.StageLoading)
+	r.Complete()
+
+	want := `{"event":"start","stage":"discovery","current":0,"total":0}
+{"stage":"discovery","current":5,"total":0,"detail":"5 packages found"}
+{"event":"done","stage":"discovery","current":5,"total":0}
+{"event":"start","stage":"loading","current":0,"total":30}
+{"stage":"loading","current":12,"total":30,"detail":"Loaded 12 of 30 packages"}
+{"event":"done","stage":"loading","current":30,"total":30}
+{"event":"complete","current":0,"total":0}
+`
+	if buf.String() != want {
+		t.Errorf("expected the progress stream\n%s\ngot\n%s", want, buf.String())
+	}
+}
@@ -0,0 +1,78 @@
+// Package reportertest provides snapshot testing for report formats built on
+// package reporter. A test renders a report into memory and compares it with a
+// golden file; running the tests with UPDATE_SNAPSHOTS=1 rewrites the golden
+// files instead.
+package reportertest
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/alkbt/aid-metrics/pkg/models"
+	"github.com/alkbt/aid-metrics/pkg/reporter"
+)
+
+// UpdateEnv is the environment variable that makes Snapshot rewrite golden files
+const UpdateEnv = "UPDATE_SNAPSHOTS"
+
+// Sample returns a small module with two packages, a cycle and a finding.
+// Every call returns a fresh value, so tests may modify it.
+func Sample() models.ModuleMetrics {
+	return models.ModuleMetrics{
+		Path: "example.com/shop",
+		Packages: map[string]models.PackageMetrics{
+			"example.com/shop/api": {
+				Name: "api", Ce: 1, Instability: 1, Role: models.RoleHandler, Health: 100, Zone: models.ZoneMainSequence,
+				Dependencies: []string{"store"},
+			},
+			"example.com/shop/store": {
+				Name: "store", Ca: 1, Nc: 4, Distance: 1, Role: models.RoleRepository, Health: 60, Zone: models.ZonePain,
+				Dependents: []string{"api"},
+			},
+		},
+		Cycles: [][]string{{"api", "store"}},
+		Summary: models.Summary{
+			Packages: 2, Measured: 2, MeanDistance: 0.5, MedianDistance: 0.5, P90Distance: 1,
+			WorstOffenders: []string{"store"},
+			Zones:          map[string]int{models.ZoneMainSequence: 1, models.ZonePain: 1},
+		},
+		Findings: []models.Finding{
+			{ID: "AM003", Severity: models.SeverityWarning, Category: models.CategorySAP, Package: "store", Message: "zone of pain", Points: 3},
+		},
+	}
+}
+
+// Render renders metrics in the given format and fails the test on error
+func Render(t testing.TB, metrics models.ModuleMetrics, format reporter.FormatType, options reporter.ReportOptions) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := reporter.Render(&buf, metrics, format, options); err != nil {
+		t.Fatalf("rendering %s report: %v", format, err)
+	}
+	return buf.Bytes()
+}
+
+// Snapshot compares got with the golden file at path, failing the test when
+// they differ. With UPDATE_SNAPSHOTS=1 it writes got to the file instead.
+func Snapshot(t testing.TB, path string, got []byte) {
+	t.Helper()
+	if os.Getenv(UpdateEnv) == "1" {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading snapshot (run with %s=1 to create it): %v", UpdateEnv, err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("output differs from snapshot %s (run with %s=1 to update it)\ngot:\n%s\nwant:\n%s", path, UpdateEnv, got, want)
+	}
+}
@@ -0,0 +1,58 @@
+package reportertest
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/alkbt/aid-metrics/pkg/reporter"
+)
+
+func TestSnapshots(t *testing.T) {
+	for _, format := range []reporter.FormatType{reporter.FormatText, reporter.FormatCSV, reporter.FormatJSON, reporter.FormatAIContext} {
+		t.Run(string(format), func(t *testing.T) {
+			got := Render(t, Sample(), format, reporter.ReportOptions{Findings: true})
+			Snapshot(t, filepath.Join("testdata", string(format)+".golden"), got)
+		})
+	}
+}
+
+func TestCustomAssets(t *testing.T) {
+	assets := fstest.MapFS{
+		reporter.HTMLTemplate: {Data: []byte("{{.Module}}:{{range .Packages}} {{.Name}}{{end}}\n")},
+	}
+	got := Render(t, Sample(), reporter.FormatHTML, reporter.ReportOptions{Assets: assets})
+	if string(got) != "example.com/shop: api store\n" {
+		t.Errorf("unexpected report from custom template: %q", got)
+	}
+
+	var missing strings.Builder
+	if err := reporter.Render(&missing, Sample(), reporter.FormatSVG, reporter.ReportOptions{Assets: assets}); err == nil {
+		t.Error("expected an error for assets without the SVG template")
+	}
+}
+
+func TestOfflineAssets(t *testing.T) {
+	for _, format := range []reporter.FormatType{reporter.FormatHTML, reporter.FormatSVG, reporter.FormatDSMHTML} {
+		var out strings.Builder
+		if err := reporter.Render(&out, Sample(), format, reporter.ReportOptions{Findings: true, Offline: true}); err != nil {
+			t.Errorf("expected the built-in %s template to work offline, got %v", format, err)
+		}
+	}
+
+	assets := fstest.MapFS{
+		reporter.HTMLTemplate: {Data: []byte(`<link rel="stylesheet" href="https://cdn.example.com/style.css">` +
+			`<a href="https://example.com/docs">docs</a><script src="//cdn.example.com/chart.js"></script>` +
+			`<style>@font-face { src: url('https://fonts.example.com/inter.woff2'); }</style>{{.Module}}`)},
+	}
+	var out strings.Builder
+	if err := reporter.Render(&out, Sample(), reporter.FormatHTML, reporter.ReportOptions{Assets: assets}); err != nil {
+		t.Fatalf("expected network resources to be allowed without Offline, got %v", err)
+	}
+	err := reporter.Render(&out, Sample(), reporter.FormatHTML, reporter.ReportOptions{Assets: assets, Offline: true})
+	want := "https://cdn.example.com/style.css, //cdn.example.com/chart.js, https://fonts.example.com/inter.woff2"
+	if err == nil || !strings.HasSuffix(err.Error(), want) {
+		t.Errorf("expected an error listing %s, got %v", want, err)
+	}
+}
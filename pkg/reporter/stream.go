@@ -0,0 +1,209 @@
+// Package reporter handles output generation for aid-metrics analysis results.
+// This file implements the streaming writers used by the CSV and JSON formats.
+package reporter
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// jsonStream writes an indented JSON object member by member.
+// Arrays are written one element at a time, so only a single element is held
+// in memory at once. The first error is kept and returned by close.
+type jsonStream struct {
+	w       *bufio.Writer
+	buf     bytes.Buffer
+	values  *json.Encoder // Encodes member values, indented one level
+	elems   *json.Encoder // Encodes array elements, indented two levels
+	members int
+	err     error
+}
+
+// newJSONStream creates a stream writing to w
+func newJSONStream(w io.Writer) *jsonStream {
+	s := &jsonStream{w: bufio.NewWriter(w)}
+	s.values = json.NewEncoder(&s.buf)
+	s.values.SetIndent("  ", "  ")
+	s.elems = json.NewEncoder(&s.buf)
+	s.elems.SetIndent("    ", "  ")
+	return s
+}
+
+// member writes an object member with the given value
+func (s *jsonStream) member(name string, value any) {
+	s.key(name)
+	s.encode(s.values, value)
+}
+
+// array writes an object member holding an array of n elements produced by elem.
+// With omitEmpty, an empty array is left out like a slice tagged omitempty.
+func (s *jsonStream) array(name string, n int, omitEmpty bool, elem func(i int) any) {
+	if n == 0 && omitEmpty {
+		return
+	}
+	s.key(name)
+	if n == 0 {
+		s.write("[]")
+		return
+	}
+
+	s.write("[")
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			s.write(",")
+		}
+		s.write("\n    ")
+		s.encode(s.elems, elem(i))
+	}
+	s.write("\n  ]")
+}
+
+// close terminates the object and flushes the output
+func (s *jsonStream) close() error {
+	if s.members == 0 {
+		s.write("{")
+	}
+	s.write("\n}\n")
+	if s.err != nil {
+		return s.err
+	}
+	return s.w.Flush()
+}
+
+// key writes the separator and the name of the next member
+func (s *jsonStream) key(name string) {
+	if s.members == 0 {
+		s.write("{")
+	} else {
+		s.write(",")
+	}
+	s.members++
+	s.write("\n  ")
+	s.write(strconv.Quote(name))
+	s.write(": ")
+}
+
+// encode writes a value using the given encoder, without the trailing newline
+func (s *jsonStream) encode(enc *json.Encoder, value any) {
+	if s.err != nil {
+		return
+	}
+	s.buf.Reset()
+	if err := enc.Encode(value); err != nil {
+		s.err = err
+		return
+	}
+	s.buf.Truncate(s.buf.Len() - 1)
+	_, s.err = s.w.Write(s.buf.Bytes())
+}
+
+// write writes a literal string
+func (s *jsonStream) write(str string) {
+	if s.err != nil {
+		return
+	}
+	_, s.err = s.w.WriteString(str)
+}
+
+// csvStream writes CSV records without allocating per field.
+// Numbers are formatted into a reusable scratch buffer and fields are quoted
+// following the same rules as encoding/csv.
+type csvStream struct {
+	w       *bufio.Writer
+	scratch []byte
+	fields  int
+	err     error
+
+	precision int // Decimals of float fields
+}
+
+// newCSVStream creates a CSV stream writing to w
+func newCSVStream(w io.Writer) *csvStream {
+	return &csvStream{w: bufio.NewWriter(w), scratch: make([]byte, 0, 32), precision: DefaultPrecision}
+}
+
+// str writes a string field
+func (c *csvStream) str(field string) {
+	c.separate()
+	if !csvNeedsQuotes(field) {
+		c.writeString(field)
+		return
+	}
+	c.writeString(`"`)
+	c.writeString(strings.ReplaceAll(field, `"`, `""`))
+	c.writeString(`"`)
+}
+
+// int writes an integer field
+func (c *csvStream) int(v int) {
+	c.separate()
+	c.scratch = strconv.AppendInt(c.scratch[:0], int64(v), 10)
+	c.writeBytes(c.scratch)
+}
+
+// float writes a float field with the stream's precision
+func (c *csvStream) float(v float64) {
+	c.separate()
+	c.scratch = strconv.AppendFloat(c.scratch[:0], v, 'f', c.precision, 64)
+	c.writeBytes(c.scratch)
+}
+
+// record writes all string fields of a record and ends it
+func (c *csvStream) record(fields ...string) {
+	for _, field := range fields {
+		c.str(field)
+	}
+	c.end()
+}
+
+// end terminates the current record
+func (c *csvStream) end() {
+	c.writeString("\n")
+	c.fields = 0
+}
+
+// flush flushes the output and returns the first error
+func (c *csvStream) flush() error {
+	if c.err != nil {
+		return c.err
+	}
+	return c.w.Flush()
+}
+
+// separate writes the field separator before every field but the first
+func (c *csvStream) separate() {
+	if c.fields > 0 {
+		c.writeString(",")
+	}
+	c.fields++
+}
+
+func (c *csvStream) writeString(s string) {
+	if c.err == nil {
+		_, c.err = c.w.WriteString(s)
+	}
+}
+
+func (c *csvStream) writeBytes(b []byte) {
+	if c.err == nil {
+		_, c.err = c.w.Write(b)
+	}
+}
+
+// csvNeedsQuotes reports whether a field must be quoted, matching encoding/csv
+func csvNeedsQuotes(field string) bool {
+	if field == "" {
+		return false
+	}
+	if field == `\.` || strings.ContainsAny(field, "\",\r\n") {
+		return true
+	}
+	r, _ := utf8.DecodeRuneInString(field)
+	return unicode.IsSpace(r)
+}
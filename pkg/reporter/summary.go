@@ -0,0 +1,66 @@
+// Package reporter handles output generation for aid-metrics analysis results.
+// This file implements the module summary block at the top of reports.
+package reporter
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/alkbt/aid-metrics/pkg/models"
+)
+
+// summaryZones lists the zones in the order of the summary block
+var summaryZones = []string{models.ZoneMainSequence, models.ZonePain, models.ZoneUselessness}
+
+// JSONSummary is the JSON representation of the module summary statistics
+type JSONSummary struct {
+	Packages       int            `json:"packages"`
+	Measured       int            `json:"measured"`
+	MeanDistance   float64        `json:"mean_distance"`
+	MedianDistance float64        `json:"median_distance"`
+	P90Distance    float64        `json:"p90_distance"`
+	WorstOffenders []string       `json:"worst_offenders,omitempty"`
+	Zones          map[string]int `json:"zones,omitempty"`
+}
+
+// NewJSONSummary converts the module summary statistics into their JSON representation
+func NewJSONSummary(summary models.Summary) JSONSummary {
+	return JSONSummary{
+		Packages:       summary.Packages,
+		Measured:       summary.Measured,
+		MeanDistance:   summary.MeanDistance,
+		MedianDistance: summary.MedianDistance,
+		P90Distance:    summary.P90Distance,
+		WorstOffenders: summary.WorstOffenders,
+		Zones:          summary.Zones,
+	}
+}
+
+// zoneCounts returns the number of packages per zone as a line of text
+func zoneCounts(summary models.Summary) string {
+	zones := make([]string, 0, len(summaryZones))
+	for _, zone := range summaryZones {
+		zones = append(zones, fmt.Sprintf("%s %d", zone, summary.Zones[zone]))
+	}
+	return strings.Join(zones, ", ")
+}
+
+// writeTextSummary writes the summary block of the text report, if the
+// summary statistics were computed
+func (r *Reporter) writeTextSummary(w io.Writer) {
+	summary := r.metrics.Summary
+	if summary.Packages == 0 {
+		return
+	}
+	fmt.Fprintf(w, "Packages: %d (%d measured for D)\n", summary.Packages, summary.Measured)
+	if summary.Measured > 0 {
+		fmt.Fprintf(w, "D: mean %.2f, median %.2f, p90 %.2f\n", summary.MeanDistance, summary.MedianDistance, summary.P90Distance)
+	}
+	if len(summary.Zones) > 0 {
+		fmt.Fprintf(w, "Zones: %s\n", zoneCounts(summary))
+	}
+	if len(summary.WorstOffenders) > 0 {
+		fmt.Fprintf(w, "Worst offenders: %s\n", strings.Join(summary.WorstOffenders, ", "))
+	}
+}
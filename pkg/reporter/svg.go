@@ -0,0 +1,90 @@
+// Package reporter handles output generation for aid-metrics analysis results.
+// This file implements the standalone SVG scatter plot of abstractness versus instability.
+package reporter
+
+import (
+	"fmt"
+	"io"
+)
+
+// Layout of the SVG chart: the plot area is svgChartSize pixels square, with
+// svgChartMargin pixels around it for the axis labels
+const (
+	svgChartSize   = 480
+	svgChartMargin = 60
+)
+
+// svgZoneDistance is the distance from the main sequence beyond which the chart
+// shades the zones of pain and uselessness, matching the SAP findings
+const svgZoneDistance = 0.7
+
+// svgPoint is a package plotted on the chart
+type svgPoint struct {
+	Name                                string
+	Instability, Abstractness, Distance float64
+	X, Y                                float64
+
+	// High is set when the package exceeds the distance limit of its role;
+	// such packages are drawn in red and labeled
+	High bool
+}
+
+// svgChart is the data rendered by SVGTemplate
+type svgChart struct {
+	Module string
+	Points []svgPoint
+
+	// Size is the width and height of the image, PlotSize of the plot area
+	Size, PlotSize int
+
+	// Plot area edges and center, and the corners of the zone triangles
+	Left, Top, Right, Bottom, Middle float64
+	PainZone, UselessZone            string
+}
+
+// generateSVGReport generates the classic main sequence chart: one point per
+// package with instability on the horizontal and abstractness on the vertical
+// axis, the main sequence diagonal, and the zones of pain (stable and concrete)
+// and uselessness (unstable and abstract) shaded in the corners.
+func (r *Reporter) generateSVGReport(w io.Writer) error {
+	pos := func(i, a float64) (float64, float64) {
+		return svgChartMargin + i*svgChartSize, svgChartMargin + (1-a)*svgChartSize
+	}
+	point := func(i, a float64) string {
+		x, y := pos(i, a)
+		return fmt.Sprintf("%.1f,%.1f", x, y)
+	}
+
+	zone := 1 - svgZoneDistance
+	chart := svgChart{
+		Module:      r.metrics.Path,
+		Size:        svgChartSize + 2*svgChartMargin,
+		PlotSize:    svgChartSize,
+		Left:        svgChartMargin,
+		Top:         svgChartMargin,
+		Right:       svgChartMargin + svgChartSize,
+		Bottom:      svgChartMargin + svgChartSize,
+		Middle:      svgChartMargin + svgChartSize/2,
+		PainZone:    point(0, 0) + " " + point(zone, 0) + " " + point(0, zone),
+		UselessZone: point(1, 1) + " " + point(1-zone, 1) + " " + point(1, 1-zone),
+	}
+
+	for _, id := range r.packageIDsByName() {
+		pkg := r.metrics.Packages[id]
+		p := svgPoint{
+			Name:         pkg.Name,
+			Instability:  pkg.Instability,
+			Abstractness: pkg.Abstractness,
+			Distance:     pkg.Distance,
+			High:         r.highDistance(pkg),
+		}
+		p.X, p.Y = pos(pkg.Instability, pkg.Abstractness)
+		chart.Points = append(chart.Points, p)
+	}
+
+	tmpl, err := r.template(SVGTemplate)
+	if err != nil {
+		return err
+	}
+	return tmpl.Execute(w, chart)
+}
@@ -0,0 +1,163 @@
+// Package reporter handles output generation for aid-metrics analysis results.
+// This file implements the orderings of packages in the text and CSV reports.
+package reporter
+
+import (
+	"sort"
+
+	"github.com/alkbt/aid-metrics/pkg/models"
+)
+
+// Package orderings of the text and CSV reports
+const (
+	SortName = "name" // Alphabetical, the default
+	SortTopo = "topo" // By dependency layer, dependencies before their dependents
+
+	// Worst first: descending by the metric, ties by name
+	SortDistance    = "distance"
+	SortInstability = "instability"
+	SortCa          = "ca"
+	SortCe          = "ce"
+)
+
+// SortOrders lists the package orderings accepted by ReportOptions.Sort
+var SortOrders = []string{SortName, SortTopo, SortDistance, SortInstability, SortCa, SortCe}
+
+// sortKeys maps the worst-first orderings to the metric they sort by
+var sortKeys = map[string]func(models.PackageMetrics) float64{
+	SortDistance:    func(pkg models.PackageMetrics) float64 { return pkg.Distance },
+	SortInstability: func(pkg models.PackageMetrics) float64 { return pkg.Instability },
+	SortCa:          func(pkg models.PackageMetrics) float64 { return float64(pkg.Ca) },
+	SortCe:          func(pkg models.PackageMetrics) float64 { return float64(pkg.Ce) },
+}
+
+// PackageOrder returns the keys of metrics.Packages in the order of the text
+// and CSV reports for the given ordering (one of SortOrders) and top limit (0
+// for all packages), with the dependency layers for SortTopo. Custom formats
+// use it to list packages the way the built-in ones do.
+func PackageOrder(metrics models.ModuleMetrics, order string, top int) (ids []string, layers map[string]int) {
+	r := &Reporter{metrics: &metrics, options: ReportOptions{Sort: order, Top: top}}
+	if order == SortTopo {
+		layers = r.dependencyLayers()
+	}
+	return r.packageIDsInOrder(layers), layers
+}
+
+// layers returns the dependency layers when the columns include them or the
+// packages are sorted by them, and the layers to order the packages by
+func (r *Reporter) layers(columns []packageColumn) (layers, order map[string]int) {
+	if r.options.Sort != SortTopo && !hasLayer(columns) {
+		return nil, nil
+	}
+	layers = r.dependencyLayers()
+	if r.options.Sort == SortTopo {
+		order = layers
+	}
+	return layers, order
+}
+
+// packageIDsInOrder returns the package keys in the order of options.Sort,
+// ordered by layer first if layers are given (see dependencyLayers), and cut
+// to the first options.Top of them if set
+func (r *Reporter) packageIDsInOrder(layers map[string]int) []string {
+	ids := make([]string, 0, len(r.metrics.Packages))
+	for id := range r.metrics.Packages {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	if layers != nil {
+		sort.SliceStable(ids, func(i, j int) bool { return layers[ids[i]] < layers[ids[j]] })
+	}
+	if key, ok := sortKeys[r.options.Sort]; ok {
+		sort.SliceStable(ids, func(i, j int) bool {
+			return key(r.metrics.Packages[ids[i]]) > key(r.metrics.Packages[ids[j]])
+		})
+	}
+	if r.options.Top > 0 && r.options.Top < len(ids) {
+		ids = ids[:r.options.Top]
+	}
+	return ids
+}
+
+// dependencyLayers assigns each package its dependency layer: 0 for packages with
+// no dependencies among the reported packages, otherwise one more than the highest
+// layer of its dependencies. The packages of an import cycle share a layer.
+func (r *Reporter) dependencyLayers() map[string]int {
+	byName := make(map[string]string, len(r.metrics.Packages))
+	for id, pkg := range r.metrics.Packages {
+		byName[pkg.Name] = id
+	}
+	deps := make(map[string][]string, len(r.metrics.Packages))
+	for id, pkg := range r.metrics.Packages {
+		for _, name := range pkg.Dependencies {
+			if dep, ok := byName[name]; ok {
+				deps[id] = append(deps[id], dep)
+			}
+		}
+	}
+
+	// Tarjan's algorithm emits the strongly connected components (cycles or single
+	// packages) dependencies first, so each component's layer follows from the
+	// layers already assigned to its dependencies
+	layers := make(map[string]int, len(r.metrics.Packages))
+	index := make(map[string]int)
+	lowlink := make(map[string]int)
+	onStack := make(map[string]bool)
+	var stack []string
+	var visit func(id string)
+	visit = func(id string) {
+		index[id] = len(index)
+		lowlink[id] = index[id]
+		stack = append(stack, id)
+		onStack[id] = true
+		for _, dep := range deps[id] {
+			if _, seen := index[dep]; !seen {
+				visit(dep)
+				lowlink[id] = min(lowlink[id], lowlink[dep])
+			} else if onStack[dep] {
+				lowlink[id] = min(lowlink[id], index[dep])
+			}
+		}
+		if lowlink[id] != index[id] {
+			return
+		}
+
+		var component []string
+		for {
+			top := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[top] = false
+			component = append(component, top)
+			if top == id {
+				break
+			}
+		}
+		inComponent := make(map[string]bool, len(component))
+		for _, member := range component {
+			inComponent[member] = true
+		}
+		layer := 0
+		for _, member := range component {
+			for _, dep := range deps[member] {
+				if !inComponent[dep] {
+					layer = max(layer, layers[dep]+1)
+				}
+			}
+		}
+		for _, member := range component {
+			layers[member] = layer
+		}
+	}
+
+	ids := make([]string, 0, len(r.metrics.Packages))
+	for id := range r.metrics.Packages {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		if _, seen := index[id]; !seen {
+			visit(id)
+		}
+	}
+	return layers
+}
@@ -0,0 +1,188 @@
+// Package scorecard ranks the repositories of an organization by the health of
+// their latest stored run (see the publish subcommand), with the cycles, mean
+// distance and trend of each, as the periodic review artifact of a multi-repo scan.
+package scorecard
+
+import (
+	"embed"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"sort"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/alkbt/aid-metrics/pkg/models"
+	"github.com/alkbt/aid-metrics/pkg/publish"
+	"github.com/alkbt/aid-metrics/pkg/reporter"
+)
+
+// Directions of the health trend of a repository
+const (
+	Improving = "improving"
+	Steady    = "steady"
+	Worsening = "worsening"
+	New       = "new" // A single run, nothing to compare with
+)
+
+// trendThreshold is the change of the mean health, in points, below which a
+// repository counts as steady
+const trendThreshold = 1.0
+
+// Repository is the scorecard entry of a repository
+type Repository struct {
+	Rank   int    `json:"rank"`
+	Name   string `json:"name"`
+	Module string `json:"module"`
+
+	Packages     int     `json:"packages"`
+	Health       float64 `json:"health"` // Mean health score of the packages, 100 is healthiest
+	MeanDistance float64 `json:"mean_distance"`
+	Cycles       int     `json:"cycles"`
+	DebtPoints   int     `json:"debt_points"`
+
+	// History is the mean health of the runs, oldest first; the trend is the
+	// change from the first to the last of them
+	History     []float64 `json:"history"`
+	HealthDelta float64   `json:"health_delta"`
+	Trend       string    `json:"trend"`
+}
+
+// Scorecard is the ranking of the repositories, healthiest first
+type Scorecard struct {
+	Generated    time.Time    `json:"generated"`
+	Repositories []Repository `json:"repositories"`
+}
+
+// Build computes the scorecard of repositories, given their names and their
+// runs, oldest first. Repositories without runs are left out. Ties in health
+// are ranked by mean distance, then by name.
+func Build(names []string, runs [][]*reporter.JSONReport) *Scorecard {
+	card := &Scorecard{Generated: time.Now().UTC(), Repositories: []Repository{}}
+	for i, name := range names {
+		if len(runs[i]) == 0 {
+			continue
+		}
+		latest := runs[i][len(runs[i])-1]
+		repo := Repository{
+			Name:         name,
+			Module:       latest.Module,
+			Packages:     len(latest.Packages),
+			MeanDistance: publish.Summarize(latest).MeanDistance,
+			DebtPoints:   latest.DebtPoints,
+		}
+		for _, finding := range latest.Findings {
+			if finding.Category == models.CategoryCycle {
+				repo.Cycles++
+			}
+		}
+		for _, run := range runs[i] {
+			repo.History = append(repo.History, meanHealth(run))
+		}
+		repo.Health = repo.History[len(repo.History)-1]
+		repo.HealthDelta = repo.Health - repo.History[0]
+		switch {
+		case len(repo.History) == 1:
+			repo.Trend = New
+		case repo.HealthDelta >= trendThreshold:
+			repo.Trend = Improving
+		case repo.HealthDelta <= -trendThreshold:
+			repo.Trend = Worsening
+		default:
+			repo.Trend = Steady
+		}
+		card.Repositories = append(card.Repositories, repo)
+	}
+
+	sort.SliceStable(card.Repositories, func(i, j int) bool {
+		a, b := card.Repositories[i], card.Repositories[j]
+		if a.Health != b.Health {
+			return a.Health > b.Health
+		}
+		if a.MeanDistance != b.MeanDistance {
+			return a.MeanDistance < b.MeanDistance
+		}
+		return a.Name < b.Name
+	})
+	for i := range card.Repositories {
+		card.Repositories[i].Rank = i + 1
+	}
+	return card
+}
+
+// meanHealth returns the mean health score of the packages of a run
+func meanHealth(run *reporter.JSONReport) float64 {
+	if len(run.Packages) == 0 {
+		return 0
+	}
+	total := 0
+	for _, pkg := range run.Packages {
+		total += pkg.Health
+	}
+	return float64(total) / float64(len(run.Packages))
+}
+
+// WriteJSON writes the scorecard as indented JSON
+func (c *Scorecard) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(c)
+}
+
+// WriteText writes the scorecard as a table
+func (c *Scorecard) WriteText(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "RANK\tREPOSITORY\tHealth\tAvgD\tCycles\tDebt\tTrend")
+	fmt.Fprintln(tw, "----\t----------\t------\t----\t------\t----\t-----")
+	for _, repo := range c.Repositories {
+		fmt.Fprintf(tw, "%d\t%s\t%.1f\t%.2f\t%d\t%d\t%s\n", repo.Rank, repo.Name,
+			repo.Health, repo.MeanDistance, repo.Cycles, repo.DebtPoints, trendLabel(repo))
+	}
+	fmt.Fprintf(tw, "\n%d repositories\n", len(c.Repositories))
+	return tw.Flush()
+}
+
+// WriteCSV writes a row per repository, for spreadsheets
+func (c *Scorecard) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	_ = cw.Write([]string{"Rank", "Repository", "Module", "Packages", "Health", "AvgD", "Cycles", "Debt", "HealthDelta", "Trend"})
+	for _, repo := range c.Repositories {
+		_ = cw.Write([]string{
+			strconv.Itoa(repo.Rank), repo.Name, repo.Module, strconv.Itoa(repo.Packages),
+			strconv.FormatFloat(repo.Health, 'f', 1, 64),
+			strconv.FormatFloat(repo.MeanDistance, 'f', 2, 64),
+			strconv.Itoa(repo.Cycles), strconv.Itoa(repo.DebtPoints),
+			strconv.FormatFloat(repo.HealthDelta, 'f', 1, 64), repo.Trend,
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+//go:embed scorecard.html.tmpl
+var htmlTemplate embed.FS
+
+// WriteHTML writes the scorecard as a self-contained HTML page
+func (c *Scorecard) WriteHTML(w io.Writer, title string) error {
+	tmpl, err := template.New("scorecard.html.tmpl").Funcs(template.FuncMap{
+		"trend": trendLabel,
+	}).ParseFS(htmlTemplate, "scorecard.html.tmpl")
+	if err != nil {
+		return err
+	}
+	return tmpl.Execute(w, struct {
+		Title string
+		*Scorecard
+	}{title, c})
+}
+
+// trendLabel returns the trend of a repository with its change of health
+func trendLabel(repo Repository) string {
+	if repo.Trend == New {
+		return New
+	}
+	return fmt.Sprintf("%s (%+.1f)", repo.Trend, repo.HealthDelta)
+}
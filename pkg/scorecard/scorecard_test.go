@@ -0,0 +1,57 @@
+package scorecard
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/alkbt/aid-metrics/pkg/reporter"
+)
+
+func TestBuild(t *testing.T) {
+	run := func(module string, health ...int) *reporter.JSONReport {
+		report := &reporter.JSONReport{Module: module}
+		for _, h := range health {
+			report.Packages = append(report.Packages, reporter.JSONPackage{Name: "p", Health: h, Distance: 1 - float64(h)/100})
+		}
+		return report
+	}
+	shop := []*reporter.JSONReport{run("example.com/shop", 60, 80), run("example.com/shop", 80, 90)}
+	shop[1].Findings = []reporter.JSONFinding{{ID: "AM001", Category: "cycle"}, {ID: "AM003", Category: "sap"}}
+	billing := []*reporter.JSONReport{run("example.com/billing", 90, 90), run("example.com/billing", 80, 90)}
+	search := []*reporter.JSONReport{run("example.com/search", 85)}
+
+	card := Build([]string{"shop", "billing", "search", "empty"}, [][]*reporter.JSONReport{shop, billing, search, nil})
+
+	var got []string
+	for _, repo := range card.Repositories {
+		got = append(got, repo.Name+" "+repo.Trend)
+	}
+	want := []string{"billing worsening", "shop improving", "search new"}
+	if strings.Join(got, ", ") != strings.Join(want, ", ") {
+		t.Errorf("expected ranking %v, got %v", want, got)
+	}
+	if shop := card.Repositories[1]; shop.Rank != 2 || shop.Cycles != 1 || shop.HealthDelta != 15 {
+		t.Errorf("unexpected entry %+v", shop)
+	}
+
+	var text, csv, html bytes.Buffer
+	if err := card.WriteText(&text); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(text.String(), "2     shop        85.0    0.15  1       0     improving (+15.0)") {
+		t.Errorf("unexpected text output:\n%s", text.String())
+	}
+	if err := card.WriteCSV(&csv); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(csv.String(), "2,shop,example.com/shop,2,85.0,0.15,1,0,15.0,improving\n") {
+		t.Errorf("unexpected CSV output:\n%s", csv.String())
+	}
+	if err := card.WriteHTML(&html, "Scorecard"); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(html.String(), `<td class="worsening">worsening (-5.0)</td>`) {
+		t.Errorf("unexpected HTML output:\n%s", html.String())
+	}
+}
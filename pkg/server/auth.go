@@ -0,0 +1,165 @@
+package server
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Authenticator validates the bearer tokens of API requests
+type Authenticator interface {
+	// Authenticate reports whether token grants access. An error means the
+	// token could not be checked, not that it is invalid.
+	Authenticate(ctx context.Context, token string) (bool, error)
+}
+
+// StaticToken accepts a single pre-shared token
+type StaticToken string
+
+// Authenticate compares the token in constant time
+func (t StaticToken) Authenticate(ctx context.Context, token string) (bool, error) {
+	want := sha256.Sum256([]byte(t))
+	got := sha256.Sum256([]byte(token))
+	return t != "" && subtle.ConstantTimeCompare(want[:], got[:]) == 1, nil
+}
+
+// DefaultIntrospectionTTL is how long introspection results are cached
+const DefaultIntrospectionTTL = time.Minute
+
+// Introspector validates tokens with an OAuth 2.0 token introspection endpoint
+// (RFC 7662), as provided by most OIDC identity providers. Results are cached
+// for TTL so the provider is not queried on every request.
+type Introspector struct {
+	// URL is the introspection endpoint
+	URL string
+
+	// ClientID and ClientSecret authenticate the server at the endpoint
+	ClientID     string
+	ClientSecret string
+
+	// TTL overrides DefaultIntrospectionTTL if set
+	TTL time.Duration
+
+	// Client is the HTTP client used for requests. If nil, a client with a short timeout is used.
+	Client *http.Client
+
+	mu    sync.Mutex
+	cache map[[sha256.Size]byte]introspectionResult
+}
+
+// introspectionResult is a cached introspection response
+type introspectionResult struct {
+	active  bool
+	expires time.Time
+}
+
+// Authenticate asks the introspection endpoint whether the token is active
+func (i *Introspector) Authenticate(ctx context.Context, token string) (bool, error) {
+	if token == "" {
+		return false, nil
+	}
+	key := sha256.Sum256([]byte(token))
+
+	i.mu.Lock()
+	if cached, ok := i.cache[key]; ok && time.Now().Before(cached.expires) {
+		i.mu.Unlock()
+		return cached.active, nil
+	}
+	i.mu.Unlock()
+
+	form := url.Values{"token": {token}, "token_type_hint": {"access_token"}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, i.URL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if i.ClientID != "" {
+		req.SetBasicAuth(url.QueryEscape(i.ClientID), url.QueryEscape(i.ClientSecret))
+	}
+
+	resp, err := i.client().Do(req)
+	if err != nil {
+		return false, fmt.Errorf("token introspection failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("token introspection failed: unexpected status %s", resp.Status)
+	}
+
+	var body struct {
+		Active bool  `json:"active"`
+		Exp    int64 `json:"exp"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return false, fmt.Errorf("token introspection failed: %w", err)
+	}
+
+	// Never cache a token beyond its own expiry
+	expires := time.Now().Add(i.ttl())
+	if body.Exp > 0 && time.Unix(body.Exp, 0).Before(expires) {
+		expires = time.Unix(body.Exp, 0)
+	}
+
+	i.mu.Lock()
+	if i.cache == nil {
+		i.cache = make(map[[sha256.Size]byte]introspectionResult)
+	}
+	now := time.Now()
+	for k, cached := range i.cache {
+		if now.After(cached.expires) {
+			delete(i.cache, k)
+		}
+	}
+	i.cache[key] = introspectionResult{active: body.Active, expires: expires}
+	i.mu.Unlock()
+
+	return body.Active, nil
+}
+
+// ttl returns the effective cache TTL
+func (i *Introspector) ttl() time.Duration {
+	if i.TTL > 0 {
+		return i.TTL
+	}
+	return DefaultIntrospectionTTL
+}
+
+// client returns the HTTP client to use
+func (i *Introspector) client() *http.Client {
+	if i.Client != nil {
+		return i.Client
+	}
+	return &http.Client{Timeout: 10 * time.Second}
+}
+
+// RequireToken wraps a handler so that only requests with a valid bearer token
+// in the Authorization header reach it. Browsers cannot set headers on WebSocket
+// connections, so upgrade requests may pass the token as access_token query parameter.
+func RequireToken(auth Authenticator, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok && headerContains(r.Header, "Upgrade", "websocket") {
+			token = r.URL.Query().Get("access_token")
+		}
+
+		valid, err := auth.Authenticate(r.Context(), strings.TrimSpace(token))
+		if err != nil {
+			writeError(w, http.StatusServiceUnavailable, "cannot verify token")
+			return
+		}
+		if !valid {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="aid-metrics"`)
+			writeError(w, http.StatusUnauthorized, "missing or invalid token")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
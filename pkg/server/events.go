@@ -0,0 +1,197 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+
+	"github.com/alkbt/aid-metrics/pkg/models"
+	"github.com/alkbt/aid-metrics/pkg/reporter"
+)
+
+// Event types pushed over GET /events
+const (
+	// EventStatus reports a change of the analysis status
+	EventStatus = "status"
+
+	// EventProgress reports analysis progress on the 0-100 scale
+	EventProgress = "progress"
+
+	// EventPackage carries the metrics of one package once an analysis completed.
+	// Package events are sent in package order.
+	EventPackage = "package"
+
+	// EventDone follows the last package event of a completed analysis
+	EventDone = "done"
+)
+
+// eventBuffer is the number of events buffered per subscriber. Subscribers that
+// fall further behind are disconnected rather than slowing down the analysis.
+const eventBuffer = 1024
+
+// Event is a message pushed to /events subscribers as a WebSocket text frame
+type Event struct {
+	Type        string                `json:"type"`
+	Status      string                `json:"status,omitempty"`
+	Error       string                `json:"error,omitempty"`
+	Stage       models.Stage          `json:"stage,omitempty"`
+	Progress    int                   `json:"progress,omitempty"`
+	Total       int                   `json:"total,omitempty"`
+	Description string                `json:"description,omitempty"`
+	Package     *reporter.JSONPackage `json:"package,omitempty"`
+	Packages    int                   `json:"packages,omitempty"`
+
+	// metrics are the results announced by a done event. Each connection expands
+	// them into package events itself, so a large module cannot overflow the buffers.
+	metrics *models.ModuleMetrics
+}
+
+// subscribe registers a new event subscriber
+func (s *Server) subscribe() chan Event {
+	ch := make(chan Event, eventBuffer)
+	s.subMu.Lock()
+	s.subscribers[ch] = struct{}{}
+	s.subMu.Unlock()
+	return ch
+}
+
+// unsubscribe removes a subscriber if it is still registered
+func (s *Server) unsubscribe(ch chan Event) {
+	s.subMu.Lock()
+	defer s.subMu.Unlock()
+	if _, ok := s.subscribers[ch]; ok {
+		delete(s.subscribers, ch)
+		close(ch)
+	}
+}
+
+// publish sends an event to all subscribers, dropping those whose buffer is full
+func (s *Server) publish(e Event) {
+	s.subMu.Lock()
+	defer s.subMu.Unlock()
+	for ch := range s.subscribers {
+		select {
+		case ch <- e:
+		default:
+			delete(s.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// publishResults announces the results of a completed analysis
+func (s *Server) publishResults(metrics *models.ModuleMetrics) {
+	s.publish(Event{Type: EventDone, Status: StatusReady, Packages: len(metrics.Packages), metrics: metrics})
+}
+
+// eventProgress forwards analysis progress to the event subscribers. Events
+// carry the stage and the steps done of its total.
+type eventProgress struct {
+	server *Server
+
+	mu    sync.Mutex
+	stage models.Stage
+	total int
+}
+
+func (p *eventProgress) StageStart(stage models.Stage, total int) {
+	p.mu.Lock()
+	p.stage, p.total = stage, total
+	p.mu.Unlock()
+	p.server.publish(Event{Type: EventProgress, Stage: stage, Total: total})
+}
+
+func (p *eventProgress) Update(current int, description string) {
+	p.mu.Lock()
+	stage, total := p.stage, p.total
+	p.mu.Unlock()
+	p.server.publish(Event{Type: EventProgress, Stage: stage, Progress: current, Total: total, Description: description})
+}
+
+func (p *eventProgress) StageDone(stage models.Stage) {
+	p.mu.Lock()
+	total := p.total
+	p.mu.Unlock()
+	p.server.publish(Event{Type: EventProgress, Stage: stage, Progress: total, Total: total})
+}
+
+func (p *eventProgress) Complete() {}
+
+// handleEvents upgrades the connection to a WebSocket and pushes events until the
+// client disconnects. The current status is sent first.
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	conn, err := upgradeWebSocket(w, r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	defer conn.close()
+
+	events := s.subscribe()
+	defer s.unsubscribe(events)
+
+	s.mu.RLock()
+	initial := Event{Type: EventStatus, Status: s.status}
+	if s.err != nil {
+		initial.Error = s.err.Error()
+	}
+	s.mu.RUnlock()
+	if err := s.writeEvent(conn, initial); err != nil {
+		return
+	}
+
+	pongs := make(chan []byte, 1)
+	closed := make(chan struct{})
+	go func() {
+		conn.readLoop(pongs)
+		close(closed)
+	}()
+
+	for {
+		select {
+		case e, ok := <-events:
+			if !ok {
+				return // Too slow; the client may reconnect
+			}
+			if err := s.writeEvents(conn, e); err != nil {
+				return
+			}
+		case payload := <-pongs:
+			if err := conn.writeFrame(opPong, payload); err != nil {
+				return
+			}
+		case <-closed:
+			return
+		}
+	}
+}
+
+// writeEvents sends an event, preceded by a package event for every package
+// in the results if it announces a completed analysis
+func (s *Server) writeEvents(conn *websocketConn, e Event) error {
+	if e.metrics != nil {
+		ids := make([]string, 0, len(e.metrics.Packages))
+		for id := range e.metrics.Packages {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+
+		for _, id := range ids {
+			pkg := reporter.NewJSONPackage(e.metrics.Packages[id])
+			if err := s.writeEvent(conn, Event{Type: EventPackage, Package: &pkg}); err != nil {
+				return err
+			}
+		}
+	}
+	return s.writeEvent(conn, e)
+}
+
+// writeEvent sends an event as a JSON text frame
+func (s *Server) writeEvent(conn *websocketConn, e Event) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	return conn.writeText(data)
+}
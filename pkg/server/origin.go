@@ -0,0 +1,33 @@
+package server
+
+import (
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// RequireSameOrigin wraps a handler so that browsers can only reach it from pages
+// of the server itself or of the allowed origins (e.g. "https://dash.example.com").
+// Otherwise any page a user visits could open the event WebSocket with the user's
+// network access, or trigger analyses. Requests without an Origin header, which
+// browsers always send on WebSocket upgrades and cross-origin POSTs, pass.
+func RequireSameOrigin(allowed []string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if origin := r.Header.Get("Origin"); origin != "" && !originAllowed(origin, r.Host, allowed) {
+			writeError(w, http.StatusForbidden, "origin "+origin+" is not allowed")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// originAllowed reports whether origin is the host of the request or one of the allowed origins
+func originAllowed(origin, host string, allowed []string) bool {
+	origin = strings.TrimSuffix(origin, "/")
+	if slices.ContainsFunc(allowed, func(a string) bool { return strings.EqualFold(strings.TrimSuffix(a, "/"), origin) }) {
+		return true
+	}
+	u, err := url.Parse(origin)
+	return err == nil && u.Host != "" && strings.EqualFold(u.Host, host)
+}
@@ -0,0 +1,154 @@
+package server
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/alkbt/aid-metrics/pkg/models"
+)
+
+// Pagination defaults and limits of GET /packages
+const (
+	DefaultPerPage = 100
+	MaxPerPage     = 1000
+)
+
+// packageMetrics maps the metric names accepted by the sort and min_/max_ filter
+// parameters to their values
+var packageMetrics = map[string]func(models.PackageMetrics) float64{
+	"ca":           func(p models.PackageMetrics) float64 { return float64(p.Ca) },
+	"ce":           func(p models.PackageMetrics) float64 { return float64(p.Ce) },
+	"na":           func(p models.PackageMetrics) float64 { return float64(p.Na) },
+	"nc":           func(p models.PackageMetrics) float64 { return float64(p.Nc) },
+	"instability":  func(p models.PackageMetrics) float64 { return p.Instability },
+	"abstractness": func(p models.PackageMetrics) float64 { return p.Abstractness },
+	"distance":     func(p models.PackageMetrics) float64 { return p.Distance },
+	"methods":      func(p models.PackageMetrics) float64 { return float64(p.Methods) },
+	"constructors": func(p models.PackageMetrics) float64 { return float64(p.Constructors) },
+}
+
+// metricRange is a min_/max_ filter on a metric
+type metricRange struct {
+	metric   string
+	min, max float64
+	hasMin   bool
+	hasMax   bool
+}
+
+// packageQuery holds the parsed query parameters of GET /packages:
+//
+//	page, per_page          1-based page and page size (default 100, at most 1000)
+//	sort                    "name" or a metric name, prefixed with "-" for descending order
+//	q                       substring of the package name
+//	role                    package role
+//	min_<metric>, max_<metric>  inclusive bounds, e.g. min_distance=0.5
+type packageQuery struct {
+	page       int
+	perPage    int
+	sortBy     string
+	descending bool
+	search     string
+	role       string
+	ranges     []metricRange
+}
+
+// parsePackageQuery parses and validates the query parameters of GET /packages
+func parsePackageQuery(values url.Values) (packageQuery, error) {
+	q := packageQuery{page: 1, perPage: DefaultPerPage, sortBy: "name"}
+	ranges := make(map[string]*metricRange)
+
+	for key, vals := range values {
+		value := vals[len(vals)-1]
+		switch {
+		case key == "page" || key == "per_page":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return q, fmt.Errorf("%s must be a positive integer", key)
+			}
+			if key == "page" {
+				q.page = n
+			} else {
+				q.perPage = min(n, MaxPerPage)
+			}
+		case key == "sort":
+			field := strings.TrimPrefix(value, "-")
+			if _, ok := packageMetrics[field]; !ok && field != "name" {
+				return q, fmt.Errorf("cannot sort by %q", field)
+			}
+			q.sortBy = field
+			q.descending = strings.HasPrefix(value, "-")
+		case key == "q":
+			q.search = value
+		case key == "role":
+			q.role = value
+		case strings.HasPrefix(key, "min_") || strings.HasPrefix(key, "max_"):
+			metric := key[len("min_"):]
+			if _, ok := packageMetrics[metric]; !ok {
+				return q, fmt.Errorf("unknown metric %q in %s", metric, key)
+			}
+			bound, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return q, fmt.Errorf("%s must be a number", key)
+			}
+			r := ranges[metric]
+			if r == nil {
+				r = &metricRange{metric: metric}
+				ranges[metric] = r
+			}
+			if strings.HasPrefix(key, "min_") {
+				r.min, r.hasMin = bound, true
+			} else {
+				r.max, r.hasMax = bound, true
+			}
+		default:
+			return q, fmt.Errorf("unknown query parameter %q", key)
+		}
+	}
+
+	for _, r := range ranges {
+		q.ranges = append(q.ranges, *r)
+	}
+	return q, nil
+}
+
+// apply returns the packages matching the filters, in the requested order.
+// Ties are broken by package name so pages are stable.
+func (q packageQuery) apply(metrics *models.ModuleMetrics) []models.PackageMetrics {
+	var matched []models.PackageMetrics
+	for _, pkg := range metrics.Packages {
+		if q.matches(pkg) {
+			matched = append(matched, pkg)
+		}
+	}
+
+	value := packageMetrics[q.sortBy]
+	sort.Slice(matched, func(i, j int) bool {
+		a, b := matched[i], matched[j]
+		if value == nil || value(a) == value(b) {
+			// The name order is only reversed when sorting by name
+			return (a.Name < b.Name) != (value == nil && q.descending)
+		}
+		return (value(a) < value(b)) != q.descending
+	})
+	return matched
+}
+
+// matches reports whether a package passes all filters
+func (q packageQuery) matches(pkg models.PackageMetrics) bool {
+	if q.search != "" && !strings.Contains(pkg.Name, q.search) {
+		return false
+	}
+	if q.role != "" && pkg.Role != q.role {
+		return false
+	}
+	for _, r := range q.ranges {
+		v := packageMetrics[r.metric](pkg)
+		if (r.hasMin && v < r.min) || (r.hasMax && v > r.max) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,142 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Project is a module registered with a Registry
+type Project struct {
+	// Name identifies the project in API paths
+	Name string
+
+	// Source describes where the module comes from (a path or git URL), for display only
+	Source string
+
+	// Interval re-runs the analysis periodically. Zero analyzes only on startup
+	// and on POST /projects/<name>/analyze.
+	Interval time.Duration
+
+	server *Server
+}
+
+// Registry serves several projects, turning serve mode into a metrics service
+// for a whole team:
+//
+//	GET /projects          all projects with their analysis status
+//	    /projects/<name>/  the API of a single project (see Server)
+type Registry struct {
+	mu       sync.RWMutex
+	projects map[string]*Project
+}
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{projects: make(map[string]*Project)}
+}
+
+// Add registers a project analyzed by analyze. Project names must be unique.
+func (r *Registry) Add(project Project, analyze AnalyzeFunc) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, exists := r.projects[project.Name]; exists {
+		return fmt.Errorf("project %q is already registered", project.Name)
+	}
+	project.server = New(analyze)
+	r.projects[project.Name] = &project
+	return nil
+}
+
+// Run analyzes every project once and then on its schedule until ctx is done.
+// Projects are analyzed independently of each other.
+func (r *Registry) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, project := range r.list() {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			project.schedule(ctx)
+		}()
+	}
+	wg.Wait()
+}
+
+// schedule runs the analyses of a project until ctx is done
+func (p *Project) schedule(ctx context.Context) {
+	p.server.Analyze()
+	if p.Interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(p.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			p.server.Analyze()
+		}
+	}
+}
+
+// list returns the registered projects sorted by name
+func (r *Registry) list() []*Project {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	projects := make([]*Project, 0, len(r.projects))
+	for _, project := range r.projects {
+		projects = append(projects, project)
+	}
+	sort.Slice(projects, func(i, j int) bool {
+		return projects[i].Name < projects[j].Name
+	})
+	return projects
+}
+
+// ProjectStatus is an element of the response of GET /projects
+type ProjectStatus struct {
+	Name     string `json:"name"`
+	Source   string `json:"source,omitempty"`
+	Interval string `json:"interval,omitempty"`
+	ModuleStatus
+}
+
+// Handler returns the HTTP handler serving all projects
+func (r *Registry) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /projects", r.handleProjects)
+	mux.HandleFunc("/projects/{name}/", func(w http.ResponseWriter, req *http.Request) {
+		name := req.PathValue("name")
+		r.mu.RLock()
+		project, ok := r.projects[name]
+		r.mu.RUnlock()
+		if !ok {
+			writeError(w, http.StatusNotFound, fmt.Sprintf("unknown project %q", name))
+			return
+		}
+		http.StripPrefix("/projects/"+name, project.server.Handler()).ServeHTTP(w, req)
+	})
+	return mux
+}
+
+func (r *Registry) handleProjects(w http.ResponseWriter, req *http.Request) {
+	projects := r.list()
+	resp := make([]ProjectStatus, 0, len(projects))
+	for _, project := range projects {
+		entry := ProjectStatus{
+			Name:         project.Name,
+			Source:       project.Source,
+			ModuleStatus: project.server.moduleStatus(),
+		}
+		if project.Interval > 0 {
+			entry.Interval = project.Interval.String()
+		}
+		resp = append(resp, entry)
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
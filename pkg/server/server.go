@@ -0,0 +1,283 @@
+// Package server implements serve mode: an HTTP API exposing the metrics of a
+// module to dashboards and other tools.
+//
+// The API is read-mostly JSON:
+//
+//	GET  /module    analysis status and module-wide aggregates
+//	GET  /packages  package metrics with pagination, sorting and filters
+//	GET  /findings  all findings
+//	GET  /history   module-wide aggregates of past analyses, oldest first
+//	POST /analyze   starts a new analysis in the background (Content-Type: application/json)
+//	GET  /events    WebSocket streaming progress events and results (see Event)
+//
+// A Registry serves several projects, each with its own Server under
+// /projects/<name>/ (see Registry.Handler).
+package server
+
+import (
+	"encoding/json"
+	"mime"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/alkbt/aid-metrics/pkg/models"
+	"github.com/alkbt/aid-metrics/pkg/reporter"
+)
+
+// Analysis status values reported by GET /module
+const (
+	StatusPending   = "pending"
+	StatusAnalyzing = "analyzing"
+	StatusReady     = "ready"
+	StatusFailed    = "failed"
+)
+
+// MaxHistory is the number of past analyses kept for GET /history
+const MaxHistory = 100
+
+// AnalyzeFunc runs an analysis of the served module, reporting progress to progress
+type AnalyzeFunc func(progress models.ProgressReporter) (*models.ModuleMetrics, error)
+
+// Server serves the metrics of the most recent successful analysis.
+// A failed or running analysis keeps serving the previous results.
+type Server struct {
+	analyze AnalyzeFunc
+
+	mu         sync.RWMutex
+	metrics    *models.ModuleMetrics
+	status     string
+	err        error
+	analyzedAt time.Time
+	history    []HistoryEntry
+
+	subMu       sync.Mutex
+	subscribers map[chan Event]struct{}
+}
+
+// New creates a server using analyze to (re)compute the metrics
+func New(analyze AnalyzeFunc) *Server {
+	return &Server{
+		analyze:     analyze,
+		status:      StatusPending,
+		subscribers: make(map[chan Event]struct{}),
+	}
+}
+
+// Analyze runs an analysis and stores its results.
+// It returns false without doing anything if an analysis is already running.
+func (s *Server) Analyze() bool {
+	s.mu.Lock()
+	if s.status == StatusAnalyzing {
+		s.mu.Unlock()
+		return false
+	}
+	s.status = StatusAnalyzing
+	s.mu.Unlock()
+	s.publish(Event{Type: EventStatus, Status: StatusAnalyzing})
+
+	metrics, err := s.analyze(&eventProgress{server: s})
+
+	s.mu.Lock()
+	if err != nil {
+		s.status = StatusFailed
+		s.err = err
+		s.mu.Unlock()
+		s.publish(Event{Type: EventStatus, Status: StatusFailed, Error: err.Error()})
+		return true
+	}
+	s.metrics = metrics
+	s.status = StatusReady
+	s.err = nil
+	s.analyzedAt = time.Now()
+	s.history = append(s.history, HistoryEntry{AnalyzedAt: s.analyzedAt, Summary: summarize(metrics)})
+	if len(s.history) > MaxHistory {
+		s.history = s.history[len(s.history)-MaxHistory:]
+	}
+	s.mu.Unlock()
+
+	s.publishResults(metrics)
+	return true
+}
+
+// Handler returns the HTTP handler of the API
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /module", s.handleModule)
+	mux.HandleFunc("GET /packages", s.handlePackages)
+	mux.HandleFunc("GET /findings", s.handleFindings)
+	mux.HandleFunc("GET /history", s.handleHistory)
+	mux.HandleFunc("POST /analyze", s.handleAnalyze)
+	mux.HandleFunc("GET /events", s.handleEvents)
+	return mux
+}
+
+// snapshot returns the current metrics, or writes 503 and returns nil if there are none yet
+func (s *Server) snapshot(w http.ResponseWriter) *models.ModuleMetrics {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.metrics == nil {
+		writeError(w, http.StatusServiceUnavailable, "no analysis results yet (status: "+s.status+")")
+		return nil
+	}
+	return s.metrics
+}
+
+// Summary holds the module-wide aggregates of an analysis
+type Summary struct {
+	Packages         int     `json:"packages"`
+	Findings         int     `json:"findings"`
+	Cycles           int     `json:"cycles"`
+	MeanInstability  float64 `json:"mean_instability"`
+	MeanAbstractness float64 `json:"mean_abstractness"`
+	MeanDistance     float64 `json:"mean_distance"`
+}
+
+// summarize computes the module-wide aggregates of metrics
+func summarize(metrics *models.ModuleMetrics) Summary {
+	summary := Summary{
+		Packages: len(metrics.Packages),
+		Findings: len(metrics.Findings),
+		Cycles:   len(metrics.Cycles),
+	}
+	for _, pkg := range metrics.Packages {
+		summary.MeanInstability += pkg.Instability
+		summary.MeanAbstractness += pkg.Abstractness
+		summary.MeanDistance += pkg.Distance
+	}
+	if n := float64(len(metrics.Packages)); n > 0 {
+		summary.MeanInstability /= n
+		summary.MeanAbstractness /= n
+		summary.MeanDistance /= n
+	}
+	return summary
+}
+
+// ModuleStatus is the response of GET /module
+type ModuleStatus struct {
+	Module     string     `json:"module,omitempty"`
+	Status     string     `json:"status"`
+	Error      string     `json:"error,omitempty"`
+	AnalyzedAt *time.Time `json:"analyzed_at,omitempty"`
+	Summary
+}
+
+// HistoryEntry is an element of the response of GET /history
+type HistoryEntry struct {
+	AnalyzedAt time.Time `json:"analyzed_at"`
+	Summary
+}
+
+// moduleStatus returns the current status and, if there are results, their summary
+func (s *Server) moduleStatus() ModuleStatus {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	resp := ModuleStatus{Status: s.status}
+	if s.err != nil {
+		resp.Error = s.err.Error()
+	}
+	if metrics := s.metrics; metrics != nil {
+		analyzedAt := s.analyzedAt
+		resp.AnalyzedAt = &analyzedAt
+		resp.Module = metrics.Path
+		resp.Summary = summarize(metrics)
+	}
+	return resp
+}
+
+func (s *Server) handleModule(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.moduleStatus())
+}
+
+func (s *Server) handleHistory(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	history := append([]HistoryEntry{}, s.history...)
+	s.mu.RUnlock()
+
+	writeJSON(w, http.StatusOK, history)
+}
+
+// PackagePage is the response of GET /packages
+type PackagePage struct {
+	Total    int                    `json:"total"`
+	Page     int                    `json:"page"`
+	PerPage  int                    `json:"per_page"`
+	Packages []reporter.JSONPackage `json:"packages"`
+}
+
+func (s *Server) handlePackages(w http.ResponseWriter, r *http.Request) {
+	query, err := parsePackageQuery(r.URL.Query())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	metrics := s.snapshot(w)
+	if metrics == nil {
+		return
+	}
+
+	matched := query.apply(metrics)
+	resp := PackagePage{
+		Total:    len(matched),
+		Page:     query.page,
+		PerPage:  query.perPage,
+		Packages: make([]reporter.JSONPackage, 0, query.perPage),
+	}
+	// Pages past the end are empty. Compare before multiplying, as huge pages overflow.
+	start := len(matched)
+	if query.page-1 < len(matched)/query.perPage+1 {
+		start = min((query.page-1)*query.perPage, len(matched))
+	}
+	end := min(start+query.perPage, len(matched))
+	for _, pkg := range matched[start:end] {
+		resp.Packages = append(resp.Packages, reporter.NewJSONPackage(pkg))
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (s *Server) handleFindings(w http.ResponseWriter, r *http.Request) {
+	metrics := s.snapshot(w)
+	if metrics == nil {
+		return
+	}
+
+	findings := make([]reporter.JSONFinding, 0, len(metrics.Findings))
+	for _, finding := range metrics.Findings {
+		findings = append(findings, reporter.NewJSONFinding(finding))
+	}
+	writeJSON(w, http.StatusOK, findings)
+}
+
+func (s *Server) handleAnalyze(w http.ResponseWriter, r *http.Request) {
+	// Browsers send cross-origin POSTs with form or text content types without
+	// asking first (CORS preflight), so these cannot trigger analyses
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType != "application/json" {
+		writeError(w, http.StatusUnsupportedMediaType, "POST /analyze requires Content-Type: application/json")
+		return
+	}
+
+	s.mu.RLock()
+	running := s.status == StatusAnalyzing
+	s.mu.RUnlock()
+	if running {
+		writeError(w, http.StatusConflict, "an analysis is already running")
+		return
+	}
+
+	go s.Analyze()
+	writeJSON(w, http.StatusAccepted, map[string]string{"status": StatusAnalyzing})
+}
+
+// writeJSON writes v as the JSON response body
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError writes a JSON error response
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
@@ -0,0 +1,327 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alkbt/aid-metrics/pkg/models"
+)
+
+func newTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	srv := New(func(progress models.ProgressReporter) (*models.ModuleMetrics, error) {
+		progress.Update(50, "Analyzing store")
+		return &models.ModuleMetrics{
+			Path: "example.com/shop",
+			Packages: map[string]models.PackageMetrics{
+				"a": {Name: "api", Ce: 2, Instability: 1, Distance: 0, Role: models.RoleHandler},
+				"b": {Name: "billing", Ca: 1, Ce: 1, Instability: 0.5, Distance: 0.5, Role: models.RoleDomain},
+				"c": {Name: "catalog", Ca: 1, Ce: 1, Instability: 0.5, Distance: 0.4, Role: models.RoleDomain},
+				"s": {Name: "store", Ca: 2, Distance: 1, Role: models.RoleRepository},
+			},
+		}, nil
+	})
+	srv.Analyze()
+	ts := httptest.NewServer(srv.Handler())
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func getPackages(t *testing.T, ts *httptest.Server, query string) (int, PackagePage) {
+	t.Helper()
+	resp, err := http.Get(ts.URL + "/packages?" + query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	var body PackagePage
+	if resp.StatusCode == http.StatusOK {
+		if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return resp.StatusCode, body
+}
+
+func TestPackagesQuery(t *testing.T) {
+	ts := newTestServer(t)
+
+	tests := []struct {
+		query string
+		total int
+		names []string
+	}{
+		{"", 4, []string{"api", "billing", "catalog", "store"}},
+		{"sort=-name&per_page=2", 4, []string{"store", "catalog"}},
+		{"sort=-distance&per_page=2&page=2", 4, []string{"catalog", "api"}},
+		{"sort=instability", 4, []string{"store", "billing", "catalog", "api"}},
+		{"role=domain&min_distance=0.45", 1, []string{"billing"}},
+		{"q=i&max_ca=1&sort=name", 2, []string{"api", "billing"}},
+		{"page=9", 4, []string{}},
+		{"page=9223372036854775807", 4, []string{}},
+		{"page=4611686018427387904&per_page=2", 4, []string{}},
+	}
+	for _, tt := range tests {
+		status, body := getPackages(t, ts, tt.query)
+		if status != http.StatusOK {
+			t.Errorf("%q: unexpected status %d", tt.query, status)
+			continue
+		}
+		var names []string
+		for _, pkg := range body.Packages {
+			names = append(names, pkg.Name)
+		}
+		if body.Total != tt.total || len(names) != len(tt.names) {
+			t.Errorf("%q: got total %d and %v, want %d and %v", tt.query, body.Total, names, tt.total, tt.names)
+			continue
+		}
+		for i := range names {
+			if names[i] != tt.names[i] {
+				t.Errorf("%q: got %v, want %v", tt.query, names, tt.names)
+				break
+			}
+		}
+	}
+
+	for _, query := range []string{"sort=color", "min_color=1", "per_page=0", "page=x", "limit=5"} {
+		if status, _ := getPackages(t, ts, query); status != http.StatusBadRequest {
+			t.Errorf("%q: expected status 400, got %d", query, status)
+		}
+	}
+}
+
+// readEvent reads a single unfragmented server frame and decodes its event
+func readEvent(t *testing.T, r *bufio.Reader) Event {
+	t.Helper()
+	var head [2]byte
+	if _, err := io.ReadFull(r, head[:]); err != nil {
+		t.Fatalf("failed to read frame: %v", err)
+	}
+	length := int(head[1] & 0x7F)
+	switch length {
+	case 126:
+		var ext [2]byte
+		io.ReadFull(r, ext[:])
+		length = int(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		io.ReadFull(r, ext[:])
+		length = int(binary.BigEndian.Uint64(ext[:]))
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		t.Fatalf("failed to read payload: %v", err)
+	}
+
+	var e Event
+	if err := json.Unmarshal(payload, &e); err != nil {
+		t.Fatalf("invalid event %q: %v", payload, err)
+	}
+	return e
+}
+
+func TestEventsWebSocket(t *testing.T) {
+	ts := newTestServer(t)
+
+	conn, err := net.Dial("tcp", strings.TrimPrefix(ts.URL, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	io.WriteString(conn, "GET /events HTTP/1.1\r\nHost: test\r\nConnection: Upgrade\r\nUpgrade: websocket\r\n"+
+		"Sec-WebSocket-Version: 13\r\nSec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\n\r\n")
+	r := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(r, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols || resp.Header.Get("Sec-WebSocket-Accept") != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Fatalf("unexpected handshake response %s %v", resp.Status, resp.Header)
+	}
+
+	if e := readEvent(t, r); e.Type != EventStatus || e.Status != StatusReady {
+		t.Fatalf("expected the initial ready status, got %+v", e)
+	}
+
+	if resp, err := http.Post(ts.URL+"/analyze", "application/json", nil); err != nil {
+		t.Fatal(err)
+	} else {
+		resp.Body.Close()
+	}
+
+	var types []string
+	for {
+		e := readEvent(t, r)
+		types = append(types, e.Type)
+		if e.Type == EventDone {
+			break
+		}
+	}
+	want := "status progress package package package package done"
+	if got := strings.Join(types, " "); got != want {
+		t.Errorf("got events %q, want %q", got, want)
+	}
+}
+
+func TestRequireToken(t *testing.T) {
+	var introspections int
+	provider := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		introspections++
+		if id, secret, _ := r.BasicAuth(); id != "aid" || secret != "s3cret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]bool{"active": r.FormValue("token") == "good"})
+	}))
+	defer provider.Close()
+
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	tests := []struct {
+		name   string
+		auth   Authenticator
+		header string
+		status int
+	}{
+		{"static valid", StaticToken("good"), "Bearer good", http.StatusOK},
+		{"static invalid", StaticToken("good"), "Bearer bad", http.StatusUnauthorized},
+		{"static missing", StaticToken("good"), "", http.StatusUnauthorized},
+		{"introspection active", &Introspector{URL: provider.URL, ClientID: "aid", ClientSecret: "s3cret"}, "Bearer good", http.StatusOK},
+		{"introspection inactive", &Introspector{URL: provider.URL, ClientID: "aid", ClientSecret: "s3cret"}, "Bearer bad", http.StatusUnauthorized},
+		{"introspection rejected", &Introspector{URL: provider.URL, ClientID: "aid"}, "Bearer good", http.StatusServiceUnavailable},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/module", nil)
+		if tt.header != "" {
+			req.Header.Set("Authorization", tt.header)
+		}
+		rec := httptest.NewRecorder()
+		RequireToken(tt.auth, ok).ServeHTTP(rec, req)
+		if rec.Code != tt.status {
+			t.Errorf("%s: got status %d, want %d", tt.name, rec.Code, tt.status)
+		}
+	}
+
+	// Introspection results are cached
+	auth := &Introspector{URL: provider.URL, ClientID: "aid", ClientSecret: "s3cret"}
+	introspections = 0
+	for range 3 {
+		if active, err := auth.Authenticate(context.Background(), "good"); err != nil || !active {
+			t.Fatalf("Authenticate: %v, %v", active, err)
+		}
+	}
+	if introspections != 1 {
+		t.Errorf("expected 1 introspection request, got %d", introspections)
+	}
+}
+
+func TestRequireSameOrigin(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	handler := RequireSameOrigin([]string{"https://dash.example.com"}, ok)
+	tests := []struct {
+		origin string
+		status int
+	}{
+		{"", http.StatusOK},
+		{"http://metrics.internal:8090", http.StatusOK},
+		{"https://dash.example.com", http.StatusOK},
+		{"https://evil.example.com", http.StatusForbidden},
+		{"http://metrics.internal", http.StatusForbidden},
+		{"null", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "http://metrics.internal:8090/events", nil)
+		if tt.origin != "" {
+			req.Header.Set("Origin", tt.origin)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != tt.status {
+			t.Errorf("origin %q: got status %d, want %d", tt.origin, rec.Code, tt.status)
+		}
+	}
+}
+
+func TestAnalyzeRequiresJSON(t *testing.T) {
+	ts := newTestServer(t)
+	for _, contentType := range []string{"", "text/plain", "application/x-www-form-urlencoded"} {
+		resp, err := http.Post(ts.URL+"/analyze", contentType, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusUnsupportedMediaType {
+			t.Errorf("content type %q: expected 415, got %d", contentType, resp.StatusCode)
+		}
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	registry := NewRegistry()
+	for _, name := range []string{"shop", "billing"} {
+		err := registry.Add(Project{Name: name, Source: "/src/" + name}, func(progress models.ProgressReporter) (*models.ModuleMetrics, error) {
+			return &models.ModuleMetrics{
+				Path:     "example.com/" + name,
+				Packages: map[string]models.PackageMetrics{"a": {Name: name + "/api", Instability: 1}},
+			}, nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := registry.Add(Project{Name: "shop"}, nil); err == nil {
+		t.Error("expected an error registering a duplicate project")
+	}
+	registry.Run(context.Background())
+
+	ts := httptest.NewServer(registry.Handler())
+	defer ts.Close()
+
+	get := func(path string, v any) int {
+		t.Helper()
+		resp, err := http.Get(ts.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		if v != nil && resp.StatusCode == http.StatusOK {
+			if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return resp.StatusCode
+	}
+
+	var projects []ProjectStatus
+	get("/projects", &projects)
+	if len(projects) != 2 || projects[0].Name != "billing" || projects[1].Module != "example.com/shop" || projects[1].Status != StatusReady {
+		t.Errorf("unexpected projects %+v", projects)
+	}
+
+	var packages PackagePage
+	get("/projects/shop/packages", &packages)
+	if len(packages.Packages) != 1 || packages.Packages[0].Name != "shop/api" {
+		t.Errorf("unexpected packages %+v", packages)
+	}
+
+	var history []HistoryEntry
+	get("/projects/billing/history", &history)
+	if len(history) != 1 || history[0].Packages != 1 || history[0].MeanInstability != 1 {
+		t.Errorf("unexpected history %+v", history)
+	}
+
+	if status := get("/projects/unknown/module", nil); status != http.StatusNotFound {
+		t.Errorf("expected 404 for an unknown project, got %d", status)
+	}
+}
@@ -0,0 +1,177 @@
+package server
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// websocketGUID is the fixed GUID used to compute Sec-WebSocket-Accept (RFC 6455, section 1.3)
+const websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// WebSocket opcodes used by the server
+const (
+	opText  = 0x1
+	opClose = 0x8
+	opPing  = 0x9
+	opPong  = 0xA
+)
+
+// maxControlPayload is the largest payload a control frame may carry
+const maxControlPayload = 125
+
+// websocketConn is a minimal server side WebSocket connection (RFC 6455).
+// It only sends unfragmented text frames; frames from the client are read to
+// answer pings and to notice when the client goes away.
+type websocketConn struct {
+	conn net.Conn
+	rw   *bufio.ReadWriter
+}
+
+// upgradeWebSocket performs the opening handshake and takes over the connection
+func upgradeWebSocket(w http.ResponseWriter, r *http.Request) (*websocketConn, error) {
+	if !headerContains(r.Header, "Connection", "upgrade") || !headerContains(r.Header, "Upgrade", "websocket") {
+		return nil, errors.New("not a websocket handshake")
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		return nil, errors.New("unsupported websocket version")
+	}
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if key == "" {
+		return nil, errors.New("missing Sec-WebSocket-Key")
+	}
+
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		return nil, errors.New("connection does not support hijacking")
+	}
+	conn, rw, err := hijacker.Hijack()
+	if err != nil {
+		return nil, err
+	}
+
+	sum := sha1.Sum([]byte(key + websocketGUID))
+	rw.WriteString("HTTP/1.1 101 Switching Protocols\r\n")
+	rw.WriteString("Upgrade: websocket\r\n")
+	rw.WriteString("Connection: Upgrade\r\n")
+	rw.WriteString("Sec-WebSocket-Accept: " + base64.StdEncoding.EncodeToString(sum[:]) + "\r\n\r\n")
+	if err := rw.Flush(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return &websocketConn{conn: conn, rw: rw}, nil
+}
+
+// writeText sends a text message
+func (c *websocketConn) writeText(payload []byte) error {
+	return c.writeFrame(opText, payload)
+}
+
+// writeFrame sends a single unmasked frame
+func (c *websocketConn) writeFrame(opcode byte, payload []byte) error {
+	header := []byte{0x80 | opcode, 0}
+	switch n := len(payload); {
+	case n <= maxControlPayload:
+		header[1] = byte(n)
+	case n <= 0xFFFF:
+		header[1] = 126
+		header = binary.BigEndian.AppendUint16(header, uint16(n))
+	default:
+		header[1] = 127
+		header = binary.BigEndian.AppendUint64(header, uint64(n))
+	}
+
+	if _, err := c.rw.Write(header); err != nil {
+		return err
+	}
+	if _, err := c.rw.Write(payload); err != nil {
+		return err
+	}
+	return c.rw.Flush()
+}
+
+// readLoop reads frames from the client until it closes the connection or an
+// error occurs, answering pings with pongs through the pong channel.
+// Data frames are discarded: the event stream is one-way.
+func (c *websocketConn) readLoop(pongs chan<- []byte) error {
+	for {
+		var head [2]byte
+		if _, err := io.ReadFull(c.rw, head[:]); err != nil {
+			return err
+		}
+		opcode := head[0] & 0x0F
+		masked := head[1]&0x80 != 0
+		length := uint64(head[1] & 0x7F)
+
+		switch length {
+		case 126:
+			var ext [2]byte
+			if _, err := io.ReadFull(c.rw, ext[:]); err != nil {
+				return err
+			}
+			length = uint64(binary.BigEndian.Uint16(ext[:]))
+		case 127:
+			var ext [8]byte
+			if _, err := io.ReadFull(c.rw, ext[:]); err != nil {
+				return err
+			}
+			length = binary.BigEndian.Uint64(ext[:])
+		}
+
+		var mask [4]byte
+		if masked {
+			if _, err := io.ReadFull(c.rw, mask[:]); err != nil {
+				return err
+			}
+		}
+
+		switch opcode {
+		case opClose:
+			return io.EOF
+		case opPing:
+			if length > maxControlPayload {
+				return errors.New("oversized control frame")
+			}
+			payload := make([]byte, length)
+			if _, err := io.ReadFull(c.rw, payload); err != nil {
+				return err
+			}
+			for i := range payload {
+				payload[i] ^= mask[i%4]
+			}
+			select {
+			case pongs <- payload:
+			default: // A pong is already pending
+			}
+		default:
+			if _, err := io.CopyN(io.Discard, c.rw, int64(length)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// close sends a close frame and closes the connection
+func (c *websocketConn) close() error {
+	c.writeFrame(opClose, nil)
+	return c.conn.Close()
+}
+
+// headerContains reports whether a comma-separated header contains token, ignoring case
+func headerContains(h http.Header, name, token string) bool {
+	for _, value := range h.Values(name) {
+		for _, part := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(part), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,142 @@
+// Package snapshot writes the metrics of a module into a directory of its own
+// repository, as canonical JSON with a short Markdown summary, for teams that
+// track the state of their architecture in version control and review changes
+// to it like code.
+package snapshot
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+
+	"github.com/alkbt/aid-metrics/pkg/models"
+	"github.com/alkbt/aid-metrics/pkg/publish"
+	"github.com/alkbt/aid-metrics/pkg/reporter"
+)
+
+// Files of a snapshot directory
+const (
+	JSONFile    = "metrics.json" // Canonical JSON report with findings
+	SummaryFile = "SUMMARY.md"   // Markdown summary
+)
+
+// maxListed is the number of packages and findings listed in the summary
+const maxListed = 10
+
+// Write writes the snapshot of the metrics into dir, creating it if needed, and
+// returns the paths of the files written. Ratios are rounded to precision
+// decimals (reporter.DefaultPrecision if 0).
+//
+// Snapshots hold no timestamps, machine-specific paths or comparisons with
+// earlier snapshots, which the history of the repository holds: the metrics of
+// an unchanged module give identical files, so only changes show up in diffs.
+// metrics.Path should hence be the module path rather than a directory.
+func Write(dir string, metrics *models.ModuleMetrics, precision int) ([]string, error) {
+	if precision <= 0 {
+		precision = reporter.DefaultPrecision
+	}
+	var report bytes.Buffer
+	options := reporter.ReportOptions{Findings: true, Canonical: true, Precision: precision}
+	if err := reporter.NewReporterWithOptions(metrics, reporter.FormatJSON, options).Generate(&report); err != nil {
+		return nil, err
+	}
+	// The summary is written from the rounded values of the JSON file, so the
+	// two never disagree
+	current, err := reporter.ReadJSONReport(bytes.NewReader(report.Bytes()))
+	if err != nil {
+		return nil, err
+	}
+	var summary bytes.Buffer
+	if err := WriteMarkdown(&summary, current, precision); err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	jsonPath, summaryPath := filepath.Join(dir, JSONFile), filepath.Join(dir, SummaryFile)
+	for path, content := range map[string][]byte{jsonPath: report.Bytes(), summaryPath: summary.Bytes()} {
+		if err := os.WriteFile(path, content, 0o644); err != nil {
+			return nil, err
+		}
+	}
+	return []string{jsonPath, summaryPath}, nil
+}
+
+// WriteMarkdown writes the summary of a snapshot: the module aggregates, the
+// findings per severity and the packages furthest from the main sequence
+func WriteMarkdown(w io.Writer, report *reporter.JSONReport, precision int) error {
+	ratio := func(f float64) string { return strconv.FormatFloat(f, 'f', precision, 64) }
+	summary := publish.Summarize(report)
+
+	fmt.Fprintf(w, "# Architecture metrics of %s\n\n", report.Module)
+	fmt.Fprintln(w, "Generated by `aid-metrics snapshot`; do not edit.")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "| Packages | Mean I | Mean A | Mean D | Cycles | Findings | Debt points |")
+	fmt.Fprintln(w, "| ---: | ---: | ---: | ---: | ---: | ---: | ---: |")
+	fmt.Fprintf(w, "| %d | %s | %s | %s | %d | %d | %d |\n", summary.Packages, ratio(summary.MeanInstability),
+		ratio(summary.MeanAbstractness), ratio(summary.MeanDistance), cycles(report), summary.Findings, report.DebtPoints)
+
+	if len(report.Findings) > 0 {
+		counts := make(map[string]int)
+		for _, f := range report.Findings {
+			counts[f.Severity]++
+		}
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "## Findings")
+		fmt.Fprintln(w)
+		for _, severity := range []models.Severity{models.SeverityError, models.SeverityWarning, models.SeverityInfo} {
+			if n := counts[string(severity)]; n > 0 {
+				fmt.Fprintf(w, "- %s: %d\n", severity, n)
+			}
+		}
+	}
+
+	worst := worstPackages(report)
+	if len(worst) > 0 {
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "## Furthest from the main sequence")
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "| Package | I | A | D | Health |")
+		fmt.Fprintln(w, "| --- | ---: | ---: | ---: | ---: |")
+		for _, pkg := range worst {
+			fmt.Fprintf(w, "| %s | %s | %s | %s | %d |\n", pkg.Name, ratio(pkg.Instability), ratio(pkg.Abstractness), ratio(pkg.Distance), pkg.Health)
+		}
+	}
+	return nil
+}
+
+// cycles returns the number of import cycles of a report
+func cycles(report *reporter.JSONReport) int {
+	n := 0
+	for _, f := range report.Findings {
+		if f.Category == models.CategoryCycle {
+			n++
+		}
+	}
+	return n
+}
+
+// worstPackages returns the packages off the main sequence, furthest first
+func worstPackages(report *reporter.JSONReport) []reporter.JSONPackage {
+	var worst []reporter.JSONPackage
+	for _, pkg := range report.Packages {
+		if pkg.Distance > 0 {
+			worst = append(worst, pkg)
+		}
+	}
+	sort.SliceStable(worst, func(i, j int) bool {
+		if worst[i].Distance != worst[j].Distance {
+			return worst[i].Distance > worst[j].Distance
+		}
+		return worst[i].Name < worst[j].Name
+	})
+	if len(worst) > maxListed {
+		worst = worst[:maxListed]
+	}
+	return worst
+}
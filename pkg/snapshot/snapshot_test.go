@@ -0,0 +1,63 @@
+package snapshot
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/alkbt/aid-metrics/pkg/models"
+	"github.com/alkbt/aid-metrics/pkg/reporter"
+)
+
+func TestWrite(t *testing.T) {
+	metrics := &models.ModuleMetrics{
+		Path: "example.com/shop",
+		Packages: map[string]models.PackageMetrics{
+			"example.com/shop/api":   {Name: "api", Ce: 1, Instability: 1, Health: 100},
+			"example.com/shop/store": {Name: "store", Ca: 1, Nc: 3, Distance: 1.0 / 3, Health: 80},
+		},
+		Findings: []models.Finding{
+			{ID: "AM003", Severity: models.SeverityWarning, Category: models.CategorySAP, Package: "store", Message: "zone of pain", Points: 3},
+		},
+	}
+	dir := filepath.Join(t.TempDir(), "metrics")
+
+	files, err := Write(dir, metrics, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 || filepath.Base(files[0]) != JSONFile || filepath.Base(files[1]) != SummaryFile {
+		t.Fatalf("unexpected files %v", files)
+	}
+	read := func(name string) string {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+	report, summary := read(JSONFile), read(SummaryFile)
+
+	for _, want := range []string{`"distance": 0.33,`, `"module": "example.com/shop",`, `"id": "AM003"`} {
+		if !strings.Contains(report, want) {
+			t.Errorf("expected the JSON snapshot to contain %s, got\n%s", want, report)
+		}
+	}
+	for _, want := range []string{"# Architecture metrics of example.com/shop", "| 2 | 0.50 | 0.00 | 0.17 | 0 | 1 | 3 |", "- warning: 1", "| store | 0.00 | 0.00 | 0.33 | 80 |"} {
+		if !strings.Contains(summary, want) {
+			t.Errorf("expected the summary to contain %q, got\n%s", want, summary)
+		}
+	}
+	if _, err := reporter.ReadJSONReport(strings.NewReader(report)); err != nil {
+		t.Errorf("expected the JSON snapshot to be readable: %v", err)
+	}
+
+	// Snapshots of the same metrics are identical
+	if _, err := Write(dir, metrics, 0); err != nil {
+		t.Fatal(err)
+	}
+	if read(JSONFile) != report || read(SummaryFile) != summary {
+		t.Error("expected a second snapshot of the same metrics to be identical")
+	}
+}
@@ -0,0 +1,184 @@
+// Package trend follows the metrics of every package across a series of stored
+// runs (see the publish subcommand), flagging packages drifting away from the
+// main sequence run after run, which a comparison of two runs cannot tell from noise.
+package trend
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/alkbt/aid-metrics/pkg/diff"
+	"github.com/alkbt/aid-metrics/pkg/reporter"
+)
+
+// MinRuns is the number of runs a package must appear in for its trend to be judged
+const MinRuns = 3
+
+// Metrics whose trend is judged
+const (
+	Instability = "instability"
+	Distance    = "distance"
+)
+
+// PackageTrend is the series of the metrics of a package, one entry per run
+// of the trend in the same order; nil where the package is missing from a run
+type PackageTrend struct {
+	Name        string     `json:"package"`
+	Instability []*float64 `json:"instability"`
+	Distance    []*float64 `json:"distance"`
+
+	// Change from the first to the last run the package appears in
+	DeltaInstability float64 `json:"delta_instability"`
+	DeltaDistance    float64 `json:"delta_distance"`
+
+	// Worsening lists the metrics (Instability, Distance) that rose from every
+	// run to the next or stayed flat, rising by more than diff.Epsilon overall
+	Worsening []string `json:"worsening,omitempty"`
+}
+
+// Trend is the evolution of the packages of a module across runs
+type Trend struct {
+	Module   string         `json:"module"`
+	Runs     []string       `json:"runs"`
+	Packages []PackageTrend `json:"packages"`
+}
+
+// Build computes the trend of the runs, oldest first, labeled by names.
+// Packages are matched by name.
+func Build(names []string, runs []*reporter.JSONReport) *Trend {
+	trend := &Trend{Runs: names, Packages: []PackageTrend{}}
+	if len(runs) > 0 {
+		trend.Module = runs[len(runs)-1].Module
+	}
+
+	packages := make(map[string]*PackageTrend)
+	for i, run := range runs {
+		for _, pkg := range run.Packages {
+			t, ok := packages[pkg.Name]
+			if !ok {
+				t = &PackageTrend{
+					Name:        pkg.Name,
+					Instability: make([]*float64, len(runs)),
+					Distance:    make([]*float64, len(runs)),
+				}
+				packages[pkg.Name] = t
+			}
+			instability, distance := pkg.Instability, pkg.Distance
+			t.Instability[i] = &instability
+			t.Distance[i] = &distance
+		}
+	}
+
+	for _, t := range packages {
+		var worsening bool
+		t.DeltaInstability, worsening = judge(t.Instability)
+		if worsening {
+			t.Worsening = append(t.Worsening, Instability)
+		}
+		t.DeltaDistance, worsening = judge(t.Distance)
+		if worsening {
+			t.Worsening = append(t.Worsening, Distance)
+		}
+		trend.Packages = append(trend.Packages, *t)
+	}
+	sort.Slice(trend.Packages, func(i, j int) bool { return trend.Packages[i].Name < trend.Packages[j].Name })
+	return trend
+}
+
+// judge returns the change of a series from its first to its last value, and
+// whether it never decreased and rose overall, over at least MinRuns values
+func judge(series []*float64) (float64, bool) {
+	var values []float64
+	for _, value := range series {
+		if value != nil {
+			values = append(values, *value)
+		}
+	}
+	if len(values) == 0 {
+		return 0, false
+	}
+
+	delta := values[len(values)-1] - values[0]
+	if len(values) < MinRuns || delta <= diff.Epsilon {
+		return delta, false
+	}
+	for i := 1; i < len(values); i++ {
+		if values[i] < values[i-1]-diff.Epsilon {
+			return delta, false
+		}
+	}
+	return delta, true
+}
+
+// Worsening returns the packages with a worsening metric
+func (t *Trend) Worsening() []PackageTrend {
+	var result []PackageTrend
+	for _, pkg := range t.Packages {
+		if len(pkg.Worsening) > 0 {
+			result = append(result, pkg)
+		}
+	}
+	return result
+}
+
+// sparkBars are the bars of a sparkline, from 0 to 1
+var sparkBars = []rune("▁▂▃▄▅▆▇█")
+
+// sparkline draws a series of values between 0 and 1 on an absolute scale, so
+// the lines of different packages compare; missing values are left blank
+func sparkline(series []*float64) string {
+	var b strings.Builder
+	for _, value := range series {
+		if value == nil {
+			b.WriteRune(' ')
+			continue
+		}
+		i := int(*value*float64(len(sparkBars)-1) + 0.5)
+		b.WriteRune(sparkBars[max(0, min(i, len(sparkBars)-1))])
+	}
+	return b.String()
+}
+
+// last returns the last value of a series, or 0 if it has none
+func last(series []*float64) float64 {
+	for i := len(series) - 1; i >= 0; i-- {
+		if series[i] != nil {
+			return *series[i]
+		}
+	}
+	return 0
+}
+
+// WriteJSON writes the trend as indented JSON
+func (t *Trend) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(t)
+}
+
+// WriteText writes the trend as a table with sparklines of I and D, their latest
+// values and changes over the runs, followed by the worsening packages
+func (t *Trend) WriteText(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintf(tw, "MODULE: %s\n", t.Module)
+	if len(t.Runs) > 0 {
+		fmt.Fprintf(tw, "RUNS: %d (%s .. %s)\n", len(t.Runs), t.Runs[0], t.Runs[len(t.Runs)-1])
+	}
+	fmt.Fprintln(tw, "\nPACKAGE\tI trend\tI\tD trend\tD\tWorsening")
+	fmt.Fprintln(tw, "-------\t-------\t-\t-------\t-\t---------")
+	for _, pkg := range t.Packages {
+		fmt.Fprintf(tw, "%s\t%s\t%.2f (%+.2f)\t%s\t%.2f (%+.2f)\t%s\n", pkg.Name,
+			sparkline(pkg.Instability), last(pkg.Instability), pkg.DeltaInstability,
+			sparkline(pkg.Distance), last(pkg.Distance), pkg.DeltaDistance,
+			strings.Join(pkg.Worsening, ", "))
+	}
+
+	fmt.Fprintf(tw, "\n%d of %d packages consistently worsening over %d runs\n",
+		len(t.Worsening()), len(t.Packages), len(t.Runs))
+	return tw.Flush()
+}
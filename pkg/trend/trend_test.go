@@ -0,0 +1,53 @@
+package trend
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/alkbt/aid-metrics/pkg/reporter"
+)
+
+func TestBuild(t *testing.T) {
+	run := func(store, util float64) *reporter.JSONReport {
+		return &reporter.JSONReport{
+			Module: "example.com/shop",
+			Packages: []reporter.JSONPackage{
+				{Name: "store", Instability: 0.5, Distance: store},
+				{Name: "util", Instability: util, Distance: 0.2},
+			},
+		}
+	}
+	runs := []*reporter.JSONReport{run(0.1, 0.5), run(0.3, 0.4), run(0.3, 0.6), run(0.6, 0.5)}
+	runs[1].Packages = append(runs[1].Packages, reporter.JSONPackage{Name: "api", Instability: 1})
+	names := []string{"20261001T000000Z", "20261002T000000Z", "20261003T000000Z", "20261004T000000Z"}
+
+	trend := Build(names, runs)
+
+	if len(trend.Packages) != 3 {
+		t.Fatalf("expected 3 packages, got %+v", trend.Packages)
+	}
+	api, store, util := trend.Packages[0], trend.Packages[1], trend.Packages[2]
+	if api.Instability[0] != nil || api.Instability[1] == nil || len(api.Worsening) != 0 {
+		t.Errorf("expected api to appear in the second run only, got %+v", api)
+	}
+	if strings.Join(store.Worsening, ",") != Distance || store.DeltaDistance < 0.49 {
+		t.Errorf("expected store's distance to be worsening, got %+v", store)
+	}
+	if len(util.Worsening) != 0 {
+		t.Errorf("expected util's fluctuating instability not to be worsening, got %v", util.Worsening)
+	}
+
+	var buf bytes.Buffer
+	if err := trend.WriteText(&buf); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{
+		"store    ▅▅▅▅     0.50 (+0.00)  ▂▃▃▅     0.60 (+0.50)  distance",
+		"1 of 3 packages consistently worsening over 4 runs",
+	} {
+		if !strings.Contains(buf.String(), line) {
+			t.Errorf("expected output to contain %q, got:\n%s", line, buf.String())
+		}
+	}
+}
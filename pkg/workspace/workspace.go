@@ -0,0 +1,368 @@
+// Package workspace analyzes the modules of a go.work workspace, or all modules
+// found in a directory tree, in one run.
+//
+// Every member module is analyzed on its own and the results are combined into
+// a single report, with package names prefixed by the directory of their module
+// (e.g. services/billing/pkg/invoice) and keyed by module path and package
+// (e.g. example.com/billing:pkg/invoice). In Merged mode, imports of packages of
+// other member modules become edges between the reported packages, so their
+// afferent coupling, instability and distance cover the whole workspace.
+// Findings and cycles are those of the individual modules.
+package workspace
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/alkbt/aid-metrics/pkg/analyzer"
+	"github.com/alkbt/aid-metrics/pkg/models"
+)
+
+// FileName is the name of the workspace file
+const FileName = "go.work"
+
+// Workspace modes
+const (
+	Merged  = "merged"  // Imports between member modules are edges of the report
+	Modules = "modules" // Member modules are reported side by side, importing each other as third-party code
+)
+
+// Module is a member module of a workspace
+type Module struct {
+	Dir    string // Absolute directory of the module
+	Path   string // Module path declared in its go.mod
+	Prefix string // Prefix of the module's package names: its directory relative to the workspace
+}
+
+// Workspace is a parsed go.work file
+type Workspace struct {
+	Dir     string   // Directory of the go.work file
+	Modules []Module // Member modules, sorted by prefix
+}
+
+// Exists reports whether dir holds a go.work file
+func Exists(dir string) bool {
+	_, err := os.Stat(filepath.Join(dir, FileName))
+	return err == nil
+}
+
+// Load reads the go.work file in dir and the go.mod files of its member modules
+func Load(dir string) (*Workspace, error) {
+	data, err := os.ReadFile(filepath.Join(dir, FileName))
+	if err != nil {
+		return nil, err
+	}
+
+	ws := &Workspace{Dir: dir}
+	for _, use := range useDirectives(string(data)) {
+		moduleDir := filepath.Join(dir, filepath.FromSlash(use))
+		modulePath, err := readModulePath(moduleDir)
+		if err != nil {
+			return nil, fmt.Errorf("%s: use %s: %w", FileName, use, err)
+		}
+		prefix := path.Clean(use)
+		if prefix == "." {
+			prefix = path.Base(modulePath)
+		}
+		ws.Modules = append(ws.Modules, Module{Dir: moduleDir, Path: modulePath, Prefix: prefix})
+	}
+	if len(ws.Modules) == 0 {
+		return nil, fmt.Errorf("%s in %s uses no modules", FileName, dir)
+	}
+	sort.Slice(ws.Modules, func(i, j int) bool {
+		return ws.Modules[i].Prefix < ws.Modules[j].Prefix
+	})
+	return ws, nil
+}
+
+// Discover walks the tree under dir for go.mod files and returns their modules
+// as a workspace, for monorepos of independent modules without a go.work file.
+// Vendor, testdata and hidden directories are skipped.
+func Discover(dir string) (*Workspace, error) {
+	ws := &Workspace{Dir: dir}
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		name := d.Name()
+		if p != dir && (name == "vendor" || name == "testdata" || name == "node_modules" || strings.HasPrefix(name, ".")) {
+			return fs.SkipDir
+		}
+		if _, err := os.Stat(filepath.Join(p, "go.mod")); err != nil {
+			return nil
+		}
+		modulePath, err := readModulePath(p)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		prefix := filepath.ToSlash(rel)
+		if prefix == "." {
+			prefix = path.Base(modulePath)
+		}
+		ws.Modules = append(ws.Modules, Module{Dir: p, Path: modulePath, Prefix: prefix})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(ws.Modules) == 0 {
+		return nil, fmt.Errorf("no go.mod file under %s", dir)
+	}
+	sort.Slice(ws.Modules, func(i, j int) bool {
+		return ws.Modules[i].Prefix < ws.Modules[j].Prefix
+	})
+	return ws, nil
+}
+
+// useDirectives returns the directories of the use directives of a go.work file
+func useDirectives(data string) []string {
+	var dirs []string
+	inBlock := false
+	for _, line := range strings.Split(data, "\n") {
+		if i := strings.Index(line, "//"); i >= 0 {
+			line = line[:i]
+		}
+		line = strings.TrimSpace(line)
+		switch {
+		case inBlock && line == ")":
+			inBlock = false
+			continue
+		case line == "use (":
+			inBlock = true
+			continue
+		case strings.HasPrefix(line, "use "):
+			line = strings.TrimSpace(strings.TrimPrefix(line, "use "))
+		case !inBlock:
+			continue
+		}
+		if line != "" {
+			dirs = append(dirs, strings.Trim(line, `"`))
+		}
+	}
+	return dirs
+}
+
+// readModulePath reads the module path from the go.mod file in dir
+func readModulePath(dir string) (string, error) {
+	data, err := os.ReadFile(filepath.Join(dir, "go.mod"))
+	if err != nil {
+		return "", err
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if fields := strings.Fields(line); len(fields) >= 2 && fields[0] == "module" {
+			return strings.Trim(fields[1], `"`), nil
+		}
+	}
+	return "", fmt.Errorf("no module declaration in %s", filepath.Join(dir, "go.mod"))
+}
+
+// Analyze analyzes the packages matching pattern in every member module and
+// combines the results in the given mode (Merged or Modules)
+func Analyze(ctx context.Context, ws *Workspace, pattern, mode string, options analyzer.AnalyzerOptions) (*models.ModuleMetrics, error) {
+	if mode != Merged && mode != Modules {
+		return nil, fmt.Errorf("unknown workspace mode %q (merged, modules)", mode)
+	}
+
+	// A member module without packages matching the pattern (e.g. a root module
+	// holding only nested modules) contributes nothing; only all of them being
+	// empty is an error
+	results := make([]*models.ModuleMetrics, len(ws.Modules))
+	empty := 0
+	for i, module := range ws.Modules {
+		metrics, err := analyzer.AnalyzeModuleContext(ctx, module.Dir, pattern, options)
+		if errors.Is(err, analyzer.ErrNoPackagesFound) {
+			empty++
+			metrics, err = &models.ModuleMetrics{Path: module.Path, Packages: map[string]models.PackageMetrics{}}, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("module %s: %w", module.Prefix, err)
+		}
+		results[i] = metrics
+	}
+	if empty > 0 && empty == len(ws.Modules) {
+		return nil, &analyzer.NoPackagesError{Pattern: pattern, Roots: moduleDirs(ws)}
+	}
+	combined := Combine(ws, results, mode == Merged)
+	if mode == Merged {
+		// Edges between modules changed I and D
+		analyzer.ScoreHealth(combined, options)
+		analyzer.ClassifyZones(combined, options)
+		combined.Summary = analyzer.Summarize(combined.Packages)
+	}
+	return combined, nil
+}
+
+// moduleDirs returns the directories of the member modules
+func moduleDirs(ws *Workspace) []string {
+	dirs := make([]string, len(ws.Modules))
+	for i, module := range ws.Modules {
+		dirs[i] = module.Dir
+	}
+	return dirs
+}
+
+// Combine combines the metrics of the member modules, given in the order of
+// ws.Modules. With merge set, imports between member modules become edges.
+func Combine(ws *Workspace, results []*models.ModuleMetrics, merge bool) *models.ModuleMetrics {
+	combined := &models.ModuleMetrics{
+		Path:     ws.Dir,
+		Packages: make(map[string]models.PackageMetrics),
+		Names:    make(map[string]string),
+	}
+
+	// Prefixed names of all workspace packages by import path, and of each
+	// module's packages by their name within the module
+	workspaceNames := make(map[string]string)
+	moduleNames := make([]map[string]string, len(results))
+	for i, metrics := range results {
+		combined.ExportedOnly = combined.ExportedOnly || metrics.ExportedOnly
+		moduleNames[i] = make(map[string]string, len(metrics.Packages))
+		for id, pkg := range metrics.Packages {
+			name := ws.Modules[i].prefixed(id)
+			workspaceNames[id] = name
+			moduleNames[i][pkg.Name] = name
+		}
+	}
+
+	keys := make(map[string]string)
+	for i, metrics := range results {
+		rename := func(name string) string {
+			if prefixed, ok := moduleNames[i][name]; ok {
+				return prefixed
+			}
+			if importPath, ok := metrics.Names[name]; ok {
+				if prefixed, ok := workspaceNames[importPath]; ok && merge {
+					return prefixed
+				}
+			}
+			return name
+		}
+		renameAll := func(names []string) []string {
+			if names == nil {
+				return nil
+			}
+			renamed := make([]string, 0, len(names))
+			for _, name := range names {
+				renamed = append(renamed, rename(name))
+			}
+			sort.Strings(renamed)
+			return renamed
+		}
+
+		for id, pkg := range metrics.Packages {
+			pkg.Name = workspaceNames[id]
+			pkg.Dependencies = renameAll(pkg.Dependencies)
+			pkg.Dependents = renameAll(pkg.Dependents)
+			pkg.ExposedDependencies = renameAll(pkg.ExposedDependencies)
+			for i := range pkg.ErrorTargets {
+				pkg.ErrorTargets[i].CheckedBy = renameAll(pkg.ErrorTargets[i].CheckedBy)
+			}
+			for i := range pkg.ConstantSets {
+				pkg.ConstantSets[i].ReferencedBy = renameAll(pkg.ConstantSets[i].ReferencedBy)
+			}
+			keys[pkg.Name] = ws.Modules[i].key(id)
+			combined.Packages[keys[pkg.Name]] = pkg
+			combined.Names[pkg.Name] = id
+		}
+		for name, importPath := range metrics.Names {
+			_, own := metrics.Packages[importPath]
+			_, inWorkspace := workspaceNames[importPath]
+			if !own && (!inWorkspace || !merge) {
+				combined.Names[name] = importPath
+			}
+		}
+		for _, endpoint := range metrics.Endpoints {
+			endpoint.RegisteredIn = rename(endpoint.RegisteredIn)
+			endpoint.Handler = rename(endpoint.Handler)
+			endpoint.Dependencies = renameAll(endpoint.Dependencies)
+			combined.Endpoints = append(combined.Endpoints, endpoint)
+		}
+		for _, effect := range metrics.SideEffects {
+			effect.BlankImportedBy = renameAll(effect.BlankImportedBy)
+			effect.Binaries = renameAll(effect.Binaries)
+			combined.SideEffects = append(combined.SideEffects, effect)
+		}
+		for _, coupling := range metrics.StringCouplings {
+			coupling.Packages = renameAll(coupling.Packages)
+			combined.StringCouplings = append(combined.StringCouplings, coupling)
+		}
+		for _, cycle := range metrics.Cycles {
+			combined.Cycles = append(combined.Cycles, renameAll(cycle))
+		}
+		for _, finding := range metrics.Findings {
+			finding.Package = rename(finding.Package)
+			combined.Findings = append(combined.Findings, finding)
+		}
+		for _, diagnostic := range metrics.Diagnostics {
+			diagnostic.Package = rename(diagnostic.Package)
+			combined.Diagnostics = append(combined.Diagnostics, diagnostic)
+		}
+	}
+
+	if merge {
+		addDependents(combined, keys)
+		analyzer.TransitiveCoupling(combined)
+	}
+	combined.Roles = analyzer.SummarizeRoles(combined.Packages)
+	combined.Summary = analyzer.Summarize(combined.Packages)
+	analyzer.SortFindings(combined.Findings)
+	return combined
+}
+
+// addDependents adds the packages importing a package of another member module
+// to its dependents and updates its coupling metrics. keys maps the names of
+// the packages to their keys.
+func addDependents(combined *models.ModuleMetrics, keys map[string]string) {
+	added := make(map[string][]string)
+	for _, pkg := range combined.Packages {
+		for _, dep := range pkg.Dependencies {
+			id, ok := keys[dep]
+			if !ok || slices.Contains(combined.Packages[id].Dependents, pkg.Name) {
+				continue
+			}
+			added[id] = append(added[id], pkg.Name)
+		}
+	}
+
+	for id, dependents := range added {
+		pkg := combined.Packages[id]
+		pkg.Dependents = append(pkg.Dependents, dependents...)
+		sort.Strings(pkg.Dependents)
+		pkg.Ca = len(pkg.Dependents)
+		pkg.Instability = float64(pkg.Ce) / float64(pkg.Ca+pkg.Ce)
+		pkg.Distance = math.Abs(pkg.Abstractness + pkg.Instability - 1)
+		combined.Packages[id] = pkg
+	}
+}
+
+// key returns the key of a package of the module in the combined metrics
+func (m Module) key(importPath string) string {
+	if importPath == m.Path {
+		return m.Path + ":."
+	}
+	return m.Path + ":" + strings.TrimPrefix(importPath, m.Path+"/")
+}
+
+// prefixed returns the name of a package of the module within the workspace
+func (m Module) prefixed(importPath string) string {
+	if importPath == m.Path {
+		return m.Prefix
+	}
+	return m.Prefix + "/" + strings.TrimPrefix(importPath, m.Path+"/")
+}
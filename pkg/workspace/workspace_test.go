@@ -0,0 +1,125 @@
+package workspace
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/alkbt/aid-metrics/pkg/models"
+)
+
+func TestLoad(t *testing.T) {
+	dir := writeModule(t, map[string]string{
+		"go.work":        "go 1.23\n\nuse ./tools // linters\n\nuse (\n\t./shop\n\t\"./billing\"\n)\n",
+		"billing/go.mod": "module example.com/billing\n",
+		"shop/go.mod":    "module example.com/shop\n",
+		"tools/go.mod":   "module example.com/tools\n",
+		"unused/go.mod":  "module example.com/unused\n",
+	})
+
+	ws, err := Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var modules []string
+	for _, m := range ws.Modules {
+		modules = append(modules, m.Prefix+"="+m.Path)
+	}
+	if got := strings.Join(modules, " "); got != "billing=example.com/billing shop=example.com/shop tools=example.com/tools" {
+		t.Errorf("unexpected modules: %s", got)
+	}
+}
+
+func TestDiscover(t *testing.T) {
+	dir := writeModule(t, map[string]string{
+		"go.mod":                        "module example.com/platform\n",
+		"services/billing/go.mod":       "module example.com/billing\n",
+		"services/billing/api/go.mod":   "module example.com/billing/api\n",
+		"tools/go.mod":                  "module example.com/tools\n",
+		"tools/testdata/fixture/go.mod": "module example.com/fixture\n",
+		".cache/mod/go.mod":             "module example.com/cached\n",
+	})
+
+	ws, err := Discover(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var modules []string
+	for _, m := range ws.Modules {
+		modules = append(modules, m.Prefix+"="+m.Path)
+	}
+	want := "platform=example.com/platform services/billing=example.com/billing services/billing/api=example.com/billing/api tools=example.com/tools"
+	if got := strings.Join(modules, " "); got != want {
+		t.Errorf("unexpected modules: %s", got)
+	}
+
+	if _, err := Discover(t.TempDir()); err == nil {
+		t.Error("expected an error for a tree without go.mod files")
+	}
+}
+
+func TestCombine(t *testing.T) {
+	ws := &Workspace{Dir: "/ws", Modules: []Module{
+		{Path: "example.com/billing", Prefix: "billing"},
+		{Path: "example.com/shop", Prefix: "services/shop"},
+	}}
+	billing := &models.ModuleMetrics{
+		Packages: map[string]models.PackageMetrics{
+			"example.com/billing/invoice": {Name: "invoice", Nc: 1, Distance: 1},
+		},
+	}
+	shop := &models.ModuleMetrics{
+		Packages: map[string]models.PackageMetrics{
+			"example.com/shop":      {Name: "shop", Ce: 1, Dependencies: []string{"cart"}},
+			"example.com/shop/cart": {Name: "cart", Ca: 1, Ce: 2, Instability: 2.0 / 3, Dependencies: []string{"billing/invoice", "x/tools"}, Dependents: []string{"shop"}},
+		},
+		Names: map[string]string{
+			"shop":            "example.com/shop",
+			"billing/invoice": "example.com/billing/invoice",
+			"x/tools":         "golang.org/x/tools",
+		},
+		Findings: []models.Finding{{Package: "cart", Severity: models.SeverityWarning}},
+	}
+
+	modules := Combine(ws, []*models.ModuleMetrics{billing, shop}, false)
+	if invoice := modules.Packages["example.com/billing:invoice"]; invoice.Name != "billing/invoice" || invoice.Ca != 0 {
+		t.Errorf("expected billing/invoice without dependents, got %+v", invoice)
+	}
+	cart := modules.Packages["example.com/shop:cart"]
+	if cart.Name != "services/shop/cart" || strings.Join(cart.Dependents, ",") != "services/shop" {
+		t.Errorf("expected prefixed names, got %q with dependents %v", cart.Name, cart.Dependents)
+	}
+	if modules.Findings[0].Package != "services/shop/cart" {
+		t.Errorf("expected prefixed finding, got %q", modules.Findings[0].Package)
+	}
+
+	merged := Combine(ws, []*models.ModuleMetrics{billing, shop}, true)
+	invoice := merged.Packages["example.com/billing:invoice"]
+	if invoice.Ca != 1 || strings.Join(invoice.Dependents, ",") != "services/shop/cart" || invoice.Instability != 0 || invoice.Distance != 1 {
+		t.Errorf("expected the cross-module dependent to count, got %+v", invoice)
+	}
+	if deps := strings.Join(merged.Packages["example.com/shop:cart"].Dependencies, ","); deps != "billing/invoice,x/tools" {
+		t.Errorf("unexpected dependencies of cart: %s", deps)
+	}
+	if merged.Names["x/tools"] != "golang.org/x/tools" || merged.Names["services/shop"] != "example.com/shop" {
+		t.Errorf("unexpected name table: %v", merged.Names)
+	}
+}
+
+// writeModule writes files, keyed by slash-separated paths, into a new temporary
+// directory and returns the directory
+func writeModule(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}